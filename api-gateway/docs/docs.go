@@ -16,6 +16,26 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/.well-known/jwks.json": {
+            "get": {
+                "description": "public keys of the access tokens as JSON Web Key Set, keys of the rotation included",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Account"
+                ],
+                "summary": "Get JWKS",
+                "responses": {
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    }
+                }
+            }
+        },
         "/account": {
             "get": {
                 "description": "get all accounts, returns accounts",
@@ -48,7 +68,7 @@ const docTemplate = `{
                 }
             },
             "post": {
-                "description": "register new account, returns account",
+                "description": "register new account with the sign-in email and password, returns account\noptional X-Device header names the device of the session",
                 "consumes": [
                     "application/json"
                 ],
@@ -66,7 +86,7 @@ const docTemplate = `{
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/github.com_Edbeer_api-gateway_pkg_auth_routes.CreateRequest"
+                            "$ref": "#/definitions/pkg_auth_routes.CreateRequest"
                         }
                     }
                 ],
@@ -94,7 +114,7 @@ const docTemplate = `{
         },
         "/account/deposit": {
             "post": {
-                "description": "deposit money to account, returns account",
+                "description": "deposit money to the card of the account, returns account",
                 "consumes": [
                     "application/json"
                 ],
@@ -123,6 +143,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/utils.ApiError"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
@@ -138,37 +164,32 @@ const docTemplate = `{
                 }
             }
         },
-        "/account/refresh": {
-            "post": {
-                "description": "refresh access and refresh tokens, returns tokens",
-                "consumes": [
-                    "application/json"
-                ],
+        "/account/holds/{id}": {
+            "get": {
+                "description": "get holds of the account, returns blocked money as the sum of active holds and the holds with amount, captured amount, state, reference and expiry",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "Account"
                 ],
-                "summary": "Refresh tokens",
+                "summary": "Get account holds",
                 "parameters": [
                     {
-                        "description": "refresh tokens account info",
-                        "name": "input",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/routes.RefreshRequest"
-                        }
+                        "type": "string",
+                        "description": "get holds info",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "only active holds",
+                        "name": "active",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/routes.Tokens"
-                        }
-                    },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
@@ -190,9 +211,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/account/sign-in": {
+        "/account/mfa/disable/{id}": {
             "post": {
-                "description": "log in to your account, returns account",
+                "description": "disable two-factor authentication with the password and TOTP or recovery code, returns status",
                 "consumes": [
                     "application/json"
                 ],
@@ -202,15 +223,22 @@ const docTemplate = `{
                 "tags": [
                     "Account"
                 ],
-                "summary": "Login",
+                "summary": "Disable TOTP",
                 "parameters": [
                     {
-                        "description": "login account info",
+                        "type": "string",
+                        "description": "disable totp account info",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "password and code",
                         "name": "input",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/routes.LoginRequest"
+                            "$ref": "#/definitions/routes.DisableTOTPRequest"
                         }
                     }
                 ],
@@ -221,12 +249,6 @@ const docTemplate = `{
                             "$ref": "#/definitions/utils.ApiError"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "$ref": "#/definitions/utils.ApiError"
-                        }
-                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -236,21 +258,30 @@ const docTemplate = `{
                 }
             }
         },
-        "/account/sign-out": {
+        "/account/mfa/enroll/{id}": {
             "post": {
-                "description": "log out of your account, returns status",
+                "description": "start two-factor authentication, returns TOTP secret and otpauth uri, enabled after verify",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "Account"
                 ],
-                "summary": "Logout",
+                "summary": "Enroll TOTP",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "enroll totp account info",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "integer"
+                            "$ref": "#/definitions/routes.TOTPEnrollment"
                         }
                     },
                     "400": {
@@ -259,12 +290,6 @@ const docTemplate = `{
                             "$ref": "#/definitions/utils.ApiError"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "$ref": "#/definitions/utils.ApiError"
-                        }
-                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -274,34 +299,46 @@ const docTemplate = `{
                 }
             }
         },
-        "/account/statement/{id}": {
-            "get": {
-                "description": "get account statement, returns statement",
+        "/account/mfa/verify/{id}": {
+            "post": {
+                "description": "enable two-factor authentication with the first TOTP code, returns recovery codes",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "Account"
                 ],
-                "summary": "Get account statement",
+                "summary": "Verify TOTP",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "get statement info",
+                        "description": "verify totp account info",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "totp code",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/routes.VerifyTOTPRequest"
+                        }
                     }
                 ],
                 "responses": {
-                    "400": {
-                        "description": "Bad Request",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/utils.ApiError"
+                            "$ref": "#/definitions/routes.RecoveryCodes"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/utils.ApiError"
                         }
@@ -315,20 +352,20 @@ const docTemplate = `{
                 }
             }
         },
-        "/account/{id}": {
+        "/account/oauth/clients/{id}": {
             "get": {
-                "description": "get account by id, returns account",
+                "description": "clients of the merchant servers without secrets",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Account"
+                    "OAuth"
                 ],
-                "summary": "Get account by id",
+                "summary": "List OAuth2 clients",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "get account by id info",
+                        "description": "merchant account info",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -341,8 +378,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/utils.ApiError"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/utils.ApiError"
                         }
@@ -355,8 +392,8 @@ const docTemplate = `{
                     }
                 }
             },
-            "put": {
-                "description": "update account, returns updated account",
+            "post": {
+                "description": "register client of the merchant server for the client credentials grant, returns client with its secret, the secret is not shown again",
                 "consumes": [
                     "application/json"
                 ],
@@ -364,24 +401,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Account"
+                    "OAuth"
                 ],
-                "summary": "Update account",
+                "summary": "Create OAuth2 client",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "update account info",
+                        "description": "merchant account info",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "update account info",
+                        "description": "client info",
                         "name": "input",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/routes.UpdateRequest"
+                            "$ref": "#/definitions/routes.OAuthClientRequest"
                         }
                     }
                 ],
@@ -392,8 +429,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/utils.ApiError"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/utils.ApiError"
                         }
@@ -405,40 +442,43 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
+            }
+        },
+        "/account/oauth/clients/{id}/{client_id}": {
             "delete": {
-                "description": "delete account, returns status",
+                "description": "delete client of the merchant server, its tokens are valid until they expire, returns status",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Account"
+                    "OAuth"
                 ],
-                "summary": "Delete account",
+                "summary": "Delete OAuth2 client",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "delete account info",
+                        "description": "merchant account info",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "client id",
+                        "name": "client_id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "type": "integer"
-                        }
-                    },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/utils.ApiError"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/utils.ApiError"
                         }
@@ -452,9 +492,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/payment/auth": {
+        "/account/password/forgot": {
             "post": {
-                "description": "Create payment: Acceptance of payment",
+                "description": "send the password reset link to the email, the response is the same for unknown emails",
                 "consumes": [
                     "application/json"
                 ],
@@ -462,24 +502,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Payment"
+                    "Account"
                 ],
-                "summary": "Create payment",
+                "summary": "Request password reset",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "create payment info",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "create payment info",
+                        "description": "email info",
                         "name": "input",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/github.com_Edbeer_api-gateway_pkg_payment_routes.CreateRequest"
+                            "$ref": "#/definitions/routes.PasswordResetRequest"
                         }
                     }
                 ],
@@ -490,8 +523,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/utils.ApiError"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "429": {
+                        "description": "Too Many Requests",
                         "schema": {
                             "$ref": "#/definitions/utils.ApiError"
                         }
@@ -505,9 +538,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/payment/cancel/{id}": {
+        "/account/password/reset": {
             "post": {
-                "description": "Cancel payment: cancel authorization payment",
+                "description": "set the new password by the token of the reset link, the token is used once, sessions of the account are revoked",
                 "consumes": [
                     "application/json"
                 ],
@@ -515,24 +548,64 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Payment"
+                    "Account"
                 ],
-                "summary": "Cancel payment",
+                "summary": "Reset password",
+                "parameters": [
+                    {
+                        "description": "reset info",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/routes.ResetPasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/account/password/{id}": {
+            "put": {
+                "description": "change password of the account, the old password is required, returns status",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Account"
+                ],
+                "summary": "Change password",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "cancel payment info",
+                        "description": "change password account info",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "cancel payment info",
+                        "description": "change password info",
                         "name": "input",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/routes.PaidRequest"
+                            "$ref": "#/definitions/routes.ChangePasswordRequest"
                         }
                     }
                 ],
@@ -543,6 +616,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/utils.ApiError"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
@@ -558,9 +637,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/payment/capture/{id}": {
+        "/account/refresh": {
             "post": {
-                "description": "Capture payment: Successful payment",
+                "description": "refresh access and refresh tokens, returns tokens",
                 "consumes": [
                     "application/json"
                 ],
@@ -568,28 +647,27 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Payment"
+                    "Account"
                 ],
-                "summary": "Capture payment",
+                "summary": "Refresh tokens",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "capture payment info",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "capture payment info",
+                        "description": "refresh tokens account info",
                         "name": "input",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/routes.PaidRequest"
+                            "$ref": "#/definitions/routes.RefreshRequest"
                         }
                     }
                 ],
                 "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/routes.Tokens"
+                        }
+                    },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
@@ -611,9 +689,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/payment/refund/{id}": {
-            "post": {
-                "description": "Refund: Refunded payment, if there is a refund",
+        "/account/role/{id}": {
+            "put": {
+                "description": "set role of the account: customer, merchant, support or admin, sessions of the account are revoked, admins only",
                 "consumes": [
                     "application/json"
                 ],
@@ -621,24 +699,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Payment"
+                    "Account"
                 ],
-                "summary": "Refund payment",
+                "summary": "Set role",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "refund payment info",
+                        "description": "set role account info",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "refund payment info",
+                        "description": "role info",
                         "name": "input",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/routes.PaidRequest"
+                            "$ref": "#/definitions/routes.SetRoleRequest"
                         }
                     }
                 ],
@@ -649,8 +727,14 @@ const docTemplate = `{
                             "$ref": "#/definitions/utils.ApiError"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/utils.ApiError"
                         }
@@ -663,17 +747,1688 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "github.com_Edbeer_api-gateway_pkg_auth_routes.CreateRequest": {
-            "type": "object",
-            "properties": {
-                "card_expiry_month": {
-                    "type": "string"
-                },
-                "card_expiry_year": {
-                    "type": "string"
+        },
+        "/account/sessions/{id}": {
+            "get": {
+                "description": "get signed in sessions of the account, the session of the refresh-token cookie is current",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Account"
+                ],
+                "summary": "Get account sessions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "get sessions account info",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/routes.Session"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "sign out all sessions of the account, returns status",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Account"
+                ],
+                "summary": "Revoke all sessions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "revoke sessions account info",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/account/sessions/{id}/{session_id}": {
+            "delete": {
+                "description": "sign out the session of the account, returns status",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Account"
+                ],
+                "summary": "Revoke session",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "revoke session account info",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "session id",
+                        "name": "session_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/account/sign-in": {
+            "post": {
+                "description": "log in to your account with email and password, returns account\nor the mfa token for the second factor if two-factor authentication is enabled,\noptional X-Device header names the device of the session",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Account"
+                ],
+                "summary": "Login",
+                "parameters": [
+                    {
+                        "description": "login account info",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/routes.LoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/account/sign-in/mfa": {
+            "post": {
+                "description": "second step of the login with the mfa token and TOTP or recovery code, returns account",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Account"
+                ],
+                "summary": "Login second factor",
+                "parameters": [
+                    {
+                        "description": "second factor info",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/routes.MFASignInRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/account/sign-out": {
+            "post": {
+                "description": "log out of your account, returns status",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Account"
+                ],
+                "summary": "Logout",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/account/statement/{id}": {
+            "get": {
+                "description": "get account statement, returns entries with the counterparty, operation, status, signed amount, currency and balance after the entry",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Account"
+                ],
+                "summary": "Get account statement",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "get statement info",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "start of the period, RFC 3339 or YYYY-MM-DD",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "end of the period, RFC 3339 or YYYY-MM-DD",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "resume after the entry with this cursor",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "entries on the page, 100 by default, 1000 at most",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/account/statement/{id}/export": {
+            "get": {
+                "description": "Export account statement for the period as ISO 20022 camt.053, CSV or OFX file with opening and closing balances",
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "Account"
+                ],
+                "summary": "Export account statement",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "account id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "camt.053, csv or ofx",
+                        "name": "format",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "start of the period, RFC 3339 or YYYY-MM-DD",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "end of the period, RFC 3339 or YYYY-MM-DD, now by default",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/account/unlock/{id}": {
+            "post": {
+                "description": "lift the lockout of the account after failed sign-ins, and of the client address if given, admins only",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Account"
+                ],
+                "summary": "Unlock account",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "unlock account info",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "address info",
+                        "name": "input",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/routes.UnlockAccountRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/account/verify-email": {
+            "post": {
+                "description": "verify the email by the token of the verification link, payments are allowed to verified accounts only",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Account"
+                ],
+                "summary": "Verify email",
+                "parameters": [
+                    {
+                        "description": "verification info",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/routes.VerifyEmailRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/account/verify-email/resend/{id}": {
+            "post": {
+                "description": "send a new verification link to the email of the account, the previous link stops working",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Account"
+                ],
+                "summary": "Resend verification",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "resend verification account info",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/account/{id}": {
+            "get": {
+                "description": "get account by id, returns account",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Account"
+                ],
+                "summary": "Get account by id",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "get account by id info",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "update account, returns updated account",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Account"
+                ],
+                "summary": "Update account",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "update account info",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "update account info",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/routes.UpdateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "delete account, returns status",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Account"
+                ],
+                "summary": "Delete account",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "delete account info",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/oauth/token": {
+            "post": {
+                "description": "client credentials grant: grant_type=client_credentials with the client id and secret in HTTP Basic auth or in the form, optional space separated scope, returns access token for the payment routes",
+                "consumes": [
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "OAuth"
+                ],
+                "summary": "OAuth2 token",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "client_credentials",
+                        "name": "grant_type",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "payments:write payments:read",
+                        "name": "scope",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "client id",
+                        "name": "client_id",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "client secret",
+                        "name": "client_secret",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/routes.OAuthToken"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/routes.OAuthError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/routes.OAuthError"
+                        }
+                    }
+                }
+            }
+        },
+        "/payment/auth": {
+            "post": {
+                "description": "Create payment: Acceptance of payment",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Payment"
+                ],
+                "summary": "Create payment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "create payment info",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "create payment info",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/pkg_payment_routes.CreateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/payment/cancel/{id}": {
+            "post": {
+                "description": "Cancel payment: cancel authorization payment",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Payment"
+                ],
+                "summary": "Cancel payment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "cancel payment info",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "cancel payment info",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/routes.PaidRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/payment/capture/{id}": {
+            "post": {
+                "description": "Capture payment: Successful payment",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Payment"
+                ],
+                "summary": "Capture payment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "capture payment info",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "capture payment info",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/routes.PaidRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/payment/challenge/{id}": {
+            "post": {
+                "description": "Complete challenge: customer confirms the payment in \"Requires action\" status with the one-time code",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Payment"
+                ],
+                "summary": "Complete challenge",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "challenge id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "challenge info",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/routes.ChallengeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/payment/decrement/{id}": {
+            "post": {
+                "description": "Decrement authorization: release part of the money blocked for the approved payment",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Payment"
+                ],
+                "summary": "Decrement authorization",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "authorization payment id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "decrement info",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/routes.PaidRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/payment/delinquent": {
+            "get": {
+                "description": "Delinquent and defaulted installments of the merchant or the customer",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Payment"
+                ],
+                "summary": "Delinquent installments",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "merchant account id",
+                        "name": "merchant_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "customer account id",
+                        "name": "customer_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/payment/escrow/refund/{id}": {
+            "post": {
+                "description": "Refund escrow: money held for the captured payment is returned to the customer",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Payment"
+                ],
+                "summary": "Refund escrow",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "captured payment id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "refund escrow info",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/routes.PaidRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/payment/escrow/release/{id}": {
+            "post": {
+                "description": "Release escrow: money held for the captured payment goes to the merchant balance",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Payment"
+                ],
+                "summary": "Release escrow",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "captured payment id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/payment/events": {
+            "get": {
+                "description": "Server-Sent Events feed of payment state changes, resumes after Last-Event-ID header or cursor",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "Payment"
+                ],
+                "summary": "Payment events",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "merchant account id",
+                        "name": "merchant_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "customer account id",
+                        "name": "customer_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "resume after this event cursor",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/payment/import": {
+            "post": {
+                "description": "Import payments: batch of transfers from CSV or ISO 20022 pain.001 file, every row is reported, pain.002 status report is returned for pain.001",
+                "consumes": [
+                    "text/plain"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Payment"
+                ],
+                "summary": "Import payments",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "csv or pain.001",
+                        "name": "format",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "payment file",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/payment/increment/{id}": {
+            "post": {
+                "description": "Increment authorization: block extra money of the customer for the approved payment",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Payment"
+                ],
+                "summary": "Increment authorization",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "authorization payment id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "increment info",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/routes.PaidRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/payment/installments/{id}": {
+            "get": {
+                "description": "Get installment plan with the schedule of installments",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Payment"
+                ],
+                "summary": "Get installment plan",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "installment plan id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create installment plan: approved authorization is paid to the merchant in full, the customer is charged by schedule",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Payment"
+                ],
+                "summary": "Create installment plan",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "authorization payment id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "installment plan info",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/routes.InstallmentRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/payment/refund/{id}": {
+            "post": {
+                "description": "Refund: Refunded payment, if there is a refund",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Payment"
+                ],
+                "summary": "Refund payment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "refund payment info",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "refund payment info",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/routes.PaidRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/payment/scheduled": {
+            "get": {
+                "description": "Future-dated payments of the merchant or the customer with their state",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Payment"
+                ],
+                "summary": "Scheduled payments",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "merchant account id",
+                        "name": "merchant_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "customer account id",
+                        "name": "customer_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/payment/scheduled/cancel/{id}": {
+            "post": {
+                "description": "Cancel scheduled payment until it is executed",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Payment"
+                ],
+                "summary": "Cancel scheduled payment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "scheduled payment id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/payment/scheduled/update/{id}": {
+            "post": {
+                "description": "Update scheduled payment: change amount or execution time until the payment is executed",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Payment"
+                ],
+                "summary": "Update scheduled payment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "scheduled payment id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "scheduled payment changes",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/routes.ScheduledUpdateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/payment/split": {
+            "post": {
+                "description": "Create split payment: one authorization distributed between several merchants on capture",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Payment"
+                ],
+                "summary": "Create split payment",
+                "parameters": [
+                    {
+                        "description": "create split payment info",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/routes.SplitRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/transfer": {
+            "post": {
+                "description": "Transfer: instant account-to-account transfer, repeated request with the same Idempotency-Key returns the first result",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Payment"
+                ],
+                "summary": "Transfer",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "unique key of the transfer",
+                        "name": "Idempotency-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "transfer info",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/routes.TransferRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ApiError"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "pkg_auth_routes.CreateRequest": {
+            "type": "object",
+            "properties": {
+                "card_expiry_month": {
+                    "type": "string"
+                },
+                "card_expiry_year": {
+                    "type": "string"
                 },
                 "card_number": {
                     "type": "string"
@@ -681,15 +2436,25 @@ const docTemplate = `{
                 "card_security_code": {
                     "type": "string"
                 },
+                "email": {
+                    "type": "string"
+                },
                 "first_name": {
                     "type": "string"
                 },
                 "last_name": {
                     "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                },
+                "role": {
+                    "description": "customer or merchant, customer by default",
+                    "type": "string"
                 }
             }
         },
-        "github.com_Edbeer_api-gateway_pkg_payment_routes.CreateRequest": {
+        "pkg_payment_routes.CreateRequest": {
             "type": "object",
             "properties": {
                 "amount": {
@@ -707,17 +2472,53 @@ const docTemplate = `{
                 "card_security_code": {
                     "type": "string"
                 },
+                "challenge": {
+                    "description": "ask the customer for the 3-D Secure challenge",
+                    "type": "boolean"
+                },
                 "currency": {
                     "type": "string"
                 },
                 "customer_id": {
                     "type": "string"
                 },
+                "escrow": {
+                    "description": "captured amount is held in escrow until released",
+                    "type": "boolean"
+                },
+                "escrow_hold_hours": {
+                    "description": "automatic release after capture, 0 - only on request",
+                    "type": "integer"
+                },
+                "execute_at": {
+                    "description": "payment is authorized and captured at this time, empty - now",
+                    "type": "string"
+                },
                 "merchant_id": {
                     "type": "string"
                 }
             }
         },
+        "routes.ChallengeRequest": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "one-time code sent to the customer",
+                    "type": "string"
+                }
+            }
+        },
+        "routes.ChangePasswordRequest": {
+            "type": "object",
+            "properties": {
+                "new_password": {
+                    "type": "string"
+                },
+                "old_password": {
+                    "type": "string"
+                }
+            }
+        },
         "routes.DepositRequest": {
             "type": "object",
             "properties": {
@@ -729,10 +2530,97 @@ const docTemplate = `{
                 }
             }
         },
+        "routes.DisableTOTPRequest": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "TOTP or recovery code",
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "routes.InstallmentRequest": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "description": "number of installments, from 2 to 48",
+                    "type": "integer"
+                },
+                "fee": {
+                    "description": "fee of the plan paid by the customer with the installments",
+                    "type": "integer"
+                },
+                "interval_days": {
+                    "description": "days between installments, 0 - monthly",
+                    "type": "integer"
+                }
+            }
+        },
         "routes.LoginRequest": {
             "type": "object",
             "properties": {
-                "id": {
+                "email": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "routes.MFASignInRequest": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "TOTP or recovery code",
+                    "type": "string"
+                },
+                "mfa_token": {
+                    "type": "string"
+                }
+            }
+        },
+        "routes.OAuthClientRequest": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "description": "payments:write, payments:read",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "routes.OAuthError": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "error_description": {
+                    "type": "string"
+                }
+            }
+        },
+        "routes.OAuthToken": {
+            "type": "object",
+            "properties": {
+                "access_token": {
+                    "type": "string"
+                },
+                "expires_in": {
+                    "type": "integer"
+                },
+                "scope": {
+                    "type": "string"
+                },
+                "token_type": {
                     "type": "string"
                 }
             }
@@ -745,6 +2633,29 @@ const docTemplate = `{
                 }
             }
         },
+        "routes.PasswordResetRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string"
+                }
+            }
+        },
+        "routes.RecoveryCodes": {
+            "type": "object",
+            "properties": {
+                "recovery_codes": {
+                    "description": "shown once",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
         "routes.RefreshRequest": {
             "type": "object",
             "properties": {
@@ -753,6 +2664,132 @@ const docTemplate = `{
                 }
             }
         },
+        "routes.ResetPasswordRequest": {
+            "type": "object",
+            "properties": {
+                "new_password": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "routes.ScheduledUpdateRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "description": "new amount, 0 - unchanged",
+                    "type": "integer"
+                },
+                "execute_at": {
+                    "description": "new execution time, empty - unchanged",
+                    "type": "string"
+                }
+            }
+        },
+        "routes.Session": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "current": {
+                    "description": "session of the request",
+                    "type": "boolean"
+                },
+                "device": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "ip": {
+                    "type": "string"
+                },
+                "user_agent": {
+                    "type": "string"
+                }
+            }
+        },
+        "routes.SetRoleRequest": {
+            "type": "object",
+            "properties": {
+                "role": {
+                    "type": "string"
+                }
+            }
+        },
+        "routes.Split": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "description": "fixed share",
+                    "type": "integer"
+                },
+                "merchant_id": {
+                    "type": "string"
+                },
+                "percent": {
+                    "description": "percentage share in basis points, 100 = 1%",
+                    "type": "integer"
+                }
+            }
+        },
+        "routes.SplitRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "integer"
+                },
+                "card_expiry_month": {
+                    "type": "string"
+                },
+                "card_expiry_year": {
+                    "type": "string"
+                },
+                "card_number": {
+                    "type": "string"
+                },
+                "card_security_code": {
+                    "type": "string"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "customer_id": {
+                    "type": "string"
+                },
+                "platform_fee": {
+                    "type": "integer"
+                },
+                "platform_fee_percent": {
+                    "type": "integer"
+                },
+                "platform_id": {
+                    "type": "string"
+                },
+                "splits": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/routes.Split"
+                    }
+                }
+            }
+        },
+        "routes.TOTPEnrollment": {
+            "type": "object",
+            "properties": {
+                "secret": {
+                    "description": "base32 secret for manual entry",
+                    "type": "string"
+                },
+                "uri": {
+                    "description": "otpauth:// uri for the QR code",
+                    "type": "string"
+                }
+            }
+        },
         "routes.Tokens": {
             "type": "object",
             "properties": {
@@ -764,6 +2801,35 @@ const docTemplate = `{
                 }
             }
         },
+        "routes.TransferRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "integer"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "note": {
+                    "type": "string"
+                },
+                "receiver_id": {
+                    "type": "string"
+                },
+                "sender_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "routes.UnlockAccountRequest": {
+            "type": "object",
+            "properties": {
+                "ip": {
+                    "description": "address of the client, optional",
+                    "type": "string"
+                }
+            }
+        },
         "routes.UpdateRequest": {
             "type": "object",
             "properties": {
@@ -787,6 +2853,22 @@ const docTemplate = `{
                 }
             }
         },
+        "routes.VerifyEmailRequest": {
+            "type": "object",
+            "properties": {
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "routes.VerifyTOTPRequest": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                }
+            }
+        },
         "utils.ApiError": {
             "type": "object",
             "properties": {
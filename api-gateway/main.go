@@ -10,7 +10,10 @@ import (
 	"time"
 
 	"github.com/Edbeer/api-gateway/pkg/auth"
+	red "github.com/Edbeer/api-gateway/pkg/db/redis"
 	"github.com/Edbeer/api-gateway/pkg/payment"
+	"github.com/Edbeer/api-gateway/pkg/ratelimit"
+	"github.com/Edbeer/api-gateway/pkg/utils"
 	"github.com/gorilla/mux"
 	"github.com/gorilla/handlers"
 	_ "github.com/Edbeer/api-gateway/docs"
@@ -27,6 +30,21 @@ import (
 func main() {
 	router := mux.NewRouter()
 
+	// X-Forwarded-For of the proxies in front of the gateway
+	if err := utils.SetTrustedProxies(os.Getenv("TRUSTED_PROXIES")); err != nil {
+		log.Fatal(err)
+	}
+
+	// rate limits of the routes, kept in memory while redis is unavailable
+	limits, err := ratelimit.ConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	redisClient := red.NewRedisClient()
+	defer redisClient.Close()
+	limiter := ratelimit.NewLimiter(limits, redisClient)
+	router.Use(limiter.Middleware(auth.RateLimitKey))
+
 	// auth
 	auth.RegisterAuthRoutes(router)
 	// payment
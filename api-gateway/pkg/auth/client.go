@@ -1,6 +1,10 @@
 package auth
 
 import (
+	"log"
+	"os"
+
+	"github.com/Edbeer/api-gateway/pkg/utils"
 	authpb "github.com/Edbeer/payment-proto/auth-grpc/proto"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
@@ -10,11 +14,18 @@ type AuthClient struct {
 	client authpb.AuthServiceClient
 }
 
+// client authenticated as the gateway, the auth service
+// trusts the client addresses of the gateway only
 func AuthServiceClient() authpb.AuthServiceClient {
-	conn, err := grpc.Dial("auth:50052", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	secret := os.Getenv("SERVICE_SECRET")
+	if secret == "" {
+		log.Fatal("SERVICE_SECRET is not set")
+	}
+	conn, err := grpc.Dial("auth:50052", grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithPerRPCCredentials(utils.NewServiceCredentials("gateway", secret)))
 	if err != nil {
 		return nil
 	}
 
 	return authpb.NewAuthServiceClient(conn)
-}
\ No newline at end of file
+}
@@ -4,35 +4,95 @@ import (
 	"net/http"
 
 	"github.com/Edbeer/api-gateway/pkg/utils"
-	"github.com/golang-jwt/jwt/v4"
+	"google.golang.org/grpc/metadata"
 )
 
-// auth middleware
+// public keys of the access tokens, set by RegisterAuthRoutes
+var jwks *utils.JWKS
+
+// auth middleware, the account of the path is the caller,
+// or the caller may read or change accounts of others
 func AuthJWT(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		tokenString := r.Header.Get("x-jwt-token")
-		token, err := utils.ValidateJWT(tokenString)
-		if err != nil {
-			utils.WriteJSON(w, http.StatusBadRequest, "permission denied")
+	return authenticate(func(w http.ResponseWriter, r *http.Request, claims *utils.AccessClaims) {
+		// client tokens are scoped to the payment routes
+		if claims.ClientID != "" {
+			utils.WriteJSON(w, http.StatusForbidden, "permission denied")
 			return
 		}
 
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			utils.WriteJSON(w, http.StatusBadRequest, "permission denied")
-			return
-		}
 		uid, err := utils.GetUUID(r)
 		if err != nil {
 			utils.WriteJSON(w, http.StatusBadRequest, "permission denied")
 			return
 		}
 
-		if claims["id"] != uid.String() {
-			utils.WriteJSON(w, http.StatusBadRequest, "permission denied")
+		anyPerm := PermAccountWriteAny
+		if r.Method == http.MethodGet {
+			anyPerm = PermAccountReadAny
+		}
+		if claims.AccountID != uid.String() && !Can(claims.Role, anyPerm) {
+			utils.WriteJSON(w, http.StatusForbidden, "permission denied")
+			return
+		}
+
+		next(w, r)
+	})
+}
+
+// policy middleware, role of the caller has the permission and client tokens
+// have its scope, owners of the resources are checked by the services
+func Authorize(perm Permission, next http.HandlerFunc) http.HandlerFunc {
+	return authenticate(func(w http.ResponseWriter, r *http.Request, claims *utils.AccessClaims) {
+		if !Can(claims.Role, perm) {
+			utils.WriteJSON(w, http.StatusForbidden, "permission denied")
+			return
+		}
+		if claims.ClientID != "" && !HasScope(claims, perm) {
+			utils.WriteJSON(w, http.StatusForbidden, "insufficient scope")
+			return
+		}
+		if Unverified(claims, perm) {
+			utils.WriteJSON(w, http.StatusForbidden, "email is not verified")
 			return
 		}
 
 		next(w, r)
+	})
+}
+
+// validate the access token and pass it to the services
+func authenticate(next func(w http.ResponseWriter, r *http.Request, claims *utils.AccessClaims)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if jwks == nil {
+			utils.WriteJSON(w, http.StatusUnauthorized, "permission denied")
+			return
+		}
+		tokenString := r.Header.Get("x-jwt-token")
+		claims, err := utils.ValidateJWT(r.Context(), tokenString, jwks)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusUnauthorized, "permission denied")
+			return
+		}
+		// tokens issued before the roles
+		if claims.Role == "" {
+			claims.Role = RoleCustomer
+		}
+
+		ctx := metadata.AppendToOutgoingContext(r.Context(), "authorization", "Bearer "+tokenString)
+		next(w, r.WithContext(ctx), claims)
+	}
+}
+
+// key of the caller for the rate limits: the OAuth2 client or the account
+// of a valid access token, the address of the client otherwise
+func RateLimitKey(r *http.Request) string {
+	if tokenString := r.Header.Get("x-jwt-token"); tokenString != "" && jwks != nil {
+		if claims, err := utils.ValidateJWT(r.Context(), tokenString, jwks); err == nil {
+			if claims.ClientID != "" {
+				return "client:" + claims.ClientID
+			}
+			return "account:" + claims.AccountID
+		}
 	}
-}
\ No newline at end of file
+	return "ip:" + utils.ClientIP(r)
+}
@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"strings"
+
+	"github.com/Edbeer/api-gateway/pkg/utils"
+)
+
+// Roles of the accounts
+const (
+	RoleCustomer = "customer"
+	RoleMerchant = "merchant"
+	RoleSupport  = "support"
+	RoleAdmin    = "admin"
+)
+
+// Permission of the role, the services check the same permissions
+// and the owners of the resources
+type Permission string
+
+const (
+	PermAccountReadAny  Permission = "account:read:any"
+	PermAccountWriteAny Permission = "account:write:any"
+	PermAccountList     Permission = "account:list"
+	PermRoleManage      Permission = "role:manage"
+	PermLoginUnlock     Permission = "login:unlock"
+	PermPaymentCreate   Permission = "payment:create"
+	PermPaymentManage   Permission = "payment:manage"
+	PermPaymentPay      Permission = "payment:pay"
+	PermPaymentRead     Permission = "payment:read"
+	PermPaymentImport   Permission = "payment:import"
+)
+
+var rolePermissions = map[string][]Permission{
+	RoleCustomer: {PermPaymentPay, PermPaymentRead},
+	RoleMerchant: {PermPaymentCreate, PermPaymentManage, PermPaymentPay, PermPaymentRead},
+	RoleSupport:  {PermAccountReadAny, PermPaymentRead},
+	RoleAdmin: {
+		PermAccountReadAny, PermAccountWriteAny, PermAccountList, PermRoleManage, PermLoginUnlock,
+		PermPaymentCreate, PermPaymentManage, PermPaymentPay, PermPaymentRead, PermPaymentImport,
+	},
+}
+
+// Scopes of the OAuth2 client tokens
+const (
+	ScopePaymentsWrite = "payments:write"
+	ScopePaymentsRead  = "payments:read"
+)
+
+// scope of the client tokens by the permission,
+// client tokens have no other permissions
+var permissionScopes = map[Permission]string{
+	PermPaymentCreate: ScopePaymentsWrite,
+	PermPaymentManage: ScopePaymentsWrite,
+	PermPaymentPay:    ScopePaymentsWrite,
+	PermPaymentRead:   ScopePaymentsRead,
+}
+
+// permissions denied to the accounts with an unverified email
+var verifiedPermissions = map[Permission]bool{
+	PermPaymentCreate: true,
+	PermPaymentPay:    true,
+}
+
+// Has the role the permission
+func Can(role string, perm Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// Has the client token the scope of the permission
+func HasScope(claims *utils.AccessClaims, perm Permission) bool {
+	scope, ok := permissionScopes[perm]
+	if !ok {
+		return false
+	}
+	for _, s := range strings.Fields(claims.Scope) {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Is the permission denied by the unverified email of the token
+func Unverified(claims *utils.AccessClaims, perm Permission) bool {
+	return claims.EmailVerified != nil && !*claims.EmailVerified && verifiedPermissions[perm]
+}
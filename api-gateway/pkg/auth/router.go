@@ -12,24 +12,45 @@ func RegisterAuthRoutes(router *mux.Router) *AuthClient {
 	client := &AuthClient{
 		client: AuthServiceClient(),
 	}
+	jwks = utils.NewJWKS(client.client)
 	// POST
 	postRouter := router.Methods(http.MethodPost).Subrouter()
 	postRouter.HandleFunc("/account", utils.HTTPHandler(client.CreateAccount))
-	postRouter.HandleFunc("/account/deposit", utils.HTTPHandler(client.DepositAccount))
+	postRouter.HandleFunc("/account/deposit", AuthJWT(utils.HTTPHandler(client.DepositAccount)))
 	postRouter.HandleFunc("/account/sign-in", utils.HTTPHandler(client.SignIn))
+	postRouter.HandleFunc("/account/sign-in/mfa", utils.HTTPHandler(client.SignInMFA))
+	postRouter.HandleFunc("/account/mfa/enroll/{id}", AuthJWT(utils.HTTPHandler(client.EnrollTOTP)))
+	postRouter.HandleFunc("/account/mfa/verify/{id}", AuthJWT(utils.HTTPHandler(client.VerifyTOTP)))
+	postRouter.HandleFunc("/account/mfa/disable/{id}", AuthJWT(utils.HTTPHandler(client.DisableTOTP)))
 	postRouter.HandleFunc("/account/sign-out", utils.HTTPHandler(client.Signout))
 	postRouter.HandleFunc("/account/refresh", utils.HTTPHandler(client.RefreshTokens))
+	postRouter.HandleFunc("/oauth/token", utils.HTTPHandler(client.IssueToken))
+	postRouter.HandleFunc("/account/oauth/clients/{id}", AuthJWT(utils.HTTPHandler(client.CreateOAuthClient)))
+	postRouter.HandleFunc("/account/unlock/{id}", Authorize(PermLoginUnlock, utils.HTTPHandler(client.UnlockAccount)))
+	postRouter.HandleFunc("/account/password/forgot", utils.HTTPHandler(client.RequestPasswordReset))
+	postRouter.HandleFunc("/account/password/reset", utils.HTTPHandler(client.ResetPassword))
+	postRouter.HandleFunc("/account/verify-email", utils.HTTPHandler(client.VerifyEmail))
+	postRouter.HandleFunc("/account/verify-email/resend/{id}", AuthJWT(utils.HTTPHandler(client.ResendVerification)))
 	// GET
 	getRouter := router.Methods(http.MethodGet).Subrouter()
-	getRouter.HandleFunc("/account", utils.HTTPHandler(client.GetAccount))
+	getRouter.HandleFunc("/account", Authorize(PermAccountList, utils.HTTPHandler(client.GetAccount)))
+	getRouter.HandleFunc("/.well-known/jwks.json", utils.HTTPHandler(client.GetJWKS))
 	getRouter.HandleFunc("/account/{id}", AuthJWT(utils.HTTPHandler(client.GetAccountByID)))
 	getRouter.HandleFunc("/account/statement/{id}", AuthJWT(utils.HTTPHandler(client.GetStatement)))
+	getRouter.HandleFunc("/account/holds/{id}", AuthJWT(utils.HTTPHandler(client.GetHolds)))
+	getRouter.HandleFunc("/account/sessions/{id}", AuthJWT(utils.HTTPHandler(client.ListSessions)))
+	getRouter.HandleFunc("/account/oauth/clients/{id}", AuthJWT(utils.HTTPHandler(client.ListOAuthClients)))
 	// PUT
 	putRouter := router.Methods(http.MethodPut).Subrouter()
 	putRouter.HandleFunc("/account/{id}", AuthJWT(utils.HTTPHandler(client.UpdateAccount)))
+	putRouter.HandleFunc("/account/password/{id}", AuthJWT(utils.HTTPHandler(client.ChangePassword)))
+	putRouter.HandleFunc("/account/role/{id}", Authorize(PermRoleManage, utils.HTTPHandler(client.SetRole)))
 	// DELETE
 	deleteRouter := router.Methods(http.MethodDelete).Subrouter()
 	deleteRouter.HandleFunc("/account/{id}", AuthJWT(utils.HTTPHandler(client.DeleteAccount)))
+	deleteRouter.HandleFunc("/account/sessions/{id}", AuthJWT(utils.HTTPHandler(client.RevokeAllSessions)))
+	deleteRouter.HandleFunc("/account/sessions/{id}/{session_id}", AuthJWT(utils.HTTPHandler(client.RevokeSession)))
+	deleteRouter.HandleFunc("/account/oauth/clients/{id}/{client_id}", AuthJWT(utils.HTTPHandler(client.DeleteOAuthClient)))
 	return client
 }
 
@@ -48,6 +69,101 @@ func (s *AuthClient) SignIn(w http.ResponseWriter, r *http.Request) error {
 	return routes.SignIn(w, r, s.client)
 }
 
+// SignIn second factor
+func (s *AuthClient) SignInMFA(w http.ResponseWriter, r *http.Request) error {
+	return routes.SignInMFA(w, r, s.client)
+}
+
+// Enroll TOTP
+func (s *AuthClient) EnrollTOTP(w http.ResponseWriter, r *http.Request) error {
+	return routes.EnrollTOTP(w, r, s.client)
+}
+
+// Verify TOTP
+func (s *AuthClient) VerifyTOTP(w http.ResponseWriter, r *http.Request) error {
+	return routes.VerifyTOTP(w, r, s.client)
+}
+
+// Disable TOTP
+func (s *AuthClient) DisableTOTP(w http.ResponseWriter, r *http.Request) error {
+	return routes.DisableTOTP(w, r, s.client)
+}
+
+// List Sessions
+func (s *AuthClient) ListSessions(w http.ResponseWriter, r *http.Request) error {
+	return routes.ListSessions(w, r, s.client)
+}
+
+// Revoke Session
+func (s *AuthClient) RevokeSession(w http.ResponseWriter, r *http.Request) error {
+	return routes.RevokeSession(w, r, s.client)
+}
+
+// Revoke All Sessions
+func (s *AuthClient) RevokeAllSessions(w http.ResponseWriter, r *http.Request) error {
+	return routes.RevokeAllSessions(w, r, s.client)
+}
+
+// Get JWKS
+func (s *AuthClient) GetJWKS(w http.ResponseWriter, r *http.Request) error {
+	return routes.GetJWKS(w, r, jwks)
+}
+
+// Change Password
+func (s *AuthClient) ChangePassword(w http.ResponseWriter, r *http.Request) error {
+	return routes.ChangePassword(w, r, s.client)
+}
+
+// Set Role
+func (s *AuthClient) SetRole(w http.ResponseWriter, r *http.Request) error {
+	return routes.SetRole(w, r, s.client)
+}
+
+// Unlock Account
+func (s *AuthClient) UnlockAccount(w http.ResponseWriter, r *http.Request) error {
+	return routes.UnlockAccount(w, r, s.client)
+}
+
+// Request Password Reset
+func (s *AuthClient) RequestPasswordReset(w http.ResponseWriter, r *http.Request) error {
+	return routes.RequestPasswordReset(w, r, s.client)
+}
+
+// Reset Password
+func (s *AuthClient) ResetPassword(w http.ResponseWriter, r *http.Request) error {
+	return routes.ResetPassword(w, r, s.client)
+}
+
+// Verify Email
+func (s *AuthClient) VerifyEmail(w http.ResponseWriter, r *http.Request) error {
+	return routes.VerifyEmail(w, r, s.client)
+}
+
+// Resend Verification
+func (s *AuthClient) ResendVerification(w http.ResponseWriter, r *http.Request) error {
+	return routes.ResendVerification(w, r, s.client)
+}
+
+// Create OAuth2 Client
+func (s *AuthClient) CreateOAuthClient(w http.ResponseWriter, r *http.Request) error {
+	return routes.CreateOAuthClient(w, r, s.client)
+}
+
+// List OAuth2 Clients
+func (s *AuthClient) ListOAuthClients(w http.ResponseWriter, r *http.Request) error {
+	return routes.ListOAuthClients(w, r, s.client)
+}
+
+// Delete OAuth2 Client
+func (s *AuthClient) DeleteOAuthClient(w http.ResponseWriter, r *http.Request) error {
+	return routes.DeleteOAuthClient(w, r, s.client)
+}
+
+// OAuth2 Token
+func (s *AuthClient) IssueToken(w http.ResponseWriter, r *http.Request) error {
+	return routes.IssueToken(w, r, s.client)
+}
+
 // SignOut
 func (s *AuthClient) Signout(w http.ResponseWriter, r *http.Request) error {
 	return routes.SignOut(w, r, s.client)
@@ -73,6 +189,11 @@ func (s *AuthClient) GetStatement(w http.ResponseWriter, r *http.Request) error
 	return routes.GetStatement(w, r, s.client)
 }
 
+// Get Holds of Account
+func (s *AuthClient) GetHolds(w http.ResponseWriter, r *http.Request) error {
+	return routes.GetHolds(w, r, s.client)
+}
+
 // Update Account
 func (s *AuthClient) UpdateAccount(w http.ResponseWriter, r *http.Request) error {
 	return routes.UpdateAccount(w, r, s.client)
@@ -4,9 +4,15 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/Edbeer/api-gateway/pkg/utils"
 	authpb "github.com/Edbeer/payment-proto/auth-grpc/proto"
+	"github.com/gorilla/mux"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type CreateRequest struct {
@@ -16,11 +22,16 @@ type CreateRequest struct {
 	CardExpiryMonth  string `json:"card_expiry_month"`
 	CardExpiryYear   string `json:"card_expiry_year"`
 	CardSecurityCode string `json:"card_security_code"`
+	Email            string `json:"email"`
+	Password         string `json:"password"`
+	// customer or merchant, customer by default
+	Role string `json:"role"`
 }
 
 // createAccount godoc
 // @Summary Create new account
-// @Description register new account, returns account
+// @Description register new account with the sign-in email and password, returns account
+// @Description optional X-Device header names the device of the session
 // @Tags Account
 // @Accept json
 // @Produce json
@@ -36,13 +47,16 @@ func CreateAccount(w http.ResponseWriter, r *http.Request, cc authpb.AuthService
 		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
 	}
 
-	accountWithToken, err := cc.CreateAccount(r.Context(), &authpb.CreateRequest{
+	accountWithToken, err := cc.CreateAccount(utils.ClientContext(r), &authpb.CreateRequest{
 		FirstName:        req.FirstName,
 		LastName:         req.LastName,
 		CardNumber:       req.CardNumber,
 		CardExpiryMonth:  req.CardExpiryMonth,
 		CardExpiryYear:   req.CardExpiryYear,
 		CardSecurityCode: req.CardSecurityCode,
+		Email:            req.Email,
+		Password:         req.Password,
+		Role:             req.Role,
 	})
 	if err != nil {
 		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
@@ -120,18 +134,28 @@ func RefreshTokens(w http.ResponseWriter, r *http.Request, cc authpb.AuthService
 }
 
 type LoginRequest struct {
-	Id string `json:"id"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// sign-in waits for the second factor
+type MFAChallenge struct {
+	MfaRequired bool   `json:"mfa_required"`
+	MfaToken    string `json:"mfa_token"`
 }
 
 // signIn godoc
 // @Summary Login
-// @Description log in to your account, returns account
+// @Description log in to your account with email and password, returns account
+// @Description or the mfa token for the second factor if two-factor authentication is enabled,
+// @Description optional X-Device header names the device of the session
 // @Tags Account
 // @Accept json
 // @Produce json
 // @Param input body LoginRequest true "login account info"
 // @Failure 400  {object}  utils.ApiError
 // @Failure 404  {object}  utils.ApiError
+// @Failure 429  {object}  utils.ApiError
 // @Failure 500  {object}  utils.ApiError
 // @Router /account/sign-in [post]
 func SignIn(w http.ResponseWriter, r *http.Request, cc authpb.AuthServiceClient) error {
@@ -141,11 +165,23 @@ func SignIn(w http.ResponseWriter, r *http.Request, cc authpb.AuthServiceClient)
 		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
 	}
 
-	accountWithToken, err := cc.SignIn(r.Context(), &authpb.LoginRequest{
-		Id: req.Id,
+	accountWithToken, err := cc.SignIn(utils.ClientContext(r), &authpb.LoginRequest{
+		Email:    req.Email,
+		Password: req.Password,
 	})
 	if err != nil {
-		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+		// delayed or locked after failed sign-ins
+		if status.Code(err) == codes.ResourceExhausted {
+			return utils.WriteJSON(w, http.StatusTooManyRequests, utils.ApiError{Error: status.Convert(err).Message()})
+		}
+		return utils.WriteJSON(w, http.StatusUnauthorized, utils.ApiError{Error: err.Error()})
+	}
+	// tokens are issued by sign-in/mfa
+	if accountWithToken.MfaRequired {
+		return utils.WriteJSON(w, http.StatusOK, &MFAChallenge{
+			MfaRequired: true,
+			MfaToken:    accountWithToken.MfaToken,
+		})
 	}
 	w.Header().Add("x-jwt-token", accountWithToken.AccessToken)
 	// cookie
@@ -274,12 +310,13 @@ type DepositRequest struct {
 
 // depositAccount godoc
 // @Summary Deposit money
-// @Description deposit money to account, returns account
+// @Description deposit money to the card of the account, returns account
 // @Tags Account
 // @Accept json
 // @Produce json
 // @Param input body DepositRequest true "deposit account info"
 // @Failure 400  {object}  utils.ApiError
+// @Failure 401  {object}  utils.ApiError
 // @Failure 404  {object}  utils.ApiError
 // @Failure 500  {object}  utils.ApiError
 // @Router /account/deposit [post]
@@ -359,10 +396,14 @@ func GetAccount(w http.ResponseWriter, r *http.Request, cc authpb.AuthServiceCli
 
 // getStatement godoc
 // @Summary Get account statement
-// @Description get account statement, returns statement
+// @Description get account statement, returns entries with the counterparty, operation, status, signed amount, currency and balance after the entry
 // @Tags Account
 // @Produce json
 // @Param id path string true "get statement info"
+// @Param from query string false "start of the period, RFC 3339 or YYYY-MM-DD"
+// @Param to query string false "end of the period, RFC 3339 or YYYY-MM-DD"
+// @Param cursor query int false "resume after the entry with this cursor"
+// @Param limit query int false "entries on the page, 100 by default, 1000 at most"
 // @Failure 400  {object}  utils.ApiError
 // @Failure 404  {object}  utils.ApiError
 // @Failure 500  {object}  utils.ApiError
@@ -373,9 +414,30 @@ func GetStatement(w http.ResponseWriter, r *http.Request, cc authpb.AuthServiceC
 		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
 	}
 
-	stream, err := cc.GetStatement(r.Context(), &authpb.StatementGet{
+	req := &authpb.StatementGet{
 		AccountId: uuid.String(),
-	})
+	}
+	query := r.URL.Query()
+	if req.From, err = utils.ParsePeriod(query.Get("from")); err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+	if req.To, err = utils.ParsePeriod(query.Get("to")); err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+	if cursor := query.Get("cursor"); cursor != "" {
+		if req.Cursor, err = strconv.ParseUint(cursor, 10, 64); err != nil {
+			return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+		}
+	}
+	if limit := query.Get("limit"); limit != "" {
+		l, err := strconv.ParseUint(limit, 10, 32)
+		if err != nil {
+			return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+		}
+		req.Limit = uint32(l)
+	}
+
+	stream, err := cc.GetStatement(r.Context(), req)
 	if err != nil {
 		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
 	}
@@ -394,3 +456,724 @@ func GetStatement(w http.ResponseWriter, r *http.Request, cc authpb.AuthServiceC
 
 	return utils.WriteJSON(w, http.StatusOK, statements)
 }
+
+// getHolds godoc
+// @Summary Get account holds
+// @Description get holds of the account, returns blocked money as the sum of active holds and the holds with amount, captured amount, state, reference and expiry
+// @Tags Account
+// @Produce json
+// @Param id path string true "get holds info"
+// @Param active query bool false "only active holds"
+// @Failure 400  {object}  utils.ApiError
+// @Failure 404  {object}  utils.ApiError
+// @Failure 500  {object}  utils.ApiError
+// @Router /account/holds/{id} [get]
+func GetHolds(w http.ResponseWriter, r *http.Request, cc authpb.AuthServiceClient) error {
+	uuid, err := utils.GetUUID(r)
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	req := &authpb.GetHoldsRequest{
+		AccountId: uuid.String(),
+	}
+	if active := r.URL.Query().Get("active"); active != "" {
+		if req.Active, err = strconv.ParseBool(active); err != nil {
+			return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+		}
+	}
+
+	holds, err := cc.GetHolds(r.Context(), req)
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, holds)
+}
+
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password"`
+	NewPassword string `json:"new_password"`
+}
+
+// changePassword godoc
+// @Summary Change password
+// @Description change password of the account, the old password is required, returns status
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Param id path string true "change password account info"
+// @Param input body ChangePasswordRequest true "change password info"
+// @Failure 400  {object}  utils.ApiError
+// @Failure 401  {object}  utils.ApiError
+// @Failure 404  {object}  utils.ApiError
+// @Failure 500  {object}  utils.ApiError
+// @Router /account/password/{id} [put]
+func ChangePassword(w http.ResponseWriter, r *http.Request, cc authpb.AuthServiceClient) error {
+	uuid, err := utils.GetUUID(r)
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+	req := &ChangePasswordRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	resp, err := cc.ChangePassword(r.Context(), &authpb.ChangePasswordRequest{
+		Id:          uuid.String(),
+		OldPassword: req.OldPassword,
+		NewPassword: req.NewPassword,
+	})
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, resp.Status)
+}
+
+type MFASignInRequest struct {
+	MfaToken string `json:"mfa_token"`
+	// TOTP or recovery code
+	Code string `json:"code"`
+}
+
+// signInMFA godoc
+// @Summary Login second factor
+// @Description second step of the login with the mfa token and TOTP or recovery code, returns account
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Param input body MFASignInRequest true "second factor info"
+// @Failure 400  {object}  utils.ApiError
+// @Failure 401  {object}  utils.ApiError
+// @Failure 500  {object}  utils.ApiError
+// @Router /account/sign-in/mfa [post]
+func SignInMFA(w http.ResponseWriter, r *http.Request, cc authpb.AuthServiceClient) error {
+	req := &MFASignInRequest{}
+
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	accountWithToken, err := cc.SignInMFA(utils.ClientContext(r), &authpb.MFASignInRequest{
+		MfaToken: req.MfaToken,
+		Code:     req.Code,
+	})
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusUnauthorized, utils.ApiError{Error: err.Error()})
+	}
+	w.Header().Add("x-jwt-token", accountWithToken.AccessToken)
+	// cookie
+	cookie := &http.Cookie{
+		Name:       "refresh-token",
+		Value:      accountWithToken.RefreshToken,
+		Path:       "/",
+		RawExpires: "",
+		MaxAge:     86400,
+		Secure:     false,
+		HttpOnly:   true,
+		SameSite:   0,
+	}
+	http.SetCookie(w, cookie)
+
+	return utils.WriteJSON(w, http.StatusOK, accountWithToken.Account)
+}
+
+type TOTPEnrollment struct {
+	// base32 secret for manual entry
+	Secret string `json:"secret"`
+	// otpauth:// uri for the QR code
+	Uri string `json:"uri"`
+}
+
+// enrollTOTP godoc
+// @Summary Enroll TOTP
+// @Description start two-factor authentication, returns TOTP secret and otpauth uri, enabled after verify
+// @Tags Account
+// @Produce json
+// @Param id path string true "enroll totp account info"
+// @Success 200 {object} TOTPEnrollment
+// @Failure 400  {object}  utils.ApiError
+// @Failure 500  {object}  utils.ApiError
+// @Router /account/mfa/enroll/{id} [post]
+func EnrollTOTP(w http.ResponseWriter, r *http.Request, cc authpb.AuthServiceClient) error {
+	uuid, err := utils.GetUUID(r)
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	resp, err := cc.EnrollTOTP(r.Context(), &authpb.EnrollTOTPRequest{
+		Id: uuid.String(),
+	})
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, &TOTPEnrollment{
+		Secret: resp.Secret,
+		Uri:    resp.Uri,
+	})
+}
+
+type VerifyTOTPRequest struct {
+	Code string `json:"code"`
+}
+
+type RecoveryCodes struct {
+	Status string `json:"status"`
+	// shown once
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// verifyTOTP godoc
+// @Summary Verify TOTP
+// @Description enable two-factor authentication with the first TOTP code, returns recovery codes
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Param id path string true "verify totp account info"
+// @Param input body VerifyTOTPRequest true "totp code"
+// @Success 200 {object} RecoveryCodes
+// @Failure 400  {object}  utils.ApiError
+// @Failure 500  {object}  utils.ApiError
+// @Router /account/mfa/verify/{id} [post]
+func VerifyTOTP(w http.ResponseWriter, r *http.Request, cc authpb.AuthServiceClient) error {
+	uuid, err := utils.GetUUID(r)
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+	req := &VerifyTOTPRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	resp, err := cc.VerifyTOTP(r.Context(), &authpb.VerifyTOTPRequest{
+		Id:   uuid.String(),
+		Code: req.Code,
+	})
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, &RecoveryCodes{
+		Status:        resp.Status,
+		RecoveryCodes: resp.RecoveryCodes,
+	})
+}
+
+type DisableTOTPRequest struct {
+	Password string `json:"password"`
+	// TOTP or recovery code
+	Code string `json:"code"`
+}
+
+// disableTOTP godoc
+// @Summary Disable TOTP
+// @Description disable two-factor authentication with the password and TOTP or recovery code, returns status
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Param id path string true "disable totp account info"
+// @Param input body DisableTOTPRequest true "password and code"
+// @Failure 400  {object}  utils.ApiError
+// @Failure 500  {object}  utils.ApiError
+// @Router /account/mfa/disable/{id} [post]
+func DisableTOTP(w http.ResponseWriter, r *http.Request, cc authpb.AuthServiceClient) error {
+	uuid, err := utils.GetUUID(r)
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+	req := &DisableTOTPRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	resp, err := cc.DisableTOTP(r.Context(), &authpb.DisableTOTPRequest{
+		Id:       uuid.String(),
+		Password: req.Password,
+		Code:     req.Code,
+	})
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, resp.Status)
+}
+
+type Session struct {
+	Id        string    `json:"id"`
+	Device    string    `json:"device"`
+	UserAgent string    `json:"user_agent"`
+	Ip        string    `json:"ip"`
+	CreatedAt time.Time `json:"created_at"`
+	// session of the request
+	Current bool `json:"current"`
+}
+
+// listSessions godoc
+// @Summary Get account sessions
+// @Description get signed in sessions of the account, the session of the refresh-token cookie is current
+// @Tags Account
+// @Produce json
+// @Param id path string true "get sessions account info"
+// @Success 200 {array} Session
+// @Failure 400  {object}  utils.ApiError
+// @Failure 500  {object}  utils.ApiError
+// @Router /account/sessions/{id} [get]
+func ListSessions(w http.ResponseWriter, r *http.Request, cc authpb.AuthServiceClient) error {
+	uuid, err := utils.GetUUID(r)
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+	req := &authpb.ListSessionsRequest{
+		Id: uuid.String(),
+	}
+	if cookie, err := r.Cookie("refresh-token"); err == nil {
+		req.RefreshToken = cookie.Value
+	}
+
+	resp, err := cc.ListSessions(r.Context(), req)
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	sessions := make([]*Session, 0, len(resp.Sessions))
+	for _, session := range resp.Sessions {
+		sessions = append(sessions, &Session{
+			Id:        session.Id,
+			Device:    session.Device,
+			UserAgent: session.UserAgent,
+			Ip:        session.Ip,
+			CreatedAt: session.CreatedAt.AsTime(),
+			Current:   session.Current,
+		})
+	}
+	return utils.WriteJSON(w, http.StatusOK, sessions)
+}
+
+// revokeSession godoc
+// @Summary Revoke session
+// @Description sign out the session of the account, returns status
+// @Tags Account
+// @Produce json
+// @Param id path string true "revoke session account info"
+// @Param session_id path string true "session id"
+// @Failure 400  {object}  utils.ApiError
+// @Failure 404  {object}  utils.ApiError
+// @Failure 500  {object}  utils.ApiError
+// @Router /account/sessions/{id}/{session_id} [delete]
+func RevokeSession(w http.ResponseWriter, r *http.Request, cc authpb.AuthServiceClient) error {
+	uuid, err := utils.GetUUID(r)
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	resp, err := cc.RevokeSession(r.Context(), &authpb.RevokeSessionRequest{
+		Id:        uuid.String(),
+		SessionId: mux.Vars(r)["session_id"],
+	})
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, resp.Status)
+}
+
+// revokeAllSessions godoc
+// @Summary Revoke all sessions
+// @Description sign out all sessions of the account, returns status
+// @Tags Account
+// @Produce json
+// @Param id path string true "revoke sessions account info"
+// @Failure 400  {object}  utils.ApiError
+// @Failure 500  {object}  utils.ApiError
+// @Router /account/sessions/{id} [delete]
+func RevokeAllSessions(w http.ResponseWriter, r *http.Request, cc authpb.AuthServiceClient) error {
+	uuid, err := utils.GetUUID(r)
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	resp, err := cc.RevokeAllSessions(r.Context(), &authpb.RevokeAllSessionsRequest{
+		Id: uuid.String(),
+	})
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, resp.Status)
+}
+
+// getJWKS godoc
+// @Summary Get JWKS
+// @Description public keys of the access tokens as JSON Web Key Set, keys of the rotation included
+// @Tags Account
+// @Produce json
+// @Failure 500  {object}  utils.ApiError
+// @Router /.well-known/jwks.json [get]
+func GetJWKS(w http.ResponseWriter, r *http.Request, jwks *utils.JWKS) error {
+	keys, err := jwks.Keys(r.Context())
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusInternalServerError, utils.ApiError{Error: err.Error()})
+	}
+	return utils.WriteJSON(w, http.StatusOK, keys)
+}
+
+type SetRoleRequest struct {
+	Role string `json:"role"`
+}
+
+// setRole godoc
+// @Summary Set role
+// @Description set role of the account: customer, merchant, support or admin, sessions of the account are revoked, admins only
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Param id path string true "set role account info"
+// @Param input body SetRoleRequest true "role info"
+// @Failure 400  {object}  utils.ApiError
+// @Failure 401  {object}  utils.ApiError
+// @Failure 403  {object}  utils.ApiError
+// @Failure 500  {object}  utils.ApiError
+// @Router /account/role/{id} [put]
+func SetRole(w http.ResponseWriter, r *http.Request, cc authpb.AuthServiceClient) error {
+	uuid, err := utils.GetUUID(r)
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+	req := &SetRoleRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	resp, err := cc.SetRole(r.Context(), &authpb.SetRoleRequest{
+		Id:   uuid.String(),
+		Role: req.Role,
+	})
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, resp)
+}
+
+type UnlockAccountRequest struct {
+	// address of the client, optional
+	IP string `json:"ip"`
+}
+
+// unlockAccount godoc
+// @Summary Unlock account
+// @Description lift the lockout of the account after failed sign-ins, and of the client address if given, admins only
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Param id path string true "unlock account info"
+// @Param input body UnlockAccountRequest false "address info"
+// @Failure 400  {object}  utils.ApiError
+// @Failure 401  {object}  utils.ApiError
+// @Failure 403  {object}  utils.ApiError
+// @Failure 500  {object}  utils.ApiError
+// @Router /account/unlock/{id} [post]
+func UnlockAccount(w http.ResponseWriter, r *http.Request, cc authpb.AuthServiceClient) error {
+	uuid, err := utils.GetUUID(r)
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+	req := &UnlockAccountRequest{}
+	// the body is optional
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil && err != io.EOF {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	resp, err := cc.UnlockAccount(r.Context(), &authpb.UnlockAccountRequest{
+		Id: uuid.String(),
+		Ip: req.IP,
+	})
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, resp.Status)
+}
+
+type PasswordResetRequest struct {
+	Email string `json:"email"`
+}
+
+// requestPasswordReset godoc
+// @Summary Request password reset
+// @Description send the password reset link to the email, the response is the same for unknown emails
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Param input body PasswordResetRequest true "email info"
+// @Failure 400  {object}  utils.ApiError
+// @Failure 429  {object}  utils.ApiError
+// @Failure 500  {object}  utils.ApiError
+// @Router /account/password/forgot [post]
+func RequestPasswordReset(w http.ResponseWriter, r *http.Request, cc authpb.AuthServiceClient) error {
+	req := &PasswordResetRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	resp, err := cc.RequestPasswordReset(r.Context(), &authpb.PasswordResetRequest{
+		Email: req.Email,
+	})
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, resp.Status)
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+// resetPassword godoc
+// @Summary Reset password
+// @Description set the new password by the token of the reset link, the token is used once, sessions of the account are revoked
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Param input body ResetPasswordRequest true "reset info"
+// @Failure 400  {object}  utils.ApiError
+// @Failure 500  {object}  utils.ApiError
+// @Router /account/password/reset [post]
+func ResetPassword(w http.ResponseWriter, r *http.Request, cc authpb.AuthServiceClient) error {
+	req := &ResetPasswordRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	resp, err := cc.ResetPassword(r.Context(), &authpb.ResetPasswordRequest{
+		Token:       req.Token,
+		NewPassword: req.NewPassword,
+	})
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, resp.Status)
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+// verifyEmail godoc
+// @Summary Verify email
+// @Description verify the email by the token of the verification link, payments are allowed to verified accounts only
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Param input body VerifyEmailRequest true "verification info"
+// @Failure 400  {object}  utils.ApiError
+// @Failure 500  {object}  utils.ApiError
+// @Router /account/verify-email [post]
+func VerifyEmail(w http.ResponseWriter, r *http.Request, cc authpb.AuthServiceClient) error {
+	req := &VerifyEmailRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	resp, err := cc.VerifyEmail(r.Context(), &authpb.VerifyEmailRequest{
+		Token: req.Token,
+	})
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, resp.Status)
+}
+
+// resendVerification godoc
+// @Summary Resend verification
+// @Description send a new verification link to the email of the account, the previous link stops working
+// @Tags Account
+// @Produce json
+// @Param id path string true "resend verification account info"
+// @Failure 400  {object}  utils.ApiError
+// @Failure 401  {object}  utils.ApiError
+// @Failure 500  {object}  utils.ApiError
+// @Router /account/verify-email/resend/{id} [post]
+func ResendVerification(w http.ResponseWriter, r *http.Request, cc authpb.AuthServiceClient) error {
+	uuid, err := utils.GetUUID(r)
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	resp, err := cc.ResendVerification(r.Context(), &authpb.ResendVerificationRequest{
+		Id: uuid.String(),
+	})
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, resp.Status)
+}
+
+type OAuthClientRequest struct {
+	Name string `json:"name"`
+	// payments:write, payments:read
+	Scopes []string `json:"scopes"`
+}
+
+// createOAuthClient godoc
+// @Summary Create OAuth2 client
+// @Description register client of the merchant server for the client credentials grant, returns client with its secret, the secret is not shown again
+// @Tags OAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "merchant account info"
+// @Param input body OAuthClientRequest true "client info"
+// @Failure 400  {object}  utils.ApiError
+// @Failure 403  {object}  utils.ApiError
+// @Failure 500  {object}  utils.ApiError
+// @Router /account/oauth/clients/{id} [post]
+func CreateOAuthClient(w http.ResponseWriter, r *http.Request, cc authpb.AuthServiceClient) error {
+	uuid, err := utils.GetUUID(r)
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+	req := &OAuthClientRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	client, err := cc.CreateOAuthClient(r.Context(), &authpb.CreateOAuthClientRequest{
+		Id:     uuid.String(),
+		Name:   req.Name,
+		Scopes: req.Scopes,
+	})
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, client)
+}
+
+// listOAuthClients godoc
+// @Summary List OAuth2 clients
+// @Description clients of the merchant servers without secrets
+// @Tags OAuth
+// @Produce json
+// @Param id path string true "merchant account info"
+// @Failure 400  {object}  utils.ApiError
+// @Failure 403  {object}  utils.ApiError
+// @Failure 500  {object}  utils.ApiError
+// @Router /account/oauth/clients/{id} [get]
+func ListOAuthClients(w http.ResponseWriter, r *http.Request, cc authpb.AuthServiceClient) error {
+	uuid, err := utils.GetUUID(r)
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	clients, err := cc.ListOAuthClients(r.Context(), &authpb.ListOAuthClientsRequest{
+		Id: uuid.String(),
+	})
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, clients.Clients)
+}
+
+// deleteOAuthClient godoc
+// @Summary Delete OAuth2 client
+// @Description delete client of the merchant server, its tokens are valid until they expire, returns status
+// @Tags OAuth
+// @Produce json
+// @Param id path string true "merchant account info"
+// @Param client_id path string true "client id"
+// @Failure 400  {object}  utils.ApiError
+// @Failure 403  {object}  utils.ApiError
+// @Failure 500  {object}  utils.ApiError
+// @Router /account/oauth/clients/{id}/{client_id} [delete]
+func DeleteOAuthClient(w http.ResponseWriter, r *http.Request, cc authpb.AuthServiceClient) error {
+	uuid, err := utils.GetUUID(r)
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	resp, err := cc.DeleteOAuthClient(r.Context(), &authpb.DeleteOAuthClientRequest{
+		Id:       uuid.String(),
+		ClientId: mux.Vars(r)["client_id"],
+	})
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, resp.Status)
+}
+
+// Access token response of RFC 6749
+type OAuthToken struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Scope       string `json:"scope"`
+}
+
+// Error response of RFC 6749
+type OAuthError struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// issueToken godoc
+// @Summary OAuth2 token
+// @Description client credentials grant: grant_type=client_credentials with the client id and secret in HTTP Basic auth or in the form, optional space separated scope, returns access token for the payment routes
+// @Tags OAuth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param grant_type formData string true "client_credentials"
+// @Param scope formData string false "payments:write payments:read"
+// @Param client_id formData string false "client id"
+// @Param client_secret formData string false "client secret"
+// @Success 200  {object}  OAuthToken
+// @Failure 400  {object}  OAuthError
+// @Failure 401  {object}  OAuthError
+// @Router /oauth/token [post]
+func IssueToken(w http.ResponseWriter, r *http.Request, cc authpb.AuthServiceClient) error {
+	// tokens are not cached by the clients
+	w.Header().Set("Cache-Control", "no-store")
+	if err := r.ParseForm(); err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, OAuthError{Error: "invalid_request", ErrorDescription: err.Error()})
+	}
+	if grantType := r.PostForm.Get("grant_type"); grantType != "client_credentials" {
+		return utils.WriteJSON(w, http.StatusBadRequest, OAuthError{Error: "unsupported_grant_type"})
+	}
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID == "" || clientSecret == "" {
+		return utils.WriteJSON(w, http.StatusUnauthorized, OAuthError{Error: "invalid_client"})
+	}
+
+	token, err := cc.IssueClientToken(r.Context(), &authpb.ClientTokenRequest{
+		ClientId:     clientID,
+		ClientSecret: clientSecret,
+		Scopes:       strings.Fields(r.PostForm.Get("scope")),
+	})
+	if err != nil {
+		switch status.Code(err) {
+		case codes.Unauthenticated:
+			return utils.WriteJSON(w, http.StatusUnauthorized, OAuthError{Error: "invalid_client"})
+		case codes.InvalidArgument:
+			return utils.WriteJSON(w, http.StatusBadRequest, OAuthError{Error: "invalid_scope", ErrorDescription: status.Convert(err).Message()})
+		}
+		return utils.WriteJSON(w, http.StatusInternalServerError, OAuthError{Error: "server_error"})
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, OAuthToken{
+		AccessToken: token.AccessToken,
+		TokenType:   token.TokenType,
+		ExpiresIn:   token.ExpiresIn,
+		Scope:       strings.Join(token.Scopes, " "),
+	})
+}
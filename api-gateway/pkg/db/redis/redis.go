@@ -0,0 +1,27 @@
+package red
+
+import (
+	"os"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func NewRedisClient() *redis.Client {
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		addr = "redis:6379"
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr:        addr,
+		PoolTimeout: time.Second,
+		// requests are not held up by an unavailable redis
+		DialTimeout:  200 * time.Millisecond,
+		ReadTimeout:  100 * time.Millisecond,
+		WriteTimeout: 100 * time.Millisecond,
+		DB:           0, // use default DB
+	})
+
+	return client
+}
@@ -3,6 +3,7 @@ package payment
 import (
 	"net/http"
 
+	"github.com/Edbeer/api-gateway/pkg/auth"
 	"github.com/Edbeer/api-gateway/pkg/payment/routes"
 	"github.com/Edbeer/api-gateway/pkg/utils"
 	"github.com/gorilla/mux"
@@ -13,12 +14,31 @@ func RegisterPaymentRouter(router *mux.Router) *PaymentClient {
 		client: PaymentServiceClient(),
 	}
 
+	// roles of the callers are checked by the gateway, owners of the payments by the service
 	// POST
 	postRouter := router.Methods(http.MethodPost).Subrouter()
-	postRouter.HandleFunc("/payment/auth", utils.HTTPHandler(client.CreatePayment))
-	postRouter.HandleFunc("/payment/capture/{id}", utils.HTTPHandler(client.CapturePayment))
-	postRouter.HandleFunc("/payment/cancel/{id}", utils.HTTPHandler(client.CancelPayment))
-	postRouter.HandleFunc("/payment/refund/{id}", utils.HTTPHandler(client.RefundPayment))
+	postRouter.HandleFunc("/payment/auth", auth.Authorize(auth.PermPaymentCreate, utils.HTTPHandler(client.CreatePayment)))
+	postRouter.HandleFunc("/payment/split", auth.Authorize(auth.PermPaymentCreate, utils.HTTPHandler(client.CreateSplitPayment)))
+	postRouter.HandleFunc("/payment/capture/{id}", auth.Authorize(auth.PermPaymentManage, utils.HTTPHandler(client.CapturePayment)))
+	postRouter.HandleFunc("/payment/cancel/{id}", auth.Authorize(auth.PermPaymentManage, utils.HTTPHandler(client.CancelPayment)))
+	postRouter.HandleFunc("/payment/refund/{id}", auth.Authorize(auth.PermPaymentManage, utils.HTTPHandler(client.RefundPayment)))
+	postRouter.HandleFunc("/payment/increment/{id}", auth.Authorize(auth.PermPaymentManage, utils.HTTPHandler(client.IncrementAuthorization)))
+	postRouter.HandleFunc("/payment/decrement/{id}", auth.Authorize(auth.PermPaymentManage, utils.HTTPHandler(client.DecrementAuthorization)))
+	postRouter.HandleFunc("/payment/challenge/{id}", auth.Authorize(auth.PermPaymentPay, utils.HTTPHandler(client.CompleteChallenge)))
+	postRouter.HandleFunc("/transfer", auth.Authorize(auth.PermPaymentPay, utils.HTTPHandler(client.Transfer)))
+	postRouter.HandleFunc("/payment/escrow/release/{id}", auth.Authorize(auth.PermPaymentManage, utils.HTTPHandler(client.ReleaseEscrow)))
+	postRouter.HandleFunc("/payment/escrow/refund/{id}", auth.Authorize(auth.PermPaymentManage, utils.HTTPHandler(client.RefundEscrow)))
+	postRouter.HandleFunc("/payment/import", auth.Authorize(auth.PermPaymentImport, utils.HTTPHandler(client.ImportPayments)))
+	postRouter.HandleFunc("/payment/installments/{id}", auth.Authorize(auth.PermPaymentManage, utils.HTTPHandler(client.CreateInstallmentPlan)))
+	postRouter.HandleFunc("/payment/scheduled/update/{id}", auth.Authorize(auth.PermPaymentManage, utils.HTTPHandler(client.UpdateScheduledPayment)))
+	postRouter.HandleFunc("/payment/scheduled/cancel/{id}", auth.Authorize(auth.PermPaymentManage, utils.HTTPHandler(client.CancelScheduledPayment)))
+	// GET
+	getRouter := router.Methods(http.MethodGet).Subrouter()
+	getRouter.HandleFunc("/payment/events", auth.Authorize(auth.PermPaymentRead, utils.HTTPHandler(client.SubscribePaymentEvents)))
+	getRouter.HandleFunc("/payment/installments/{id}", auth.Authorize(auth.PermPaymentRead, utils.HTTPHandler(client.GetInstallmentPlan)))
+	getRouter.HandleFunc("/payment/delinquent", auth.Authorize(auth.PermPaymentRead, utils.HTTPHandler(client.GetDelinquentInstallments)))
+	getRouter.HandleFunc("/payment/scheduled", auth.Authorize(auth.PermPaymentRead, utils.HTTPHandler(client.ListScheduledPayments)))
+	getRouter.HandleFunc("/account/statement/{id}/export", auth.Authorize(auth.PermPaymentRead, utils.HTTPHandler(client.ExportStatement)))
 
 	return client
 }
@@ -27,14 +47,78 @@ func (s *PaymentClient) CreatePayment(w http.ResponseWriter, r *http.Request) er
 	return routes.CreatePayment(w, r, s.client)
 }
 
+func (s *PaymentClient) CreateSplitPayment(w http.ResponseWriter, r *http.Request) error {
+	return routes.CreateSplitPayment(w, r, s.client)
+}
+
 func (s *PaymentClient) CapturePayment(w http.ResponseWriter, r *http.Request) error {
 	return routes.CapturePayment(w, r, s.client)
 }
 
 func (s *PaymentClient) CancelPayment(w http.ResponseWriter, r *http.Request) error {
-	return routes.CapturePayment(w, r, s.client)
+	return routes.CancelPayment(w, r, s.client)
 }
 
 func (s *PaymentClient) RefundPayment(w http.ResponseWriter, r *http.Request) error {
-	return routes.CapturePayment(w, r, s.client)
-}
\ No newline at end of file
+	return routes.RefundPayment(w, r, s.client)
+}
+
+func (s *PaymentClient) IncrementAuthorization(w http.ResponseWriter, r *http.Request) error {
+	return routes.IncrementAuthorization(w, r, s.client)
+}
+
+func (s *PaymentClient) DecrementAuthorization(w http.ResponseWriter, r *http.Request) error {
+	return routes.DecrementAuthorization(w, r, s.client)
+}
+
+func (s *PaymentClient) Transfer(w http.ResponseWriter, r *http.Request) error {
+	return routes.Transfer(w, r, s.client)
+}
+
+func (s *PaymentClient) CompleteChallenge(w http.ResponseWriter, r *http.Request) error {
+	return routes.CompleteChallenge(w, r, s.client)
+}
+
+func (s *PaymentClient) ReleaseEscrow(w http.ResponseWriter, r *http.Request) error {
+	return routes.ReleaseEscrow(w, r, s.client)
+}
+
+func (s *PaymentClient) RefundEscrow(w http.ResponseWriter, r *http.Request) error {
+	return routes.RefundEscrow(w, r, s.client)
+}
+
+func (s *PaymentClient) ImportPayments(w http.ResponseWriter, r *http.Request) error {
+	return routes.ImportPayments(w, r, s.client)
+}
+
+func (s *PaymentClient) CreateInstallmentPlan(w http.ResponseWriter, r *http.Request) error {
+	return routes.CreateInstallmentPlan(w, r, s.client)
+}
+
+func (s *PaymentClient) GetInstallmentPlan(w http.ResponseWriter, r *http.Request) error {
+	return routes.GetInstallmentPlan(w, r, s.client)
+}
+
+func (s *PaymentClient) GetDelinquentInstallments(w http.ResponseWriter, r *http.Request) error {
+	return routes.GetDelinquentInstallments(w, r, s.client)
+}
+
+func (s *PaymentClient) ListScheduledPayments(w http.ResponseWriter, r *http.Request) error {
+	return routes.ListScheduledPayments(w, r, s.client)
+}
+
+func (s *PaymentClient) UpdateScheduledPayment(w http.ResponseWriter, r *http.Request) error {
+	return routes.UpdateScheduledPayment(w, r, s.client)
+}
+
+func (s *PaymentClient) CancelScheduledPayment(w http.ResponseWriter, r *http.Request) error {
+	return routes.CancelScheduledPayment(w, r, s.client)
+}
+
+func (s *PaymentClient) SubscribePaymentEvents(w http.ResponseWriter, r *http.Request) error {
+	return routes.SubscribePaymentEvents(w, r, s.client)
+}
+
+func (s *PaymentClient) ExportStatement(w http.ResponseWriter, r *http.Request) error {
+	return routes.ExportStatement(w, r, s.client)
+}
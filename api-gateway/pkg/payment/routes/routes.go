@@ -1,12 +1,18 @@
 package routes
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/Edbeer/api-gateway/pkg/utils"
 	paymentpb "github.com/Edbeer/payment-proto/payment-grpc/proto"
 	"github.com/google/uuid"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 type CreateRequest struct {
@@ -18,6 +24,14 @@ type CreateRequest struct {
 	CardSecurityCode string    `json:"card_security_code"`
 	Currency         string    `json:"currency"`
 	Amount           uint64    `json:"amount"`
+	// captured amount is held in escrow until released
+	Escrow bool `json:"escrow"`
+	// automatic release after capture, 0 - only on request
+	EscrowHoldHours uint32 `json:"escrow_hold_hours"`
+	// ask the customer for the 3-D Secure challenge
+	Challenge bool `json:"challenge"`
+	// payment is authorized and captured at this time, empty - now
+	ExecuteAt *time.Time `json:"execute_at"`
 }
 
 // createPayment godoc
@@ -39,7 +53,7 @@ func CreatePayment(w http.ResponseWriter, r *http.Request, cc paymentpb.PaymentS
 		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
 	}
 
-	statement, err := cc.CreatePayment(r.Context(), &paymentpb.CreateRequest{
+	pbReq := &paymentpb.CreateRequest{
 		Merchant:         req.Merchant.String(),
 		Customer:         req.Customer.String(),
 		CardNumber:       req.CardNumber,
@@ -48,6 +62,82 @@ func CreatePayment(w http.ResponseWriter, r *http.Request, cc paymentpb.PaymentS
 		CardSecurityCode: req.CardSecurityCode,
 		Currency:         req.Currency,
 		Amount:           req.Amount,
+		Escrow:           req.Escrow,
+		EscrowHoldHours:  req.EscrowHoldHours,
+		Challenge:        req.Challenge,
+	}
+	if req.ExecuteAt != nil {
+		pbReq.ExecuteAt = timestamppb.New(*req.ExecuteAt)
+	}
+	statement, err := cc.CreatePayment(r.Context(), pbReq)
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, statement)
+}
+
+type Split struct {
+	Merchant uuid.UUID `json:"merchant_id"`
+	// fixed share
+	Amount uint64 `json:"amount"`
+	// percentage share in basis points, 100 = 1%
+	Percent uint32 `json:"percent"`
+}
+
+type SplitRequest struct {
+	Platform           uuid.UUID `json:"platform_id"`
+	Customer           uuid.UUID `json:"customer_id"`
+	CardNumber         string    `json:"card_number"`
+	CardExpiryMonth    string    `json:"card_expiry_month"`
+	CardExpiryYear     string    `json:"card_expiry_year"`
+	CardSecurityCode   string    `json:"card_security_code"`
+	Currency           string    `json:"currency"`
+	Amount             uint64    `json:"amount"`
+	Splits             []Split   `json:"splits"`
+	PlatformFee        uint64    `json:"platform_fee"`
+	PlatformFeePercent uint32    `json:"platform_fee_percent"`
+}
+
+// createSplitPayment godoc
+// @Summary Create split payment
+// @Description Create split payment: one authorization distributed between several merchants on capture
+// @Tags Payment
+// @Accept json
+// @Produce json
+// @Param input body SplitRequest true "create split payment info"
+// @Failure 400  {object}  utils.ApiError
+// @Failure 404  {object}  utils.ApiError
+// @Failure 500  {object}  utils.ApiError
+// @Router /payment/split [post]
+func CreateSplitPayment(w http.ResponseWriter, r *http.Request, cc paymentpb.PaymentServiceClient) error {
+	req := &SplitRequest{}
+
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	splits := make([]*paymentpb.Split, 0, len(req.Splits))
+	for _, split := range req.Splits {
+		splits = append(splits, &paymentpb.Split{
+			Merchant: split.Merchant.String(),
+			Amount:   split.Amount,
+			Percent:  split.Percent,
+		})
+	}
+
+	statement, err := cc.CreateSplitPayment(r.Context(), &paymentpb.SplitRequest{
+		Platform:           req.Platform.String(),
+		Customer:           req.Customer.String(),
+		CardNumber:         req.CardNumber,
+		CardExpiryMonth:    req.CardExpiryMonth,
+		CardExpiryYear:     req.CardExpiryYear,
+		CardSecurityCode:   req.CardSecurityCode,
+		Currency:           req.Currency,
+		Amount:             req.Amount,
+		Splits:             splits,
+		PlatformFee:        req.PlatformFee,
+		PlatformFeePercent: req.PlatformFeePercent,
 	})
 	if err != nil {
 		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
@@ -160,4 +250,566 @@ func RefundPayment(w http.ResponseWriter, r *http.Request, cc paymentpb.PaymentS
 	}
 
 	return utils.WriteJSON(w, http.StatusOK, statement)
-}
\ No newline at end of file
+}
+
+// incrementAuthorization godoc
+// @Summary Increment authorization
+// @Description Increment authorization: block extra money of the customer for the approved payment
+// @Tags Payment
+// @Accept json
+// @Produce json
+// @Param id path string true "authorization payment id"
+// @Param input body PaidRequest true "increment info"
+// @Failure 400  {object}  utils.ApiError
+// @Failure 404  {object}  utils.ApiError
+// @Failure 500  {object}  utils.ApiError
+// @Router /payment/increment/{id} [post]
+func IncrementAuthorization(w http.ResponseWriter, r *http.Request, cc paymentpb.PaymentServiceClient) error {
+	uuid, err := utils.GetUUID(r)
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	req := &PaidRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	statement, err := cc.IncrementAuthorization(r.Context(), &paymentpb.PaidRequest{
+		PaymentId: uuid.String(),
+		Amount:    req.Amount,
+	})
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, statement)
+}
+
+// decrementAuthorization godoc
+// @Summary Decrement authorization
+// @Description Decrement authorization: release part of the money blocked for the approved payment
+// @Tags Payment
+// @Accept json
+// @Produce json
+// @Param id path string true "authorization payment id"
+// @Param input body PaidRequest true "decrement info"
+// @Failure 400  {object}  utils.ApiError
+// @Failure 404  {object}  utils.ApiError
+// @Failure 500  {object}  utils.ApiError
+// @Router /payment/decrement/{id} [post]
+func DecrementAuthorization(w http.ResponseWriter, r *http.Request, cc paymentpb.PaymentServiceClient) error {
+	uuid, err := utils.GetUUID(r)
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	req := &PaidRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	statement, err := cc.DecrementAuthorization(r.Context(), &paymentpb.PaidRequest{
+		PaymentId: uuid.String(),
+		Amount:    req.Amount,
+	})
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, statement)
+}
+
+type TransferRequest struct {
+	Sender   uuid.UUID `json:"sender_id"`
+	Receiver uuid.UUID `json:"receiver_id"`
+	Currency string    `json:"currency"`
+	Amount   uint64    `json:"amount"`
+	Note     string    `json:"note"`
+}
+
+// transfer godoc
+// @Summary Transfer
+// @Description Transfer: instant account-to-account transfer, repeated request with the same Idempotency-Key returns the first result
+// @Tags Payment
+// @Accept json
+// @Produce json
+// @Param Idempotency-Key header string true "unique key of the transfer"
+// @Param input body TransferRequest true "transfer info"
+// @Failure 400  {object}  utils.ApiError
+// @Failure 404  {object}  utils.ApiError
+// @Failure 500  {object}  utils.ApiError
+// @Router /transfer [post]
+func Transfer(w http.ResponseWriter, r *http.Request, cc paymentpb.PaymentServiceClient) error {
+	req := &TransferRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	statement, err := cc.Transfer(r.Context(), &paymentpb.TransferRequest{
+		Sender:         req.Sender.String(),
+		Receiver:       req.Receiver.String(),
+		Currency:       req.Currency,
+		Amount:         req.Amount,
+		Note:           req.Note,
+		IdempotencyKey: r.Header.Get("Idempotency-Key"),
+	})
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, statement)
+}
+
+type ChallengeRequest struct {
+	// one-time code sent to the customer
+	Code string `json:"code"`
+}
+
+// completeChallenge godoc
+// @Summary Complete challenge
+// @Description Complete challenge: customer confirms the payment in "Requires action" status with the one-time code
+// @Tags Payment
+// @Accept json
+// @Produce json
+// @Param id path string true "challenge id"
+// @Param input body ChallengeRequest true "challenge info"
+// @Failure 400  {object}  utils.ApiError
+// @Failure 404  {object}  utils.ApiError
+// @Failure 500  {object}  utils.ApiError
+// @Router /payment/challenge/{id} [post]
+func CompleteChallenge(w http.ResponseWriter, r *http.Request, cc paymentpb.PaymentServiceClient) error {
+	uuid, err := utils.GetUUID(r)
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	req := &ChallengeRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	statement, err := cc.CompleteChallenge(r.Context(), &paymentpb.ChallengeRequest{
+		ChallengeId: uuid.String(),
+		Code:        req.Code,
+	})
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, statement)
+}
+
+// releaseEscrow godoc
+// @Summary Release escrow
+// @Description Release escrow: money held for the captured payment goes to the merchant balance
+// @Tags Payment
+// @Produce json
+// @Param id path string true "captured payment id"
+// @Failure 400  {object}  utils.ApiError
+// @Failure 404  {object}  utils.ApiError
+// @Failure 500  {object}  utils.ApiError
+// @Router /payment/escrow/release/{id} [post]
+func ReleaseEscrow(w http.ResponseWriter, r *http.Request, cc paymentpb.PaymentServiceClient) error {
+	uuid, err := utils.GetUUID(r)
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	statement, err := cc.ReleaseEscrow(r.Context(), &paymentpb.PaidRequest{
+		PaymentId: uuid.String(),
+	})
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, statement)
+}
+
+// refundEscrow godoc
+// @Summary Refund escrow
+// @Description Refund escrow: money held for the captured payment is returned to the customer
+// @Tags Payment
+// @Accept json
+// @Produce json
+// @Param id path string true "captured payment id"
+// @Param input body PaidRequest true "refund escrow info"
+// @Failure 400  {object}  utils.ApiError
+// @Failure 404  {object}  utils.ApiError
+// @Failure 500  {object}  utils.ApiError
+// @Router /payment/escrow/refund/{id} [post]
+func RefundEscrow(w http.ResponseWriter, r *http.Request, cc paymentpb.PaymentServiceClient) error {
+	uuid, err := utils.GetUUID(r)
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	req := &PaidRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	statement, err := cc.RefundEscrow(r.Context(), &paymentpb.PaidRequest{
+		PaymentId: uuid.String(),
+		Amount:    req.Amount,
+	})
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, statement)
+}
+
+// largest accepted payment file, below the default grpc message size
+const maxImportSize = 3 << 20
+
+// importPayments godoc
+// @Summary Import payments
+// @Description Import payments: batch of transfers from CSV or ISO 20022 pain.001 file, every row is reported, pain.002 status report is returned for pain.001
+// @Tags Payment
+// @Accept plain
+// @Produce json
+// @Param format query string true "csv or pain.001"
+// @Param input body string true "payment file"
+// @Failure 400  {object}  utils.ApiError
+// @Failure 404  {object}  utils.ApiError
+// @Failure 500  {object}  utils.ApiError
+// @Router /payment/import [post]
+func ImportPayments(w http.ResponseWriter, r *http.Request, cc paymentpb.PaymentServiceClient) error {
+	file, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportSize))
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	report, err := cc.ImportPayments(r.Context(), &paymentpb.ImportRequest{
+		Format: r.URL.Query().Get("format"),
+		File:   file,
+	})
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, report)
+}
+
+type InstallmentRequest struct {
+	// number of installments, from 2 to 48
+	Count uint32 `json:"count"`
+	// days between installments, 0 - monthly
+	IntervalDays uint32 `json:"interval_days"`
+	// fee of the plan paid by the customer with the installments
+	Fee uint64 `json:"fee"`
+}
+
+// createInstallmentPlan godoc
+// @Summary Create installment plan
+// @Description Create installment plan: approved authorization is paid to the merchant in full, the customer is charged by schedule
+// @Tags Payment
+// @Accept json
+// @Produce json
+// @Param id path string true "authorization payment id"
+// @Param input body InstallmentRequest true "installment plan info"
+// @Failure 400  {object}  utils.ApiError
+// @Failure 404  {object}  utils.ApiError
+// @Failure 500  {object}  utils.ApiError
+// @Router /payment/installments/{id} [post]
+func CreateInstallmentPlan(w http.ResponseWriter, r *http.Request, cc paymentpb.PaymentServiceClient) error {
+	uuid, err := utils.GetUUID(r)
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	req := &InstallmentRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	plan, err := cc.CreateInstallmentPlan(r.Context(), &paymentpb.InstallmentRequest{
+		PaymentId:    uuid.String(),
+		Count:        req.Count,
+		IntervalDays: req.IntervalDays,
+		Fee:          req.Fee,
+	})
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, plan)
+}
+
+// getInstallmentPlan godoc
+// @Summary Get installment plan
+// @Description Get installment plan with the schedule of installments
+// @Tags Payment
+// @Produce json
+// @Param id path string true "installment plan id"
+// @Failure 400  {object}  utils.ApiError
+// @Failure 404  {object}  utils.ApiError
+// @Failure 500  {object}  utils.ApiError
+// @Router /payment/installments/{id} [get]
+func GetInstallmentPlan(w http.ResponseWriter, r *http.Request, cc paymentpb.PaymentServiceClient) error {
+	uuid, err := utils.GetUUID(r)
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	plan, err := cc.GetInstallmentPlan(r.Context(), &paymentpb.InstallmentPlanRequest{
+		PlanId: uuid.String(),
+	})
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, plan)
+}
+
+// getDelinquentInstallments godoc
+// @Summary Delinquent installments
+// @Description Delinquent and defaulted installments of the merchant or the customer
+// @Tags Payment
+// @Produce json
+// @Param merchant_id query string false "merchant account id"
+// @Param customer_id query string false "customer account id"
+// @Failure 400  {object}  utils.ApiError
+// @Failure 404  {object}  utils.ApiError
+// @Failure 500  {object}  utils.ApiError
+// @Router /payment/delinquent [get]
+func GetDelinquentInstallments(w http.ResponseWriter, r *http.Request, cc paymentpb.PaymentServiceClient) error {
+	query := r.URL.Query()
+	list, err := cc.GetDelinquentInstallments(r.Context(), &paymentpb.DelinquentRequest{
+		Merchant: query.Get("merchant_id"),
+		Customer: query.Get("customer_id"),
+	})
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, list)
+}
+
+// listScheduledPayments godoc
+// @Summary Scheduled payments
+// @Description Future-dated payments of the merchant or the customer with their state
+// @Tags Payment
+// @Produce json
+// @Param merchant_id query string false "merchant account id"
+// @Param customer_id query string false "customer account id"
+// @Failure 400  {object}  utils.ApiError
+// @Failure 404  {object}  utils.ApiError
+// @Failure 500  {object}  utils.ApiError
+// @Router /payment/scheduled [get]
+func ListScheduledPayments(w http.ResponseWriter, r *http.Request, cc paymentpb.PaymentServiceClient) error {
+	query := r.URL.Query()
+	list, err := cc.ListScheduledPayments(r.Context(), &paymentpb.ScheduledListRequest{
+		Merchant: query.Get("merchant_id"),
+		Customer: query.Get("customer_id"),
+	})
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, list)
+}
+
+type ScheduledUpdateRequest struct {
+	// new amount, 0 - unchanged
+	Amount uint64 `json:"amount"`
+	// new execution time, empty - unchanged
+	ExecuteAt *time.Time `json:"execute_at"`
+}
+
+// updateScheduledPayment godoc
+// @Summary Update scheduled payment
+// @Description Update scheduled payment: change amount or execution time until the payment is executed
+// @Tags Payment
+// @Accept json
+// @Produce json
+// @Param id path string true "scheduled payment id"
+// @Param input body ScheduledUpdateRequest true "scheduled payment changes"
+// @Failure 400  {object}  utils.ApiError
+// @Failure 404  {object}  utils.ApiError
+// @Failure 500  {object}  utils.ApiError
+// @Router /payment/scheduled/update/{id} [post]
+func UpdateScheduledPayment(w http.ResponseWriter, r *http.Request, cc paymentpb.PaymentServiceClient) error {
+	uuid, err := utils.GetUUID(r)
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	req := &ScheduledUpdateRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	pbReq := &paymentpb.ScheduledUpdateRequest{
+		PaymentId: uuid.String(),
+		Amount:    req.Amount,
+	}
+	if req.ExecuteAt != nil {
+		pbReq.ExecuteAt = timestamppb.New(*req.ExecuteAt)
+	}
+	scheduled, err := cc.UpdateScheduledPayment(r.Context(), pbReq)
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, scheduled)
+}
+
+// cancelScheduledPayment godoc
+// @Summary Cancel scheduled payment
+// @Description Cancel scheduled payment until it is executed
+// @Tags Payment
+// @Produce json
+// @Param id path string true "scheduled payment id"
+// @Failure 400  {object}  utils.ApiError
+// @Failure 404  {object}  utils.ApiError
+// @Failure 500  {object}  utils.ApiError
+// @Router /payment/scheduled/cancel/{id} [post]
+func CancelScheduledPayment(w http.ResponseWriter, r *http.Request, cc paymentpb.PaymentServiceClient) error {
+	uuid, err := utils.GetUUID(r)
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	scheduled, err := cc.CancelScheduledPayment(r.Context(), &paymentpb.PaidRequest{
+		PaymentId: uuid.String(),
+	})
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, scheduled)
+}
+
+// the stream is closed before the server WriteTimeout,
+// the browser reconnects with Last-Event-ID and resumes
+const eventsStreamTimeout = 9 * time.Second
+
+// reconnection delay sent to the browser
+const eventsRetry = time.Second
+
+// subscribePaymentEvents godoc
+// @Summary Payment events
+// @Description Server-Sent Events feed of payment state changes, resumes after Last-Event-ID header or cursor
+// @Tags Payment
+// @Produce text/event-stream
+// @Param merchant_id query string false "merchant account id"
+// @Param customer_id query string false "customer account id"
+// @Param cursor query int false "resume after this event cursor"
+// @Failure 400  {object}  utils.ApiError
+// @Failure 404  {object}  utils.ApiError
+// @Failure 500  {object}  utils.ApiError
+// @Router /payment/events [get]
+func SubscribePaymentEvents(w http.ResponseWriter, r *http.Request, cc paymentpb.PaymentServiceClient) error {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return utils.WriteJSON(w, http.StatusInternalServerError, utils.ApiError{Error: "streaming unsupported"})
+	}
+
+	query := r.URL.Query()
+	req := &paymentpb.SubscribeRequest{
+		Merchant: query.Get("merchant_id"),
+		Customer: query.Get("customer_id"),
+	}
+	if req.Merchant != "" {
+		if _, err := uuid.Parse(req.Merchant); err != nil {
+			return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+		}
+	}
+	if req.Customer != "" {
+		if _, err := uuid.Parse(req.Customer); err != nil {
+			return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+		}
+	}
+	// reconnecting browsers send the last received event id
+	cursor := query.Get("cursor")
+	if lastEventID := r.Header.Get("Last-Event-ID"); lastEventID != "" {
+		cursor = lastEventID
+	}
+	if cursor != "" {
+		c, err := strconv.ParseUint(cursor, 10, 64)
+		if err != nil {
+			return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+		}
+		req.Cursor = c
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), eventsStreamTimeout)
+	defer cancel()
+
+	stream, err := cc.SubscribePaymentEvents(ctx, req)
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", eventsRetry.Milliseconds())
+	flusher.Flush()
+
+	for {
+		event, err := stream.Recv()
+		if err != nil {
+			// stream timeout or client has gone,
+			// the response is already started
+			return nil
+		}
+		data, err := json.Marshal(event)
+		if err != nil {
+			return nil
+		}
+		if _, err := fmt.Fprintf(w, "id: %d\nevent: payment\ndata: %s\n\n", event.Cursor, data); err != nil {
+			return nil
+		}
+		flusher.Flush()
+	}
+}
+
+// exportStatement godoc
+// @Summary Export account statement
+// @Description Export account statement for the period as ISO 20022 camt.053, CSV or OFX file with opening and closing balances
+// @Tags Account
+// @Produce octet-stream
+// @Param id path string true "account id"
+// @Param format query string true "camt.053, csv or ofx"
+// @Param from query string false "start of the period, RFC 3339 or YYYY-MM-DD"
+// @Param to query string false "end of the period, RFC 3339 or YYYY-MM-DD, now by default"
+// @Failure 400  {object}  utils.ApiError
+// @Failure 404  {object}  utils.ApiError
+// @Failure 500  {object}  utils.ApiError
+// @Router /account/statement/{id}/export [get]
+func ExportStatement(w http.ResponseWriter, r *http.Request, cc paymentpb.PaymentServiceClient) error {
+	uuid, err := utils.GetUUID(r)
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	query := r.URL.Query()
+	from, err := utils.ParsePeriod(query.Get("from"))
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+	to, err := utils.ParsePeriod(query.Get("to"))
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	file, err := cc.ExportStatement(r.Context(), &paymentpb.ExportRequest{
+		AccountId: uuid.String(),
+		From:      from,
+		To:        to,
+		Format:    query.Get("format"),
+	})
+	if err != nil {
+		return utils.WriteJSON(w, http.StatusBadRequest, utils.ApiError{Error: err.Error()})
+	}
+
+	w.Header().Set("Content-Type", file.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", file.FileName))
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(file.Data)
+	return err
+}
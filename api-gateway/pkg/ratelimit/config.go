@@ -0,0 +1,113 @@
+package ratelimit
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit of the requests in the window, the bucket of the caller holds
+// Requests tokens and refills them evenly over the window
+type Limit struct {
+	Requests int
+	Window   time.Duration
+}
+
+func (l Limit) String() string {
+	return fmt.Sprintf("%d/%s", l.Requests, l.Window)
+}
+
+// Limits of the routes by "METHOD /path/template" of the router,
+// other routes have the default limit; zero requests is no limit
+type Config struct {
+	Default Limit
+	Routes  map[string]Limit
+}
+
+// Limits of the gateway, credentials are guessed on sign-in and token routes,
+// mail is sent on sign-up and password reset
+func DefaultConfig() *Config {
+	return &Config{
+		Default: Limit{Requests: 120, Window: time.Minute},
+		Routes: map[string]Limit{
+			"POST /account":             {Requests: 5, Window: time.Minute},
+			"POST /account/sign-in":     {Requests: 10, Window: time.Minute},
+			"POST /account/sign-in/mfa": {Requests: 10, Window: time.Minute},
+			"POST /account/refresh":     {Requests: 30, Window: time.Minute},
+			"POST /oauth/token":         {Requests: 20, Window: time.Minute},
+			// reset links are mailed
+			"POST /account/password/forgot": {Requests: 5, Window: time.Minute},
+		},
+	}
+}
+
+// ConfigFromEnv reads RATE_LIMIT_DEFAULT as requests/window and
+// RATE_LIMIT_ROUTES as comma separated "METHOD /path=requests/window"
+// pairs over the default config
+func ConfigFromEnv() (*Config, error) {
+	config := DefaultConfig()
+	if value := os.Getenv("RATE_LIMIT_DEFAULT"); value != "" {
+		limit, err := ParseLimit(value)
+		if err != nil {
+			return nil, err
+		}
+		config.Default = limit
+	}
+	routes, err := ParseRoutes(os.Getenv("RATE_LIMIT_ROUTES"))
+	if err != nil {
+		return nil, err
+	}
+	for route, limit := range routes {
+		config.Routes[route] = limit
+	}
+	return config, nil
+}
+
+// ParseLimit reads limit as requests/window, e.g. 100/1m
+func ParseLimit(value string) (Limit, error) {
+	requests, window, ok := strings.Cut(strings.TrimSpace(value), "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid rate limit %q", value)
+	}
+	n, err := strconv.Atoi(requests)
+	if err != nil || n < 0 {
+		return Limit{}, fmt.Errorf("invalid requests of rate limit %q", value)
+	}
+	d, err := time.ParseDuration(window)
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("invalid window of rate limit %q", value)
+	}
+	return Limit{Requests: n, Window: d}, nil
+}
+
+// ParseRoutes reads limits of the routes as comma separated route=limit pairs
+func ParseRoutes(value string) (map[string]Limit, error) {
+	routes := map[string]Limit{}
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		route, limit, ok := strings.Cut(pair, "=")
+		method, path, hasPath := strings.Cut(strings.TrimSpace(route), " ")
+		if !ok || !hasPath || method == "" || !strings.HasPrefix(path, "/") {
+			return nil, fmt.Errorf("invalid route limit %q", pair)
+		}
+		l, err := ParseLimit(limit)
+		if err != nil {
+			return nil, err
+		}
+		routes[strings.ToUpper(method)+" "+path] = l
+	}
+	return routes, nil
+}
+
+// limit of the route
+func (c *Config) limit(route string) Limit {
+	if limit, ok := c.Routes[route]; ok {
+		return limit
+	}
+	return c.Default
+}
@@ -0,0 +1,37 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ParseLimit(t *testing.T) {
+	t.Parallel()
+
+	limit, err := ParseLimit(" 10/1m ")
+	require.NoError(t, err)
+	require.Equal(t, Limit{Requests: 10, Window: time.Minute}, limit)
+
+	for _, value := range []string{"", "10", "-1/1m", "ten/1m", "10/0s", "10/-1m", "10/minute"} {
+		_, err := ParseLimit(value)
+		require.Error(t, err, value)
+	}
+}
+
+func Test_ParseRoutes(t *testing.T) {
+	t.Parallel()
+
+	routes, err := ParseRoutes("post /account/sign-in=5/1m, GET /account/{id}=100/1m,")
+	require.NoError(t, err)
+	require.Equal(t, map[string]Limit{
+		"POST /account/sign-in": {Requests: 5, Window: time.Minute},
+		"GET /account/{id}":     {Requests: 100, Window: time.Minute},
+	}, routes)
+
+	for _, value := range []string{"POST /account", "/account=5/1m", "POST account=5/1m", "POST /account=5"} {
+		_, err := ParseRoutes(value)
+		require.Error(t, err, value)
+	}
+}
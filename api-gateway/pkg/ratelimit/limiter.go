@@ -0,0 +1,189 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redis is not asked again for the interval after it failed
+const redisRetryInterval = 5 * time.Second
+
+// Result of taking a token from the bucket of the caller
+type Result struct {
+	Allowed   bool
+	Limit     Limit
+	Remaining int
+	// until the next token, when denied
+	RetryAfter time.Duration
+	// until the bucket is full
+	Reset time.Duration
+}
+
+// Token buckets of the callers, stored in redis and shared by the gateways;
+// buckets are kept in memory while redis is unavailable
+type Limiter struct {
+	config *Config
+	redis  *redis.Client
+	memory *memoryStore
+
+	mu         sync.Mutex
+	redisUntil time.Time
+}
+
+// NewLimiter with the buckets in redis, in memory only without a client
+func NewLimiter(config *Config, client *redis.Client) *Limiter {
+	return &Limiter{
+		config: config,
+		redis:  client,
+		memory: newMemoryStore(),
+	}
+}
+
+// Take a token of the key for the request of the route
+func (l *Limiter) Take(ctx context.Context, route, key string) Result {
+	limit := l.config.limit(route)
+	if limit.Requests == 0 {
+		return Result{Allowed: true, Limit: limit}
+	}
+	key = "ratelimit:" + route + ":" + key
+	if l.redisAvailable() {
+		tokens, allowed, err := l.takeRedis(ctx, key, limit)
+		if err == nil {
+			return result(allowed, tokens, limit)
+		}
+		// canceled requests say nothing of redis
+		if ctx.Err() == nil {
+			log.Printf("rate limit: redis is unavailable, limits are kept in memory: %v", err)
+			l.redisFailed()
+		}
+	}
+	tokens, allowed := l.memory.take(key, limit, time.Now())
+	return result(allowed, tokens, limit)
+}
+
+func (l *Limiter) redisAvailable() bool {
+	if l.redis == nil {
+		return false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return time.Now().After(l.redisUntil)
+}
+
+func (l *Limiter) redisFailed() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.redisUntil = time.Now().Add(redisRetryInterval)
+}
+
+// token bucket of the key refilled by the time of redis, so that
+// the clocks of the gateways don't matter; the bucket expires when full
+var takeScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+	tokens = capacity
+else
+	tokens = math.min(capacity, tokens + math.max(0, now - ts) * capacity / window)
+end
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], window)
+return {allowed, tostring(tokens)}
+`)
+
+func (l *Limiter) takeRedis(ctx context.Context, key string, limit Limit) (float64, bool, error) {
+	values, err := takeScript.Run(ctx, l.redis, []string{key}, limit.Requests, limit.Window.Milliseconds()).Slice()
+	if err != nil {
+		return 0, false, err
+	}
+	allowed, _ := values[0].(int64)
+	tokens, _ := values[1].(string)
+	var remaining float64
+	if _, err := fmt.Sscan(tokens, &remaining); err != nil {
+		return 0, false, err
+	}
+	return remaining, allowed == 1, nil
+}
+
+// result of the bucket with the tokens left
+func result(allowed bool, tokens float64, limit Limit) Result {
+	perToken := float64(limit.Window) / float64(limit.Requests)
+	res := Result{
+		Allowed:   allowed,
+		Limit:     limit,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     time.Duration((float64(limit.Requests) - tokens) * perToken),
+	}
+	if !allowed {
+		res.RetryAfter = time.Duration((1 - tokens) * perToken)
+	}
+	return res
+}
+
+// buckets of this gateway, full buckets are swept once in a minute
+type memoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	window  time.Duration
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{buckets: map[string]*bucket{}}
+}
+
+func (s *memoryStore) take(key string, limit Limit, now time.Time) (float64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now.Sub(s.lastSweep) >= time.Minute {
+		s.sweep(now)
+	}
+
+	capacity := float64(limit.Requests)
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, updated: now}
+		s.buckets[key] = b
+	}
+	if elapsed := now.Sub(b.updated); elapsed > 0 {
+		b.tokens = math.Min(capacity, b.tokens+float64(elapsed)*capacity/float64(limit.Window))
+	}
+	b.updated = now
+	b.window = limit.Window
+	if b.tokens < 1 {
+		return b.tokens, false
+	}
+	b.tokens--
+	return b.tokens, true
+}
+
+// buckets refilled since their last request are removed
+func (s *memoryStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if now.Sub(b.updated) >= b.window {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastSweep = now
+}
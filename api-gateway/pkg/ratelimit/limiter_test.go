@@ -0,0 +1,52 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_MemoryStore(t *testing.T) {
+	t.Parallel()
+
+	store := newMemoryStore()
+	limit := Limit{Requests: 3, Window: 3 * time.Second}
+	now := time.Now()
+
+	// burst of the whole bucket
+	for i := 2; i >= 0; i-- {
+		tokens, allowed := store.take("key", limit, now)
+		require.True(t, allowed)
+		require.Equal(t, float64(i), tokens)
+	}
+	_, allowed := store.take("key", limit, now)
+	require.False(t, allowed)
+	// buckets of other keys are full
+	_, allowed = store.take("other", limit, now)
+	require.True(t, allowed)
+
+	// one token in a second
+	_, allowed = store.take("key", limit, now.Add(500*time.Millisecond))
+	require.False(t, allowed)
+	_, allowed = store.take("key", limit, now.Add(time.Second))
+	require.True(t, allowed)
+
+	// refilled up to the capacity
+	tokens, allowed := store.take("key", limit, now.Add(time.Hour))
+	require.True(t, allowed)
+	require.Equal(t, float64(2), tokens)
+}
+
+func Test_Result(t *testing.T) {
+	t.Parallel()
+
+	limit := Limit{Requests: 10, Window: 10 * time.Second}
+	res := result(true, 4.5, limit)
+	require.Equal(t, 4, res.Remaining)
+	require.Equal(t, 5500*time.Millisecond, res.Reset)
+
+	res = result(false, 0.25, limit)
+	require.Equal(t, 0, res.Remaining)
+	require.Equal(t, 750*time.Millisecond, res.RetryAfter)
+}
@@ -0,0 +1,58 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Edbeer/api-gateway/pkg/utils"
+	"github.com/gorilla/mux"
+)
+
+// Key of the caller of the request
+type KeyFunc func(r *http.Request) string
+
+// Middleware limits the requests of the callers by the route,
+// denied requests get 429 with Retry-After; every response
+// has the RateLimit headers of the bucket
+func (l *Limiter) Middleware(key KeyFunc) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			res := l.Take(r.Context(), routeName(r), key(r))
+			if res.Limit.Requests == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			header := w.Header()
+			header.Set("RateLimit-Limit", strconv.Itoa(res.Limit.Requests))
+			header.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			header.Set("RateLimit-Reset", strconv.Itoa(seconds(res.Reset)))
+			header.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", res.Limit.Requests, seconds(res.Limit.Window)))
+			if !res.Allowed {
+				header.Set("Retry-After", strconv.Itoa(seconds(res.RetryAfter)))
+				utils.WriteJSON(w, http.StatusTooManyRequests, utils.ApiError{Error: "rate limit exceeded"})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// route of the request as "METHOD /path/template"
+func routeName(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			return r.Method + " " + template
+		}
+	}
+	return r.Method + " *"
+}
+
+// whole seconds, at least one
+func seconds(d time.Duration) int {
+	return int(math.Max(1, math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,56 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Middleware(t *testing.T) {
+	t.Parallel()
+
+	config := &Config{
+		Default: Limit{},
+		Routes: map[string]Limit{
+			"POST /account/sign-in": {Requests: 2, Window: time.Minute},
+		},
+	}
+	router := mux.NewRouter()
+	router.Use(NewLimiter(config, nil).Middleware(func(r *http.Request) string {
+		return r.Header.Get("X-Caller")
+	}))
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	router.HandleFunc("/account/sign-in", ok).Methods(http.MethodPost)
+	router.HandleFunc("/account", ok).Methods(http.MethodGet)
+
+	send := func(method, path, caller string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, nil)
+		r.Header.Set("X-Caller", caller)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	w := send(http.MethodPost, "/account/sign-in", "first")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+	require.Equal(t, "1", w.Header().Get("RateLimit-Remaining"))
+	require.Equal(t, "30", w.Header().Get("RateLimit-Reset"))
+	require.Equal(t, "2;w=60", w.Header().Get("RateLimit-Policy"))
+
+	require.Equal(t, http.StatusOK, send(http.MethodPost, "/account/sign-in", "first").Code)
+	w = send(http.MethodPost, "/account/sign-in", "first")
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	require.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+	require.Equal(t, "30", w.Header().Get("Retry-After"))
+
+	// other callers and unlimited routes
+	require.Equal(t, http.StatusOK, send(http.MethodPost, "/account/sign-in", "second").Code)
+	w = send(http.MethodGet, "/account", "first")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Empty(t, w.Header().Get("RateLimit-Limit"))
+}
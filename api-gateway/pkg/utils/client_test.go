@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ClientIP(t *testing.T) {
+	require.NoError(t, SetTrustedProxies("10.0.0.1, 172.16.0.0/12"))
+	defer SetTrustedProxies("")
+
+	tests := []struct {
+		name      string
+		remote    string
+		forwarded string
+		ip        string
+	}{
+		{"Direct", "203.0.113.7:5000", "", "203.0.113.7"},
+		{"Direct with forwarded", "203.0.113.7:5000", "198.51.100.1", "203.0.113.7"},
+		{"Proxy", "10.0.0.1:5000", "198.51.100.1", "198.51.100.1"},
+		{"Proxy without forwarded", "10.0.0.1:5000", "", "10.0.0.1"},
+		{"Client sets forwarded", "10.0.0.1:5000", "1.1.1.1, 198.51.100.1", "198.51.100.1"},
+		{"Chain of proxies", "10.0.0.1:5000", "198.51.100.1, 172.16.3.4", "198.51.100.1"},
+		{"Invalid forwarded", "10.0.0.1:5000", "client", "10.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remote
+			if tt.forwarded != "" {
+				r.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			require.Equal(t, tt.ip, ClientIP(r))
+		})
+	}
+
+	require.Error(t, SetTrustedProxies("10.0.0.1/33"))
+	require.Error(t, SetTrustedProxies("proxy"))
+}
@@ -1,22 +1,158 @@
 package utils
 
 import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"os"
+	"sync"
+	"time"
 
+	authpb "github.com/Edbeer/payment-proto/auth-grpc/proto"
 	"github.com/golang-jwt/jwt/v4"
 )
 
-// Validate JWT
-func ValidateJWT(tokenString string) (*jwt.Token, error) {
-	secret := os.Getenv("JWT_SECRET")
-	return jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		// Don't forget to validate the alg is what you expect:
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
+const (
+	// unknown kids refetch the keys at most once in the interval
+	jwksRefetchInterval = time.Minute
+	// published keys are refetched after
+	jwksMaxAge = 10 * time.Minute
+)
+
+// Claims of the access token
+type AccessClaims struct {
+	AccountID string `json:"id"`
+	Card      string `json:"card"`
+	Role      string `json:"role"`
+	// OAuth2 client of the token and its space separated scopes
+	ClientID string `json:"client_id,omitempty"`
+	Scope    string `json:"scope,omitempty"`
+	// none in the tokens issued before the email verification
+	EmailVerified *bool `json:"email_verified,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Public keys of the auth service by kid, keys of the rotation
+// are fetched when a token signed with an unknown kid arrives
+type JWKS struct {
+	client authpb.AuthServiceClient
+
+	mu        sync.RWMutex
+	jwks      *authpb.JWKS
+	keys      map[string]ed25519.PublicKey
+	fetchedAt time.Time
+}
+
+func NewJWKS(client authpb.AuthServiceClient) *JWKS {
+	return &JWKS{
+		client: client,
+		keys:   map[string]ed25519.PublicKey{},
+	}
+}
+
+// Get public keys, fetched again when they are old
+func (s *JWKS) Keys(ctx context.Context) (*authpb.JWKS, error) {
+	s.mu.RLock()
+	jwks, fetchedAt := s.jwks, s.fetchedAt
+	s.mu.RUnlock()
+	if jwks != nil && time.Since(fetchedAt) < jwksMaxAge {
+		return jwks, nil
+	}
+	if err := s.fetch(ctx); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.jwks, nil
+}
+
+// Get public key of the kid
+func (s *JWKS) Key(ctx context.Context, kid string) (ed25519.PublicKey, error) {
+	s.mu.RLock()
+	key, ok := s.keys[kid]
+	fetchedAt := s.fetchedAt
+	s.mu.RUnlock()
+	if ok {
+		return key, nil
+	}
+	if time.Since(fetchedAt) < jwksRefetchInterval {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+	if err := s.fetch(ctx); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if key, ok := s.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key %q", kid)
+}
+
+func (s *JWKS) fetch(ctx context.Context) error {
+	jwks, err := s.client.GetJWKS(ctx, &authpb.JWKSRequest{})
+	if err != nil {
+		return err
+	}
+	keys := make(map[string]ed25519.PublicKey, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk.Kty != "OKP" || jwk.Crv != "Ed25519" {
+			continue
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			continue
 		}
+		keys[jwk.Kid] = ed25519.PublicKey(x)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jwks = jwks
+	s.keys = keys
+	s.fetchedAt = time.Now()
+	return nil
+}
 
-		// hmacSampleSecret is a []byte containing your secret, e.g. []byte("my_secret_key")
-		return []byte(secret), nil
-	})
-}
\ No newline at end of file
+// issuer and audience of the access tokens, same as in the auth service
+func JWTIssuer() string {
+	if issuer := os.Getenv("JWT_ISSUER"); issuer != "" {
+		return issuer
+	}
+	return "payment-auth"
+}
+
+func JWTAudience() string {
+	if audience := os.Getenv("JWT_AUDIENCE"); audience != "" {
+		return audience
+	}
+	return "payment-api"
+}
+
+// Validate JWT: EdDSA signature of a known key, not expired,
+// issued by the auth service for the api audience
+func ValidateJWT(ctx context.Context, tokenString string, jwks *JWKS) (*AccessClaims, error) {
+	claims := &AccessClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			return nil, errors.New("token has no kid")
+		}
+		return jwks.Key(ctx, kid)
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodEdDSA.Alg()}))
+	if err != nil {
+		return nil, err
+	}
+	// exp, iat and nbf are checked by the parser
+	if claims.ExpiresAt == nil {
+		return nil, errors.New("token has no expiry")
+	}
+	if !claims.VerifyIssuer(JWTIssuer(), true) {
+		return nil, errors.New("invalid token issuer")
+	}
+	if !claims.VerifyAudience(JWTAudience(), true) {
+		return nil, errors.New("invalid token audience")
+	}
+	return claims, nil
+}
@@ -0,0 +1,23 @@
+package utils
+
+import (
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Parse period boundary as RFC 3339 time or date, empty value is no boundary
+func ParsePeriod(value string) (*timestamppb.Timestamp, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t, err = time.Parse("2006-01-02", value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date %q", value)
+		}
+	}
+	return timestamppb.New(t), nil
+}
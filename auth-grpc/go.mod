@@ -12,6 +12,7 @@ require (
 	github.com/lib/pq v1.10.7
 	github.com/redis/go-redis/v9 v9.0.2
 	github.com/stretchr/testify v1.8.1
+	golang.org/x/crypto v0.5.0
 	google.golang.org/grpc v1.52.3
 	google.golang.org/protobuf v1.28.1
 )
@@ -1,15 +1,22 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net"
+	"os"
+	"time"
 
 	"github.com/Edbeer/auth-grpc/pkg/db/psql"
 	red "github.com/Edbeer/auth-grpc/pkg/db/redis"
+	"github.com/Edbeer/auth-grpc/pkg/events"
+	"github.com/Edbeer/auth-grpc/pkg/mail"
+	"github.com/Edbeer/auth-grpc/pkg/utils"
 	authpb "github.com/Edbeer/payment-proto/auth-grpc/proto"
 	"github.com/Edbeer/auth-grpc/service"
 	"github.com/Edbeer/auth-grpc/storage/psql"
 	"github.com/Edbeer/auth-grpc/storage/redis"
+	"github.com/Edbeer/auth-grpc/types"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 )
@@ -27,12 +34,44 @@ func main() {
 	defer redisClient.Close()
 	log.Println("init redis")
 
+	// signing keys of the access tokens
+	utils.DefaultKeyRing()
+	log.Println("init jwt keys")
+
 	storage := storage.NewPostgresStorage(db)
 	redis := redisrepo.NewRedisStorage(redisClient)
 
-	srv := service.NewAuthService(storage, redis)
+	// mails of the accounts, written locally without an SMTP relay
+	var mailer service.Mailer = mail.NewLocalMailer(os.Getenv("MAIL_DIR"), os.Getenv("MAIL_FROM"))
+	if addr := os.Getenv("SMTP_ADDR"); addr != "" {
+		mailer = mail.NewSMTPMailer(addr, os.Getenv("MAIL_FROM"), os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"))
+	}
+
+	srv := service.NewAuthService(storage, redis, events.NewLogEmitter(), mailer)
+	// throttling of the sign-ins
+	loginPolicy, err := service.LoginPolicyFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	srv.SetLoginPolicy(loginPolicy)
+	// release expired holds
+	go srv.RunHoldExpiry(context.Background(), time.Minute)
 	
-	server := grpc.NewServer(grpc.MaxConcurrentStreams(1000))
+	// secrets of the services calling the auth service
+	services, err := utils.ParseServiceSecrets(os.Getenv("SERVICE_SECRETS"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if _, ok := services[types.ServicePayment]; !ok {
+		log.Fatal("SERVICE_SECRETS has no secret of the payment service")
+	}
+
+	// roles and permissions of the callers
+	server := grpc.NewServer(
+		grpc.MaxConcurrentStreams(1000),
+		grpc.UnaryInterceptor(service.UnaryInterceptor(services)),
+		grpc.StreamInterceptor(service.StreamInterceptor(services)),
+	)
 
 	authpb.RegisterAuthServiceServer(server, srv)
 
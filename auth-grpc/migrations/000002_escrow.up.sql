@@ -0,0 +1,2 @@
+-- captured money held in escrow until released to the account
+ALTER TABLE account ADD COLUMN IF NOT EXISTS escrow_money BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,8 @@
+ALTER TABLE account ADD COLUMN IF NOT EXISTS statement text[];
+
+UPDATE account a SET statement = (
+	SELECT array_agg(e.payment_id::text ORDER BY e.entry_id)
+	FROM statement_entry e WHERE e.account_id = a.id
+);
+
+DROP TABLE IF EXISTS statement_entry;
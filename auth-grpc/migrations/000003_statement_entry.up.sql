@@ -0,0 +1,27 @@
+-- statement entry per payment of the account, replaces account.statement array
+CREATE TABLE IF NOT EXISTS statement_entry
+(
+	entry_id BIGSERIAL PRIMARY KEY,
+	account_id UUID NOT NULL REFERENCES account (id) ON DELETE CASCADE,
+	payment_id UUID NOT NULL,
+	-- credit, debit or none
+	direction VARCHAR(6) NOT NULL,
+	amount BIGINT NOT NULL DEFAULT 0,
+	-- account balance after the entry,
+	-- null - entry migrated from the statement array
+	balance BIGINT,
+	created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS statement_entry_account_idx ON statement_entry (account_id, entry_id);
+CREATE INDEX IF NOT EXISTS statement_entry_created_at_idx ON statement_entry (account_id, created_at);
+
+-- payments of the array have no amounts and dates, the payments are stored
+-- by the payment service: amounts, directions and dates are filled
+-- by scripts/statement_backfill.sql, the balances stay unknown
+INSERT INTO statement_entry (account_id, payment_id, direction, amount, balance, created_at)
+	SELECT a.id, s.payment_id::uuid, 'none', 0, NULL, a.created_at
+	FROM account a, unnest(a.statement) WITH ORDINALITY AS s(payment_id, position)
+	ORDER BY a.id, s.position;
+
+ALTER TABLE account DROP COLUMN IF EXISTS statement;
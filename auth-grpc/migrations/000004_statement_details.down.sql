@@ -0,0 +1,6 @@
+ALTER TABLE statement_entry
+	DROP COLUMN IF EXISTS counterparty_id,
+	DROP COLUMN IF EXISTS counterparty_name,
+	DROP COLUMN IF EXISTS operation,
+	DROP COLUMN IF EXISTS status,
+	DROP COLUMN IF EXISTS currency;
@@ -0,0 +1,8 @@
+-- payment details of the statement entry recorded by CreateStatement,
+-- entries created before are left without them
+ALTER TABLE statement_entry
+	ADD COLUMN IF NOT EXISTS counterparty_id UUID,
+	ADD COLUMN IF NOT EXISTS counterparty_name VARCHAR(101) NOT NULL DEFAULT '',
+	ADD COLUMN IF NOT EXISTS operation VARCHAR(50) NOT NULL DEFAULT '',
+	ADD COLUMN IF NOT EXISTS status VARCHAR(50) NOT NULL DEFAULT '',
+	ADD COLUMN IF NOT EXISTS currency VARCHAR(50) NOT NULL DEFAULT '';
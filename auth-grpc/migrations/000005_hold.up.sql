@@ -0,0 +1,20 @@
+-- money of the account reserved until captured, released or expired,
+-- blocked_money of the account is the sum of its active holds;
+-- money blocked before the holds is left on the accounts as is
+CREATE TABLE IF NOT EXISTS hold
+(
+	hold_id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+	account_id UUID NOT NULL REFERENCES account (id) ON DELETE CASCADE,
+	amount BIGINT NOT NULL,
+	captured_amount BIGINT NOT NULL DEFAULT 0,
+	-- active, captured, released or expired
+	state VARCHAR(8) NOT NULL,
+	-- what the money is held for, e.g. the payment id
+	reference VARCHAR(100) NOT NULL DEFAULT '',
+	expires_at TIMESTAMP NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT now(),
+	updated_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS hold_account_idx ON hold (account_id, created_at);
+CREATE INDEX IF NOT EXISTS hold_expires_at_idx ON hold (expires_at) WHERE state = 'active';
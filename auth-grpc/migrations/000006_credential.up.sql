@@ -0,0 +1,10 @@
+-- sign-in credentials of the account, password is stored as a bcrypt hash;
+-- accounts created before credentials can't sign in until they are set
+CREATE TABLE IF NOT EXISTS credential
+(
+	account_id UUID PRIMARY KEY REFERENCES account (id) ON DELETE CASCADE,
+	email CITEXT NOT NULL UNIQUE,
+	password_hash VARCHAR(100) NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT now(),
+	updated_at TIMESTAMP NOT NULL DEFAULT now()
+);
@@ -0,0 +1,24 @@
+-- TOTP secret of the account, two-factor authentication is enabled
+-- after the first code is verified
+CREATE TABLE IF NOT EXISTS totp
+(
+	account_id UUID PRIMARY KEY REFERENCES account (id) ON DELETE CASCADE,
+	secret VARCHAR(64) NOT NULL,
+	enabled BOOLEAN NOT NULL DEFAULT false,
+	-- time step of the last accepted code, codes are accepted once
+	last_step BIGINT NOT NULL DEFAULT 0,
+	created_at TIMESTAMP NOT NULL DEFAULT now(),
+	updated_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+-- one-time codes replacing TOTP, stored as sha256 hashes
+CREATE TABLE IF NOT EXISTS recovery_code
+(
+	code_id BIGSERIAL PRIMARY KEY,
+	account_id UUID NOT NULL REFERENCES account (id) ON DELETE CASCADE,
+	code_hash VARCHAR(64) NOT NULL,
+	used_at TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS recovery_code_account_idx ON recovery_code (account_id, code_hash);
@@ -0,0 +1,10 @@
+-- role of the account, accounts without a row are customers;
+-- the first admin is promoted here, other roles are set by admins
+CREATE TABLE IF NOT EXISTS account_role
+(
+	account_id UUID PRIMARY KEY REFERENCES account (id) ON DELETE CASCADE,
+	role VARCHAR(16) NOT NULL DEFAULT 'customer'
+		CHECK (role IN ('customer', 'merchant', 'support', 'admin')),
+	created_at TIMESTAMP NOT NULL DEFAULT now(),
+	updated_at TIMESTAMP NOT NULL DEFAULT now()
+);
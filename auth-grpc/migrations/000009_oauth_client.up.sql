@@ -0,0 +1,12 @@
+-- OAuth2 clients of the merchant servers, the secret is stored as a sha256 hash
+CREATE TABLE IF NOT EXISTS oauth_client
+(
+	client_id VARCHAR(64) PRIMARY KEY,
+	account_id UUID NOT NULL REFERENCES account (id) ON DELETE CASCADE,
+	name VARCHAR(100) NOT NULL DEFAULT '',
+	secret_hash VARCHAR(64) NOT NULL,
+	scopes TEXT[] NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS oauth_client_account_idx ON oauth_client (account_id);
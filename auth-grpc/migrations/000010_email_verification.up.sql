@@ -0,0 +1,11 @@
+-- verified emails of the accounts, payments are made by verified accounts;
+-- accounts with credentials before the verification are trusted
+CREATE TABLE IF NOT EXISTS email_verification
+(
+	account_id UUID PRIMARY KEY REFERENCES account (id) ON DELETE CASCADE,
+	verified_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+INSERT INTO email_verification (account_id)
+	SELECT account_id FROM credential
+	ON CONFLICT DO NOTHING;
@@ -0,0 +1,26 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/Edbeer/auth-grpc/types"
+)
+
+// LogEmitter writes security events to the service log,
+// stand-in for the audit pipeline in local environment
+type LogEmitter struct{}
+
+func NewLogEmitter() *LogEmitter {
+	return &LogEmitter{}
+}
+
+func (e *LogEmitter) Emit(ctx context.Context, event *types.SecurityEvent) error {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	log.Printf("security event: %s", b)
+	return nil
+}
@@ -0,0 +1,87 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Edbeer/auth-grpc/types"
+)
+
+const defaultFrom = "no-reply@payment.local"
+
+// SMTPMailer sends mails with the relay, with PLAIN auth if it has a username
+type SMTPMailer struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+func NewSMTPMailer(addr, from, username, password string) *SMTPMailer {
+	m := &SMTPMailer{addr: addr, from: sender(from)}
+	if username != "" {
+		host, _, _ := net.SplitHostPort(addr)
+		m.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return m
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, mail *types.Mail) error {
+	return smtp.SendMail(m.addr, m.auth, m.from, []string{header(mail.To)}, message(m.from, mail))
+}
+
+// LocalMailer writes mails as .eml files to the directory, or to the
+// service log without it, stand-in for the SMTP relay in local environment
+type LocalMailer struct {
+	dir  string
+	from string
+}
+
+func NewLocalMailer(dir, from string) *LocalMailer {
+	return &LocalMailer{dir: dir, from: sender(from)}
+}
+
+func (m *LocalMailer) Send(ctx context.Context, mail *types.Mail) error {
+	msg := message(m.from, mail)
+	if m.dir == "" {
+		log.Printf("mail to %s:\n%s", header(mail.To), msg)
+		return nil
+	}
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), strings.NewReplacer("@", "_at_", "/", "_").Replace(header(mail.To)))
+	return os.WriteFile(filepath.Join(m.dir, name), msg, 0o600)
+}
+
+func sender(from string) string {
+	if from == "" {
+		return defaultFrom
+	}
+	return from
+}
+
+// plain text message with the headers
+func message(from string, mail *types.Mail) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", header(from))
+	fmt.Fprintf(&b, "To: %s\r\n", header(mail.To))
+	fmt.Fprintf(&b, "Subject: %s\r\n", header(mail.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(mail.Body, "\n", "\r\n"))
+	return b.Bytes()
+}
+
+// header value without line breaks, so that no headers are injected
+func header(value string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(value)
+}
@@ -1,24 +1,241 @@
 package utils
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/Edbeer/auth-grpc/types"
+	authpb "github.com/Edbeer/payment-proto/auth-grpc/proto"
 	"github.com/golang-jwt/jwt/v4"
 )
 
-// Create JWT
-func CreateJWT(account *types.Account) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"id":        account.ID.String(),
-		"card":      account.CardNumber,
-		"expire_at": 15000,
-	})
+// lifetime of the access token, refresh tokens renew it
+const accessTokenTTL = 15 * time.Minute
+
+// Claims of the access token
+type AccessClaims struct {
+	AccountID string `json:"id"`
+	Card      string `json:"card"`
+	// role of the account, permissions of the role are checked by the services
+	Role string `json:"role"`
+	// payments are made by accounts with a verified email
+	EmailVerified bool `json:"email_verified"`
+	// OAuth2 client of the token and its space separated scopes
+	ClientID string `json:"client_id,omitempty"`
+	Scope    string `json:"scope,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Ed25519 key of the ring
+type SigningKey struct {
+	ID         string
+	PrivateKey ed25519.PrivateKey
+}
+
+// Key ring of the access tokens: the current key signs, all keys are
+// published in JWKS. Rotation: add the new key and let validators fetch it,
+// make it current, remove the old key after the access token lifetime
+type KeyRing struct {
+	keys    []*SigningKey
+	current *SigningKey
+}
+
+func NewKeyRing(keys []*SigningKey, currentID string) (*KeyRing, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("key ring has no keys")
+	}
+	ring := &KeyRing{keys: keys}
+	for _, key := range keys {
+		if key.ID == currentID {
+			ring.current = key
+		}
+	}
+	if ring.current == nil {
+		return nil, fmt.Errorf("current key %q is not in the key ring", currentID)
+	}
+	return ring, nil
+}
+
+// Load key ring from PKCS #8 PEM files <kid>.pem of JWT_KEYS_DIR,
+// JWT_KEY_ID is the current key, the last kid by name by default
+func LoadKeyRing() (*KeyRing, error) {
+	dir := os.Getenv("JWT_KEYS_DIR")
+	if dir == "" {
+		return nil, errors.New("JWT_KEYS_DIR is not set")
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	keys := make([]*SigningKey, 0, len(paths))
+	for _, path := range paths {
+		key, err := readSigningKey(path)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no keys in %s", dir)
+	}
+	currentID := os.Getenv("JWT_KEY_ID")
+	if currentID == "" {
+		currentID = keys[len(keys)-1].ID
+	}
+	return NewKeyRing(keys, currentID)
+}
 
-	tokenString, err := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+func readSigningKey(path string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not a PEM file", path)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an Ed25519 key", path)
 	}
+	return &SigningKey{
+		ID:         strings.TrimSuffix(filepath.Base(path), ".pem"),
+		PrivateKey: privateKey,
+	}, nil
+}
 
-	return tokenString, nil
-}
\ No newline at end of file
+// Sign the claims with the current key
+func (k *KeyRing) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = k.current.ID
+	return token.SignedString(k.current.PrivateKey)
+}
+
+// Parse the token signed with a key of the ring
+func (k *KeyRing) Parse(tokenString string, claims jwt.Claims) error {
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		for _, key := range k.keys {
+			if key.ID == kid {
+				return key.PrivateKey.Public(), nil
+			}
+		}
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodEdDSA.Alg()}))
+	return err
+}
+
+// Public keys of the ring
+func (k *KeyRing) JWKS() *authpb.JWKS {
+	jwks := &authpb.JWKS{Keys: make([]*authpb.JWK, 0, len(k.keys))}
+	for _, key := range k.keys {
+		jwks.Keys = append(jwks.Keys, &authpb.JWK{
+			Kid: key.ID,
+			Kty: "OKP",
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(key.PrivateKey.Public().(ed25519.PublicKey)),
+			Alg: jwt.SigningMethodEdDSA.Alg(),
+			Use: "sig",
+		})
+	}
+	return jwks
+}
+
+var (
+	keyRing     *KeyRing
+	keyRingOnce sync.Once
+)
+
+// key ring of the service, without JWT_KEYS_DIR the tokens are signed
+// with a key generated on start that is valid until restart
+func DefaultKeyRing() *KeyRing {
+	keyRingOnce.Do(func() {
+		ring, err := LoadKeyRing()
+		if err == nil {
+			keyRing = ring
+			return
+		}
+		if os.Getenv("JWT_KEYS_DIR") != "" {
+			log.Fatalf("jwt keys: %v", err)
+		}
+		log.Printf("jwt keys: %v, using a temporary key", err)
+		_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			log.Fatal(err)
+		}
+		id := fmt.Sprintf("temporary-%d", time.Now().Unix())
+		keyRing, _ = NewKeyRing([]*SigningKey{{ID: id, PrivateKey: privateKey}}, id)
+	})
+	return keyRing
+}
+
+// issuer and audience of the access tokens
+func JWTIssuer() string {
+	if issuer := os.Getenv("JWT_ISSUER"); issuer != "" {
+		return issuer
+	}
+	return "payment-auth"
+}
+
+func JWTAudience() string {
+	if audience := os.Getenv("JWT_AUDIENCE"); audience != "" {
+		return audience
+	}
+	return "payment-api"
+}
+
+// Create JWT
+func CreateJWT(account *types.Account, role string, emailVerified bool) (string, error) {
+	now := time.Now()
+	return DefaultKeyRing().Sign(&AccessClaims{
+		AccountID:     account.ID.String(),
+		Card:          account.CardNumber,
+		Role:          role,
+		EmailVerified: emailVerified,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    JWTIssuer(),
+			Subject:   account.ID.String(),
+			Audience:  jwt.ClaimStrings{JWTAudience()},
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(accessTokenTTL)),
+		},
+	})
+}
+
+// Validate JWT of the service: signature of the key ring, not expired,
+// issued for the api audience
+func ValidateJWT(tokenString string) (*AccessClaims, error) {
+	claims := &AccessClaims{}
+	if err := DefaultKeyRing().Parse(tokenString, claims); err != nil {
+		return nil, err
+	}
+	// exp, iat and nbf are checked by the parser
+	if claims.ExpiresAt == nil {
+		return nil, errors.New("token has no expiry")
+	}
+	if !claims.VerifyIssuer(JWTIssuer(), true) {
+		return nil, errors.New("invalid token issuer")
+	}
+	if !claims.VerifyAudience(JWTAudience(), true) {
+		return nil, errors.New("invalid token audience")
+	}
+	return claims, nil
+}
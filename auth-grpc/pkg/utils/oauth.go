@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+)
+
+// New client id and secret of the OAuth2 client
+func NewClientCredentials() (string, string, error) {
+	id := make([]byte, 12)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", err
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+	return "cli_" + hex.EncodeToString(id), base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// client secrets are random, so a fast hash is enough to store them
+func HashClientSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// Create JWT of the client credentials grant, the client acts as
+// its merchant account within the scopes
+func CreateClientJWT(clientID string, accountID uuid.UUID, role string, scope string, emailVerified bool) (string, time.Duration, error) {
+	now := time.Now()
+	token, err := DefaultKeyRing().Sign(&AccessClaims{
+		AccountID:     accountID.String(),
+		Role:          role,
+		EmailVerified: emailVerified,
+		ClientID:      clientID,
+		Scope:         scope,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    JWTIssuer(),
+			Subject:   clientID,
+			Audience:  jwt.ClaimStrings{JWTAudience()},
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(accessTokenTTL)),
+		},
+	})
+	return token, accessTokenTTL, err
+}
@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"os"
+	"strconv"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// bcrypt reads at most 72 bytes of the password
+const (
+	MinPasswordLength = 8
+	MaxPasswordLength = 72
+)
+
+// bcrypt cost of new password hashes, PASSWORD_COST overrides the default,
+// hashes of another cost are rehashed on sign-in
+func PasswordCost() int {
+	cost, err := strconv.Atoi(os.Getenv("PASSWORD_COST"))
+	if err != nil || cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return bcrypt.DefaultCost
+	}
+	return cost
+}
+
+// Hash password
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), PasswordCost())
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// Check password against the hash
+func CheckPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// hash was made with other parameters than the current ones
+func NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != PasswordCost()
+}
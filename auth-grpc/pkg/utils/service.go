@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// services sign their tokens for the auth service only
+const (
+	serviceAudience = "payment-auth"
+	serviceTokenTTL = time.Minute
+)
+
+// Parse secrets of the services as comma separated service=secret pairs
+func ParseServiceSecrets(value string) (map[string]string, error) {
+	secrets := map[string]string{}
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		service, secret, ok := strings.Cut(pair, "=")
+		if !ok || service == "" || secret == "" {
+			return nil, fmt.Errorf("invalid secret of service %q", service)
+		}
+		secrets[service] = secret
+	}
+	return secrets, nil
+}
+
+// Create JWT of the service signed with its shared secret
+func CreateServiceJWT(service, secret string) (string, error) {
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &jwt.RegisteredClaims{
+		Subject:   service,
+		Audience:  jwt.ClaimStrings{serviceAudience},
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(serviceTokenTTL)),
+	})
+	return token.SignedString([]byte(secret))
+}
+
+// Validate JWT of the service, the service is the subject
+// and the token is signed with the secret of the subject
+func ValidateServiceJWT(tokenString string, secrets map[string]string) (string, error) {
+	claims := &jwt.RegisteredClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		secret, ok := secrets[claims.Subject]
+		if !ok {
+			return nil, fmt.Errorf("unknown service %q", claims.Subject)
+		}
+		return []byte(secret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return "", err
+	}
+	if claims.ExpiresAt == nil {
+		return "", errors.New("token has no expiry")
+	}
+	if !claims.VerifyAudience(serviceAudience, true) {
+		return "", errors.New("invalid token audience")
+	}
+	return claims.Subject, nil
+}
@@ -0,0 +1,53 @@
+-- amounts, directions and dates of the statement entries migrated from
+-- the account statement array, taken from the payments of the payment service:
+-- payment_id, merchant, customer, operation, status, currency, amount, created_at
+-- are read from the standard input, see backfill-statements of the Makefile.
+-- Entries of unknown payments are left as migrated,
+-- balances of the migrated entries stay unknown.
+BEGIN;
+
+CREATE TEMP TABLE payment_backfill
+(
+	payment_id UUID PRIMARY KEY,
+	merchant UUID,
+	customer UUID,
+	operation VARCHAR(50),
+	status VARCHAR(50),
+	currency VARCHAR(50),
+	amount BIGINT,
+	created_at TIMESTAMP
+) ON COMMIT DROP;
+
+\copy payment_backfill FROM pstdin
+
+-- the customer pays at authorization and gets the money back at cancel and refund,
+-- the merchant gets the money at capture and returns it at refund
+WITH backfill AS (
+	SELECT e.entry_id, p.operation, p.status, p.currency, p.amount, p.created_at,
+		CASE
+		WHEN p.operation = 'Authorization' AND p.status = 'Approved'
+			AND e.account_id = p.customer THEN 'debit'
+		WHEN p.operation = 'Capture' AND p.status = 'Successful payment'
+			AND e.account_id = p.merchant THEN 'credit'
+		WHEN p.operation = 'Cancel' AND p.status = 'Successful cancel'
+			AND e.account_id = p.customer THEN 'credit'
+		WHEN p.operation = 'Refund' AND p.status = 'Successful refund'
+			AND e.account_id = p.customer THEN 'credit'
+		WHEN p.operation = 'Refund' AND p.status = 'Successful refund'
+			AND e.account_id = p.merchant THEN 'debit'
+		ELSE 'none' END AS direction
+	FROM statement_entry e
+	JOIN payment_backfill p ON p.payment_id = e.payment_id
+	WHERE e.balance IS NULL
+)
+UPDATE statement_entry e SET
+	direction = b.direction,
+	amount = CASE b.direction WHEN 'none' THEN 0 ELSE b.amount END,
+	created_at = COALESCE(b.created_at, e.created_at),
+	operation = COALESCE(b.operation, ''),
+	status = COALESCE(b.status, ''),
+	currency = COALESCE(b.currency, '')
+FROM backfill b
+WHERE e.entry_id = b.entry_id;
+
+COMMIT;
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/mail"
+	"strings"
+
+	"github.com/Edbeer/auth-grpc/pkg/utils"
+	"github.com/Edbeer/auth-grpc/types"
+	authpb "github.com/Edbeer/payment-proto/auth-grpc/proto"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// same error for unknown email and wrong password
+var errInvalidCredentials = status.Error(codes.Unauthenticated, "invalid email or password")
+
+// hash of no password, checked for unknown emails
+// so that they take as long as wrong passwords
+const dummyPasswordHash = "$2a$10$cpxug.KXeHWHdQ8P38N42.PzkJgINOodorYWlgOrzMvtcbMf1KH66"
+
+// change password of the account, the old password is required
+func (s *AuthService) ChangePassword(ctx context.Context, req *authpb.ChangePasswordRequest) (*authpb.ChangePasswordResponse, error) {
+	accountID, err := uuid.Parse(req.Id)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid account id")
+	}
+	if err := validatePassword(req.NewPassword); err != nil {
+		return nil, err
+	}
+	cred, err := s.storage.GetCredential(ctx, accountID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, status.Error(codes.NotFound, "account has no credentials")
+		}
+		return nil, err
+	}
+	if !utils.CheckPassword(cred.PasswordHash, req.OldPassword) {
+		return nil, errInvalidCredentials
+	}
+	passwordHash, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.storage.UpdatePasswordHash(ctx, accountID, passwordHash); err != nil {
+		return nil, err
+	}
+	// sessions signed in with the old password
+	if err := s.redisStorage.RevokeAllSessions(ctx, accountID); err != nil {
+		return nil, err
+	}
+	return &authpb.ChangePasswordResponse{
+		Status: "Password was changed",
+	}, nil
+}
+
+// credentials of the email if the password matches,
+// hash of the old parameters is replaced by the current ones
+func (s *AuthService) checkCredentials(ctx context.Context, email, password string) (*types.Credential, error) {
+	cred, err := s.storage.GetCredentialByEmail(ctx, strings.TrimSpace(email))
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		utils.CheckPassword(dummyPasswordHash, password)
+		return nil, errInvalidCredentials
+	}
+	if !utils.CheckPassword(cred.PasswordHash, password) {
+		return nil, errInvalidCredentials
+	}
+	// the password is known only on sign-in
+	if utils.NeedsRehash(cred.PasswordHash) {
+		passwordHash, err := utils.HashPassword(password)
+		if err == nil {
+			err = s.storage.UpdatePasswordHash(ctx, cred.AccountID, passwordHash)
+		}
+		if err != nil {
+			log.Printf("rehash password of account %s: %v", cred.AccountID, err)
+		}
+	}
+	return cred, nil
+}
+
+// address part of the email
+func validateEmail(email string) (string, error) {
+	addr, err := mail.ParseAddress(strings.TrimSpace(email))
+	if err != nil {
+		return "", status.Error(codes.InvalidArgument, "invalid email")
+	}
+	return addr.Address, nil
+}
+
+func validatePassword(password string) error {
+	if len(password) < utils.MinPasswordLength || len(password) > utils.MaxPasswordLength {
+		return status.Error(codes.InvalidArgument, "password must be 8 to 72 bytes long")
+	}
+	return nil
+}
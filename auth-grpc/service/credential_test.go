@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/Edbeer/auth-grpc/pkg/utils"
+	mockstore "github.com/Edbeer/auth-grpc/service/mock"
+	"github.com/Edbeer/auth-grpc/types"
+	authpb "github.com/Edbeer/payment-proto/auth-grpc/proto"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func Test_ChangePassword(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mockstore.NewMockStorage(ctrl)
+	mockRedis := mockstore.NewMockRedisStorage(ctrl)
+
+	mockService := NewAuthService(mockStorage, mockRedis, nil, nil)
+	uid := uuid.New()
+
+	passwordHash, err := utils.HashPassword("password123")
+	require.NoError(t, err)
+	cred := &types.Credential{
+		AccountID:    uid,
+		Email:        "pasha@example.com",
+		PasswordHash: passwordHash,
+	}
+
+	t.Run("Change", func(t *testing.T) {
+		mockStorage.EXPECT().GetCredential(context.Background(), uid).Return(cred, nil)
+		mockStorage.EXPECT().UpdatePasswordHash(context.Background(), uid, gomock.Any()).DoAndReturn(
+			func(ctx context.Context, accountID uuid.UUID, passwordHash string) error {
+				require.True(t, utils.CheckPassword(passwordHash, "password456"))
+				return nil
+			},
+		)
+		// sessions signed in with the old password
+		mockRedis.EXPECT().RevokeAllSessions(context.Background(), uid).Return(nil)
+
+		resp, err := mockService.ChangePassword(context.Background(), &authpb.ChangePasswordRequest{
+			Id:          uid.String(),
+			OldPassword: "password123",
+			NewPassword: "password456",
+		})
+		require.NoError(t, err)
+		require.Equal(t, "Password was changed", resp.Status)
+	})
+
+	t.Run("Wrong old password", func(t *testing.T) {
+		mockStorage.EXPECT().GetCredential(context.Background(), uid).Return(cred, nil)
+
+		_, err := mockService.ChangePassword(context.Background(), &authpb.ChangePasswordRequest{
+			Id:          uid.String(),
+			OldPassword: "password124",
+			NewPassword: "password456",
+		})
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("Short new password", func(t *testing.T) {
+		_, err := mockService.ChangePassword(context.Background(), &authpb.ChangePasswordRequest{
+			Id:          uid.String(),
+			OldPassword: "password123",
+			NewPassword: "short",
+		})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("No credentials", func(t *testing.T) {
+		mockStorage.EXPECT().GetCredential(context.Background(), uid).Return(nil, sql.ErrNoRows)
+
+		_, err := mockService.ChangePassword(context.Background(), &authpb.ChangePasswordRequest{
+			Id:          uid.String(),
+			OldPassword: "password123",
+			NewPassword: "password456",
+		})
+		require.Equal(t, codes.NotFound, status.Code(err))
+	})
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/Edbeer/auth-grpc/pkg/utils"
+	"github.com/Edbeer/auth-grpc/types"
+	authpb "github.com/Edbeer/payment-proto/auth-grpc/proto"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// tokens sent by email expire after
+const (
+	verifyTokenTTL = 24 * time.Hour
+	resetTokenTTL  = time.Hour
+)
+
+// Verify email of the account with the token sent to it,
+// payments are allowed with the tokens issued after it
+func (s *AuthService) VerifyEmail(ctx context.Context, req *authpb.VerifyEmailRequest) (*authpb.VerifyEmailResponse, error) {
+	emailToken, err := s.redisStorage.UseEmailToken(ctx, types.EmailTokenVerify, req.Token)
+	if err != nil {
+		if errors.Is(err, types.ErrEmailTokenInvalid) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return nil, err
+	}
+	if err := s.storage.SetEmailVerified(ctx, emailToken.AccountID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, status.Error(codes.NotFound, "account not found")
+		}
+		return nil, err
+	}
+	return &authpb.VerifyEmailResponse{
+		Status: "Email was verified",
+	}, nil
+}
+
+// send new verification token, the old one can't be used
+func (s *AuthService) ResendVerification(ctx context.Context, req *authpb.ResendVerificationRequest) (*authpb.VerifyEmailResponse, error) {
+	accountID, err := uuid.Parse(req.Id)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid account id")
+	}
+	verified, err := s.storage.IsEmailVerified(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if verified {
+		return nil, status.Error(codes.FailedPrecondition, "email is already verified")
+	}
+	cred, err := s.storage.GetCredential(ctx, accountID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, status.Error(codes.NotFound, "account has no credentials")
+		}
+		return nil, err
+	}
+	if err := s.sendVerification(ctx, accountID, cred.Email); err != nil {
+		return nil, err
+	}
+	return &authpb.VerifyEmailResponse{
+		Status: "Verification email was sent",
+	}, nil
+}
+
+// send reset token to the email, unknown emails get the same response
+func (s *AuthService) RequestPasswordReset(ctx context.Context, req *authpb.PasswordResetRequest) (*authpb.PasswordResetResponse, error) {
+	email, err := validateEmail(req.Email)
+	if err != nil {
+		return nil, err
+	}
+	resp := &authpb.PasswordResetResponse{
+		Status: "Reset link was sent if the email has an account",
+	}
+	cred, err := s.storage.GetCredentialByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return resp, nil
+		}
+		return nil, err
+	}
+	token, err := s.redisStorage.CreateEmailToken(ctx, types.EmailTokenReset, cred.AccountID, resetTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.mailer.Send(ctx, &types.Mail{
+		To:      cred.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Set a new password within %s: %s\n\nIgnore this mail if you didn't ask for it, your password is not changed.",
+			resetTokenTTL, emailLink("/reset-password", token)),
+	}); err != nil {
+		// failures of the known emails are not told apart
+		log.Printf("send password reset to account %s: %v", cred.AccountID, err)
+	}
+	return resp, nil
+}
+
+// set new password with the reset token, sessions and the lockout
+// of the account are cleared
+func (s *AuthService) ResetPassword(ctx context.Context, req *authpb.ResetPasswordRequest) (*authpb.PasswordResetResponse, error) {
+	if err := validatePassword(req.NewPassword); err != nil {
+		return nil, err
+	}
+	emailToken, err := s.redisStorage.UseEmailToken(ctx, types.EmailTokenReset, req.Token)
+	if err != nil {
+		if errors.Is(err, types.ErrEmailTokenInvalid) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return nil, err
+	}
+	cred, err := s.storage.GetCredential(ctx, emailToken.AccountID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, status.Error(codes.NotFound, "account has no credentials")
+		}
+		return nil, err
+	}
+	passwordHash, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.storage.UpdatePasswordHash(ctx, cred.AccountID, passwordHash); err != nil {
+		return nil, err
+	}
+	// the token was received by the email
+	if err := s.storage.SetEmailVerified(ctx, cred.AccountID); err != nil {
+		return nil, err
+	}
+	if err := s.redisStorage.RevokeAllSessions(ctx, cred.AccountID); err != nil {
+		return nil, err
+	}
+	if err := s.redisStorage.ResetLoginAttempts(ctx, emailSubject(cred.Email).key); err != nil {
+		return nil, err
+	}
+	s.emit(ctx, &types.SecurityEvent{
+		Type:      types.EventPasswordReset,
+		AccountID: cred.AccountID.String(),
+		Email:     cred.Email,
+		IP:        clientIP(ctx),
+		Time:      time.Now(),
+	})
+	return &authpb.PasswordResetResponse{
+		Status: "Password was reset",
+	}, nil
+}
+
+// send verification token to the email of the account
+func (s *AuthService) sendVerification(ctx context.Context, accountID uuid.UUID, email string) error {
+	token, err := s.redisStorage.CreateEmailToken(ctx, types.EmailTokenVerify, accountID, verifyTokenTTL)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, &types.Mail{
+		To:      email,
+		Subject: "Verify your email",
+		Body: fmt.Sprintf("Verify your email within %s to make payments: %s",
+			verifyTokenTTL, emailLink("/verify-email", token)),
+	})
+}
+
+// link of the app with the token, APP_URL is the address of the app
+func emailLink(path, token string) string {
+	appURL := os.Getenv("APP_URL")
+	if appURL == "" {
+		appURL = "http://localhost:8080"
+	}
+	return appURL + path + "?token=" + url.QueryEscape(token)
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/Edbeer/auth-grpc/pkg/utils"
+	mockstore "github.com/Edbeer/auth-grpc/service/mock"
+	"github.com/Edbeer/auth-grpc/types"
+	authpb "github.com/Edbeer/payment-proto/auth-grpc/proto"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func Test_VerifyEmail(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mockstore.NewMockStorage(ctrl)
+	mockRedis := mockstore.NewMockRedisStorage(ctrl)
+	mockMailer := mockstore.NewMockMailer(ctrl)
+	mockService := NewAuthService(mockStorage, mockRedis, nil, mockMailer)
+	uid := uuid.New()
+
+	t.Run("Verify", func(t *testing.T) {
+		mockRedis.EXPECT().UseEmailToken(context.Background(), types.EmailTokenVerify, "verify-token").Return(&types.EmailToken{
+			AccountID: uid,
+			Purpose:   types.EmailTokenVerify,
+		}, nil)
+		mockStorage.EXPECT().SetEmailVerified(context.Background(), uid).Return(nil)
+
+		resp, err := mockService.VerifyEmail(context.Background(), &authpb.VerifyEmailRequest{Token: "verify-token"})
+		require.NoError(t, err)
+		require.Equal(t, "Email was verified", resp.Status)
+	})
+
+	t.Run("Invalid token", func(t *testing.T) {
+		mockRedis.EXPECT().UseEmailToken(context.Background(), types.EmailTokenVerify, "used-token").Return(nil, types.ErrEmailTokenInvalid)
+
+		_, err := mockService.VerifyEmail(context.Background(), &authpb.VerifyEmailRequest{Token: "used-token"})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("Resend", func(t *testing.T) {
+		mockStorage.EXPECT().IsEmailVerified(context.Background(), uid).Return(false, nil)
+		mockStorage.EXPECT().GetCredential(context.Background(), uid).Return(&types.Credential{AccountID: uid, Email: "pasha@example.com"}, nil)
+		mockRedis.EXPECT().CreateEmailToken(context.Background(), types.EmailTokenVerify, uid, verifyTokenTTL).Return("new-token", nil)
+		mockMailer.EXPECT().Send(context.Background(), gomock.Any()).DoAndReturn(func(ctx context.Context, mail *types.Mail) error {
+			require.Equal(t, "pasha@example.com", mail.To)
+			require.Contains(t, mail.Body, "/verify-email?token=new-token")
+			return nil
+		})
+
+		resp, err := mockService.ResendVerification(context.Background(), &authpb.ResendVerificationRequest{Id: uid.String()})
+		require.NoError(t, err)
+		require.Equal(t, "Verification email was sent", resp.Status)
+	})
+
+	t.Run("Resend verified", func(t *testing.T) {
+		mockStorage.EXPECT().IsEmailVerified(context.Background(), uid).Return(true, nil)
+
+		_, err := mockService.ResendVerification(context.Background(), &authpb.ResendVerificationRequest{Id: uid.String()})
+		require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+}
+
+func Test_RequestPasswordReset(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mockstore.NewMockStorage(ctrl)
+	mockRedis := mockstore.NewMockRedisStorage(ctrl)
+	mockMailer := mockstore.NewMockMailer(ctrl)
+	mockService := NewAuthService(mockStorage, mockRedis, nil, mockMailer)
+	uid := uuid.New()
+
+	mockStorage.EXPECT().GetCredentialByEmail(context.Background(), "pasha@example.com").Return(&types.Credential{AccountID: uid, Email: "pasha@example.com"}, nil)
+	mockRedis.EXPECT().CreateEmailToken(context.Background(), types.EmailTokenReset, uid, resetTokenTTL).Return("reset-token", nil)
+	mockMailer.EXPECT().Send(context.Background(), gomock.Any()).DoAndReturn(func(ctx context.Context, mail *types.Mail) error {
+		require.Equal(t, "pasha@example.com", mail.To)
+		require.Contains(t, mail.Body, "/reset-password?token=reset-token")
+		return nil
+	})
+
+	known, err := mockService.RequestPasswordReset(context.Background(), &authpb.PasswordResetRequest{Email: "pasha@example.com"})
+	require.NoError(t, err)
+
+	// unknown emails are not told apart
+	mockStorage.EXPECT().GetCredentialByEmail(context.Background(), "unknown@example.com").Return(nil, sql.ErrNoRows)
+
+	unknown, err := mockService.RequestPasswordReset(context.Background(), &authpb.PasswordResetRequest{Email: "unknown@example.com"})
+	require.NoError(t, err)
+	require.Equal(t, known.Status, unknown.Status)
+}
+
+func Test_ResetPassword(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mockstore.NewMockStorage(ctrl)
+	mockRedis := mockstore.NewMockRedisStorage(ctrl)
+	mockEvents := mockstore.NewMockSecurityEvents(ctrl)
+	mockService := NewAuthService(mockStorage, mockRedis, mockEvents, nil)
+	uid := uuid.New()
+
+	t.Run("Reset", func(t *testing.T) {
+		mockRedis.EXPECT().UseEmailToken(context.Background(), types.EmailTokenReset, "reset-token").Return(&types.EmailToken{
+			AccountID: uid,
+			Purpose:   types.EmailTokenReset,
+		}, nil)
+		mockStorage.EXPECT().GetCredential(context.Background(), uid).Return(&types.Credential{AccountID: uid, Email: "Pasha@example.com"}, nil)
+		mockStorage.EXPECT().UpdatePasswordHash(context.Background(), uid, gomock.Any()).DoAndReturn(
+			func(ctx context.Context, accountID uuid.UUID, passwordHash string) error {
+				require.True(t, utils.CheckPassword(passwordHash, "new-password"))
+				return nil
+			},
+		)
+		mockStorage.EXPECT().SetEmailVerified(context.Background(), uid).Return(nil)
+		// sessions and the lockout of the old password
+		mockRedis.EXPECT().RevokeAllSessions(context.Background(), uid).Return(nil)
+		mockRedis.EXPECT().ResetLoginAttempts(context.Background(), "email:pasha@example.com").Return(nil)
+		mockEvents.EXPECT().Emit(context.Background(), gomock.Any()).DoAndReturn(func(ctx context.Context, event *types.SecurityEvent) error {
+			require.Equal(t, types.EventPasswordReset, event.Type)
+			require.Equal(t, uid.String(), event.AccountID)
+			return nil
+		})
+
+		resp, err := mockService.ResetPassword(context.Background(), &authpb.ResetPasswordRequest{
+			Token:       "reset-token",
+			NewPassword: "new-password",
+		})
+		require.NoError(t, err)
+		require.Equal(t, "Password was reset", resp.Status)
+	})
+
+	t.Run("Invalid token", func(t *testing.T) {
+		mockRedis.EXPECT().UseEmailToken(context.Background(), types.EmailTokenReset, "used-token").Return(nil, types.ErrEmailTokenInvalid)
+
+		_, err := mockService.ResetPassword(context.Background(), &authpb.ResetPasswordRequest{
+			Token:       "used-token",
+			NewPassword: "new-password",
+		})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("Short password", func(t *testing.T) {
+		// the token is not used
+		_, err := mockService.ResetPassword(context.Background(), &authpb.ResetPasswordRequest{
+			Token:       "reset-token",
+			NewPassword: "short",
+		})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"math"
+	"time"
+
+	authpb "github.com/Edbeer/payment-proto/auth-grpc/proto"
+	"github.com/Edbeer/auth-grpc/types"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// reserve money of the account, it moves from the balance to blocked money
+func (s *AuthService) CreateHold(ctx context.Context, req *authpb.CreateHoldRequest) (*authpb.Hold, error) {
+	if req.Amount == 0 || req.Amount > math.MaxInt64 {
+		return nil, status.Error(codes.InvalidArgument, "invalid amount")
+	}
+	if req.Ttl == nil || req.Ttl.AsDuration() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid ttl")
+	}
+	hold, err := types.NewHold(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid account id")
+	}
+	entries, err := statementEntries(req.Statements)
+	if err != nil {
+		return nil, err
+	}
+	saved, err := s.storage.CreateHold(ctx, hold, entries)
+	if err != nil {
+		return nil, holdError(err)
+	}
+	return holdToProto(saved), nil
+}
+
+// pay the transfers from the hold, the rest is returned to the balance
+func (s *AuthService) CaptureHold(ctx context.Context, req *authpb.CaptureHoldRequest) (*authpb.Hold, error) {
+	holdID, err := uuid.Parse(req.HoldId)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid hold id")
+	}
+	if len(req.Transfers) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "capture without transfers")
+	}
+	for _, transfer := range req.Transfers {
+		if _, err := uuid.Parse(transfer.AccountId); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid account id")
+		}
+		if transfer.Amount == 0 || transfer.Amount > math.MaxInt64 {
+			return nil, status.Error(codes.InvalidArgument, "invalid amount")
+		}
+	}
+	entries, err := statementEntries(req.Statements)
+	if err != nil {
+		return nil, err
+	}
+	hold, err := s.storage.CaptureHold(ctx, holdID, req.Transfers, entries)
+	if err != nil {
+		return nil, holdError(err)
+	}
+	return holdToProto(hold), nil
+}
+
+// return the held money to the balance
+func (s *AuthService) ReleaseHold(ctx context.Context, req *authpb.ReleaseHoldRequest) (*authpb.Hold, error) {
+	holdID, err := uuid.Parse(req.HoldId)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid hold id")
+	}
+	entries, err := statementEntries(req.Statements)
+	if err != nil {
+		return nil, err
+	}
+	hold, err := s.storage.ReleaseHold(ctx, holdID, types.HoldReleased, entries)
+	if err != nil {
+		return nil, holdError(err)
+	}
+	return holdToProto(hold), nil
+}
+
+// hold more money of the balance or release part of the hold
+func (s *AuthService) ChangeHold(ctx context.Context, req *authpb.ChangeHoldRequest) (*authpb.Hold, error) {
+	holdID, err := uuid.Parse(req.HoldId)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid hold id")
+	}
+	if req.Amount == 0 || req.Amount == math.MinInt64 {
+		return nil, status.Error(codes.InvalidArgument, "invalid amount")
+	}
+	entries, err := statementEntries(req.Statements)
+	if err != nil {
+		return nil, err
+	}
+	hold, err := s.storage.ChangeHold(ctx, holdID, req.Amount, entries)
+	if err != nil {
+		return nil, holdError(err)
+	}
+	return holdToProto(hold), nil
+}
+
+// holds of the account with the money they block
+func (s *AuthService) GetHolds(ctx context.Context, req *authpb.GetHoldsRequest) (*authpb.Holds, error) {
+	if _, err := uuid.Parse(req.AccountId); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid account id")
+	}
+	state := ""
+	if req.Active {
+		state = types.HoldActive
+	}
+	holds, err := s.storage.GetHolds(ctx, req.AccountId, state)
+	if err != nil {
+		return nil, err
+	}
+	resp := &authpb.Holds{}
+	for _, hold := range holds {
+		if hold.State == types.HoldActive {
+			resp.BlockedMoney += hold.Amount
+		}
+		resp.Holds = append(resp.Holds, holdToProto(hold))
+	}
+	return resp, nil
+}
+
+// release holds whose time is over, the release of a payment hold
+// is written to the statement of the account
+func (s *AuthService) ExpireHolds(ctx context.Context) error {
+	holds, err := s.storage.GetExpiredHolds(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	for _, hold := range holds {
+		entries := []*types.StatementEntry{}
+		if _, err := uuid.Parse(hold.Reference); err == nil {
+			entry, err := types.NewStatementEntry(&authpb.StatementRequest{
+				AccountId: hold.AccountID.String(),
+				PaymentId: hold.Reference,
+				Amount:    int64(hold.Amount),
+				Operation: "Hold expiry",
+				Status:    "Expired",
+			})
+			if err != nil {
+				return err
+			}
+			entries = append(entries, entry)
+		}
+		if _, err := s.storage.ReleaseHold(ctx, hold.ID, types.HoldExpired, entries); err != nil {
+			log.Printf("expire hold %s: %v", hold.ID, err)
+		}
+	}
+	return nil
+}
+
+// expire holds every interval until ctx is done
+func (s *AuthService) RunHoldExpiry(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.ExpireHolds(ctx); err != nil {
+				log.Printf("expire holds: %v", err)
+			}
+		}
+	}
+}
+
+func holdError(err error) error {
+	switch {
+	case errors.Is(err, types.ErrInsufficientFunds),
+		errors.Is(err, types.ErrHoldNotActive),
+		errors.Is(err, types.ErrHoldAmount):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, sql.ErrNoRows):
+		return status.Error(codes.NotFound, "hold or account not found")
+	}
+	return err
+}
+
+func holdToProto(hold *types.Hold) *authpb.Hold {
+	return &authpb.Hold{
+		Id:             hold.ID.String(),
+		AccountId:      hold.AccountID.String(),
+		Amount:         hold.Amount,
+		CapturedAmount: hold.CapturedAmount,
+		State:          hold.State,
+		Reference:      hold.Reference,
+		ExpiresAt:      timestamppb.New(hold.ExpiresAt),
+		CreatedAt:      timestamppb.New(hold.CreatedAt),
+		UpdatedAt:      timestamppb.New(hold.UpdatedAt),
+	}
+}
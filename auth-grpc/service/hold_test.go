@@ -0,0 +1,258 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"testing"
+	"time"
+
+	authpb "github.com/Edbeer/payment-proto/auth-grpc/proto"
+	mockstore "github.com/Edbeer/auth-grpc/service/mock"
+	"github.com/Edbeer/auth-grpc/types"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func newTestHold(account uuid.UUID, amount uint64, state string) *types.Hold {
+	return &types.Hold{
+		ID:        uuid.New(),
+		AccountID: account,
+		Amount:    amount,
+		State:     state,
+		Reference: uuid.New().String(),
+		ExpiresAt: time.Now().Add(time.Hour),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+}
+
+func Test_CreateHold(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mockstore.NewMockStorage(ctrl)
+	mockService := NewAuthService(mockStorage, nil, nil, nil)
+
+	customer := uuid.New()
+	req := &authpb.CreateHoldRequest{
+		AccountId: customer.String(),
+		Amount:    30,
+		Ttl:       durationpb.New(time.Hour),
+		Reference: uuid.New().String(),
+		Statements: []*authpb.StatementRequest{
+			{AccountId: customer.String(), PaymentId: uuid.New().String(), Amount: -30},
+		},
+	}
+
+	t.Run("Created", func(t *testing.T) {
+		mockStorage.EXPECT().CreateHold(context.Background(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, hold *types.Hold, entries []*types.StatementEntry) (*types.Hold, error) {
+				require.Equal(t, customer, hold.AccountID)
+				require.Equal(t, types.HoldActive, hold.State)
+				require.WithinDuration(t, time.Now().Add(time.Hour), hold.ExpiresAt, time.Minute)
+				require.Len(t, entries, 1)
+				return hold, nil
+			},
+		)
+
+		resp, err := mockService.CreateHold(context.Background(), req)
+		require.NoError(t, err)
+		require.Equal(t, uint64(30), resp.Amount)
+		require.Equal(t, req.Reference, resp.Reference)
+	})
+
+	t.Run("Insufficient funds", func(t *testing.T) {
+		mockStorage.EXPECT().CreateHold(context.Background(), gomock.Any(), gomock.Any()).Return(nil, types.ErrInsufficientFunds)
+
+		_, err := mockService.CreateHold(context.Background(), req)
+		require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+
+	t.Run("Without ttl", func(t *testing.T) {
+		_, err := mockService.CreateHold(context.Background(), &authpb.CreateHoldRequest{
+			AccountId: customer.String(),
+			Amount:    30,
+		})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("Amount overflow", func(t *testing.T) {
+		_, err := mockService.CreateHold(context.Background(), &authpb.CreateHoldRequest{
+			AccountId: customer.String(),
+			Amount:    math.MaxInt64 + 1,
+			Ttl:       durationpb.New(time.Hour),
+		})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func Test_CaptureHold(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mockstore.NewMockStorage(ctrl)
+	mockService := NewAuthService(mockStorage, nil, nil, nil)
+
+	hold := newTestHold(uuid.New(), 30, types.HoldCaptured)
+	transfers := []*authpb.HoldTransfer{{AccountId: uuid.New().String(), Amount: 30}}
+
+	t.Run("Captured", func(t *testing.T) {
+		mockStorage.EXPECT().CaptureHold(context.Background(), hold.ID, transfers, gomock.Any()).Return(hold, nil)
+
+		resp, err := mockService.CaptureHold(context.Background(), &authpb.CaptureHoldRequest{
+			HoldId:    hold.ID.String(),
+			Transfers: transfers,
+		})
+		require.NoError(t, err)
+		require.Equal(t, types.HoldCaptured, resp.State)
+	})
+
+	t.Run("Not active", func(t *testing.T) {
+		mockStorage.EXPECT().CaptureHold(context.Background(), hold.ID, transfers, gomock.Any()).Return(nil, types.ErrHoldNotActive)
+
+		_, err := mockService.CaptureHold(context.Background(), &authpb.CaptureHoldRequest{
+			HoldId:    hold.ID.String(),
+			Transfers: transfers,
+		})
+		require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+
+	t.Run("Without transfers", func(t *testing.T) {
+		_, err := mockService.CaptureHold(context.Background(), &authpb.CaptureHoldRequest{
+			HoldId: hold.ID.String(),
+		})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("Amount overflow", func(t *testing.T) {
+		_, err := mockService.CaptureHold(context.Background(), &authpb.CaptureHoldRequest{
+			HoldId:    hold.ID.String(),
+			Transfers: []*authpb.HoldTransfer{{AccountId: uuid.New().String(), Amount: math.MaxUint64}},
+		})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func Test_ReleaseHold(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mockstore.NewMockStorage(ctrl)
+	mockService := NewAuthService(mockStorage, nil, nil, nil)
+
+	hold := newTestHold(uuid.New(), 30, types.HoldReleased)
+
+	t.Run("Released", func(t *testing.T) {
+		mockStorage.EXPECT().ReleaseHold(context.Background(), hold.ID, types.HoldReleased, gomock.Any()).Return(hold, nil)
+
+		resp, err := mockService.ReleaseHold(context.Background(), &authpb.ReleaseHoldRequest{HoldId: hold.ID.String()})
+		require.NoError(t, err)
+		require.Equal(t, types.HoldReleased, resp.State)
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		mockStorage.EXPECT().ReleaseHold(context.Background(), hold.ID, types.HoldReleased, gomock.Any()).Return(nil, sql.ErrNoRows)
+
+		_, err := mockService.ReleaseHold(context.Background(), &authpb.ReleaseHoldRequest{HoldId: hold.ID.String()})
+		require.Equal(t, codes.NotFound, status.Code(err))
+	})
+}
+
+func Test_ChangeHold(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mockstore.NewMockStorage(ctrl)
+	mockService := NewAuthService(mockStorage, nil, nil, nil)
+
+	hold := newTestHold(uuid.New(), 40, types.HoldActive)
+
+	t.Run("Changed", func(t *testing.T) {
+		mockStorage.EXPECT().ChangeHold(context.Background(), hold.ID, int64(10), gomock.Any()).Return(hold, nil)
+
+		resp, err := mockService.ChangeHold(context.Background(), &authpb.ChangeHoldRequest{HoldId: hold.ID.String(), Amount: 10})
+		require.NoError(t, err)
+		require.Equal(t, uint64(40), resp.Amount)
+	})
+
+	t.Run("Invalid amount", func(t *testing.T) {
+		mockStorage.EXPECT().ChangeHold(context.Background(), hold.ID, int64(-40), gomock.Any()).Return(nil, types.ErrHoldAmount)
+
+		_, err := mockService.ChangeHold(context.Background(), &authpb.ChangeHoldRequest{HoldId: hold.ID.String(), Amount: -40})
+		require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+
+	t.Run("Amount overflow", func(t *testing.T) {
+		_, err := mockService.ChangeHold(context.Background(), &authpb.ChangeHoldRequest{HoldId: hold.ID.String(), Amount: math.MinInt64})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func Test_GetHolds(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mockstore.NewMockStorage(ctrl)
+	mockService := NewAuthService(mockStorage, nil, nil, nil)
+
+	account := uuid.New()
+	mockStorage.EXPECT().GetHolds(context.Background(), account.String(), "").Return([]*types.Hold{
+		newTestHold(account, 30, types.HoldActive),
+		newTestHold(account, 20, types.HoldCaptured),
+		newTestHold(account, 10, types.HoldActive),
+	}, nil)
+
+	resp, err := mockService.GetHolds(context.Background(), &authpb.GetHoldsRequest{AccountId: account.String()})
+	require.NoError(t, err)
+	require.Len(t, resp.Holds, 3)
+	// only active holds block money
+	require.Equal(t, uint64(40), resp.BlockedMoney)
+}
+
+func Test_ExpireHolds(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mockstore.NewMockStorage(ctrl)
+	mockService := NewAuthService(mockStorage, nil, nil, nil)
+
+	payment := newTestHold(uuid.New(), 30, types.HoldActive)
+	other := newTestHold(uuid.New(), 10, types.HoldActive)
+	other.Reference = "order-1"
+
+	mockStorage.EXPECT().GetExpiredHolds(context.Background(), gomock.Any()).Return([]*types.Hold{payment, other}, nil)
+	mockStorage.EXPECT().ReleaseHold(context.Background(), payment.ID, types.HoldExpired, gomock.Any()).DoAndReturn(
+		func(ctx context.Context, holdID uuid.UUID, state string, entries []*types.StatementEntry) (*types.Hold, error) {
+			// release of a payment hold is written to the statement
+			require.Len(t, entries, 1)
+			require.Equal(t, payment.Reference, entries[0].PaymentID.String())
+			require.Equal(t, types.Credit, entries[0].Direction)
+			return payment, nil
+		},
+	)
+	mockStorage.EXPECT().ReleaseHold(context.Background(), other.ID, types.HoldExpired, gomock.Any()).DoAndReturn(
+		func(ctx context.Context, holdID uuid.UUID, state string, entries []*types.StatementEntry) (*types.Hold, error) {
+			require.Empty(t, entries)
+			return other, nil
+		},
+	)
+
+	require.NoError(t, mockService.ExpireHolds(context.Background()))
+}
@@ -7,9 +7,10 @@ package mock
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	types "github.com/Edbeer/auth-grpc/types"
-	proto "github.com/Edbeer/payment-proto/auth-grpc/proto"
+	authpb "github.com/Edbeer/payment-proto/auth-grpc/proto"
 	gomock "github.com/golang/mock/gomock"
 	uuid "github.com/google/uuid"
 )
@@ -37,26 +38,86 @@ func (m *MockStorage) EXPECT() *MockStorageMockRecorder {
 	return m.recorder
 }
 
+// ApplyMovements mocks base method.
+func (m *MockStorage) ApplyMovements(ctx context.Context, movements []*authpb.Movement, entries []*types.StatementEntry) ([]*types.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApplyMovements", ctx, movements, entries)
+	ret0, _ := ret[0].([]*types.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ApplyMovements indicates an expected call of ApplyMovements.
+func (mr *MockStorageMockRecorder) ApplyMovements(ctx, movements, entries interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplyMovements", reflect.TypeOf((*MockStorage)(nil).ApplyMovements), ctx, movements, entries)
+}
+
+// CaptureHold mocks base method.
+func (m *MockStorage) CaptureHold(ctx context.Context, holdID uuid.UUID, transfers []*authpb.HoldTransfer, entries []*types.StatementEntry) (*types.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CaptureHold", ctx, holdID, transfers, entries)
+	ret0, _ := ret[0].(*types.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CaptureHold indicates an expected call of CaptureHold.
+func (mr *MockStorageMockRecorder) CaptureHold(ctx, holdID, transfers, entries interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CaptureHold", reflect.TypeOf((*MockStorage)(nil).CaptureHold), ctx, holdID, transfers, entries)
+}
+
+// ChangeHold mocks base method.
+func (m *MockStorage) ChangeHold(ctx context.Context, holdID uuid.UUID, amount int64, entries []*types.StatementEntry) (*types.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangeHold", ctx, holdID, amount, entries)
+	ret0, _ := ret[0].(*types.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ChangeHold indicates an expected call of ChangeHold.
+func (mr *MockStorageMockRecorder) ChangeHold(ctx, holdID, amount, entries interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeHold", reflect.TypeOf((*MockStorage)(nil).ChangeHold), ctx, holdID, amount, entries)
+}
+
 // CreateAccount mocks base method.
-func (m *MockStorage) CreateAccount(ctx context.Context, account *proto.CreateRequest) (*types.Account, error) {
+func (m *MockStorage) CreateAccount(ctx context.Context, account *authpb.CreateRequest, passwordHash string) (*types.Account, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateAccount", ctx, account)
+	ret := m.ctrl.Call(m, "CreateAccount", ctx, account, passwordHash)
 	ret0, _ := ret[0].(*types.Account)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateAccount indicates an expected call of CreateAccount.
-func (mr *MockStorageMockRecorder) CreateAccount(ctx, account interface{}) *gomock.Call {
+func (mr *MockStorageMockRecorder) CreateAccount(ctx, account, passwordHash interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAccount", reflect.TypeOf((*MockStorage)(nil).CreateAccount), ctx, account)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAccount", reflect.TypeOf((*MockStorage)(nil).CreateAccount), ctx, account, passwordHash)
+}
+
+// CreateHold mocks base method.
+func (m *MockStorage) CreateHold(ctx context.Context, hold *types.Hold, entries []*types.StatementEntry) (*types.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateHold", ctx, hold, entries)
+	ret0, _ := ret[0].(*types.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateHold indicates an expected call of CreateHold.
+func (mr *MockStorageMockRecorder) CreateHold(ctx, hold, entries interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateHold", reflect.TypeOf((*MockStorage)(nil).CreateHold), ctx, hold, entries)
 }
 
 // DeleteAccount mocks base method.
-func (m *MockStorage) DeleteAccount(ctx context.Context, req *proto.DeleteRequest) (*proto.DeleteResponse, error) {
+func (m *MockStorage) DeleteAccount(ctx context.Context, req *authpb.DeleteRequest) (*authpb.DeleteResponse, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "DeleteAccount", ctx, req)
-	ret0, _ := ret[0].(*proto.DeleteResponse)
+	ret0, _ := ret[0].(*authpb.DeleteResponse)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -67,11 +128,25 @@ func (mr *MockStorageMockRecorder) DeleteAccount(ctx, req interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAccount", reflect.TypeOf((*MockStorage)(nil).DeleteAccount), ctx, req)
 }
 
+// DeleteOAuthClient mocks base method.
+func (m *MockStorage) DeleteOAuthClient(ctx context.Context, accountID uuid.UUID, clientID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteOAuthClient", ctx, accountID, clientID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteOAuthClient indicates an expected call of DeleteOAuthClient.
+func (mr *MockStorageMockRecorder) DeleteOAuthClient(ctx, accountID, clientID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOAuthClient", reflect.TypeOf((*MockStorage)(nil).DeleteOAuthClient), ctx, accountID, clientID)
+}
+
 // DepositAccount mocks base method.
-func (m *MockStorage) DepositAccount(ctx context.Context, req *proto.DepositRequest) (*proto.DepositResponse, error) {
+func (m *MockStorage) DepositAccount(ctx context.Context, req *authpb.DepositRequest) (*authpb.DepositResponse, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "DepositAccount", ctx, req)
-	ret0, _ := ret[0].(*proto.DepositResponse)
+	ret0, _ := ret[0].(*authpb.DepositResponse)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -82,6 +157,34 @@ func (mr *MockStorageMockRecorder) DepositAccount(ctx, req interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DepositAccount", reflect.TypeOf((*MockStorage)(nil).DepositAccount), ctx, req)
 }
 
+// DisableTOTP mocks base method.
+func (m *MockStorage) DisableTOTP(ctx context.Context, accountID uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisableTOTP", ctx, accountID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DisableTOTP indicates an expected call of DisableTOTP.
+func (mr *MockStorageMockRecorder) DisableTOTP(ctx, accountID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisableTOTP", reflect.TypeOf((*MockStorage)(nil).DisableTOTP), ctx, accountID)
+}
+
+// EnableTOTP mocks base method.
+func (m *MockStorage) EnableTOTP(ctx context.Context, accountID uuid.UUID, codeHashes []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnableTOTP", ctx, accountID, codeHashes)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnableTOTP indicates an expected call of EnableTOTP.
+func (mr *MockStorageMockRecorder) EnableTOTP(ctx, accountID, codeHashes interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnableTOTP", reflect.TypeOf((*MockStorage)(nil).EnableTOTP), ctx, accountID, codeHashes)
+}
+
 // GetAccount mocks base method.
 func (m *MockStorage) GetAccount(ctx context.Context) ([]*types.Account, error) {
 	m.ctrl.T.Helper()
@@ -98,7 +201,7 @@ func (mr *MockStorageMockRecorder) GetAccount(ctx interface{}) *gomock.Call {
 }
 
 // GetAccountByID mocks base method.
-func (m *MockStorage) GetAccountByID(ctx context.Context, req *proto.GetIDRequest) (*types.Account, error) {
+func (m *MockStorage) GetAccountByID(ctx context.Context, req *authpb.GetIDRequest) (*types.Account, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetAccountByID", ctx, req)
 	ret0, _ := ret[0].(*types.Account)
@@ -112,8 +215,188 @@ func (mr *MockStorageMockRecorder) GetAccountByID(ctx, req interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccountByID", reflect.TypeOf((*MockStorage)(nil).GetAccountByID), ctx, req)
 }
 
+// GetAccountsByIDs mocks base method.
+func (m *MockStorage) GetAccountsByIDs(ctx context.Context, ids []string) ([]*types.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAccountsByIDs", ctx, ids)
+	ret0, _ := ret[0].([]*types.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAccountsByIDs indicates an expected call of GetAccountsByIDs.
+func (mr *MockStorageMockRecorder) GetAccountsByIDs(ctx, ids interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccountsByIDs", reflect.TypeOf((*MockStorage)(nil).GetAccountsByIDs), ctx, ids)
+}
+
+// GetCredential mocks base method.
+func (m *MockStorage) GetCredential(ctx context.Context, accountID uuid.UUID) (*types.Credential, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCredential", ctx, accountID)
+	ret0, _ := ret[0].(*types.Credential)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCredential indicates an expected call of GetCredential.
+func (mr *MockStorageMockRecorder) GetCredential(ctx, accountID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCredential", reflect.TypeOf((*MockStorage)(nil).GetCredential), ctx, accountID)
+}
+
+// GetCredentialByEmail mocks base method.
+func (m *MockStorage) GetCredentialByEmail(ctx context.Context, email string) (*types.Credential, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCredentialByEmail", ctx, email)
+	ret0, _ := ret[0].(*types.Credential)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCredentialByEmail indicates an expected call of GetCredentialByEmail.
+func (mr *MockStorageMockRecorder) GetCredentialByEmail(ctx, email interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCredentialByEmail", reflect.TypeOf((*MockStorage)(nil).GetCredentialByEmail), ctx, email)
+}
+
+// GetExpiredHolds mocks base method.
+func (m *MockStorage) GetExpiredHolds(ctx context.Context, now time.Time) ([]*types.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetExpiredHolds", ctx, now)
+	ret0, _ := ret[0].([]*types.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetExpiredHolds indicates an expected call of GetExpiredHolds.
+func (mr *MockStorageMockRecorder) GetExpiredHolds(ctx, now interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetExpiredHolds", reflect.TypeOf((*MockStorage)(nil).GetExpiredHolds), ctx, now)
+}
+
+// GetHolds mocks base method.
+func (m *MockStorage) GetHolds(ctx context.Context, accountID, state string) ([]*types.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHolds", ctx, accountID, state)
+	ret0, _ := ret[0].([]*types.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHolds indicates an expected call of GetHolds.
+func (mr *MockStorageMockRecorder) GetHolds(ctx, accountID, state interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHolds", reflect.TypeOf((*MockStorage)(nil).GetHolds), ctx, accountID, state)
+}
+
+// GetOAuthClient mocks base method.
+func (m *MockStorage) GetOAuthClient(ctx context.Context, clientID string) (*types.OAuthClient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOAuthClient", ctx, clientID)
+	ret0, _ := ret[0].(*types.OAuthClient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOAuthClient indicates an expected call of GetOAuthClient.
+func (mr *MockStorageMockRecorder) GetOAuthClient(ctx, clientID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOAuthClient", reflect.TypeOf((*MockStorage)(nil).GetOAuthClient), ctx, clientID)
+}
+
+// GetOAuthClients mocks base method.
+func (m *MockStorage) GetOAuthClients(ctx context.Context, accountID uuid.UUID) ([]*types.OAuthClient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOAuthClients", ctx, accountID)
+	ret0, _ := ret[0].([]*types.OAuthClient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOAuthClients indicates an expected call of GetOAuthClients.
+func (mr *MockStorageMockRecorder) GetOAuthClients(ctx, accountID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOAuthClients", reflect.TypeOf((*MockStorage)(nil).GetOAuthClients), ctx, accountID)
+}
+
+// GetRole mocks base method.
+func (m *MockStorage) GetRole(ctx context.Context, accountID uuid.UUID) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRole", ctx, accountID)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRole indicates an expected call of GetRole.
+func (mr *MockStorageMockRecorder) GetRole(ctx, accountID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRole", reflect.TypeOf((*MockStorage)(nil).GetRole), ctx, accountID)
+}
+
+// GetStatementEntries mocks base method.
+func (m *MockStorage) GetStatementEntries(ctx context.Context, req *authpb.StatementGet, limit int) ([]*types.StatementEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStatementEntries", ctx, req, limit)
+	ret0, _ := ret[0].([]*types.StatementEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetStatementEntries indicates an expected call of GetStatementEntries.
+func (mr *MockStorageMockRecorder) GetStatementEntries(ctx, req, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStatementEntries", reflect.TypeOf((*MockStorage)(nil).GetStatementEntries), ctx, req, limit)
+}
+
+// GetTOTP mocks base method.
+func (m *MockStorage) GetTOTP(ctx context.Context, accountID uuid.UUID) (*types.TOTP, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTOTP", ctx, accountID)
+	ret0, _ := ret[0].(*types.TOTP)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTOTP indicates an expected call of GetTOTP.
+func (mr *MockStorageMockRecorder) GetTOTP(ctx, accountID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTOTP", reflect.TypeOf((*MockStorage)(nil).GetTOTP), ctx, accountID)
+}
+
+// IsEmailVerified mocks base method.
+func (m *MockStorage) IsEmailVerified(ctx context.Context, accountID uuid.UUID) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsEmailVerified", ctx, accountID)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsEmailVerified indicates an expected call of IsEmailVerified.
+func (mr *MockStorageMockRecorder) IsEmailVerified(ctx, accountID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsEmailVerified", reflect.TypeOf((*MockStorage)(nil).IsEmailVerified), ctx, accountID)
+}
+
+// ReleaseHold mocks base method.
+func (m *MockStorage) ReleaseHold(ctx context.Context, holdID uuid.UUID, state string, entries []*types.StatementEntry) (*types.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseHold", ctx, holdID, state, entries)
+	ret0, _ := ret[0].(*types.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReleaseHold indicates an expected call of ReleaseHold.
+func (mr *MockStorageMockRecorder) ReleaseHold(ctx, holdID, state, entries interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseHold", reflect.TypeOf((*MockStorage)(nil).ReleaseHold), ctx, holdID, state, entries)
+}
+
 // SaveBalance mocks base method.
-func (m *MockStorage) SaveBalance(ctx context.Context, req *proto.UpdateBalanceRequest) (*types.Account, error) {
+func (m *MockStorage) SaveBalance(ctx context.Context, req *authpb.UpdateBalanceRequest) (*types.Account, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "SaveBalance", ctx, req)
 	ret0, _ := ret[0].(*types.Account)
@@ -127,8 +410,80 @@ func (mr *MockStorageMockRecorder) SaveBalance(ctx, req interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveBalance", reflect.TypeOf((*MockStorage)(nil).SaveBalance), ctx, req)
 }
 
+// SaveOAuthClient mocks base method.
+func (m *MockStorage) SaveOAuthClient(ctx context.Context, client *types.OAuthClient) (*types.OAuthClient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveOAuthClient", ctx, client)
+	ret0, _ := ret[0].(*types.OAuthClient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveOAuthClient indicates an expected call of SaveOAuthClient.
+func (mr *MockStorageMockRecorder) SaveOAuthClient(ctx, client interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveOAuthClient", reflect.TypeOf((*MockStorage)(nil).SaveOAuthClient), ctx, client)
+}
+
+// SaveStatementEntry mocks base method.
+func (m *MockStorage) SaveStatementEntry(ctx context.Context, entry *types.StatementEntry) (*types.StatementEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveStatementEntry", ctx, entry)
+	ret0, _ := ret[0].(*types.StatementEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveStatementEntry indicates an expected call of SaveStatementEntry.
+func (mr *MockStorageMockRecorder) SaveStatementEntry(ctx, entry interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveStatementEntry", reflect.TypeOf((*MockStorage)(nil).SaveStatementEntry), ctx, entry)
+}
+
+// SaveTOTPSecret mocks base method.
+func (m *MockStorage) SaveTOTPSecret(ctx context.Context, accountID uuid.UUID, secret string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveTOTPSecret", ctx, accountID, secret)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveTOTPSecret indicates an expected call of SaveTOTPSecret.
+func (mr *MockStorageMockRecorder) SaveTOTPSecret(ctx, accountID, secret interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveTOTPSecret", reflect.TypeOf((*MockStorage)(nil).SaveTOTPSecret), ctx, accountID, secret)
+}
+
+// SetEmailVerified mocks base method.
+func (m *MockStorage) SetEmailVerified(ctx context.Context, accountID uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetEmailVerified", ctx, accountID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetEmailVerified indicates an expected call of SetEmailVerified.
+func (mr *MockStorageMockRecorder) SetEmailVerified(ctx, accountID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetEmailVerified", reflect.TypeOf((*MockStorage)(nil).SetEmailVerified), ctx, accountID)
+}
+
+// SetRole mocks base method.
+func (m *MockStorage) SetRole(ctx context.Context, accountID uuid.UUID, role string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetRole", ctx, accountID, role)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetRole indicates an expected call of SetRole.
+func (mr *MockStorageMockRecorder) SetRole(ctx, accountID, role interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetRole", reflect.TypeOf((*MockStorage)(nil).SetRole), ctx, accountID, role)
+}
+
 // UpdateAccount mocks base method.
-func (m *MockStorage) UpdateAccount(ctx context.Context, account *proto.UpdateRequest) (*types.Account, error) {
+func (m *MockStorage) UpdateAccount(ctx context.Context, account *authpb.UpdateRequest) (*types.Account, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "UpdateAccount", ctx, account)
 	ret0, _ := ret[0].(*types.Account)
@@ -142,19 +497,48 @@ func (mr *MockStorageMockRecorder) UpdateAccount(ctx, account interface{}) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAccount", reflect.TypeOf((*MockStorage)(nil).UpdateAccount), ctx, account)
 }
 
-// UpdateStatement mocks base method.
-func (m *MockStorage) UpdateStatement(ctx context.Context, req *proto.StatementRequest) ([]string, error) {
+// UpdatePasswordHash mocks base method.
+func (m *MockStorage) UpdatePasswordHash(ctx context.Context, accountID uuid.UUID, passwordHash string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateStatement", ctx, req)
-	ret0, _ := ret[0].([]string)
+	ret := m.ctrl.Call(m, "UpdatePasswordHash", ctx, accountID, passwordHash)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePasswordHash indicates an expected call of UpdatePasswordHash.
+func (mr *MockStorageMockRecorder) UpdatePasswordHash(ctx, accountID, passwordHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePasswordHash", reflect.TypeOf((*MockStorage)(nil).UpdatePasswordHash), ctx, accountID, passwordHash)
+}
+
+// UseRecoveryCode mocks base method.
+func (m *MockStorage) UseRecoveryCode(ctx context.Context, accountID uuid.UUID, codeHash string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseRecoveryCode", ctx, accountID, codeHash)
+	ret0, _ := ret[0].(bool)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// UpdateStatement indicates an expected call of UpdateStatement.
-func (mr *MockStorageMockRecorder) UpdateStatement(ctx, req interface{}) *gomock.Call {
+// UseRecoveryCode indicates an expected call of UseRecoveryCode.
+func (mr *MockStorageMockRecorder) UseRecoveryCode(ctx, accountID, codeHash interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateStatement", reflect.TypeOf((*MockStorage)(nil).UpdateStatement), ctx, req)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseRecoveryCode", reflect.TypeOf((*MockStorage)(nil).UseRecoveryCode), ctx, accountID, codeHash)
+}
+
+// UseTOTPStep mocks base method.
+func (m *MockStorage) UseTOTPStep(ctx context.Context, accountID uuid.UUID, step int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseTOTPStep", ctx, accountID, step)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseTOTPStep indicates an expected call of UseTOTPStep.
+func (mr *MockStorageMockRecorder) UseTOTPStep(ctx, accountID, step interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseTOTPStep", reflect.TypeOf((*MockStorage)(nil).UseTOTPStep), ctx, accountID, step)
 }
 
 // MockRedisStorage is a mock of RedisStorage interface.
@@ -180,6 +564,51 @@ func (m *MockRedisStorage) EXPECT() *MockRedisStorageMockRecorder {
 	return m.recorder
 }
 
+// AddLoginFailure mocks base method.
+func (m *MockRedisStorage) AddLoginFailure(ctx context.Context, subject string, window time.Duration) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddLoginFailure", ctx, subject, window)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddLoginFailure indicates an expected call of AddLoginFailure.
+func (mr *MockRedisStorageMockRecorder) AddLoginFailure(ctx, subject, window interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddLoginFailure", reflect.TypeOf((*MockRedisStorage)(nil).AddLoginFailure), ctx, subject, window)
+}
+
+// CreateEmailToken mocks base method.
+func (m *MockRedisStorage) CreateEmailToken(ctx context.Context, purpose string, accountID uuid.UUID, expire time.Duration) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateEmailToken", ctx, purpose, accountID, expire)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateEmailToken indicates an expected call of CreateEmailToken.
+func (mr *MockRedisStorageMockRecorder) CreateEmailToken(ctx, purpose, accountID, expire interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateEmailToken", reflect.TypeOf((*MockRedisStorage)(nil).CreateEmailToken), ctx, purpose, accountID, expire)
+}
+
+// CreateMFAChallenge mocks base method.
+func (m *MockRedisStorage) CreateMFAChallenge(ctx context.Context, accountID uuid.UUID, expire int) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateMFAChallenge", ctx, accountID, expire)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateMFAChallenge indicates an expected call of CreateMFAChallenge.
+func (mr *MockRedisStorageMockRecorder) CreateMFAChallenge(ctx, accountID, expire interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateMFAChallenge", reflect.TypeOf((*MockRedisStorage)(nil).CreateMFAChallenge), ctx, accountID, expire)
+}
+
 // CreateSession mocks base method.
 func (m *MockRedisStorage) CreateSession(ctx context.Context, session *types.Session, expire int) (string, error) {
 	m.ctrl.T.Helper()
@@ -195,6 +624,20 @@ func (mr *MockRedisStorageMockRecorder) CreateSession(ctx, session, expire inter
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSession", reflect.TypeOf((*MockRedisStorage)(nil).CreateSession), ctx, session, expire)
 }
 
+// DeleteMFAChallenge mocks base method.
+func (m *MockRedisStorage) DeleteMFAChallenge(ctx context.Context, token string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteMFAChallenge", ctx, token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteMFAChallenge indicates an expected call of DeleteMFAChallenge.
+func (mr *MockRedisStorageMockRecorder) DeleteMFAChallenge(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteMFAChallenge", reflect.TypeOf((*MockRedisStorage)(nil).DeleteMFAChallenge), ctx, token)
+}
+
 // DeleteSession mocks base method.
 func (m *MockRedisStorage) DeleteSession(ctx context.Context, refreshToken string) error {
 	m.ctrl.T.Helper()
@@ -209,17 +652,228 @@ func (mr *MockRedisStorageMockRecorder) DeleteSession(ctx, refreshToken interfac
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSession", reflect.TypeOf((*MockRedisStorage)(nil).DeleteSession), ctx, refreshToken)
 }
 
-// GetUserID mocks base method.
-func (m *MockRedisStorage) GetUserID(ctx context.Context, refreshToken string) (uuid.UUID, error) {
+// GetLoginAttempts mocks base method.
+func (m *MockRedisStorage) GetLoginAttempts(ctx context.Context, subject string) (*types.LoginAttempts, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetUserID", ctx, refreshToken)
+	ret := m.ctrl.Call(m, "GetLoginAttempts", ctx, subject)
+	ret0, _ := ret[0].(*types.LoginAttempts)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLoginAttempts indicates an expected call of GetLoginAttempts.
+func (mr *MockRedisStorageMockRecorder) GetLoginAttempts(ctx, subject interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLoginAttempts", reflect.TypeOf((*MockRedisStorage)(nil).GetLoginAttempts), ctx, subject)
+}
+
+// GetMFAChallenge mocks base method.
+func (m *MockRedisStorage) GetMFAChallenge(ctx context.Context, token string) (uuid.UUID, int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMFAChallenge", ctx, token)
 	ret0, _ := ret[0].(uuid.UUID)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetMFAChallenge indicates an expected call of GetMFAChallenge.
+func (mr *MockRedisStorageMockRecorder) GetMFAChallenge(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMFAChallenge", reflect.TypeOf((*MockRedisStorage)(nil).GetMFAChallenge), ctx, token)
+}
+
+// GetSession mocks base method.
+func (m *MockRedisStorage) GetSession(ctx context.Context, refreshToken string) (*types.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSession", ctx, refreshToken)
+	ret0, _ := ret[0].(*types.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSession indicates an expected call of GetSession.
+func (mr *MockRedisStorageMockRecorder) GetSession(ctx, refreshToken interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSession", reflect.TypeOf((*MockRedisStorage)(nil).GetSession), ctx, refreshToken)
+}
+
+// ListSessions mocks base method.
+func (m *MockRedisStorage) ListSessions(ctx context.Context, userID uuid.UUID) ([]*types.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSessions", ctx, userID)
+	ret0, _ := ret[0].([]*types.Session)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetUserID indicates an expected call of GetUserID.
-func (mr *MockRedisStorageMockRecorder) GetUserID(ctx, refreshToken interface{}) *gomock.Call {
+// ListSessions indicates an expected call of ListSessions.
+func (mr *MockRedisStorageMockRecorder) ListSessions(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSessions", reflect.TypeOf((*MockRedisStorage)(nil).ListSessions), ctx, userID)
+}
+
+// LockLogin mocks base method.
+func (m *MockRedisStorage) LockLogin(ctx context.Context, subject string, duration time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LockLogin", ctx, subject, duration)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LockLogin indicates an expected call of LockLogin.
+func (mr *MockRedisStorageMockRecorder) LockLogin(ctx, subject, duration interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockLogin", reflect.TypeOf((*MockRedisStorage)(nil).LockLogin), ctx, subject, duration)
+}
+
+// ResetLoginAttempts mocks base method.
+func (m *MockRedisStorage) ResetLoginAttempts(ctx context.Context, subjects ...string) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx}
+	for _, a := range subjects {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ResetLoginAttempts", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResetLoginAttempts indicates an expected call of ResetLoginAttempts.
+func (mr *MockRedisStorageMockRecorder) ResetLoginAttempts(ctx interface{}, subjects ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx}, subjects...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetLoginAttempts", reflect.TypeOf((*MockRedisStorage)(nil).ResetLoginAttempts), varargs...)
+}
+
+// RevokeAllSessions mocks base method.
+func (m *MockRedisStorage) RevokeAllSessions(ctx context.Context, userID uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeAllSessions", ctx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeAllSessions indicates an expected call of RevokeAllSessions.
+func (mr *MockRedisStorageMockRecorder) RevokeAllSessions(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAllSessions", reflect.TypeOf((*MockRedisStorage)(nil).RevokeAllSessions), ctx, userID)
+}
+
+// RevokeSession mocks base method.
+func (m *MockRedisStorage) RevokeSession(ctx context.Context, userID uuid.UUID, familyID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeSession", ctx, userID, familyID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeSession indicates an expected call of RevokeSession.
+func (mr *MockRedisStorageMockRecorder) RevokeSession(ctx, userID, familyID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeSession", reflect.TypeOf((*MockRedisStorage)(nil).RevokeSession), ctx, userID, familyID)
+}
+
+// RotateSession mocks base method.
+func (m *MockRedisStorage) RotateSession(ctx context.Context, refreshToken string, expire int) (*types.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RotateSession", ctx, refreshToken, expire)
+	ret0, _ := ret[0].(*types.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RotateSession indicates an expected call of RotateSession.
+func (mr *MockRedisStorageMockRecorder) RotateSession(ctx, refreshToken, expire interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateSession", reflect.TypeOf((*MockRedisStorage)(nil).RotateSession), ctx, refreshToken, expire)
+}
+
+// UseEmailToken mocks base method.
+func (m *MockRedisStorage) UseEmailToken(ctx context.Context, purpose, token string) (*types.EmailToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseEmailToken", ctx, purpose, token)
+	ret0, _ := ret[0].(*types.EmailToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseEmailToken indicates an expected call of UseEmailToken.
+func (mr *MockRedisStorageMockRecorder) UseEmailToken(ctx, purpose, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseEmailToken", reflect.TypeOf((*MockRedisStorage)(nil).UseEmailToken), ctx, purpose, token)
+}
+
+// MockSecurityEvents is a mock of SecurityEvents interface.
+type MockSecurityEvents struct {
+	ctrl     *gomock.Controller
+	recorder *MockSecurityEventsMockRecorder
+}
+
+// MockSecurityEventsMockRecorder is the mock recorder for MockSecurityEvents.
+type MockSecurityEventsMockRecorder struct {
+	mock *MockSecurityEvents
+}
+
+// NewMockSecurityEvents creates a new mock instance.
+func NewMockSecurityEvents(ctrl *gomock.Controller) *MockSecurityEvents {
+	mock := &MockSecurityEvents{ctrl: ctrl}
+	mock.recorder = &MockSecurityEventsMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSecurityEvents) EXPECT() *MockSecurityEventsMockRecorder {
+	return m.recorder
+}
+
+// Emit mocks base method.
+func (m *MockSecurityEvents) Emit(ctx context.Context, event *types.SecurityEvent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Emit", ctx, event)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Emit indicates an expected call of Emit.
+func (mr *MockSecurityEventsMockRecorder) Emit(ctx, event interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Emit", reflect.TypeOf((*MockSecurityEvents)(nil).Emit), ctx, event)
+}
+
+// MockMailer is a mock of Mailer interface.
+type MockMailer struct {
+	ctrl     *gomock.Controller
+	recorder *MockMailerMockRecorder
+}
+
+// MockMailerMockRecorder is the mock recorder for MockMailer.
+type MockMailerMockRecorder struct {
+	mock *MockMailer
+}
+
+// NewMockMailer creates a new mock instance.
+func NewMockMailer(ctrl *gomock.Controller) *MockMailer {
+	mock := &MockMailer{ctrl: ctrl}
+	mock.recorder = &MockMailerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockMailer) EXPECT() *MockMailerMockRecorder {
+	return m.recorder
+}
+
+// Send mocks base method.
+func (m *MockMailer) Send(ctx context.Context, mail *types.Mail) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Send", ctx, mail)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Send indicates an expected call of Send.
+func (mr *MockMailerMockRecorder) Send(ctx, mail interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserID", reflect.TypeOf((*MockRedisStorage)(nil).GetUserID), ctx, refreshToken)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Send", reflect.TypeOf((*MockMailer)(nil).Send), ctx, mail)
 }
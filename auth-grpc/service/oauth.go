@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/Edbeer/auth-grpc/pkg/utils"
+	"github.com/Edbeer/auth-grpc/types"
+	authpb "github.com/Edbeer/payment-proto/auth-grpc/proto"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// max length of the client name
+const maxClientNameLength = 100
+
+// same error for unknown clients and wrong secrets
+var errInvalidClient = status.Error(codes.Unauthenticated, "invalid client credentials")
+
+// register OAuth2 client of the merchant, the secret is returned once
+func (s *AuthService) CreateOAuthClient(ctx context.Context, req *authpb.CreateOAuthClientRequest) (*authpb.OAuthClient, error) {
+	accountID, err := uuid.Parse(req.Id)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid account id")
+	}
+	if len(req.Name) > maxClientNameLength {
+		return nil, status.Error(codes.InvalidArgument, "client name is too long")
+	}
+	scopes, err := validateScopes(req.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	if len(scopes) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "client has no scopes")
+	}
+	clientID, secret, err := utils.NewClientCredentials()
+	if err != nil {
+		return nil, err
+	}
+	client, err := s.storage.SaveOAuthClient(ctx, &types.OAuthClient{
+		ClientID:   clientID,
+		AccountID:  accountID,
+		Name:       req.Name,
+		SecretHash: utils.HashClientSecret(secret),
+		Scopes:     scopes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp := clientToProto(client)
+	resp.ClientSecret = secret
+	return resp, nil
+}
+
+// OAuth2 clients of the merchant, without secrets
+func (s *AuthService) ListOAuthClients(ctx context.Context, req *authpb.ListOAuthClientsRequest) (*authpb.OAuthClients, error) {
+	accountID, err := uuid.Parse(req.Id)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid account id")
+	}
+	clients, err := s.storage.GetOAuthClients(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	resp := &authpb.OAuthClients{Clients: make([]*authpb.OAuthClient, 0, len(clients))}
+	for _, client := range clients {
+		resp.Clients = append(resp.Clients, clientToProto(client))
+	}
+	return resp, nil
+}
+
+// delete OAuth2 client, issued tokens are valid until they expire
+func (s *AuthService) DeleteOAuthClient(ctx context.Context, req *authpb.DeleteOAuthClientRequest) (*authpb.DeleteOAuthClientResponse, error) {
+	accountID, err := uuid.Parse(req.Id)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid account id")
+	}
+	if err := s.storage.DeleteOAuthClient(ctx, accountID, req.ClientId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, status.Error(codes.NotFound, "client not found")
+		}
+		return nil, err
+	}
+	return &authpb.DeleteOAuthClientResponse{
+		Status: "Client was deleted",
+	}, nil
+}
+
+// client credentials grant: access token of the merchant account
+// limited to the requested scopes of the client
+func (s *AuthService) IssueClientToken(ctx context.Context, req *authpb.ClientTokenRequest) (*authpb.ClientToken, error) {
+	client, err := s.storage.GetOAuthClient(ctx, req.ClientId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errInvalidClient
+		}
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(utils.HashClientSecret(req.ClientSecret)), []byte(client.SecretHash)) != 1 {
+		return nil, errInvalidClient
+	}
+	scopes, err := grantedScopes(client.Scopes, req.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	// merchants demoted after the registration lose their clients
+	role, err := s.storage.GetRole(ctx, client.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	if !can(role, permClientManage) {
+		return nil, errInvalidClient
+	}
+	verified, err := s.storage.IsEmailVerified(ctx, client.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	token, ttl, err := utils.CreateClientJWT(client.ClientID, client.AccountID, role, strings.Join(scopes, " "), verified)
+	if err != nil {
+		return nil, err
+	}
+	return &authpb.ClientToken{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(ttl.Seconds()),
+		Scopes:      scopes,
+	}, nil
+}
+
+// known scopes without duplicates
+func validateScopes(scopes []string) ([]string, error) {
+	valid := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !types.ValidScope(scope) {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid scope %q", scope)
+		}
+		if !containsScope(valid, scope) {
+			valid = append(valid, scope)
+		}
+	}
+	return valid, nil
+}
+
+// requested scopes of the client, all its scopes by default
+func grantedScopes(clientScopes, requested []string) ([]string, error) {
+	if len(requested) == 0 {
+		return clientScopes, nil
+	}
+	scopes, err := validateScopes(requested)
+	if err != nil {
+		return nil, err
+	}
+	for _, scope := range scopes {
+		if !containsScope(clientScopes, scope) {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid scope %q", scope)
+		}
+	}
+	return scopes, nil
+}
+
+func containsScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func clientToProto(client *types.OAuthClient) *authpb.OAuthClient {
+	return &authpb.OAuthClient{
+		ClientId:  client.ClientID,
+		Name:      client.Name,
+		Scopes:    client.Scopes,
+		CreatedAt: timestamppb.New(client.CreatedAt),
+	}
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/Edbeer/auth-grpc/pkg/utils"
+	mockstore "github.com/Edbeer/auth-grpc/service/mock"
+	"github.com/Edbeer/auth-grpc/types"
+	authpb "github.com/Edbeer/payment-proto/auth-grpc/proto"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func Test_CreateOAuthClient(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mockstore.NewMockStorage(ctrl)
+	mockService := NewAuthService(mockStorage, nil, nil, nil)
+
+	uid := uuid.New()
+	var saved *types.OAuthClient
+	mockStorage.EXPECT().SaveOAuthClient(context.Background(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, client *types.OAuthClient) (*types.OAuthClient, error) {
+			saved = client
+			return client, nil
+		},
+	)
+
+	client, err := mockService.CreateOAuthClient(context.Background(), &authpb.CreateOAuthClientRequest{
+		Id:     uid.String(),
+		Name:   "shop",
+		Scopes: []string{types.ScopePaymentsRead, types.ScopePaymentsRead},
+	})
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(client.ClientId, "cli_"))
+	require.Equal(t, []string{types.ScopePaymentsRead}, client.Scopes)
+	// secret is stored as the hash only
+	require.NotEmpty(t, client.ClientSecret)
+	require.Equal(t, utils.HashClientSecret(client.ClientSecret), saved.SecretHash)
+	require.Equal(t, uid, saved.AccountID)
+
+	t.Run("Invalid scopes", func(t *testing.T) {
+		_, err := mockService.CreateOAuthClient(context.Background(), &authpb.CreateOAuthClientRequest{
+			Id:     uid.String(),
+			Scopes: []string{"accounts:write"},
+		})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		_, err = mockService.CreateOAuthClient(context.Background(), &authpb.CreateOAuthClientRequest{
+			Id: uid.String(),
+		})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		mockStorage.EXPECT().DeleteOAuthClient(context.Background(), uid, "cli_1").Return(sql.ErrNoRows)
+
+		_, err := mockService.DeleteOAuthClient(context.Background(), &authpb.DeleteOAuthClientRequest{
+			Id:       uid.String(),
+			ClientId: "cli_1",
+		})
+		require.Equal(t, codes.NotFound, status.Code(err))
+	})
+}
+
+func Test_IssueClientToken(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mockstore.NewMockStorage(ctrl)
+	mockService := NewAuthService(mockStorage, nil, nil, nil)
+
+	uid := uuid.New()
+	clientID, secret, err := utils.NewClientCredentials()
+	require.NoError(t, err)
+	client := &types.OAuthClient{
+		ClientID:   clientID,
+		AccountID:  uid,
+		SecretHash: utils.HashClientSecret(secret),
+		Scopes:     []string{types.ScopePaymentsWrite, types.ScopePaymentsRead},
+	}
+	mockStorage.EXPECT().GetOAuthClient(context.Background(), clientID).Return(client, nil).AnyTimes()
+	mockStorage.EXPECT().GetOAuthClient(context.Background(), "cli_unknown").Return(nil, sql.ErrNoRows)
+	mockStorage.EXPECT().GetRole(context.Background(), uid).Return(types.RoleMerchant, nil).Times(2)
+	mockStorage.EXPECT().IsEmailVerified(context.Background(), uid).Return(true, nil).Times(2)
+
+	token, err := mockService.IssueClientToken(context.Background(), &authpb.ClientTokenRequest{
+		ClientId:     clientID,
+		ClientSecret: secret,
+		Scopes:       []string{types.ScopePaymentsRead},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "Bearer", token.TokenType)
+	require.Equal(t, []string{types.ScopePaymentsRead}, token.Scopes)
+
+	// client acts as its merchant within the scopes
+	claims, err := utils.ValidateJWT(token.AccessToken)
+	require.NoError(t, err)
+	require.Equal(t, uid.String(), claims.AccountID)
+	require.Equal(t, types.RoleMerchant, claims.Role)
+	require.Equal(t, clientID, claims.ClientID)
+	require.Equal(t, types.ScopePaymentsRead, claims.Scope)
+
+	t.Run("All scopes by default", func(t *testing.T) {
+		token, err := mockService.IssueClientToken(context.Background(), &authpb.ClientTokenRequest{
+			ClientId:     clientID,
+			ClientSecret: secret,
+		})
+		require.NoError(t, err)
+		require.Equal(t, client.Scopes, token.Scopes)
+	})
+
+	t.Run("Invalid client", func(t *testing.T) {
+		_, err := mockService.IssueClientToken(context.Background(), &authpb.ClientTokenRequest{
+			ClientId:     clientID,
+			ClientSecret: "wrong",
+		})
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+		_, err = mockService.IssueClientToken(context.Background(), &authpb.ClientTokenRequest{
+			ClientId:     "cli_unknown",
+			ClientSecret: secret,
+		})
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("Scope of other client", func(t *testing.T) {
+		readOnly := *client
+		readOnly.ClientID = "cli_read"
+		readOnly.Scopes = []string{types.ScopePaymentsRead}
+		mockStorage.EXPECT().GetOAuthClient(context.Background(), "cli_read").Return(&readOnly, nil)
+
+		_, err := mockService.IssueClientToken(context.Background(), &authpb.ClientTokenRequest{
+			ClientId:     "cli_read",
+			ClientSecret: secret,
+			Scopes:       []string{types.ScopePaymentsWrite},
+		})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("Merchant demoted", func(t *testing.T) {
+		mockStorage.EXPECT().GetRole(context.Background(), uid).Return(types.RoleCustomer, nil)
+
+		_, err := mockService.IssueClientToken(context.Background(), &authpb.ClientTokenRequest{
+			ClientId:     clientID,
+			ClientSecret: secret,
+		})
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+}
@@ -0,0 +1,315 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Edbeer/auth-grpc/pkg/utils"
+	"github.com/Edbeer/auth-grpc/types"
+	authpb "github.com/Edbeer/payment-proto/auth-grpc/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// metadata of the service token of the calling service
+const serviceHeader = "x-service-token"
+
+// Permission of the role
+type permission string
+
+const (
+	// own account
+	permAccountRead  permission = "account:read"
+	permAccountWrite permission = "account:write"
+	// accounts of others
+	permAccountReadAny  permission = "account:read:any"
+	permAccountWriteAny permission = "account:write:any"
+	permAccountList     permission = "account:list"
+	permRoleManage      permission = "role:manage"
+	// OAuth2 clients of the merchant servers
+	permClientManage permission = "client:manage"
+	// lockouts after failed sign-ins
+	permLoginUnlock permission = "login:unlock"
+)
+
+var rolePermissions = map[string][]permission{
+	types.RoleCustomer: {permAccountRead, permAccountWrite},
+	types.RoleMerchant: {permAccountRead, permAccountWrite, permClientManage},
+	types.RoleSupport:  {permAccountRead, permAccountWrite, permAccountReadAny},
+	types.RoleAdmin: {
+		permAccountRead, permAccountWrite,
+		permAccountReadAny, permAccountWriteAny,
+		permAccountList, permRoleManage, permClientManage,
+		permLoginUnlock,
+	},
+}
+
+// Has the role the permission
+func can(role string, perm permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// Access rule of the method
+type rule struct {
+	// called without a caller: sign-up, sign-in and public keys
+	public bool
+	// called by the payment service with its service token
+	internal bool
+	// required permission of the caller
+	permission permission
+	// request is of the account with the id, only its owner
+	// or a caller with the any permission may send it
+	owned bool
+	any   permission
+}
+
+var methodRules = map[string]rule{
+	"CreateAccount": {public: true},
+	"SignIn":        {public: true},
+	"SignInMFA":     {public: true},
+	"SignOut":       {public: true},
+	"RefreshTokens": {public: true},
+	"GetJWKS":       {public: true},
+	// deposit to the card of the caller
+	"DepositAccount": {permission: permAccountWrite, owned: true, any: permAccountWriteAny},
+	// recovery and verification by the tokens sent to the email
+	"RequestPasswordReset": {public: true},
+	"ResetPassword":        {public: true},
+	"VerifyEmail":          {public: true},
+	"ResendVerification":   {permission: permAccountWrite, owned: true},
+	// client credentials grant
+	"IssueClientToken": {public: true},
+	// credentials are changed by the owner only
+	"ChangePassword":    {permission: permAccountWrite, owned: true},
+	"EnrollTOTP":        {permission: permAccountWrite, owned: true},
+	"VerifyTOTP":        {permission: permAccountWrite, owned: true},
+	"DisableTOTP":       {permission: permAccountWrite, owned: true},
+	"ListSessions":      {permission: permAccountRead, owned: true, any: permAccountReadAny},
+	"RevokeSession":     {permission: permAccountWrite, owned: true, any: permAccountWriteAny},
+	"RevokeAllSessions": {permission: permAccountWrite, owned: true, any: permAccountWriteAny},
+	"UpdateAccount":     {permission: permAccountWrite, owned: true, any: permAccountWriteAny},
+	"DeleteAccount":     {permission: permAccountWrite, owned: true, any: permAccountWriteAny},
+	"GetHolds":          {permission: permAccountRead, owned: true, any: permAccountReadAny},
+	"GetAccount":        {permission: permAccountList},
+	"SetRole":           {permission: permRoleManage},
+	"UnlockAccount":     {permission: permLoginUnlock},
+	"CreateOAuthClient": {permission: permClientManage, owned: true, any: permAccountWriteAny},
+	"ListOAuthClients":  {permission: permClientManage, owned: true, any: permAccountWriteAny},
+	"DeleteOAuthClient": {permission: permClientManage, owned: true, any: permAccountWriteAny},
+	"GetAccountByID":    {internal: true, permission: permAccountRead, owned: true, any: permAccountReadAny},
+	"GetStatement":      {internal: true, permission: permAccountRead, owned: true, any: permAccountReadAny},
+	// balance changes of the payments
+	"UpdateBalance":         {internal: true},
+	"GetAccountsByIDs":      {internal: true},
+	"ApplyPaymentMovements": {internal: true},
+	"CreateStatement":       {internal: true},
+	"CreateHold":            {internal: true},
+	"CaptureHold":           {internal: true},
+	"ReleaseHold":           {internal: true},
+	"ChangeHold":            {internal: true},
+}
+
+// Caller of the request from its access token
+type Caller struct {
+	AccountID string
+	Card      string
+	Role      string
+	// OAuth2 client acting as the account
+	ClientID string
+}
+
+type callerKey struct{}
+
+type serviceKey struct{}
+
+// Caller of the request, none for public and internal calls without a token
+func CallerFromContext(ctx context.Context) (*Caller, bool) {
+	caller, ok := ctx.Value(callerKey{}).(*Caller)
+	return caller, ok
+}
+
+// Service of the service token of the request
+func ServiceFromContext(ctx context.Context) (string, bool) {
+	service, ok := ctx.Value(serviceKey{}).(string)
+	return service, ok
+}
+
+// Check the caller of the auth service methods against the policy,
+// services authenticate with tokens signed by their secrets
+func UnaryInterceptor(services map[string]string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		rule, ok, err := methodRule(info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return handler(ctx, req)
+		}
+		ctx, err = authorize(ctx, services, rule)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkOwner(ctx, rule, req); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func StreamInterceptor(services map[string]string) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		rule, ok, err := methodRule(info.FullMethod)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return handler(srv, stream)
+		}
+		ctx, err := authorize(stream.Context(), services, rule)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &policyStream{ServerStream: stream, ctx: ctx, rule: rule})
+	}
+}
+
+// rule of the auth service method, methods without a rule are denied,
+// methods of other services are not checked
+func methodRule(fullMethod string) (rule, bool, error) {
+	prefix := "/" + authpb.AuthService_ServiceDesc.ServiceName + "/"
+	if !strings.HasPrefix(fullMethod, prefix) {
+		return rule{}, false, nil
+	}
+	rule, ok := methodRules[strings.TrimPrefix(fullMethod, prefix)]
+	if !ok {
+		return rule, false, status.Error(codes.PermissionDenied, "method is not allowed")
+	}
+	return rule, true, nil
+}
+
+// context with the service and the caller of the tokens if the rule lets them call
+func authorize(ctx context.Context, services map[string]string, rule rule) (context.Context, error) {
+	service, err := serviceFromMetadata(ctx, services)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid service token")
+	}
+	if service != "" {
+		ctx = context.WithValue(ctx, serviceKey{}, service)
+	}
+	caller, err := callerFromMetadata(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid access token")
+	}
+	if caller == nil {
+		if rule.public || rule.internal && service == types.ServicePayment {
+			return ctx, nil
+		}
+		return nil, status.Error(codes.Unauthenticated, "access token is required")
+	}
+	if !rule.public && !can(caller.Role, rule.permission) {
+		return nil, status.Error(codes.PermissionDenied, "permission denied")
+	}
+	// client tokens are scoped to the payment api
+	if !rule.public && caller.ClientID != "" {
+		return nil, status.Error(codes.PermissionDenied, "permission denied")
+	}
+	return context.WithValue(ctx, callerKey{}, caller), nil
+}
+
+// owned requests are of the caller or the caller has the any permission,
+// the payment service calls without a caller
+func checkOwner(ctx context.Context, rule rule, req interface{}) error {
+	if !rule.owned {
+		return nil
+	}
+	caller, ok := CallerFromContext(ctx)
+	if !ok {
+		if service, _ := ServiceFromContext(ctx); rule.internal && service == types.ServicePayment {
+			return nil
+		}
+		return status.Error(codes.PermissionDenied, "permission denied")
+	}
+	if caller.AccountID == requestAccountID(req) {
+		return nil
+	}
+	if card := requestCard(req); card != "" && card == caller.Card {
+		return nil
+	}
+	if rule.any != "" && can(caller.Role, rule.any) {
+		return nil
+	}
+	return status.Error(codes.PermissionDenied, "permission denied")
+}
+
+// account of the request
+func requestAccountID(req interface{}) string {
+	switch req := req.(type) {
+	case interface{ GetAccountId() string }:
+		return req.GetAccountId()
+	case interface{ GetId() string }:
+		return req.GetId()
+	}
+	return ""
+}
+
+// card of the request
+func requestCard(req interface{}) string {
+	if req, ok := req.(interface{ GetCardNumber() string }); ok {
+		return req.GetCardNumber()
+	}
+	return ""
+}
+
+// caller of the bearer token in the authorization metadata, nil without a token
+func callerFromMetadata(ctx context.Context) (*Caller, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	header := firstValue(md, "authorization")
+	if header == "" {
+		return nil, nil
+	}
+	claims, err := utils.ValidateJWT(strings.TrimPrefix(header, "Bearer "))
+	if err != nil {
+		return nil, err
+	}
+	role := claims.Role
+	// tokens issued before the roles
+	if role == "" {
+		role = types.RoleCustomer
+	}
+	return &Caller{AccountID: claims.AccountID, Card: claims.Card, Role: role, ClientID: claims.ClientID}, nil
+}
+
+// service of the service token in the metadata, none without a token
+func serviceFromMetadata(ctx context.Context, services map[string]string) (string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	token := firstValue(md, serviceHeader)
+	if token == "" {
+		return "", nil
+	}
+	return utils.ValidateServiceJWT(token, services)
+}
+
+// stream with the caller, every received request is checked
+type policyStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	rule rule
+}
+
+func (s *policyStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *policyStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return checkOwner(s.ctx, s.rule, m)
+}
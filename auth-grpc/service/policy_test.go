@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Edbeer/auth-grpc/pkg/utils"
+	"github.com/Edbeer/auth-grpc/types"
+	authpb "github.com/Edbeer/payment-proto/auth-grpc/proto"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func Test_MethodRules(t *testing.T) {
+	t.Parallel()
+
+	// every method of the service has a rule
+	for _, method := range authpb.AuthService_ServiceDesc.Methods {
+		require.Contains(t, methodRules, method.MethodName)
+	}
+	for _, stream := range authpb.AuthService_ServiceDesc.Streams {
+		require.Contains(t, methodRules, stream.StreamName)
+	}
+}
+
+func Test_UnaryInterceptor(t *testing.T) {
+	t.Parallel()
+
+	services := map[string]string{types.ServicePayment: "payment-secret", types.ServiceGateway: "gateway-secret"}
+	interceptor := UnaryInterceptor(services)
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+	call := func(ctx context.Context, method string, req interface{}) error {
+		_, err := interceptor(ctx, req, &grpc.UnaryServerInfo{
+			FullMethod: "/auth.AuthService/" + method,
+		}, handler)
+		return err
+	}
+	withToken := func(accountID uuid.UUID, role string) context.Context {
+		token, err := utils.CreateJWT(&types.Account{ID: accountID, CardNumber: "4444" + accountID.String()[:12]}, role, true)
+		require.NoError(t, err)
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+	}
+	withService := func(service, secret string) context.Context {
+		token, err := utils.CreateServiceJWT(service, secret)
+		require.NoError(t, err)
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-service-token", token))
+	}
+
+	owner := uuid.New()
+	other := uuid.New().String()
+	payment := withService(types.ServicePayment, "payment-secret")
+	gateway := withService(types.ServiceGateway, "gateway-secret")
+	forged := withService(types.ServicePayment, "gateway-secret")
+	customer := withToken(owner, types.RoleCustomer)
+	support := withToken(uuid.New(), types.RoleSupport)
+	admin := withToken(uuid.New(), types.RoleAdmin)
+	clientToken, _, err := utils.CreateClientJWT("cli_1", owner, types.RoleMerchant, types.ScopePaymentsWrite, true)
+	require.NoError(t, err)
+	client := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+clientToken))
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		method string
+		req    interface{}
+		code   codes.Code
+	}{
+		{"Public", context.Background(), "SignIn", &authpb.LoginRequest{}, codes.OK},
+		{"Internal", payment, "ApplyPaymentMovements", &authpb.MovementsRequest{}, codes.OK},
+		{"Internal without token", context.Background(), "ApplyPaymentMovements", &authpb.MovementsRequest{}, codes.Unauthenticated},
+		{"Internal of gateway", gateway, "UpdateBalance", &authpb.UpdateBalanceRequest{}, codes.Unauthenticated},
+		{"Forged service token", forged, "ApplyPaymentMovements", &authpb.MovementsRequest{}, codes.Unauthenticated},
+		{"Internal with token", customer, "ApplyPaymentMovements", &authpb.MovementsRequest{}, codes.PermissionDenied},
+		{"Payment service reads account", payment, "GetAccountByID", &authpb.GetIDRequest{Id: other}, codes.OK},
+		{"Account without token", context.Background(), "GetAccountByID", &authpb.GetIDRequest{Id: other}, codes.Unauthenticated},
+		{"Account of other", customer, "GetAccountByID", &authpb.GetIDRequest{Id: other}, codes.PermissionDenied},
+		{"No token", context.Background(), "UpdateAccount", &authpb.UpdateRequest{Id: other}, codes.Unauthenticated},
+		{"Invalid token", metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer token")), "SignIn", &authpb.LoginRequest{}, codes.Unauthenticated},
+		{"Owner", customer, "UpdateAccount", &authpb.UpdateRequest{Id: owner.String()}, codes.OK},
+		{"Not owner", customer, "UpdateAccount", &authpb.UpdateRequest{Id: other}, codes.PermissionDenied},
+		{"Support reads", support, "GetAccountByID", &authpb.GetIDRequest{Id: other}, codes.OK},
+		{"Support writes", support, "UpdateAccount", &authpb.UpdateRequest{Id: other}, codes.PermissionDenied},
+		{"Admin writes", admin, "DeleteAccount", &authpb.DeleteRequest{Id: other}, codes.OK},
+		{"Admin changes password", admin, "ChangePassword", &authpb.ChangePasswordRequest{Id: other}, codes.PermissionDenied},
+		{"Customer lists accounts", customer, "GetAccount", &authpb.GetRequest{}, codes.PermissionDenied},
+		{"Customer sets role", customer, "SetRole", &authpb.SetRoleRequest{Id: owner.String(), Role: types.RoleAdmin}, codes.PermissionDenied},
+		{"Admin sets role", admin, "SetRole", &authpb.SetRoleRequest{Id: other, Role: types.RoleSupport}, codes.OK},
+		{"Support unlocks", support, "UnlockAccount", &authpb.UnlockAccountRequest{Id: other}, codes.PermissionDenied},
+		{"Admin unlocks", admin, "UnlockAccount", &authpb.UnlockAccountRequest{Id: other}, codes.OK},
+		{"Deposit without token", context.Background(), "DepositAccount", &authpb.DepositRequest{CardNumber: "4444" + owner.String()[:12]}, codes.Unauthenticated},
+		{"Deposit to own card", customer, "DepositAccount", &authpb.DepositRequest{CardNumber: "4444" + owner.String()[:12]}, codes.OK},
+		{"Deposit to other card", customer, "DepositAccount", &authpb.DepositRequest{CardNumber: "4444444444444444"}, codes.PermissionDenied},
+		{"Admin deposits", admin, "DepositAccount", &authpb.DepositRequest{CardNumber: "4444444444444444"}, codes.OK},
+		{"Public reset", context.Background(), "ResetPassword", &authpb.ResetPasswordRequest{}, codes.OK},
+		{"Resend verification of other", customer, "ResendVerification", &authpb.ResendVerificationRequest{Id: other}, codes.PermissionDenied},
+		{"Client token", client, "UpdateAccount", &authpb.UpdateRequest{Id: owner.String()}, codes.PermissionDenied},
+		{"Client token on public method", client, "GetJWKS", &authpb.JWKSRequest{}, codes.OK},
+		{"Unknown method", admin, "DropAccounts", &authpb.GetRequest{}, codes.PermissionDenied},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			err := call(tt.ctx, tt.method, tt.req)
+			require.Equal(t, tt.code, status.Code(err))
+		})
+	}
+}
+
+func Test_StreamInterceptor(t *testing.T) {
+	t.Parallel()
+
+	services := map[string]string{types.ServicePayment: "payment-secret"}
+	owner := uuid.New()
+	token, err := utils.CreateJWT(&types.Account{ID: owner}, types.RoleCustomer, true)
+	require.NoError(t, err)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+
+	// statement of the account is received by the handler
+	receive := func(ctx context.Context, req *authpb.StatementGet) error {
+		return StreamInterceptor(services)(nil, &recvStream{ctx: ctx, req: req}, &grpc.StreamServerInfo{
+			FullMethod: "/auth.AuthService/GetStatement",
+		}, func(srv interface{}, stream grpc.ServerStream) error {
+			return stream.RecvMsg(&authpb.StatementGet{})
+		})
+	}
+
+	require.NoError(t, receive(ctx, &authpb.StatementGet{AccountId: owner.String()}))
+	err = receive(ctx, &authpb.StatementGet{AccountId: uuid.New().String()})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// statements of any account are read by the payment service only
+	err = receive(context.Background(), &authpb.StatementGet{AccountId: owner.String()})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+	serviceToken, err := utils.CreateServiceJWT(types.ServicePayment, "payment-secret")
+	require.NoError(t, err)
+	payment := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-service-token", serviceToken))
+	require.NoError(t, receive(payment, &authpb.StatementGet{AccountId: uuid.New().String()}))
+}
+
+// server stream receiving one request
+type recvStream struct {
+	grpc.ServerStream
+	ctx context.Context
+	req proto.Message
+}
+
+func (s *recvStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *recvStream) RecvMsg(m interface{}) error {
+	proto.Merge(m.(proto.Message), s.req)
+	return nil
+}
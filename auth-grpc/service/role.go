@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/Edbeer/auth-grpc/types"
+	authpb "github.com/Edbeer/payment-proto/auth-grpc/proto"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Set role of the account, sessions of the account are revoked
+// so that the tokens of the old role are not refreshed
+func (s *AuthService) SetRole(ctx context.Context, req *authpb.SetRoleRequest) (*authpb.SetRoleResponse, error) {
+	accountID, err := uuid.Parse(req.Id)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid account id")
+	}
+	if !types.ValidRole(req.Role) {
+		return nil, status.Error(codes.InvalidArgument, "invalid role")
+	}
+	if err := s.storage.SetRole(ctx, accountID, req.Role); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, status.Error(codes.NotFound, "account not found")
+		}
+		return nil, err
+	}
+	if err := s.redisStorage.RevokeAllSessions(ctx, accountID); err != nil {
+		return nil, err
+	}
+	return &authpb.SetRoleResponse{
+		Id:   req.Id,
+		Role: req.Role,
+	}, nil
+}
+
+// role chosen on sign-up, support and admin are set by admins
+func signUpRole(role string) (string, error) {
+	switch role {
+	case "":
+		return types.RoleCustomer, nil
+	case types.RoleCustomer, types.RoleMerchant:
+		return role, nil
+	}
+	return "", status.Error(codes.InvalidArgument, "invalid role")
+}
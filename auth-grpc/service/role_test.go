@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	mockstore "github.com/Edbeer/auth-grpc/service/mock"
+	"github.com/Edbeer/auth-grpc/types"
+	authpb "github.com/Edbeer/payment-proto/auth-grpc/proto"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func Test_SetRole(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mockstore.NewMockStorage(ctrl)
+	mockRedis := mockstore.NewMockRedisStorage(ctrl)
+	mockService := NewAuthService(mockStorage, mockRedis, nil, nil)
+
+	uid := uuid.New()
+	mockStorage.EXPECT().SetRole(context.Background(), uid, types.RoleMerchant).Return(nil)
+	// tokens of the old role are not refreshed
+	mockRedis.EXPECT().RevokeAllSessions(context.Background(), uid).Return(nil)
+
+	resp, err := mockService.SetRole(context.Background(), &authpb.SetRoleRequest{
+		Id:   uid.String(),
+		Role: types.RoleMerchant,
+	})
+	require.NoError(t, err)
+	require.Equal(t, types.RoleMerchant, resp.Role)
+
+	t.Run("Unknown role", func(t *testing.T) {
+		_, err := mockService.SetRole(context.Background(), &authpb.SetRoleRequest{
+			Id:   uid.String(),
+			Role: "owner",
+		})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("Unknown account", func(t *testing.T) {
+		mockStorage.EXPECT().SetRole(context.Background(), uid, types.RoleAdmin).Return(sql.ErrNoRows)
+
+		_, err := mockService.SetRole(context.Background(), &authpb.SetRoleRequest{
+			Id:   uid.String(),
+			Role: types.RoleAdmin,
+		})
+		require.Equal(t, codes.NotFound, status.Code(err))
+	})
+}
@@ -3,7 +3,11 @@ package service
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"io"
+	"log"
+	"time"
 
 	"github.com/Edbeer/auth-grpc/pkg/utils"
 	authpb "github.com/Edbeer/payment-proto/auth-grpc/proto"
@@ -15,36 +19,112 @@ import (
 )
 
 type Storage interface {
-	CreateAccount(ctx context.Context, account *authpb.CreateRequest) (*types.Account, error)
+	CreateAccount(ctx context.Context, account *authpb.CreateRequest, passwordHash string) (*types.Account, error)
 	UpdateAccount(ctx context.Context, account *authpb.UpdateRequest) (*types.Account, error)
 	DeleteAccount(ctx context.Context, req *authpb.DeleteRequest) (*authpb.DeleteResponse, error)
 	DepositAccount(ctx context.Context, req *authpb.DepositRequest) (*authpb.DepositResponse, error)
 	GetAccountByID(ctx context.Context, req *authpb.GetIDRequest) (*types.Account, error)
 	GetAccount(ctx context.Context) ([]*types.Account, error)
 	SaveBalance(ctx context.Context, req *authpb.UpdateBalanceRequest) (*types.Account, error)
-	UpdateStatement(ctx context.Context, req *authpb.StatementRequest) ([]string, error)
+	SaveStatementEntry(ctx context.Context, entry *types.StatementEntry) (*types.StatementEntry, error)
+	GetStatementEntries(ctx context.Context, req *authpb.StatementGet, limit int) ([]*types.StatementEntry, error)
+	GetAccountsByIDs(ctx context.Context, ids []string) ([]*types.Account, error)
+	ApplyMovements(ctx context.Context, movements []*authpb.Movement, entries []*types.StatementEntry) ([]*types.Account, error)
+	CreateHold(ctx context.Context, hold *types.Hold, entries []*types.StatementEntry) (*types.Hold, error)
+	CaptureHold(ctx context.Context, holdID uuid.UUID, transfers []*authpb.HoldTransfer, entries []*types.StatementEntry) (*types.Hold, error)
+	ReleaseHold(ctx context.Context, holdID uuid.UUID, state string, entries []*types.StatementEntry) (*types.Hold, error)
+	ChangeHold(ctx context.Context, holdID uuid.UUID, amount int64, entries []*types.StatementEntry) (*types.Hold, error)
+	GetHolds(ctx context.Context, accountID string, state string) ([]*types.Hold, error)
+	GetExpiredHolds(ctx context.Context, now time.Time) ([]*types.Hold, error)
+	GetCredentialByEmail(ctx context.Context, email string) (*types.Credential, error)
+	GetCredential(ctx context.Context, accountID uuid.UUID) (*types.Credential, error)
+	UpdatePasswordHash(ctx context.Context, accountID uuid.UUID, passwordHash string) error
+	SaveTOTPSecret(ctx context.Context, accountID uuid.UUID, secret string) error
+	GetTOTP(ctx context.Context, accountID uuid.UUID) (*types.TOTP, error)
+	UseTOTPStep(ctx context.Context, accountID uuid.UUID, step int64) (bool, error)
+	EnableTOTP(ctx context.Context, accountID uuid.UUID, codeHashes []string) error
+	DisableTOTP(ctx context.Context, accountID uuid.UUID) error
+	UseRecoveryCode(ctx context.Context, accountID uuid.UUID, codeHash string) (bool, error)
+	GetRole(ctx context.Context, accountID uuid.UUID) (string, error)
+	SetRole(ctx context.Context, accountID uuid.UUID, role string) error
+	SaveOAuthClient(ctx context.Context, client *types.OAuthClient) (*types.OAuthClient, error)
+	GetOAuthClient(ctx context.Context, clientID string) (*types.OAuthClient, error)
+	GetOAuthClients(ctx context.Context, accountID uuid.UUID) ([]*types.OAuthClient, error)
+	DeleteOAuthClient(ctx context.Context, accountID uuid.UUID, clientID string) error
+	IsEmailVerified(ctx context.Context, accountID uuid.UUID) (bool, error)
+	SetEmailVerified(ctx context.Context, accountID uuid.UUID) error
 }
 
+// statement entries sent at once by default and at most
+const (
+	statementPageSize    = 100
+	maxStatementPageSize = 1000
+)
+
 type RedisStorage interface {
 	CreateSession(ctx context.Context, session *types.Session, expire int) (string, error)
-	GetUserID(ctx context.Context, refreshToken string) (uuid.UUID, error)
+	RotateSession(ctx context.Context, refreshToken string, expire int) (*types.Session, error)
 	DeleteSession(ctx context.Context, refreshToken string) error
+	GetSession(ctx context.Context, refreshToken string) (*types.Session, error)
+	ListSessions(ctx context.Context, userID uuid.UUID) ([]*types.Session, error)
+	RevokeSession(ctx context.Context, userID uuid.UUID, familyID string) error
+	RevokeAllSessions(ctx context.Context, userID uuid.UUID) error
+	CreateMFAChallenge(ctx context.Context, accountID uuid.UUID, expire int) (string, error)
+	GetMFAChallenge(ctx context.Context, token string) (uuid.UUID, int64, error)
+	DeleteMFAChallenge(ctx context.Context, token string) error
+	GetLoginAttempts(ctx context.Context, subject string) (*types.LoginAttempts, error)
+	AddLoginFailure(ctx context.Context, subject string, window time.Duration) (int64, error)
+	LockLogin(ctx context.Context, subject string, duration time.Duration) error
+	ResetLoginAttempts(ctx context.Context, subjects ...string) error
+	CreateEmailToken(ctx context.Context, purpose string, accountID uuid.UUID, expire time.Duration) (string, error)
+	UseEmailToken(ctx context.Context, purpose string, token string) (*types.EmailToken, error)
+}
+
+// audit of the security events, e.g. lockouts
+type SecurityEvents interface {
+	Emit(ctx context.Context, event *types.SecurityEvent) error
+}
+
+// delivery of the mails to the accounts
+type Mailer interface {
+	Send(ctx context.Context, mail *types.Mail) error
 }
 
 type AuthService struct {
 	authpb.UnimplementedAuthServiceServer
 	redisStorage RedisStorage
 	storage      Storage
+	events       SecurityEvents
+	mailer       Mailer
+	loginPolicy  *LoginPolicy
 }
 
-func NewAuthService(storage Storage, redisStorage RedisStorage) *AuthService {
+func NewAuthService(storage Storage, redisStorage RedisStorage, events SecurityEvents, mailer Mailer) *AuthService {
 	return &AuthService{
 		storage:      storage,
 		redisStorage: redisStorage,
+		events:       events,
+		mailer:       mailer,
+		loginPolicy:  DefaultLoginPolicy(),
 	}
 }
 
 func (s *AuthService) CreateAccount(ctx context.Context, req *authpb.CreateRequest) (*authpb.AccountWithTokens, error) {
+	email, err := validateEmail(req.Email)
+	if err != nil {
+		return nil, err
+	}
+	if err := validatePassword(req.Password); err != nil {
+		return nil, err
+	}
+	role, err := signUpRole(req.Role)
+	if err != nil {
+		return nil, err
+	}
+	passwordHash, err := utils.HashPassword(req.Password)
+	if err != nil {
+		return nil, err
+	}
 	accReq := &authpb.CreateRequest{
 		FirstName:        req.FirstName,
 		LastName:         req.LastName,
@@ -52,25 +132,33 @@ func (s *AuthService) CreateAccount(ctx context.Context, req *authpb.CreateReque
 		CardExpiryMonth:  req.CardExpiryMonth,
 		CardExpiryYear:   req.CardExpiryYear,
 		CardSecurityCode: req.CardSecurityCode,
+		Email:            email,
+		Role:             role,
 	}
 
-	account, err := s.storage.CreateAccount(ctx, accReq)
+	account, err := s.storage.CreateAccount(ctx, accReq, passwordHash)
 	if err != nil {
+		if errors.Is(err, types.ErrEmailTaken) {
+			return nil, status.Error(codes.AlreadyExists, err.Error())
+		}
 		return nil, err
 	}
 
-	accessToken, err := utils.CreateJWT(account)
+	// the email is verified with the token sent to it
+	accessToken, err := utils.CreateJWT(account, role, false)
 	if err != nil {
 		return nil, err
 	}
 
 	// refreshToken
-	refreshToken, err := s.redisStorage.CreateSession(ctx, &types.Session{
-		UserID: account.ID,
-	}, 86400)
+	refreshToken, err := s.redisStorage.CreateSession(ctx, clientSession(ctx, account.ID), 86400)
 	if err != nil {
 		return nil, err
 	}
+	// the account is created without the mail, it is sent again on request
+	if err := s.sendVerification(ctx, account.ID, email); err != nil {
+		log.Printf("send verification to account %s: %v", account.ID, err)
+	}
 
 	return accAndTokenToProto(account, accessToken, refreshToken), nil
 }
@@ -110,6 +198,12 @@ func (s *AuthService) DeleteAccount(ctx context.Context, req *authpb.DeleteReque
 	if err != nil {
 		return nil, err
 	}
+	// refresh tokens of the deleted account
+	if accountID, err := uuid.Parse(req.Id); err == nil {
+		if err := s.redisStorage.RevokeAllSessions(ctx, accountID); err != nil {
+			return nil, err
+		}
+	}
 	return status, nil
 }
 
@@ -129,6 +223,7 @@ func (s *AuthService) DepositAccount(ctx context.Context, req *authpb.DepositReq
 	return status, nil
 }
 
+// Deprecated: balances are changed by ApplyPaymentMovements and holds.
 func (s *AuthService) UpdateBalance(ctx context.Context, req *authpb.UpdateBalanceRequest) (*authpb.Account, error) {
 	account, err := s.storage.SaveBalance(ctx, req)
 	if err != nil {
@@ -137,8 +232,48 @@ func (s *AuthService) UpdateBalance(ctx context.Context, req *authpb.UpdateBalan
 	return accountToProto(account), nil
 }
 
+// accounts found by the ids, missing accounts are not returned
+func (s *AuthService) GetAccountsByIDs(ctx context.Context, req *authpb.GetIDsRequest) (*authpb.Accounts, error) {
+	for _, id := range req.Ids {
+		if _, err := uuid.Parse(id); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid account id")
+		}
+	}
+	accounts, err := s.storage.GetAccountsByIDs(ctx, req.Ids)
+	if err != nil {
+		return nil, err
+	}
+	return accountsToProto(accounts), nil
+}
+
+// money changes and statements of the payment are applied at once or not at all
+func (s *AuthService) ApplyPaymentMovements(ctx context.Context, req *authpb.MovementsRequest) (*authpb.Accounts, error) {
+	for _, m := range req.Movements {
+		if _, err := uuid.Parse(m.AccountId); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid account id")
+		}
+	}
+	entries, err := statementEntries(req.Statements)
+	if err != nil {
+		return nil, err
+	}
+	accounts, err := s.storage.ApplyMovements(ctx, req.Movements, entries)
+	if errors.Is(err, types.ErrInsufficientFunds) {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Error(codes.NotFound, "account not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return accountsToProto(accounts), nil
+}
+
+// Deprecated: statements are saved by ApplyPaymentMovements and holds.
 func (s *AuthService) CreateStatement(stream authpb.AuthService_CreateStatementServer) error {
-	for i := 0; i < 2; i++ {
+	// one statement per party of the payment, until the client closes the stream
+	for {
 		statement, err := stream.Recv()
 		if err == io.EOF {
 			break
@@ -146,8 +281,11 @@ func (s *AuthService) CreateStatement(stream authpb.AuthService_CreateStatementS
 		if err != nil {
 			return err
 		}
-		_, err = s.storage.UpdateStatement(stream.Context(), statement)
+		entry, err := types.NewStatementEntry(statement)
 		if err != nil {
+			return status.Error(codes.InvalidArgument, "invalid statement")
+		}
+		if _, err := s.storage.SaveStatementEntry(stream.Context(), entry); err != nil {
 			return err
 		}
 		if err = stream.Send(&authpb.StatementResponse{}); err != nil {
@@ -157,49 +295,89 @@ func (s *AuthService) CreateStatement(stream authpb.AuthService_CreateStatementS
 	return nil
 }
 
+// one page of the statement entries in the order they were created,
+// the next page starts after the cursor of the last entry
 func (s *AuthService) GetStatement(req *authpb.StatementGet, stream authpb.AuthService_GetStatementServer) error {
-	account, err := s.storage.GetAccountByID(stream.Context(), &authpb.GetIDRequest{
-		Id: req.AccountId,
-	})
+	if _, err := uuid.Parse(req.AccountId); err != nil {
+		return status.Error(codes.InvalidArgument, "invalid account id")
+	}
+	if req.From != nil && req.To != nil && !req.From.AsTime().Before(req.To.AsTime()) {
+		return status.Error(codes.InvalidArgument, "invalid period")
+	}
+	limit := int(req.Limit)
+	if limit == 0 {
+		limit = statementPageSize
+	}
+	if limit > maxStatementPageSize {
+		limit = maxStatementPageSize
+	}
+	entries, err := s.storage.GetStatementEntries(stream.Context(), req, limit)
 	if err != nil {
 		return err
 	}
-	for {
-		select {
-		case <-stream.Context().Done():
-			return status.Error(codes.Canceled, "Stream has ended")
-		default:
-			for _, statement := range account.Statement {
-				if err := stream.Send(&authpb.Statement{
-					PaymentId: statement,
-				}); err != nil {
-					return err
-				}
-			}
+	for _, entry := range entries {
+		if err := stream.Send(statementToProto(entry)); err != nil {
+			return err
 		}
-		break
 	}
-
 	return nil
 }
 
 func (s *AuthService) SignIn(ctx context.Context, req *authpb.LoginRequest) (*authpb.AccountWithTokens, error) {
+	// brute force of the email or from the address
+	subjects := loginSubjects(ctx, req.Email)
+	if err := s.checkLoginThrottle(ctx, subjects); err != nil {
+		return nil, err
+	}
+	cred, err := s.checkCredentials(ctx, req.Email, req.Password)
+	if err != nil {
+		if err == errInvalidCredentials {
+			return nil, s.loginFailed(ctx, subjects, err)
+		}
+		return nil, err
+	}
+	// tokens are issued after the second factor, the failures
+	// are forgotten when the whole sign-in succeeds
+	mfaToken, err := s.mfaChallenge(ctx, cred.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	if mfaToken != "" {
+		return &authpb.AccountWithTokens{
+			MfaRequired: true,
+			MfaToken:    mfaToken,
+		}, nil
+	}
+	if err := s.loginSucceeded(ctx, subjects); err != nil {
+		return nil, err
+	}
+	return s.issueTokens(ctx, cred.AccountID)
+}
+
+// account with new access and refresh tokens
+func (s *AuthService) issueTokens(ctx context.Context, accountID uuid.UUID) (*authpb.AccountWithTokens, error) {
 	account, err := s.storage.GetAccountByID(ctx, &authpb.GetIDRequest{
-		Id: req.Id,
+		Id: accountID.String(),
 	})
 	if err != nil {
 		return nil, err
 	}
+	role, err := s.storage.GetRole(ctx, account.ID)
+	if err != nil {
+		return nil, err
+	}
+	verified, err := s.storage.IsEmailVerified(ctx, account.ID)
+	if err != nil {
+		return nil, err
+	}
 
-	accessToken, err := utils.CreateJWT(account)
+	accessToken, err := utils.CreateJWT(account, role, verified)
 	if err != nil {
 		return nil, err
 	}
 
 	// refreshToken
-	refreshToken, err := s.redisStorage.CreateSession(ctx, &types.Session{
-		UserID: account.ID,
-	}, 86400)
+	refreshToken, err := s.redisStorage.CreateSession(ctx, clientSession(ctx, account.ID), 86400)
 	if err != nil {
 		return nil, err
 	}
@@ -218,37 +396,51 @@ func (s *AuthService) SignOut(ctx context.Context, req *authpb.QuitRequest) (*au
 }
 
 func (s *AuthService) RefreshTokens(ctx context.Context, req *authpb.RefreshRequest) (*authpb.Tokens, error) {
-	uid, err := s.redisStorage.GetUserID(ctx, req.RefreshToken)
+	// every refresh token is exchanged once
+	session, err := s.redisStorage.RotateSession(ctx, req.RefreshToken, 86400)
 	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrTokenReused):
+			return nil, status.Error(codes.Unauthenticated, "refresh token was already used, session is revoked")
+		case errors.Is(err, types.ErrSessionNotFound):
+			return nil, status.Error(codes.Unauthenticated, "invalid refresh token")
+		}
 		return nil, err
 	}
 
 	account, err := s.storage.GetAccountByID(ctx, &authpb.GetIDRequest{
-		Id: uid.String(),
+		Id: session.UserID.String(),
 	})
 	if err != nil {
 		return nil, err
 	}
-
-	// jwt-token
-	tokenString, err := utils.CreateJWT(account)
+	// role changes and the verification take effect on the next refresh
+	role, err := s.storage.GetRole(ctx, account.ID)
 	if err != nil {
 		return nil, err
 	}
-	// refreshToken
-	refreshToken, err := s.redisStorage.CreateSession(ctx, &types.Session{
-		UserID: account.ID,
-	}, 86400)
+	verified, err := s.storage.IsEmailVerified(ctx, account.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// jwt-token
+	tokenString, err := utils.CreateJWT(account, role, verified)
 	if err != nil {
 		return nil, err
 	}
 
 	return &authpb.Tokens{
 		AccessToken:  tokenString,
-		RefreshToken: refreshToken,
+		RefreshToken: session.RefreshToken,
 	}, nil
 }
 
+// public keys of the access tokens for validators
+func (s *AuthService) GetJWKS(ctx context.Context, req *authpb.JWKSRequest) (*authpb.JWKS, error) {
+	return utils.DefaultKeyRing().JWKS(), nil
+}
+
 func accountToProto(acc *types.Account) *authpb.Account {
 	return &authpb.Account{
 		Id:               acc.ID.String(),
@@ -261,7 +453,63 @@ func accountToProto(acc *types.Account) *authpb.Account {
 		Balance:          acc.Balance,
 		BlockedMoney:     acc.BlockedMoney,
 		CreatedAt:        timestamppb.New(acc.CreatedAt),
+		EscrowMoney:      acc.EscrowMoney,
+	}
+}
+
+func accountsToProto(accounts []*types.Account) *authpb.Accounts {
+	resp := &authpb.Accounts{Accounts: make([]*authpb.Account, 0, len(accounts))}
+	for _, acc := range accounts {
+		resp.Accounts = append(resp.Accounts, accountToProto(acc))
+	}
+	return resp
+}
+
+// statement entries written together with the money changes
+func statementEntries(statements []*authpb.StatementRequest) ([]*types.StatementEntry, error) {
+	entries := make([]*types.StatementEntry, 0, len(statements))
+	for _, statement := range statements {
+		entry, err := types.NewStatementEntry(statement)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid statement")
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func statementToProto(entry *types.StatementEntry) *authpb.Statement {
+	counterpartyID := ""
+	if entry.CounterpartyID.Valid {
+		counterpartyID = entry.CounterpartyID.UUID.String()
+	}
+	var balance *uint64
+	if entry.Balance.Valid {
+		b := uint64(entry.Balance.Int64)
+		balance = &b
+	}
+	return &authpb.Statement{
+		PaymentId:        entry.PaymentID.String(),
+		Direction:        entry.Direction,
+		Amount:           entry.Amount,
+		Balance:          balance,
+		CreatedAt:        timestamppb.New(entry.CreatedAt),
+		Cursor:           entry.ID,
+		CounterpartyId:   counterpartyID,
+		CounterpartyName: entry.CounterpartyName,
+		Operation:        entry.Operation,
+		Status:           entry.Status,
+		SignedAmount:     signedAmount(entry),
+		Currency:         entry.Currency,
+	}
+}
+
+// amount of the entry with the sign of the direction
+func signedAmount(entry *types.StatementEntry) int64 {
+	if entry.Direction == types.Debit {
+		return -int64(entry.Amount)
 	}
+	return int64(entry.Amount)
 }
 
 func accAndTokenToProto(acc *types.Account, accessToken, refreshToken string) *authpb.AccountWithTokens {
@@ -277,6 +525,7 @@ func accAndTokenToProto(acc *types.Account, accessToken, refreshToken string) *a
 			Balance:          acc.Balance,
 			BlockedMoney:     acc.BlockedMoney,
 			CreatedAt:        timestamppb.New(acc.CreatedAt),
+			EscrowMoney:      acc.EscrowMoney,
 		},
 		AccessToken: accessToken,
 		RefreshToken: refreshToken,
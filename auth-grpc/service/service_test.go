@@ -2,7 +2,9 @@ package service
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"io"
 
 	"testing"
 	"time"
@@ -11,10 +13,15 @@ import (
 	mockclient "github.com/Edbeer/payment-proto/auth-grpc/client/mock"
 	authpb "github.com/Edbeer/payment-proto/auth-grpc/proto"
 	mockstore "github.com/Edbeer/auth-grpc/service/mock"
+	"github.com/Edbeer/auth-grpc/pkg/utils"
 	"github.com/Edbeer/auth-grpc/types"
 	"github.com/golang/mock/gomock"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 func Test_CreateAccount(t *testing.T) {
@@ -29,8 +36,9 @@ func Test_CreateAccount(t *testing.T) {
 
 	mockStorage := mockstore.NewMockStorage(ctrl)
 	mockRedis := mockstore.NewMockRedisStorage(ctrl)
+	mockMailer := mockstore.NewMockMailer(ctrl)
 
-	mockService := NewAuthService(mockStorage, mockRedis)
+	mockService := NewAuthService(mockStorage, mockRedis, nil, mockMailer)
 
 	req := &authpb.CreateRequest{
 		FirstName:        "Pasha",
@@ -39,6 +47,9 @@ func Test_CreateAccount(t *testing.T) {
 		CardExpiryMonth:  "12",
 		CardExpiryYear:   "24",
 		CardSecurityCode: "123",
+		Email:            "Pasha@example.com",
+		Password:         "password123",
+		Role:             types.RoleMerchant,
 	}
 	reqAcc := types.NewAccount(req)
 	acc := &types.Account{
@@ -53,19 +64,63 @@ func Test_CreateAccount(t *testing.T) {
 		BlockedMoney:     0,
 		CreatedAt:        time.Now(),
 	}
-	mockStorage.EXPECT().CreateAccount(context.Background(), gomock.Eq(req)).Return(acc, nil).AnyTimes()
+	mockStorage.EXPECT().CreateAccount(context.Background(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, account *authpb.CreateRequest, passwordHash string) (*types.Account, error) {
+			// password is stored as the hash only
+			require.Empty(t, account.Password)
+			require.Equal(t, req.Email, account.Email)
+			require.Equal(t, types.RoleMerchant, account.Role)
+			require.True(t, utils.CheckPassword(passwordHash, req.Password))
+			return acc, nil
+		},
+	)
 
 	sess := &types.Session{
 		UserID: reqAcc.ID,
 	}
 	token := "refresh-token"
 	mockRedis.EXPECT().CreateSession(context.Background(), gomock.Eq(sess), 86400).Return(token, nil)
-
+	// the email is verified with the token sent on sign-up
+	mockRedis.EXPECT().CreateEmailToken(context.Background(), types.EmailTokenVerify, reqAcc.ID, verifyTokenTTL).Return("verify-token", nil)
+	mockMailer.EXPECT().Send(context.Background(), gomock.Any()).DoAndReturn(func(ctx context.Context, mail *types.Mail) error {
+		require.Equal(t, "Pasha@example.com", mail.To)
+		require.Contains(t, mail.Body, "token=verify-token")
+		return nil
+	})
 
 	account, err := mockService.CreateAccount(context.Background(), req)
 	require.NoError(t, err)
 	require.Nil(t, err)
 	require.Equal(t, accountToProto(acc), account.Account)
+
+	t.Run("Email taken", func(t *testing.T) {
+		mockStorage.EXPECT().CreateAccount(context.Background(), gomock.Any(), gomock.Any()).Return(nil, types.ErrEmailTaken)
+
+		_, err := mockService.CreateAccount(context.Background(), req)
+		require.Equal(t, codes.AlreadyExists, status.Code(err))
+	})
+
+	t.Run("Invalid credentials", func(t *testing.T) {
+		_, err := mockService.CreateAccount(context.Background(), &authpb.CreateRequest{
+			Email:    "not an email",
+			Password: "password123",
+		})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		_, err = mockService.CreateAccount(context.Background(), &authpb.CreateRequest{
+			Email:    "pasha@example.com",
+			Password: "short",
+		})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		// admins are not created on sign-up
+		_, err = mockService.CreateAccount(context.Background(), &authpb.CreateRequest{
+			Email:    "pasha@example.com",
+			Password: "password123",
+			Role:     types.RoleAdmin,
+		})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
 }
 
 func Test_UpdateAccount(t *testing.T) {
@@ -79,7 +134,7 @@ func Test_UpdateAccount(t *testing.T) {
 	defer db.Close()
 
 	mockStorage := mockstore.NewMockStorage(ctrl)
-	mockService := NewAuthService(mockStorage, nil)
+	mockService := NewAuthService(mockStorage, nil, nil, nil)
 
 	uid := uuid.New()
 	reqToUpdate := &authpb.UpdateRequest{
@@ -131,7 +186,7 @@ func Test_DeleteAccount(t *testing.T) {
 	mockStorage := mockstore.NewMockStorage(ctrl)
 	mockRedis := mockstore.NewMockRedisStorage(ctrl)
 
-	mockService := NewAuthService(mockStorage, mockRedis)
+	mockService := NewAuthService(mockStorage, mockRedis, nil, nil)
 
 	req := &authpb.DeleteRequest{
 		Id: uuid.New().String(),
@@ -140,8 +195,8 @@ func Test_DeleteAccount(t *testing.T) {
 		Status: "Account was deleted",
 	}
 	mockStorage.EXPECT().DeleteAccount(context.Background(), req).Return(resp, nil).AnyTimes()
-
-
+	// sessions of the deleted account
+	mockRedis.EXPECT().RevokeAllSessions(context.Background(), uuid.MustParse(req.Id)).Return(nil)
 
 	result, err := mockService.DeleteAccount(context.Background(), req)
 	require.NoError(t, err)
@@ -155,7 +210,7 @@ func Test_DepositAccount(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockStorage := mockstore.NewMockStorage(ctrl)
-	mockService := NewAuthService(mockStorage, nil)
+	mockService := NewAuthService(mockStorage, nil, nil, nil)
 
 	reqDep := &authpb.DepositRequest{
 		CardNumber: "4444444444444444",
@@ -180,7 +235,7 @@ func Test_UpdateBalance(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockStorage := mockstore.NewMockStorage(ctrl)
-	mockService := NewAuthService(mockStorage, nil)
+	mockService := NewAuthService(mockStorage, nil, nil, nil)
 
 	req := &authpb.UpdateBalanceRequest{
 		Id:           uuid.New().String(),
@@ -208,6 +263,91 @@ func Test_UpdateBalance(t *testing.T) {
 	require.Equal(t, account.BlockedMoney, acc.BlockedMoney)
 }
 
+func Test_ApplyPaymentMovements(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mockstore.NewMockStorage(ctrl)
+	mockService := NewAuthService(mockStorage, nil, nil, nil)
+
+	customer, merchant := uuid.New(), uuid.New()
+	req := &authpb.MovementsRequest{
+		Movements: []*authpb.Movement{
+			{AccountId: customer.String(), Balance: -30},
+			{AccountId: merchant.String(), Balance: 30},
+		},
+		Statements: []*authpb.StatementRequest{
+			{AccountId: customer.String(), PaymentId: uuid.New().String(), Amount: -30},
+			{AccountId: merchant.String(), PaymentId: uuid.New().String()},
+		},
+	}
+
+	t.Run("Applied", func(t *testing.T) {
+		mockStorage.EXPECT().ApplyMovements(context.Background(), req.Movements, gomock.Any()).DoAndReturn(
+			func(ctx context.Context, movements []*authpb.Movement, entries []*types.StatementEntry) ([]*types.Account, error) {
+				require.Len(t, entries, 2)
+				require.Equal(t, types.Debit, entries[0].Direction)
+				require.Equal(t, types.NoChange, entries[1].Direction)
+				return []*types.Account{
+					{ID: customer, Balance: 70, CreatedAt: time.Now()},
+					{ID: merchant, Balance: 30, CreatedAt: time.Now()},
+				}, nil
+			},
+		)
+
+		resp, err := mockService.ApplyPaymentMovements(context.Background(), req)
+		require.NoError(t, err)
+		require.Len(t, resp.Accounts, 2)
+		require.Equal(t, uint64(70), resp.Accounts[0].Balance)
+	})
+
+	t.Run("Insufficient funds", func(t *testing.T) {
+		mockStorage.EXPECT().ApplyMovements(context.Background(), req.Movements, gomock.Any()).Return(nil, types.ErrInsufficientFunds)
+
+		_, err := mockService.ApplyPaymentMovements(context.Background(), req)
+		require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		mockStorage.EXPECT().ApplyMovements(context.Background(), req.Movements, gomock.Any()).Return(nil, sql.ErrNoRows)
+
+		_, err := mockService.ApplyPaymentMovements(context.Background(), req)
+		require.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("Invalid statement", func(t *testing.T) {
+		_, err := mockService.ApplyPaymentMovements(context.Background(), &authpb.MovementsRequest{
+			Statements: []*authpb.StatementRequest{{AccountId: "account"}},
+		})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func Test_GetAccountsByIDs(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mockstore.NewMockStorage(ctrl)
+	mockService := NewAuthService(mockStorage, nil, nil, nil)
+
+	ids := []string{uuid.New().String(), uuid.New().String()}
+	mockStorage.EXPECT().GetAccountsByIDs(context.Background(), ids).Return([]*types.Account{
+		{ID: uuid.MustParse(ids[0]), CreatedAt: time.Now()},
+	}, nil)
+
+	resp, err := mockService.GetAccountsByIDs(context.Background(), &authpb.GetIDsRequest{Ids: ids})
+	require.NoError(t, err)
+	require.Len(t, resp.Accounts, 1)
+	require.Equal(t, ids[0], resp.Accounts[0].Id)
+
+	_, err = mockService.GetAccountsByIDs(context.Background(), &authpb.GetIDsRequest{Ids: []string{"account"}})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
 func Test_GetAccountByID(t *testing.T) {
 	t.Parallel()
 
@@ -219,7 +359,7 @@ func Test_GetAccountByID(t *testing.T) {
 	defer db.Close()
 
 	mockStorage := mockstore.NewMockStorage(ctrl)
-	mockService := NewAuthService(mockStorage, nil)
+	mockService := NewAuthService(mockStorage, nil, nil, nil)
 
 	req := &authpb.GetIDRequest{
 		Id: uuid.New().String(),
@@ -255,7 +395,7 @@ func Test_GetAccount(t *testing.T) {
 	defer ctrl.Finish()
 
 	storage := mockstore.NewMockStorage(ctrl)
-	service := NewAuthService(storage, nil)
+	service := NewAuthService(storage, nil, nil, nil)
 	acc1 := &types.Account{
 		ID:               uuid.New(),
 		FirstName:        "Pasha",
@@ -266,7 +406,6 @@ func Test_GetAccount(t *testing.T) {
 		CardSecurityCode: "123",
 		Balance:          0,
 		BlockedMoney:     0,
-		Statement:        []string{},
 		CreatedAt:        time.Now(),
 	}
 	acc2 := &types.Account{
@@ -279,7 +418,6 @@ func Test_GetAccount(t *testing.T) {
 		CardSecurityCode: "123",
 		Balance:          0,
 		BlockedMoney:     0,
-		Statement:        []string{},
 		CreatedAt:        time.Now(),
 	}
 	accs := []*types.Account{acc1, acc2}
@@ -311,33 +449,72 @@ func Test_CreateStatement(t *testing.T) {
 	defer ctrl.Finish()
 
 	storage := mockstore.NewMockStorage(ctrl)
-	service := NewAuthService(storage, nil)
+	service := NewAuthService(storage, nil, nil, nil)
 
 	req1 := &authpb.StatementRequest{
 		AccountId: uuid.New().String(),
 		PaymentId: uuid.New().String(),
+		Amount:    -50,
+		Operation: "Transfer",
+		Status:    "Successful transfer",
+		Currency:  "rub",
 	}
 
 	req2 := &authpb.StatementRequest{
-		AccountId: uuid.New().String(),
-		PaymentId: uuid.New().String(),
+		AccountId:      uuid.New().String(),
+		PaymentId:      req1.PaymentId,
+		Amount:         50,
+		CounterpartyId: req1.AccountId,
+		Operation:      "Transfer",
+		Status:         "Successful transfer",
+		Currency:       "rub",
 	}
+	req1.CounterpartyId = req2.AccountId
 
 	streamServer := mockclient.NewMockAuthService_CreateStatementServer(ctrl)
 
 	streamServer.EXPECT().Context().Return(context.Background()).AnyTimes()
 
-	streamServer.EXPECT().Recv().Return(req1, nil).AnyTimes()
-	storage.EXPECT().UpdateStatement(context.Background(), req1).Return([]string{req1.PaymentId}, nil).AnyTimes()
-	streamServer.EXPECT().Send(&authpb.StatementResponse{}).Return(nil).AnyTimes()
+	req3 := &authpb.StatementRequest{
+		AccountId: uuid.New().String(),
+		PaymentId: uuid.New().String(),
+	}
 
-	streamServer.EXPECT().Recv().Return(req2, nil).AnyTimes()
-	storage.EXPECT().UpdateStatement(context.Background(), req2).Return([]string{req2.PaymentId}, nil).AnyTimes()
-	streamServer.EXPECT().Send(&authpb.StatementResponse{}).Return(nil).AnyTimes()
+	gomock.InOrder(
+		streamServer.EXPECT().Recv().Return(req1, nil),
+		streamServer.EXPECT().Recv().Return(req2, nil),
+		streamServer.EXPECT().Recv().Return(req3, nil),
+		streamServer.EXPECT().Recv().Return(nil, io.EOF),
+	)
+	directions := map[string]string{}
+	storage.EXPECT().SaveStatementEntry(context.Background(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, entry *types.StatementEntry) (*types.StatementEntry, error) {
+			directions[entry.AccountID.String()] = entry.Direction
+			if entry.Operation != "" {
+				require.True(t, entry.CounterpartyID.Valid)
+				require.Equal(t, "rub", entry.Currency)
+			}
+			require.Equal(t, map[string]uint64{types.Debit: 50, types.Credit: 50, types.NoChange: 0}[entry.Direction], entry.Amount)
+			return entry, nil
+		},
+	).Times(3)
+	streamServer.EXPECT().Send(&authpb.StatementResponse{}).Return(nil).Times(3)
 
 	err := service.CreateStatement(streamServer)
 	require.NoError(t, err)
-	require.Nil(t, err)
+	require.Equal(t, map[string]string{
+		req1.AccountId: types.Debit,
+		req2.AccountId: types.Credit,
+		req3.AccountId: types.NoChange,
+	}, directions)
+
+	t.Run("Invalid statement", func(t *testing.T) {
+		streamServer := mockclient.NewMockAuthService_CreateStatementServer(ctrl)
+		streamServer.EXPECT().Recv().Return(&authpb.StatementRequest{AccountId: "account"}, nil)
+
+		err := service.CreateStatement(streamServer)
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
 }
 
 func Test_GetStatement(t *testing.T) {
@@ -347,54 +524,94 @@ func Test_GetStatement(t *testing.T) {
 	defer ctrl.Finish()
 
 	storage := mockstore.NewMockStorage(ctrl)
-	service := NewAuthService(storage, nil)
+	service := NewAuthService(storage, nil, nil, nil)
 
-	req := &authpb.StatementGet{
-		AccountId: uuid.New().String(),
-	}
-
-	uid, _ := uuid.Parse(req.AccountId)
-	st1 := &authpb.Statement{
-		PaymentId: uuid.New().String(),
-	}
-	st2 := &authpb.Statement{
-		PaymentId: uuid.New().String(),
-	}
-	acc := &types.Account{
-		ID:               uid,
-		FirstName:        "Pasha1",
-		LastName:         "Volkov1",
-		CardNumber:       "4444444444444444",
-		CardExpiryMonth:  "12",
-		CardExpiryYear:   "24",
-		CardSecurityCode: "123",
-		Balance:          0,
-		BlockedMoney:     0,
-		Statement:        []string{st1.PaymentId, st2.PaymentId},
-		CreatedAt:        time.Now(),
+	uid := uuid.New()
+	entries := []*types.StatementEntry{
+		{
+			ID:               11,
+			AccountID:        uid,
+			PaymentID:        uuid.New(),
+			Direction:        types.Debit,
+			Amount:           30,
+			Balance:          sql.NullInt64{Int64: 70, Valid: true},
+			CreatedAt:        time.Now(),
+			CounterpartyID:   uuid.NullUUID{UUID: uuid.New(), Valid: true},
+			CounterpartyName: "Shop Owner",
+			Operation:        "Authorization",
+			Status:           "Approved",
+			Currency:         "rub",
+		},
+		{
+			ID:        12,
+			AccountID: uid,
+			PaymentID: uuid.New(),
+			Direction: types.Credit,
+			Amount:    10,
+			// migrated from the statement array
+			CreatedAt: time.Now(),
+		},
 	}
 
-	streamServer := mockclient.NewMockAuthService_GetStatementServer(ctrl)
-
-	storage.EXPECT().GetAccountByID(context.Background(), &authpb.GetIDRequest{
-		Id: req.AccountId,
-	}).Return(acc, nil).AnyTimes()
-	streamServer.EXPECT().Context().Return(context.Background()).AnyTimes()
-	streamServer.EXPECT().Send(gomock.Any()).DoAndReturn(
-		func(req *authpb.Statement) error {
-			if req.PaymentId == st1.PaymentId {
+	t.Run("Page", func(t *testing.T) {
+		req := &authpb.StatementGet{
+			AccountId: uid.String(),
+			Cursor:    10,
+		}
+		streamServer := mockclient.NewMockAuthService_GetStatementServer(ctrl)
+		streamServer.EXPECT().Context().Return(context.Background()).AnyTimes()
+		storage.EXPECT().GetStatementEntries(context.Background(), req, statementPageSize).Return(entries, nil)
+		sent := []*authpb.Statement{}
+		streamServer.EXPECT().Send(gomock.Any()).DoAndReturn(
+			func(st *authpb.Statement) error {
+				sent = append(sent, st)
 				return nil
-			}
-			if req.PaymentId == st2.PaymentId {
-				return nil
-			}
-			return fmt.Errorf("not found")
-		},
-	).AnyTimes()
+			},
+		).Times(2)
+
+		err := service.GetStatement(req, streamServer)
+		require.NoError(t, err)
+		require.Equal(t, entries[0].PaymentID.String(), sent[0].PaymentId)
+		require.Equal(t, uint64(11), sent[0].Cursor)
+		require.Equal(t, types.Debit, sent[0].Direction)
+		require.Equal(t, int64(-30), sent[0].SignedAmount)
+		require.Equal(t, entries[0].CounterpartyID.UUID.String(), sent[0].CounterpartyId)
+		require.Equal(t, "Shop Owner", sent[0].CounterpartyName)
+		require.Equal(t, "Authorization", sent[0].Operation)
+		require.Equal(t, "Approved", sent[0].Status)
+		require.Equal(t, "rub", sent[0].Currency)
+		require.Equal(t, uint64(70), sent[0].GetBalance())
+		require.Nil(t, sent[1].Balance)
+		require.Equal(t, int64(10), sent[1].SignedAmount)
+		require.Empty(t, sent[1].CounterpartyId)
+	})
 
-	err := service.GetStatement(req, streamServer)
-	require.NoError(t, err)
-	require.Nil(t, err)
+	t.Run("Limit", func(t *testing.T) {
+		req := &authpb.StatementGet{
+			AccountId: uid.String(),
+			Limit:     5000,
+		}
+		streamServer := mockclient.NewMockAuthService_GetStatementServer(ctrl)
+		streamServer.EXPECT().Context().Return(context.Background()).AnyTimes()
+		storage.EXPECT().GetStatementEntries(context.Background(), req, maxStatementPageSize).Return(nil, nil)
+
+		err := service.GetStatement(req, streamServer)
+		require.NoError(t, err)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		streamServer := mockclient.NewMockAuthService_GetStatementServer(ctrl)
+		err := service.GetStatement(&authpb.StatementGet{AccountId: "account"}, streamServer)
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		now := time.Now()
+		err = service.GetStatement(&authpb.StatementGet{
+			AccountId: uid.String(),
+			From:      timestamppb.New(now),
+			To:        timestamppb.New(now.Add(-time.Hour)),
+		}, streamServer)
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
 }
 
 func Test_RefreshTokens(t *testing.T) {
@@ -410,14 +627,18 @@ func Test_RefreshTokens(t *testing.T) {
 	mockStorage := mockstore.NewMockStorage(ctrl)
 	mockRedis := mockstore.NewMockRedisStorage(ctrl)
 
-	mockService := NewAuthService(mockStorage, mockRedis)
+	mockService := NewAuthService(mockStorage, mockRedis, nil, nil)
 
 	req := &authpb.RefreshRequest{
 		RefreshToken: "cookieValue",
 	}
 
 	uid := uuid.New()
-	mockRedis.EXPECT().GetUserID(context.Background(), req.RefreshToken).Return(uid, nil).AnyTimes()
+	mockRedis.EXPECT().RotateSession(context.Background(), req.RefreshToken, 86400).Return(&types.Session{
+		RefreshToken: "refresh-token",
+		UserID:       uid,
+		FamilyID:     "family",
+	}, nil)
 
 	account := &types.Account{
 		ID:               uid,
@@ -429,23 +650,32 @@ func Test_RefreshTokens(t *testing.T) {
 		CardSecurityCode: "924",
 		Balance:          0,
 		BlockedMoney:     0,
-		Statement:        []string{},
 		CreatedAt:        time.Now(),
 	}
 
 	mockStorage.EXPECT().GetAccountByID(context.Background(), gomock.Any()).Return(account, nil).AnyTimes()
-
-	token := "refresh-token"
-	sess := &types.Session{
-		UserID: uid,
-	}
-	mockRedis.EXPECT().CreateSession(context.Background(), gomock.Eq(sess), 86400).Return(token, nil).AnyTimes()
-
+	mockStorage.EXPECT().GetRole(context.Background(), account.ID).Return(types.RoleMerchant, nil)
+	mockStorage.EXPECT().IsEmailVerified(context.Background(), account.ID).Return(true, nil)
 
 	tokens, err := mockService.RefreshTokens(context.Background(), req)
 	require.NoError(t, err)
 	require.Nil(t, err)
 	require.NotNil(t, tokens)
+	require.Equal(t, "refresh-token", tokens.RefreshToken)
+
+	t.Run("Reused token", func(t *testing.T) {
+		mockRedis.EXPECT().RotateSession(context.Background(), req.RefreshToken, 86400).Return(nil, types.ErrTokenReused)
+
+		_, err := mockService.RefreshTokens(context.Background(), req)
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("Unknown token", func(t *testing.T) {
+		mockRedis.EXPECT().RotateSession(context.Background(), "unknown", 86400).Return(nil, types.ErrSessionNotFound)
+
+		_, err := mockService.RefreshTokens(context.Background(), &authpb.RefreshRequest{RefreshToken: "unknown"})
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
 }
 
 func Test_SignIn(t *testing.T) {
@@ -461,11 +691,19 @@ func Test_SignIn(t *testing.T) {
 	mockStorage := mockstore.NewMockStorage(ctrl)
 	mockRedis := mockstore.NewMockRedisStorage(ctrl)
 
-	mockService := NewAuthService(mockStorage, mockRedis)
+	mockService := NewAuthService(mockStorage, mockRedis, nil, nil)
 	uid := uuid.New()
 
 	req := &authpb.LoginRequest{
-		Id: uid.String(),
+		Email:    "pasha@example.com",
+		Password: "password123",
+	}
+	passwordHash, err := utils.HashPassword(req.Password)
+	require.NoError(t, err)
+	cred := &types.Credential{
+		AccountID:    uid,
+		Email:        req.Email,
+		PasswordHash: passwordHash,
 	}
 
 	account := &types.Account{
@@ -478,21 +716,77 @@ func Test_SignIn(t *testing.T) {
 		CardSecurityCode: "924",
 		Balance:          0,
 		BlockedMoney:     0,
-		Statement:        []string{},
 		CreatedAt:        time.Now(),
 	}
 
 	mockStorage.EXPECT().GetAccountByID(context.Background(), gomock.Any()).Return(account, nil).AnyTimes()
+	mockStorage.EXPECT().GetRole(context.Background(), uid).Return(types.RoleCustomer, nil).AnyTimes()
+	mockStorage.EXPECT().IsEmailVerified(context.Background(), uid).Return(true, nil).AnyTimes()
 	sess := &types.Session{
 		UserID: uid,
 	}
 	token := "refresh-token"
 	mockRedis.EXPECT().CreateSession(context.Background(), gomock.Eq(sess), 86400).Return(token, nil).AnyTimes()
+	// no two-factor authentication
+	mockStorage.EXPECT().GetTOTP(context.Background(), uid).Return(nil, sql.ErrNoRows).AnyTimes()
+	// no failed sign-ins before
+	mockRedis.EXPECT().GetLoginAttempts(context.Background(), gomock.Any()).Return(&types.LoginAttempts{}, nil).AnyTimes()
+	mockRedis.EXPECT().ResetLoginAttempts(context.Background(), "email:pasha@example.com").Return(nil).AnyTimes()
+
+	t.Run("Success", func(t *testing.T) {
+		mockStorage.EXPECT().GetCredentialByEmail(context.Background(), req.Email).Return(cred, nil)
+
+		accWithTokens, err := mockService.SignIn(context.Background(), req)
+		require.NoError(t, err)
+		require.Nil(t, err)
+		require.NotNil(t, accWithTokens)
+		require.Equal(t, uid.String(), accWithTokens.Account.Id)
+	})
 
-	accWithTokens, err := mockService.SignIn(context.Background(), req)
-	require.NoError(t, err)
-	require.Nil(t, err)
-	require.NotNil(t, accWithTokens)
+	t.Run("Rehash", func(t *testing.T) {
+		// hash of the old cost is replaced on sign-in
+		oldHash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.MinCost)
+		require.NoError(t, err)
+		mockStorage.EXPECT().GetCredentialByEmail(context.Background(), req.Email).Return(&types.Credential{
+			AccountID:    uid,
+			Email:        req.Email,
+			PasswordHash: string(oldHash),
+		}, nil)
+		mockStorage.EXPECT().UpdatePasswordHash(context.Background(), uid, gomock.Any()).DoAndReturn(
+			func(ctx context.Context, accountID uuid.UUID, passwordHash string) error {
+				require.False(t, utils.NeedsRehash(passwordHash))
+				require.True(t, utils.CheckPassword(passwordHash, req.Password))
+				return nil
+			},
+		)
+
+		_, err = mockService.SignIn(context.Background(), req)
+		require.NoError(t, err)
+	})
+
+	t.Run("Wrong password", func(t *testing.T) {
+		mockStorage.EXPECT().GetCredentialByEmail(context.Background(), req.Email).Return(cred, nil)
+		mockRedis.EXPECT().AddLoginFailure(context.Background(), "email:pasha@example.com", 15*time.Minute).Return(int64(1), nil)
+
+		_, err := mockService.SignIn(context.Background(), &authpb.LoginRequest{
+			Email:    req.Email,
+			Password: "password124",
+		})
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("Unknown email", func(t *testing.T) {
+		mockStorage.EXPECT().GetCredentialByEmail(context.Background(), "unknown@example.com").Return(nil, sql.ErrNoRows)
+		// failures of unknown emails are counted too
+		mockRedis.EXPECT().AddLoginFailure(context.Background(), "email:unknown@example.com", 15*time.Minute).Return(int64(1), nil)
+
+		_, err := mockService.SignIn(context.Background(), &authpb.LoginRequest{
+			Email:    "unknown@example.com",
+			Password: req.Password,
+		})
+		// same error as the wrong password
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
 }
 
 func Test_SignOut(t *testing.T) {
@@ -508,7 +802,7 @@ func Test_SignOut(t *testing.T) {
 	mockStorage := mockstore.NewMockStorage(ctrl)
 	mockRedis := mockstore.NewMockRedisStorage(ctrl)
 
-	mockService := NewAuthService(mockStorage, mockRedis)
+	mockService := NewAuthService(mockStorage, mockRedis, nil, nil)
 
 	cookieValue := "cookieValue"
 
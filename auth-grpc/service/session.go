@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"github.com/Edbeer/auth-grpc/types"
+	authpb "github.com/Edbeer/payment-proto/auth-grpc/proto"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// metadata of the client set by the gateway
+const (
+	deviceHeader    = "x-client-device"
+	userAgentHeader = "x-client-user-agent"
+	ipHeader        = "x-client-ip"
+)
+
+// sessions of the account with the current one marked
+func (s *AuthService) ListSessions(ctx context.Context, req *authpb.ListSessionsRequest) (*authpb.Sessions, error) {
+	accountID, err := uuid.Parse(req.Id)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid account id")
+	}
+	sessions, err := s.redisStorage.ListSessions(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	current := ""
+	if req.RefreshToken != "" {
+		session, err := s.redisStorage.GetSession(ctx, req.RefreshToken)
+		switch {
+		case err == nil:
+			current = session.FamilyID
+		case !errors.Is(err, types.ErrSessionNotFound):
+			return nil, err
+		}
+	}
+	resp := &authpb.Sessions{Sessions: make([]*authpb.Session, 0, len(sessions))}
+	for _, session := range sessions {
+		resp.Sessions = append(resp.Sessions, &authpb.Session{
+			Id:        session.FamilyID,
+			Device:    session.Device,
+			UserAgent: session.UserAgent,
+			Ip:        session.IP,
+			CreatedAt: timestamppb.New(session.CreatedAt),
+			Current:   session.FamilyID == current,
+		})
+	}
+	return resp, nil
+}
+
+// revoke one session of the account, its refresh tokens can't be used
+func (s *AuthService) RevokeSession(ctx context.Context, req *authpb.RevokeSessionRequest) (*authpb.RevokeSessionResponse, error) {
+	accountID, err := uuid.Parse(req.Id)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid account id")
+	}
+	if err := s.redisStorage.RevokeSession(ctx, accountID, req.SessionId); err != nil {
+		if errors.Is(err, types.ErrSessionNotFound) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, err
+	}
+	return &authpb.RevokeSessionResponse{
+		Status: "Session was revoked",
+	}, nil
+}
+
+// revoke all sessions of the account
+func (s *AuthService) RevokeAllSessions(ctx context.Context, req *authpb.RevokeAllSessionsRequest) (*authpb.RevokeSessionResponse, error) {
+	accountID, err := uuid.Parse(req.Id)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid account id")
+	}
+	if err := s.redisStorage.RevokeAllSessions(ctx, accountID); err != nil {
+		return nil, err
+	}
+	return &authpb.RevokeSessionResponse{
+		Status: "Sessions were revoked",
+	}, nil
+}
+
+// new session of the account with the client of the request,
+// direct callers are described by the grpc user agent and peer address
+func clientSession(ctx context.Context, accountID uuid.UUID) *types.Session {
+	session := &types.Session{UserID: accountID}
+	md, _ := metadata.FromIncomingContext(ctx)
+	session.Device = firstValue(md, deviceHeader)
+	session.UserAgent = firstValue(md, userAgentHeader)
+	if session.UserAgent == "" {
+		session.UserAgent = firstValue(md, "user-agent")
+	}
+	session.IP = clientIP(ctx)
+	return session
+}
+
+// address of the client set by the authenticated gateway or of the direct caller,
+// other callers can't choose the address of their sign-ins
+func clientIP(ctx context.Context) string {
+	if service, _ := ServiceFromContext(ctx); service == types.ServiceGateway {
+		md, _ := metadata.FromIncomingContext(ctx)
+		if ip := firstValue(md, ipHeader); ip != "" {
+			return ip
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+			return host
+		}
+	}
+	return ""
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
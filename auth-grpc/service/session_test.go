@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Edbeer/auth-grpc/pkg/utils"
+	mockstore "github.com/Edbeer/auth-grpc/service/mock"
+	"github.com/Edbeer/auth-grpc/types"
+	authpb "github.com/Edbeer/payment-proto/auth-grpc/proto"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func Test_ListSessions(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mockstore.NewMockStorage(ctrl)
+	mockRedis := mockstore.NewMockRedisStorage(ctrl)
+
+	mockService := NewAuthService(mockStorage, mockRedis, nil, nil)
+	uid := uuid.New()
+	now := time.Now()
+
+	sessions := []*types.Session{
+		{UserID: uid, FamilyID: "laptop", Device: "laptop", UserAgent: "Firefox", IP: "10.0.0.1", CreatedAt: now.Add(-time.Hour)},
+		{UserID: uid, FamilyID: "phone", Device: "phone", UserAgent: "Safari", IP: "10.0.0.2", CreatedAt: now},
+	}
+	mockRedis.EXPECT().ListSessions(context.Background(), uid).Return(sessions, nil)
+	mockRedis.EXPECT().GetSession(context.Background(), "refresh-token").Return(sessions[1], nil)
+
+	resp, err := mockService.ListSessions(context.Background(), &authpb.ListSessionsRequest{
+		Id:           uid.String(),
+		RefreshToken: "refresh-token",
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Sessions, 2)
+	require.Equal(t, "laptop", resp.Sessions[0].Id)
+	require.Equal(t, "Firefox", resp.Sessions[0].UserAgent)
+	require.Equal(t, "10.0.0.1", resp.Sessions[0].Ip)
+	require.False(t, resp.Sessions[0].Current)
+	require.True(t, resp.Sessions[1].Current)
+}
+
+func Test_RevokeSession(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mockstore.NewMockStorage(ctrl)
+	mockRedis := mockstore.NewMockRedisStorage(ctrl)
+
+	mockService := NewAuthService(mockStorage, mockRedis, nil, nil)
+	uid := uuid.New()
+
+	t.Run("Revoke", func(t *testing.T) {
+		mockRedis.EXPECT().RevokeSession(context.Background(), uid, "phone").Return(nil)
+
+		resp, err := mockService.RevokeSession(context.Background(), &authpb.RevokeSessionRequest{
+			Id:        uid.String(),
+			SessionId: "phone",
+		})
+		require.NoError(t, err)
+		require.Equal(t, "Session was revoked", resp.Status)
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		mockRedis.EXPECT().RevokeSession(context.Background(), uid, "other").Return(types.ErrSessionNotFound)
+
+		_, err := mockService.RevokeSession(context.Background(), &authpb.RevokeSessionRequest{
+			Id:        uid.String(),
+			SessionId: "other",
+		})
+		require.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("Revoke all", func(t *testing.T) {
+		mockRedis.EXPECT().RevokeAllSessions(context.Background(), uid).Return(nil)
+
+		resp, err := mockService.RevokeAllSessions(context.Background(), &authpb.RevokeAllSessionsRequest{
+			Id: uid.String(),
+		})
+		require.NoError(t, err)
+		require.Equal(t, "Sessions were revoked", resp.Status)
+	})
+}
+
+func Test_ClientSession(t *testing.T) {
+	t.Parallel()
+
+	uid := uuid.New()
+	md := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		deviceHeader, "phone",
+		userAgentHeader, "Safari",
+		ipHeader, "10.0.0.2",
+	))
+	ctx := context.WithValue(md, serviceKey{}, types.ServiceGateway)
+
+	require.Equal(t, &types.Session{
+		UserID:    uid,
+		Device:    "phone",
+		UserAgent: "Safari",
+		IP:        "10.0.0.2",
+	}, clientSession(ctx, uid))
+	// no client metadata
+	require.Equal(t, &types.Session{UserID: uid}, clientSession(context.Background(), uid))
+
+	// address of other callers is their peer
+	ctx = peer.NewContext(md, &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 5000}})
+	require.Equal(t, "192.0.2.1", clientSession(ctx, uid).IP)
+	ctx = context.WithValue(md, serviceKey{}, types.ServicePayment)
+	require.Empty(t, clientSession(ctx, uid).IP)
+}
+
+func Test_GetJWKS(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := NewAuthService(mockstore.NewMockStorage(ctrl), mockstore.NewMockRedisStorage(ctrl), nil, nil)
+
+	jwks, err := mockService.GetJWKS(context.Background(), &authpb.JWKSRequest{})
+	require.NoError(t, err)
+	require.NotEmpty(t, jwks.Keys)
+
+	account := &types.Account{ID: uuid.New(), CardNumber: "4444444444444444"}
+	tokenString, err := utils.CreateJWT(account, types.RoleMerchant, true)
+	require.NoError(t, err)
+
+	// access token is verified with the published key of its kid
+	claims := &utils.AccessClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		for _, jwk := range jwks.Keys {
+			if jwk.Kid == token.Header["kid"] {
+				return ed25519.PublicKey(mustDecodeBase64URL(t, jwk.X)), nil
+			}
+		}
+		return nil, fmt.Errorf("unknown key")
+	}, jwt.WithValidMethods([]string{"EdDSA"}))
+	require.NoError(t, err)
+	require.True(t, token.Valid)
+	require.Equal(t, account.ID.String(), claims.AccountID)
+	require.Equal(t, types.RoleMerchant, claims.Role)
+	require.True(t, claims.VerifyIssuer(utils.JWTIssuer(), true))
+	require.True(t, claims.VerifyAudience(utils.JWTAudience(), true))
+	require.True(t, claims.ExpiresAt.After(time.Now()))
+	require.Equal(t, "OKP", jwks.Keys[0].Kty)
+	require.Equal(t, "Ed25519", jwks.Keys[0].Crv)
+}
+
+func mustDecodeBase64URL(t *testing.T, s string) []byte {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	require.NoError(t, err)
+	return b
+}
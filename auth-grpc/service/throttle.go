@@ -0,0 +1,263 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Edbeer/auth-grpc/types"
+	authpb "github.com/Edbeer/payment-proto/auth-grpc/proto"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Throttling of the sign-ins, failures are counted for the email
+// and for the address of the client
+type LoginPolicy struct {
+	// failures before the delays, every next failure doubles the delay
+	DelayAfter int64
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+	// failures before the lockout of the email and of the address,
+	// one address is shared by the customers behind a NAT
+	AccountLockoutAfter int64
+	IPLockoutAfter      int64
+	LockoutDuration     time.Duration
+	// failures are forgotten after the window without failures
+	FailureWindow time.Duration
+}
+
+func DefaultLoginPolicy() *LoginPolicy {
+	return &LoginPolicy{
+		DelayAfter:          3,
+		BaseDelay:           time.Second,
+		MaxDelay:            30 * time.Second,
+		AccountLockoutAfter: 10,
+		IPLockoutAfter:      50,
+		LockoutDuration:     15 * time.Minute,
+		FailureWindow:       15 * time.Minute,
+	}
+}
+
+// LoginPolicyFromEnv reads LOGIN_DELAY_AFTER, LOGIN_LOCKOUT_AFTER,
+// LOGIN_IP_LOCKOUT_AFTER, LOGIN_LOCKOUT_DURATION and LOGIN_FAILURE_WINDOW
+// over the default policy, zero thresholds turn the step off
+func LoginPolicyFromEnv() (*LoginPolicy, error) {
+	policy := DefaultLoginPolicy()
+	counts := map[string]*int64{
+		"LOGIN_DELAY_AFTER":      &policy.DelayAfter,
+		"LOGIN_LOCKOUT_AFTER":    &policy.AccountLockoutAfter,
+		"LOGIN_IP_LOCKOUT_AFTER": &policy.IPLockoutAfter,
+	}
+	for name, count := range counts {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid %s %q", name, value)
+		}
+		*count = n
+	}
+	durations := map[string]*time.Duration{
+		"LOGIN_LOCKOUT_DURATION": &policy.LockoutDuration,
+		"LOGIN_FAILURE_WINDOW":   &policy.FailureWindow,
+	}
+	for name, duration := range durations {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid %s %q", name, value)
+		}
+		*duration = d
+	}
+	return policy, nil
+}
+
+// Set throttling of the sign-ins
+func (s *AuthService) SetLoginPolicy(policy *LoginPolicy) {
+	s.loginPolicy = policy
+}
+
+// delay after the failures in a row
+func (p *LoginPolicy) delay(failures int64) time.Duration {
+	if p.DelayAfter == 0 || failures < p.DelayAfter {
+		return 0
+	}
+	delay := p.BaseDelay
+	for i := p.DelayAfter; i < failures && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		return p.MaxDelay
+	}
+	return delay
+}
+
+// email or address of the sign-ins
+type loginSubject struct {
+	key   string
+	email string
+	ip    string
+}
+
+func emailSubject(email string) loginSubject {
+	email = strings.TrimSpace(email)
+	return loginSubject{key: "email:" + strings.ToLower(email), email: email}
+}
+
+func ipSubject(ip string) loginSubject {
+	return loginSubject{key: "ip:" + ip, ip: ip}
+}
+
+// failures before the lockout of the subject
+func (sub loginSubject) lockoutAfter(p *LoginPolicy) int64 {
+	if sub.ip != "" {
+		return p.IPLockoutAfter
+	}
+	return p.AccountLockoutAfter
+}
+
+// email of the sign-in and the address of the client if known
+func loginSubjects(ctx context.Context, email string) []loginSubject {
+	subjects := []loginSubject{emailSubject(email)}
+	if ip := clientIP(ctx); ip != "" {
+		subjects = append(subjects, ipSubject(ip))
+	}
+	return subjects
+}
+
+// sign-ins of locked subjects and sign-ins before the delay are refused
+func (s *AuthService) checkLoginThrottle(ctx context.Context, subjects []loginSubject) error {
+	now := time.Now()
+	var wait time.Duration
+	locked := false
+	for _, sub := range subjects {
+		attempts, err := s.redisStorage.GetLoginAttempts(ctx, sub.key)
+		if err != nil {
+			return err
+		}
+		if d := attempts.LockedUntil.Sub(now); d > 0 {
+			locked = true
+			if d > wait {
+				wait = d
+			}
+			continue
+		}
+		if attempts.Failures == 0 {
+			continue
+		}
+		if d := attempts.LastFailure.Add(s.loginPolicy.delay(attempts.Failures)).Sub(now); d > wait {
+			wait = d
+		}
+	}
+	// whole seconds up
+	if rest := wait % time.Second; rest > 0 {
+		wait += time.Second - rest
+	}
+	if locked {
+		return status.Errorf(codes.ResourceExhausted, "too many failed sign-ins, try again in %s", wait)
+	}
+	if wait > 0 {
+		return status.Errorf(codes.ResourceExhausted, "sign-in is delayed after failed attempts, try again in %s", wait)
+	}
+	return nil
+}
+
+// count the failure of the subjects and lock them after the threshold,
+// returns the error of the sign-in
+func (s *AuthService) loginFailed(ctx context.Context, subjects []loginSubject, signInErr error) error {
+	for _, sub := range subjects {
+		failures, err := s.redisStorage.AddLoginFailure(ctx, sub.key, s.loginPolicy.FailureWindow)
+		if err != nil {
+			return err
+		}
+		if after := sub.lockoutAfter(s.loginPolicy); after == 0 || failures < after {
+			continue
+		}
+		if err := s.redisStorage.LockLogin(ctx, sub.key, s.loginPolicy.LockoutDuration); err != nil {
+			return err
+		}
+		event := &types.SecurityEvent{
+			Type:   types.EventLoginLocked,
+			Email:  sub.email,
+			IP:     sub.ip,
+			Detail: fmt.Sprintf("%d failed sign-ins, locked for %s", failures, s.loginPolicy.LockoutDuration),
+			Time:   time.Now(),
+		}
+		if sub.email != "" {
+			cred, err := s.storage.GetCredentialByEmail(ctx, sub.email)
+			if err == nil {
+				event.AccountID = cred.AccountID.String()
+			}
+		}
+		s.emit(ctx, event)
+	}
+	return signInErr
+}
+
+// failures of the email are forgotten, failures of the address are kept
+// so that its own account doesn't reset the guesses of others
+func (s *AuthService) loginSucceeded(ctx context.Context, subjects []loginSubject) error {
+	return s.redisStorage.ResetLoginAttempts(ctx, subjects[0].key)
+}
+
+// Lift the lockout of the account and of the address by admins
+func (s *AuthService) UnlockAccount(ctx context.Context, req *authpb.UnlockAccountRequest) (*authpb.UnlockAccountResponse, error) {
+	accountID, err := uuid.Parse(req.Id)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid account id")
+	}
+	ip := strings.TrimSpace(req.Ip)
+	if ip != "" && net.ParseIP(ip) == nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid ip")
+	}
+	cred, err := s.storage.GetCredential(ctx, accountID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, status.Error(codes.NotFound, "account has no credentials")
+		}
+		return nil, err
+	}
+	subjects := []string{emailSubject(cred.Email).key}
+	if ip != "" {
+		subjects = append(subjects, ipSubject(ip).key)
+	}
+	if err := s.redisStorage.ResetLoginAttempts(ctx, subjects...); err != nil {
+		return nil, err
+	}
+
+	event := &types.SecurityEvent{
+		Type:      types.EventLoginUnlocked,
+		AccountID: accountID.String(),
+		Email:     cred.Email,
+		IP:        ip,
+		Time:      time.Now(),
+	}
+	if caller, ok := CallerFromContext(ctx); ok {
+		event.Actor = caller.AccountID
+	}
+	s.emit(ctx, event)
+	return &authpb.UnlockAccountResponse{
+		Status: "Account was unlocked",
+	}, nil
+}
+
+// events don't fail the request
+func (s *AuthService) emit(ctx context.Context, event *types.SecurityEvent) {
+	if err := s.events.Emit(ctx, event); err != nil {
+		log.Printf("emit security event %s: %v", event.Type, err)
+	}
+}
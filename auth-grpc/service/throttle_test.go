@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/Edbeer/auth-grpc/pkg/utils"
+	mockstore "github.com/Edbeer/auth-grpc/service/mock"
+	"github.com/Edbeer/auth-grpc/types"
+	authpb "github.com/Edbeer/payment-proto/auth-grpc/proto"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func Test_LoginDelay(t *testing.T) {
+	t.Parallel()
+
+	policy := DefaultLoginPolicy()
+	require.Zero(t, policy.delay(0))
+	require.Zero(t, policy.delay(2))
+	require.Equal(t, time.Second, policy.delay(3))
+	require.Equal(t, 2*time.Second, policy.delay(4))
+	require.Equal(t, 16*time.Second, policy.delay(7))
+	require.Equal(t, 30*time.Second, policy.delay(8))
+	require.Equal(t, 30*time.Second, policy.delay(1000))
+	// no delays
+	require.Zero(t, (&LoginPolicy{}).delay(1000))
+}
+
+func Test_LoginPolicyFromEnv(t *testing.T) {
+	t.Setenv("LOGIN_LOCKOUT_AFTER", "5")
+	t.Setenv("LOGIN_IP_LOCKOUT_AFTER", "0")
+	t.Setenv("LOGIN_LOCKOUT_DURATION", "1h")
+
+	policy, err := LoginPolicyFromEnv()
+	require.NoError(t, err)
+	require.Equal(t, int64(5), policy.AccountLockoutAfter)
+	require.Zero(t, policy.IPLockoutAfter)
+	require.Equal(t, time.Hour, policy.LockoutDuration)
+	require.Equal(t, int64(3), policy.DelayAfter)
+
+	t.Setenv("LOGIN_FAILURE_WINDOW", "soon")
+	_, err = LoginPolicyFromEnv()
+	require.Error(t, err)
+}
+
+func Test_SignInThrottle(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mockstore.NewMockStorage(ctrl)
+	mockRedis := mockstore.NewMockRedisStorage(ctrl)
+	mockEvents := mockstore.NewMockSecurityEvents(ctrl)
+	mockService := NewAuthService(mockStorage, mockRedis, mockEvents, nil)
+
+	uid := uuid.New()
+	passwordHash, err := utils.HashPassword("password123")
+	require.NoError(t, err)
+	cred := &types.Credential{AccountID: uid, Email: "pasha@example.com", PasswordHash: passwordHash}
+	// address of the client set by the gateway
+	ctx := context.WithValue(metadata.NewIncomingContext(context.Background(), metadata.Pairs(ipHeader, "10.0.0.1")),
+		serviceKey{}, types.ServiceGateway)
+	req := &authpb.LoginRequest{Email: "Pasha@example.com", Password: "password124"}
+
+	t.Run("Locked", func(t *testing.T) {
+		mockRedis.EXPECT().GetLoginAttempts(ctx, "email:pasha@example.com").Return(&types.LoginAttempts{
+			LockedUntil: time.Now().Add(10 * time.Minute),
+		}, nil)
+		mockRedis.EXPECT().GetLoginAttempts(ctx, "ip:10.0.0.1").Return(&types.LoginAttempts{}, nil)
+
+		// the password is not checked
+		_, err := mockService.SignIn(ctx, req)
+		require.Equal(t, codes.ResourceExhausted, status.Code(err))
+		require.Contains(t, status.Convert(err).Message(), "too many failed sign-ins")
+	})
+
+	t.Run("Delayed", func(t *testing.T) {
+		mockRedis.EXPECT().GetLoginAttempts(ctx, "email:pasha@example.com").Return(&types.LoginAttempts{}, nil)
+		mockRedis.EXPECT().GetLoginAttempts(ctx, "ip:10.0.0.1").Return(&types.LoginAttempts{
+			Failures:    5,
+			LastFailure: time.Now(),
+		}, nil)
+
+		_, err := mockService.SignIn(ctx, req)
+		require.Equal(t, codes.ResourceExhausted, status.Code(err))
+		require.Contains(t, status.Convert(err).Message(), "try again in 4s")
+	})
+
+	t.Run("Lockout", func(t *testing.T) {
+		mockRedis.EXPECT().GetLoginAttempts(ctx, gomock.Any()).Return(&types.LoginAttempts{
+			Failures:    9,
+			LastFailure: time.Now().Add(-time.Minute),
+		}, nil).Times(2)
+		mockStorage.EXPECT().GetCredentialByEmail(ctx, "Pasha@example.com").Return(cred, nil).Times(2)
+		mockRedis.EXPECT().AddLoginFailure(ctx, "email:pasha@example.com", 15*time.Minute).Return(int64(10), nil)
+		mockRedis.EXPECT().AddLoginFailure(ctx, "ip:10.0.0.1", 15*time.Minute).Return(int64(10), nil)
+		// the address is locked after more failures
+		mockRedis.EXPECT().LockLogin(ctx, "email:pasha@example.com", 15*time.Minute).Return(nil)
+		mockEvents.EXPECT().Emit(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, event *types.SecurityEvent) error {
+			require.Equal(t, types.EventLoginLocked, event.Type)
+			require.Equal(t, uid.String(), event.AccountID)
+			require.Equal(t, "Pasha@example.com", event.Email)
+			return nil
+		})
+
+		_, err := mockService.SignIn(ctx, req)
+		require.Equal(t, errInvalidCredentials, err)
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		mockRedis.EXPECT().GetLoginAttempts(ctx, gomock.Any()).Return(&types.LoginAttempts{}, nil).Times(2)
+		mockStorage.EXPECT().GetCredentialByEmail(ctx, "Pasha@example.com").Return(cred, nil)
+		// failures of the address are kept
+		mockRedis.EXPECT().ResetLoginAttempts(ctx, "email:pasha@example.com").Return(nil)
+		mockStorage.EXPECT().GetTOTP(ctx, uid).Return(nil, sql.ErrNoRows)
+		mockStorage.EXPECT().GetAccountByID(ctx, gomock.Any()).Return(&types.Account{ID: uid}, nil)
+		mockStorage.EXPECT().GetRole(ctx, uid).Return(types.RoleCustomer, nil)
+		mockStorage.EXPECT().IsEmailVerified(ctx, uid).Return(true, nil)
+		mockRedis.EXPECT().CreateSession(ctx, gomock.Any(), 86400).Return("refresh-token", nil)
+
+		resp, err := mockService.SignIn(ctx, &authpb.LoginRequest{Email: req.Email, Password: "password123"})
+		require.NoError(t, err)
+		require.Equal(t, "refresh-token", resp.RefreshToken)
+	})
+}
+
+func Test_UnlockAccount(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mockstore.NewMockStorage(ctrl)
+	mockRedis := mockstore.NewMockRedisStorage(ctrl)
+	mockEvents := mockstore.NewMockSecurityEvents(ctrl)
+	mockService := NewAuthService(mockStorage, mockRedis, mockEvents, nil)
+
+	uid := uuid.New()
+	admin := &Caller{AccountID: uuid.New().String(), Role: types.RoleAdmin}
+	ctx := context.WithValue(context.Background(), callerKey{}, admin)
+
+	t.Run("Unlock", func(t *testing.T) {
+		mockStorage.EXPECT().GetCredential(ctx, uid).Return(&types.Credential{AccountID: uid, Email: "Pasha@example.com"}, nil)
+		mockRedis.EXPECT().ResetLoginAttempts(ctx, "email:pasha@example.com", "ip:10.0.0.1").Return(nil)
+		mockEvents.EXPECT().Emit(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, event *types.SecurityEvent) error {
+			require.Equal(t, types.EventLoginUnlocked, event.Type)
+			require.Equal(t, uid.String(), event.AccountID)
+			require.Equal(t, admin.AccountID, event.Actor)
+			return nil
+		})
+
+		resp, err := mockService.UnlockAccount(ctx, &authpb.UnlockAccountRequest{
+			Id: uid.String(),
+			Ip: "10.0.0.1",
+		})
+		require.NoError(t, err)
+		require.Equal(t, "Account was unlocked", resp.Status)
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		mockStorage.EXPECT().GetCredential(ctx, uid).Return(nil, sql.ErrNoRows)
+
+		_, err := mockService.UnlockAccount(ctx, &authpb.UnlockAccountRequest{Id: uid.String()})
+		require.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("Invalid ip", func(t *testing.T) {
+		_, err := mockService.UnlockAccount(ctx, &authpb.UnlockAccountRequest{Id: uid.String(), Ip: "local"})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
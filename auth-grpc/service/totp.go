@@ -0,0 +1,216 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Edbeer/auth-grpc/pkg/utils"
+	"github.com/Edbeer/auth-grpc/types"
+	authpb "github.com/Edbeer/payment-proto/auth-grpc/proto"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// seconds to enter the second factor after the password
+	mfaChallengeTTL = 300
+	// codes tried for one mfa challenge
+	maxMFAAttempts = 5
+	// recovery codes issued when TOTP is enabled
+	recoveryCodeCount = 10
+)
+
+var errInvalidCode = status.Error(codes.Unauthenticated, "invalid code")
+
+// second step of the sign-in, TOTP or recovery code of the mfa challenge
+func (s *AuthService) SignInMFA(ctx context.Context, req *authpb.MFASignInRequest) (*authpb.AccountWithTokens, error) {
+	accountID, attempts, err := s.redisStorage.GetMFAChallenge(ctx, req.MfaToken)
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, status.Error(codes.Unauthenticated, "invalid or expired mfa token")
+		}
+		return nil, err
+	}
+	if attempts > maxMFAAttempts {
+		s.redisStorage.DeleteMFAChallenge(ctx, req.MfaToken)
+		return nil, status.Error(codes.Unauthenticated, "too many attempts, sign in again")
+	}
+	// codes are throttled with the passwords, new challenges
+	// don't give more guesses
+	cred, err := s.storage.GetCredential(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	subjects := loginSubjects(ctx, cred.Email)
+	if err := s.checkLoginThrottle(ctx, subjects); err != nil {
+		return nil, err
+	}
+	totp, err := s.storage.GetTOTP(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkSecondFactor(ctx, totp, req.Code); err != nil {
+		if err == errInvalidCode {
+			return nil, s.loginFailed(ctx, subjects, err)
+		}
+		return nil, err
+	}
+	if err := s.redisStorage.DeleteMFAChallenge(ctx, req.MfaToken); err != nil {
+		return nil, err
+	}
+	if err := s.loginSucceeded(ctx, subjects); err != nil {
+		return nil, err
+	}
+	return s.issueTokens(ctx, accountID)
+}
+
+// start TOTP enrollment, the secret is enabled by VerifyTOTP
+func (s *AuthService) EnrollTOTP(ctx context.Context, req *authpb.EnrollTOTPRequest) (*authpb.EnrollTOTPResponse, error) {
+	accountID, err := uuid.Parse(req.Id)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid account id")
+	}
+	// label of the secret in authenticator apps
+	label := accountID.String()
+	cred, err := s.storage.GetCredential(ctx, accountID)
+	switch {
+	case err == nil:
+		label = cred.Email
+	case !errors.Is(err, sql.ErrNoRows):
+		return nil, err
+	}
+	secret, err := utils.NewTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.storage.SaveTOTPSecret(ctx, accountID, secret); err != nil {
+		if errors.Is(err, types.ErrTOTPEnabled) {
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
+		return nil, err
+	}
+	return &authpb.EnrollTOTPResponse{
+		Secret: secret,
+		Uri:    utils.TOTPURI(secret, label),
+	}, nil
+}
+
+// enable TOTP with the first code of the enrolled secret, returns recovery codes
+func (s *AuthService) VerifyTOTP(ctx context.Context, req *authpb.VerifyTOTPRequest) (*authpb.VerifyTOTPResponse, error) {
+	accountID, err := uuid.Parse(req.Id)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid account id")
+	}
+	totp, err := s.storage.GetTOTP(ctx, accountID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, status.Error(codes.FailedPrecondition, "two-factor authentication is not enrolled")
+		}
+		return nil, err
+	}
+	if totp.Enabled {
+		return nil, status.Error(codes.FailedPrecondition, types.ErrTOTPEnabled.Error())
+	}
+	if err := s.checkTOTP(ctx, totp, req.Code); err != nil {
+		return nil, err
+	}
+	recoveryCodes, err := utils.NewRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([]string, 0, len(recoveryCodes))
+	for _, code := range recoveryCodes {
+		hashes = append(hashes, utils.HashRecoveryCode(code))
+	}
+	if err := s.storage.EnableTOTP(ctx, accountID, hashes); err != nil {
+		return nil, err
+	}
+	return &authpb.VerifyTOTPResponse{
+		Status:        "Two-factor authentication was enabled",
+		RecoveryCodes: recoveryCodes,
+	}, nil
+}
+
+// disable TOTP with the password and the second factor
+func (s *AuthService) DisableTOTP(ctx context.Context, req *authpb.DisableTOTPRequest) (*authpb.DisableTOTPResponse, error) {
+	accountID, err := uuid.Parse(req.Id)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid account id")
+	}
+	cred, err := s.storage.GetCredential(ctx, accountID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, status.Error(codes.NotFound, "account has no credentials")
+		}
+		return nil, err
+	}
+	if !utils.CheckPassword(cred.PasswordHash, req.Password) {
+		return nil, errInvalidCredentials
+	}
+	totp, err := s.storage.GetTOTP(ctx, accountID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err != nil || !totp.Enabled {
+		return nil, status.Error(codes.FailedPrecondition, "two-factor authentication is not enabled")
+	}
+	if err := s.checkSecondFactor(ctx, totp, req.Code); err != nil {
+		return nil, err
+	}
+	if err := s.storage.DisableTOTP(ctx, accountID); err != nil {
+		return nil, err
+	}
+	return &authpb.DisableTOTPResponse{
+		Status: "Two-factor authentication was disabled",
+	}, nil
+}
+
+// mfa token of the sign-in if the account has TOTP enabled, empty otherwise
+func (s *AuthService) mfaChallenge(ctx context.Context, accountID uuid.UUID) (string, error) {
+	totp, err := s.storage.GetTOTP(ctx, accountID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
+		}
+		return "", err
+	}
+	if !totp.Enabled {
+		return "", nil
+	}
+	return s.redisStorage.CreateMFAChallenge(ctx, accountID, mfaChallengeTTL)
+}
+
+// TOTP code or an unused recovery code
+func (s *AuthService) checkSecondFactor(ctx context.Context, totp *types.TOTP, code string) error {
+	if err := s.checkTOTP(ctx, totp, code); err == nil || !errors.Is(err, errInvalidCode) {
+		return err
+	}
+	ok, err := s.storage.UseRecoveryCode(ctx, totp.AccountID, utils.HashRecoveryCode(code))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errInvalidCode
+	}
+	return nil
+}
+
+// TOTP code valid now and not accepted before
+func (s *AuthService) checkTOTP(ctx context.Context, totp *types.TOTP, code string) error {
+	step, ok := utils.ValidateTOTP(totp.Secret, code, time.Now())
+	if !ok {
+		return errInvalidCode
+	}
+	ok, err := s.storage.UseTOTPStep(ctx, totp.AccountID, step)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errInvalidCode
+	}
+	return nil
+}
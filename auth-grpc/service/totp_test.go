@@ -0,0 +1,314 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/Edbeer/auth-grpc/pkg/utils"
+	mockstore "github.com/Edbeer/auth-grpc/service/mock"
+	"github.com/Edbeer/auth-grpc/types"
+	authpb "github.com/Edbeer/payment-proto/auth-grpc/proto"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func Test_SignInMFA(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mockstore.NewMockStorage(ctrl)
+	mockRedis := mockstore.NewMockRedisStorage(ctrl)
+
+	mockService := NewAuthService(mockStorage, mockRedis, nil, nil)
+	uid := uuid.New()
+
+	totp := newTestTOTP(t, uid, true)
+	code := currentTOTPCode(t, totp)
+	cred := &types.Credential{AccountID: uid, Email: "pasha@example.com"}
+
+	t.Run("Challenge", func(t *testing.T) {
+		passwordHash, err := utils.HashPassword("password123")
+		require.NoError(t, err)
+		mockStorage.EXPECT().GetCredentialByEmail(context.Background(), "pasha@example.com").Return(&types.Credential{
+			AccountID:    uid,
+			Email:        "pasha@example.com",
+			PasswordHash: passwordHash,
+		}, nil)
+		mockStorage.EXPECT().GetTOTP(context.Background(), uid).Return(totp, nil)
+		mockRedis.EXPECT().CreateMFAChallenge(context.Background(), uid, mfaChallengeTTL).Return("mfa-token", nil)
+		// failures are kept until the second factor
+		mockRedis.EXPECT().GetLoginAttempts(context.Background(), "email:pasha@example.com").Return(&types.LoginAttempts{}, nil)
+
+		resp, err := mockService.SignIn(context.Background(), &authpb.LoginRequest{
+			Email:    "pasha@example.com",
+			Password: "password123",
+		})
+		require.NoError(t, err)
+		// no account and tokens before the second factor
+		require.True(t, resp.MfaRequired)
+		require.Equal(t, "mfa-token", resp.MfaToken)
+		require.Nil(t, resp.Account)
+		require.Empty(t, resp.AccessToken)
+		require.Empty(t, resp.RefreshToken)
+	})
+
+	t.Run("TOTP", func(t *testing.T) {
+		mockRedis.EXPECT().GetMFAChallenge(context.Background(), "mfa-token").Return(uid, int64(1), nil)
+		mockStorage.EXPECT().GetCredential(context.Background(), uid).Return(cred, nil)
+		mockRedis.EXPECT().GetLoginAttempts(context.Background(), "email:pasha@example.com").Return(&types.LoginAttempts{}, nil)
+		mockStorage.EXPECT().GetTOTP(context.Background(), uid).Return(totp, nil)
+		mockStorage.EXPECT().UseTOTPStep(context.Background(), uid, gomock.Any()).Return(true, nil)
+		mockRedis.EXPECT().DeleteMFAChallenge(context.Background(), "mfa-token").Return(nil)
+		mockRedis.EXPECT().ResetLoginAttempts(context.Background(), "email:pasha@example.com").Return(nil)
+		mockStorage.EXPECT().GetAccountByID(context.Background(), gomock.Any()).Return(&types.Account{ID: uid}, nil)
+		mockStorage.EXPECT().GetRole(context.Background(), uid).Return(types.RoleCustomer, nil)
+		mockStorage.EXPECT().IsEmailVerified(context.Background(), uid).Return(true, nil)
+		mockRedis.EXPECT().CreateSession(context.Background(), &types.Session{UserID: uid}, 86400).Return("refresh-token", nil)
+
+		resp, err := mockService.SignInMFA(context.Background(), &authpb.MFASignInRequest{
+			MfaToken: "mfa-token",
+			Code:     code,
+		})
+		require.NoError(t, err)
+		require.Equal(t, uid.String(), resp.Account.Id)
+		require.Equal(t, "refresh-token", resp.RefreshToken)
+	})
+
+	t.Run("Replayed TOTP", func(t *testing.T) {
+		mockRedis.EXPECT().GetMFAChallenge(context.Background(), "mfa-token").Return(uid, int64(1), nil)
+		mockStorage.EXPECT().GetCredential(context.Background(), uid).Return(cred, nil)
+		mockRedis.EXPECT().GetLoginAttempts(context.Background(), "email:pasha@example.com").Return(&types.LoginAttempts{}, nil)
+		mockStorage.EXPECT().GetTOTP(context.Background(), uid).Return(totp, nil)
+		mockStorage.EXPECT().UseTOTPStep(context.Background(), uid, gomock.Any()).Return(false, nil)
+		mockStorage.EXPECT().UseRecoveryCode(context.Background(), uid, utils.HashRecoveryCode(code)).Return(false, nil)
+		// counted as a failed sign-in of the email
+		mockRedis.EXPECT().AddLoginFailure(context.Background(), "email:pasha@example.com", DefaultLoginPolicy().FailureWindow).Return(int64(1), nil)
+
+		_, err := mockService.SignInMFA(context.Background(), &authpb.MFASignInRequest{
+			MfaToken: "mfa-token",
+			Code:     code,
+		})
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("Recovery code", func(t *testing.T) {
+		mockRedis.EXPECT().GetMFAChallenge(context.Background(), "mfa-token").Return(uid, int64(2), nil)
+		mockStorage.EXPECT().GetCredential(context.Background(), uid).Return(cred, nil)
+		mockRedis.EXPECT().GetLoginAttempts(context.Background(), "email:pasha@example.com").Return(&types.LoginAttempts{}, nil)
+		mockStorage.EXPECT().GetTOTP(context.Background(), uid).Return(totp, nil)
+		mockStorage.EXPECT().UseRecoveryCode(context.Background(), uid, utils.HashRecoveryCode("1a2b3-c4d5e")).Return(true, nil)
+		mockRedis.EXPECT().DeleteMFAChallenge(context.Background(), "mfa-token").Return(nil)
+		mockRedis.EXPECT().ResetLoginAttempts(context.Background(), "email:pasha@example.com").Return(nil)
+		mockStorage.EXPECT().GetAccountByID(context.Background(), gomock.Any()).Return(&types.Account{ID: uid}, nil)
+		mockStorage.EXPECT().GetRole(context.Background(), uid).Return(types.RoleCustomer, nil)
+		mockStorage.EXPECT().IsEmailVerified(context.Background(), uid).Return(true, nil)
+		mockRedis.EXPECT().CreateSession(context.Background(), &types.Session{UserID: uid}, 86400).Return("refresh-token", nil)
+
+		_, err := mockService.SignInMFA(context.Background(), &authpb.MFASignInRequest{
+			MfaToken: "mfa-token",
+			Code:     "1a2b3-c4d5e",
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("Locked", func(t *testing.T) {
+		mockRedis.EXPECT().GetMFAChallenge(context.Background(), "mfa-token").Return(uid, int64(1), nil)
+		mockStorage.EXPECT().GetCredential(context.Background(), uid).Return(cred, nil)
+		mockRedis.EXPECT().GetLoginAttempts(context.Background(), "email:pasha@example.com").Return(&types.LoginAttempts{
+			LockedUntil: time.Now().Add(10 * time.Minute),
+		}, nil)
+
+		// the code is not checked
+		_, err := mockService.SignInMFA(context.Background(), &authpb.MFASignInRequest{
+			MfaToken: "mfa-token",
+			Code:     code,
+		})
+		require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	})
+
+	t.Run("Too many attempts", func(t *testing.T) {
+		mockRedis.EXPECT().GetMFAChallenge(context.Background(), "mfa-token").Return(uid, int64(maxMFAAttempts+1), nil)
+		mockRedis.EXPECT().DeleteMFAChallenge(context.Background(), "mfa-token").Return(nil)
+
+		_, err := mockService.SignInMFA(context.Background(), &authpb.MFASignInRequest{
+			MfaToken: "mfa-token",
+			Code:     code,
+		})
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("Expired challenge", func(t *testing.T) {
+		mockRedis.EXPECT().GetMFAChallenge(context.Background(), "expired").Return(uuid.Nil, int64(0), redis.Nil)
+
+		_, err := mockService.SignInMFA(context.Background(), &authpb.MFASignInRequest{
+			MfaToken: "expired",
+			Code:     code,
+		})
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+}
+
+func Test_EnrollTOTP(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mockstore.NewMockStorage(ctrl)
+	mockRedis := mockstore.NewMockRedisStorage(ctrl)
+
+	mockService := NewAuthService(mockStorage, mockRedis, nil, nil)
+	uid := uuid.New()
+
+	t.Run("Enroll", func(t *testing.T) {
+		mockStorage.EXPECT().GetCredential(context.Background(), uid).Return(&types.Credential{
+			AccountID: uid,
+			Email:     "pasha@example.com",
+		}, nil)
+		mockStorage.EXPECT().SaveTOTPSecret(context.Background(), uid, gomock.Any()).Return(nil)
+
+		resp, err := mockService.EnrollTOTP(context.Background(), &authpb.EnrollTOTPRequest{Id: uid.String()})
+		require.NoError(t, err)
+		require.NotEmpty(t, resp.Secret)
+		require.Contains(t, resp.Uri, "otpauth://totp/Payment:pasha@example.com?")
+		require.Contains(t, resp.Uri, "secret="+resp.Secret)
+	})
+
+	t.Run("Already enabled", func(t *testing.T) {
+		mockStorage.EXPECT().GetCredential(context.Background(), uid).Return(nil, sql.ErrNoRows)
+		mockStorage.EXPECT().SaveTOTPSecret(context.Background(), uid, gomock.Any()).Return(types.ErrTOTPEnabled)
+
+		_, err := mockService.EnrollTOTP(context.Background(), &authpb.EnrollTOTPRequest{Id: uid.String()})
+		require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+}
+
+func Test_VerifyTOTP(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mockstore.NewMockStorage(ctrl)
+	mockRedis := mockstore.NewMockRedisStorage(ctrl)
+
+	mockService := NewAuthService(mockStorage, mockRedis, nil, nil)
+	uid := uuid.New()
+
+	totp := newTestTOTP(t, uid, false)
+
+	t.Run("Verify", func(t *testing.T) {
+		mockStorage.EXPECT().GetTOTP(context.Background(), uid).Return(totp, nil)
+		mockStorage.EXPECT().UseTOTPStep(context.Background(), uid, gomock.Any()).Return(true, nil)
+		var hashes []string
+		mockStorage.EXPECT().EnableTOTP(context.Background(), uid, gomock.Any()).DoAndReturn(
+			func(ctx context.Context, accountID uuid.UUID, codeHashes []string) error {
+				hashes = codeHashes
+				return nil
+			},
+		)
+
+		resp, err := mockService.VerifyTOTP(context.Background(), &authpb.VerifyTOTPRequest{
+			Id:   uid.String(),
+			Code: currentTOTPCode(t, totp),
+		})
+		require.NoError(t, err)
+		require.Len(t, resp.RecoveryCodes, recoveryCodeCount)
+		// only hashes are stored
+		for i, code := range resp.RecoveryCodes {
+			require.Equal(t, utils.HashRecoveryCode(code), hashes[i])
+		}
+	})
+
+	t.Run("Invalid code", func(t *testing.T) {
+		mockStorage.EXPECT().GetTOTP(context.Background(), uid).Return(totp, nil)
+
+		_, err := mockService.VerifyTOTP(context.Background(), &authpb.VerifyTOTPRequest{
+			Id:   uid.String(),
+			Code: "12345",
+		})
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("Not enrolled", func(t *testing.T) {
+		mockStorage.EXPECT().GetTOTP(context.Background(), uid).Return(nil, sql.ErrNoRows)
+
+		_, err := mockService.VerifyTOTP(context.Background(), &authpb.VerifyTOTPRequest{
+			Id:   uid.String(),
+			Code: "123456",
+		})
+		require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+}
+
+func Test_DisableTOTP(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mockstore.NewMockStorage(ctrl)
+	mockRedis := mockstore.NewMockRedisStorage(ctrl)
+
+	mockService := NewAuthService(mockStorage, mockRedis, nil, nil)
+	uid := uuid.New()
+
+	totp := newTestTOTP(t, uid, true)
+	passwordHash, err := utils.HashPassword("password123")
+	require.NoError(t, err)
+	cred := &types.Credential{
+		AccountID:    uid,
+		Email:        "pasha@example.com",
+		PasswordHash: passwordHash,
+	}
+
+	t.Run("Disable", func(t *testing.T) {
+		mockStorage.EXPECT().GetCredential(context.Background(), uid).Return(cred, nil)
+		mockStorage.EXPECT().GetTOTP(context.Background(), uid).Return(totp, nil)
+		mockStorage.EXPECT().UseTOTPStep(context.Background(), uid, gomock.Any()).Return(true, nil)
+		mockStorage.EXPECT().DisableTOTP(context.Background(), uid).Return(nil)
+
+		resp, err := mockService.DisableTOTP(context.Background(), &authpb.DisableTOTPRequest{
+			Id:       uid.String(),
+			Password: "password123",
+			Code:     currentTOTPCode(t, totp),
+		})
+		require.NoError(t, err)
+		require.Equal(t, "Two-factor authentication was disabled", resp.Status)
+	})
+
+	t.Run("Wrong password", func(t *testing.T) {
+		mockStorage.EXPECT().GetCredential(context.Background(), uid).Return(cred, nil)
+
+		_, err := mockService.DisableTOTP(context.Background(), &authpb.DisableTOTPRequest{
+			Id:       uid.String(),
+			Password: "password124",
+			Code:     currentTOTPCode(t, totp),
+		})
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+}
+
+func newTestTOTP(t *testing.T, accountID uuid.UUID, enabled bool) *types.TOTP {
+	secret, err := utils.NewTOTPSecret()
+	require.NoError(t, err)
+	return &types.TOTP{
+		AccountID: accountID,
+		Secret:    secret,
+		Enabled:   enabled,
+	}
+}
+
+func currentTOTPCode(t *testing.T, totp *types.TOTP) string {
+	code, err := utils.TOTPCode(totp.Secret, utils.TOTPStep(time.Now()))
+	require.NoError(t, err)
+	return code
+}
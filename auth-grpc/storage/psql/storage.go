@@ -3,13 +3,22 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"math"
+	"time"
 
 	authpb "github.com/Edbeer/payment-proto/auth-grpc/proto"
 	"github.com/Edbeer/auth-grpc/types"
+	"github.com/google/uuid"
 	"github.com/lib/pq"
 	_ "github.com/lib/pq"
 )
 
+// postgres error codes of the constraint violations
+const (
+	uniqueViolation     = "23505"
+	foreignKeyViolation = "23503"
+)
+
 type PostgresStorage struct {
 	db *sql.DB
 }
@@ -20,17 +29,22 @@ func NewPostgresStorage(db *sql.DB) *PostgresStorage {
 	}
 }
 
-// Create account
-func (s *PostgresStorage) CreateAccount(ctx context.Context, account *authpb.CreateRequest) (*types.Account, error) {
+// Create account with the sign-in credentials in one transaction
+func (s *PostgresStorage) CreateAccount(ctx context.Context, account *authpb.CreateRequest, passwordHash string) (*types.Account, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
 	query := `INSERT INTO account (first_name, 
 		last_name, card_number, card_expiry_month, 
 		card_expiry_year, card_security_code, 
-		balance, blocked_money, statement, created_at)
-			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, now())
+		balance, blocked_money, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, now())
 			RETURNING *`
 	reqAcc := types.NewAccount(account)
 	acc := &types.Account{}
-	if err := s.db.QueryRowContext(
+	if err := tx.QueryRowContext(
 		ctx, query,
 		reqAcc.FirstName,
 		reqAcc.LastName,
@@ -40,17 +54,31 @@ func (s *PostgresStorage) CreateAccount(ctx context.Context, account *authpb.Cre
 		reqAcc.CardSecurityCode,
 		reqAcc.Balance,
 		reqAcc.BlockedMoney,
-		pq.Array(reqAcc.Statement),
 	).Scan(
 		&acc.ID, &acc.FirstName,
 		&acc.LastName, &acc.CardNumber,
 		&acc.CardExpiryMonth, &acc.CardExpiryYear,
 		&acc.CardSecurityCode, &acc.Balance,
-		&acc.BlockedMoney, pq.Array(&acc.Statement), 
-		&acc.CreatedAt,
+		&acc.BlockedMoney, &acc.CreatedAt,
+		&acc.EscrowMoney,
 	); err != nil {
 		return nil, err
 	}
+	credentialQuery := `INSERT INTO credential (account_id, email, password_hash)
+		VALUES ($1, $2, $3)`
+	if _, err := tx.ExecContext(ctx, credentialQuery, acc.ID, account.Email, passwordHash); err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == uniqueViolation {
+			return nil, types.ErrEmailTaken
+		}
+		return nil, err
+	}
+	roleQuery := `INSERT INTO account_role (account_id, role) VALUES ($1, $2)`
+	if _, err := tx.ExecContext(ctx, roleQuery, acc.ID, account.Role); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
 
 	return acc, nil
 }
@@ -81,8 +109,8 @@ func (s *PostgresStorage) UpdateAccount(ctx context.Context, account *authpb.Upd
 		&acc.LastName, &acc.CardNumber,
 		&acc.CardExpiryMonth, &acc.CardExpiryYear,
 		&acc.CardSecurityCode, &acc.Balance,
-		&acc.BlockedMoney, pq.Array(&acc.Statement), 
-		&acc.CreatedAt,
+		&acc.BlockedMoney, &acc.CreatedAt,
+		&acc.EscrowMoney,
 	); err != nil {
 		return nil, err
 	}
@@ -103,13 +131,24 @@ func (s *PostgresStorage) DeleteAccount(ctx context.Context, req *authpb.DeleteR
 
 // Deposit account
 func (s *PostgresStorage) DepositAccount(ctx context.Context, req *authpb.DepositRequest) (*authpb.DepositResponse, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	var balance uint64
+	if err := tx.QueryRowContext(
+		ctx, `SELECT balance FROM account WHERE card_number = $1 FOR UPDATE`, req.CardNumber,
+	).Scan(&balance); err != nil {
+		return nil, err
+	}
 	query := `UPDATE account
 		SET balance = COALESCE(NULLIF($1, 0), balance)
 		WHERE card_number = $2
 		RETURNING *`
 	acc := &types.Account{}
 
-	if err := s.db.QueryRowContext(
+	if err := tx.QueryRowContext(
 		ctx,
 		query,
 		req.Balance,
@@ -119,11 +158,30 @@ func (s *PostgresStorage) DepositAccount(ctx context.Context, req *authpb.Deposi
 		&acc.LastName, &acc.CardNumber,
 		&acc.CardExpiryMonth, &acc.CardExpiryYear,
 		&acc.CardSecurityCode, &acc.Balance,
-		&acc.BlockedMoney, pq.Array(&acc.Statement), 
-		&acc.CreatedAt,
+		&acc.BlockedMoney, &acc.CreatedAt,
+		&acc.EscrowMoney,
 	); err != nil {
 		return nil, err
 	}
+	// the deposit is written to the statement like the payments
+	if acc.Balance != balance {
+		entry, err := types.NewStatementEntry(&authpb.StatementRequest{
+			AccountId: acc.ID.String(),
+			PaymentId: uuid.New().String(),
+			Amount:    int64(acc.Balance) - int64(balance),
+			Operation: "Deposit",
+			Status:    "Successful deposit",
+		})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := saveStatementEntry(ctx, tx, entry); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
 	return &authpb.DepositResponse{
 		Status: "Successful deposit",
 	}, nil
@@ -145,8 +203,8 @@ func (s *PostgresStorage) GetAccount(ctx context.Context) ([]*types.Account, err
 			&acc.LastName, &acc.CardNumber,
 			&acc.CardExpiryMonth, &acc.CardExpiryYear,
 			&acc.CardSecurityCode, &acc.Balance,
-			&acc.BlockedMoney, pq.Array(&acc.Statement), 
-			&acc.CreatedAt,
+			&acc.BlockedMoney, &acc.CreatedAt,
+			&acc.EscrowMoney,
 		); err != nil {
 			return nil, err
 		}
@@ -167,8 +225,8 @@ func (s *PostgresStorage) GetAccountByID(ctx context.Context, req *authpb.GetIDR
 		&acc.LastName, &acc.CardNumber,
 		&acc.CardExpiryMonth, &acc.CardExpiryYear,
 		&acc.CardSecurityCode, &acc.Balance,
-		&acc.BlockedMoney, pq.Array(&acc.Statement), 
-		&acc.CreatedAt,
+		&acc.BlockedMoney, &acc.CreatedAt,
+		&acc.EscrowMoney,
 	); err != nil {
 		return nil, err
 	}
@@ -178,7 +236,7 @@ func (s *PostgresStorage) GetAccountByID(ctx context.Context, req *authpb.GetIDR
 func (s *PostgresStorage) SaveBalance(ctx context.Context, req *authpb.UpdateBalanceRequest) (*types.Account, error) {
 	query := `UPDATE account
 				SET balance = COALESCE($1, balance),
-					blocked_money = COALESCE($2, blocked_money)
+					escrow_money = COALESCE($2, escrow_money)
 				WHERE id = $3
 				RETURNING *`
 	tx, err := s.db.BeginTx(ctx, nil)
@@ -190,15 +248,15 @@ func (s *PostgresStorage) SaveBalance(ctx context.Context, req *authpb.UpdateBal
 	if err := tx.QueryRowContext(
 		ctx, query,
 		req.Balance,
-		req.BlockedMoney,
+		req.EscrowMoney,
 		req.Id,
 	).Scan(
 		&acc.ID, &acc.FirstName,
 		&acc.LastName, &acc.CardNumber,
 		&acc.CardExpiryMonth, &acc.CardExpiryYear,
 		&acc.CardSecurityCode, &acc.Balance,
-		&acc.BlockedMoney, pq.Array(&acc.Statement), 
-		&acc.CreatedAt,
+		&acc.BlockedMoney, &acc.CreatedAt,
+		&acc.EscrowMoney,
 	); err != nil {
 		return nil, err
 	}
@@ -208,34 +266,784 @@ func (s *PostgresStorage) SaveBalance(ctx context.Context, req *authpb.UpdateBal
 	return acc, nil
 }
 
-func (s *PostgresStorage) UpdateStatement(ctx context.Context, req *authpb.StatementRequest) ([]string, error) {
+// Get accounts by ids, missing accounts are skipped
+func (s *PostgresStorage) GetAccountsByIDs(ctx context.Context, ids []string) ([]*types.Account, error) {
+	query := `SELECT * FROM account
+				WHERE id = ANY($1::uuid[])`
+	rows, err := s.db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	accounts := []*types.Account{}
+	for rows.Next() {
+		acc := &types.Account{}
+		if err := rows.Scan(
+			&acc.ID, &acc.FirstName,
+			&acc.LastName, &acc.CardNumber,
+			&acc.CardExpiryMonth, &acc.CardExpiryYear,
+			&acc.CardSecurityCode, &acc.Balance,
+			&acc.BlockedMoney, &acc.CreatedAt,
+			&acc.EscrowMoney,
+		); err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, acc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
+
+// Apply money changes of the payment and save its statement entries
+// in one transaction, no money of the accounts may become negative
+func (s *PostgresStorage) ApplyMovements(ctx context.Context, movements []*authpb.Movement, entries []*types.StatementEntry) ([]*types.Account, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	changes := make([]*moneyChange, 0, len(movements))
+	for _, m := range movements {
+		changes = append(changes, &moneyChange{
+			accountID: m.AccountId,
+			balance:   m.Balance,
+			escrow:    m.EscrowMoney,
+		})
+	}
+	accounts, err := applyChanges(ctx, tx, changes)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if _, err := saveStatementEntry(ctx, tx, entry); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
+
+// change of the account money, negative - decrease
+type moneyChange struct {
+	accountID string
+	balance   int64
+	blocked   int64
+	escrow    int64
+}
+
+// apply money changes in tx, changes of the same account are applied at once,
+// accounts are returned in order of their first change
+func applyChanges(ctx context.Context, tx *sql.Tx, changes []*moneyChange) ([]*types.Account, error) {
+	byID := map[string]*moneyChange{}
+	ids := []string{}
+	for _, c := range changes {
+		change, ok := byID[c.accountID]
+		if !ok {
+			change = &moneyChange{accountID: c.accountID}
+			byID[c.accountID] = change
+			ids = append(ids, c.accountID)
+		}
+		change.balance += c.balance
+		change.blocked += c.blocked
+		change.escrow += c.escrow
+	}
+	// accounts are locked in the same order to avoid deadlocks
+	lockQuery := `SELECT id, balance, blocked_money, escrow_money FROM account
+				WHERE id = ANY($1::uuid[])
+				ORDER BY id
+				FOR UPDATE`
+	rows, err := tx.QueryContext(ctx, lockQuery, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	locked := 0
+	for rows.Next() {
+		var (
+			id                       string
+			balance, blocked, escrow int64
+		)
+		if err := rows.Scan(&id, &balance, &blocked, &escrow); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		change := byID[id]
+		if change == nil {
+			continue
+		}
+		locked++
+		if balance+change.balance < 0 || blocked+change.blocked < 0 || escrow+change.escrow < 0 {
+			rows.Close()
+			return nil, types.ErrInsufficientFunds
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if locked != len(ids) {
+		return nil, sql.ErrNoRows
+	}
+
 	query := `UPDATE account
-				SET statement = array_append(statement, $1)
-				WHERE id = $2
+				SET balance = balance + $1,
+					blocked_money = blocked_money + $2,
+					escrow_money = escrow_money + $3
+				WHERE id = $4
 				RETURNING *`
-	acc := &types.Account{}
+	accounts := make([]*types.Account, 0, len(ids))
+	for _, id := range ids {
+		change := byID[id]
+		acc := &types.Account{}
+		if err := tx.QueryRowContext(
+			ctx, query,
+			change.balance,
+			change.blocked,
+			change.escrow,
+			id,
+		).Scan(
+			&acc.ID, &acc.FirstName,
+			&acc.LastName, &acc.CardNumber,
+			&acc.CardExpiryMonth, &acc.CardExpiryYear,
+			&acc.CardSecurityCode, &acc.Balance,
+			&acc.BlockedMoney, &acc.CreatedAt,
+			&acc.EscrowMoney,
+		); err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, acc)
+	}
+	return accounts, nil
+}
+
+// statement entry is saved with the account balance after the payment
+// and the current name of the counterparty
+const saveStatementEntryQuery = `INSERT INTO statement_entry (account_id, payment_id,
+		direction, amount, balance, created_at,
+		counterparty_id, counterparty_name, operation, status, currency)
+			SELECT id, $2, $3, $4, balance, $5,
+				$6, COALESCE((SELECT TRIM(CONCAT(first_name, ' ', last_name))
+					FROM account WHERE id = $6), ''), $7, $8, $9
+			FROM account
+			WHERE id = $1
+			RETURNING *`
+
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Save statement entry with the account balance after the payment
+func (s *PostgresStorage) SaveStatementEntry(ctx context.Context, entry *types.StatementEntry) (*types.StatementEntry, error) {
+	return saveStatementEntry(ctx, s.db, entry)
+}
+
+func saveStatementEntry(ctx context.Context, q queryRower, entry *types.StatementEntry) (*types.StatementEntry, error) {
+	saved := &types.StatementEntry{}
+	if err := q.QueryRowContext(
+		ctx, saveStatementEntryQuery,
+		entry.AccountID,
+		entry.PaymentID,
+		entry.Direction,
+		entry.Amount,
+		entry.CreatedAt,
+		entry.CounterpartyID,
+		entry.Operation,
+		entry.Status,
+		entry.Currency,
+	).Scan(
+		&saved.ID, &saved.AccountID,
+		&saved.PaymentID, &saved.Direction,
+		&saved.Amount, &saved.Balance,
+		&saved.CreatedAt, &saved.CounterpartyID,
+		&saved.CounterpartyName, &saved.Operation,
+		&saved.Status, &saved.Currency,
+	); err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+// Get statement entries of the account after the cursor,
+// empty period boundaries are not checked
+func (s *PostgresStorage) GetStatementEntries(ctx context.Context, req *authpb.StatementGet, limit int) ([]*types.StatementEntry, error) {
+	query := `SELECT * FROM statement_entry
+				WHERE account_id = $1
+					AND entry_id > $2
+					AND ($3::timestamp IS NULL OR created_at >= $3)
+					AND ($4::timestamp IS NULL OR created_at < $4)
+				ORDER BY entry_id
+				LIMIT $5`
+	var from, to sql.NullTime
+	if req.From != nil {
+		from = sql.NullTime{Time: req.From.AsTime(), Valid: true}
+	}
+	if req.To != nil {
+		to = sql.NullTime{Time: req.To.AsTime(), Valid: true}
+	}
+	rows, err := s.db.QueryContext(
+		ctx, query,
+		req.AccountId,
+		req.Cursor,
+		from,
+		to,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []*types.StatementEntry{}
+	for rows.Next() {
+		entry := &types.StatementEntry{}
+		if err := rows.Scan(
+			&entry.ID, &entry.AccountID,
+			&entry.PaymentID, &entry.Direction,
+			&entry.Amount, &entry.Balance,
+			&entry.CreatedAt, &entry.CounterpartyID,
+			&entry.CounterpartyName, &entry.Operation,
+			&entry.Status, &entry.Currency,
+		); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Create hold and save the statement entries in one transaction,
+// held money moves from the balance to blocked money
+func (s *PostgresStorage) CreateHold(ctx context.Context, hold *types.Hold, entries []*types.StatementEntry) (*types.Hold, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	if hold.Amount > math.MaxInt64 {
+		return nil, types.ErrHoldAmount
+	}
+	if _, err := applyChanges(ctx, tx, []*moneyChange{
+		{accountID: hold.AccountID.String(), balance: -int64(hold.Amount), blocked: int64(hold.Amount)},
+	}); err != nil {
+		return nil, err
+	}
+	query := `INSERT INTO hold (hold_id, account_id, amount,
+		captured_amount, state, reference,
+		expires_at, created_at, updated_at)
+			VALUES ($1, $2, $3, 0, $4, $5, $6, $7, $8)
+			RETURNING *`
+	saved := &types.Hold{}
+	if err := tx.QueryRowContext(
+		ctx, query,
+		hold.ID,
+		hold.AccountID,
+		hold.Amount,
+		hold.State,
+		hold.Reference,
+		hold.ExpiresAt,
+		hold.CreatedAt,
+		hold.UpdatedAt,
+	).Scan(
+		&saved.ID, &saved.AccountID,
+		&saved.Amount, &saved.CapturedAmount,
+		&saved.State, &saved.Reference,
+		&saved.ExpiresAt, &saved.CreatedAt,
+		&saved.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if _, err := saveStatementEntry(ctx, tx, entry); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+// Capture the hold: transfers are paid from the held money,
+// the rest is returned to the balance of the hold account
+func (s *PostgresStorage) CaptureHold(ctx context.Context, holdID uuid.UUID, transfers []*authpb.HoldTransfer, entries []*types.StatementEntry) (*types.Hold, error) {
 	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
 	defer tx.Rollback()
+	hold, err := getHoldForUpdate(ctx, tx, holdID)
 	if err != nil {
 		return nil, err
 	}
+	if hold.State != types.HoldActive || !time.Now().Before(hold.ExpiresAt) {
+		return nil, types.ErrHoldNotActive
+	}
+	// the sum is checked before it is added, so it can't overflow
+	var captured uint64
+	for _, transfer := range transfers {
+		if transfer.Amount > hold.Amount-captured {
+			return nil, types.ErrHoldAmount
+		}
+		captured += transfer.Amount
+	}
+	changes := []*moneyChange{
+		{accountID: hold.AccountID.String(), balance: int64(hold.Amount - captured), blocked: -int64(hold.Amount)},
+	}
+	for _, transfer := range transfers {
+		change := &moneyChange{accountID: transfer.AccountId, balance: int64(transfer.Amount)}
+		if transfer.Escrow {
+			change = &moneyChange{accountID: transfer.AccountId, escrow: int64(transfer.Amount)}
+		}
+		changes = append(changes, change)
+	}
+	hold.CapturedAmount = captured
+	hold.State = types.HoldCaptured
+	return completeHold(ctx, tx, hold, changes, entries)
+}
+
+// Release the hold with the state, held money is returned to the balance
+func (s *PostgresStorage) ReleaseHold(ctx context.Context, holdID uuid.UUID, state string, entries []*types.StatementEntry) (*types.Hold, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	hold, err := getHoldForUpdate(ctx, tx, holdID)
+	if err != nil {
+		return nil, err
+	}
+	if hold.State != types.HoldActive {
+		return nil, types.ErrHoldNotActive
+	}
+	changes := []*moneyChange{
+		{accountID: hold.AccountID.String(), balance: int64(hold.Amount), blocked: -int64(hold.Amount)},
+	}
+	hold.State = state
+	return completeHold(ctx, tx, hold, changes, entries)
+}
+
+// Change held money by amount, negative - part of the hold is released
+func (s *PostgresStorage) ChangeHold(ctx context.Context, holdID uuid.UUID, amount int64, entries []*types.StatementEntry) (*types.Hold, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	hold, err := getHoldForUpdate(ctx, tx, holdID)
+	if err != nil {
+		return nil, err
+	}
+	if hold.State != types.HoldActive || !time.Now().Before(hold.ExpiresAt) {
+		return nil, types.ErrHoldNotActive
+	}
+	if int64(hold.Amount)+amount <= 0 || amount > 0 && int64(hold.Amount) > math.MaxInt64-amount {
+		return nil, types.ErrHoldAmount
+	}
+	changes := []*moneyChange{
+		{accountID: hold.AccountID.String(), balance: -amount, blocked: amount},
+	}
+	hold.Amount = uint64(int64(hold.Amount) + amount)
+	return completeHold(ctx, tx, hold, changes, entries)
+}
+
+// Get holds of the account in order of creation, empty state - all holds
+func (s *PostgresStorage) GetHolds(ctx context.Context, accountID string, state string) ([]*types.Hold, error) {
+	query := `SELECT * FROM hold
+				WHERE account_id = $1
+					AND ($2 = '' OR state = $2)
+				ORDER BY created_at`
+	rows, err := s.db.QueryContext(ctx, query, accountID, state)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	holds := []*types.Hold{}
+	for rows.Next() {
+		hold := &types.Hold{}
+		if err := rows.Scan(
+			&hold.ID, &hold.AccountID,
+			&hold.Amount, &hold.CapturedAmount,
+			&hold.State, &hold.Reference,
+			&hold.ExpiresAt, &hold.CreatedAt,
+			&hold.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		holds = append(holds, hold)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return holds, nil
+}
+
+// Get active holds whose time is over
+func (s *PostgresStorage) GetExpiredHolds(ctx context.Context, now time.Time) ([]*types.Hold, error) {
+	query := `SELECT * FROM hold
+				WHERE state = $1 AND expires_at <= $2
+				ORDER BY expires_at`
+	rows, err := s.db.QueryContext(ctx, query, types.HoldActive, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	holds := []*types.Hold{}
+	for rows.Next() {
+		hold := &types.Hold{}
+		if err := rows.Scan(
+			&hold.ID, &hold.AccountID,
+			&hold.Amount, &hold.CapturedAmount,
+			&hold.State, &hold.Reference,
+			&hold.ExpiresAt, &hold.CreatedAt,
+			&hold.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		holds = append(holds, hold)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return holds, nil
+}
+
+// hold locked until the end of tx
+func getHoldForUpdate(ctx context.Context, tx *sql.Tx, holdID uuid.UUID) (*types.Hold, error) {
+	query := `SELECT * FROM hold WHERE hold_id = $1 FOR UPDATE`
+	hold := &types.Hold{}
 	if err := tx.QueryRowContext(
-		ctx,
-		query,
-		req.PaymentId,
-		req.AccountId,
+		ctx, query, holdID,
 	).Scan(
-		&acc.ID, &acc.FirstName,
-		&acc.LastName, &acc.CardNumber,
-		&acc.CardExpiryMonth, &acc.CardExpiryYear,
-		&acc.CardSecurityCode, &acc.Balance,
-		&acc.BlockedMoney, pq.Array(&acc.Statement),
-		&acc.CreatedAt,
+		&hold.ID, &hold.AccountID,
+		&hold.Amount, &hold.CapturedAmount,
+		&hold.State, &hold.Reference,
+		&hold.ExpiresAt, &hold.CreatedAt,
+		&hold.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return hold, nil
+}
+
+// apply money changes of the hold, save it with the statement entries and commit tx
+func completeHold(ctx context.Context, tx *sql.Tx, hold *types.Hold, changes []*moneyChange, entries []*types.StatementEntry) (*types.Hold, error) {
+	if _, err := applyChanges(ctx, tx, changes); err != nil {
+		return nil, err
+	}
+	query := `UPDATE hold
+				SET amount = $1,
+					captured_amount = $2,
+					state = $3,
+					updated_at = $4
+				WHERE hold_id = $5
+				RETURNING *`
+	saved := &types.Hold{}
+	if err := tx.QueryRowContext(
+		ctx, query,
+		hold.Amount,
+		hold.CapturedAmount,
+		hold.State,
+		time.Now(),
+		hold.ID,
+	).Scan(
+		&saved.ID, &saved.AccountID,
+		&saved.Amount, &saved.CapturedAmount,
+		&saved.State, &saved.Reference,
+		&saved.ExpiresAt, &saved.CreatedAt,
+		&saved.UpdatedAt,
 	); err != nil {
 		return nil, err
 	}
+	for _, entry := range entries {
+		if _, err := saveStatementEntry(ctx, tx, entry); err != nil {
+			return nil, err
+		}
+	}
 	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
-	return acc.Statement, nil
-}
\ No newline at end of file
+	return saved, nil
+}
+
+// Get credentials by the sign-in email, email is case insensitive
+func (s *PostgresStorage) GetCredentialByEmail(ctx context.Context, email string) (*types.Credential, error) {
+	query := `SELECT * FROM credential WHERE email = $1`
+	cred := &types.Credential{}
+	if err := s.db.QueryRowContext(ctx, query, email).Scan(
+		&cred.AccountID, &cred.Email,
+		&cred.PasswordHash, &cred.CreatedAt,
+		&cred.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return cred, nil
+}
+
+// Get credentials of the account
+func (s *PostgresStorage) GetCredential(ctx context.Context, accountID uuid.UUID) (*types.Credential, error) {
+	query := `SELECT * FROM credential WHERE account_id = $1`
+	cred := &types.Credential{}
+	if err := s.db.QueryRowContext(ctx, query, accountID).Scan(
+		&cred.AccountID, &cred.Email,
+		&cred.PasswordHash, &cred.CreatedAt,
+		&cred.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return cred, nil
+}
+
+// Update password hash of the account
+func (s *PostgresStorage) UpdatePasswordHash(ctx context.Context, accountID uuid.UUID, passwordHash string) error {
+	query := `UPDATE credential
+		SET password_hash = $1, updated_at = now()
+		WHERE account_id = $2`
+	res, err := s.db.ExecContext(ctx, query, passwordHash, accountID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Save new TOTP secret of the account, enrollment is
+// started again until the secret is enabled
+func (s *PostgresStorage) SaveTOTPSecret(ctx context.Context, accountID uuid.UUID, secret string) error {
+	query := `INSERT INTO totp (account_id, secret)
+		VALUES ($1, $2)
+		ON CONFLICT (account_id) DO UPDATE
+			SET secret = $2, enabled = false, last_step = 0, updated_at = now()
+			WHERE totp.enabled = false`
+	res, err := s.db.ExecContext(ctx, query, accountID, secret)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return types.ErrTOTPEnabled
+	}
+	return nil
+}
+
+// Get TOTP secret of the account
+func (s *PostgresStorage) GetTOTP(ctx context.Context, accountID uuid.UUID) (*types.TOTP, error) {
+	query := `SELECT * FROM totp WHERE account_id = $1`
+	totp := &types.TOTP{}
+	if err := s.db.QueryRowContext(ctx, query, accountID).Scan(
+		&totp.AccountID, &totp.Secret,
+		&totp.Enabled, &totp.LastStep,
+		&totp.CreatedAt, &totp.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return totp, nil
+}
+
+// Accept the TOTP code of the time step once,
+// false if a code of the step or a later one was accepted
+func (s *PostgresStorage) UseTOTPStep(ctx context.Context, accountID uuid.UUID, step int64) (bool, error) {
+	query := `UPDATE totp SET last_step = $1, updated_at = now()
+		WHERE account_id = $2 AND last_step < $1`
+	res, err := s.db.ExecContext(ctx, query, step, accountID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// Enable TOTP of the account and replace its recovery codes in one transaction
+func (s *PostgresStorage) EnableTOTP(ctx context.Context, accountID uuid.UUID, codeHashes []string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	query := `UPDATE totp SET enabled = true, updated_at = now()
+		WHERE account_id = $1`
+	if _, err := tx.ExecContext(ctx, query, accountID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recovery_code WHERE account_id = $1`, accountID); err != nil {
+		return err
+	}
+	for _, hash := range codeHashes {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO recovery_code (account_id, code_hash) VALUES ($1, $2)`,
+			accountID, hash,
+		); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Disable TOTP of the account, secret and recovery codes are deleted
+func (s *PostgresStorage) DisableTOTP(ctx context.Context, accountID uuid.UUID) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recovery_code WHERE account_id = $1`, accountID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM totp WHERE account_id = $1`, accountID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Use the recovery code of the account once,
+// false if there is no unused code with the hash
+func (s *PostgresStorage) UseRecoveryCode(ctx context.Context, accountID uuid.UUID, codeHash string) (bool, error) {
+	query := `UPDATE recovery_code SET used_at = now()
+		WHERE code_id = (
+			SELECT code_id FROM recovery_code
+			WHERE account_id = $1 AND code_hash = $2 AND used_at IS NULL
+			LIMIT 1
+			FOR UPDATE
+		)`
+	res, err := s.db.ExecContext(ctx, query, accountID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// Get role of the account, accounts without a role are customers
+func (s *PostgresStorage) GetRole(ctx context.Context, accountID uuid.UUID) (string, error) {
+	query := `SELECT role FROM account_role WHERE account_id = $1`
+	var role string
+	if err := s.db.QueryRowContext(ctx, query, accountID).Scan(&role); err != nil {
+		if err == sql.ErrNoRows {
+			return types.RoleCustomer, nil
+		}
+		return "", err
+	}
+	return role, nil
+}
+
+// Set role of the account, sql.ErrNoRows if there is no account
+func (s *PostgresStorage) SetRole(ctx context.Context, accountID uuid.UUID, role string) error {
+	query := `INSERT INTO account_role (account_id, role)
+		VALUES ($1, $2)
+		ON CONFLICT (account_id) DO UPDATE
+			SET role = $2, updated_at = now()`
+	if _, err := s.db.ExecContext(ctx, query, accountID, role); err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == foreignKeyViolation {
+			return sql.ErrNoRows
+		}
+		return err
+	}
+	return nil
+}
+
+// Save new OAuth2 client of the account
+func (s *PostgresStorage) SaveOAuthClient(ctx context.Context, client *types.OAuthClient) (*types.OAuthClient, error) {
+	query := `INSERT INTO oauth_client (client_id, account_id, name, secret_hash, scopes)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at`
+	saved := *client
+	if err := s.db.QueryRowContext(ctx, query,
+		client.ClientID, client.AccountID, client.Name,
+		client.SecretHash, pq.Array(client.Scopes),
+	).Scan(&saved.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &saved, nil
+}
+
+// Get OAuth2 client by its id
+func (s *PostgresStorage) GetOAuthClient(ctx context.Context, clientID string) (*types.OAuthClient, error) {
+	query := `SELECT client_id, account_id, name, secret_hash, scopes, created_at
+		FROM oauth_client WHERE client_id = $1`
+	client := &types.OAuthClient{}
+	if err := s.db.QueryRowContext(ctx, query, clientID).Scan(
+		&client.ClientID, &client.AccountID,
+		&client.Name, &client.SecretHash,
+		pq.Array(&client.Scopes), &client.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// Get OAuth2 clients of the account
+func (s *PostgresStorage) GetOAuthClients(ctx context.Context, accountID uuid.UUID) ([]*types.OAuthClient, error) {
+	query := `SELECT client_id, account_id, name, secret_hash, scopes, created_at
+		FROM oauth_client WHERE account_id = $1
+		ORDER BY created_at`
+	rows, err := s.db.QueryContext(ctx, query, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	clients := []*types.OAuthClient{}
+	for rows.Next() {
+		client := &types.OAuthClient{}
+		if err := rows.Scan(
+			&client.ClientID, &client.AccountID,
+			&client.Name, &client.SecretHash,
+			pq.Array(&client.Scopes), &client.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		clients = append(clients, client)
+	}
+	return clients, rows.Err()
+}
+
+// Delete OAuth2 client of the account, sql.ErrNoRows if the account has no such client
+func (s *PostgresStorage) DeleteOAuthClient(ctx context.Context, accountID uuid.UUID, clientID string) error {
+	query := `DELETE FROM oauth_client WHERE client_id = $1 AND account_id = $2`
+	res, err := s.db.ExecContext(ctx, query, clientID, accountID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Is the email of the account verified
+func (s *PostgresStorage) IsEmailVerified(ctx context.Context, accountID uuid.UUID) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM email_verification WHERE account_id = $1)`
+	var verified bool
+	if err := s.db.QueryRowContext(ctx, query, accountID).Scan(&verified); err != nil {
+		return false, err
+	}
+	return verified, nil
+}
+
+// Mark the email of the account verified, sql.ErrNoRows if there is no account
+func (s *PostgresStorage) SetEmailVerified(ctx context.Context, accountID uuid.UUID) error {
+	query := `INSERT INTO email_verification (account_id)
+		VALUES ($1)
+		ON CONFLICT (account_id) DO NOTHING`
+	if _, err := s.db.ExecContext(ctx, query, accountID); err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == foreignKeyViolation {
+			return sql.ErrNoRows
+		}
+		return err
+	}
+	return nil
+}
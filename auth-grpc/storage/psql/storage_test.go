@@ -2,8 +2,11 @@ package storage
 
 import (
 	"context"
+	"database/sql"
+	"math"
 	"regexp"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	authpb "github.com/Edbeer/payment-proto/auth-grpc/proto"
@@ -11,6 +14,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/lib/pq"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 func Test_CreateAccount(t *testing.T) {
@@ -30,6 +34,8 @@ func Test_CreateAccount(t *testing.T) {
 			CardExpiryMonth:  "12",
 			CardExpiryYear:   "24",
 			CardSecurityCode: "123",
+			Email:            "pasha@example.com",
+			Role:             types.RoleMerchant,
 		}
 
 		account := types.NewAccount(req)
@@ -43,8 +49,8 @@ func Test_CreateAccount(t *testing.T) {
 			"card_expiry_year",
 			"card_security_code",
 			"balance", "blocked_money",
-			"statement",
 			"created_at",
+			"escrow_money",
 		}
 		rows := sqlmock.NewRows(colums).AddRow(
 			account.ID,
@@ -56,14 +62,15 @@ func Test_CreateAccount(t *testing.T) {
 			"123",
 			0,
 			0,
-			pq.Array(account.Statement),
 			account.CreatedAt,
+			0,
 		)
+		mock.ExpectBegin()
 		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO account (first_name, 
 			last_name, card_number, card_expiry_month, 
 			card_expiry_year, card_security_code, 
-			balance, blocked_money, statement, created_at)
-				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, now())
+			balance, blocked_money, created_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, now())
 				RETURNING *`)).WithArgs(
 			account.FirstName,
 			account.LastName,
@@ -73,12 +80,95 @@ func Test_CreateAccount(t *testing.T) {
 			account.CardSecurityCode,
 			account.Balance,
 			account.BlockedMoney,
-			pq.Array(account.Statement),).WillReturnRows(rows)
-		createdUser, err := psql.CreateAccount(context.Background(), req)
+		).WillReturnRows(rows)
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO credential (account_id, email, password_hash)
+			VALUES ($1, $2, $3)`)).WithArgs(account.ID, req.Email, "hash").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO account_role (account_id, role) VALUES ($1, $2)`)).
+			WithArgs(account.ID, types.RoleMerchant).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		createdUser, err := psql.CreateAccount(context.Background(), req, "hash")
 		require.NoError(t, err)
 		require.NotNil(t, createdUser)
 		require.Equal(t, createdUser, account)	
 	})
+
+	t.Run("Email taken", func(t *testing.T) {
+		req := &authpb.CreateRequest{
+			FirstName: "Pasha",
+			Email:     "pasha@example.com",
+		}
+		account := types.NewAccount(req)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO account`)).WillReturnRows(
+			sqlmock.NewRows([]string{
+				"id", "first_name", "last_name", "card_number",
+				"card_expiry_month", "card_expiry_year", "card_security_code",
+				"balance", "blocked_money", "created_at", "escrow_money",
+			}).AddRow(account.ID, "Pasha", "", "", "", "", "", 0, 0, account.CreatedAt, 0),
+		)
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO credential`)).
+			WillReturnError(&pq.Error{Code: "23505"})
+		mock.ExpectRollback()
+
+		_, err := psql.CreateAccount(context.Background(), req, "hash")
+		require.ErrorIs(t, err, types.ErrEmailTaken)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func Test_Credential(t *testing.T) {
+	t.Parallel()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	psql := NewPostgresStorage(db)
+
+	uid := uuid.New()
+	now := time.Now()
+	columns := []string{"account_id", "email", "password_hash", "created_at", "updated_at"}
+
+	t.Run("GetCredentialByEmail", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM credential WHERE email = $1`)).
+			WithArgs("pasha@example.com").
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(uid, "pasha@example.com", "hash", now, now))
+
+		cred, err := psql.GetCredentialByEmail(context.Background(), "pasha@example.com")
+		require.NoError(t, err)
+		require.Equal(t, &types.Credential{
+			AccountID:    uid,
+			Email:        "pasha@example.com",
+			PasswordHash: "hash",
+			CreatedAt:    now,
+			UpdatedAt:    now,
+		}, cred)
+	})
+
+	t.Run("GetCredential", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM credential WHERE account_id = $1`)).
+			WithArgs(uid).
+			WillReturnRows(sqlmock.NewRows(columns))
+
+		_, err := psql.GetCredential(context.Background(), uid)
+		require.ErrorIs(t, err, sql.ErrNoRows)
+	})
+
+	t.Run("UpdatePasswordHash", func(t *testing.T) {
+		query := regexp.QuoteMeta(`UPDATE credential
+			SET password_hash = $1, updated_at = now()
+			WHERE account_id = $2`)
+		mock.ExpectExec(query).WithArgs("new hash", uid).WillReturnResult(sqlmock.NewResult(0, 1))
+		require.NoError(t, psql.UpdatePasswordHash(context.Background(), uid, "new hash"))
+
+		// account without credentials
+		mock.ExpectExec(query).WithArgs("new hash", uid).WillReturnResult(sqlmock.NewResult(0, 0))
+		require.ErrorIs(t, psql.UpdatePasswordHash(context.Background(), uid, "new hash"), sql.ErrNoRows)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
 }
 
 func Test_UpdateAccount(t *testing.T) {
@@ -120,8 +210,8 @@ func Test_UpdateAccount(t *testing.T) {
 			"card_expiry_year",
 			"card_security_code",
 			"balance", "blocked_money",
-			"statement",
 			"created_at",
+			"escrow_money",
 		}
 		rows := sqlmock.NewRows(colums).AddRow(
 			account.ID,
@@ -133,8 +223,8 @@ func Test_UpdateAccount(t *testing.T) {
 			"123",
 			0,
 			0,
-			pq.Array(account.Statement),
 			account.CreatedAt,
+			0,
 		)
 
 		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE account
@@ -216,8 +306,8 @@ func Test_DepositAccount(t *testing.T) {
 			"card_expiry_year",
 			"card_security_code",
 			"balance", "blocked_money",
-			"statement",
 			"created_at",
+			"escrow_money",
 		}
 		rows := sqlmock.NewRows(colums).AddRow(
 			account.ID,
@@ -229,16 +319,29 @@ func Test_DepositAccount(t *testing.T) {
 			"123",
 			50,
 			0,
-			pq.Array(account.Statement),
 			account.CreatedAt,
+			0,
 		)
 
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT balance FROM account WHERE card_number = $1 FOR UPDATE`)).
+			WithArgs(req.CardNumber).WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(20))
 		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE account
 		SET balance = COALESCE(NULLIF($1, 0), balance)
 		WHERE card_number = $2
 		RETURNING *`)).WithArgs(uint64(50), req.CardNumber).WillReturnRows(rows)
+		// credit of the difference is written to the statement
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO statement_entry`)).
+			WithArgs(account.ID, sqlmock.AnyArg(), types.Credit, uint64(30), sqlmock.AnyArg(),
+				uuid.NullUUID{}, "Deposit", "Successful deposit", "").
+			WillReturnRows(sqlmock.NewRows([]string{
+				"entry_id", "account_id", "payment_id", "direction", "amount", "balance", "created_at",
+				"counterparty_id", "counterparty_name", "operation", "status", "currency",
+			}).AddRow(1, account.ID, uuid.New(), types.Credit, 30, 50, time.Now(), nil, "", "Deposit", "Successful deposit", ""))
+		mock.ExpectCommit()
 		_, err := psql.DepositAccount(context.Background(), req)
 		require.NoError(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
 		require.Equal(t, req.CardNumber, account.CardNumber)
 		require.Equal(t, req.Balance, uint64(50))
 	})
@@ -272,8 +375,8 @@ func Test_GetAccount(t *testing.T) {
 			"card_expiry_year",
 			"card_security_code",
 			"balance", "blocked_money",
-			"statement",
 			"created_at",
+			"escrow_money",
 		}
 		rows1 := sqlmock.NewRows(colums).AddRow(
 			account1.ID,
@@ -285,8 +388,8 @@ func Test_GetAccount(t *testing.T) {
 			"924",
 			0,
 			0,
-			pq.Array(account1.Statement),
 			account1.CreatedAt,
+			0,
 		)
 		req2 := &authpb.CreateRequest{
 			FirstName:        "Pasha",
@@ -308,8 +411,8 @@ func Test_GetAccount(t *testing.T) {
 			"924",
 			0,
 			0,
-			pq.Array(account2.Statement),
 			account2.CreatedAt,
+			0,
 		)
 
 		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM account`)).WillReturnRows(rows1, rows2)
@@ -347,8 +450,8 @@ func Test_GetAccountByID(t *testing.T) {
 			"card_expiry_year",
 			"card_security_code",
 			"balance", "blocked_money",
-			"statement",
 			"created_at",
+			"escrow_money",
 		}
 		rows := sqlmock.NewRows(colums).AddRow(
 			account.ID,
@@ -360,8 +463,8 @@ func Test_GetAccountByID(t *testing.T) {
 			"924",
 			0,
 			0,
-			pq.Array(account.Statement),
 			account.CreatedAt,
+			0,
 		)
 		reqID := &authpb.GetIDRequest{
 			Id: account.ID.String(),
@@ -408,8 +511,8 @@ func Test_SaveBalance(t *testing.T) {
 			"card_expiry_year",
 			"card_security_code",
 			"balance", "blocked_money",
-			"statement",
 			"created_at",
+			"escrow_money",
 		}
 		rows := sqlmock.NewRows(colums).AddRow(
 			account.ID,
@@ -421,16 +524,16 @@ func Test_SaveBalance(t *testing.T) {
 			"924",
 			0,
 			50,
-			pq.Array(account.Statement),
 			account.CreatedAt,
+			0,
 		)
 
 		mock.ExpectBegin()
 		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE account
-		SET balance = COALESCE($1, balance),
-			blocked_money = COALESCE($2, blocked_money)
-		WHERE id = $3
-		RETURNING *`)).WithArgs(0, 50, account.ID).WillReturnRows(rows)
+				SET balance = COALESCE($1, balance),
+					escrow_money = COALESCE($2, escrow_money)
+				WHERE id = $3
+				RETURNING *`)).WithArgs(0, nil, account.ID).WillReturnRows(rows)
 		mock.ExpectCommit()
 		acc, err := psql.SaveBalance(context.Background(), reqB)
 		require.NoError(t, err)
@@ -438,7 +541,7 @@ func Test_SaveBalance(t *testing.T) {
 	})
 }
 
-func Test_UpdateStatement(t *testing.T) {
+func Test_StatementEntry(t *testing.T) {
 	t.Parallel()
 
 	db, mock, err := sqlmock.New()
@@ -447,54 +550,760 @@ func Test_UpdateStatement(t *testing.T) {
 
 	psql := NewPostgresStorage(db)
 
-	t.Run("UpdateStatement", func(t *testing.T) {
-		reqAcc := &authpb.CreateRequest{
-			FirstName:        "Pasha1",
-			LastName:         "volkov1",
-			CardNumber:       "444444444444444",
-			CardExpiryMonth:  "12",
-			CardExpiryYear:   "24",
-			CardSecurityCode: "924",
+	colums := []string{
+		"entry_id",
+		"account_id",
+		"payment_id",
+		"direction",
+		"amount",
+		"balance",
+		"created_at",
+		"counterparty_id",
+		"counterparty_name",
+		"operation",
+		"status",
+		"currency",
+	}
+
+	t.Run("SaveStatementEntry", func(t *testing.T) {
+		entry, err := types.NewStatementEntry(&authpb.StatementRequest{
+			AccountId:      uuid.New().String(),
+			PaymentId:      uuid.New().String(),
+			Amount:         -50,
+			CounterpartyId: uuid.New().String(),
+			Operation:      "Authorization",
+			Status:         "Approved",
+			Currency:       "rub",
+		})
+		require.NoError(t, err)
+		require.Equal(t, types.Debit, entry.Direction)
+		require.Equal(t, uint64(50), entry.Amount)
+
+		rows := sqlmock.NewRows(colums).AddRow(
+			1,
+			entry.AccountID,
+			entry.PaymentID,
+			entry.Direction,
+			entry.Amount,
+			100,
+			entry.CreatedAt,
+			entry.CounterpartyID,
+			"Shop Owner",
+			entry.Operation,
+			entry.Status,
+			entry.Currency,
+		)
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO statement_entry (account_id, payment_id,
+		direction, amount, balance, created_at,
+		counterparty_id, counterparty_name, operation, status, currency)
+			SELECT id, $2, $3, $4, balance, $5,
+				$6, COALESCE((SELECT TRIM(CONCAT(first_name, ' ', last_name))
+					FROM account WHERE id = $6), ''), $7, $8, $9
+			FROM account
+			WHERE id = $1
+			RETURNING *`)).WithArgs(
+			entry.AccountID,
+			entry.PaymentID,
+			entry.Direction,
+			entry.Amount,
+			entry.CreatedAt,
+			entry.CounterpartyID,
+			entry.Operation,
+			entry.Status,
+			entry.Currency,
+		).WillReturnRows(rows)
+		saved, err := psql.SaveStatementEntry(context.Background(), entry)
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), saved.ID)
+		require.Equal(t, sql.NullInt64{Int64: 100, Valid: true}, saved.Balance)
+		require.Equal(t, "Shop Owner", saved.CounterpartyName)
+		require.Equal(t, entry.CounterpartyID, saved.CounterpartyID)
+	})
+
+	t.Run("GetStatementEntries", func(t *testing.T) {
+		accountID := uuid.New()
+		from := time.Now().Add(-time.Hour)
+		req := &authpb.StatementGet{
+			AccountId: accountID.String(),
+			From:      timestamppb.New(from),
+			Cursor:    10,
 		}
-		account := types.NewAccount(reqAcc)
-		req := &authpb.StatementRequest{
-			AccountId: account.ID.String(),
-			PaymentId: uuid.New().String(),
+		rows := sqlmock.NewRows(colums).
+			AddRow(11, accountID, uuid.New(), types.Credit, 30, 130, time.Now(), uuid.New(), "Pasha Volkov", "Capture", "Successful payment", "rub").
+			AddRow(12, accountID, uuid.New(), types.NoChange, 0, nil, time.Now(), nil, "", "", "", "")
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM statement_entry
+				WHERE account_id = $1
+					AND entry_id > $2
+					AND ($3::timestamp IS NULL OR created_at >= $3)
+					AND ($4::timestamp IS NULL OR created_at < $4)
+				ORDER BY entry_id
+				LIMIT $5`)).WithArgs(
+			req.AccountId,
+			req.Cursor,
+			sql.NullTime{Time: from.UTC(), Valid: true},
+			sql.NullTime{},
+			2,
+		).WillReturnRows(rows)
+		entries, err := psql.GetStatementEntries(context.Background(), req, 2)
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		require.Equal(t, uint64(11), entries[0].ID)
+		require.Equal(t, "Pasha Volkov", entries[0].CounterpartyName)
+		require.Equal(t, types.NoChange, entries[1].Direction)
+		require.False(t, entries[1].CounterpartyID.Valid)
+		// migrated from the statement array
+		require.False(t, entries[1].Balance.Valid)
+	})
+}
+
+func Test_ApplyMovements(t *testing.T) {
+	t.Parallel()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	psql := NewPostgresStorage(db)
+
+	colums := []string{
+		"id",
+		"first_name",
+		"last_name",
+		"card_number",
+		"card_expiry_month",
+		"card_expiry_year",
+		"card_security_code",
+		"balance", "blocked_money",
+		"created_at",
+		"escrow_money",
+	}
+	accountRow := func(id uuid.UUID, balance, blocked uint64) *sqlmock.Rows {
+		return sqlmock.NewRows(colums).AddRow(
+			id, "Pasha", "Volkov", "4444444444444444", "12", "24", "123",
+			balance, blocked, time.Now(), 0,
+		)
+	}
+	lockQuery := regexp.QuoteMeta(`SELECT id, balance, blocked_money, escrow_money FROM account
+				WHERE id = ANY($1::uuid[])
+				ORDER BY id
+				FOR UPDATE`)
+	updateQuery := regexp.QuoteMeta(`UPDATE account
+				SET balance = balance + $1,
+					blocked_money = blocked_money + $2,
+					escrow_money = escrow_money + $3
+				WHERE id = $4
+				RETURNING *`)
+	lockColumns := []string{"id", "balance", "blocked_money", "escrow_money"}
+
+	t.Run("GetAccountsByIDs", func(t *testing.T) {
+		customer, merchant := uuid.New(), uuid.New()
+		rows := accountRow(customer, 100, 0).AddRow(
+			merchant, "Shop", "Owner", "4444444444444443", "12", "24", "123",
+			0, 0, time.Now(), 0,
+		)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM account
+				WHERE id = ANY($1::uuid[])`)).WillReturnRows(rows)
+
+		accounts, err := psql.GetAccountsByIDs(context.Background(), []string{customer.String(), merchant.String()})
+		require.NoError(t, err)
+		require.Len(t, accounts, 2)
+		require.Equal(t, merchant, accounts[1].ID)
+	})
+
+	t.Run("ApplyMovements", func(t *testing.T) {
+		customer, merchant := uuid.New(), uuid.New()
+		movements := []*authpb.Movement{
+			{AccountId: customer.String(), Balance: -30},
+			{AccountId: merchant.String(), EscrowMoney: 30},
 		}
-		colums := []string{
-			"id",
-			"first_name",
-			"last_name",
-			"card_number",
-			"card_expiry_month",
-			"card_expiry_year",
-			"card_security_code",
-			"balance", "blocked_money",
-			"statement",
-			"created_at",
+		entry, err := types.NewStatementEntry(&authpb.StatementRequest{
+			AccountId: customer.String(),
+			PaymentId: uuid.New().String(),
+			Amount:    -30,
+		})
+		require.NoError(t, err)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockQuery).WillReturnRows(sqlmock.NewRows(lockColumns).
+			AddRow(customer.String(), 100, 0, 0).
+			AddRow(merchant.String(), 0, 0, 0))
+		mock.ExpectQuery(updateQuery).WithArgs(-30, 0, 0, customer.String()).WillReturnRows(accountRow(customer, 70, 0))
+		mock.ExpectQuery(updateQuery).WithArgs(0, 0, 30, merchant.String()).WillReturnRows(accountRow(merchant, 0, 0))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO statement_entry`)).WillReturnRows(sqlmock.NewRows([]string{
+			"entry_id", "account_id", "payment_id", "direction", "amount", "balance", "created_at",
+			"counterparty_id", "counterparty_name", "operation", "status", "currency",
+		}).AddRow(1, customer, entry.PaymentID, types.Debit, 30, 70, entry.CreatedAt, nil, "", "", "", ""))
+		mock.ExpectCommit()
+
+		accounts, err := psql.ApplyMovements(context.Background(), movements, []*types.StatementEntry{entry})
+		require.NoError(t, err)
+		require.Len(t, accounts, 2)
+		require.Equal(t, uint64(70), accounts[0].Balance)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Insufficient funds", func(t *testing.T) {
+		customer := uuid.New()
+		// both movements of the account are checked together
+		movements := []*authpb.Movement{
+			{AccountId: customer.String(), Balance: -30},
+			{AccountId: customer.String(), Balance: -30},
 		}
-		rows := sqlmock.NewRows(colums).AddRow(
-			account.ID,
-			"Pasha1",
-			"volkov1",
-			"444444444444444",
-			"12",
-			"24",
-			"924",
-			50,
-			50,
-			pq.Array([]string{req.PaymentId}),
-			account.CreatedAt,
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockQuery).WillReturnRows(sqlmock.NewRows(lockColumns).
+			AddRow(customer.String(), 50, 0, 0))
+		mock.ExpectRollback()
+
+		_, err := psql.ApplyMovements(context.Background(), movements, nil)
+		require.ErrorIs(t, err, types.ErrInsufficientFunds)
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockQuery).WillReturnRows(sqlmock.NewRows(lockColumns))
+		mock.ExpectRollback()
+
+		_, err := psql.ApplyMovements(context.Background(), []*authpb.Movement{
+			{AccountId: uuid.New().String(), Balance: 10},
+		}, nil)
+		require.ErrorIs(t, err, sql.ErrNoRows)
+	})
+}
+
+func Test_Hold(t *testing.T) {
+	t.Parallel()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	psql := NewPostgresStorage(db)
+
+	colums := []string{
+		"id",
+		"first_name",
+		"last_name",
+		"card_number",
+		"card_expiry_month",
+		"card_expiry_year",
+		"card_security_code",
+		"balance", "blocked_money",
+		"created_at",
+		"escrow_money",
+	}
+	accountRow := func(id uuid.UUID, balance, blocked uint64) *sqlmock.Rows {
+		return sqlmock.NewRows(colums).AddRow(
+			id, "Pasha", "Volkov", "4444444444444444", "12", "24", "123",
+			balance, blocked, time.Now(), 0,
 		)
+	}
+	holdColumns := []string{
+		"hold_id", "account_id", "amount", "captured_amount", "state",
+		"reference", "expires_at", "created_at", "updated_at",
+	}
+	holdRow := func(hold *types.Hold) *sqlmock.Rows {
+		return sqlmock.NewRows(holdColumns).AddRow(
+			hold.ID, hold.AccountID, hold.Amount, hold.CapturedAmount, hold.State,
+			hold.Reference, hold.ExpiresAt, hold.CreatedAt, hold.UpdatedAt,
+		)
+	}
+	newHold := func(account uuid.UUID, amount uint64) *types.Hold {
+		return &types.Hold{
+			ID:        uuid.New(),
+			AccountID: account,
+			Amount:    amount,
+			State:     types.HoldActive,
+			Reference: uuid.New().String(),
+			ExpiresAt: time.Now().Add(time.Hour),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		}
+	}
+	lockQuery := regexp.QuoteMeta(`SELECT id, balance, blocked_money, escrow_money FROM account
+				WHERE id = ANY($1::uuid[])
+				ORDER BY id
+				FOR UPDATE`)
+	updateQuery := regexp.QuoteMeta(`UPDATE account
+				SET balance = balance + $1,
+					blocked_money = blocked_money + $2,
+					escrow_money = escrow_money + $3
+				WHERE id = $4
+				RETURNING *`)
+	lockColumns := []string{"id", "balance", "blocked_money", "escrow_money"}
+	holdQuery := regexp.QuoteMeta(`SELECT * FROM hold WHERE hold_id = $1 FOR UPDATE`)
+	saveHoldQuery := regexp.QuoteMeta(`UPDATE hold
+				SET amount = $1,
+					captured_amount = $2,
+					state = $3,
+					updated_at = $4
+				WHERE hold_id = $5
+				RETURNING *`)
+
+	t.Run("CreateHold", func(t *testing.T) {
+		customer := uuid.New()
+		hold := newHold(customer, 30)
 
 		mock.ExpectBegin()
-		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE account
-		SET statement = array_append(statement, $1)
-		WHERE id = $2
-		RETURNING *`)).WithArgs(req.PaymentId, req.AccountId).WillReturnRows(rows)
+		mock.ExpectQuery(lockQuery).WillReturnRows(sqlmock.NewRows(lockColumns).
+			AddRow(customer.String(), 100, 0, 0))
+		mock.ExpectQuery(updateQuery).WithArgs(-30, 30, 0, customer.String()).WillReturnRows(accountRow(customer, 70, 30))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO hold (hold_id, account_id, amount,
+		captured_amount, state, reference,
+		expires_at, created_at, updated_at)
+			VALUES ($1, $2, $3, 0, $4, $5, $6, $7, $8)
+			RETURNING *`)).WithArgs(
+			hold.ID, hold.AccountID, hold.Amount, hold.State, hold.Reference,
+			hold.ExpiresAt, hold.CreatedAt, hold.UpdatedAt,
+		).WillReturnRows(holdRow(hold))
+		mock.ExpectCommit()
+
+		saved, err := psql.CreateHold(context.Background(), hold, nil)
+		require.NoError(t, err)
+		require.Equal(t, hold.ID, saved.ID)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("CreateHold insufficient funds", func(t *testing.T) {
+		customer := uuid.New()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockQuery).WillReturnRows(sqlmock.NewRows(lockColumns).
+			AddRow(customer.String(), 10, 0, 0))
+		mock.ExpectRollback()
+
+		_, err := psql.CreateHold(context.Background(), newHold(customer, 30), nil)
+		require.ErrorIs(t, err, types.ErrInsufficientFunds)
+	})
+
+	t.Run("CaptureHold", func(t *testing.T) {
+		customer, merchant := uuid.New(), uuid.New()
+		hold := newHold(customer, 30)
+		captured := *hold
+		captured.CapturedAmount = 20
+		captured.State = types.HoldCaptured
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(holdQuery).WithArgs(hold.ID).WillReturnRows(holdRow(hold))
+		mock.ExpectQuery(lockQuery).WillReturnRows(sqlmock.NewRows(lockColumns).
+			AddRow(customer.String(), 70, 30, 0).
+			AddRow(merchant.String(), 0, 0, 0))
+		// the rest of the hold is returned to the customer
+		mock.ExpectQuery(updateQuery).WithArgs(10, -30, 0, customer.String()).WillReturnRows(accountRow(customer, 80, 0))
+		mock.ExpectQuery(updateQuery).WithArgs(20, 0, 0, merchant.String()).WillReturnRows(accountRow(merchant, 20, 0))
+		mock.ExpectQuery(saveHoldQuery).WithArgs(30, 20, types.HoldCaptured, sqlmock.AnyArg(), hold.ID).
+			WillReturnRows(holdRow(&captured))
+		mock.ExpectCommit()
+
+		saved, err := psql.CaptureHold(context.Background(), hold.ID, []*authpb.HoldTransfer{
+			{AccountId: merchant.String(), Amount: 20},
+		}, nil)
+		require.NoError(t, err)
+		require.Equal(t, types.HoldCaptured, saved.State)
+		require.Equal(t, uint64(20), saved.CapturedAmount)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("CaptureHold expired", func(t *testing.T) {
+		hold := newHold(uuid.New(), 30)
+		hold.ExpiresAt = time.Now().Add(-time.Minute)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(holdQuery).WithArgs(hold.ID).WillReturnRows(holdRow(hold))
+		mock.ExpectRollback()
+
+		_, err := psql.CaptureHold(context.Background(), hold.ID, []*authpb.HoldTransfer{
+			{AccountId: uuid.New().String(), Amount: 30},
+		}, nil)
+		require.ErrorIs(t, err, types.ErrHoldNotActive)
+	})
+
+	t.Run("CaptureHold more than held", func(t *testing.T) {
+		hold := newHold(uuid.New(), 30)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(holdQuery).WithArgs(hold.ID).WillReturnRows(holdRow(hold))
+		mock.ExpectRollback()
+
+		_, err := psql.CaptureHold(context.Background(), hold.ID, []*authpb.HoldTransfer{
+			{AccountId: uuid.New().String(), Amount: 40},
+		}, nil)
+		require.ErrorIs(t, err, types.ErrHoldAmount)
+	})
+
+	t.Run("CaptureHold overflow", func(t *testing.T) {
+		hold := newHold(uuid.New(), 30)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(holdQuery).WithArgs(hold.ID).WillReturnRows(holdRow(hold))
+		mock.ExpectRollback()
+
+		// the sum of the transfers wraps around to 20
+		_, err := psql.CaptureHold(context.Background(), hold.ID, []*authpb.HoldTransfer{
+			{AccountId: uuid.New().String(), Amount: 10},
+			{AccountId: uuid.New().String(), Amount: math.MaxUint64},
+			{AccountId: uuid.New().String(), Amount: 11},
+		}, nil)
+		require.ErrorIs(t, err, types.ErrHoldAmount)
+	})
+
+	t.Run("ReleaseHold", func(t *testing.T) {
+		customer := uuid.New()
+		hold := newHold(customer, 30)
+		// expired holds are released too
+		hold.ExpiresAt = time.Now().Add(-time.Minute)
+		released := *hold
+		released.State = types.HoldExpired
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(holdQuery).WithArgs(hold.ID).WillReturnRows(holdRow(hold))
+		mock.ExpectQuery(lockQuery).WillReturnRows(sqlmock.NewRows(lockColumns).
+			AddRow(customer.String(), 70, 30, 0))
+		mock.ExpectQuery(updateQuery).WithArgs(30, -30, 0, customer.String()).WillReturnRows(accountRow(customer, 100, 0))
+		mock.ExpectQuery(saveHoldQuery).WithArgs(30, 0, types.HoldExpired, sqlmock.AnyArg(), hold.ID).
+			WillReturnRows(holdRow(&released))
+		mock.ExpectCommit()
+
+		saved, err := psql.ReleaseHold(context.Background(), hold.ID, types.HoldExpired, nil)
+		require.NoError(t, err)
+		require.Equal(t, types.HoldExpired, saved.State)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("ReleaseHold not active", func(t *testing.T) {
+		hold := newHold(uuid.New(), 30)
+		hold.State = types.HoldCaptured
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(holdQuery).WithArgs(hold.ID).WillReturnRows(holdRow(hold))
+		mock.ExpectRollback()
+
+		_, err := psql.ReleaseHold(context.Background(), hold.ID, types.HoldReleased, nil)
+		require.ErrorIs(t, err, types.ErrHoldNotActive)
+	})
+
+	t.Run("ChangeHold", func(t *testing.T) {
+		customer := uuid.New()
+		hold := newHold(customer, 30)
+		changed := *hold
+		changed.Amount = 20
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(holdQuery).WithArgs(hold.ID).WillReturnRows(holdRow(hold))
+		mock.ExpectQuery(lockQuery).WillReturnRows(sqlmock.NewRows(lockColumns).
+			AddRow(customer.String(), 70, 30, 0))
+		mock.ExpectQuery(updateQuery).WithArgs(10, -10, 0, customer.String()).WillReturnRows(accountRow(customer, 80, 20))
+		mock.ExpectQuery(saveHoldQuery).WithArgs(20, 0, types.HoldActive, sqlmock.AnyArg(), hold.ID).
+			WillReturnRows(holdRow(&changed))
+		mock.ExpectCommit()
+
+		saved, err := psql.ChangeHold(context.Background(), hold.ID, -10, nil)
+		require.NoError(t, err)
+		require.Equal(t, uint64(20), saved.Amount)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("ChangeHold below zero", func(t *testing.T) {
+		hold := newHold(uuid.New(), 30)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(holdQuery).WithArgs(hold.ID).WillReturnRows(holdRow(hold))
+		mock.ExpectRollback()
+
+		_, err := psql.ChangeHold(context.Background(), hold.ID, -30, nil)
+		require.ErrorIs(t, err, types.ErrHoldAmount)
+	})
+
+	t.Run("ChangeHold overflow", func(t *testing.T) {
+		hold := newHold(uuid.New(), 30)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(holdQuery).WithArgs(hold.ID).WillReturnRows(holdRow(hold))
+		mock.ExpectRollback()
+
+		_, err := psql.ChangeHold(context.Background(), hold.ID, math.MaxInt64, nil)
+		require.ErrorIs(t, err, types.ErrHoldAmount)
+	})
+
+	t.Run("GetHolds", func(t *testing.T) {
+		hold := newHold(uuid.New(), 30)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM hold
+				WHERE account_id = $1
+					AND ($2 = '' OR state = $2)
+				ORDER BY created_at`)).WithArgs(hold.AccountID.String(), types.HoldActive).WillReturnRows(holdRow(hold))
+
+		holds, err := psql.GetHolds(context.Background(), hold.AccountID.String(), types.HoldActive)
+		require.NoError(t, err)
+		require.Len(t, holds, 1)
+		require.Equal(t, hold.ID, holds[0].ID)
+	})
+
+	t.Run("GetExpiredHolds", func(t *testing.T) {
+		hold := newHold(uuid.New(), 30)
+		now := time.Now()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM hold
+				WHERE state = $1 AND expires_at <= $2
+				ORDER BY expires_at`)).WithArgs(types.HoldActive, now).WillReturnRows(holdRow(hold))
+
+		holds, err := psql.GetExpiredHolds(context.Background(), now)
+		require.NoError(t, err)
+		require.Len(t, holds, 1)
+	})
+}
+
+func Test_TOTP(t *testing.T) {
+	t.Parallel()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	psql := NewPostgresStorage(db)
+
+	uid := uuid.New()
+	now := time.Now()
+
+	t.Run("SaveTOTPSecret", func(t *testing.T) {
+		query := regexp.QuoteMeta(`INSERT INTO totp (account_id, secret)
+			VALUES ($1, $2)
+			ON CONFLICT (account_id) DO UPDATE
+				SET secret = $2, enabled = false, last_step = 0, updated_at = now()
+				WHERE totp.enabled = false`)
+		mock.ExpectExec(query).WithArgs(uid, "SECRET").WillReturnResult(sqlmock.NewResult(0, 1))
+		require.NoError(t, psql.SaveTOTPSecret(context.Background(), uid, "SECRET"))
+
+		// enabled secret is not replaced
+		mock.ExpectExec(query).WithArgs(uid, "SECRET").WillReturnResult(sqlmock.NewResult(0, 0))
+		require.ErrorIs(t, psql.SaveTOTPSecret(context.Background(), uid, "SECRET"), types.ErrTOTPEnabled)
+	})
+
+	t.Run("GetTOTP", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{
+			"account_id", "secret", "enabled", "last_step", "created_at", "updated_at",
+		}).AddRow(uid, "SECRET", true, 100, now, now)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM totp WHERE account_id = $1`)).
+			WithArgs(uid).WillReturnRows(rows)
+
+		totp, err := psql.GetTOTP(context.Background(), uid)
+		require.NoError(t, err)
+		require.Equal(t, &types.TOTP{
+			AccountID: uid,
+			Secret:    "SECRET",
+			Enabled:   true,
+			LastStep:  100,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}, totp)
+	})
+
+	t.Run("UseTOTPStep", func(t *testing.T) {
+		query := regexp.QuoteMeta(`UPDATE totp SET last_step = $1, updated_at = now()
+			WHERE account_id = $2 AND last_step < $1`)
+		mock.ExpectExec(query).WithArgs(101, uid).WillReturnResult(sqlmock.NewResult(0, 1))
+		ok, err := psql.UseTOTPStep(context.Background(), uid, 101)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		// code of the step is accepted once
+		mock.ExpectExec(query).WithArgs(101, uid).WillReturnResult(sqlmock.NewResult(0, 0))
+		ok, err = psql.UseTOTPStep(context.Background(), uid, 101)
+		require.NoError(t, err)
+		require.False(t, ok)
+	})
+
+	t.Run("EnableTOTP", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE totp SET enabled = true, updated_at = now()
+			WHERE account_id = $1`)).WithArgs(uid).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM recovery_code WHERE account_id = $1`)).
+			WithArgs(uid).WillReturnResult(sqlmock.NewResult(0, 0))
+		for _, hash := range []string{"hash1", "hash2"} {
+			mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO recovery_code (account_id, code_hash) VALUES ($1, $2)`)).
+				WithArgs(uid, hash).WillReturnResult(sqlmock.NewResult(0, 1))
+		}
 		mock.ExpectCommit()
-		arr, err := psql.UpdateStatement(context.Background(), req)
+
+		require.NoError(t, psql.EnableTOTP(context.Background(), uid, []string{"hash1", "hash2"}))
+	})
+
+	t.Run("UseRecoveryCode", func(t *testing.T) {
+		query := regexp.QuoteMeta(`UPDATE recovery_code SET used_at = now()
+			WHERE code_id = (
+				SELECT code_id FROM recovery_code
+				WHERE account_id = $1 AND code_hash = $2 AND used_at IS NULL
+				LIMIT 1
+				FOR UPDATE
+			)`)
+		mock.ExpectExec(query).WithArgs(uid, "hash1").WillReturnResult(sqlmock.NewResult(0, 1))
+		ok, err := psql.UseRecoveryCode(context.Background(), uid, "hash1")
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		mock.ExpectExec(query).WithArgs(uid, "hash1").WillReturnResult(sqlmock.NewResult(0, 0))
+		ok, err = psql.UseRecoveryCode(context.Background(), uid, "hash1")
 		require.NoError(t, err)
-		require.NotNil(t, arr)
+		require.False(t, ok)
+	})
+
+	t.Run("DisableTOTP", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM recovery_code WHERE account_id = $1`)).
+			WithArgs(uid).WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM totp WHERE account_id = $1`)).
+			WithArgs(uid).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		require.NoError(t, psql.DisableTOTP(context.Background(), uid))
+		require.NoError(t, mock.ExpectationsWereMet())
 	})
-}
\ No newline at end of file
+}
+
+func Test_Role(t *testing.T) {
+	t.Parallel()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	psql := NewPostgresStorage(db)
+
+	uid := uuid.New()
+	getQuery := regexp.QuoteMeta(`SELECT role FROM account_role WHERE account_id = $1`)
+	setQuery := regexp.QuoteMeta(`INSERT INTO account_role (account_id, role)
+		VALUES ($1, $2)
+		ON CONFLICT (account_id) DO UPDATE
+			SET role = $2, updated_at = now()`)
+
+	t.Run("GetRole", func(t *testing.T) {
+		mock.ExpectQuery(getQuery).WithArgs(uid).
+			WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow(types.RoleAdmin))
+		role, err := psql.GetRole(context.Background(), uid)
+		require.NoError(t, err)
+		require.Equal(t, types.RoleAdmin, role)
+	})
+
+	t.Run("GetRole without role", func(t *testing.T) {
+		mock.ExpectQuery(getQuery).WithArgs(uid).WillReturnError(sql.ErrNoRows)
+		role, err := psql.GetRole(context.Background(), uid)
+		require.NoError(t, err)
+		require.Equal(t, types.RoleCustomer, role)
+	})
+
+	t.Run("SetRole", func(t *testing.T) {
+		mock.ExpectExec(setQuery).WithArgs(uid, types.RoleSupport).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		require.NoError(t, psql.SetRole(context.Background(), uid, types.RoleSupport))
+	})
+
+	t.Run("SetRole without account", func(t *testing.T) {
+		mock.ExpectExec(setQuery).WithArgs(uid, types.RoleSupport).
+			WillReturnError(&pq.Error{Code: "23503"})
+		err := psql.SetRole(context.Background(), uid, types.RoleSupport)
+		require.ErrorIs(t, err, sql.ErrNoRows)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func Test_OAuthClient(t *testing.T) {
+	t.Parallel()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	psql := NewPostgresStorage(db)
+
+	uid := uuid.New()
+	now := time.Now()
+	client := &types.OAuthClient{
+		ClientID:   "cli_1",
+		AccountID:  uid,
+		Name:       "shop",
+		SecretHash: "hash",
+		Scopes:     []string{types.ScopePaymentsWrite, types.ScopePaymentsRead},
+	}
+	columns := []string{"client_id", "account_id", "name", "secret_hash", "scopes", "created_at"}
+
+	t.Run("SaveOAuthClient", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO oauth_client (client_id, account_id, name, secret_hash, scopes)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at`)).
+			WithArgs("cli_1", uid, "shop", "hash", pq.Array(client.Scopes)).
+			WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(now))
+
+		saved, err := psql.SaveOAuthClient(context.Background(), client)
+		require.NoError(t, err)
+		require.Equal(t, now, saved.CreatedAt)
+	})
+
+	t.Run("GetOAuthClient", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM oauth_client WHERE client_id = $1`)).WithArgs("cli_1").
+			WillReturnRows(sqlmock.NewRows(columns).AddRow("cli_1", uid, "shop", "hash", "{payments:write,payments:read}", now))
+
+		got, err := psql.GetOAuthClient(context.Background(), "cli_1")
+		require.NoError(t, err)
+		require.Equal(t, client.Scopes, got.Scopes)
+		require.Equal(t, uid, got.AccountID)
+	})
+
+	t.Run("GetOAuthClients", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM oauth_client WHERE account_id = $1`)).WithArgs(uid).
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow("cli_1", uid, "shop", "hash", "{payments:read}", now).
+				AddRow("cli_2", uid, "app", "hash", "{payments:write}", now))
+
+		clients, err := psql.GetOAuthClients(context.Background(), uid)
+		require.NoError(t, err)
+		require.Len(t, clients, 2)
+		require.Equal(t, []string{types.ScopePaymentsWrite}, clients[1].Scopes)
+	})
+
+	t.Run("DeleteOAuthClient", func(t *testing.T) {
+		query := regexp.QuoteMeta(`DELETE FROM oauth_client WHERE client_id = $1 AND account_id = $2`)
+		mock.ExpectExec(query).WithArgs("cli_1", uid).WillReturnResult(sqlmock.NewResult(0, 1))
+		require.NoError(t, psql.DeleteOAuthClient(context.Background(), uid, "cli_1"))
+
+		// client of another account
+		mock.ExpectExec(query).WithArgs("cli_1", uid).WillReturnResult(sqlmock.NewResult(0, 0))
+		require.ErrorIs(t, psql.DeleteOAuthClient(context.Background(), uid, "cli_1"), sql.ErrNoRows)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func Test_EmailVerification(t *testing.T) {
+	t.Parallel()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	psql := NewPostgresStorage(db)
+
+	uid := uuid.New()
+	getQuery := regexp.QuoteMeta(`SELECT EXISTS (SELECT 1 FROM email_verification WHERE account_id = $1)`)
+	setQuery := regexp.QuoteMeta(`INSERT INTO email_verification (account_id)
+		VALUES ($1)
+		ON CONFLICT (account_id) DO NOTHING`)
+
+	t.Run("IsEmailVerified", func(t *testing.T) {
+		mock.ExpectQuery(getQuery).WithArgs(uid).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		verified, err := psql.IsEmailVerified(context.Background(), uid)
+		require.NoError(t, err)
+		require.True(t, verified)
+	})
+
+	t.Run("SetEmailVerified", func(t *testing.T) {
+		mock.ExpectExec(setQuery).WithArgs(uid).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		require.NoError(t, psql.SetEmailVerified(context.Background(), uid))
+	})
+
+	t.Run("SetEmailVerified without account", func(t *testing.T) {
+		mock.ExpectExec(setQuery).WithArgs(uid).
+			WillReturnError(&pq.Error{Code: "23503"})
+		err := psql.SetEmailVerified(context.Background(), uid)
+		require.ErrorIs(t, err, sql.ErrNoRows)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
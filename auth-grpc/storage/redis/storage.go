@@ -2,9 +2,13 @@ package redisrepo
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
-	"fmt"
-	"math/rand"
+	"errors"
+	"sort"
+	"strconv"
 	"time"
 
 	"github.com/Edbeer/auth-grpc/types"
@@ -22,53 +26,427 @@ func NewRedisStorage(redis  *redis.Client) *RedisStorage {
 	}
 }
 
-// Add refresh token in redis
-func (s *RedisStorage) CreateSession(ctx context.Context, session *types.Session, expire int) (string, error) {
-	session.RefreshToken = newRefreshToken()
+// refresh tokens are stored as sha256 hashes, rotated tokens are kept
+// as rotated until they expire so that their reuse is detected;
+// family of the session holds the hashes of all its tokens
+func sessionKey(tokenHash string) string {
+	return "session:" + tokenHash
+}
+
+func familyKey(familyID string) string {
+	return "family:" + familyID
+}
+
+// session of the family, it is listed and revoked by the user
+func sessionInfoKey(familyID string) string {
+	return "session-info:" + familyID
+}
+
+// families of the user
+func userSessionsKey(userID uuid.UUID) string {
+	return "user-sessions:" + userID.String()
+}
 
-	sessionBytes, err := json.Marshal(&session)
+// retries of the rotation raced by another rotation
+const maxRotateRetries = 3
+
+// Add refresh token of the session in redis, new family if the session has none
+func (s *RedisStorage) CreateSession(ctx context.Context, session *types.Session, expire int) (string, error) {
+	refreshToken, err := newRefreshToken()
 	if err != nil {
 		return "", err
 	}
-	if err := s.redis.Set(ctx, session.RefreshToken, sessionBytes, time.Second*time.Duration(expire)).Err(); err != nil {
+	session.RefreshToken = refreshToken
+	if session.FamilyID == "" {
+		session.FamilyID = uuid.New().String()
+	}
+	if session.CreatedAt.IsZero() {
+		session.CreatedAt = time.Now()
+	}
+	session.Rotated = false
+
+	pipe := s.redis.TxPipeline()
+	if err := addSession(ctx, pipe, session, expire); err != nil {
+		return "", err
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
 		return "", err
 	}
 
 	return session.RefreshToken, nil
 }
 
-// Get user id from session
-func (s *RedisStorage) GetUserID(ctx context.Context, refreshToken string) (uuid.UUID, error) {
-	sessionBytes, err := s.redis.Get(ctx, refreshToken).Bytes()
+// Rotate the refresh token: the token is marked as rotated and a new token
+// of the same family is returned; reuse of a rotated token revokes the family
+func (s *RedisStorage) RotateSession(ctx context.Context, refreshToken string, expire int) (*types.Session, error) {
+	key := sessionKey(hashToken(refreshToken))
+	for i := 0; i < maxRotateRetries; i++ {
+		var rotated *types.Session
+		err := s.redis.Watch(ctx, func(tx *redis.Tx) error {
+			session, err := getSession(ctx, tx, key)
+			if err != nil {
+				return err
+			}
+			if session.Rotated {
+				return types.ErrTokenReused
+			}
+			// family is revoked
+			n, err := tx.Exists(ctx, familyKey(session.FamilyID)).Result()
+			if err != nil {
+				return err
+			}
+			if n == 0 {
+				return types.ErrSessionNotFound
+			}
+			next, err := newRefreshToken()
+			if err != nil {
+				return err
+			}
+			// client of the session is kept
+			copied := *session
+			rotated = &copied
+			rotated.RefreshToken = next
+			session.Rotated = true
+			sessionBytes, err := json.Marshal(session)
+			if err != nil {
+				return err
+			}
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.Set(ctx, key, sessionBytes, time.Second*time.Duration(expire))
+				return addSession(ctx, pipe, rotated, expire)
+			})
+			return err
+		}, key)
+		switch {
+		case err == nil:
+			return rotated, nil
+		case errors.Is(err, types.ErrTokenReused):
+			familyID, _ := s.familyOf(ctx, key)
+			if err := s.RevokeFamily(ctx, familyID); err != nil {
+				return nil, err
+			}
+			return nil, types.ErrTokenReused
+		case errors.Is(err, redis.TxFailedErr):
+			// rotated concurrently, the retry sees the rotated token
+			continue
+		default:
+			return nil, err
+		}
+	}
+	return nil, redis.TxFailedErr
+}
+
+// Delete session of the refresh token with all tokens of its family
+func (s *RedisStorage) DeleteSession(ctx context.Context, refreshToken string) error {
+	familyID, err := s.familyOf(ctx, sessionKey(hashToken(refreshToken)))
+	if err != nil {
+		// signed out already
+		if errors.Is(err, types.ErrSessionNotFound) {
+			return nil
+		}
+		return err
+	}
+	return s.RevokeFamily(ctx, familyID)
+}
+
+// Delete all tokens of the family
+func (s *RedisStorage) RevokeFamily(ctx context.Context, familyID string) error {
+	if familyID == "" {
+		return nil
+	}
+	hashes, err := s.redis.SMembers(ctx, familyKey(familyID)).Result()
 	if err != nil {
-		return uuid.Nil , err
+		return err
+	}
+	keys := []string{familyKey(familyID), sessionInfoKey(familyID)}
+	for _, hash := range hashes {
+		keys = append(keys, sessionKey(hash))
+	}
+	pipe := s.redis.TxPipeline()
+	// user of the family is known from its session
+	if info, err := getSession(ctx, s.redis, sessionInfoKey(familyID)); err == nil {
+		pipe.SRem(ctx, userSessionsKey(info.UserID), familyID)
+	}
+	pipe.Del(ctx, keys...)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// Get session of the refresh token
+func (s *RedisStorage) GetSession(ctx context.Context, refreshToken string) (*types.Session, error) {
+	return getSession(ctx, s.redis, sessionKey(hashToken(refreshToken)))
+}
+
+// Get sessions of the user, oldest first
+func (s *RedisStorage) ListSessions(ctx context.Context, userID uuid.UUID) ([]*types.Session, error) {
+	familyIDs, err := s.redis.SMembers(ctx, userSessionsKey(userID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	sessions := make([]*types.Session, 0, len(familyIDs))
+	for _, familyID := range familyIDs {
+		session, err := getSession(ctx, s.redis, sessionInfoKey(familyID))
+		if err != nil {
+			if errors.Is(err, types.ErrSessionNotFound) {
+				// expired family
+				s.redis.SRem(ctx, userSessionsKey(userID), familyID)
+				continue
+			}
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].CreatedAt.Before(sessions[j].CreatedAt)
+	})
+	return sessions, nil
+}
+
+// Revoke session of the user
+func (s *RedisStorage) RevokeSession(ctx context.Context, userID uuid.UUID, familyID string) error {
+	session, err := getSession(ctx, s.redis, sessionInfoKey(familyID))
+	if err != nil {
+		return err
+	}
+	// sessions of other users are not found
+	if session.UserID != userID {
+		return types.ErrSessionNotFound
+	}
+	return s.RevokeFamily(ctx, familyID)
+}
+
+// Revoke all sessions of the user
+func (s *RedisStorage) RevokeAllSessions(ctx context.Context, userID uuid.UUID) error {
+	familyIDs, err := s.redis.SMembers(ctx, userSessionsKey(userID)).Result()
+	if err != nil {
+		return err
+	}
+	for _, familyID := range familyIDs {
+		if err := s.RevokeFamily(ctx, familyID); err != nil {
+			return err
+		}
+	}
+	return s.redis.Del(ctx, userSessionsKey(userID)).Err()
+}
+
+// family of the stored token
+func (s *RedisStorage) familyOf(ctx context.Context, key string) (string, error) {
+	session, err := getSession(ctx, s.redis, key)
+	if err != nil {
+		return "", err
+	}
+	return session.FamilyID, nil
+}
+
+func getSession(ctx context.Context, c redis.Cmdable, key string) (*types.Session, error) {
+	sessionBytes, err := c.Get(ctx, key).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, types.ErrSessionNotFound
+		}
+		return nil, err
 	}
 	session := &types.Session{}
 	if err = json.Unmarshal(sessionBytes, session); err != nil {
-		return uuid.Nil, err
+		return nil, err
 	}
-
-	return session.UserID, nil
+	return session, nil
 }
 
-// Delete session cookie
-func (s *RedisStorage) DeleteSession(ctx context.Context, refreshToken string) error {
-	if err := s.redis.Del(ctx, refreshToken).Err(); err != nil {
+// add the token of the session to its family, the family lives
+// as long as its last token
+func addSession(ctx context.Context, pipe redis.Pipeliner, session *types.Session, expire int) error {
+	sessionBytes, err := json.Marshal(session)
+	if err != nil {
 		return err
 	}
+	hash := hashToken(session.RefreshToken)
+	ttl := time.Second * time.Duration(expire)
+	pipe.Set(ctx, sessionKey(hash), sessionBytes, ttl)
+	pipe.SAdd(ctx, familyKey(session.FamilyID), hash)
+	pipe.Expire(ctx, familyKey(session.FamilyID), ttl)
+	pipe.Set(ctx, sessionInfoKey(session.FamilyID), sessionBytes, ttl)
+	// families of the user expire after the last of them
+	pipe.SAdd(ctx, userSessionsKey(session.UserID), session.FamilyID)
+	pipe.Expire(ctx, userSessionsKey(session.UserID), ttl)
 	return nil
 }
 
-func newRefreshToken() string {
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func newRefreshToken() (string, error) {
 	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
 
-	s := rand.NewSource(time.Now().Unix())
-	r := rand.New(s)
+// key of the mfa challenge
+func mfaKey(token string) string {
+	return "mfa:" + token
+}
+
+// Add mfa challenge of the sign-in waiting for the second factor
+func (s *RedisStorage) CreateMFAChallenge(ctx context.Context, accountID uuid.UUID, expire int) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+	pipe := s.redis.TxPipeline()
+	pipe.HSet(ctx, mfaKey(token), "id", accountID.String())
+	pipe.Expire(ctx, mfaKey(token), time.Second*time.Duration(expire))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", err
+	}
+	return token, nil
+}
 
-	_, err := r.Read(b)
+// Get account id of the mfa challenge and count the attempt
+func (s *RedisStorage) GetMFAChallenge(ctx context.Context, token string) (uuid.UUID, int64, error) {
+	pipe := s.redis.TxPipeline()
+	idCmd := pipe.HGet(ctx, mfaKey(token), "id")
+	attemptsCmd := pipe.HIncrBy(ctx, mfaKey(token), "attempts", 1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		if err == redis.Nil {
+			// counter of the expired challenge
+			s.redis.Del(ctx, mfaKey(token))
+		}
+		return uuid.Nil, 0, err
+	}
+	accountID, err := uuid.Parse(idCmd.Val())
 	if err != nil {
-		return ""
+		return uuid.Nil, 0, err
+	}
+	return accountID, attemptsCmd.Val(), nil
+}
+
+// Delete mfa challenge
+func (s *RedisStorage) DeleteMFAChallenge(ctx context.Context, token string) error {
+	return s.redis.Del(ctx, mfaKey(token)).Err()
+}
+
+// failed sign-ins and the lockout of the subject, an email or an address
+func loginFailuresKey(subject string) string {
+	return "login-failures:" + subject
+}
+
+func loginLockKey(subject string) string {
+	return "login-lock:" + subject
+}
+
+// Get failed sign-ins and the lockout of the subject
+func (s *RedisStorage) GetLoginAttempts(ctx context.Context, subject string) (*types.LoginAttempts, error) {
+	pipe := s.redis.Pipeline()
+	failuresCmd := pipe.HGetAll(ctx, loginFailuresKey(subject))
+	lockCmd := pipe.PTTL(ctx, loginLockKey(subject))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	attempts := &types.LoginAttempts{}
+	values := failuresCmd.Val()
+	if count, err := strconv.ParseInt(values["count"], 10, 64); err == nil {
+		attempts.Failures = count
 	}
+	if last, err := strconv.ParseInt(values["last"], 10, 64); err == nil {
+		attempts.LastFailure = time.UnixMilli(last)
+	}
+	// no key and no expiry are negative
+	if ttl := lockCmd.Val(); ttl > 0 {
+		attempts.LockedUntil = time.Now().Add(ttl)
+	}
+	return attempts, nil
+}
 
-	return fmt.Sprintf("%x", b)
-}
\ No newline at end of file
+// Count failed sign-in of the subject, failures are forgotten
+// after the window without failures
+func (s *RedisStorage) AddLoginFailure(ctx context.Context, subject string, window time.Duration) (int64, error) {
+	pipe := s.redis.TxPipeline()
+	countCmd := pipe.HIncrBy(ctx, loginFailuresKey(subject), "count", 1)
+	pipe.HSet(ctx, loginFailuresKey(subject), "last", time.Now().UnixMilli())
+	pipe.Expire(ctx, loginFailuresKey(subject), window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return countCmd.Val(), nil
+}
+
+// Lock sign-ins of the subject for the duration, failures start again after it
+func (s *RedisStorage) LockLogin(ctx context.Context, subject string, duration time.Duration) error {
+	pipe := s.redis.TxPipeline()
+	pipe.Set(ctx, loginLockKey(subject), time.Now().Add(duration).Unix(), duration)
+	pipe.Del(ctx, loginFailuresKey(subject))
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Forget failed sign-ins and the lockout of the subjects
+func (s *RedisStorage) ResetLoginAttempts(ctx context.Context, subjects ...string) error {
+	keys := make([]string, 0, 2*len(subjects))
+	for _, subject := range subjects {
+		keys = append(keys, loginFailuresKey(subject), loginLockKey(subject))
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	return s.redis.Del(ctx, keys...).Err()
+}
+
+// tokens sent by email are stored as sha256 hashes; the account has
+// one token of a purpose, a new token replaces the old one.
+// The purpose is part of the key, a token used for another
+// purpose is not found and stays valid for its own
+func emailTokenKey(purpose, tokenHash string) string {
+	return "email-token:" + purpose + ":" + tokenHash
+}
+
+func accountEmailTokenKey(purpose string, accountID uuid.UUID) string {
+	return "account-email-token:" + purpose + ":" + accountID.String()
+}
+
+// Create single-use token of the purpose for the account
+func (s *RedisStorage) CreateEmailToken(ctx context.Context, purpose string, accountID uuid.UUID, expire time.Duration) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+	tokenHash := hashToken(token)
+	old, err := s.redis.Get(ctx, accountEmailTokenKey(purpose, accountID)).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return "", err
+	}
+
+	pipe := s.redis.TxPipeline()
+	if old != "" {
+		pipe.Del(ctx, emailTokenKey(purpose, old))
+	}
+	pipe.Set(ctx, emailTokenKey(purpose, tokenHash), accountID.String(), expire)
+	pipe.Set(ctx, accountEmailTokenKey(purpose, accountID), tokenHash, expire)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Use token of the purpose, it is deleted so that it is used once
+func (s *RedisStorage) UseEmailToken(ctx context.Context, purpose string, token string) (*types.EmailToken, error) {
+	tokenHash := hashToken(token)
+	pipe := s.redis.TxPipeline()
+	getCmd := pipe.Get(ctx, emailTokenKey(purpose, tokenHash))
+	pipe.Del(ctx, emailTokenKey(purpose, tokenHash))
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+	accountID, err := uuid.Parse(getCmd.Val())
+	if err != nil {
+		return nil, types.ErrEmailTokenInvalid
+	}
+	emailToken := &types.EmailToken{AccountID: accountID, Purpose: purpose}
+	s.redis.Del(ctx, accountEmailTokenKey(purpose, emailToken.AccountID))
+	return emailToken, nil
+}
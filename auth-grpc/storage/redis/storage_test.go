@@ -4,6 +4,7 @@ import (
 	"context"
 	"log"
 	"testing"
+	"time"
 
 	"github.com/Edbeer/auth-grpc/types"
 	"github.com/alicebob/miniredis"
@@ -31,7 +32,8 @@ func TestRedis_CreateSession(t *testing.T) {
 	sessionRedisStorage := SetupSessionRedis()
 
 	t.Run("CreateSession", func(t *testing.T) {
-		refreshToken := newRefreshToken()
+		refreshToken, err := newRefreshToken()
+		require.NoError(t, err)
 		session := &types.Session{
 			RefreshToken: refreshToken,
 		}
@@ -42,16 +44,14 @@ func TestRedis_CreateSession(t *testing.T) {
 	})
 }
 
-func TestRedis_GetUserID(t *testing.T) {
+func TestRedis_RotateSession(t *testing.T) {
 	t.Parallel()
 
 	sessionRedisStorage := SetupSessionRedis()
 
-	t.Run("GetSessionByID", func(t *testing.T) {
+	t.Run("Rotate", func(t *testing.T) {
 		userId := uuid.New()
-		refreshToken := newRefreshToken()
 		session := &types.Session{
-			RefreshToken: refreshToken,
 			UserID: userId,
 		}
 
@@ -59,9 +59,52 @@ func TestRedis_GetUserID(t *testing.T) {
 		require.NoError(t, err)
 		require.NotEqual(t, createdSession, "")
 
-		s, err := sessionRedisStorage.GetUserID(context.Background(), createdSession)
+		s, err := sessionRedisStorage.RotateSession(context.Background(), createdSession, 10)
+		require.NoError(t, err)
+		require.Equal(t, userId, s.UserID)
+		require.Equal(t, session.FamilyID, s.FamilyID)
+		require.NotEqual(t, createdSession, s.RefreshToken)
+
+		// next token is rotated as well
+		next, err := sessionRedisStorage.RotateSession(context.Background(), s.RefreshToken, 10)
 		require.NoError(t, err)
-		require.NotNil(t, s)
+		require.Equal(t, session.FamilyID, next.FamilyID)
+	})
+
+	t.Run("Reuse revokes family", func(t *testing.T) {
+		createdSession, err := sessionRedisStorage.CreateSession(context.Background(), &types.Session{
+			UserID: uuid.New(),
+		}, 10)
+		require.NoError(t, err)
+
+		s, err := sessionRedisStorage.RotateSession(context.Background(), createdSession, 10)
+		require.NoError(t, err)
+
+		_, err = sessionRedisStorage.RotateSession(context.Background(), createdSession, 10)
+		require.ErrorIs(t, err, types.ErrTokenReused)
+
+		// current token of the family is revoked too
+		_, err = sessionRedisStorage.RotateSession(context.Background(), s.RefreshToken, 10)
+		require.ErrorIs(t, err, types.ErrSessionNotFound)
+	})
+
+	t.Run("Tokens are stored hashed", func(t *testing.T) {
+		createdSession, err := sessionRedisStorage.CreateSession(context.Background(), &types.Session{
+			UserID: uuid.New(),
+		}, 10)
+		require.NoError(t, err)
+
+		keys, err := sessionRedisStorage.redis.Keys(context.Background(), "*"+createdSession+"*").Result()
+		require.NoError(t, err)
+		require.Empty(t, keys)
+		exists, err := sessionRedisStorage.redis.Exists(context.Background(), sessionKey(hashToken(createdSession))).Result()
+		require.NoError(t, err)
+		require.Equal(t, int64(1), exists)
+	})
+
+	t.Run("Unknown token", func(t *testing.T) {
+		_, err := sessionRedisStorage.RotateSession(context.Background(), "unknown", 10)
+		require.ErrorIs(t, err, types.ErrSessionNotFound)
 	})
 }
 
@@ -72,7 +115,8 @@ func TestRedis_DeleteSession(t *testing.T) {
 
 	t.Run("DeleteSession", func(t *testing.T) {
 		userId := uuid.New()
-		refreshToken := newRefreshToken()
+		refreshToken, err := newRefreshToken()
+		require.NoError(t, err)
 		session := &types.Session{
 			RefreshToken: refreshToken,
 			UserID: userId,
@@ -85,5 +129,180 @@ func TestRedis_DeleteSession(t *testing.T) {
 		err = sessionRedisStorage.DeleteSession(context.Background(), createdSession)
 		require.NoError(t, err)
 		require.Nil(t, err)
+
+		// tokens of the session can't be refreshed
+		_, err = sessionRedisStorage.RotateSession(context.Background(), createdSession, 10)
+		require.ErrorIs(t, err, types.ErrSessionNotFound)
 	})
-}
\ No newline at end of file
+}
+
+func TestRedis_MFAChallenge(t *testing.T) {
+	t.Parallel()
+
+	sessionRedisStorage := SetupSessionRedis()
+
+	t.Run("MFAChallenge", func(t *testing.T) {
+		userId := uuid.New()
+
+		token, err := sessionRedisStorage.CreateMFAChallenge(context.Background(), userId, 10)
+		require.NoError(t, err)
+		require.NotEqual(t, token, "")
+
+		// every get is an attempt
+		for i := int64(1); i <= 2; i++ {
+			id, attempts, err := sessionRedisStorage.GetMFAChallenge(context.Background(), token)
+			require.NoError(t, err)
+			require.Equal(t, userId, id)
+			require.Equal(t, i, attempts)
+		}
+
+		require.NoError(t, sessionRedisStorage.DeleteMFAChallenge(context.Background(), token))
+		_, _, err = sessionRedisStorage.GetMFAChallenge(context.Background(), token)
+		require.ErrorIs(t, err, redis.Nil)
+	})
+}
+
+func TestRedis_LoginAttempts(t *testing.T) {
+	t.Parallel()
+
+	sessionRedisStorage := SetupSessionRedis()
+	ctx := context.Background()
+	subject := "email:pasha@example.com"
+
+	attempts, err := sessionRedisStorage.GetLoginAttempts(ctx, subject)
+	require.NoError(t, err)
+	require.Equal(t, &types.LoginAttempts{}, attempts)
+
+	for i := int64(1); i <= 3; i++ {
+		failures, err := sessionRedisStorage.AddLoginFailure(ctx, subject, time.Minute)
+		require.NoError(t, err)
+		require.Equal(t, i, failures)
+	}
+	attempts, err = sessionRedisStorage.GetLoginAttempts(ctx, subject)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), attempts.Failures)
+	require.WithinDuration(t, time.Now(), attempts.LastFailure, time.Second)
+	require.True(t, attempts.LockedUntil.IsZero())
+
+	t.Run("Lock", func(t *testing.T) {
+		require.NoError(t, sessionRedisStorage.LockLogin(ctx, subject, time.Hour))
+
+		attempts, err := sessionRedisStorage.GetLoginAttempts(ctx, subject)
+		require.NoError(t, err)
+		// failures start again after the lockout
+		require.Zero(t, attempts.Failures)
+		require.WithinDuration(t, time.Now().Add(time.Hour), attempts.LockedUntil, time.Second)
+	})
+
+	t.Run("Reset", func(t *testing.T) {
+		_, err := sessionRedisStorage.AddLoginFailure(ctx, subject, time.Minute)
+		require.NoError(t, err)
+		require.NoError(t, sessionRedisStorage.ResetLoginAttempts(ctx, subject, "ip:10.0.0.1"))
+
+		attempts, err := sessionRedisStorage.GetLoginAttempts(ctx, subject)
+		require.NoError(t, err)
+		require.Equal(t, &types.LoginAttempts{}, attempts)
+	})
+}
+
+func TestRedis_EmailToken(t *testing.T) {
+	t.Parallel()
+
+	sessionRedisStorage := SetupSessionRedis()
+	ctx := context.Background()
+	userId := uuid.New()
+
+	t.Run("Use once", func(t *testing.T) {
+		token, err := sessionRedisStorage.CreateEmailToken(ctx, types.EmailTokenVerify, userId, time.Minute)
+		require.NoError(t, err)
+
+		emailToken, err := sessionRedisStorage.UseEmailToken(ctx, types.EmailTokenVerify, token)
+		require.NoError(t, err)
+		require.Equal(t, &types.EmailToken{AccountID: userId, Purpose: types.EmailTokenVerify}, emailToken)
+
+		_, err = sessionRedisStorage.UseEmailToken(ctx, types.EmailTokenVerify, token)
+		require.ErrorIs(t, err, types.ErrEmailTokenInvalid)
+	})
+
+	t.Run("Other purpose", func(t *testing.T) {
+		token, err := sessionRedisStorage.CreateEmailToken(ctx, types.EmailTokenVerify, userId, time.Minute)
+		require.NoError(t, err)
+
+		_, err = sessionRedisStorage.UseEmailToken(ctx, types.EmailTokenReset, token)
+		require.ErrorIs(t, err, types.ErrEmailTokenInvalid)
+		// the token is still valid for its own purpose
+		emailToken, err := sessionRedisStorage.UseEmailToken(ctx, types.EmailTokenVerify, token)
+		require.NoError(t, err)
+		require.Equal(t, userId, emailToken.AccountID)
+	})
+
+	t.Run("Replaced", func(t *testing.T) {
+		old, err := sessionRedisStorage.CreateEmailToken(ctx, types.EmailTokenReset, userId, time.Minute)
+		require.NoError(t, err)
+		token, err := sessionRedisStorage.CreateEmailToken(ctx, types.EmailTokenReset, userId, time.Minute)
+		require.NoError(t, err)
+
+		_, err = sessionRedisStorage.UseEmailToken(ctx, types.EmailTokenReset, old)
+		require.ErrorIs(t, err, types.ErrEmailTokenInvalid)
+		_, err = sessionRedisStorage.UseEmailToken(ctx, types.EmailTokenReset, token)
+		require.NoError(t, err)
+	})
+}
+
+func TestRedis_Sessions(t *testing.T) {
+	t.Parallel()
+
+	sessionRedisStorage := SetupSessionRedis()
+	ctx := context.Background()
+
+	userId := uuid.New()
+	laptop, err := sessionRedisStorage.CreateSession(ctx, &types.Session{
+		UserID:    userId,
+		Device:    "laptop",
+		CreatedAt: time.Now().Add(-time.Hour),
+	}, 10)
+	require.NoError(t, err)
+	phone, err := sessionRedisStorage.CreateSession(ctx, &types.Session{
+		UserID: userId,
+		Device: "phone",
+	}, 10)
+	require.NoError(t, err)
+
+	t.Run("ListSessions", func(t *testing.T) {
+		// rotation keeps the session
+		_, err := sessionRedisStorage.RotateSession(ctx, laptop, 10)
+		require.NoError(t, err)
+
+		sessions, err := sessionRedisStorage.ListSessions(ctx, userId)
+		require.NoError(t, err)
+		require.Len(t, sessions, 2)
+		require.Equal(t, "laptop", sessions[0].Device)
+		require.Equal(t, "phone", sessions[1].Device)
+		require.False(t, sessions[0].CreatedAt.IsZero())
+	})
+
+	t.Run("RevokeSession", func(t *testing.T) {
+		session, err := sessionRedisStorage.GetSession(ctx, phone)
+		require.NoError(t, err)
+
+		// sessions of other users are not found
+		err = sessionRedisStorage.RevokeSession(ctx, uuid.New(), session.FamilyID)
+		require.ErrorIs(t, err, types.ErrSessionNotFound)
+
+		require.NoError(t, sessionRedisStorage.RevokeSession(ctx, userId, session.FamilyID))
+		_, err = sessionRedisStorage.RotateSession(ctx, phone, 10)
+		require.ErrorIs(t, err, types.ErrSessionNotFound)
+
+		sessions, err := sessionRedisStorage.ListSessions(ctx, userId)
+		require.NoError(t, err)
+		require.Len(t, sessions, 1)
+	})
+
+	t.Run("RevokeAllSessions", func(t *testing.T) {
+		require.NoError(t, sessionRedisStorage.RevokeAllSessions(ctx, userId))
+
+		sessions, err := sessionRedisStorage.ListSessions(ctx, userId)
+		require.NoError(t, err)
+		require.Empty(t, sessions)
+	})
+}
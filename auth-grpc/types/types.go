@@ -1,12 +1,17 @@
 package types
 
 import (
+	"database/sql"
+	"errors"
 	"time"
 
 	authpb "github.com/Edbeer/payment-proto/auth-grpc/proto"
 	"github.com/google/uuid"
 )
 
+// sender balance is less than the transfer amount
+var ErrInsufficientFunds = errors.New("Insufficient funds")
+
 // Account
 type Account struct {
 	ID               uuid.UUID `json:"id"`
@@ -18,8 +23,8 @@ type Account struct {
 	CardSecurityCode string    `json:"card_security_code"`
 	Balance          uint64    `json:"balance"`
 	BlockedMoney     uint64    `json:"blocked_money"`
-	Statement        []string  `json:"statement"`
 	CreatedAt        time.Time `json:"created_at"`
+	EscrowMoney      uint64    `json:"escrow_money"`
 }
 
 func NewAccount(req *authpb.CreateRequest) *Account {
@@ -33,13 +38,258 @@ func NewAccount(req *authpb.CreateRequest) *Account {
 		CardSecurityCode: req.CardSecurityCode,
 		Balance:          0,
 		BlockedMoney:     0,
-		Statement:        []string{},
 		CreatedAt:        time.Now(),
 	}
 }
 
+// refresh token is unknown, expired or its family is revoked
+var ErrSessionNotFound = errors.New("session not found")
+
+// rotated refresh token is used again, its family is revoked
+var ErrTokenReused = errors.New("refresh token reuse")
+
 // Session model
 type Session struct {
-	RefreshToken string    `json:"refresh_token" redis:"refresh_token"`
+	// only the hash of the token is stored
+	RefreshToken string    `json:"-" redis:"-"`
 	UserID       uuid.UUID `json:"id" redis:"id"`
-}
\ No newline at end of file
+	// tokens rotated from the same sign-in
+	FamilyID string `json:"family_id" redis:"family_id"`
+	// token was exchanged for the next one
+	Rotated bool `json:"rotated" redis:"rotated"`
+	// client of the sign-in
+	Device    string    `json:"device" redis:"device"`
+	UserAgent string    `json:"user_agent" redis:"user_agent"`
+	IP        string    `json:"ip" redis:"ip"`
+	CreatedAt time.Time `json:"created_at" redis:"created_at"`
+}
+
+// Directions of the statement entry
+const (
+	Credit = "credit"
+	Debit  = "debit"
+	// payment did not change the balance
+	NoChange = "none"
+)
+
+// Statement entry of the account
+type StatementEntry struct {
+	ID        uint64    `json:"id"`
+	AccountID uuid.UUID `json:"account_id"`
+	PaymentID uuid.UUID `json:"payment_id"`
+	Direction string    `json:"direction"`
+	Amount    uint64    `json:"amount"`
+	// account balance after the entry,
+	// null for entries migrated from the statement array
+	Balance   sql.NullInt64 `json:"balance"`
+	CreatedAt time.Time     `json:"created_at"`
+	// other party of the payment
+	CounterpartyID   uuid.NullUUID `json:"counterparty_id"`
+	CounterpartyName string        `json:"counterparty_name"`
+	Operation        string        `json:"operation"`
+	Status           string        `json:"status"`
+	Currency         string        `json:"currency"`
+}
+
+func NewStatementEntry(req *authpb.StatementRequest) (*StatementEntry, error) {
+	accountID, err := uuid.Parse(req.AccountId)
+	if err != nil {
+		return nil, err
+	}
+	paymentID, err := uuid.Parse(req.PaymentId)
+	if err != nil {
+		return nil, err
+	}
+	entry := &StatementEntry{
+		AccountID: accountID,
+		PaymentID: paymentID,
+		Direction: NoChange,
+		CreatedAt: time.Now(),
+		Operation: req.Operation,
+		Status:    req.Status,
+		Currency:  req.Currency,
+	}
+	if req.CounterpartyId != "" {
+		counterpartyID, err := uuid.Parse(req.CounterpartyId)
+		if err != nil {
+			return nil, err
+		}
+		entry.CounterpartyID = uuid.NullUUID{UUID: counterpartyID, Valid: true}
+	}
+	switch {
+	case req.Amount > 0:
+		entry.Direction = Credit
+		entry.Amount = uint64(req.Amount)
+	case req.Amount < 0:
+		entry.Direction = Debit
+		entry.Amount = uint64(-req.Amount)
+	}
+	return entry, nil
+}
+
+// States of the hold
+const (
+	HoldActive   = "active"
+	HoldCaptured = "captured"
+	HoldReleased = "released"
+	HoldExpired  = "expired"
+)
+
+// hold is captured, released or expired
+var ErrHoldNotActive = errors.New("hold is not active")
+
+// capture or change does not fit the hold
+var ErrHoldAmount = errors.New("invalid hold amount")
+
+// Money of the account reserved until captured, released or expired
+type Hold struct {
+	ID        uuid.UUID `json:"id"`
+	AccountID uuid.UUID `json:"account_id"`
+	Amount    uint64    `json:"amount"`
+	// paid to the receivers on capture
+	CapturedAmount uint64    `json:"captured_amount"`
+	State          string    `json:"state"`
+	Reference      string    `json:"reference"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+func NewHold(req *authpb.CreateHoldRequest) (*Hold, error) {
+	accountID, err := uuid.Parse(req.AccountId)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	return &Hold{
+		ID:        uuid.New(),
+		AccountID: accountID,
+		Amount:    req.Amount,
+		State:     HoldActive,
+		Reference: req.Reference,
+		ExpiresAt: now.Add(req.Ttl.AsDuration()),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}, nil
+}
+
+// email of the new account belongs to another account
+var ErrEmailTaken = errors.New("email is already taken")
+
+// Sign-in credentials of the account
+type Credential struct {
+	AccountID    uuid.UUID `json:"account_id"`
+	Email        string    `json:"email"`
+	PasswordHash string    `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// new TOTP secret can't replace the enabled one
+var ErrTOTPEnabled = errors.New("two-factor authentication is already enabled")
+
+// TOTP secret of the account
+type TOTP struct {
+	AccountID uuid.UUID `json:"account_id"`
+	Secret    string    `json:"-"`
+	Enabled   bool      `json:"enabled"`
+	// time step of the last accepted code
+	LastStep  int64     `json:"last_step"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Roles of the accounts
+const (
+	RoleCustomer = "customer"
+	RoleMerchant = "merchant"
+	RoleSupport  = "support"
+	RoleAdmin    = "admin"
+)
+
+// Is the role known
+func ValidRole(role string) bool {
+	switch role {
+	case RoleCustomer, RoleMerchant, RoleSupport, RoleAdmin:
+		return true
+	}
+	return false
+}
+
+// Scopes of the OAuth2 clients
+const (
+	ScopePaymentsWrite = "payments:write"
+	ScopePaymentsRead  = "payments:read"
+)
+
+// Is the scope known
+func ValidScope(scope string) bool {
+	return scope == ScopePaymentsWrite || scope == ScopePaymentsRead
+}
+
+// Services calling the auth service with a service token
+const (
+	ServicePayment = "payment"
+	ServiceGateway = "gateway"
+)
+
+// OAuth2 client of the merchant server
+type OAuthClient struct {
+	ClientID   string    `json:"client_id"`
+	AccountID  uuid.UUID `json:"account_id"`
+	Name       string    `json:"name"`
+	SecretHash string    `json:"-"`
+	Scopes     []string  `json:"scopes"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Failed sign-ins of an email or a client address
+type LoginAttempts struct {
+	// failures in a row, reset by the lockout
+	Failures    int64     `json:"failures"`
+	LastFailure time.Time `json:"last_failure"`
+	// sign-ins are refused until
+	LockedUntil time.Time `json:"locked_until"`
+}
+
+// Security events of the accounts
+const (
+	EventLoginLocked   = "login.locked"
+	EventLoginUnlocked = "login.unlocked"
+	EventPasswordReset = "password.reset"
+)
+
+// Security event for the audit of the accounts
+type SecurityEvent struct {
+	Type string `json:"type"`
+	// account and email of the event, the email may have no account
+	AccountID string `json:"account_id,omitempty"`
+	Email     string `json:"email,omitempty"`
+	IP        string `json:"ip,omitempty"`
+	// admin of the manual actions
+	Actor  string    `json:"actor,omitempty"`
+	Detail string    `json:"detail,omitempty"`
+	Time   time.Time `json:"time"`
+}
+
+// Purposes of the tokens sent by email
+const (
+	EmailTokenVerify = "verify"
+	EmailTokenReset  = "reset"
+)
+
+// token sent by email is unknown, expired, used or of another purpose
+var ErrEmailTokenInvalid = errors.New("invalid or expired token")
+
+// Single-use token sent by email, only its hash is stored
+type EmailToken struct {
+	AccountID uuid.UUID `json:"account_id"`
+	Purpose   string    `json:"purpose"`
+}
+
+// Mail to the account
+type Mail struct {
+	To      string
+	Subject string
+	Body    string
+}
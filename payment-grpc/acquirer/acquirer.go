@@ -0,0 +1,360 @@
+//go:generate mockgen -source acquirer.go -destination mock/acquirer_mock.go -package mock
+
+// Package acquirer connects card terminals and acquirer simulators
+// to the payment service over ISO 8583.
+//
+// Authorization (0100) is CreatePayment, financial request (0200) is
+// the capture of the authorization with the same retrieval reference
+// number, or authorization and capture at once, reversal (0400) is
+// CancelPayment of the authorization.
+//
+// The merchant is the account registered for the terminal. The customer
+// account id and the card security code are subfields 01 and 02 of the
+// private data element 48.
+package acquirer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Edbeer/payment-grpc/pkg/iso8583"
+	"github.com/Edbeer/payment-grpc/types"
+	paymentpb "github.com/Edbeer/payment-proto/payment-grpc/proto"
+	"github.com/google/uuid"
+)
+
+// subfields of the private data element 48
+const (
+	subfieldCustomer     = "01"
+	subfieldSecurityCode = "02"
+)
+
+// Response codes of the card traffic
+const (
+	ResponseApproved           = "00"
+	ResponseInvalidMerchant    = "03"
+	ResponseDoNotHonor         = "05"
+	ResponseInvalidTransaction = "12"
+	ResponseInvalidAmount      = "13"
+	ResponseOriginalNotFound   = "25"
+	ResponseFormatError        = "30"
+	ResponseInsufficientFunds  = "51"
+	ResponseDuplicate          = "94"
+	ResponseSystemError        = "96"
+	// strong customer authentication is required
+	ResponseAuthenticationRequired = "1A"
+)
+
+// payment statuses of the service
+var responseCodes = map[string]string{
+	"Approved":              ResponseApproved,
+	"Successful payment":    ResponseApproved,
+	"In escrow":             ResponseApproved,
+	"Successful cancel":     ResponseApproved,
+	"Insufficient funds":    ResponseInsufficientFunds,
+	"wrong payment request": ResponseDoNotHonor,
+	"Requires action":       ResponseAuthenticationRequired,
+	"Invalid amount":        ResponseInvalidAmount,
+	"Invalid transaction":   ResponseInvalidTransaction,
+}
+
+// ISO 4217 numeric codes of the supported currencies
+var currencies = map[string]string{
+	"156": "cny",
+	"643": "rub",
+	"826": "gbp",
+	"840": "usd",
+	"978": "eur",
+}
+
+// time to process one message
+var requestTimeout = 30 * time.Second
+
+// Payments made by the card traffic
+type Payments interface {
+	CreatePayment(ctx context.Context, req *paymentpb.CreateRequest) (*paymentpb.Statement, error)
+	CapturePayment(ctx context.Context, req *paymentpb.PaidRequest) (*paymentpb.Statement, error)
+	CancelPayment(ctx context.Context, req *paymentpb.PaidRequest) (*paymentpb.Statement, error)
+}
+
+// References of the authorizations made by the terminals
+type References interface {
+	SaveCardReference(ctx context.Context, ref *types.CardReference) error
+	GetCardReference(ctx context.Context, terminalID, rrn string) (*types.CardReference, error)
+	UpdateCardReference(ctx context.Context, terminalID, rrn, from, to string) (bool, error)
+}
+
+type Server struct {
+	payments   Payments
+	references References
+	// merchant account id by terminal id
+	terminals map[string]string
+}
+
+func NewServer(payments Payments, references References, terminals map[string]string) *Server {
+	return &Server{payments: payments, references: references, terminals: terminals}
+}
+
+// ParseTerminals reads terminals of the merchants
+// as comma separated terminal=merchant pairs
+func ParseTerminals(value string) (map[string]string, error) {
+	terminals := map[string]string{}
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		terminal, merchant, ok := strings.Cut(pair, "=")
+		if !ok || terminal == "" {
+			return nil, fmt.Errorf("invalid terminal %q", pair)
+		}
+		if _, err := uuid.Parse(merchant); err != nil {
+			return nil, fmt.Errorf("invalid merchant of terminal %s", terminal)
+		}
+		terminals[terminal] = merchant
+	}
+	return terminals, nil
+}
+
+// Serve accepts terminal connections until the listener is closed
+func (s *Server) Serve(lis net.Listener) error {
+	for {
+		conn, err := lis.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go s.serveConn(conn)
+	}
+}
+
+// messages of the connection are answered in order
+func (s *Server) serveConn(conn net.Conn) {
+	defer conn.Close()
+	// a malformed message closes its connection, not the service
+	defer func() {
+		if err := recover(); err != nil {
+			log.Printf("iso8583 %s: panic: %v", conn.RemoteAddr(), err)
+		}
+	}()
+	for {
+		frame, err := iso8583.ReadFrame(conn)
+		if err != nil {
+			return
+		}
+		req, err := iso8583.Unpack(frame)
+		if err != nil {
+			// the response can't be matched without the request
+			log.Printf("iso8583 %s: %v", conn.RemoteAddr(), err)
+			return
+		}
+		resp := s.handle(req)
+		data, err := resp.Pack()
+		if err != nil {
+			log.Printf("iso8583 %s: %v", conn.RemoteAddr(), err)
+			return
+		}
+		if err := iso8583.WriteFrame(conn, data); err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) handle(req *iso8583.Message) *iso8583.Message {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	resp := req.Response()
+	var code string
+	switch req.MTI {
+	case iso8583.NetworkRequest:
+		code = ResponseApproved
+	case iso8583.AuthorizationRequest:
+		code = s.authorize(ctx, req, resp)
+	case iso8583.FinancialRequest:
+		code = s.financial(ctx, req, resp)
+	case iso8583.ReversalRequest:
+		code = s.reverse(ctx, req)
+	default:
+		code = ResponseInvalidTransaction
+	}
+	resp.Set(39, code)
+	return resp
+}
+
+// authorization of the card, the reference is saved for every payment
+// so that retransmission is not authorized again
+func (s *Server) authorize(ctx context.Context, req, resp *iso8583.Message) string {
+	terminal, rrn := req.Get(41), req.Get(37)
+	create, code := s.createRequest(req)
+	if code != "" {
+		return code
+	}
+	if _, err := s.references.GetCardReference(ctx, terminal, rrn); err == nil {
+		return ResponseDuplicate
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("iso8583 terminal %s: %v", terminal, err)
+		return ResponseSystemError
+	}
+	st, err := s.payments.CreatePayment(ctx, create)
+	if err != nil {
+		log.Printf("iso8583 terminal %s: %v", terminal, err)
+		return ResponseSystemError
+	}
+	paymentID, err := uuid.Parse(st.PaymentId)
+	if err != nil {
+		return ResponseSystemError
+	}
+	if err := s.references.SaveCardReference(ctx, &types.CardReference{
+		TerminalId: terminal,
+		Rrn:        rrn,
+		PaymentId:  paymentID,
+		CreatedAt:  time.Now(),
+	}); err != nil {
+		log.Printf("iso8583 terminal %s: reference %s of payment %s: %v", terminal, rrn, st.PaymentId, err)
+	}
+	code = responseCode(st.Status)
+	if code == ResponseApproved {
+		resp.Set(38, authorizationCode(st.PaymentId))
+	}
+	return code
+}
+
+// capture of the authorization, without the authorization
+// the card is authorized and captured at once
+func (s *Server) financial(ctx context.Context, req, resp *iso8583.Message) string {
+	terminal, rrn := req.Get(41), req.Get(37)
+	if _, err := s.references.GetCardReference(ctx, terminal, rrn); errors.Is(err, sql.ErrNoRows) {
+		if code := s.authorize(ctx, req, resp); code != ResponseApproved {
+			return code
+		}
+	} else if err != nil {
+		log.Printf("iso8583 terminal %s: %v", terminal, err)
+		return ResponseSystemError
+	}
+	amount, err := strconv.ParseUint(req.Get(4), 10, 64)
+	if err != nil || amount == 0 {
+		return ResponseInvalidAmount
+	}
+	return s.complete(ctx, req, s.payments.CapturePayment, amount)
+}
+
+// release of the blocked amount of the authorization
+func (s *Server) reverse(ctx context.Context, req *iso8583.Message) string {
+	amount, err := strconv.ParseUint(req.Get(4), 10, 64)
+	if err != nil || amount == 0 {
+		return ResponseInvalidAmount
+	}
+	return s.complete(ctx, req, s.payments.CancelPayment, amount)
+}
+
+// authorization is completed by one capture or reversal,
+// the claim is released if the service fails
+func (s *Server) complete(
+	ctx context.Context, req *iso8583.Message,
+	completion func(context.Context, *paymentpb.PaidRequest) (*paymentpb.Statement, error), amount uint64,
+) string {
+	terminal, rrn := req.Get(41), req.Get(37)
+	ref, err := s.references.GetCardReference(ctx, terminal, rrn)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ResponseOriginalNotFound
+	}
+	if err != nil {
+		log.Printf("iso8583 terminal %s: %v", terminal, err)
+		return ResponseSystemError
+	}
+	switch ref.CompletedBy {
+	case "":
+	case req.MTI:
+		return ResponseDuplicate
+	default:
+		// reversal of the captured payment or capture of the reversed one
+		return ResponseInvalidTransaction
+	}
+	claimed, err := s.references.UpdateCardReference(ctx, terminal, rrn, "", req.MTI)
+	if err != nil {
+		log.Printf("iso8583 terminal %s: %v", terminal, err)
+		return ResponseSystemError
+	}
+	if !claimed {
+		return ResponseDuplicate
+	}
+	st, err := completion(ctx, &paymentpb.PaidRequest{
+		PaymentId: ref.PaymentId.String(),
+		Amount:    amount,
+	})
+	if err != nil {
+		log.Printf("iso8583 terminal %s: %v", terminal, err)
+		if _, err := s.references.UpdateCardReference(ctx, terminal, rrn, req.MTI, ""); err != nil {
+			log.Printf("iso8583 terminal %s: %v", terminal, err)
+		}
+		return ResponseSystemError
+	}
+	return responseCode(st.Status)
+}
+
+// create request of the authorization message, response code if it is invalid
+func (s *Server) createRequest(req *iso8583.Message) (*paymentpb.CreateRequest, string) {
+	for _, field := range []int{2, 4, 14, 37, 41, 48, 49} {
+		if !req.Has(field) {
+			return nil, ResponseFormatError
+		}
+	}
+	merchant, ok := s.terminals[req.Get(41)]
+	if !ok {
+		return nil, ResponseInvalidMerchant
+	}
+	amount, err := strconv.ParseUint(req.Get(4), 10, 64)
+	if err != nil || amount == 0 {
+		return nil, ResponseInvalidAmount
+	}
+	currency, ok := currencies[req.Get(49)]
+	if !ok {
+		return nil, ResponseFormatError
+	}
+	subfields, err := iso8583.ParseSubfields(req.Get(48))
+	if err != nil {
+		return nil, ResponseFormatError
+	}
+	customer := subfields[subfieldCustomer]
+	if _, err := uuid.Parse(customer); err != nil {
+		return nil, ResponseFormatError
+	}
+	// expiration date is YYMM
+	expiry := req.Get(14)
+	return &paymentpb.CreateRequest{
+		Merchant:         merchant,
+		Customer:         customer,
+		CardNumber:       req.Get(2),
+		CardExpiryMonth:  expiry[2:],
+		CardExpiryYear:   expiry[:2],
+		CardSecurityCode: subfields[subfieldSecurityCode],
+		Currency:         currency,
+		Amount:           amount,
+	}, ""
+}
+
+func responseCode(status string) string {
+	if code, ok := responseCodes[status]; ok {
+		return code
+	}
+	return ResponseDoNotHonor
+}
+
+// six characters of the payment id
+func authorizationCode(paymentID string) string {
+	code := strings.ToUpper(strings.ReplaceAll(paymentID, "-", ""))
+	if len(code) > 6 {
+		code = code[:6]
+	}
+	return code
+}
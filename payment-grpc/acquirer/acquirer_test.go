@@ -0,0 +1,220 @@
+package acquirer
+
+import (
+	"context"
+	"database/sql"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/Edbeer/payment-grpc/acquirer/mock"
+	"github.com/Edbeer/payment-grpc/pkg/iso8583"
+	"github.com/Edbeer/payment-grpc/types"
+	paymentpb "github.com/Edbeer/payment-proto/payment-grpc/proto"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// mock references kept in memory
+func mockReferences(ctrl *gomock.Controller) *mock.MockReferences {
+	references := mock.NewMockReferences(ctrl)
+	refs := map[string]*types.CardReference{}
+	mu := sync.Mutex{}
+	references.EXPECT().SaveCardReference(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, ref *types.CardReference) error {
+			mu.Lock()
+			defer mu.Unlock()
+			refs[ref.TerminalId+ref.Rrn] = ref
+			return nil
+		},
+	).AnyTimes()
+	references.EXPECT().GetCardReference(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, terminalID, rrn string) (*types.CardReference, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			ref, ok := refs[terminalID+rrn]
+			if !ok {
+				return nil, sql.ErrNoRows
+			}
+			saved := *ref
+			return &saved, nil
+		},
+	).AnyTimes()
+	references.EXPECT().UpdateCardReference(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, terminalID, rrn, from, to string) (bool, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			ref, ok := refs[terminalID+rrn]
+			if !ok || ref.CompletedBy != from {
+				return false, nil
+			}
+			ref.CompletedBy = to
+			return true, nil
+		},
+	).AnyTimes()
+	return references
+}
+
+func Test_Server(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	merchant := uuid.New().String()
+	customer := uuid.New().String()
+	payments := mock.NewMockPayments(ctrl)
+	server := NewServer(payments, mockReferences(ctrl), map[string]string{"T0000001": merchant})
+
+	client, conn := net.Pipe()
+	defer client.Close()
+	go server.serveConn(conn)
+
+	exchange := func(t *testing.T, req *iso8583.Message) *iso8583.Message {
+		data, err := req.Pack()
+		require.NoError(t, err)
+		require.NoError(t, iso8583.WriteFrame(client, data))
+		frame, err := iso8583.ReadFrame(client)
+		require.NoError(t, err)
+		resp, err := iso8583.Unpack(frame)
+		require.NoError(t, err)
+		return resp
+	}
+	newRequest := func(mti, rrn, amount string) *iso8583.Message {
+		req := iso8583.NewMessage(mti)
+		req.Set(2, "4444444444444444")
+		req.Set(3, "000000")
+		req.Set(4, amount)
+		req.Set(11, "000001")
+		req.Set(14, "2412")
+		req.Set(37, rrn)
+		req.Set(41, "T0000001")
+		req.Set(48, iso8583.FormatSubfields(map[string]string{
+			subfieldCustomer:     customer,
+			subfieldSecurityCode: "999",
+		}))
+		req.Set(49, "643")
+		return req
+	}
+
+	t.Run("Echo", func(t *testing.T) {
+		req := iso8583.NewMessage(iso8583.NetworkRequest)
+		req.Set(11, "000001")
+		req.Set(70, "301")
+
+		resp := exchange(t, req)
+		require.Equal(t, iso8583.NetworkResponse, resp.MTI)
+		require.Equal(t, ResponseApproved, resp.Get(39))
+		require.Equal(t, "301", resp.Get(70))
+	})
+
+	t.Run("Authorization, capture and reversal", func(t *testing.T) {
+		paymentID := uuid.New().String()
+		payments.EXPECT().CreatePayment(gomock.Any(), &paymentpb.CreateRequest{
+			Merchant:         merchant,
+			Customer:         customer,
+			CardNumber:       "4444444444444444",
+			CardExpiryMonth:  "12",
+			CardExpiryYear:   "24",
+			CardSecurityCode: "999",
+			Currency:         "rub",
+			Amount:           1500,
+		}).Return(&paymentpb.Statement{PaymentId: paymentID, Status: "Approved"}, nil)
+
+		resp := exchange(t, newRequest(iso8583.AuthorizationRequest, "000000000001", "1500"))
+		require.Equal(t, iso8583.AuthorizationResponse, resp.MTI)
+		require.Equal(t, ResponseApproved, resp.Get(39))
+		require.Equal(t, authorizationCode(paymentID), resp.Get(38))
+		require.Equal(t, "000000000001", resp.Get(37))
+
+		// retransmission of the authorization
+		resp = exchange(t, newRequest(iso8583.AuthorizationRequest, "000000000001", "1500"))
+		require.Equal(t, ResponseDuplicate, resp.Get(39))
+
+		payments.EXPECT().CapturePayment(gomock.Any(), &paymentpb.PaidRequest{
+			PaymentId: paymentID,
+			Amount:    1200,
+		}).Return(&paymentpb.Statement{PaymentId: uuid.New().String(), Status: "Successful payment"}, nil)
+
+		completion := iso8583.NewMessage(iso8583.FinancialRequest)
+		completion.Set(4, "1200")
+		completion.Set(37, "000000000001")
+		completion.Set(41, "T0000001")
+		resp = exchange(t, completion)
+		require.Equal(t, iso8583.FinancialResponse, resp.MTI)
+		require.Equal(t, ResponseApproved, resp.Get(39))
+
+		resp = exchange(t, completion)
+		require.Equal(t, ResponseDuplicate, resp.Get(39))
+
+		// captured payment is not cancelled
+		reversal := newRequest(iso8583.ReversalRequest, "000000000001", "1500")
+		resp = exchange(t, reversal)
+		require.Equal(t, iso8583.ReversalResponse, resp.MTI)
+		require.Equal(t, ResponseInvalidTransaction, resp.Get(39))
+	})
+
+	t.Run("Reversal", func(t *testing.T) {
+		paymentID := uuid.New().String()
+		payments.EXPECT().CreatePayment(gomock.Any(), gomock.Any()).
+			Return(&paymentpb.Statement{PaymentId: paymentID, Status: "Approved"}, nil)
+		resp := exchange(t, newRequest(iso8583.AuthorizationRequest, "000000000002", "700"))
+		require.Equal(t, ResponseApproved, resp.Get(39))
+
+		payments.EXPECT().CancelPayment(gomock.Any(), &paymentpb.PaidRequest{
+			PaymentId: paymentID,
+			Amount:    700,
+		}).Return(&paymentpb.Statement{PaymentId: uuid.New().String(), Status: "Successful cancel"}, nil)
+		resp = exchange(t, newRequest(iso8583.ReversalRequest, "000000000002", "700"))
+		require.Equal(t, ResponseApproved, resp.Get(39))
+
+		resp = exchange(t, newRequest(iso8583.ReversalRequest, "000000000003", "700"))
+		require.Equal(t, ResponseOriginalNotFound, resp.Get(39))
+	})
+
+	t.Run("Financial", func(t *testing.T) {
+		paymentID := uuid.New().String()
+		gomock.InOrder(
+			payments.EXPECT().CreatePayment(gomock.Any(), gomock.Any()).
+				Return(&paymentpb.Statement{PaymentId: paymentID, Status: "Approved"}, nil),
+			payments.EXPECT().CapturePayment(gomock.Any(), &paymentpb.PaidRequest{
+				PaymentId: paymentID,
+				Amount:    300,
+			}).Return(&paymentpb.Statement{PaymentId: uuid.New().String(), Status: "Successful payment"}, nil),
+		)
+		resp := exchange(t, newRequest(iso8583.FinancialRequest, "000000000004", "300"))
+		require.Equal(t, iso8583.FinancialResponse, resp.MTI)
+		require.Equal(t, ResponseApproved, resp.Get(39))
+	})
+
+	t.Run("Declined", func(t *testing.T) {
+		payments.EXPECT().CreatePayment(gomock.Any(), gomock.Any()).
+			Return(&paymentpb.Statement{PaymentId: uuid.New().String(), Status: "Insufficient funds"}, nil)
+		resp := exchange(t, newRequest(iso8583.AuthorizationRequest, "000000000005", "100000"))
+		require.Equal(t, ResponseInsufficientFunds, resp.Get(39))
+		require.False(t, resp.Has(38))
+
+		req := newRequest(iso8583.AuthorizationRequest, "000000000006", "100")
+		req.Set(41, "T0000002")
+		resp = exchange(t, req)
+		require.Equal(t, ResponseInvalidMerchant, resp.Get(39))
+
+		req = newRequest(iso8583.AuthorizationRequest, "000000000007", "100")
+		req.Set(49, "999")
+		resp = exchange(t, req)
+		require.Equal(t, ResponseFormatError, resp.Get(39))
+	})
+}
+
+func Test_ParseTerminals(t *testing.T) {
+	t.Parallel()
+
+	merchant := uuid.New().String()
+	terminals, err := ParseTerminals("T0000001=" + merchant + ", T0000002=" + merchant)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"T0000001": merchant, "T0000002": merchant}, terminals)
+
+	_, err = ParseTerminals("T0000001=merchant")
+	require.Error(t, err)
+}
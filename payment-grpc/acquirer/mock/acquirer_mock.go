@@ -0,0 +1,149 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: acquirer.go
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	types "github.com/Edbeer/payment-grpc/types"
+	paymentpb "github.com/Edbeer/payment-proto/payment-grpc/proto"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockPayments is a mock of Payments interface.
+type MockPayments struct {
+	ctrl     *gomock.Controller
+	recorder *MockPaymentsMockRecorder
+}
+
+// MockPaymentsMockRecorder is the mock recorder for MockPayments.
+type MockPaymentsMockRecorder struct {
+	mock *MockPayments
+}
+
+// NewMockPayments creates a new mock instance.
+func NewMockPayments(ctrl *gomock.Controller) *MockPayments {
+	mock := &MockPayments{ctrl: ctrl}
+	mock.recorder = &MockPaymentsMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPayments) EXPECT() *MockPaymentsMockRecorder {
+	return m.recorder
+}
+
+// CancelPayment mocks base method.
+func (m *MockPayments) CancelPayment(ctx context.Context, req *paymentpb.PaidRequest) (*paymentpb.Statement, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelPayment", ctx, req)
+	ret0, _ := ret[0].(*paymentpb.Statement)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CancelPayment indicates an expected call of CancelPayment.
+func (mr *MockPaymentsMockRecorder) CancelPayment(ctx, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelPayment", reflect.TypeOf((*MockPayments)(nil).CancelPayment), ctx, req)
+}
+
+// CapturePayment mocks base method.
+func (m *MockPayments) CapturePayment(ctx context.Context, req *paymentpb.PaidRequest) (*paymentpb.Statement, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CapturePayment", ctx, req)
+	ret0, _ := ret[0].(*paymentpb.Statement)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CapturePayment indicates an expected call of CapturePayment.
+func (mr *MockPaymentsMockRecorder) CapturePayment(ctx, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CapturePayment", reflect.TypeOf((*MockPayments)(nil).CapturePayment), ctx, req)
+}
+
+// CreatePayment mocks base method.
+func (m *MockPayments) CreatePayment(ctx context.Context, req *paymentpb.CreateRequest) (*paymentpb.Statement, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePayment", ctx, req)
+	ret0, _ := ret[0].(*paymentpb.Statement)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreatePayment indicates an expected call of CreatePayment.
+func (mr *MockPaymentsMockRecorder) CreatePayment(ctx, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePayment", reflect.TypeOf((*MockPayments)(nil).CreatePayment), ctx, req)
+}
+
+// MockReferences is a mock of References interface.
+type MockReferences struct {
+	ctrl     *gomock.Controller
+	recorder *MockReferencesMockRecorder
+}
+
+// MockReferencesMockRecorder is the mock recorder for MockReferences.
+type MockReferencesMockRecorder struct {
+	mock *MockReferences
+}
+
+// NewMockReferences creates a new mock instance.
+func NewMockReferences(ctrl *gomock.Controller) *MockReferences {
+	mock := &MockReferences{ctrl: ctrl}
+	mock.recorder = &MockReferencesMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockReferences) EXPECT() *MockReferencesMockRecorder {
+	return m.recorder
+}
+
+// GetCardReference mocks base method.
+func (m *MockReferences) GetCardReference(ctx context.Context, terminalID, rrn string) (*types.CardReference, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCardReference", ctx, terminalID, rrn)
+	ret0, _ := ret[0].(*types.CardReference)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCardReference indicates an expected call of GetCardReference.
+func (mr *MockReferencesMockRecorder) GetCardReference(ctx, terminalID, rrn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCardReference", reflect.TypeOf((*MockReferences)(nil).GetCardReference), ctx, terminalID, rrn)
+}
+
+// SaveCardReference mocks base method.
+func (m *MockReferences) SaveCardReference(ctx context.Context, ref *types.CardReference) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveCardReference", ctx, ref)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveCardReference indicates an expected call of SaveCardReference.
+func (mr *MockReferencesMockRecorder) SaveCardReference(ctx, ref interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveCardReference", reflect.TypeOf((*MockReferences)(nil).SaveCardReference), ctx, ref)
+}
+
+// UpdateCardReference mocks base method.
+func (m *MockReferences) UpdateCardReference(ctx context.Context, terminalID, rrn, from, to string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateCardReference", ctx, terminalID, rrn, from, to)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateCardReference indicates an expected call of UpdateCardReference.
+func (mr *MockReferencesMockRecorder) UpdateCardReference(ctx, terminalID, rrn, from, to interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateCardReference", reflect.TypeOf((*MockReferences)(nil).UpdateCardReference), ctx, terminalID, rrn, from, to)
+}
@@ -1,11 +1,17 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net"
+	"os"
+	"time"
 
+	"github.com/Edbeer/payment-grpc/acquirer"
 	authpb "github.com/Edbeer/payment-proto/auth-grpc/proto"
 	"github.com/Edbeer/payment-grpc/pkg/db"
+	"github.com/Edbeer/payment-grpc/pkg/jwks"
+	"github.com/Edbeer/payment-grpc/pkg/notify"
 	paymentpb "github.com/Edbeer/payment-proto/payment-grpc/proto"
 	"github.com/Edbeer/payment-grpc/service"
 	"github.com/Edbeer/payment-grpc/storage"
@@ -25,8 +31,13 @@ func main() {
 	// postgres storage
 	storage := storage.NewPostgresStorage(db)
 
-	// client
-	conn, err := grpc.Dial("auth:50052", grpc.WithInsecure())
+	// client, authenticated as the payment service
+	secret := os.Getenv("SERVICE_SECRET")
+	if secret == "" {
+		log.Fatal("SERVICE_SECRET is not set")
+	}
+	conn, err := grpc.Dial("auth:50052", grpc.WithInsecure(),
+		grpc.WithPerRPCCredentials(jwks.NewServiceCredentials("payment", secret)))
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -34,9 +45,37 @@ func main() {
 	client := authpb.NewAuthServiceClient(conn)
 
 	// payment service
-	srv := service.NewPaymentService(storage, client, db)
-	// grpc server
-	server := grpc.NewServer(grpc.MaxConcurrentStreams(1000))
+	srv := service.NewPaymentService(storage, client, db, notify.NewLogNotifier())
+	// release escrows on timer
+	go srv.RunEscrowRelease(context.Background(), time.Minute)
+	// expire challenges on timer
+	go srv.RunChallengeExpiry(context.Background(), 30*time.Second)
+	// charge installments on timer
+	go srv.RunInstallmentCharges(context.Background(), time.Minute)
+	// execute scheduled payments on timer
+	go srv.RunScheduledPayments(context.Background(), time.Minute)
+	// ISO 8583 card traffic of the terminals
+	terminals, err := acquirer.ParseTerminals(os.Getenv("ISO8583_TERMINALS"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	cardLis, err := net.Listen("tcp", ":8583")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer cardLis.Close()
+	go func() {
+		if err := acquirer.NewServer(srv, storage, terminals).Serve(cardLis); err != nil {
+			log.Println(err)
+		}
+	}()
+	// grpc server, callers are checked with the public keys of the auth service
+	keys := jwks.NewKeySet(client)
+	server := grpc.NewServer(
+		grpc.MaxConcurrentStreams(1000),
+		grpc.UnaryInterceptor(srv.UnaryInterceptor(keys)),
+		grpc.StreamInterceptor(srv.StreamInterceptor(keys)),
+	)
 	// register service
 	paymentpb.RegisterPaymentServiceServer(server, srv)
 	// reflection 
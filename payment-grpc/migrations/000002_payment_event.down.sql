@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS payment_event_trigger ON payment;
+DROP FUNCTION IF EXISTS record_payment_event();
+DROP TABLE IF EXISTS payment_event;
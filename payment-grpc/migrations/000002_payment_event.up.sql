@@ -0,0 +1,38 @@
+CREATE TABLE IF NOT EXISTS payment_event
+(
+	event_id BIGSERIAL PRIMARY KEY,
+	payment_id UUID,
+	merchant UUID,
+	customer UUID,
+	currency VARCHAR(50),
+	operation VARCHAR(50),
+	status VARCHAR(50),
+	amount BIGINT,
+	created_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS payment_event_merchant_idx ON payment_event (merchant, event_id);
+CREATE INDEX IF NOT EXISTS payment_event_customer_idx ON payment_event (customer, event_id);
+
+CREATE OR REPLACE FUNCTION record_payment_event() RETURNS TRIGGER AS $$
+BEGIN
+	-- held until the end of the commit, the next transaction
+	-- takes its event ids after this one is visible
+	PERFORM pg_advisory_xact_lock(hashtext('payment_event'));
+	INSERT INTO payment_event (payment_id, merchant, customer,
+		currency, operation, status, amount, created_at)
+	VALUES (NEW.payment_id, NEW.merchant, NEW.customer,
+		NEW.currency, NEW.operation, NEW.status, NEW.amount, NEW.created_at);
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- deferred until commit: event ids are taken at commit time
+-- under the lock of the function, so ids become visible in order
+-- and a subscriber resuming from a cursor doesn't skip rows
+-- of a payment transaction that was still open
+DROP TRIGGER IF EXISTS payment_event_trigger ON payment;
+CREATE CONSTRAINT TRIGGER payment_event_trigger
+	AFTER INSERT ON payment
+	DEFERRABLE INITIALLY DEFERRED
+	FOR EACH ROW EXECUTE FUNCTION record_payment_event();
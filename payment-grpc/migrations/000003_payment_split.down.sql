@@ -0,0 +1,16 @@
+CREATE OR REPLACE FUNCTION record_payment_event() RETURNS TRIGGER AS $$
+BEGIN
+	-- event ids in commit order, see 000002_payment_event
+	PERFORM pg_advisory_xact_lock(hashtext('payment_event'));
+	INSERT INTO payment_event (payment_id, merchant, customer,
+		currency, operation, status, amount, created_at)
+	VALUES (NEW.payment_id, NEW.merchant, NEW.customer,
+		NEW.currency, NEW.operation, NEW.status, NEW.amount, NEW.created_at);
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE payment_event DROP COLUMN IF EXISTS parent_id;
+
+DROP INDEX IF EXISTS payment_parent_idx;
+ALTER TABLE payment DROP COLUMN IF EXISTS parent_id;
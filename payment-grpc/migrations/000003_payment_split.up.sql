@@ -0,0 +1,17 @@
+-- rows of a marketplace split payment point to the row of the whole payment
+ALTER TABLE payment ADD COLUMN IF NOT EXISTS parent_id UUID;
+CREATE INDEX IF NOT EXISTS payment_parent_idx ON payment (parent_id);
+
+ALTER TABLE payment_event ADD COLUMN IF NOT EXISTS parent_id UUID;
+
+CREATE OR REPLACE FUNCTION record_payment_event() RETURNS TRIGGER AS $$
+BEGIN
+	-- event ids in commit order, see 000002_payment_event
+	PERFORM pg_advisory_xact_lock(hashtext('payment_event'));
+	INSERT INTO payment_event (payment_id, merchant, customer,
+		currency, operation, status, amount, created_at, parent_id)
+	VALUES (NEW.payment_id, NEW.merchant, NEW.customer,
+		NEW.currency, NEW.operation, NEW.status, NEW.amount, NEW.created_at, NEW.parent_id);
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
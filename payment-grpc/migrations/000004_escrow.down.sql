@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS escrow;
+
+ALTER TABLE payment DROP COLUMN IF EXISTS escrow_hold_hours;
+ALTER TABLE payment DROP COLUMN IF EXISTS escrow;
@@ -0,0 +1,20 @@
+ALTER TABLE payment ADD COLUMN IF NOT EXISTS escrow BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE payment ADD COLUMN IF NOT EXISTS escrow_hold_hours INTEGER NOT NULL DEFAULT 0;
+
+-- captured money held for the merchant
+CREATE TABLE IF NOT EXISTS escrow
+(
+	-- capture payment
+	payment_id UUID PRIMARY KEY,
+	merchant UUID,
+	customer UUID,
+	currency VARCHAR(50),
+	-- money still held
+	amount BIGINT,
+	state VARCHAR(50),
+	-- automatic release, NULL - only on request
+	release_at TIMESTAMP,
+	created_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS escrow_release_idx ON escrow (state, release_at);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS payment_reference_idx;
+ALTER TABLE payment DROP COLUMN IF EXISTS reference_id;
@@ -0,0 +1,3 @@
+-- increment and decrement rows point to the changed authorization
+ALTER TABLE payment ADD COLUMN IF NOT EXISTS reference_id UUID;
+CREATE INDEX IF NOT EXISTS payment_reference_idx ON payment (reference_id);
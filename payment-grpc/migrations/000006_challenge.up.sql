@@ -0,0 +1,16 @@
+-- 3-D Secure challenges of payments waiting for the customer
+CREATE TABLE IF NOT EXISTS challenge
+(
+	challenge_id UUID PRIMARY KEY,
+	-- payment in "Requires action" status
+	payment_id UUID,
+	customer UUID,
+	-- sha256 of the one-time code
+	code_hash VARCHAR(64),
+	attempts INTEGER NOT NULL DEFAULT 0,
+	state VARCHAR(50),
+	expires_at TIMESTAMP,
+	created_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS challenge_expires_idx ON challenge (state, expires_at);
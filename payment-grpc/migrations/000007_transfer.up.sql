@@ -0,0 +1,12 @@
+-- account-to-account transfers, payment row of operation "Transfer"
+CREATE TABLE IF NOT EXISTS transfer
+(
+	payment_id UUID PRIMARY KEY,
+	sender UUID,
+	receiver UUID,
+	idempotency_key VARCHAR(255),
+	note TEXT,
+	created_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS transfer_idempotency_idx ON transfer (sender, idempotency_key);
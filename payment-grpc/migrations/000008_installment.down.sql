@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS installment;
+DROP TABLE IF EXISTS installment_plan;
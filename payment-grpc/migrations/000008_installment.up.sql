@@ -0,0 +1,37 @@
+-- authorization paid by the customer in several installments
+CREATE TABLE IF NOT EXISTS installment_plan
+(
+	plan_id UUID PRIMARY KEY,
+	-- payment row of the plan, merchant is paid in full
+	payment_id UUID,
+	-- converted authorization, once
+	authorization_id UUID UNIQUE,
+	merchant UUID,
+	customer UUID,
+	currency VARCHAR(50),
+	amount BIGINT,
+	fee BIGINT,
+	state VARCHAR(50),
+	created_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS installment_plan_merchant_idx ON installment_plan (merchant);
+CREATE INDEX IF NOT EXISTS installment_plan_customer_idx ON installment_plan (customer);
+
+CREATE TABLE IF NOT EXISTS installment
+(
+	installment_id UUID PRIMARY KEY,
+	plan_id UUID REFERENCES installment_plan (plan_id),
+	seq INTEGER,
+	-- debited from the customer, fee included
+	amount BIGINT,
+	fee BIGINT,
+	due_at TIMESTAMP,
+	state VARCHAR(50),
+	attempts INTEGER NOT NULL DEFAULT 0,
+	next_attempt_at TIMESTAMP,
+	created_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS installment_plan_idx ON installment (plan_id, seq);
+CREATE INDEX IF NOT EXISTS installment_due_idx ON installment (state, next_attempt_at);
@@ -0,0 +1,15 @@
+-- future-dated payments, payment row of status "Scheduled"
+CREATE TABLE IF NOT EXISTS scheduled_payment
+(
+	payment_id UUID PRIMARY KEY,
+	execute_at TIMESTAMP,
+	state VARCHAR(50),
+	-- capture or declined authorization of the execution
+	result_id UUID,
+	-- start of the execution, a stale claim is taken over
+	claimed_at TIMESTAMP,
+	created_at TIMESTAMP,
+	updated_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS scheduled_payment_due_idx ON scheduled_payment (state, execute_at);
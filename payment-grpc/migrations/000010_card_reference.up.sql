@@ -0,0 +1,12 @@
+-- ISO 8583 card traffic, retrieval reference number of the terminal
+-- links completions and reversals to the authorization,
+-- completed_by is the message type of the completion or reversal
+CREATE TABLE IF NOT EXISTS card_reference
+(
+	terminal_id VARCHAR(8),
+	rrn VARCHAR(12),
+	payment_id UUID,
+	completed_by VARCHAR(4) NOT NULL DEFAULT '',
+	created_at TIMESTAMP,
+	PRIMARY KEY (terminal_id, rrn)
+);
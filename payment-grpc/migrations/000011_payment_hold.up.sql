@@ -0,0 +1,3 @@
+-- auth service hold of the authorized money,
+-- authorizations approved before holds have none
+ALTER TABLE payment ADD COLUMN IF NOT EXISTS hold_id UUID;
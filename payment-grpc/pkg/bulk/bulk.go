@@ -0,0 +1,199 @@
+// Package bulk reads batches of transfers from CSV and ISO 20022 pain.001
+// files and writes per-row result reports.
+//
+// Amounts of the CSV file are in the units of the payment API,
+// decimal pain.001 amounts are converted to minor units.
+package bulk
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	paymentpb "github.com/Edbeer/payment-proto/payment-grpc/proto"
+	"github.com/google/uuid"
+)
+
+// Supported file formats
+const (
+	FormatCSV     = "csv"
+	FormatPain001 = "pain.001"
+)
+
+// Reasons of rejected rows, ISO 20022 external status reason codes
+const (
+	ReasonInvalidAccount = "AC01"
+	ReasonInvalidAmount  = "AM12"
+	ReasonInsufficient   = "AM04"
+	ReasonDuplicate      = "AM05"
+	ReasonInvalidFormat  = "FF01"
+	ReasonNarrative      = "NARR"
+)
+
+// columns of the CSV file, note is optional
+var csvColumns = []string{"sender_id", "receiver_id", "currency", "amount", "reference", "note"}
+
+// Batch of transfers read from one file
+type Batch struct {
+	MessageId string
+	Format    string
+	Rows      []*Row
+}
+
+// Transfer of the file, rows with Reason are not executed
+type Row struct {
+	// number of the row in the file from 1
+	Number    uint32
+	Reference string
+	// pain.001 payment information the row belongs to
+	PaymentInfoId string
+	Request       *paymentpb.TransferRequest
+	Reason        string
+	Error         string
+}
+
+// Result of the executed or rejected row
+type Result struct {
+	Row       *Row
+	PaymentId string
+	Status    string
+	Accepted  bool
+	Reason    string
+	Error     string
+}
+
+func (r *Row) reject(reason, message string) {
+	if r.Reason == "" {
+		r.Reason = reason
+		r.Error = message
+	}
+}
+
+// validate the transfer and mark duplicate references,
+// idempotency key of the transfer is derived from the reference
+func validate(batch *Batch) {
+	references := map[string]bool{}
+	for _, row := range batch.Rows {
+		req := row.Request
+		if _, err := uuid.Parse(req.Sender); err != nil {
+			row.reject(ReasonInvalidAccount, "invalid sender id")
+		}
+		if _, err := uuid.Parse(req.Receiver); err != nil {
+			row.reject(ReasonInvalidAccount, "invalid receiver id")
+		}
+		if req.Sender == req.Receiver {
+			row.reject(ReasonInvalidAccount, "sender and receiver are the same account")
+		}
+		if req.Amount == 0 {
+			row.reject(ReasonInvalidAmount, "invalid amount")
+		}
+		if req.Currency == "" {
+			row.reject(ReasonInvalidFormat, "currency is required")
+		}
+		if row.Reference == "" {
+			row.reject(ReasonInvalidFormat, "reference is required")
+			continue
+		}
+		if references[row.Reference] {
+			row.reject(ReasonDuplicate, "duplicate reference")
+		}
+		references[row.Reference] = true
+		// end-to-end ids are unique within the pain.001 message only
+		req.IdempotencyKey = row.Reference
+		if batch.Format == FormatPain001 {
+			req.IdempotencyKey = batch.MessageId + "/" + row.Reference
+		}
+	}
+}
+
+// ParseCSV reads the file with the header of csvColumns in any order
+func ParseCSV(data []byte) (*Batch, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read csv header: %w", err)
+	}
+	index := map[string]int{}
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range csvColumns[:5] {
+		if _, ok := index[name]; !ok {
+			return nil, fmt.Errorf("csv column %q is required", name)
+		}
+	}
+	batch := &Batch{
+		MessageId: isoId(uuid.New().String()),
+		Format:    FormatCSV,
+	}
+	for number := uint32(1); ; number++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		row := &Row{
+			Number:  number,
+			Request: &paymentpb.TransferRequest{},
+		}
+		batch.Rows = append(batch.Rows, row)
+		if err != nil {
+			row.reject(ReasonInvalidFormat, err.Error())
+			continue
+		}
+		field := func(name string) string {
+			i, ok := index[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		row.Reference = field("reference")
+		row.Request.Sender = field("sender_id")
+		row.Request.Receiver = field("receiver_id")
+		row.Request.Currency = field("currency")
+		row.Request.Note = field("note")
+		amount, err := strconv.ParseUint(field("amount"), 10, 64)
+		if err != nil {
+			row.reject(ReasonInvalidAmount, "invalid amount")
+		}
+		row.Request.Amount = amount
+	}
+	if len(batch.Rows) == 0 {
+		return nil, errors.New("csv file has no rows")
+	}
+	validate(batch)
+	return batch, nil
+}
+
+// WriteCSV writes the result of every row in the order of the file
+func WriteCSV(results []*Result) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	writer := csv.NewWriter(buf)
+	if err := writer.Write([]string{"row", "reference", "payment_id", "status", "accepted", "reason", "error"}); err != nil {
+		return nil, err
+	}
+	for _, result := range results {
+		if err := writer.Write([]string{
+			strconv.FormatUint(uint64(result.Row.Number), 10),
+			result.Row.Reference,
+			result.PaymentId,
+			result.Status,
+			strconv.FormatBool(result.Accepted),
+			result.Reason,
+			result.Error,
+		}); err != nil {
+			return nil, err
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
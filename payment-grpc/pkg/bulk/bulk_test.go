@@ -0,0 +1,67 @@
+package bulk
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	sender   = "2b7ea0a8-5c54-4b8f-8a8b-5c7a6f0b8e11"
+	receiver = "9d3f1c2e-7a41-4f0c-9f58-2d6a3b4c5e22"
+)
+
+func Test_ParseCSV(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Header in any order and case", func(t *testing.T) {
+		data := "Amount, REFERENCE ,receiver_id,currency,sender_id\n" +
+			"150,ref-1," + receiver + ",RUB," + sender + "\n"
+
+		batch, err := ParseCSV([]byte(data))
+		require.NoError(t, err)
+		require.Equal(t, FormatCSV, batch.Format)
+		require.LessOrEqual(t, len(batch.MessageId), 35)
+		require.Len(t, batch.Rows, 1)
+
+		row := batch.Rows[0]
+		require.Empty(t, row.Reason)
+		require.Equal(t, uint32(1), row.Number)
+		require.Equal(t, "ref-1", row.Reference)
+		require.Equal(t, sender, row.Request.Sender)
+		require.Equal(t, receiver, row.Request.Receiver)
+		require.Equal(t, "RUB", row.Request.Currency)
+		require.Equal(t, uint64(150), row.Request.Amount)
+		require.Equal(t, "ref-1", row.Request.IdempotencyKey)
+	})
+
+	t.Run("Missing column", func(t *testing.T) {
+		data := "sender_id,receiver_id,currency,amount\n" +
+			sender + "," + receiver + ",RUB,150\n"
+
+		_, err := ParseCSV([]byte(data))
+		require.EqualError(t, err, `csv column "reference" is required`)
+	})
+
+	t.Run("No rows", func(t *testing.T) {
+		_, err := ParseCSV([]byte("sender_id,receiver_id,currency,amount,reference\n"))
+		require.Error(t, err)
+	})
+
+	t.Run("Invalid rows", func(t *testing.T) {
+		data := "sender_id,receiver_id,currency,amount,reference,note\n" +
+			sender + "," + receiver + ",RUB,1.50,ref-1,\n" +
+			sender + "," + sender + ",RUB,150,ref-2,\n" +
+			sender + "," + receiver + ",RUB,150,ref-3,lunch\n" +
+			sender + "," + receiver + ",RUB,150,ref-3,\n"
+
+		batch, err := ParseCSV([]byte(data))
+		require.NoError(t, err)
+		require.Len(t, batch.Rows, 4)
+		require.Equal(t, ReasonInvalidAmount, batch.Rows[0].Reason)
+		require.Equal(t, ReasonInvalidAccount, batch.Rows[1].Reason)
+		require.Empty(t, batch.Rows[2].Reason)
+		require.Equal(t, "lunch", batch.Rows[2].Request.Note)
+		require.Equal(t, ReasonDuplicate, batch.Rows[3].Reason)
+	})
+}
@@ -0,0 +1,199 @@
+package bulk
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	paymentpb "github.com/Edbeer/payment-proto/payment-grpc/proto"
+	"github.com/google/uuid"
+)
+
+// namespace of the status report
+const pain002Namespace = "urn:iso:std:iso:20022:tech:xsd:pain.002.001.03"
+
+// message name of the initiation referenced by the report
+const pain001Name = "pain.001.001.03"
+
+// ISO 20022 transaction and group statuses
+const (
+	statusAccepted = "ACSC"
+	statusPartial  = "PART"
+	statusRejected = "RJCT"
+)
+
+// account identified by the account id of the auth service
+type painAccount struct {
+	Id string `xml:"Id>Othr>Id"`
+}
+
+type painAmount struct {
+	Currency string `xml:"Ccy,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type painTransaction struct {
+	EndToEndId  string      `xml:"PmtId>EndToEndId"`
+	Amount      painAmount  `xml:"Amt>InstdAmt"`
+	CreditorAcc painAccount `xml:"CdtrAcct"`
+	Remittance  string      `xml:"RmtInf>Ustrd"`
+}
+
+type painPaymentInfo struct {
+	PaymentInfoId string            `xml:"PmtInfId"`
+	DebtorAcc     painAccount       `xml:"DbtrAcct"`
+	Transactions  []painTransaction `xml:"CdtTrfTxInf"`
+}
+
+// customer credit transfer initiation, only the fields used by the service
+type pain001 struct {
+	MessageId   string            `xml:"CstmrCdtTrfInitn>GrpHdr>MsgId"`
+	PaymentInfo []painPaymentInfo `xml:"CstmrCdtTrfInitn>PmtInf"`
+}
+
+// ParsePain001 reads credit transfer initiation,
+// every transaction of every payment information block is a row
+func ParsePain001(data []byte) (*Batch, error) {
+	doc := &pain001{}
+	if err := xml.Unmarshal(data, doc); err != nil {
+		return nil, fmt.Errorf("read pain.001: %w", err)
+	}
+	if doc.MessageId == "" {
+		return nil, errors.New("pain.001 message id is required")
+	}
+	batch := &Batch{
+		MessageId: doc.MessageId,
+		Format:    FormatPain001,
+	}
+	number := uint32(0)
+	for _, info := range doc.PaymentInfo {
+		for _, tx := range info.Transactions {
+			number++
+			row := &Row{
+				Number:        number,
+				Reference:     strings.TrimSpace(tx.EndToEndId),
+				PaymentInfoId: info.PaymentInfoId,
+				Request: &paymentpb.TransferRequest{
+					Sender:   strings.TrimSpace(info.DebtorAcc.Id),
+					Receiver: strings.TrimSpace(tx.CreditorAcc.Id),
+					Currency: tx.Amount.Currency,
+					Note:     tx.Remittance,
+				},
+			}
+			amount, err := parseDecimal(tx.Amount.Value)
+			if err != nil {
+				row.reject(ReasonInvalidAmount, "invalid amount")
+			}
+			row.Request.Amount = amount
+			batch.Rows = append(batch.Rows, row)
+		}
+	}
+	if len(batch.Rows) == 0 {
+		return nil, errors.New("pain.001 has no transactions")
+	}
+	validate(batch)
+	return batch, nil
+}
+
+// decimal amount with up to two fraction digits in minor units
+func parseDecimal(value string) (uint64, error) {
+	units, fraction, _ := strings.Cut(strings.TrimSpace(value), ".")
+	if len(fraction) > 2 {
+		return 0, fmt.Errorf("amount %q has more than two fraction digits", value)
+	}
+	fraction += strings.Repeat("0", 2-len(fraction))
+	amount, err := strconv.ParseUint(units+fraction, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return amount, nil
+}
+
+// ISO 20022 identifiers are Max35Text, uuid without dashes fits
+func isoId(id string) string {
+	return strings.ReplaceAll(id, "-", "")
+}
+
+type painReason struct {
+	Code       string `xml:"Rsn>Cd"`
+	Additional string `xml:"AddtlInf,omitempty"`
+}
+
+type painTransactionStatus struct {
+	StatusId         string      `xml:"StsId,omitempty"`
+	OrgnlEndToEndId  string      `xml:"OrgnlEndToEndId"`
+	TransactionState string      `xml:"TxSts"`
+	Reason           *painReason `xml:"StsRsnInf,omitempty"`
+}
+
+type painPaymentInfoStatus struct {
+	OrgnlPaymentInfoId string                  `xml:"OrgnlPmtInfId"`
+	Transactions       []painTransactionStatus `xml:"TxInfAndSts"`
+}
+
+// customer payment status report
+type pain002 struct {
+	XMLName           xml.Name                 `xml:"Document"`
+	Namespace         string                   `xml:"xmlns,attr"`
+	MessageId         string                   `xml:"CstmrPmtStsRpt>GrpHdr>MsgId"`
+	CreatedAt         string                   `xml:"CstmrPmtStsRpt>GrpHdr>CreDtTm"`
+	OrgnlMessageId    string                   `xml:"CstmrPmtStsRpt>OrgnlGrpInfAndSts>OrgnlMsgId"`
+	OrgnlMessageName  string                   `xml:"CstmrPmtStsRpt>OrgnlGrpInfAndSts>OrgnlMsgNmId"`
+	OrgnlTransactions int                      `xml:"CstmrPmtStsRpt>OrgnlGrpInfAndSts>OrgnlNbOfTxs"`
+	GroupStatus       string                   `xml:"CstmrPmtStsRpt>OrgnlGrpInfAndSts>GrpSts"`
+	PaymentInfoStatus []*painPaymentInfoStatus `xml:"CstmrPmtStsRpt>OrgnlPmtInfAndSts"`
+}
+
+// WritePain002 writes status report of the batch,
+// results are grouped by the payment information of the initiation
+func WritePain002(batch *Batch, results []*Result) ([]byte, error) {
+	report := &pain002{
+		Namespace:         pain002Namespace,
+		MessageId:         isoId(uuid.New().String()),
+		CreatedAt:         time.Now().UTC().Format("2006-01-02T15:04:05"),
+		OrgnlMessageId:    batch.MessageId,
+		OrgnlMessageName:  pain001Name,
+		OrgnlTransactions: len(results),
+	}
+	accepted := 0
+	infos := map[string]*painPaymentInfoStatus{}
+	for _, result := range results {
+		info, ok := infos[result.Row.PaymentInfoId]
+		if !ok {
+			info = &painPaymentInfoStatus{OrgnlPaymentInfoId: result.Row.PaymentInfoId}
+			infos[result.Row.PaymentInfoId] = info
+			report.PaymentInfoStatus = append(report.PaymentInfoStatus, info)
+		}
+		status := painTransactionStatus{
+			StatusId:         isoId(result.PaymentId),
+			OrgnlEndToEndId:  result.Row.Reference,
+			TransactionState: statusAccepted,
+		}
+		if result.Accepted {
+			accepted++
+		} else {
+			status.TransactionState = statusRejected
+			status.Reason = &painReason{
+				Code:       result.Reason,
+				Additional: result.Error,
+			}
+		}
+		info.Transactions = append(info.Transactions, status)
+	}
+	switch accepted {
+	case len(results):
+		report.GroupStatus = statusAccepted
+	case 0:
+		report.GroupStatus = statusRejected
+	default:
+		report.GroupStatus = statusPartial
+	}
+	data, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
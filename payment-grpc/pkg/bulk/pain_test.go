@@ -0,0 +1,119 @@
+package bulk
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseDecimal(t *testing.T) {
+	t.Parallel()
+
+	for value, amount := range map[string]uint64{
+		"12":      1200,
+		"12.5":    1250,
+		"12.05":   1205,
+		" 0.99 ":  99,
+		"1000.00": 100000,
+	} {
+		parsed, err := parseDecimal(value)
+		require.NoError(t, err, value)
+		require.Equal(t, amount, parsed, value)
+	}
+
+	for _, value := range []string{"1.234", "-1.00", "1,00", "abc"} {
+		_, err := parseDecimal(value)
+		require.Error(t, err, value)
+	}
+}
+
+const pain001Doc = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:pain.001.001.03">
+  <CstmrCdtTrfInitn>
+    <GrpHdr><MsgId>MSG-1</MsgId></GrpHdr>
+    <PmtInf>
+      <PmtInfId>INF-1</PmtInfId>
+      <DbtrAcct><Id><Othr><Id>` + sender + `</Id></Othr></Id></DbtrAcct>
+      <CdtTrfTxInf>
+        <PmtId><EndToEndId>E2E-1</EndToEndId></PmtId>
+        <Amt><InstdAmt Ccy="RUB">10.50</InstdAmt></Amt>
+        <CdtrAcct><Id><Othr><Id>` + receiver + `</Id></Othr></Id></CdtrAcct>
+        <RmtInf><Ustrd>invoice 1</Ustrd></RmtInf>
+      </CdtTrfTxInf>
+      <CdtTrfTxInf>
+        <PmtId><EndToEndId>E2E-2</EndToEndId></PmtId>
+        <Amt><InstdAmt Ccy="RUB">1.005</InstdAmt></Amt>
+        <CdtrAcct><Id><Othr><Id>` + receiver + `</Id></Othr></Id></CdtrAcct>
+      </CdtTrfTxInf>
+    </PmtInf>
+  </CstmrCdtTrfInitn>
+</Document>`
+
+func Test_ParsePain001(t *testing.T) {
+	t.Parallel()
+
+	batch, err := ParsePain001([]byte(pain001Doc))
+	require.NoError(t, err)
+	require.Equal(t, "MSG-1", batch.MessageId)
+	require.Len(t, batch.Rows, 2)
+
+	row := batch.Rows[0]
+	require.Empty(t, row.Reason)
+	require.Equal(t, "INF-1", row.PaymentInfoId)
+	require.Equal(t, uint64(1050), row.Request.Amount)
+	require.Equal(t, "invoice 1", row.Request.Note)
+	require.Equal(t, "MSG-1/E2E-1", row.Request.IdempotencyKey)
+
+	require.Equal(t, ReasonInvalidAmount, batch.Rows[1].Reason)
+}
+
+func Test_WritePain002(t *testing.T) {
+	t.Parallel()
+
+	batch, err := ParsePain001([]byte(pain001Doc))
+	require.NoError(t, err)
+	results := []*Result{
+		{
+			Row:       batch.Rows[0],
+			PaymentId: "6f1c9a52-3b7e-4d8a-9c0f-1e2d3c4b5a69",
+			Status:    "Successful transfer",
+			Accepted:  true,
+		},
+		{
+			Row:      batch.Rows[1],
+			Accepted: false,
+			Reason:   batch.Rows[1].Reason,
+			Error:    batch.Rows[1].Error,
+		},
+	}
+
+	data, err := WritePain002(batch, results)
+	require.NoError(t, err)
+
+	report := &pain002{}
+	require.NoError(t, xml.Unmarshal(data, report))
+	require.Len(t, report.MessageId, 32)
+	require.Equal(t, "MSG-1", report.OrgnlMessageId)
+	require.Equal(t, 2, report.OrgnlTransactions)
+	require.Equal(t, statusPartial, report.GroupStatus)
+	require.Len(t, report.PaymentInfoStatus, 1)
+
+	info := report.PaymentInfoStatus[0]
+	require.Equal(t, "INF-1", info.OrgnlPaymentInfoId)
+	require.Len(t, info.Transactions, 2)
+
+	accepted := info.Transactions[0]
+	require.Equal(t, "6f1c9a523b7e4d8a9c0f1e2d3c4b5a69", accepted.StatusId)
+	require.Equal(t, "E2E-1", accepted.OrgnlEndToEndId)
+	require.Equal(t, statusAccepted, accepted.TransactionState)
+	require.Nil(t, accepted.Reason)
+
+	rejected := info.Transactions[1]
+	require.Equal(t, statusRejected, rejected.TransactionState)
+	require.Equal(t, ReasonInvalidAmount, rejected.Reason.Code)
+	// rejected rows have no status id
+	require.NotContains(t, string(data), "<StsId></StsId>")
+	require.Equal(t, 1, strings.Count(string(data), "<StsId>"))
+}
@@ -0,0 +1,276 @@
+// Package iso8583 packs and unpacks ISO 8583 card messages.
+//
+// Messages are ASCII encoded: four digits message type indicator,
+// binary primary bitmap, binary secondary bitmap when data elements
+// 65-128 are present, and the data elements described by Spec.
+// On the wire every message is prefixed with two bytes big-endian length.
+package iso8583
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Message type indicators of the card traffic
+const (
+	AuthorizationRequest  = "0100"
+	AuthorizationResponse = "0110"
+	FinancialRequest      = "0200"
+	FinancialResponse     = "0210"
+	ReversalRequest       = "0400"
+	ReversalResponse      = "0410"
+	NetworkRequest        = "0800"
+	NetworkResponse       = "0810"
+)
+
+// largest message of the two bytes length prefix
+const maxMessageSize = 1<<16 - 1
+
+// Message is the message type indicator with data elements
+type Message struct {
+	MTI    string
+	fields map[int]string
+}
+
+func NewMessage(mti string) *Message {
+	return &Message{MTI: mti, fields: map[int]string{}}
+}
+
+// Set data element value, empty value removes the data element
+func (m *Message) Set(field int, value string) {
+	if value == "" {
+		delete(m.fields, field)
+		return
+	}
+	m.fields[field] = value
+}
+
+// Get data element value, empty if not present
+func (m *Message) Get(field int) string {
+	return m.fields[field]
+}
+
+func (m *Message) Has(field int) bool {
+	_, ok := m.fields[field]
+	return ok
+}
+
+// Fields present in the message in ascending order
+func (m *Message) Fields() []int {
+	fields := make([]int, 0, len(m.fields))
+	for field := range m.fields {
+		fields = append(fields, field)
+	}
+	sort.Ints(fields)
+	return fields
+}
+
+// Response with the type indicator of the response
+// and the data elements that identify the request
+func (m *Message) Response() *Message {
+	response := NewMessage(responseMTI(m.MTI))
+	for _, field := range []int{2, 3, 4, 7, 11, 12, 13, 32, 37, 41, 42, 49, 70, 90} {
+		response.Set(field, m.Get(field))
+	}
+	return response
+}
+
+// function digit of the request + 1
+func responseMTI(mti string) string {
+	if len(mti) != 4 {
+		return mti
+	}
+	return mti[:2] + string(mti[2]+1) + mti[3:]
+}
+
+// Pack encodes the message
+func (m *Message) Pack() ([]byte, error) {
+	if len(m.MTI) != 4 || !isDigits(m.MTI) {
+		return nil, fmt.Errorf("invalid message type indicator %q", m.MTI)
+	}
+	bitmap := make([]byte, 8)
+	body := strings.Builder{}
+	for _, field := range m.Fields() {
+		spec, ok := Spec[field]
+		if !ok || field < 2 || field > 128 {
+			return nil, fmt.Errorf("field %d is not supported", field)
+		}
+		if field > 64 && len(bitmap) == 8 {
+			// secondary bitmap is present
+			bitmap = append(bitmap, make([]byte, 8)...)
+			bitmap[0] |= 0x80
+		}
+		bitmap[(field-1)/8] |= 0x80 >> ((field - 1) % 8)
+		value, err := spec.encode(m.fields[field])
+		if err != nil {
+			return nil, fmt.Errorf("field %d: %w", field, err)
+		}
+		body.WriteString(value)
+	}
+	data := make([]byte, 0, 4+len(bitmap)+body.Len())
+	data = append(data, m.MTI...)
+	data = append(data, bitmap...)
+	data = append(data, body.String()...)
+	return data, nil
+}
+
+// Unpack decodes the message
+func Unpack(data []byte) (*Message, error) {
+	if len(data) < 12 {
+		return nil, errors.New("message is too short")
+	}
+	m := NewMessage(string(data[:4]))
+	if !isDigits(m.MTI) {
+		return nil, fmt.Errorf("invalid message type indicator %q", m.MTI)
+	}
+	bitmap := data[4:12]
+	pos := 12
+	if bitmap[0]&0x80 != 0 {
+		if len(data) < 20 {
+			return nil, errors.New("message is too short for the secondary bitmap")
+		}
+		bitmap = data[4:20]
+		pos = 20
+	}
+	for field := 2; field <= len(bitmap)*8; field++ {
+		if bitmap[(field-1)/8]&(0x80>>((field-1)%8)) == 0 {
+			continue
+		}
+		spec, ok := Spec[field]
+		if !ok {
+			return nil, fmt.Errorf("field %d is not supported", field)
+		}
+		value, n, err := spec.decode(data[pos:])
+		if err != nil {
+			return nil, fmt.Errorf("field %d: %w", field, err)
+		}
+		m.fields[field] = value
+		pos += n
+	}
+	if pos != len(data) {
+		return nil, fmt.Errorf("%d bytes after the last field", len(data)-pos)
+	}
+	return m, nil
+}
+
+func (f Field) encode(value string) (string, error) {
+	if f.Kind == Numeric && !isDigits(value) {
+		return "", fmt.Errorf("%s must be numeric", f.Name)
+	}
+	if len(value) > f.Size {
+		return "", fmt.Errorf("%s is longer than %d", f.Name, f.Size)
+	}
+	switch f.Length {
+	case LLVAR:
+		return fmt.Sprintf("%02d%s", len(value), value), nil
+	case LLLVAR:
+		return fmt.Sprintf("%03d%s", len(value), value), nil
+	}
+	if f.Kind == Numeric {
+		return strings.Repeat("0", f.Size-len(value)) + value, nil
+	}
+	return value + strings.Repeat(" ", f.Size-len(value)), nil
+}
+
+// value and the number of bytes read
+func (f Field) decode(data []byte) (string, int, error) {
+	size, prefix := f.Size, 0
+	switch f.Length {
+	case LLVAR:
+		prefix = 2
+	case LLLVAR:
+		prefix = 3
+	}
+	if prefix > 0 {
+		if len(data) < prefix {
+			return "", 0, errors.New("length is truncated")
+		}
+		// signs are not digits of the length
+		n, err := strconv.Atoi(string(data[:prefix]))
+		if err != nil || !isDigits(string(data[:prefix])) || n > f.Size {
+			return "", 0, fmt.Errorf("invalid length %q", data[:prefix])
+		}
+		size = n
+	}
+	if len(data) < prefix+size {
+		return "", 0, errors.New("value is truncated")
+	}
+	value := string(data[prefix : prefix+size])
+	if f.Kind == Numeric && !isDigits(value) {
+		return "", 0, fmt.Errorf("%s must be numeric", f.Name)
+	}
+	if f.Length == Fixed && f.Kind == Alphanumeric {
+		value = strings.TrimRight(value, " ")
+	}
+	return value, prefix + size, nil
+}
+
+func isDigits(value string) bool {
+	for _, c := range value {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// ReadFrame reads one message with the length prefix
+func ReadFrame(r io.Reader) ([]byte, error) {
+	prefix := make([]byte, 2)
+	if _, err := io.ReadFull(r, prefix); err != nil {
+		return nil, err
+	}
+	data := make([]byte, binary.BigEndian.Uint16(prefix))
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// WriteFrame writes the message with the length prefix
+func WriteFrame(w io.Writer, data []byte) error {
+	if len(data) > maxMessageSize {
+		return fmt.Errorf("message of %d bytes is too long", len(data))
+	}
+	frame := make([]byte, 2, 2+len(data))
+	binary.BigEndian.PutUint16(frame, uint16(len(data)))
+	_, err := w.Write(append(frame, data...))
+	return err
+}
+
+// ParseSubfields reads private data element as subfields:
+// two characters tag, two digits length and the value
+func ParseSubfields(value string) (map[string]string, error) {
+	subfields := map[string]string{}
+	for len(value) > 0 {
+		if len(value) < 4 {
+			return nil, errors.New("subfield is truncated")
+		}
+		n, err := strconv.Atoi(value[2:4])
+		if err != nil || !isDigits(value[2:4]) || len(value) < 4+n {
+			return nil, fmt.Errorf("invalid length of subfield %s", value[:2])
+		}
+		subfields[value[:2]] = value[4 : 4+n]
+		value = value[4+n:]
+	}
+	return subfields, nil
+}
+
+// FormatSubfields writes subfields in ascending order of tags
+func FormatSubfields(subfields map[string]string) string {
+	tags := make([]string, 0, len(subfields))
+	for tag := range subfields {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	value := strings.Builder{}
+	for _, tag := range tags {
+		fmt.Fprintf(&value, "%s%02d%s", tag, len(subfields[tag]), subfields[tag])
+	}
+	return value.String()
+}
@@ -0,0 +1,170 @@
+package iso8583
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Message(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Round trip", func(t *testing.T) {
+		m := NewMessage(AuthorizationRequest)
+		m.Set(2, "4444444444444444")
+		m.Set(3, "0")
+		m.Set(4, "1500")
+		m.Set(11, "123456")
+		m.Set(14, "2412")
+		m.Set(37, "000000000042")
+		m.Set(41, "T1")
+		m.Set(48, FormatSubfields(map[string]string{"01": "account", "02": "999"}))
+		m.Set(49, "643")
+
+		data, err := m.Pack()
+		require.NoError(t, err)
+		require.Equal(t, "0100", string(data[:4]))
+		// fields 2, 3, 4, 11, 14, 37, 41, 48, 49
+		require.Equal(t, []byte{0x70, 0x24, 0x00, 0x00, 0x08, 0x81, 0x80, 0x00}, data[4:12])
+		require.Equal(t, "16"+"4444444444444444"+"000000"+"000000001500"+"123456"+"2412"+"000000000042"+
+			"T1      "+"018"+"0107account0203999"+"643", string(data[12:]))
+
+		unpacked, err := Unpack(data)
+		require.NoError(t, err)
+		require.Equal(t, m.MTI, unpacked.MTI)
+		require.Equal(t, []int{2, 3, 4, 11, 14, 37, 41, 48, 49}, unpacked.Fields())
+		require.Equal(t, "4444444444444444", unpacked.Get(2))
+		// fixed numeric values are padded
+		require.Equal(t, "000000", unpacked.Get(3))
+		require.Equal(t, "000000001500", unpacked.Get(4))
+		require.Equal(t, "T1", unpacked.Get(41))
+
+		subfields, err := ParseSubfields(unpacked.Get(48))
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{"01": "account", "02": "999"}, subfields)
+	})
+
+	t.Run("Secondary bitmap", func(t *testing.T) {
+		m := NewMessage(NetworkRequest)
+		m.Set(7, "0611120000")
+		m.Set(11, "000001")
+		m.Set(70, "301")
+
+		data, err := m.Pack()
+		require.NoError(t, err)
+		require.Equal(t, byte(0x82), data[4])
+		require.Equal(t, byte(0x04), data[4+8])
+
+		unpacked, err := Unpack(data)
+		require.NoError(t, err)
+		require.Equal(t, "301", unpacked.Get(70))
+		require.Equal(t, []int{7, 11, 70}, unpacked.Fields())
+	})
+
+	t.Run("Response", func(t *testing.T) {
+		m := NewMessage(ReversalRequest)
+		m.Set(11, "000002")
+		m.Set(48, "0103abc")
+
+		response := m.Response()
+		require.Equal(t, ReversalResponse, response.MTI)
+		require.Equal(t, "000002", response.Get(11))
+		require.False(t, response.Has(48))
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		m := NewMessage(AuthorizationRequest)
+		m.Set(4, "15.00")
+		_, err := m.Pack()
+		require.Error(t, err)
+
+		m = NewMessage(AuthorizationRequest)
+		m.Set(2, "44444444444444444444")
+		_, err = m.Pack()
+		require.Error(t, err)
+
+		m = NewMessage(AuthorizationRequest)
+		m.Set(5, "1")
+		_, err = m.Pack()
+		require.Error(t, err)
+
+		m = NewMessage(AuthorizationRequest)
+		m.Set(2, "4444444444444444")
+		data, err := m.Pack()
+		require.NoError(t, err)
+		_, err = Unpack(data[:len(data)-1])
+		require.Error(t, err)
+		_, err = Unpack(append(data, '0'))
+		require.Error(t, err)
+
+		// field 5 is not in the spec
+		data[4] |= 0x08
+		_, err = Unpack(data)
+		require.Error(t, err)
+	})
+
+	t.Run("Negative length", func(t *testing.T) {
+		// field 2 of -1 digits
+		data := append([]byte("0100"), 0x40, 0, 0, 0, 0, 0, 0, 0)
+		_, err := Unpack(append(data, "-1"...))
+		require.Error(t, err)
+		_, err = Unpack(append(data, "+1"...))
+		require.Error(t, err)
+
+		_, err = ParseSubfields("01-1")
+		require.Error(t, err)
+		_, err = ParseSubfields("01 1a")
+		require.Error(t, err)
+	})
+
+	t.Run("Truncated frame", func(t *testing.T) {
+		_, err := ReadFrame(bytes.NewReader([]byte{0x00}))
+		require.Error(t, err)
+		_, err = ReadFrame(bytes.NewReader([]byte{0x00, 0x05, 'f', 'i'}))
+		require.Error(t, err)
+	})
+
+	t.Run("Frame", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		require.NoError(t, WriteFrame(buf, []byte("first")))
+		require.NoError(t, WriteFrame(buf, []byte("second")))
+		require.Equal(t, []byte{0x00, 0x05}, buf.Bytes()[:2])
+
+		frame, err := ReadFrame(buf)
+		require.NoError(t, err)
+		require.Equal(t, "first", string(frame))
+		frame, err = ReadFrame(buf)
+		require.NoError(t, err)
+		require.Equal(t, "second", string(frame))
+		_, err = ReadFrame(buf)
+		require.Error(t, err)
+	})
+}
+
+func FuzzUnpack(f *testing.F) {
+	m := NewMessage(AuthorizationRequest)
+	m.Set(2, "4444444444444444")
+	m.Set(4, "1500")
+	m.Set(41, "T1")
+	m.Set(48, FormatSubfields(map[string]string{"01": "account"}))
+	data, err := m.Pack()
+	require.NoError(f, err)
+	f.Add(data)
+	f.Add(append([]byte("0100"), 0x40, 0, 0, 0, 0, 0, 0, 0, '-', '1'))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		m, err := Unpack(data)
+		if err != nil {
+			return
+		}
+		// decoded messages are encoded again
+		packed, err := m.Pack()
+		require.NoError(t, err)
+		_, err = Unpack(packed)
+		require.NoError(t, err)
+		if m.Has(48) {
+			_, _ = ParseSubfields(m.Get(48))
+		}
+	})
+}
@@ -0,0 +1,62 @@
+package iso8583
+
+// Length of the field value
+type Length int
+
+const (
+	// Fixed length value
+	Fixed Length = iota
+	// LLVAR value with two digits length prefix
+	LLVAR
+	// LLLVAR value with three digits length prefix
+	LLLVAR
+)
+
+// Kind of the field value
+type Kind int
+
+const (
+	// Numeric digits, fixed values are padded with zeros on the left
+	Numeric Kind = iota
+	// Alphanumeric and special characters, fixed values are padded with spaces on the right
+	Alphanumeric
+)
+
+// Field describes encoding of a data element
+type Field struct {
+	Name   string
+	Length Length
+	Kind   Kind
+	// fixed length or max length of variable value
+	Size int
+}
+
+// Spec of the data elements used by the card traffic of the service,
+// messages with other data elements are rejected
+var Spec = map[int]Field{
+	2:   {Name: "Primary account number", Length: LLVAR, Kind: Numeric, Size: 19},
+	3:   {Name: "Processing code", Length: Fixed, Kind: Numeric, Size: 6},
+	4:   {Name: "Amount, transaction", Length: Fixed, Kind: Numeric, Size: 12},
+	7:   {Name: "Transmission date and time", Length: Fixed, Kind: Numeric, Size: 10},
+	11:  {Name: "System trace audit number", Length: Fixed, Kind: Numeric, Size: 6},
+	12:  {Name: "Time, local transaction", Length: Fixed, Kind: Numeric, Size: 6},
+	13:  {Name: "Date, local transaction", Length: Fixed, Kind: Numeric, Size: 4},
+	14:  {Name: "Date, expiration", Length: Fixed, Kind: Numeric, Size: 4},
+	18:  {Name: "Merchant type", Length: Fixed, Kind: Numeric, Size: 4},
+	22:  {Name: "Point of service entry mode", Length: Fixed, Kind: Numeric, Size: 3},
+	25:  {Name: "Point of service condition code", Length: Fixed, Kind: Numeric, Size: 2},
+	32:  {Name: "Acquiring institution identification code", Length: LLVAR, Kind: Numeric, Size: 11},
+	35:  {Name: "Track 2 data", Length: LLVAR, Kind: Alphanumeric, Size: 37},
+	37:  {Name: "Retrieval reference number", Length: Fixed, Kind: Alphanumeric, Size: 12},
+	38:  {Name: "Authorization identification response", Length: Fixed, Kind: Alphanumeric, Size: 6},
+	39:  {Name: "Response code", Length: Fixed, Kind: Alphanumeric, Size: 2},
+	41:  {Name: "Card acceptor terminal identification", Length: Fixed, Kind: Alphanumeric, Size: 8},
+	42:  {Name: "Card acceptor identification code", Length: Fixed, Kind: Alphanumeric, Size: 15},
+	43:  {Name: "Card acceptor name/location", Length: Fixed, Kind: Alphanumeric, Size: 40},
+	48:  {Name: "Additional data, private", Length: LLLVAR, Kind: Alphanumeric, Size: 999},
+	49:  {Name: "Currency code, transaction", Length: Fixed, Kind: Numeric, Size: 3},
+	54:  {Name: "Additional amounts", Length: LLLVAR, Kind: Alphanumeric, Size: 120},
+	70:  {Name: "Network management information code", Length: Fixed, Kind: Numeric, Size: 3},
+	90:  {Name: "Original data elements", Length: Fixed, Kind: Numeric, Size: 42},
+	102: {Name: "Account identification 1", Length: LLVAR, Kind: Alphanumeric, Size: 28},
+}
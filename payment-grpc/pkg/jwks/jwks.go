@@ -0,0 +1,136 @@
+package jwks
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	authpb "github.com/Edbeer/payment-proto/auth-grpc/proto"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// unknown kids refetch the keys at most once in the interval
+const refetchInterval = time.Minute
+
+// Claims of the access token
+type Claims struct {
+	AccountID string `json:"id"`
+	Card      string `json:"card"`
+	Role      string `json:"role"`
+	// OAuth2 client of the token and its space separated scopes
+	ClientID string `json:"client_id,omitempty"`
+	Scope    string `json:"scope,omitempty"`
+	// none in the tokens issued before the email verification
+	EmailVerified *bool `json:"email_verified,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Public keys of the auth service by kid, keys of the rotation
+// are fetched when a token signed with an unknown kid arrives
+type KeySet struct {
+	client authpb.AuthServiceClient
+
+	mu        sync.RWMutex
+	keys      map[string]ed25519.PublicKey
+	fetchedAt time.Time
+}
+
+func NewKeySet(client authpb.AuthServiceClient) *KeySet {
+	return &KeySet{
+		client: client,
+		keys:   map[string]ed25519.PublicKey{},
+	}
+}
+
+// Get public key of the kid
+func (s *KeySet) Key(ctx context.Context, kid string) (ed25519.PublicKey, error) {
+	s.mu.RLock()
+	key, ok := s.keys[kid]
+	fetchedAt := s.fetchedAt
+	s.mu.RUnlock()
+	if ok {
+		return key, nil
+	}
+	if time.Since(fetchedAt) < refetchInterval {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+	if err := s.fetch(ctx); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if key, ok := s.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key %q", kid)
+}
+
+func (s *KeySet) fetch(ctx context.Context) error {
+	jwks, err := s.client.GetJWKS(ctx, &authpb.JWKSRequest{})
+	if err != nil {
+		return err
+	}
+	keys := make(map[string]ed25519.PublicKey, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk.Kty != "OKP" || jwk.Crv != "Ed25519" {
+			continue
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			continue
+		}
+		keys[jwk.Kid] = ed25519.PublicKey(x)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = keys
+	s.fetchedAt = time.Now()
+	return nil
+}
+
+// Validate access token: EdDSA signature of a known key, not expired,
+// issued by the auth service for the api audience
+func (s *KeySet) Validate(ctx context.Context, tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			return nil, errors.New("token has no kid")
+		}
+		return s.Key(ctx, kid)
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodEdDSA.Alg()}))
+	if err != nil {
+		return nil, err
+	}
+	// exp, iat and nbf are checked by the parser
+	if claims.ExpiresAt == nil {
+		return nil, errors.New("token has no expiry")
+	}
+	if !claims.VerifyIssuer(issuer(), true) {
+		return nil, errors.New("invalid token issuer")
+	}
+	if !claims.VerifyAudience(audience(), true) {
+		return nil, errors.New("invalid token audience")
+	}
+	return claims, nil
+}
+
+// issuer and audience of the access tokens, same as in the auth service
+func issuer() string {
+	if issuer := os.Getenv("JWT_ISSUER"); issuer != "" {
+		return issuer
+	}
+	return "payment-auth"
+}
+
+func audience() string {
+	if audience := os.Getenv("JWT_AUDIENCE"); audience != "" {
+		return audience
+	}
+	return "payment-api"
+}
@@ -0,0 +1,19 @@
+package notify
+
+import (
+	"context"
+	"log"
+)
+
+// LogNotifier writes one-time codes to the service log,
+// stand-in for sms or push delivery in local environment
+type LogNotifier struct{}
+
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{}
+}
+
+func (n *LogNotifier) Notify(ctx context.Context, accountID, challengeID, code string) error {
+	log.Printf("challenge %s for account %s: code %s", challengeID, accountID, code)
+	return nil
+}
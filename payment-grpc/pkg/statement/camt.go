@@ -0,0 +1,139 @@
+package statement
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// namespace of the bank to customer statement
+const camt053Namespace = "urn:iso:std:iso:20022:tech:xsd:camt.053.001.02"
+
+// ISO 20022 date time without zone
+const isoDateTime = "2006-01-02T15:04:05"
+
+type camtAmount struct {
+	Currency string `xml:"Ccy,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type camtAccount struct {
+	Id string `xml:"Id>Othr>Id"`
+}
+
+type camtBalance struct {
+	Code      string     `xml:"Tp>CdOrPrtry>Cd"`
+	Amount    camtAmount `xml:"Amt"`
+	Indicator string     `xml:"CdtDbtInd"`
+	Date      string     `xml:"Dt>DtTm"`
+}
+
+type camtRelatedParties struct {
+	DebtorName      string       `xml:"Dbtr>Nm,omitempty"`
+	DebtorAccount   *camtAccount `xml:"DbtrAcct,omitempty"`
+	CreditorName    string       `xml:"Cdtr>Nm,omitempty"`
+	CreditorAccount *camtAccount `xml:"CdtrAcct,omitempty"`
+}
+
+type camtEntry struct {
+	Reference   string             `xml:"NtryRef"`
+	Amount      camtAmount         `xml:"Amt"`
+	Indicator   string             `xml:"CdtDbtInd"`
+	Status      string             `xml:"Sts"`
+	BookingDate string             `xml:"BookgDt>DtTm"`
+	ValueDate   string             `xml:"ValDt>DtTm"`
+	ServicerRef string             `xml:"AcctSvcrRef"`
+	Operation   string             `xml:"BkTxCd>Prtry>Cd"`
+	Parties     camtRelatedParties `xml:"NtryDtls>TxDtls>RltdPties"`
+	Info        string             `xml:"NtryDtls>TxDtls>AddtlTxInf,omitempty"`
+}
+
+type camt053 struct {
+	XMLName   xml.Name      `xml:"Document"`
+	Namespace string        `xml:"xmlns,attr"`
+	MessageId string        `xml:"BkToCstmrStmt>GrpHdr>MsgId"`
+	CreatedAt string        `xml:"BkToCstmrStmt>GrpHdr>CreDtTm"`
+	Id        string        `xml:"BkToCstmrStmt>Stmt>Id"`
+	StmtDate  string        `xml:"BkToCstmrStmt>Stmt>CreDtTm"`
+	From      string        `xml:"BkToCstmrStmt>Stmt>FrToDt>FrDtTm"`
+	To        string        `xml:"BkToCstmrStmt>Stmt>FrToDt>ToDtTm"`
+	Account   string        `xml:"BkToCstmrStmt>Stmt>Acct>Id>Othr>Id"`
+	Currency  string        `xml:"BkToCstmrStmt>Stmt>Acct>Ccy"`
+	Owner     string        `xml:"BkToCstmrStmt>Stmt>Acct>Ownr>Nm,omitempty"`
+	Balances  []camtBalance `xml:"BkToCstmrStmt>Stmt>Bal"`
+	Entries   []camtEntry   `xml:"BkToCstmrStmt>Stmt>Ntry"`
+}
+
+// opening booked and closing booked balances with the entries of the period
+func writeCamt053(st *Statement) ([]byte, error) {
+	doc := &camt053{
+		Namespace: camt053Namespace,
+		MessageId: st.Id,
+		CreatedAt: st.CreatedAt.UTC().Format(isoDateTime),
+		Id:        st.Id,
+		StmtDate:  st.CreatedAt.UTC().Format(isoDateTime),
+		From:      st.From.UTC().Format(isoDateTime),
+		To:        st.To.UTC().Format(isoDateTime),
+		Account:   st.AccountId,
+		Currency:  st.Currency,
+		Owner:     st.Owner,
+		Balances: []camtBalance{
+			camtBalanceOf("OPBD", st.Opening, st.Currency, st.From),
+			camtBalanceOf("CLBD", st.Closing, st.Currency, st.To),
+		},
+	}
+	for _, entry := range st.Entries {
+		booked := entry.BookedAt.UTC().Format(isoDateTime)
+		ntry := camtEntry{
+			Reference:   entry.PaymentId,
+			Amount:      camtAmount{Currency: entry.Currency, Value: formatDecimal(entry.Amount)},
+			Indicator:   indicator(entry.Amount),
+			Status:      "BOOK",
+			BookingDate: booked,
+			ValueDate:   booked,
+			ServicerRef: entry.PaymentId,
+			Operation:   entry.Operation,
+			Info:        entry.Status,
+		}
+		counterparty := &camtAccount{Id: entry.CounterpartyId}
+		own := &camtAccount{Id: st.AccountId}
+		// money comes from the debtor to the creditor
+		if entry.Amount < 0 {
+			ntry.Parties = camtRelatedParties{
+				DebtorName:      st.Owner,
+				DebtorAccount:   own,
+				CreditorName:    entry.CounterpartyName,
+				CreditorAccount: counterparty,
+			}
+		} else {
+			ntry.Parties = camtRelatedParties{
+				DebtorName:      entry.CounterpartyName,
+				DebtorAccount:   counterparty,
+				CreditorName:    st.Owner,
+				CreditorAccount: own,
+			}
+		}
+		doc.Entries = append(doc.Entries, ntry)
+	}
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+func camtBalanceOf(code string, amount int64, currency string, date time.Time) camtBalance {
+	return camtBalance{
+		Code:      code,
+		Amount:    camtAmount{Currency: currency, Value: formatDecimal(amount)},
+		Indicator: indicator(amount),
+		Date:      date.UTC().Format(isoDateTime),
+	}
+}
+
+// negative amounts are debits
+func indicator(amount int64) string {
+	if amount < 0 {
+		return "DBIT"
+	}
+	return "CRDT"
+}
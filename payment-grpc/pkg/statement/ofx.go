@@ -0,0 +1,97 @@
+package statement
+
+import (
+	"encoding/xml"
+)
+
+// OFX 2.2 header of the XML file
+const ofxHeader = `<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>` + "\n"
+
+// OFX date time, zone is UTC
+const ofxDateTime = "20060102150405"
+
+// bank identifier of the payment service
+const ofxBankId = "PAYMENT"
+
+type ofxTransaction struct {
+	Type     string `xml:"TRNTYPE"`
+	Posted   string `xml:"DTPOSTED"`
+	Amount   string `xml:"TRNAMT"`
+	Id       string `xml:"FITID"`
+	Name     string `xml:"NAME,omitempty"`
+	Memo     string `xml:"MEMO,omitempty"`
+	Currency string `xml:"CURRENCY>CURSYM,omitempty"`
+}
+
+type ofxBalance struct {
+	Amount string `xml:"BALAMT"`
+	Date   string `xml:"DTASOF"`
+}
+
+type ofx struct {
+	XMLName      xml.Name         `xml:"OFX"`
+	TransferId   string           `xml:"BANKMSGSRSV1>STMTTRNRS>TRNUID"`
+	StatusCode   int              `xml:"BANKMSGSRSV1>STMTTRNRS>STATUS>CODE"`
+	Severity     string           `xml:"BANKMSGSRSV1>STMTTRNRS>STATUS>SEVERITY"`
+	Currency     string           `xml:"BANKMSGSRSV1>STMTTRNRS>STMTRS>CURDEF"`
+	BankId       string           `xml:"BANKMSGSRSV1>STMTTRNRS>STMTRS>BANKACCTFROM>BANKID"`
+	AccountId    string           `xml:"BANKMSGSRSV1>STMTTRNRS>STMTRS>BANKACCTFROM>ACCTID"`
+	AccountType  string           `xml:"BANKMSGSRSV1>STMTTRNRS>STMTRS>BANKACCTFROM>ACCTTYPE"`
+	Start        string           `xml:"BANKMSGSRSV1>STMTTRNRS>STMTRS>BANKTRANLIST>DTSTART"`
+	End          string           `xml:"BANKMSGSRSV1>STMTTRNRS>STMTRS>BANKTRANLIST>DTEND"`
+	Transactions []ofxTransaction `xml:"BANKMSGSRSV1>STMTTRNRS>STMTRS>BANKTRANLIST>STMTTRN"`
+	Ledger       ofxBalance       `xml:"BANKMSGSRSV1>STMTTRNRS>STMTRS>LEDGERBAL"`
+	Available    ofxBalance       `xml:"BANKMSGSRSV1>STMTTRNRS>STMTRS>AVAILBAL"`
+}
+
+// bank statement response with closing balance as ledger and available balance
+func writeOFX(st *Statement) ([]byte, error) {
+	closing := ofxBalance{
+		Amount: signedDecimal(st.Closing),
+		Date:   st.To.UTC().Format(ofxDateTime),
+	}
+	doc := &ofx{
+		TransferId:  st.Id,
+		Severity:    "INFO",
+		Currency:    st.Currency,
+		BankId:      ofxBankId,
+		AccountId:   st.AccountId,
+		AccountType: "CHECKING",
+		Start:       st.From.UTC().Format(ofxDateTime),
+		End:         st.To.UTC().Format(ofxDateTime),
+		Ledger:      closing,
+		Available:   closing,
+	}
+	for _, entry := range st.Entries {
+		trnType := "CREDIT"
+		if entry.Amount < 0 {
+			trnType = "DEBIT"
+		}
+		tr := ofxTransaction{
+			Type:   trnType,
+			Posted: entry.BookedAt.UTC().Format(ofxDateTime),
+			Amount: signedDecimal(entry.Amount),
+			Id:     entry.PaymentId,
+			Name:   entry.CounterpartyName,
+			Memo:   entry.Operation + ": " + entry.Status,
+		}
+		// foreign currency entries only
+		if entry.Currency != st.Currency {
+			tr.Currency = entry.Currency
+		}
+		doc.Transactions = append(doc.Transactions, tr)
+	}
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header+ofxHeader), data...), nil
+}
+
+// decimal with minus sign for debits
+func signedDecimal(amount int64) string {
+	if amount < 0 {
+		return "-" + formatDecimal(amount)
+	}
+	return formatDecimal(amount)
+}
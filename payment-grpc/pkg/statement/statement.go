@@ -0,0 +1,140 @@
+// Package statement renders account statements as ISO 20022 camt.053,
+// CSV and OFX files.
+//
+// Amounts of the CSV file are in the units of the payment API,
+// camt.053 and OFX amounts are decimal with two fraction digits.
+package statement
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Supported file formats
+const (
+	FormatCamt053 = "camt.053"
+	FormatCSV     = "csv"
+	FormatOFX     = "ofx"
+)
+
+// currency of the statement without entries
+const noCurrency = "XXX"
+
+// Statement of the account for the period
+type Statement struct {
+	Id        string
+	AccountId string
+	Owner     string
+	Currency  string
+	From      time.Time
+	To        time.Time
+	// balances before and after the period
+	Opening   int64
+	Closing   int64
+	Entries   []*Entry
+	CreatedAt time.Time
+}
+
+// Entry is a payment that changed the account balance
+type Entry struct {
+	PaymentId string
+	Operation string
+	Status    string
+	// other side of the payment
+	CounterpartyId   string
+	CounterpartyName string
+	Currency         string
+	// positive for credit, negative for debit
+	Amount   int64
+	Balance  int64
+	BookedAt time.Time
+}
+
+// File is the rendered statement
+type File struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// Render writes the statement in the format
+func Render(st *Statement, format string) (*File, error) {
+	if st.Currency == "" {
+		st.Currency = noCurrency
+	}
+	// deposits are booked without currency
+	for _, entry := range st.Entries {
+		if entry.Currency == "" {
+			entry.Currency = st.Currency
+		}
+	}
+	var (
+		file = &File{}
+		err  error
+	)
+	switch format {
+	case FormatCamt053:
+		file.ContentType = "application/xml"
+		file.Data, err = writeCamt053(st)
+	case FormatCSV:
+		file.ContentType = "text/csv"
+		file.Data, err = writeCSV(st)
+	case FormatOFX:
+		file.ContentType = "application/x-ofx"
+		file.Data, err = writeOFX(st)
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	file.Name = fmt.Sprintf("statement-%s-%s-%s.%s",
+		st.AccountId, st.From.Format("20060102"), st.To.Format("20060102"), extension(format))
+	return file, nil
+}
+
+func extension(format string) string {
+	if format == FormatCamt053 {
+		return "xml"
+	}
+	return format
+}
+
+// opening and closing balances are the first and the last rows
+func writeCSV(st *Statement) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	writer := csv.NewWriter(buf)
+	rows := [][]string{
+		{"booked_at", "payment_id", "operation", "status", "counterparty_id", "counterparty_name", "currency", "amount", "balance"},
+		{st.From.Format(time.RFC3339), "", "Opening balance", "", "", "", st.Currency, "", strconv.FormatInt(st.Opening, 10)},
+	}
+	for _, entry := range st.Entries {
+		rows = append(rows, []string{
+			entry.BookedAt.Format(time.RFC3339),
+			entry.PaymentId,
+			entry.Operation,
+			entry.Status,
+			entry.CounterpartyId,
+			entry.CounterpartyName,
+			entry.Currency,
+			strconv.FormatInt(entry.Amount, 10),
+			strconv.FormatInt(entry.Balance, 10),
+		})
+	}
+	rows = append(rows, []string{st.To.Format(time.RFC3339), "", "Closing balance", "", "", "", st.Currency, "", strconv.FormatInt(st.Closing, 10)})
+	if err := writer.WriteAll(rows); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// minor units as decimal, sign is dropped
+func formatDecimal(amount int64) string {
+	if amount < 0 {
+		amount = -amount
+	}
+	return fmt.Sprintf("%d.%02d", amount/100, amount%100)
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+
+	"github.com/Edbeer/payment-grpc/types"
+	authpb "github.com/Edbeer/payment-proto/auth-grpc/proto"
+	paymentpb "github.com/Edbeer/payment-proto/payment-grpc/proto"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// hold extra money of the customer for the approved authorization
+func (s *PaymentService) IncrementAuthorization(ctx context.Context, req *paymentpb.PaidRequest) (*paymentpb.Statement, error) {
+	return s.changeAuthorization(ctx, req, "Increment", "Successful increment", 1)
+}
+
+// release part of the money held for the approved authorization
+func (s *PaymentService) DecrementAuthorization(ctx context.Context, req *paymentpb.PaidRequest) (*paymentpb.Statement, error) {
+	return s.changeAuthorization(ctx, req, "Decrement", "Successful decrement", -1)
+}
+
+// change the authorized amount by req amount in the direction of sign,
+// every change is saved as a separate row referencing the authorization
+func (s *PaymentService) changeAuthorization(
+	ctx context.Context, req *paymentpb.PaidRequest,
+	operation, paymentStatus string, sign int64,
+) (*paymentpb.Statement, error) {
+	paymentID, err := uuid.Parse(req.PaymentId)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid payment id")
+	}
+	if req.Amount == 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid amount")
+	}
+	// Begin Tx
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	// Get authorization, concurrent changes wait for the end of tx
+	refPayment, err := s.storage.GetPaymentForUpdate(ctx, paymentID, tx)
+	if err != nil {
+		return nil, err
+	}
+	if refPayment.Operation != "Authorization" || refPayment.Status != "Approved" || refPayment.ParentId.Valid {
+		return &paymentpb.Statement{
+			PaymentId: req.PaymentId,
+			Status:    "Invalid transaction",
+		}, nil
+	}
+	// shares of a split payment are fixed on authorization
+	children, err := s.storage.GetChildPayments(ctx, refPayment.PaymentId)
+	if err != nil {
+		return nil, err
+	}
+	if len(children) > 0 {
+		return &paymentpb.Statement{
+			PaymentId: req.PaymentId,
+			Status:    "Invalid transaction",
+		}, nil
+	}
+	// Get customer and merchant
+	accounts, err := getAccounts(ctx, s.client, refPayment.Customer.String(), refPayment.Merchant.String())
+	if err != nil {
+		return nil, err
+	}
+	customer, merchant := accounts[0], accounts[1]
+	refPayment.Operation = operation
+	// balance < req amount
+	if sign > 0 && customer.Balance < req.Amount {
+		return s.declinePayment(ctx, tx, authorizationChange(req, refPayment, "Insufficient funds"), merchant)
+	}
+	// whole authorization is released by cancel
+	if sign < 0 && refPayment.Amount <= req.Amount {
+		return s.declinePayment(ctx, tx, authorizationChange(req, refPayment, "Invalid amount"), merchant)
+	}
+	amount := sign * int64(req.Amount)
+	// captures are checked against the new authorized amount
+	if err := s.storage.UpdatePaymentAmount(ctx, refPayment.PaymentId, uint64(int64(refPayment.Amount)+amount), tx); err != nil {
+		return nil, err
+	}
+	savedPayment, err := s.storage.SavePayment(ctx, authorizationChange(req, refPayment, paymentStatus), tx)
+	if err != nil {
+		return nil, err
+	}
+	sts := []*authpb.StatementRequest{
+		newStatement(customer.Id, savedPayment, -amount),
+		newStatement(merchant.Id, savedPayment, 0),
+	}
+	if err := s.commitHoldChange(ctx, tx, refPayment, amount, sts); err != nil {
+		return nil, err
+	}
+	return &paymentpb.Statement{
+		PaymentId: savedPayment.PaymentId.String(),
+		Status:    savedPayment.Status,
+	}, nil
+}
+
+// row of the authorization change
+func authorizationChange(req *paymentpb.PaidRequest, refPayment *types.Payment, status string) *types.Payment {
+	payment := types.CreateCompletePayment(req, refPayment, status)
+	payment.ReferenceId = uuid.NullUUID{UUID: refPayment.PaymentId, Valid: true}
+	return payment
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	paymentpb "github.com/Edbeer/payment-proto/payment-grpc/proto"
+	mockpay "github.com/Edbeer/payment-grpc/service/mock"
+
+	"github.com/Edbeer/payment-grpc/types"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ChangeAuthorization(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	customer := newSplitAccount(40, 100)
+	merchant := newSplitAccount(0, 100)
+	newAuthorization := func() *types.Payment {
+		return heldPayment(&types.Payment{
+			PaymentId: uuid.New(),
+			Merchant:  uuid.MustParse(merchant.Id),
+			Customer:  uuid.MustParse(customer.Id),
+			Currency:  "rub",
+			Operation: "Authorization",
+			Status:    "Approved",
+			Amount:    100,
+			CreatedAt: time.Now(),
+		})
+	}
+
+	t.Run("Successful increment", func(t *testing.T) {
+		clientAuth, updates := mockSplitAccounts(ctrl, customer, merchant)
+		storagePay := mockpay.NewMockStorage(ctrl)
+		servicePay := NewPaymentService(storagePay, clientAuth, db, nil)
+
+		auth := newAuthorization()
+		req := &paymentpb.PaidRequest{
+			PaymentId: auth.PaymentId.String(),
+			Amount:    30,
+		}
+
+		mock.ExpectBegin()
+		storagePay.EXPECT().GetPaymentForUpdate(context.Background(), auth.PaymentId, gomock.Any()).Return(auth, nil)
+		storagePay.EXPECT().GetChildPayments(context.Background(), auth.PaymentId).Return([]*types.Payment{}, nil)
+		storagePay.EXPECT().UpdatePaymentAmount(context.Background(), auth.PaymentId, uint64(130), gomock.Any()).Return(nil)
+		var saved *types.Payment
+		storagePay.EXPECT().SavePayment(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, payment *types.Payment, tx *sql.Tx) (*types.Payment, error) {
+				saved = payment
+				return payment, nil
+			},
+		)
+		mock.ExpectCommit()
+
+		st, err := servicePay.IncrementAuthorization(context.Background(), req)
+		require.NoError(t, err)
+		require.Equal(t, "Successful increment", st.Status)
+
+		require.Equal(t, uint64(10), updates[customer.Id].Balance)
+		require.Equal(t, uint64(130), updates[customer.Id].BlockedMoney)
+		require.NotContains(t, updates, merchant.Id)
+
+		// increment is kept in history with a link to the authorization
+		require.Equal(t, "Increment", saved.Operation)
+		require.Equal(t, uint64(30), saved.Amount)
+		require.Equal(t, auth.PaymentId, saved.ReferenceId.UUID)
+	})
+
+	t.Run("Insufficient funds", func(t *testing.T) {
+		clientAuth, updates := mockSplitAccounts(ctrl, customer, merchant)
+		storagePay := mockpay.NewMockStorage(ctrl)
+		servicePay := NewPaymentService(storagePay, clientAuth, db, nil)
+
+		auth := newAuthorization()
+		req := &paymentpb.PaidRequest{
+			PaymentId: auth.PaymentId.String(),
+			Amount:    50,
+		}
+
+		mock.ExpectBegin()
+		storagePay.EXPECT().GetPaymentForUpdate(context.Background(), auth.PaymentId, gomock.Any()).Return(auth, nil)
+		storagePay.EXPECT().GetChildPayments(context.Background(), auth.PaymentId).Return([]*types.Payment{}, nil)
+		storagePay.EXPECT().SavePayment(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, payment *types.Payment, tx *sql.Tx) (*types.Payment, error) {
+				return payment, nil
+			},
+		)
+		mock.ExpectCommit()
+
+		st, err := servicePay.IncrementAuthorization(context.Background(), req)
+		require.NoError(t, err)
+		require.Equal(t, "Insufficient funds", st.Status)
+		require.Empty(t, updates)
+	})
+
+	t.Run("Successful decrement", func(t *testing.T) {
+		clientAuth, updates := mockSplitAccounts(ctrl, customer, merchant)
+		storagePay := mockpay.NewMockStorage(ctrl)
+		servicePay := NewPaymentService(storagePay, clientAuth, db, nil)
+
+		auth := newAuthorization()
+		req := &paymentpb.PaidRequest{
+			PaymentId: auth.PaymentId.String(),
+			Amount:    60,
+		}
+
+		mock.ExpectBegin()
+		storagePay.EXPECT().GetPaymentForUpdate(context.Background(), auth.PaymentId, gomock.Any()).Return(auth, nil)
+		storagePay.EXPECT().GetChildPayments(context.Background(), auth.PaymentId).Return([]*types.Payment{}, nil)
+		storagePay.EXPECT().UpdatePaymentAmount(context.Background(), auth.PaymentId, uint64(40), gomock.Any()).Return(nil)
+		var saved *types.Payment
+		storagePay.EXPECT().SavePayment(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, payment *types.Payment, tx *sql.Tx) (*types.Payment, error) {
+				saved = payment
+				return payment, nil
+			},
+		)
+		mock.ExpectCommit()
+
+		st, err := servicePay.DecrementAuthorization(context.Background(), req)
+		require.NoError(t, err)
+		require.Equal(t, "Successful decrement", st.Status)
+
+		require.Equal(t, uint64(100), updates[customer.Id].Balance)
+		require.Equal(t, uint64(40), updates[customer.Id].BlockedMoney)
+		require.NotContains(t, updates, merchant.Id)
+		require.Equal(t, "Decrement", saved.Operation)
+		require.Equal(t, auth.PaymentId, saved.ReferenceId.UUID)
+	})
+
+	t.Run("Decrement of the whole amount", func(t *testing.T) {
+		clientAuth, updates := mockSplitAccounts(ctrl, customer, merchant)
+		storagePay := mockpay.NewMockStorage(ctrl)
+		servicePay := NewPaymentService(storagePay, clientAuth, db, nil)
+
+		auth := newAuthorization()
+		req := &paymentpb.PaidRequest{
+			PaymentId: auth.PaymentId.String(),
+			Amount:    100,
+		}
+
+		mock.ExpectBegin()
+		storagePay.EXPECT().GetPaymentForUpdate(context.Background(), auth.PaymentId, gomock.Any()).Return(auth, nil)
+		storagePay.EXPECT().GetChildPayments(context.Background(), auth.PaymentId).Return([]*types.Payment{}, nil)
+		storagePay.EXPECT().SavePayment(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, payment *types.Payment, tx *sql.Tx) (*types.Payment, error) {
+				return payment, nil
+			},
+		)
+		mock.ExpectCommit()
+
+		st, err := servicePay.DecrementAuthorization(context.Background(), req)
+		require.NoError(t, err)
+		require.Equal(t, "Invalid amount", st.Status)
+		require.Empty(t, updates)
+	})
+
+	t.Run("Split payment", func(t *testing.T) {
+		clientAuth, updates := mockSplitAccounts(ctrl, customer, merchant)
+		storagePay := mockpay.NewMockStorage(ctrl)
+		servicePay := NewPaymentService(storagePay, clientAuth, db, nil)
+
+		auth := newAuthorization()
+		req := &paymentpb.PaidRequest{
+			PaymentId: auth.PaymentId.String(),
+			Amount:    10,
+		}
+
+		mock.ExpectBegin()
+		storagePay.EXPECT().GetPaymentForUpdate(context.Background(), auth.PaymentId, gomock.Any()).Return(auth, nil)
+		storagePay.EXPECT().GetChildPayments(context.Background(), auth.PaymentId).Return([]*types.Payment{
+			types.CreateSplitPayment(auth, uuid.New(), 50),
+		}, nil)
+		mock.ExpectRollback()
+
+		st, err := servicePay.IncrementAuthorization(context.Background(), req)
+		require.NoError(t, err)
+		require.Equal(t, "Invalid transaction", st.Status)
+		require.Empty(t, updates)
+	})
+}
@@ -0,0 +1,229 @@
+package service
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	authpb "github.com/Edbeer/payment-proto/auth-grpc/proto"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// time of the auth-side database query behind every request
+const benchQueryLatency = 200 * time.Microsecond
+
+// local stand-in of the auth service keeping the accounts in memory
+type benchAuthServer struct {
+	authpb.UnimplementedAuthServiceServer
+	mu       sync.Mutex
+	accounts map[string]*authpb.Account
+}
+
+func (s *benchAuthServer) account(id string) (*authpb.Account, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	account, ok := s.accounts[id]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "account not found")
+	}
+	return account, nil
+}
+
+func (s *benchAuthServer) GetAccountByID(ctx context.Context, req *authpb.GetIDRequest) (*authpb.Account, error) {
+	time.Sleep(benchQueryLatency)
+	return s.account(req.Id)
+}
+
+func (s *benchAuthServer) UpdateBalance(ctx context.Context, req *authpb.UpdateBalanceRequest) (*authpb.Account, error) {
+	time.Sleep(benchQueryLatency)
+	account, err := s.account(req.Id)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	account.Balance = req.Balance
+	account.BlockedMoney = req.BlockedMoney
+	return account, nil
+}
+
+func (s *benchAuthServer) CreateStatement(stream authpb.AuthService_CreateStatementServer) error {
+	for {
+		_, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		time.Sleep(benchQueryLatency)
+		if err := stream.Send(&authpb.StatementResponse{}); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *benchAuthServer) GetAccountsByIDs(ctx context.Context, req *authpb.GetIDsRequest) (*authpb.Accounts, error) {
+	time.Sleep(benchQueryLatency)
+	resp := &authpb.Accounts{}
+	for _, id := range req.Ids {
+		account, err := s.account(id)
+		if err != nil {
+			return nil, err
+		}
+		resp.Accounts = append(resp.Accounts, account)
+	}
+	return resp, nil
+}
+
+func (s *benchAuthServer) ApplyPaymentMovements(ctx context.Context, req *authpb.MovementsRequest) (*authpb.Accounts, error) {
+	time.Sleep(benchQueryLatency)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	resp := &authpb.Accounts{}
+	for _, m := range req.Movements {
+		account, ok := s.accounts[m.AccountId]
+		if !ok {
+			return nil, status.Error(codes.NotFound, "account not found")
+		}
+		account.Balance = uint64(int64(account.Balance) + m.Balance)
+		account.EscrowMoney = uint64(int64(account.EscrowMoney) + m.EscrowMoney)
+		resp.Accounts = append(resp.Accounts, account)
+	}
+	return resp, nil
+}
+
+func (s *benchAuthServer) CreateHold(ctx context.Context, req *authpb.CreateHoldRequest) (*authpb.Hold, error) {
+	time.Sleep(benchQueryLatency)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	account, ok := s.accounts[req.AccountId]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "account not found")
+	}
+	if account.Balance < req.Amount {
+		return nil, status.Error(codes.FailedPrecondition, "insufficient funds")
+	}
+	account.Balance -= req.Amount
+	account.BlockedMoney += req.Amount
+	return &authpb.Hold{Id: uuid.New().String(), AccountId: account.Id, Amount: req.Amount}, nil
+}
+
+// auth client connected to the stand-in over an in-memory listener
+func newBenchAuthClient(b *testing.B, accounts ...*authpb.Account) authpb.AuthServiceClient {
+	lis := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	standIn := &benchAuthServer{accounts: map[string]*authpb.Account{}}
+	for _, account := range accounts {
+		standIn.accounts[account.Id] = account
+	}
+	authpb.RegisterAuthServiceServer(server, standIn)
+	go server.Serve(lis)
+	b.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, s string) (net.Conn, error) {
+			return lis.Dial()
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { conn.Close() })
+	return authpb.NewAuthServiceClient(conn)
+}
+
+// authorization over the deprecated UpdateBalance and CreateStatement rpcs,
+// kept as the baseline of the benchmark
+func sequentialAuthorize(ctx context.Context, client authpb.AuthServiceClient, customerID, merchantID string, amount uint64) error {
+	customer, err := client.GetAccountByID(ctx, &authpb.GetIDRequest{Id: customerID})
+	if err != nil {
+		return err
+	}
+	merchant, err := client.GetAccountByID(ctx, &authpb.GetIDRequest{Id: merchantID})
+	if err != nil {
+		return err
+	}
+	if _, err := client.UpdateBalance(ctx, &authpb.UpdateBalanceRequest{
+		Id:           customer.Id,
+		Balance:      customer.Balance - amount,
+		BlockedMoney: customer.BlockedMoney + amount,
+	}); err != nil {
+		return err
+	}
+	if _, err := client.UpdateBalance(ctx, &authpb.UpdateBalanceRequest{
+		Id:           merchant.Id,
+		Balance:      merchant.Balance,
+		BlockedMoney: merchant.BlockedMoney + amount,
+	}); err != nil {
+		return err
+	}
+	stream, err := client.CreateStatement(ctx)
+	if err != nil {
+		return err
+	}
+	paymentID := uuid.New().String()
+	for _, id := range []string{customer.Id, merchant.Id} {
+		if err := stream.Send(&authpb.StatementRequest{AccountId: id, PaymentId: paymentID}); err != nil {
+			return err
+		}
+		if _, err := stream.Recv(); err != nil {
+			return err
+		}
+	}
+	return stream.CloseSend()
+}
+
+// authorization with one lookup and one hold request
+func batchedAuthorize(ctx context.Context, client authpb.AuthServiceClient, customerID, merchantID string, amount uint64) error {
+	accounts, err := getAccounts(ctx, client, customerID, merchantID)
+	if err != nil {
+		return err
+	}
+	customer, merchant := accounts[0], accounts[1]
+	paymentID := uuid.New().String()
+	_, err = client.CreateHold(ctx, &authpb.CreateHoldRequest{
+		AccountId: customer.Id,
+		Amount:    amount,
+		Reference: paymentID,
+		Statements: []*authpb.StatementRequest{
+			{AccountId: customer.Id, PaymentId: paymentID, Amount: -int64(amount)},
+			{AccountId: merchant.Id, PaymentId: paymentID},
+		},
+	})
+	return err
+}
+
+func BenchmarkAuthorize(b *testing.B) {
+	flows := []struct {
+		name      string
+		authorize func(ctx context.Context, client authpb.AuthServiceClient, customerID, merchantID string, amount uint64) error
+	}{
+		{name: "Sequential", authorize: sequentialAuthorize},
+		{name: "Batched", authorize: batchedAuthorize},
+	}
+	for _, flow := range flows {
+		b.Run(flow.name, func(b *testing.B) {
+			customer := newSplitAccount(uint64(b.N), 0)
+			merchant := newSplitAccount(0, 0)
+			client := newBenchAuthClient(b, customer, merchant)
+			ctx := context.Background()
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := flow.authorize(ctx, client, customer.Id, merchant.Id, 1); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
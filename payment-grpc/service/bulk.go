@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Edbeer/payment-grpc/pkg/bulk"
+	paymentpb "github.com/Edbeer/payment-proto/payment-grpc/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// transfers of the file executed at once
+var importWorkers = 8
+
+// execute transfers of the file, rows are independent:
+// failed row is reported and does not stop the others
+func (s *PaymentService) ImportPayments(ctx context.Context, req *paymentpb.ImportRequest) (*paymentpb.ImportReport, error) {
+	var (
+		batch *bulk.Batch
+		err   error
+	)
+	switch req.Format {
+	case bulk.FormatCSV:
+		batch, err = bulk.ParseCSV(req.File)
+	case bulk.FormatPain001:
+		batch, err = bulk.ParsePain001(req.File)
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "unknown format %q", req.Format)
+	}
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	results := make([]*bulk.Result, len(batch.Rows))
+	sem := make(chan struct{}, importWorkers)
+	wg := sync.WaitGroup{}
+	for i, row := range batch.Rows {
+		if row.Reason != "" {
+			results[i] = &bulk.Result{
+				Row:    row,
+				Status: "Invalid row",
+				Reason: row.Reason,
+				Error:  row.Error,
+			}
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, row *bulk.Row) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = s.importRow(ctx, row)
+		}(i, row)
+	}
+	wg.Wait()
+
+	report := &paymentpb.ImportReport{
+		MessageId: batch.MessageId,
+	}
+	for _, result := range results {
+		if result.Accepted {
+			report.Accepted++
+		} else {
+			report.Rejected++
+		}
+		report.Rows = append(report.Rows, &paymentpb.ImportRow{
+			Row:       result.Row.Number,
+			Reference: result.Row.Reference,
+			PaymentId: result.PaymentId,
+			Status:    result.Status,
+			Accepted:  result.Accepted,
+			Reason:    result.Error,
+		})
+	}
+	if batch.Format == bulk.FormatPain001 {
+		report.Report, err = bulk.WritePain002(batch, results)
+	} else {
+		report.Report, err = bulk.WriteCSV(results)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// execute the row as account-to-account transfer
+func (s *PaymentService) importRow(ctx context.Context, row *bulk.Row) *bulk.Result {
+	st, err := s.Transfer(ctx, row.Request)
+	if err != nil {
+		return &bulk.Result{
+			Row:    row,
+			Status: "Failed",
+			Reason: bulk.ReasonNarrative,
+			Error:  status.Convert(err).Message(),
+		}
+	}
+	result := &bulk.Result{
+		Row:       row,
+		PaymentId: st.PaymentId,
+		Status:    st.Status,
+		Accepted:  st.Status == "Successful transfer",
+	}
+	if !result.Accepted {
+		result.Reason = bulk.ReasonNarrative
+		result.Error = st.Status
+		if st.Status == "Insufficient funds" {
+			result.Reason = bulk.ReasonInsufficient
+		}
+	}
+	return result
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	paymentpb "github.com/Edbeer/payment-proto/payment-grpc/proto"
+	mockpay "github.com/Edbeer/payment-grpc/service/mock"
+
+	"github.com/Edbeer/payment-grpc/types"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func Test_ImportPayments(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	sender := newSplitAccount(100, 0)
+	receiver := newSplitAccount(0, 0)
+
+	// rows are executed concurrently
+	newImport := func(t *testing.T, executed int) (*PaymentService, map[string]*types.Transfer) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+		mock.MatchExpectationsInOrder(false)
+		for i := 0; i < executed; i++ {
+			mock.ExpectBegin()
+			mock.ExpectCommit()
+		}
+
+		clientAuth, _ := mockSplitAccounts(ctrl, sender, receiver)
+		storagePay := mockpay.NewMockStorage(ctrl)
+		storagePay.EXPECT().GetTransferByKey(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, sql.ErrNoRows).Times(executed)
+		storagePay.EXPECT().SavePayment(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, payment *types.Payment, tx *sql.Tx) (*types.Payment, error) {
+				return payment, nil
+			},
+		).Times(executed)
+		transfers := map[string]*types.Transfer{}
+		mu := sync.Mutex{}
+		storagePay.EXPECT().SaveTransfer(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, tr *types.Transfer, tx *sql.Tx) (*types.Transfer, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				transfers[tr.IdempotencyKey] = tr
+				return tr, nil
+			},
+		).Times(executed)
+		return NewPaymentService(storagePay, clientAuth, db, nil), transfers
+	}
+
+	t.Run("CSV", func(t *testing.T) {
+		servicePay, transfers := newImport(t, 2)
+		file := strings.Join([]string{
+			"sender_id,receiver_id,currency,amount,reference,note",
+			fmt.Sprintf("%s,%s,rub,40,salary-1,june", sender.Id, receiver.Id),
+			fmt.Sprintf("%s,not-an-account,rub,40,salary-2,june", sender.Id),
+			fmt.Sprintf("%s,%s,rub,500,salary-3,june", sender.Id, receiver.Id),
+			fmt.Sprintf("%s,%s,rub,40,salary-1,june", sender.Id, receiver.Id),
+		}, "\n")
+
+		report, err := servicePay.ImportPayments(context.Background(), &paymentpb.ImportRequest{
+			Format: "csv",
+			File:   []byte(file),
+		})
+		require.NoError(t, err)
+		require.Equal(t, uint32(1), report.Accepted)
+		require.Equal(t, uint32(3), report.Rejected)
+		require.Len(t, report.Rows, 4)
+
+		statuses := []string{}
+		for _, row := range report.Rows {
+			statuses = append(statuses, row.Status)
+		}
+		require.Equal(t, []string{"Successful transfer", "Invalid row", "Insufficient funds", "Invalid row"}, statuses)
+		require.Equal(t, "invalid receiver id", report.Rows[1].Reason)
+		require.Equal(t, "duplicate reference", report.Rows[3].Reason)
+		require.Equal(t, "june", transfers["salary-1"].Note)
+
+		lines := strings.Split(strings.TrimSpace(string(report.Report)), "\n")
+		require.Len(t, lines, 5)
+		require.True(t, strings.HasPrefix(lines[3], "3,salary-3,"))
+		require.True(t, strings.HasSuffix(lines[3], ",false,AM04,Insufficient funds"))
+	})
+
+	t.Run("pain.001", func(t *testing.T) {
+		servicePay, transfers := newImport(t, 1)
+		file := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:pain.001.001.03">
+  <CstmrCdtTrfInitn>
+    <GrpHdr><MsgId>PAYROLL-06</MsgId><NbOfTxs>2</NbOfTxs></GrpHdr>
+    <PmtInf>
+      <PmtInfId>BATCH-1</PmtInfId>
+      <DbtrAcct><Id><Othr><Id>%[1]s</Id></Othr></Id></DbtrAcct>
+      <CdtTrfTxInf>
+        <PmtId><EndToEndId>E2E-1</EndToEndId></PmtId>
+        <Amt><InstdAmt Ccy="RUB">0.40</InstdAmt></Amt>
+        <CdtrAcct><Id><Othr><Id>%[2]s</Id></Othr></Id></CdtrAcct>
+        <RmtInf><Ustrd>salary</Ustrd></RmtInf>
+      </CdtTrfTxInf>
+      <CdtTrfTxInf>
+        <PmtId><EndToEndId>E2E-2</EndToEndId></PmtId>
+        <Amt><InstdAmt Ccy="RUB">0.405</InstdAmt></Amt>
+        <CdtrAcct><Id><Othr><Id>%[2]s</Id></Othr></Id></CdtrAcct>
+      </CdtTrfTxInf>
+    </PmtInf>
+  </CstmrCdtTrfInitn>
+</Document>`, sender.Id, receiver.Id)
+
+		report, err := servicePay.ImportPayments(context.Background(), &paymentpb.ImportRequest{
+			Format: "pain.001",
+			File:   []byte(file),
+		})
+		require.NoError(t, err)
+		require.Equal(t, "PAYROLL-06", report.MessageId)
+		require.Equal(t, uint32(1), report.Accepted)
+		require.Equal(t, uint32(1), report.Rejected)
+
+		// end-to-end id is unique within the message only
+		transfer := transfers["PAYROLL-06/E2E-1"]
+		require.Equal(t, "salary", transfer.Note)
+		require.Equal(t, report.Rows[0].PaymentId, transfer.PaymentId.String())
+
+		pain002 := string(report.Report)
+		require.Contains(t, pain002, "pain.002.001.03")
+		require.Contains(t, pain002, "<OrgnlMsgId>PAYROLL-06</OrgnlMsgId>")
+		require.Contains(t, pain002, "<GrpSts>PART</GrpSts>")
+		require.Contains(t, pain002, "<OrgnlPmtInfId>BATCH-1</OrgnlPmtInfId>")
+		require.Contains(t, pain002, "<OrgnlEndToEndId>E2E-2</OrgnlEndToEndId>\n        <TxSts>RJCT</TxSts>")
+		require.Contains(t, pain002, "<Cd>AM12</Cd>")
+	})
+
+	t.Run("Unknown format", func(t *testing.T) {
+		servicePay := NewPaymentService(mockpay.NewMockStorage(ctrl), nil, nil, nil)
+
+		_, err := servicePay.ImportPayments(context.Background(), &paymentpb.ImportRequest{
+			Format: "xlsx",
+			File:   []byte(uuid.New().String()),
+		})
+		require.Error(t, err)
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
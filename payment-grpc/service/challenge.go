@@ -0,0 +1,232 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+
+	"github.com/Edbeer/payment-grpc/types"
+	authpb "github.com/Edbeer/payment-proto/auth-grpc/proto"
+	paymentpb "github.com/Edbeer/payment-proto/payment-grpc/proto"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// payments from this amount are challenged regardless of the merchant
+const challengeAmount = 100000
+
+// wrong codes before the challenge fails
+const maxChallengeAttempts = 3
+
+// time for the customer to complete the challenge
+var challengeTTL = 5 * time.Minute
+
+func (s *PaymentService) CompleteChallenge(ctx context.Context, req *paymentpb.ChallengeRequest) (*paymentpb.Statement, error) {
+	challengeID, err := uuid.Parse(req.ChallengeId)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid challenge id")
+	}
+	// Begin Tx
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	// Get challenge, concurrent answers wait for the end of tx
+	challenge, err := s.storage.GetChallenge(ctx, challengeID, tx)
+	if err != nil {
+		return nil, err
+	}
+	// only the customer of the payment completes the challenge
+	if err := checkCaller(ctx, permPaymentManageAny, challenge.Customer.String()); err != nil {
+		return nil, err
+	}
+	if challenge.State != types.ChallengePending {
+		return &paymentpb.Statement{
+			PaymentId:   challenge.PaymentId.String(),
+			Status:      "Invalid transaction",
+			ChallengeId: req.ChallengeId,
+		}, nil
+	}
+	// Get payment waiting for the challenge
+	refPayment, err := s.storage.GetPaymentByID(ctx, &paymentpb.PaidRequest{
+		PaymentId: challenge.PaymentId.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Get customer and merchant
+	accounts, err := getAccounts(ctx, s.client, refPayment.Customer.String(), refPayment.Merchant.String())
+	if err != nil {
+		return nil, err
+	}
+	customer, merchant := accounts[0], accounts[1]
+	// expired challenge is closed before the code is checked
+	if !time.Now().Before(challenge.ExpiresAt) {
+		return s.closeChallenge(ctx, tx, challenge, refPayment, merchant, types.ChallengeExpired, "Challenge expired")
+	}
+	// Wrong code
+	if subtle.ConstantTimeCompare([]byte(hashCode(req.Code)), []byte(challenge.CodeHash)) != 1 {
+		challenge.Attempts++
+		if challenge.Attempts >= maxChallengeAttempts {
+			return s.closeChallenge(ctx, tx, challenge, refPayment, merchant, types.ChallengeFailed, "Challenge failed")
+		}
+		if err := s.storage.UpdateChallenge(ctx, challenge, tx); err != nil {
+			return nil, err
+		}
+		// commit tx
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+		return &paymentpb.Statement{
+			PaymentId:   refPayment.PaymentId.String(),
+			Status:      "Invalid code",
+			ChallengeId: req.ChallengeId,
+		}, nil
+	}
+	// balance < payment amount
+	if customer.Balance < refPayment.Amount {
+		return s.closeChallenge(ctx, tx, challenge, refPayment, merchant, types.ChallengeCompleted, "Insufficient funds")
+	}
+	// hold money like on authorization without challenge
+	amount := int64(refPayment.Amount)
+	challenge.State = types.ChallengeCompleted
+	if err := s.storage.UpdateChallenge(ctx, challenge, tx); err != nil {
+		return nil, err
+	}
+	savedPayment, err := s.storage.SavePayment(ctx, challengeResult(refPayment, "Approved"), tx)
+	if err != nil {
+		return nil, err
+	}
+	sts := []*authpb.StatementRequest{
+		newStatement(customer.Id, savedPayment, -amount),
+		newStatement(merchant.Id, savedPayment, 0),
+	}
+	if err := s.commitHold(ctx, tx, savedPayment, sts); err != nil {
+		return nil, err
+	}
+	return &paymentpb.Statement{
+		PaymentId:   savedPayment.PaymentId.String(),
+		Status:      savedPayment.Status,
+		ChallengeId: req.ChallengeId,
+	}, nil
+}
+
+// save the payment waiting for the customer and send the one-time code
+func (s *PaymentService) requireChallenge(
+	ctx context.Context, tx *sql.Tx,
+	req *paymentpb.CreateRequest, customer, merchant *authpb.Account,
+) (*paymentpb.Statement, error) {
+	payment := types.CreateAuthPayment(req, customer, merchant, "Requires action")
+	savedPayment, err := s.storage.SavePayment(ctx, payment, tx)
+	if err != nil {
+		return nil, err
+	}
+	code, err := generateCode()
+	if err != nil {
+		return nil, err
+	}
+	challenge, err := s.storage.SaveChallenge(ctx, types.CreateChallenge(savedPayment, hashCode(code), challengeTTL), tx)
+	if err != nil {
+		return nil, err
+	}
+	// send statements to auth service
+	sts := []*authpb.StatementRequest{newStatement(merchant.Id, savedPayment, 0)}
+	if err := applyMovements(ctx, s.client, nil, sts); err != nil {
+		return nil, err
+	}
+	if err := s.notifier.Notify(ctx, customer.Id, challenge.ChallengeId.String(), code); err != nil {
+		return nil, err
+	}
+	// commit tx
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &paymentpb.Statement{
+		PaymentId:   savedPayment.PaymentId.String(),
+		Status:      savedPayment.Status,
+		ChallengeId: challenge.ChallengeId.String(),
+	}, nil
+}
+
+// finish the challenge without holding money
+func (s *PaymentService) closeChallenge(
+	ctx context.Context, tx *sql.Tx,
+	challenge *types.Challenge, refPayment *types.Payment, merchant *authpb.Account,
+	state, paymentStatus string,
+) (*paymentpb.Statement, error) {
+	challenge.State = state
+	if err := s.storage.UpdateChallenge(ctx, challenge, tx); err != nil {
+		return nil, err
+	}
+	statement, err := s.declinePayment(ctx, tx, challengeResult(refPayment, paymentStatus), merchant)
+	if err != nil {
+		return nil, err
+	}
+	statement.ChallengeId = challenge.ChallengeId.String()
+	return statement, nil
+}
+
+// authorization row with the result of the challenge
+func challengeResult(refPayment *types.Payment, status string) *types.Payment {
+	payment := types.CreateCompletePayment(&paymentpb.PaidRequest{
+		PaymentId: refPayment.PaymentId.String(),
+		Amount:    refPayment.Amount,
+	}, refPayment, status)
+	payment.ReferenceId = uuid.NullUUID{UUID: refPayment.PaymentId, Valid: true}
+	return payment
+}
+
+// close challenges the customer did not complete in time
+func (s *PaymentService) ExpireChallenges(ctx context.Context) error {
+	challenges, err := s.storage.GetExpiredChallenges(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	for _, challenge := range challenges {
+		if _, err := s.CompleteChallenge(ctx, &paymentpb.ChallengeRequest{
+			ChallengeId: challenge.ChallengeId.String(),
+		}); err != nil {
+			log.Printf("expire challenge %s: %v", challenge.ChallengeId, err)
+		}
+	}
+	return nil
+}
+
+// expire challenges every interval until ctx is done
+func (s *PaymentService) RunChallengeExpiry(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.ExpireChallenges(ctx); err != nil {
+				log.Printf("expire challenges: %v", err)
+			}
+		}
+	}
+}
+
+// six digit one-time code
+func generateCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+func hashCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,239 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	paymentpb "github.com/Edbeer/payment-proto/payment-grpc/proto"
+	mockpay "github.com/Edbeer/payment-grpc/service/mock"
+
+	"github.com/Edbeer/payment-grpc/types"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_CreatePaymentChallenge(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	customer := newSplitAccount(100, 0)
+	merchant := newSplitAccount(0, 0)
+	clientAuth, updates := mockSplitAccounts(ctrl, customer, merchant)
+	storagePay := mockpay.NewMockStorage(ctrl)
+	notifier := mockpay.NewMockNotifier(ctrl)
+
+	servicePay := NewPaymentService(storagePay, clientAuth, db, notifier)
+	req := &paymentpb.CreateRequest{
+		Merchant:         merchant.Id,
+		Customer:         customer.Id,
+		CardNumber:       customer.CardNumber,
+		CardExpiryMonth:  customer.CardExpiryMonth,
+		CardExpiryYear:   customer.CardExpiryYear,
+		CardSecurityCode: customer.CardSecurityCode,
+		Currency:         "rub",
+		Amount:           50,
+		Challenge:        true,
+	}
+
+	mock.ExpectBegin()
+	var saved *types.Payment
+	storagePay.EXPECT().SavePayment(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, payment *types.Payment, tx *sql.Tx) (*types.Payment, error) {
+			saved = payment
+			return payment, nil
+		},
+	)
+	var challenge *types.Challenge
+	storagePay.EXPECT().SaveChallenge(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, ch *types.Challenge, tx *sql.Tx) (*types.Challenge, error) {
+			challenge = ch
+			return ch, nil
+		},
+	)
+	var code string
+	notifier.EXPECT().Notify(gomock.Any(), customer.Id, gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, accountID, challengeID, c string) error {
+			code = c
+			return nil
+		},
+	)
+	mock.ExpectCommit()
+
+	st, err := servicePay.CreatePayment(context.Background(), req)
+	require.NoError(t, err)
+	require.Equal(t, "Requires action", st.Status)
+	require.Equal(t, challenge.ChallengeId.String(), st.ChallengeId)
+
+	// money is not blocked until the challenge is completed
+	require.Empty(t, updates)
+	require.Equal(t, "Authorization", saved.Operation)
+	require.Equal(t, saved.PaymentId, challenge.PaymentId)
+	require.Equal(t, types.ChallengePending, challenge.State)
+	// only the hash of the code is stored
+	require.Len(t, code, 6)
+	require.Equal(t, hashCode(code), challenge.CodeHash)
+}
+
+func Test_CompleteChallenge(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	customer := newSplitAccount(100, 0)
+	merchant := newSplitAccount(0, 0)
+	pending := &types.Payment{
+		PaymentId: uuid.New(),
+		Merchant:  uuid.MustParse(merchant.Id),
+		Customer:  uuid.MustParse(customer.Id),
+		Currency:  "rub",
+		Operation: "Authorization",
+		Status:    "Requires action",
+		Amount:    60,
+		CreatedAt: time.Now(),
+	}
+
+	t.Run("Approved", func(t *testing.T) {
+		clientAuth, updates := mockSplitAccounts(ctrl, customer, merchant)
+		storagePay := mockpay.NewMockStorage(ctrl)
+		servicePay := NewPaymentService(storagePay, clientAuth, db, nil)
+
+		challenge := types.CreateChallenge(pending, hashCode("123456"), time.Minute)
+
+		mock.ExpectBegin()
+		storagePay.EXPECT().GetChallenge(context.Background(), challenge.ChallengeId, gomock.Any()).Return(challenge, nil)
+		storagePay.EXPECT().GetPaymentByID(context.Background(), gomock.Any()).Return(pending, nil)
+		storagePay.EXPECT().UpdateChallenge(context.Background(), challenge, gomock.Any()).Return(nil)
+		var saved *types.Payment
+		storagePay.EXPECT().SavePayment(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, payment *types.Payment, tx *sql.Tx) (*types.Payment, error) {
+				saved = payment
+				return payment, nil
+			},
+		)
+		// approved row holds the money for the capture
+		storagePay.EXPECT().UpdatePaymentHold(context.Background(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, paymentID, holdID uuid.UUID, tx *sql.Tx) error {
+				require.Equal(t, saved.PaymentId, paymentID)
+				return nil
+			},
+		)
+		mock.ExpectCommit()
+
+		st, err := servicePay.CompleteChallenge(context.Background(), &paymentpb.ChallengeRequest{
+			ChallengeId: challenge.ChallengeId.String(),
+			Code:        "123456",
+		})
+		require.NoError(t, err)
+		require.Equal(t, "Approved", st.Status)
+		require.Equal(t, saved.PaymentId.String(), st.PaymentId)
+
+		require.Equal(t, types.ChallengeCompleted, challenge.State)
+		require.Equal(t, "Authorization", saved.Operation)
+		require.Equal(t, pending.PaymentId, saved.ReferenceId.UUID)
+		require.Equal(t, uint64(40), updates[customer.Id].Balance)
+		require.Equal(t, uint64(60), updates[customer.Id].BlockedMoney)
+		require.NotContains(t, updates, merchant.Id)
+	})
+
+	t.Run("Invalid code", func(t *testing.T) {
+		clientAuth, updates := mockSplitAccounts(ctrl, customer, merchant)
+		storagePay := mockpay.NewMockStorage(ctrl)
+		servicePay := NewPaymentService(storagePay, clientAuth, db, nil)
+
+		challenge := types.CreateChallenge(pending, hashCode("123456"), time.Minute)
+
+		mock.ExpectBegin()
+		storagePay.EXPECT().GetChallenge(context.Background(), challenge.ChallengeId, gomock.Any()).Return(challenge, nil)
+		storagePay.EXPECT().GetPaymentByID(context.Background(), gomock.Any()).Return(pending, nil)
+		storagePay.EXPECT().UpdateChallenge(context.Background(), challenge, gomock.Any()).Return(nil)
+		mock.ExpectCommit()
+
+		st, err := servicePay.CompleteChallenge(context.Background(), &paymentpb.ChallengeRequest{
+			ChallengeId: challenge.ChallengeId.String(),
+			Code:        "000000",
+		})
+		require.NoError(t, err)
+		require.Equal(t, "Invalid code", st.Status)
+		require.Equal(t, uint32(1), challenge.Attempts)
+		require.Equal(t, types.ChallengePending, challenge.State)
+		require.Empty(t, updates)
+	})
+
+	for _, tc := range []struct {
+		name     string
+		attempts uint32
+		ttl      time.Duration
+		state    string
+		status   string
+	}{
+		{name: "Failed", attempts: maxChallengeAttempts - 1, ttl: time.Minute, state: types.ChallengeFailed, status: "Challenge failed"},
+		{name: "Expired", ttl: -time.Minute, state: types.ChallengeExpired, status: "Challenge expired"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			clientAuth, updates := mockSplitAccounts(ctrl, customer, merchant)
+			storagePay := mockpay.NewMockStorage(ctrl)
+			servicePay := NewPaymentService(storagePay, clientAuth, db, nil)
+
+			challenge := types.CreateChallenge(pending, hashCode("123456"), tc.ttl)
+			challenge.Attempts = tc.attempts
+
+			mock.ExpectBegin()
+			storagePay.EXPECT().GetChallenge(context.Background(), challenge.ChallengeId, gomock.Any()).Return(challenge, nil)
+			storagePay.EXPECT().GetPaymentByID(context.Background(), gomock.Any()).Return(pending, nil)
+			storagePay.EXPECT().UpdateChallenge(context.Background(), challenge, gomock.Any()).Return(nil)
+			var saved *types.Payment
+			storagePay.EXPECT().SavePayment(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, payment *types.Payment, tx *sql.Tx) (*types.Payment, error) {
+					saved = payment
+					return payment, nil
+				},
+			)
+			mock.ExpectCommit()
+
+			st, err := servicePay.CompleteChallenge(context.Background(), &paymentpb.ChallengeRequest{
+				ChallengeId: challenge.ChallengeId.String(),
+				Code:        "000000",
+			})
+			require.NoError(t, err)
+			require.Equal(t, tc.status, st.Status)
+			require.Equal(t, tc.state, challenge.State)
+			require.Equal(t, pending.PaymentId, saved.ReferenceId.UUID)
+			require.Empty(t, updates)
+		})
+	}
+
+	t.Run("Not pending", func(t *testing.T) {
+		clientAuth, _ := mockSplitAccounts(ctrl, customer, merchant)
+		storagePay := mockpay.NewMockStorage(ctrl)
+		servicePay := NewPaymentService(storagePay, clientAuth, db, nil)
+
+		challenge := types.CreateChallenge(pending, hashCode("123456"), time.Minute)
+		challenge.State = types.ChallengeCompleted
+
+		mock.ExpectBegin()
+		storagePay.EXPECT().GetChallenge(context.Background(), challenge.ChallengeId, gomock.Any()).Return(challenge, nil)
+		mock.ExpectRollback()
+
+		st, err := servicePay.CompleteChallenge(context.Background(), &paymentpb.ChallengeRequest{
+			ChallengeId: challenge.ChallengeId.String(),
+			Code:        "123456",
+		})
+		require.NoError(t, err)
+		require.Equal(t, "Invalid transaction", st.Status)
+	})
+}
@@ -0,0 +1,223 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/Edbeer/payment-grpc/types"
+	authpb "github.com/Edbeer/payment-proto/auth-grpc/proto"
+	paymentpb "github.com/Edbeer/payment-proto/payment-grpc/proto"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *PaymentService) ReleaseEscrow(ctx context.Context, req *paymentpb.PaidRequest) (*paymentpb.Statement, error) {
+	paymentID, err := uuid.Parse(req.PaymentId)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid payment id")
+	}
+	// Begin Tx
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	// Get escrow, concurrent release or refund waits for the end of tx
+	escrow, err := s.storage.GetEscrow(ctx, paymentID, tx)
+	if err != nil {
+		return nil, err
+	}
+	if escrow.State != types.EscrowHeld {
+		return &paymentpb.Statement{
+			PaymentId: req.PaymentId,
+			Status:    "Invalid transaction",
+		}, nil
+	}
+	// Get referenced payment
+	refPayment, err := s.storage.GetPaymentByID(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	// Get merchant
+	accounts, err := getAccounts(ctx, s.client, escrow.Merchant.String())
+	if err != nil {
+		return nil, err
+	}
+	merchant := accounts[0]
+	// money left in escrow goes to the merchant balance
+	amount := int64(escrow.Amount)
+	movements := []*movement{
+		{account: merchant, balance: amount, escrow: -amount},
+	}
+	// released money is captured for the merchant and can be refunded as usual
+	released := types.CreateCompletePayment(&paymentpb.PaidRequest{
+		PaymentId: req.PaymentId,
+		Amount:    escrow.Amount,
+	}, refPayment, "Successful payment")
+	return s.completeEscrow(ctx, tx, escrow, released, types.EscrowReleased, movements)
+}
+
+func (s *PaymentService) RefundEscrow(ctx context.Context, req *paymentpb.PaidRequest) (*paymentpb.Statement, error) {
+	paymentID, err := uuid.Parse(req.PaymentId)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid payment id")
+	}
+	// Begin Tx
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	// Get escrow, concurrent release or refund waits for the end of tx
+	escrow, err := s.storage.GetEscrow(ctx, paymentID, tx)
+	if err != nil {
+		return nil, err
+	}
+	if escrow.State != types.EscrowHeld {
+		return &paymentpb.Statement{
+			PaymentId: req.PaymentId,
+			Status:    "Invalid transaction",
+		}, nil
+	}
+	// Get referenced payment
+	refPayment, err := s.storage.GetPaymentByID(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	refPayment.Operation = "Refund"
+	// Invalid amount
+	if escrow.Amount < req.Amount {
+		completedPayment := types.CreateCompletePayment(req, refPayment, "Invalid amount")
+		invalidPayment, err := s.storage.SavePayment(ctx, completedPayment, tx)
+		if err != nil {
+			return nil, err
+		}
+		sts := []*authpb.StatementRequest{newStatement(escrow.Merchant.String(), invalidPayment, 0)}
+		if err := s.commitPayment(ctx, tx, nil, sts); err != nil {
+			return nil, err
+		}
+		return &paymentpb.Statement{
+			PaymentId: invalidPayment.PaymentId.String(),
+			Status:    invalidPayment.Status,
+		}, nil
+	}
+	// Get customer and merchant
+	accounts, err := getAccounts(ctx, s.client, escrow.Customer.String(), escrow.Merchant.String())
+	if err != nil {
+		return nil, err
+	}
+	customer, merchant := accounts[0], accounts[1]
+	// money is returned from escrow to the customer
+	amount := int64(req.Amount)
+	movements := []*movement{
+		{account: customer, balance: amount},
+		{account: merchant, escrow: -amount},
+	}
+	// escrow stays held until the whole amount is refunded
+	escrow.Amount = escrow.Amount - req.Amount
+	state := types.EscrowHeld
+	if escrow.Amount == 0 {
+		state = types.EscrowRefunded
+	}
+	refunded := types.CreateCompletePayment(req, refPayment, "Successful refund")
+	return s.completeEscrow(ctx, tx, escrow, refunded, state, movements)
+}
+
+// move captured money of the authorization to escrow of the merchant
+func (s *PaymentService) captureToEscrow(
+	ctx context.Context, tx *sql.Tx,
+	req *paymentpb.PaidRequest, refPayment *types.Payment,
+	customer, merchant *authpb.Account,
+) (*paymentpb.Statement, error) {
+	// the rest of the hold is returned to the customer
+	amount := int64(req.Amount)
+	movements := []*movement{
+		{account: customer, balance: int64(refPayment.Amount) - amount},
+		{account: merchant, escrow: amount},
+	}
+	// make complete payment held in escrow
+	completedPayment := types.CreateCompletePayment(req, refPayment, "In escrow")
+	completedPayment, err := s.storage.SavePayment(ctx, completedPayment, tx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.storage.SaveEscrow(ctx, types.CreateEscrow(completedPayment), tx); err != nil {
+		return nil, err
+	}
+	if err := s.commitCapture(ctx, tx, refPayment, completedPayment, movements[1:], escrowStatements(completedPayment, movements)); err != nil {
+		return nil, err
+	}
+	return &paymentpb.Statement{
+		PaymentId: completedPayment.PaymentId.String(),
+		Status:    completedPayment.Status,
+	}, nil
+}
+
+// save payment of the escrow release or refund and the new escrow state
+func (s *PaymentService) completeEscrow(
+	ctx context.Context, tx *sql.Tx,
+	escrow *types.Escrow, payment *types.Payment, state string, movements []*movement,
+) (*paymentpb.Statement, error) {
+	if state == types.EscrowReleased {
+		escrow.Amount = 0
+	}
+	escrow.State = state
+	if _, err := s.storage.UpdateEscrow(ctx, escrow, tx); err != nil {
+		return nil, err
+	}
+	savedPayment, err := s.storage.SavePayment(ctx, payment, tx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.commitPayment(ctx, tx, movements, escrowStatements(savedPayment, movements)); err != nil {
+		return nil, err
+	}
+	return &paymentpb.Statement{
+		PaymentId: savedPayment.PaymentId.String(),
+		Status:    savedPayment.Status,
+	}, nil
+}
+
+// statements for the customer and the merchant
+func escrowStatements(payment *types.Payment, movements []*movement) []*authpb.StatementRequest {
+	customer, merchant := payment.Customer.String(), payment.Merchant.String()
+	return []*authpb.StatementRequest{
+		newStatement(customer, payment, statementAmount(movements, customer)),
+		newStatement(merchant, payment, statementAmount(movements, merchant)),
+	}
+}
+
+// release escrows whose hold time has passed
+func (s *PaymentService) ReleaseDueEscrows(ctx context.Context) error {
+	escrows, err := s.storage.GetDueEscrows(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	for _, escrow := range escrows {
+		if _, err := s.ReleaseEscrow(ctx, &paymentpb.PaidRequest{
+			PaymentId: escrow.PaymentId.String(),
+		}); err != nil {
+			log.Printf("release escrow %s: %v", escrow.PaymentId, err)
+		}
+	}
+	return nil
+}
+
+// release due escrows every interval until ctx is done
+func (s *PaymentService) RunEscrowRelease(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.ReleaseDueEscrows(ctx); err != nil {
+				log.Printf("release due escrows: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,325 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	paymentpb "github.com/Edbeer/payment-proto/payment-grpc/proto"
+	mockpay "github.com/Edbeer/payment-grpc/service/mock"
+
+	"github.com/Edbeer/payment-grpc/types"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_CaptureEscrowPayment(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	customer := newSplitAccount(50, 100)
+	merchant := newSplitAccount(0, 0)
+	clientAuth, updates := mockSplitAccounts(ctrl, customer, merchant)
+	storagePay := mockpay.NewMockStorage(ctrl)
+
+	servicePay := NewPaymentService(storagePay, clientAuth, db, nil)
+	refPayment := heldPayment(&types.Payment{
+		PaymentId:       uuid.New(),
+		Merchant:        uuid.MustParse(merchant.Id),
+		Customer:        uuid.MustParse(customer.Id),
+		Currency:        "rub",
+		Operation:       "Authorization",
+		Status:          "Approved",
+		Amount:          100,
+		CreatedAt:       time.Now(),
+		Escrow:          true,
+		EscrowHoldHours: 24,
+	})
+	req := &paymentpb.PaidRequest{
+		PaymentId: refPayment.PaymentId.String(),
+		Amount:    80,
+	}
+
+	mock.ExpectBegin()
+	storagePay.EXPECT().GetPaymentByID(context.Background(), req).Return(refPayment, nil)
+	storagePay.EXPECT().GetChildPayments(context.Background(), refPayment.PaymentId).Return([]*types.Payment{}, nil)
+	var saved *types.Payment
+	storagePay.EXPECT().SavePayment(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, payment *types.Payment, tx *sql.Tx) (*types.Payment, error) {
+			saved = payment
+			return payment, nil
+		},
+	)
+	var held *types.Escrow
+	storagePay.EXPECT().SaveEscrow(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, escrow *types.Escrow, tx *sql.Tx) (*types.Escrow, error) {
+			held = escrow
+			return escrow, nil
+		},
+	)
+	mock.ExpectCommit()
+
+	st, err := servicePay.CapturePayment(context.Background(), req)
+	require.NoError(t, err)
+	require.Equal(t, "In escrow", st.Status)
+	require.Equal(t, "Capture", saved.Operation)
+
+	// captured money is held in escrow, not on the merchant balance,
+	// the rest of the hold returns to the customer
+	require.Equal(t, uint64(70), updates[customer.Id].Balance)
+	require.Equal(t, uint64(0), updates[customer.Id].BlockedMoney)
+	require.Equal(t, uint64(0), updates[merchant.Id].Balance)
+	require.Equal(t, uint64(80), updates[merchant.Id].GetEscrowMoney())
+
+	require.Equal(t, saved.PaymentId, held.PaymentId)
+	require.Equal(t, uint64(80), held.Amount)
+	require.Equal(t, types.EscrowHeld, held.State)
+	require.True(t, held.ReleaseAt.Valid)
+	require.Equal(t, 24*time.Hour, held.ReleaseAt.Time.Sub(held.CreatedAt))
+}
+
+func Test_ReleaseEscrow(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	customer := newSplitAccount(50, 0)
+	merchant := newSplitAccount(10, 0)
+	merchant.EscrowMoney = 80
+	capture := &types.Payment{
+		PaymentId: uuid.New(),
+		Merchant:  uuid.MustParse(merchant.Id),
+		Customer:  uuid.MustParse(customer.Id),
+		Currency:  "rub",
+		Operation: "Capture",
+		Status:    "In escrow",
+		Amount:    80,
+		CreatedAt: time.Now(),
+		Escrow:    true,
+	}
+	req := &paymentpb.PaidRequest{
+		PaymentId: capture.PaymentId.String(),
+	}
+
+	t.Run("Released", func(t *testing.T) {
+		clientAuth, updates := mockSplitAccounts(ctrl, customer, merchant)
+		storagePay := mockpay.NewMockStorage(ctrl)
+		servicePay := NewPaymentService(storagePay, clientAuth, db, nil)
+
+		escrow := types.CreateEscrow(capture)
+		// part of escrow was already refunded
+		escrow.Amount = 60
+
+		mock.ExpectBegin()
+		storagePay.EXPECT().GetEscrow(context.Background(), capture.PaymentId, gomock.Any()).Return(escrow, nil)
+		storagePay.EXPECT().GetPaymentByID(context.Background(), req).Return(capture, nil)
+		var updated *types.Escrow
+		storagePay.EXPECT().UpdateEscrow(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, escrow *types.Escrow, tx *sql.Tx) (*types.Escrow, error) {
+				updated = escrow
+				return escrow, nil
+			},
+		)
+		var saved *types.Payment
+		storagePay.EXPECT().SavePayment(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, payment *types.Payment, tx *sql.Tx) (*types.Payment, error) {
+				saved = payment
+				return payment, nil
+			},
+		)
+		mock.ExpectCommit()
+
+		st, err := servicePay.ReleaseEscrow(context.Background(), req)
+		require.NoError(t, err)
+		require.Equal(t, "Successful payment", st.Status)
+		require.Equal(t, "Capture", saved.Operation)
+		require.Equal(t, uint64(60), saved.Amount)
+
+		require.Equal(t, uint64(70), updates[merchant.Id].Balance)
+		require.Equal(t, uint64(20), updates[merchant.Id].GetEscrowMoney())
+		require.Equal(t, types.EscrowReleased, updated.State)
+		require.Equal(t, uint64(0), updated.Amount)
+	})
+
+	t.Run("Not held", func(t *testing.T) {
+		clientAuth, updates := mockSplitAccounts(ctrl, customer, merchant)
+		storagePay := mockpay.NewMockStorage(ctrl)
+		servicePay := NewPaymentService(storagePay, clientAuth, db, nil)
+
+		escrow := types.CreateEscrow(capture)
+		escrow.State = types.EscrowReleased
+
+		mock.ExpectBegin()
+		storagePay.EXPECT().GetEscrow(context.Background(), capture.PaymentId, gomock.Any()).Return(escrow, nil)
+		mock.ExpectRollback()
+
+		st, err := servicePay.ReleaseEscrow(context.Background(), req)
+		require.NoError(t, err)
+		require.Equal(t, "Invalid transaction", st.Status)
+		require.Empty(t, updates)
+	})
+}
+
+func Test_RefundEscrow(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	customer := newSplitAccount(50, 0)
+	merchant := newSplitAccount(10, 0)
+	merchant.EscrowMoney = 80
+	capture := &types.Payment{
+		PaymentId: uuid.New(),
+		Merchant:  uuid.MustParse(merchant.Id),
+		Customer:  uuid.MustParse(customer.Id),
+		Currency:  "rub",
+		Operation: "Capture",
+		Status:    "In escrow",
+		Amount:    80,
+		CreatedAt: time.Now(),
+		Escrow:    true,
+	}
+
+	for _, tc := range []struct {
+		name   string
+		amount uint64
+		state  string
+	}{
+		{name: "Partial refund", amount: 30, state: types.EscrowHeld},
+		{name: "Full refund", amount: 80, state: types.EscrowRefunded},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			clientAuth, updates := mockSplitAccounts(ctrl, customer, merchant)
+			storagePay := mockpay.NewMockStorage(ctrl)
+			servicePay := NewPaymentService(storagePay, clientAuth, db, nil)
+			req := &paymentpb.PaidRequest{
+				PaymentId: capture.PaymentId.String(),
+				Amount:    tc.amount,
+			}
+
+			mock.ExpectBegin()
+			storagePay.EXPECT().GetEscrow(context.Background(), capture.PaymentId, gomock.Any()).Return(types.CreateEscrow(capture), nil)
+			storagePay.EXPECT().GetPaymentByID(context.Background(), req).Return(capture, nil)
+			var updated *types.Escrow
+			storagePay.EXPECT().UpdateEscrow(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, escrow *types.Escrow, tx *sql.Tx) (*types.Escrow, error) {
+					updated = escrow
+					return escrow, nil
+				},
+			)
+			var saved *types.Payment
+			storagePay.EXPECT().SavePayment(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, payment *types.Payment, tx *sql.Tx) (*types.Payment, error) {
+					saved = payment
+					return payment, nil
+				},
+			)
+			mock.ExpectCommit()
+
+			st, err := servicePay.RefundEscrow(context.Background(), req)
+			require.NoError(t, err)
+			require.Equal(t, "Successful refund", st.Status)
+			require.Equal(t, "Refund", saved.Operation)
+			require.Equal(t, tc.amount, saved.Amount)
+
+			// money goes back to the customer straight from escrow
+			require.Equal(t, 50+tc.amount, updates[customer.Id].Balance)
+			require.Equal(t, uint64(10), updates[merchant.Id].Balance)
+			require.Equal(t, 80-tc.amount, updates[merchant.Id].GetEscrowMoney())
+			require.Equal(t, tc.state, updated.State)
+			require.Equal(t, 80-tc.amount, updated.Amount)
+		})
+	}
+
+	t.Run("Invalid amount", func(t *testing.T) {
+		clientAuth, updates := mockSplitAccounts(ctrl, customer, merchant)
+		storagePay := mockpay.NewMockStorage(ctrl)
+		servicePay := NewPaymentService(storagePay, clientAuth, db, nil)
+		req := &paymentpb.PaidRequest{
+			PaymentId: capture.PaymentId.String(),
+			Amount:    100,
+		}
+
+		mock.ExpectBegin()
+		storagePay.EXPECT().GetEscrow(context.Background(), capture.PaymentId, gomock.Any()).Return(types.CreateEscrow(capture), nil)
+		storagePay.EXPECT().GetPaymentByID(context.Background(), req).Return(capture, nil)
+		storagePay.EXPECT().SavePayment(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, payment *types.Payment, tx *sql.Tx) (*types.Payment, error) {
+				return payment, nil
+			},
+		)
+		mock.ExpectCommit()
+
+		st, err := servicePay.RefundEscrow(context.Background(), req)
+		require.NoError(t, err)
+		require.Equal(t, "Invalid amount", st.Status)
+		require.Empty(t, updates)
+	})
+}
+
+func Test_ReleaseDueEscrows(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	customer := newSplitAccount(0, 0)
+	merchant := newSplitAccount(0, 0)
+	merchant.EscrowMoney = 40
+	clientAuth, updates := mockSplitAccounts(ctrl, customer, merchant)
+	storagePay := mockpay.NewMockStorage(ctrl)
+	servicePay := NewPaymentService(storagePay, clientAuth, db, nil)
+
+	capture := &types.Payment{
+		PaymentId:       uuid.New(),
+		Merchant:        uuid.MustParse(merchant.Id),
+		Customer:        uuid.MustParse(customer.Id),
+		Currency:        "rub",
+		Operation:       "Capture",
+		Status:          "In escrow",
+		Amount:          40,
+		CreatedAt:       time.Now().Add(-2 * time.Hour),
+		Escrow:          true,
+		EscrowHoldHours: 1,
+	}
+	escrow := types.CreateEscrow(capture)
+
+	storagePay.EXPECT().GetDueEscrows(gomock.Any(), gomock.Any()).Return([]*types.Escrow{escrow}, nil)
+	mock.ExpectBegin()
+	storagePay.EXPECT().GetEscrow(gomock.Any(), capture.PaymentId, gomock.Any()).Return(escrow, nil)
+	storagePay.EXPECT().GetPaymentByID(gomock.Any(), gomock.Any()).Return(capture, nil)
+	storagePay.EXPECT().UpdateEscrow(gomock.Any(), gomock.Any(), gomock.Any()).Return(escrow, nil)
+	storagePay.EXPECT().SavePayment(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, payment *types.Payment, tx *sql.Tx) (*types.Payment, error) {
+			return payment, nil
+		},
+	)
+	mock.ExpectCommit()
+
+	require.NoError(t, servicePay.ReleaseDueEscrows(context.Background()))
+	require.Equal(t, uint64(40), updates[merchant.Id].Balance)
+	require.Equal(t, uint64(0), updates[merchant.Id].GetEscrowMoney())
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/Edbeer/payment-grpc/pkg/statement"
+	authpb "github.com/Edbeer/payment-proto/auth-grpc/proto"
+	paymentpb "github.com/Edbeer/payment-proto/payment-grpc/proto"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// statement of the account for the period as camt.053, CSV or OFX file,
+// balances are the account balances recorded with the statement entries
+func (s *PaymentService) ExportStatement(ctx context.Context, req *paymentpb.ExportRequest) (*paymentpb.StatementFile, error) {
+	if _, err := uuid.Parse(req.AccountId); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid account id")
+	}
+	switch req.Format {
+	case statement.FormatCamt053, statement.FormatCSV, statement.FormatOFX:
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "unknown format %q", req.Format)
+	}
+	now := time.Now()
+	from, to := time.Time{}, now
+	if req.From != nil {
+		from = req.From.AsTime()
+	}
+	if req.To != nil {
+		to = req.To.AsTime()
+	}
+	if !from.Before(to) {
+		return nil, status.Error(codes.InvalidArgument, "invalid period")
+	}
+	// Get account
+	account, err := s.client.GetAccountByID(ctx, &authpb.GetIDRequest{
+		Id: req.AccountId,
+	})
+	if err != nil {
+		return nil, err
+	}
+	entries, err := statementEntries(ctx, s.client, account.Id, from, to, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	st := &statement.Statement{
+		// camt.053 message and statement ids are Max35Text
+		Id:        strings.ReplaceAll(uuid.New().String(), "-", ""),
+		AccountId: account.Id,
+		Owner:     strings.TrimSpace(account.FirstName + " " + account.LastName),
+		From:      from,
+		To:        to,
+		CreatedAt: now,
+	}
+	// balances of the entries migrated from the statement array are unknown,
+	// they are counted from the nearest recorded balance
+	closing, ok := balanceAfter(entries)
+	if !ok {
+		// no balance is recorded in the period, the balance is the one
+		// before the next recorded entry or the current one
+		next, err := statementEntries(ctx, s.client, account.Id, to, time.Time{}, 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(next) > 0 && next[0].Balance == nil {
+			next, err = statementEntries(ctx, s.client, account.Id, to, time.Time{}, 0)
+			if err != nil {
+				return nil, err
+			}
+		}
+		closing = balanceBefore(next, int64(account.Balance))
+	}
+	balances := make([]int64, len(entries))
+	balance := closing
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Balance != nil {
+			balance = int64(*entries[i].Balance)
+		}
+		balances[i] = balance
+		balance -= entries[i].SignedAmount
+	}
+	st.Opening, st.Closing = balance, closing
+	for i, entry := range entries {
+		if entry.SignedAmount == 0 {
+			continue
+		}
+		if st.Currency == "" && entry.Currency != "" {
+			st.Currency = strings.ToUpper(entry.Currency)
+		}
+		st.Entries = append(st.Entries, &statement.Entry{
+			PaymentId:        entry.PaymentId,
+			Operation:        entry.Operation,
+			Status:           entry.Status,
+			CounterpartyId:   entry.CounterpartyId,
+			CounterpartyName: entry.CounterpartyName,
+			Currency:         strings.ToUpper(entry.Currency),
+			Amount:           entry.SignedAmount,
+			Balance:          balances[i],
+			BookedAt:         entry.CreatedAt.AsTime(),
+		})
+	}
+
+	file, err := statement.Render(st, req.Format)
+	if err != nil {
+		return nil, err
+	}
+	return &paymentpb.StatementFile{
+		FileName:    file.Name,
+		ContentType: file.ContentType,
+		Data:        file.Data,
+	}, nil
+}
+
+// account balance after the entries, counted from the last recorded balance
+func balanceAfter(entries []*authpb.Statement) (int64, bool) {
+	change := int64(0)
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Balance != nil {
+			return int64(*entries[i].Balance) + change, true
+		}
+		change += entries[i].SignedAmount
+	}
+	return 0, false
+}
+
+// account balance before the entries, counted from the first recorded balance
+// or from the current balance when none is recorded
+func balanceBefore(entries []*authpb.Statement, current int64) int64 {
+	change := int64(0)
+	for _, entry := range entries {
+		change += entry.SignedAmount
+		if entry.Balance != nil {
+			return int64(*entry.Balance) - change
+		}
+	}
+	return current - change
+}
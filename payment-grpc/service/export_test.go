@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	mockpay "github.com/Edbeer/payment-grpc/service/mock"
+	mock_proto "github.com/Edbeer/payment-proto/auth-grpc/client/mock"
+	authpb "github.com/Edbeer/payment-proto/auth-grpc/proto"
+	paymentpb "github.com/Edbeer/payment-proto/payment-grpc/proto"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func Test_ExportStatement(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// current balances include the changes after the period
+	customer := newSplitAccount(1000, 0)
+	merchant := newSplitAccount(130, 0)
+	merchant.FirstName, merchant.LastName = "Shop", "Owner"
+	idle := newSplitAccount(110, 0)
+	migrated := newSplitAccount(500, 0)
+	legacy := newSplitAccount(500, 0)
+
+	from := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+	newEntry := func(operation, status string, amount int64, balance uint64, createdAt time.Time) *authpb.Statement {
+		return &authpb.Statement{
+			PaymentId:        uuid.New().String(),
+			SignedAmount:     amount,
+			Balance:          &balance,
+			CreatedAt:        timestamppb.New(createdAt),
+			CounterpartyId:   customer.Id,
+			CounterpartyName: "Pasha Volkov",
+			Operation:        operation,
+			Status:           status,
+			Currency:         "rub",
+		}
+	}
+	// deposit without payment row, split capture with 30 paid to the seller
+	deposit := newEntry("Deposit", "Successful deposit", 100, 150, from.Add(time.Hour))
+	deposit.CounterpartyId, deposit.CounterpartyName, deposit.Currency = "", "", ""
+	authorization := newEntry("Authorization", "Approved", 0, 150, from.Add(2*time.Hour))
+	split := newEntry("Capture", "Successful payment", 70, 220, from.Add(3*time.Hour))
+	refund := newEntry("Refund", "Successful refund", -20, 200, from.Add(4*time.Hour))
+	// partial capture of 60, the rest of the authorization is released
+	customerAuthorization := newEntry("Authorization", "Approved", -100, 900, from.Add(time.Hour))
+	customerCapture := newEntry("Capture", "Successful payment", 40, 940, from.Add(2*time.Hour))
+	// entry of the idle account after the period
+	next := newEntry("Transfer", "Successful transfer", 30, 110, to.Add(time.Hour))
+	// entries migrated from the statement array have no balance
+	newMigrated := func(amount int64, createdAt time.Time) *authpb.Statement {
+		entry := newEntry("Authorization", "Approved", amount, 0, createdAt)
+		entry.Balance = nil
+		return entry
+	}
+	migratedAuthorization := newMigrated(-50, from.Add(time.Hour))
+	migratedCapture := newMigrated(20, from.Add(3*time.Hour))
+	recorded := newEntry("Transfer", "Successful transfer", 30, 280, from.Add(2*time.Hour))
+	legacyCapture := newMigrated(40, from.Add(time.Hour))
+	legacyRefund := newMigrated(-100, to.Add(time.Hour))
+
+	booked := map[string][]*authpb.Statement{
+		merchant.Id: {deposit, authorization, split, refund},
+		customer.Id: {customerAuthorization, customerCapture},
+		idle.Id:     {next},
+		migrated.Id: {migratedAuthorization, recorded, migratedCapture},
+		legacy.Id:   {legacyCapture, legacyRefund},
+	}
+	clientAuth, _ := mockSplitAccounts(ctrl, customer, merchant, idle, migrated, legacy)
+	clientAuth.EXPECT().GetStatement(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, req *authpb.StatementGet, opts ...grpc.CallOption) (authpb.AuthService_GetStatementClient, error) {
+			stream := mock_proto.NewMockAuthService_GetStatementClient(ctrl)
+			calls := []*gomock.Call{}
+			for i, entry := range booked[req.AccountId] {
+				createdAt := entry.CreatedAt.AsTime()
+				if req.From != nil && createdAt.Before(req.From.AsTime()) ||
+					req.To != nil && !createdAt.Before(req.To.AsTime()) {
+					continue
+				}
+				entry.Cursor = uint64(i + 1)
+				calls = append(calls, stream.EXPECT().Recv().Return(entry, nil))
+			}
+			calls = append(calls, stream.EXPECT().Recv().Return(nil, io.EOF))
+			gomock.InOrder(calls...)
+			return stream, nil
+		},
+	).AnyTimes()
+	servicePay := NewPaymentService(mockpay.NewMockStorage(ctrl), clientAuth, nil, nil)
+
+	export := func(accountID, format string) *paymentpb.StatementFile {
+		file, err := servicePay.ExportStatement(context.Background(), &paymentpb.ExportRequest{
+			AccountId: accountID,
+			From:      timestamppb.New(from),
+			To:        timestamppb.New(to),
+			Format:    format,
+		})
+		require.NoError(t, err)
+		return file
+	}
+
+	t.Run("CSV", func(t *testing.T) {
+		file := export(merchant.Id, "csv")
+		require.Equal(t, "text/csv", file.ContentType)
+		require.Equal(t, "statement-"+merchant.Id+"-20230301-20230401.csv", file.FileName)
+
+		lines := strings.Split(strings.TrimSpace(string(file.Data)), "\n")
+		require.Len(t, lines, 6)
+		// opening is the balance before the deposit
+		require.True(t, strings.HasSuffix(lines[1], ",Opening balance,,,,RUB,,50"))
+		require.Contains(t, lines[2], deposit.PaymentId+",Deposit,Successful deposit,,,RUB,100,150")
+		require.Contains(t, lines[3], split.PaymentId+",Capture,Successful payment,"+customer.Id+",Pasha Volkov,RUB,70,220")
+		require.Contains(t, lines[4], refund.PaymentId+",Refund,Successful refund,"+customer.Id+",Pasha Volkov,RUB,-20,200")
+		require.True(t, strings.HasSuffix(lines[5], ",Closing balance,,,,RUB,,200"))
+	})
+
+	t.Run("Partial capture", func(t *testing.T) {
+		file := export(customer.Id, "csv")
+
+		lines := strings.Split(strings.TrimSpace(string(file.Data)), "\n")
+		require.Len(t, lines, 5)
+		require.True(t, strings.HasSuffix(lines[1], ",Opening balance,,,,RUB,,1000"))
+		require.Contains(t, lines[2], customerAuthorization.PaymentId+",Authorization,Approved,")
+		require.True(t, strings.HasSuffix(lines[2], ",RUB,-100,900"))
+		// the rest of the authorization is returned by the capture
+		require.True(t, strings.HasSuffix(lines[3], ",RUB,40,940"))
+		require.True(t, strings.HasSuffix(lines[4], ",Closing balance,,,,RUB,,940"))
+	})
+
+	t.Run("Without entries", func(t *testing.T) {
+		file := export(idle.Id, "csv")
+
+		lines := strings.Split(strings.TrimSpace(string(file.Data)), "\n")
+		require.Len(t, lines, 3)
+		// balance before the entry after the period, not the current one
+		require.True(t, strings.HasSuffix(lines[1], ",Opening balance,,,,XXX,,80"))
+		require.True(t, strings.HasSuffix(lines[2], ",Closing balance,,,,XXX,,80"))
+	})
+
+	t.Run("Migrated entries", func(t *testing.T) {
+		file := export(migrated.Id, "csv")
+
+		lines := strings.Split(strings.TrimSpace(string(file.Data)), "\n")
+		require.Len(t, lines, 6)
+		// balances are counted from the recorded one
+		require.True(t, strings.HasSuffix(lines[1], ",Opening balance,,,,RUB,,300"))
+		require.True(t, strings.HasSuffix(lines[2], ",RUB,-50,250"))
+		require.True(t, strings.HasSuffix(lines[3], ",RUB,30,280"))
+		require.True(t, strings.HasSuffix(lines[4], ",RUB,20,300"))
+		require.True(t, strings.HasSuffix(lines[5], ",Closing balance,,,,RUB,,300"))
+
+		// without recorded balances the current balance is used
+		file = export(legacy.Id, "csv")
+
+		lines = strings.Split(strings.TrimSpace(string(file.Data)), "\n")
+		require.Len(t, lines, 4)
+		require.True(t, strings.HasSuffix(lines[1], ",Opening balance,,,,RUB,,560"))
+		require.True(t, strings.HasSuffix(lines[2], ",RUB,40,600"))
+		require.True(t, strings.HasSuffix(lines[3], ",Closing balance,,,,RUB,,600"))
+	})
+
+	t.Run("camt.053", func(t *testing.T) {
+		file := export(merchant.Id, "camt.053")
+		require.Equal(t, "application/xml", file.ContentType)
+
+		camt := string(file.Data)
+		require.Contains(t, camt, "camt.053.001.02")
+		require.Contains(t, camt, "<Ownr>\n          <Nm>Shop Owner</Nm>")
+		require.Contains(t, camt, "<Cd>OPBD</Cd>")
+		require.Contains(t, camt, `<Amt Ccy="RUB">0.50</Amt>`)
+		require.Contains(t, camt, `<Amt Ccy="RUB">1.00</Amt>`)
+		require.Contains(t, camt, `<Amt Ccy="RUB">0.70</Amt>`)
+		require.Contains(t, camt, "<CdtDbtInd>DBIT</CdtDbtInd>")
+		require.Equal(t, 3, strings.Count(camt, "<Ntry>"))
+		require.Regexp(t, "<MsgId>[0-9a-f]{32}</MsgId>", camt)
+	})
+
+	t.Run("OFX", func(t *testing.T) {
+		file := export(merchant.Id, "ofx")
+		require.Equal(t, "application/x-ofx", file.ContentType)
+
+		ofx := string(file.Data)
+		require.Contains(t, ofx, `OFXHEADER="200"`)
+		require.Contains(t, ofx, "<TRNAMT>-0.20</TRNAMT>")
+		require.Contains(t, ofx, "<FITID>"+refund.PaymentId+"</FITID>")
+		require.Contains(t, ofx, "<BALAMT>2.00</BALAMT>")
+	})
+
+	t.Run("Unknown format", func(t *testing.T) {
+		_, err := servicePay.ExportStatement(context.Background(), &paymentpb.ExportRequest{
+			AccountId: merchant.Id,
+			Format:    "pdf",
+		})
+		require.Error(t, err)
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
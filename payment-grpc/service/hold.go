@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/Edbeer/payment-grpc/types"
+	authpb "github.com/Edbeer/payment-proto/auth-grpc/proto"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// time for the merchant to capture the authorization,
+// after it auth service releases the held money
+var holdTTL = 7 * 24 * time.Hour
+
+// hold the amount of the saved authorization on the customer account
+// and commit it, the hold is released if the authorization is not committed
+func (s *PaymentService) commitHold(ctx context.Context, tx *sql.Tx, payment *types.Payment, sts []*authpb.StatementRequest) error {
+	// scheduled payment is not authorized again after a takeover
+	if err := s.recordScheduledResult(ctx, tx, types.ScheduledProcessing, payment); err != nil {
+		return err
+	}
+	hold, err := s.client.CreateHold(ctx, &authpb.CreateHoldRequest{
+		AccountId:  payment.Customer.String(),
+		Amount:     payment.Amount,
+		Ttl:        durationpb.New(holdTTL),
+		Reference:  payment.PaymentId.String(),
+		Statements: sts,
+	})
+	if err != nil {
+		return err
+	}
+	holdID, err := uuid.Parse(hold.Id)
+	if err == nil {
+		err = s.storage.UpdatePaymentHold(ctx, payment.PaymentId, holdID, tx)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		s.client.ReleaseHold(ctx, &authpb.ReleaseHoldRequest{HoldId: hold.Id})
+		return err
+	}
+	return nil
+}
+
+// change the hold of the authorization by amount and commit,
+// the change is undone if the authorization is not committed
+func (s *PaymentService) commitHoldChange(ctx context.Context, tx *sql.Tx, refPayment *types.Payment, amount int64, sts []*authpb.StatementRequest) error {
+	holdID, err := paymentHold(refPayment)
+	if err != nil {
+		return err
+	}
+	if _, err := s.client.ChangeHold(ctx, &authpb.ChangeHoldRequest{
+		HoldId:     holdID,
+		Amount:     amount,
+		Statements: sts,
+	}); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		s.client.ChangeHold(ctx, &authpb.ChangeHoldRequest{HoldId: holdID, Amount: -amount})
+		return err
+	}
+	return nil
+}
+
+// pay the movements of the receivers from the hold of the authorization
+// and commit the saved capture, the rest of the hold returns to the customer,
+// captured money goes back to the customer if the capture is not committed
+func (s *PaymentService) commitCapture(ctx context.Context, tx *sql.Tx, refPayment, capture *types.Payment, movements []*movement, sts []*authpb.StatementRequest) error {
+	holdID, err := paymentHold(refPayment)
+	if err != nil {
+		return err
+	}
+	// scheduled payment is executed once the capture is committed
+	if err := s.recordScheduledResult(ctx, tx, types.ScheduledExecuted, capture); err != nil {
+		return err
+	}
+	req := &authpb.CaptureHoldRequest{HoldId: holdID, Statements: sts}
+	var captured int64
+	for _, m := range movements {
+		if m.balance+m.escrow == 0 {
+			continue
+		}
+		req.Transfers = append(req.Transfers, &authpb.HoldTransfer{
+			AccountId: m.account.Id,
+			Amount:    uint64(m.balance + m.escrow),
+			Escrow:    m.escrow > 0,
+		})
+		captured += m.balance + m.escrow
+	}
+	if _, err := s.client.CaptureHold(ctx, req); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		customer := &authpb.Account{Id: refPayment.Customer.String()}
+		revertBalances(ctx, s.client, append(movements, &movement{account: customer, balance: -captured}))
+		return err
+	}
+	return nil
+}
+
+// release the hold of the authorization with the movements and commit,
+// the movements are reverted if the hold is not released
+func (s *PaymentService) commitRelease(ctx context.Context, tx *sql.Tx, refPayment *types.Payment, movements []*movement, sts []*authpb.StatementRequest) error {
+	holdID, err := paymentHold(refPayment)
+	if err != nil {
+		return err
+	}
+	if err := applyMovements(ctx, s.client, movements, nil); err != nil {
+		return err
+	}
+	if _, err := s.client.ReleaseHold(ctx, &authpb.ReleaseHoldRequest{
+		HoldId:     holdID,
+		Statements: sts,
+	}); err != nil {
+		revertBalances(ctx, s.client, movements)
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		// released money can't be held again under the same hold,
+		// capture of the authorization fails on the released hold
+		log.Printf("release of hold %s is not committed: %v", holdID, err)
+		revertBalances(ctx, s.client, movements)
+		return err
+	}
+	return nil
+}
+
+// hold of the authorization, authorizations approved
+// before holds have no hold to capture or release
+func paymentHold(payment *types.Payment) (string, error) {
+	if !payment.HoldId.Valid {
+		return "", status.Error(codes.FailedPrecondition, "authorization has no hold")
+	}
+	return payment.HoldId.UUID.String(), nil
+}
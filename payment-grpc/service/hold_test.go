@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	paymentpb "github.com/Edbeer/payment-proto/payment-grpc/proto"
+	mockpay "github.com/Edbeer/payment-grpc/service/mock"
+
+	"github.com/Edbeer/payment-grpc/types"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func Test_PaymentHold(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	customer := newSplitAccount(100, 0)
+	merchant := newSplitAccount(0, 0)
+
+	t.Run("Released if not committed", func(t *testing.T) {
+		clientAuth, updates := mockSplitAccounts(ctrl, customer, merchant)
+		storagePay := mockpay.NewMockStorage(ctrl)
+		servicePay := NewPaymentService(storagePay, clientAuth, db, nil)
+
+		storagePay.EXPECT().SavePayment(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, payment *types.Payment, tx *sql.Tx) (*types.Payment, error) {
+				return payment, nil
+			},
+		)
+		storagePay.EXPECT().UpdatePaymentHold(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+		mock.ExpectBegin()
+		mock.ExpectCommit().WillReturnError(fmt.Errorf("commit failed"))
+
+		_, err := servicePay.CreatePayment(context.Background(), &paymentpb.CreateRequest{
+			Merchant:         merchant.Id,
+			Customer:         customer.Id,
+			CardNumber:       customer.CardNumber,
+			CardExpiryMonth:  customer.CardExpiryMonth,
+			CardExpiryYear:   customer.CardExpiryYear,
+			CardSecurityCode: customer.CardSecurityCode,
+			Currency:         "rub",
+			Amount:           60,
+		})
+		require.Error(t, err)
+
+		// held money is back on the balance
+		require.Equal(t, uint64(100), updates[customer.Id].Balance)
+		require.Equal(t, uint64(0), updates[customer.Id].BlockedMoney)
+	})
+
+	t.Run("Authorization without hold", func(t *testing.T) {
+		clientAuth, updates := mockSplitAccounts(ctrl, customer, merchant)
+		storagePay := mockpay.NewMockStorage(ctrl)
+		servicePay := NewPaymentService(storagePay, clientAuth, db, nil)
+
+		// approved before holds, its money is blocked outside of holds
+		refPayment := &types.Payment{
+			PaymentId: uuid.New(),
+			Merchant:  uuid.MustParse(merchant.Id),
+			Customer:  uuid.MustParse(customer.Id),
+			Currency:  "rub",
+			Operation: "Authorization",
+			Status:    "Approved",
+			Amount:    50,
+			CreatedAt: time.Now(),
+		}
+		req := &paymentpb.PaidRequest{
+			PaymentId: refPayment.PaymentId.String(),
+			Amount:    50,
+		}
+
+		mock.ExpectBegin()
+		storagePay.EXPECT().GetPaymentByID(context.Background(), req).Return(refPayment, nil)
+		storagePay.EXPECT().GetChildPayments(context.Background(), refPayment.PaymentId).Return([]*types.Payment{}, nil)
+		storagePay.EXPECT().SavePayment(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, payment *types.Payment, tx *sql.Tx) (*types.Payment, error) {
+				return payment, nil
+			},
+		)
+		mock.ExpectRollback()
+
+		_, err := servicePay.CapturePayment(context.Background(), req)
+		require.Equal(t, codes.FailedPrecondition, status.Code(err))
+		require.Empty(t, updates)
+	})
+}
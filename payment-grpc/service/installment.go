@@ -0,0 +1,282 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Edbeer/payment-grpc/types"
+	authpb "github.com/Edbeer/payment-proto/auth-grpc/proto"
+	paymentpb "github.com/Edbeer/payment-proto/payment-grpc/proto"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// limits of the number of installments
+const (
+	minInstallments = 2
+	maxInstallments = 48
+)
+
+// days between installments when not set
+const defaultInstallmentInterval = 30
+
+// failed charges before the installment is defaulted
+const maxInstallmentAttempts = 3
+
+// delay before a failed charge is retried
+var installmentRetryDelay = 24 * time.Hour
+
+// convert approved authorization into an installment plan:
+// the merchant is paid in full, the customer is debited on every due date
+func (s *PaymentService) CreateInstallmentPlan(ctx context.Context, req *paymentpb.InstallmentRequest) (*paymentpb.InstallmentPlan, error) {
+	paymentID, err := uuid.Parse(req.PaymentId)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid payment id")
+	}
+	if req.Count < minInstallments || req.Count > maxInstallments {
+		return nil, status.Errorf(codes.InvalidArgument, "number of installments must be from %d to %d", minInstallments, maxInstallments)
+	}
+	intervalDays := req.IntervalDays
+	if intervalDays == 0 {
+		intervalDays = defaultInstallmentInterval
+	}
+	// Begin Tx
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	// Get authorization, concurrent changes wait for the end of tx
+	refPayment, err := s.storage.GetPaymentForUpdate(ctx, paymentID, tx)
+	if err != nil {
+		return nil, err
+	}
+	if refPayment.Operation != "Authorization" || refPayment.Status != "Approved" ||
+		refPayment.ParentId.Valid || refPayment.Escrow {
+		return nil, status.Error(codes.FailedPrecondition, "Invalid transaction")
+	}
+	children, err := s.storage.GetChildPayments(ctx, refPayment.PaymentId)
+	if err != nil {
+		return nil, err
+	}
+	if len(children) > 0 {
+		return nil, status.Error(codes.FailedPrecondition, "Invalid transaction")
+	}
+	// Get customer and merchant
+	accounts, err := getAccounts(ctx, s.client, refPayment.Customer.String(), refPayment.Merchant.String())
+	if err != nil {
+		return nil, err
+	}
+	customer, merchant := accounts[0], accounts[1]
+	// hold of the customer is released, the merchant is paid in full
+	amount := int64(refPayment.Amount)
+	movements := []*movement{{account: merchant, balance: amount}}
+	completedPayment := types.CreateCompletePayment(&paymentpb.PaidRequest{
+		PaymentId: req.PaymentId,
+		Amount:    refPayment.Amount,
+	}, refPayment, "Successful payment")
+	completedPayment.Operation = "Installment plan"
+	completedPayment.ReferenceId = uuid.NullUUID{UUID: refPayment.PaymentId, Valid: true}
+	savedPayment, err := s.storage.SavePayment(ctx, completedPayment, tx)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := s.storage.SaveInstallmentPlan(ctx, types.CreateInstallmentPlan(savedPayment, refPayment.PaymentId, req.Fee), tx)
+	if err != nil {
+		return nil, err
+	}
+	installments := []*types.Installment{}
+	for _, installment := range types.CreateInstallments(plan, req.Count, time.Duration(intervalDays)*24*time.Hour) {
+		saved, err := s.storage.SaveInstallment(ctx, installment, tx)
+		if err != nil {
+			return nil, err
+		}
+		installments = append(installments, saved)
+	}
+	sts := []*authpb.StatementRequest{
+		newStatement(customer.Id, savedPayment, amount),
+		newStatement(merchant.Id, savedPayment, amount),
+	}
+	if err := s.commitRelease(ctx, tx, refPayment, movements, sts); err != nil {
+		return nil, err
+	}
+	return planToProto(plan, installments), nil
+}
+
+func (s *PaymentService) GetInstallmentPlan(ctx context.Context, req *paymentpb.InstallmentPlanRequest) (*paymentpb.InstallmentPlan, error) {
+	planID, err := uuid.Parse(req.PlanId)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid plan id")
+	}
+	plan, err := s.storage.GetInstallmentPlan(ctx, planID)
+	if err != nil {
+		return nil, err
+	}
+	installments, err := s.storage.GetInstallments(ctx, planID)
+	if err != nil {
+		return nil, err
+	}
+	return planToProto(plan, installments), nil
+}
+
+func (s *PaymentService) GetDelinquentInstallments(ctx context.Context, req *paymentpb.DelinquentRequest) (*paymentpb.InstallmentList, error) {
+	if req.Merchant != "" {
+		if _, err := uuid.Parse(req.Merchant); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid merchant id")
+		}
+	}
+	if req.Customer != "" {
+		if _, err := uuid.Parse(req.Customer); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid customer id")
+		}
+	}
+	installments, err := s.storage.GetDelinquentInstallments(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	list := &paymentpb.InstallmentList{}
+	for _, installment := range installments {
+		list.Installments = append(list.Installments, installmentToProto(installment))
+	}
+	return list, nil
+}
+
+// debit the customer for the due installment,
+// failed charge is retried until the installment is defaulted
+func (s *PaymentService) ChargeInstallment(ctx context.Context, installmentID uuid.UUID) error {
+	// Begin Tx
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	// Get installment, other replicas wait for the end of tx
+	installment, err := s.storage.GetInstallmentForUpdate(ctx, installmentID, tx)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if installment.State != types.InstallmentScheduled && installment.State != types.InstallmentDelinquent ||
+		installment.NextAttemptAt.After(now) {
+		// already charged by another replica
+		return nil
+	}
+	plan, err := s.storage.GetInstallmentPlan(ctx, installment.PlanId)
+	if err != nil {
+		return err
+	}
+	// Get customer and merchant
+	accounts, err := getAccounts(ctx, s.client, plan.Customer.String(), plan.Merchant.String())
+	if err != nil {
+		return err
+	}
+	customer, merchant := accounts[0], accounts[1]
+	// balance < installment amount
+	if customer.Balance < installment.Amount {
+		installment.Attempts++
+		installment.State = types.InstallmentDelinquent
+		installment.NextAttemptAt = now.Add(installmentRetryDelay)
+		if installment.Attempts >= maxInstallmentAttempts {
+			installment.State = types.InstallmentDefaulted
+		}
+		if err := s.storage.UpdateInstallment(ctx, installment, tx); err != nil {
+			return err
+		}
+		if err := s.storage.RefreshInstallmentPlan(ctx, plan.PlanId, tx); err != nil {
+			return err
+		}
+		savedPayment, err := s.storage.SavePayment(ctx, types.CreateInstallmentPayment(plan, installment, "Insufficient funds"), tx)
+		if err != nil {
+			return err
+		}
+		sts := []*authpb.StatementRequest{newStatement(customer.Id, savedPayment, 0)}
+		return s.commitPayment(ctx, tx, nil, sts)
+	}
+	// the fee goes to the merchant, the rest was paid to the merchant in advance
+	movements := []*movement{
+		{account: customer, balance: -int64(installment.Amount)},
+	}
+	if installment.Fee > 0 {
+		movements = append(movements, &movement{account: merchant, balance: int64(installment.Fee)})
+	}
+	installment.State = types.InstallmentPaid
+	if err := s.storage.UpdateInstallment(ctx, installment, tx); err != nil {
+		return err
+	}
+	if err := s.storage.RefreshInstallmentPlan(ctx, plan.PlanId, tx); err != nil {
+		return err
+	}
+	savedPayment, err := s.storage.SavePayment(ctx, types.CreateInstallmentPayment(plan, installment, "Successful payment"), tx)
+	if err != nil {
+		return err
+	}
+	sts := []*authpb.StatementRequest{
+		newStatement(customer.Id, savedPayment, statementAmount(movements, customer.Id)),
+		newStatement(merchant.Id, savedPayment, statementAmount(movements, merchant.Id)),
+	}
+	return s.commitPayment(ctx, tx, movements, sts)
+}
+
+// charge installments whose due date or retry time has passed
+func (s *PaymentService) ChargeDueInstallments(ctx context.Context) error {
+	installments, err := s.storage.GetDueInstallments(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	for _, installment := range installments {
+		if err := s.ChargeInstallment(ctx, installment.InstallmentId); err != nil {
+			log.Printf("charge installment %s: %v", installment.InstallmentId, err)
+		}
+	}
+	return nil
+}
+
+// charge due installments every interval until ctx is done
+func (s *PaymentService) RunInstallmentCharges(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.ChargeDueInstallments(ctx); err != nil {
+				log.Printf("charge due installments: %v", err)
+			}
+		}
+	}
+}
+
+func planToProto(plan *types.InstallmentPlan, installments []*types.Installment) *paymentpb.InstallmentPlan {
+	pbPlan := &paymentpb.InstallmentPlan{
+		PlanId:    plan.PlanId.String(),
+		PaymentId: plan.PaymentId.String(),
+		Merchant:  plan.Merchant.String(),
+		Customer:  plan.Customer.String(),
+		Currency:  plan.Currency,
+		Amount:    plan.Amount,
+		Fee:       plan.Fee,
+		State:     plan.State,
+		CreatedAt: timestamppb.New(plan.CreatedAt),
+	}
+	for _, installment := range installments {
+		pbPlan.Installments = append(pbPlan.Installments, installmentToProto(installment))
+	}
+	return pbPlan
+}
+
+func installmentToProto(installment *types.Installment) *paymentpb.Installment {
+	return &paymentpb.Installment{
+		InstallmentId: installment.InstallmentId.String(),
+		PlanId:        installment.PlanId.String(),
+		Seq:           installment.Seq,
+		Amount:        installment.Amount,
+		Fee:           installment.Fee,
+		DueAt:         timestamppb.New(installment.DueAt),
+		State:         installment.State,
+		Attempts:      installment.Attempts,
+	}
+}
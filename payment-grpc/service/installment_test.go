@@ -0,0 +1,250 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	paymentpb "github.com/Edbeer/payment-proto/payment-grpc/proto"
+	mockpay "github.com/Edbeer/payment-grpc/service/mock"
+
+	"github.com/Edbeer/payment-grpc/types"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func Test_CreateInstallmentPlan(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	customer := newSplitAccount(0, 100)
+	merchant := newSplitAccount(0, 0)
+	newAuthorization := func() *types.Payment {
+		return heldPayment(&types.Payment{
+			PaymentId: uuid.New(),
+			Merchant:  uuid.MustParse(merchant.Id),
+			Customer:  uuid.MustParse(customer.Id),
+			Currency:  "rub",
+			Operation: "Authorization",
+			Status:    "Approved",
+			Amount:    100,
+			CreatedAt: time.Now(),
+		})
+	}
+
+	t.Run("Successful plan", func(t *testing.T) {
+		clientAuth, updates := mockSplitAccounts(ctrl, customer, merchant)
+		storagePay := mockpay.NewMockStorage(ctrl)
+		servicePay := NewPaymentService(storagePay, clientAuth, db, nil)
+
+		auth := newAuthorization()
+		mock.ExpectBegin()
+		storagePay.EXPECT().GetPaymentForUpdate(context.Background(), auth.PaymentId, gomock.Any()).Return(auth, nil)
+		storagePay.EXPECT().GetChildPayments(context.Background(), auth.PaymentId).Return([]*types.Payment{}, nil)
+		var saved *types.Payment
+		storagePay.EXPECT().SavePayment(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, payment *types.Payment, tx *sql.Tx) (*types.Payment, error) {
+				saved = payment
+				return payment, nil
+			},
+		)
+		storagePay.EXPECT().SaveInstallmentPlan(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, plan *types.InstallmentPlan, tx *sql.Tx) (*types.InstallmentPlan, error) {
+				return plan, nil
+			},
+		)
+		storagePay.EXPECT().SaveInstallment(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, installment *types.Installment, tx *sql.Tx) (*types.Installment, error) {
+				return installment, nil
+			},
+		).Times(3)
+		mock.ExpectCommit()
+
+		plan, err := servicePay.CreateInstallmentPlan(context.Background(), &paymentpb.InstallmentRequest{
+			PaymentId: auth.PaymentId.String(),
+			Count:     3,
+			Fee:       5,
+		})
+		require.NoError(t, err)
+		require.Equal(t, types.PlanActive, plan.State)
+		require.Equal(t, saved.PaymentId.String(), plan.PaymentId)
+
+		// the merchant is paid in full, hold of the customer is released
+		require.Equal(t, uint64(100), updates[merchant.Id].Balance)
+		require.Equal(t, uint64(100), updates[customer.Id].Balance)
+		require.Equal(t, uint64(0), updates[customer.Id].BlockedMoney)
+		require.Equal(t, "Installment plan", saved.Operation)
+		require.Equal(t, auth.PaymentId, saved.ReferenceId.UUID)
+
+		// the remainder is paid with the first installments
+		require.Len(t, plan.Installments, 3)
+		amounts := []uint64{}
+		for _, installment := range plan.Installments {
+			amounts = append(amounts, installment.Amount)
+			require.Equal(t, types.InstallmentScheduled, installment.State)
+		}
+		require.Equal(t, []uint64{36, 35, 34}, amounts)
+		require.Equal(t, 30*24*time.Hour, plan.Installments[0].DueAt.AsTime().Sub(plan.CreatedAt.AsTime()))
+	})
+
+	t.Run("Captured authorization", func(t *testing.T) {
+		clientAuth, _ := mockSplitAccounts(ctrl, customer, merchant)
+		storagePay := mockpay.NewMockStorage(ctrl)
+		servicePay := NewPaymentService(storagePay, clientAuth, db, nil)
+
+		auth := newAuthorization()
+		mock.ExpectBegin()
+		storagePay.EXPECT().GetPaymentForUpdate(context.Background(), auth.PaymentId, gomock.Any()).Return(auth, nil)
+		storagePay.EXPECT().GetChildPayments(context.Background(), auth.PaymentId).Return([]*types.Payment{{}}, nil)
+		mock.ExpectRollback()
+
+		_, err := servicePay.CreateInstallmentPlan(context.Background(), &paymentpb.InstallmentRequest{
+			PaymentId: auth.PaymentId.String(),
+			Count:     3,
+		})
+		require.Error(t, err)
+		require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+
+	t.Run("Invalid count", func(t *testing.T) {
+		storagePay := mockpay.NewMockStorage(ctrl)
+		servicePay := NewPaymentService(storagePay, nil, db, nil)
+
+		_, err := servicePay.CreateInstallmentPlan(context.Background(), &paymentpb.InstallmentRequest{
+			PaymentId: uuid.New().String(),
+			Count:     1,
+		})
+		require.Error(t, err)
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func Test_ChargeInstallment(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	merchant := newSplitAccount(100, 0)
+	newPlan := func(customer *types.Payment) (*types.InstallmentPlan, *types.Installment) {
+		plan := types.CreateInstallmentPlan(customer, uuid.New(), 6)
+		installment := types.CreateInstallments(plan, 3, -time.Hour)[0]
+		return plan, installment
+	}
+	newPayment := func(customerID string) *types.Payment {
+		return &types.Payment{
+			PaymentId: uuid.New(),
+			Merchant:  uuid.MustParse(merchant.Id),
+			Customer:  uuid.MustParse(customerID),
+			Currency:  "rub",
+			Operation: "Installment plan",
+			Status:    "Successful payment",
+			Amount:    90,
+			CreatedAt: time.Now(),
+		}
+	}
+
+	t.Run("Successful charge", func(t *testing.T) {
+		customer := newSplitAccount(50, 0)
+		clientAuth, updates := mockSplitAccounts(ctrl, customer, merchant)
+		storagePay := mockpay.NewMockStorage(ctrl)
+		servicePay := NewPaymentService(storagePay, clientAuth, db, nil)
+
+		plan, installment := newPlan(newPayment(customer.Id))
+		mock.ExpectBegin()
+		storagePay.EXPECT().GetInstallmentForUpdate(context.Background(), installment.InstallmentId, gomock.Any()).Return(installment, nil)
+		storagePay.EXPECT().GetInstallmentPlan(context.Background(), plan.PlanId).Return(plan, nil)
+		storagePay.EXPECT().UpdateInstallment(context.Background(), installment, gomock.Any()).Return(nil)
+		storagePay.EXPECT().RefreshInstallmentPlan(context.Background(), plan.PlanId, gomock.Any()).Return(nil)
+		var saved *types.Payment
+		storagePay.EXPECT().SavePayment(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, payment *types.Payment, tx *sql.Tx) (*types.Payment, error) {
+				saved = payment
+				return payment, nil
+			},
+		)
+		mock.ExpectCommit()
+
+		err := servicePay.ChargeInstallment(context.Background(), installment.InstallmentId)
+		require.NoError(t, err)
+		require.Equal(t, types.InstallmentPaid, installment.State)
+
+		// customer pays the share with the fee, the merchant gets the fee
+		require.Equal(t, uint64(18), updates[customer.Id].Balance)
+		require.Equal(t, uint64(102), updates[merchant.Id].Balance)
+		require.Equal(t, "Installment", saved.Operation)
+		require.Equal(t, "Successful payment", saved.Status)
+		require.Equal(t, plan.PaymentId, saved.ReferenceId.UUID)
+	})
+
+	for _, tc := range []struct {
+		name     string
+		attempts uint32
+		state    string
+	}{
+		{name: "Delinquent", state: types.InstallmentDelinquent},
+		{name: "Defaulted", attempts: maxInstallmentAttempts - 1, state: types.InstallmentDefaulted},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			customer := newSplitAccount(10, 0)
+			clientAuth, updates := mockSplitAccounts(ctrl, customer, merchant)
+			storagePay := mockpay.NewMockStorage(ctrl)
+			servicePay := NewPaymentService(storagePay, clientAuth, db, nil)
+
+			plan, installment := newPlan(newPayment(customer.Id))
+			installment.Attempts = tc.attempts
+			mock.ExpectBegin()
+			storagePay.EXPECT().GetInstallmentForUpdate(context.Background(), installment.InstallmentId, gomock.Any()).Return(installment, nil)
+			storagePay.EXPECT().GetInstallmentPlan(context.Background(), plan.PlanId).Return(plan, nil)
+			storagePay.EXPECT().UpdateInstallment(context.Background(), installment, gomock.Any()).Return(nil)
+			storagePay.EXPECT().RefreshInstallmentPlan(context.Background(), plan.PlanId, gomock.Any()).Return(nil)
+			var saved *types.Payment
+			storagePay.EXPECT().SavePayment(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, payment *types.Payment, tx *sql.Tx) (*types.Payment, error) {
+					saved = payment
+					return payment, nil
+				},
+			)
+			mock.ExpectCommit()
+
+			err := servicePay.ChargeInstallment(context.Background(), installment.InstallmentId)
+			require.NoError(t, err)
+			require.Equal(t, tc.state, installment.State)
+			require.Equal(t, tc.attempts+1, installment.Attempts)
+			require.True(t, installment.NextAttemptAt.After(time.Now()))
+			require.Equal(t, "Insufficient funds", saved.Status)
+			require.Empty(t, updates)
+		})
+	}
+
+	t.Run("Already paid", func(t *testing.T) {
+		customer := newSplitAccount(50, 0)
+		clientAuth, _ := mockSplitAccounts(ctrl, customer, merchant)
+		storagePay := mockpay.NewMockStorage(ctrl)
+		servicePay := NewPaymentService(storagePay, clientAuth, db, nil)
+
+		_, installment := newPlan(newPayment(customer.Id))
+		installment.State = types.InstallmentPaid
+		mock.ExpectBegin()
+		storagePay.EXPECT().GetInstallmentForUpdate(context.Background(), installment.InstallmentId, gomock.Any()).Return(installment, nil)
+		mock.ExpectRollback()
+
+		err := servicePay.ChargeInstallment(context.Background(), installment.InstallmentId)
+		require.NoError(t, err)
+	})
+}
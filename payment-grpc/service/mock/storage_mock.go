@@ -8,10 +8,12 @@ import (
 	context "context"
 	sql "database/sql"
 	reflect "reflect"
+	time "time"
 
 	types "github.com/Edbeer/payment-grpc/types"
 	paymentpb "github.com/Edbeer/payment-proto/payment-grpc/proto"
 	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
 )
 
 // MockStorage is a mock of Storage interface.
@@ -37,6 +39,186 @@ func (m *MockStorage) EXPECT() *MockStorageMockRecorder {
 	return m.recorder
 }
 
+// FinishScheduledPayment mocks base method.
+func (m *MockStorage) FinishScheduledPayment(ctx context.Context, scheduled *types.ScheduledPayment, tx *sql.Tx) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FinishScheduledPayment", ctx, scheduled, tx)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FinishScheduledPayment indicates an expected call of FinishScheduledPayment.
+func (mr *MockStorageMockRecorder) FinishScheduledPayment(ctx, scheduled, tx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FinishScheduledPayment", reflect.TypeOf((*MockStorage)(nil).FinishScheduledPayment), ctx, scheduled, tx)
+}
+
+// GetChallenge mocks base method.
+func (m *MockStorage) GetChallenge(ctx context.Context, challengeID uuid.UUID, tx *sql.Tx) (*types.Challenge, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetChallenge", ctx, challengeID, tx)
+	ret0, _ := ret[0].(*types.Challenge)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetChallenge indicates an expected call of GetChallenge.
+func (mr *MockStorageMockRecorder) GetChallenge(ctx, challengeID, tx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetChallenge", reflect.TypeOf((*MockStorage)(nil).GetChallenge), ctx, challengeID, tx)
+}
+
+// GetChildPayments mocks base method.
+func (m *MockStorage) GetChildPayments(ctx context.Context, parentID uuid.UUID) ([]*types.Payment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetChildPayments", ctx, parentID)
+	ret0, _ := ret[0].([]*types.Payment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetChildPayments indicates an expected call of GetChildPayments.
+func (mr *MockStorageMockRecorder) GetChildPayments(ctx, parentID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetChildPayments", reflect.TypeOf((*MockStorage)(nil).GetChildPayments), ctx, parentID)
+}
+
+// GetDelinquentInstallments mocks base method.
+func (m *MockStorage) GetDelinquentInstallments(ctx context.Context, req *paymentpb.DelinquentRequest) ([]*types.Installment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDelinquentInstallments", ctx, req)
+	ret0, _ := ret[0].([]*types.Installment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDelinquentInstallments indicates an expected call of GetDelinquentInstallments.
+func (mr *MockStorageMockRecorder) GetDelinquentInstallments(ctx, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDelinquentInstallments", reflect.TypeOf((*MockStorage)(nil).GetDelinquentInstallments), ctx, req)
+}
+
+// GetDueEscrows mocks base method.
+func (m *MockStorage) GetDueEscrows(ctx context.Context, now time.Time) ([]*types.Escrow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDueEscrows", ctx, now)
+	ret0, _ := ret[0].([]*types.Escrow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDueEscrows indicates an expected call of GetDueEscrows.
+func (mr *MockStorageMockRecorder) GetDueEscrows(ctx, now interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDueEscrows", reflect.TypeOf((*MockStorage)(nil).GetDueEscrows), ctx, now)
+}
+
+// GetDueInstallments mocks base method.
+func (m *MockStorage) GetDueInstallments(ctx context.Context, now time.Time) ([]*types.Installment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDueInstallments", ctx, now)
+	ret0, _ := ret[0].([]*types.Installment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDueInstallments indicates an expected call of GetDueInstallments.
+func (mr *MockStorageMockRecorder) GetDueInstallments(ctx, now interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDueInstallments", reflect.TypeOf((*MockStorage)(nil).GetDueInstallments), ctx, now)
+}
+
+// GetDueScheduledPayments mocks base method.
+func (m *MockStorage) GetDueScheduledPayments(ctx context.Context, now, claimedBefore time.Time) ([]*types.ScheduledPayment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDueScheduledPayments", ctx, now, claimedBefore)
+	ret0, _ := ret[0].([]*types.ScheduledPayment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDueScheduledPayments indicates an expected call of GetDueScheduledPayments.
+func (mr *MockStorageMockRecorder) GetDueScheduledPayments(ctx, now, claimedBefore interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDueScheduledPayments", reflect.TypeOf((*MockStorage)(nil).GetDueScheduledPayments), ctx, now, claimedBefore)
+}
+
+// GetEscrow mocks base method.
+func (m *MockStorage) GetEscrow(ctx context.Context, paymentID uuid.UUID, tx *sql.Tx) (*types.Escrow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetEscrow", ctx, paymentID, tx)
+	ret0, _ := ret[0].(*types.Escrow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetEscrow indicates an expected call of GetEscrow.
+func (mr *MockStorageMockRecorder) GetEscrow(ctx, paymentID, tx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEscrow", reflect.TypeOf((*MockStorage)(nil).GetEscrow), ctx, paymentID, tx)
+}
+
+// GetExpiredChallenges mocks base method.
+func (m *MockStorage) GetExpiredChallenges(ctx context.Context, now time.Time) ([]*types.Challenge, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetExpiredChallenges", ctx, now)
+	ret0, _ := ret[0].([]*types.Challenge)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetExpiredChallenges indicates an expected call of GetExpiredChallenges.
+func (mr *MockStorageMockRecorder) GetExpiredChallenges(ctx, now interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetExpiredChallenges", reflect.TypeOf((*MockStorage)(nil).GetExpiredChallenges), ctx, now)
+}
+
+// GetInstallmentForUpdate mocks base method.
+func (m *MockStorage) GetInstallmentForUpdate(ctx context.Context, installmentID uuid.UUID, tx *sql.Tx) (*types.Installment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetInstallmentForUpdate", ctx, installmentID, tx)
+	ret0, _ := ret[0].(*types.Installment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetInstallmentForUpdate indicates an expected call of GetInstallmentForUpdate.
+func (mr *MockStorageMockRecorder) GetInstallmentForUpdate(ctx, installmentID, tx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInstallmentForUpdate", reflect.TypeOf((*MockStorage)(nil).GetInstallmentForUpdate), ctx, installmentID, tx)
+}
+
+// GetInstallmentPlan mocks base method.
+func (m *MockStorage) GetInstallmentPlan(ctx context.Context, planID uuid.UUID) (*types.InstallmentPlan, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetInstallmentPlan", ctx, planID)
+	ret0, _ := ret[0].(*types.InstallmentPlan)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetInstallmentPlan indicates an expected call of GetInstallmentPlan.
+func (mr *MockStorageMockRecorder) GetInstallmentPlan(ctx, planID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInstallmentPlan", reflect.TypeOf((*MockStorage)(nil).GetInstallmentPlan), ctx, planID)
+}
+
+// GetInstallments mocks base method.
+func (m *MockStorage) GetInstallments(ctx context.Context, planID uuid.UUID) ([]*types.Installment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetInstallments", ctx, planID)
+	ret0, _ := ret[0].([]*types.Installment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetInstallments indicates an expected call of GetInstallments.
+func (mr *MockStorageMockRecorder) GetInstallments(ctx, planID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInstallments", reflect.TypeOf((*MockStorage)(nil).GetInstallments), ctx, planID)
+}
+
 // GetPaymentByID mocks base method.
 func (m *MockStorage) GetPaymentByID(ctx context.Context, req *paymentpb.PaidRequest) (*types.Payment, error) {
 	m.ctrl.T.Helper()
@@ -52,6 +234,155 @@ func (mr *MockStorageMockRecorder) GetPaymentByID(ctx, req interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPaymentByID", reflect.TypeOf((*MockStorage)(nil).GetPaymentByID), ctx, req)
 }
 
+// GetPaymentEvents mocks base method.
+func (m *MockStorage) GetPaymentEvents(ctx context.Context, req *paymentpb.SubscribeRequest, limit int) ([]*types.PaymentEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPaymentEvents", ctx, req, limit)
+	ret0, _ := ret[0].([]*types.PaymentEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPaymentEvents indicates an expected call of GetPaymentEvents.
+func (mr *MockStorageMockRecorder) GetPaymentEvents(ctx, req, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPaymentEvents", reflect.TypeOf((*MockStorage)(nil).GetPaymentEvents), ctx, req, limit)
+}
+
+// GetPaymentForUpdate mocks base method.
+func (m *MockStorage) GetPaymentForUpdate(ctx context.Context, paymentID uuid.UUID, tx *sql.Tx) (*types.Payment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPaymentForUpdate", ctx, paymentID, tx)
+	ret0, _ := ret[0].(*types.Payment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPaymentForUpdate indicates an expected call of GetPaymentForUpdate.
+func (mr *MockStorageMockRecorder) GetPaymentForUpdate(ctx, paymentID, tx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPaymentForUpdate", reflect.TypeOf((*MockStorage)(nil).GetPaymentForUpdate), ctx, paymentID, tx)
+}
+
+// GetScheduledPayment mocks base method.
+func (m *MockStorage) GetScheduledPayment(ctx context.Context, paymentID uuid.UUID, tx *sql.Tx) (*types.ScheduledPayment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetScheduledPayment", ctx, paymentID, tx)
+	ret0, _ := ret[0].(*types.ScheduledPayment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetScheduledPayment indicates an expected call of GetScheduledPayment.
+func (mr *MockStorageMockRecorder) GetScheduledPayment(ctx, paymentID, tx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetScheduledPayment", reflect.TypeOf((*MockStorage)(nil).GetScheduledPayment), ctx, paymentID, tx)
+}
+
+// GetScheduledPayments mocks base method.
+func (m *MockStorage) GetScheduledPayments(ctx context.Context, req *paymentpb.ScheduledListRequest) ([]*types.ScheduledPayment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetScheduledPayments", ctx, req)
+	ret0, _ := ret[0].([]*types.ScheduledPayment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetScheduledPayments indicates an expected call of GetScheduledPayments.
+func (mr *MockStorageMockRecorder) GetScheduledPayments(ctx, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetScheduledPayments", reflect.TypeOf((*MockStorage)(nil).GetScheduledPayments), ctx, req)
+}
+
+// GetTransferByKey mocks base method.
+func (m *MockStorage) GetTransferByKey(ctx context.Context, sender uuid.UUID, key string) (*types.Transfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTransferByKey", ctx, sender, key)
+	ret0, _ := ret[0].(*types.Transfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTransferByKey indicates an expected call of GetTransferByKey.
+func (mr *MockStorageMockRecorder) GetTransferByKey(ctx, sender, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransferByKey", reflect.TypeOf((*MockStorage)(nil).GetTransferByKey), ctx, sender, key)
+}
+
+// RefreshInstallmentPlan mocks base method.
+func (m *MockStorage) RefreshInstallmentPlan(ctx context.Context, planID uuid.UUID, tx *sql.Tx) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RefreshInstallmentPlan", ctx, planID, tx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RefreshInstallmentPlan indicates an expected call of RefreshInstallmentPlan.
+func (mr *MockStorageMockRecorder) RefreshInstallmentPlan(ctx, planID, tx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefreshInstallmentPlan", reflect.TypeOf((*MockStorage)(nil).RefreshInstallmentPlan), ctx, planID, tx)
+}
+
+// SaveChallenge mocks base method.
+func (m *MockStorage) SaveChallenge(ctx context.Context, challenge *types.Challenge, tx *sql.Tx) (*types.Challenge, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveChallenge", ctx, challenge, tx)
+	ret0, _ := ret[0].(*types.Challenge)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveChallenge indicates an expected call of SaveChallenge.
+func (mr *MockStorageMockRecorder) SaveChallenge(ctx, challenge, tx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveChallenge", reflect.TypeOf((*MockStorage)(nil).SaveChallenge), ctx, challenge, tx)
+}
+
+// SaveEscrow mocks base method.
+func (m *MockStorage) SaveEscrow(ctx context.Context, escrow *types.Escrow, tx *sql.Tx) (*types.Escrow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveEscrow", ctx, escrow, tx)
+	ret0, _ := ret[0].(*types.Escrow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveEscrow indicates an expected call of SaveEscrow.
+func (mr *MockStorageMockRecorder) SaveEscrow(ctx, escrow, tx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveEscrow", reflect.TypeOf((*MockStorage)(nil).SaveEscrow), ctx, escrow, tx)
+}
+
+// SaveInstallment mocks base method.
+func (m *MockStorage) SaveInstallment(ctx context.Context, installment *types.Installment, tx *sql.Tx) (*types.Installment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveInstallment", ctx, installment, tx)
+	ret0, _ := ret[0].(*types.Installment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveInstallment indicates an expected call of SaveInstallment.
+func (mr *MockStorageMockRecorder) SaveInstallment(ctx, installment, tx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveInstallment", reflect.TypeOf((*MockStorage)(nil).SaveInstallment), ctx, installment, tx)
+}
+
+// SaveInstallmentPlan mocks base method.
+func (m *MockStorage) SaveInstallmentPlan(ctx context.Context, plan *types.InstallmentPlan, tx *sql.Tx) (*types.InstallmentPlan, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveInstallmentPlan", ctx, plan, tx)
+	ret0, _ := ret[0].(*types.InstallmentPlan)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveInstallmentPlan indicates an expected call of SaveInstallmentPlan.
+func (mr *MockStorageMockRecorder) SaveInstallmentPlan(ctx, plan, tx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveInstallmentPlan", reflect.TypeOf((*MockStorage)(nil).SaveInstallmentPlan), ctx, plan, tx)
+}
+
 // SavePayment mocks base method.
 func (m *MockStorage) SavePayment(ctx context.Context, payment *types.Payment, tx *sql.Tx) (*types.Payment, error) {
 	m.ctrl.T.Helper()
@@ -66,3 +397,154 @@ func (mr *MockStorageMockRecorder) SavePayment(ctx, payment, tx interface{}) *go
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SavePayment", reflect.TypeOf((*MockStorage)(nil).SavePayment), ctx, payment, tx)
 }
+
+// SaveScheduledPayment mocks base method.
+func (m *MockStorage) SaveScheduledPayment(ctx context.Context, scheduled *types.ScheduledPayment, tx *sql.Tx) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveScheduledPayment", ctx, scheduled, tx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveScheduledPayment indicates an expected call of SaveScheduledPayment.
+func (mr *MockStorageMockRecorder) SaveScheduledPayment(ctx, scheduled, tx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveScheduledPayment", reflect.TypeOf((*MockStorage)(nil).SaveScheduledPayment), ctx, scheduled, tx)
+}
+
+// SaveTransfer mocks base method.
+func (m *MockStorage) SaveTransfer(ctx context.Context, transfer *types.Transfer, tx *sql.Tx) (*types.Transfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveTransfer", ctx, transfer, tx)
+	ret0, _ := ret[0].(*types.Transfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveTransfer indicates an expected call of SaveTransfer.
+func (mr *MockStorageMockRecorder) SaveTransfer(ctx, transfer, tx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveTransfer", reflect.TypeOf((*MockStorage)(nil).SaveTransfer), ctx, transfer, tx)
+}
+
+// UpdateChallenge mocks base method.
+func (m *MockStorage) UpdateChallenge(ctx context.Context, challenge *types.Challenge, tx *sql.Tx) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateChallenge", ctx, challenge, tx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateChallenge indicates an expected call of UpdateChallenge.
+func (mr *MockStorageMockRecorder) UpdateChallenge(ctx, challenge, tx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateChallenge", reflect.TypeOf((*MockStorage)(nil).UpdateChallenge), ctx, challenge, tx)
+}
+
+// UpdateEscrow mocks base method.
+func (m *MockStorage) UpdateEscrow(ctx context.Context, escrow *types.Escrow, tx *sql.Tx) (*types.Escrow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateEscrow", ctx, escrow, tx)
+	ret0, _ := ret[0].(*types.Escrow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateEscrow indicates an expected call of UpdateEscrow.
+func (mr *MockStorageMockRecorder) UpdateEscrow(ctx, escrow, tx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateEscrow", reflect.TypeOf((*MockStorage)(nil).UpdateEscrow), ctx, escrow, tx)
+}
+
+// UpdateInstallment mocks base method.
+func (m *MockStorage) UpdateInstallment(ctx context.Context, installment *types.Installment, tx *sql.Tx) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateInstallment", ctx, installment, tx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateInstallment indicates an expected call of UpdateInstallment.
+func (mr *MockStorageMockRecorder) UpdateInstallment(ctx, installment, tx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateInstallment", reflect.TypeOf((*MockStorage)(nil).UpdateInstallment), ctx, installment, tx)
+}
+
+// UpdatePaymentAmount mocks base method.
+func (m *MockStorage) UpdatePaymentAmount(ctx context.Context, paymentID uuid.UUID, amount uint64, tx *sql.Tx) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePaymentAmount", ctx, paymentID, amount, tx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePaymentAmount indicates an expected call of UpdatePaymentAmount.
+func (mr *MockStorageMockRecorder) UpdatePaymentAmount(ctx, paymentID, amount, tx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePaymentAmount", reflect.TypeOf((*MockStorage)(nil).UpdatePaymentAmount), ctx, paymentID, amount, tx)
+}
+
+// UpdatePaymentHold mocks base method.
+func (m *MockStorage) UpdatePaymentHold(ctx context.Context, paymentID, holdID uuid.UUID, tx *sql.Tx) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePaymentHold", ctx, paymentID, holdID, tx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePaymentHold indicates an expected call of UpdatePaymentHold.
+func (mr *MockStorageMockRecorder) UpdatePaymentHold(ctx, paymentID, holdID, tx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePaymentHold", reflect.TypeOf((*MockStorage)(nil).UpdatePaymentHold), ctx, paymentID, holdID, tx)
+}
+
+// UpdateScheduledPayment mocks base method.
+func (m *MockStorage) UpdateScheduledPayment(ctx context.Context, scheduled *types.ScheduledPayment, tx *sql.Tx) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateScheduledPayment", ctx, scheduled, tx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateScheduledPayment indicates an expected call of UpdateScheduledPayment.
+func (mr *MockStorageMockRecorder) UpdateScheduledPayment(ctx, scheduled, tx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateScheduledPayment", reflect.TypeOf((*MockStorage)(nil).UpdateScheduledPayment), ctx, scheduled, tx)
+}
+
+// MockNotifier is a mock of Notifier interface.
+type MockNotifier struct {
+	ctrl     *gomock.Controller
+	recorder *MockNotifierMockRecorder
+}
+
+// MockNotifierMockRecorder is the mock recorder for MockNotifier.
+type MockNotifierMockRecorder struct {
+	mock *MockNotifier
+}
+
+// NewMockNotifier creates a new mock instance.
+func NewMockNotifier(ctrl *gomock.Controller) *MockNotifier {
+	mock := &MockNotifier{ctrl: ctrl}
+	mock.recorder = &MockNotifierMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockNotifier) EXPECT() *MockNotifierMockRecorder {
+	return m.recorder
+}
+
+// Notify mocks base method.
+func (m *MockNotifier) Notify(ctx context.Context, accountID, challengeID, code string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Notify", ctx, accountID, challengeID, code)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Notify indicates an expected call of Notify.
+func (mr *MockNotifierMockRecorder) Notify(ctx, accountID, challengeID, code interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Notify", reflect.TypeOf((*MockNotifier)(nil).Notify), ctx, accountID, challengeID, code)
+}
@@ -0,0 +1,345 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/Edbeer/payment-grpc/pkg/jwks"
+	"github.com/Edbeer/payment-grpc/types"
+	paymentpb "github.com/Edbeer/payment-proto/payment-grpc/proto"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Permission of the role
+type permission string
+
+const (
+	// payments of the caller
+	permPaymentCreate permission = "payment:create"
+	permPaymentManage permission = "payment:manage"
+	permPaymentPay    permission = "payment:pay"
+	permPaymentRead   permission = "payment:read"
+	// payments of others
+	permPaymentReadAny   permission = "payment:read:any"
+	permPaymentManageAny permission = "payment:manage:any"
+	permPaymentImport    permission = "payment:import"
+)
+
+var rolePermissions = map[string][]permission{
+	types.RoleCustomer: {permPaymentPay, permPaymentRead},
+	types.RoleMerchant: {permPaymentCreate, permPaymentManage, permPaymentPay, permPaymentRead},
+	types.RoleSupport:  {permPaymentRead, permPaymentReadAny},
+	types.RoleAdmin: {
+		permPaymentCreate, permPaymentManage, permPaymentPay, permPaymentRead,
+		permPaymentReadAny, permPaymentManageAny, permPaymentImport,
+	},
+}
+
+// scopes of the OAuth2 client tokens by the permission,
+// client tokens have no other permissions
+var permissionScopes = map[permission]string{
+	permPaymentCreate: "payments:write",
+	permPaymentManage: "payments:write",
+	permPaymentPay:    "payments:write",
+	permPaymentRead:   "payments:read",
+}
+
+// permissions denied to the accounts with an unverified email
+var verifiedPermissions = map[permission]bool{
+	permPaymentCreate: true,
+	permPaymentPay:    true,
+}
+
+// Has the role the permission
+func can(role string, perm permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// accounts of the resource of the request
+type ownersFunc func(ctx context.Context, s *PaymentService, req interface{}) ([]string, error)
+
+// Access rule of the method
+type rule struct {
+	// required permission of the caller
+	permission permission
+	// the caller must be one of the owners
+	// unless it has the any permission
+	owners ownersFunc
+	any    permission
+}
+
+var methodRules = map[string]rule{
+	"CreatePayment":          {permission: permPaymentCreate, owners: requestMerchant, any: permPaymentManageAny},
+	"CreateSplitPayment":     {permission: permPaymentCreate, owners: requestMerchant, any: permPaymentManageAny},
+	"CapturePayment":         {permission: permPaymentManage, owners: paymentMerchant, any: permPaymentManageAny},
+	"CancelPayment":          {permission: permPaymentManage, owners: paymentMerchant, any: permPaymentManageAny},
+	"RefundPayment":          {permission: permPaymentManage, owners: paymentMerchant, any: permPaymentManageAny},
+	"IncrementAuthorization": {permission: permPaymentManage, owners: paymentMerchant, any: permPaymentManageAny},
+	"DecrementAuthorization": {permission: permPaymentManage, owners: paymentMerchant, any: permPaymentManageAny},
+	"ReleaseEscrow":          {permission: permPaymentManage, owners: paymentMerchant, any: permPaymentManageAny},
+	"RefundEscrow":           {permission: permPaymentManage, owners: paymentMerchant, any: permPaymentManageAny},
+	"CreateInstallmentPlan":  {permission: permPaymentManage, owners: paymentMerchant, any: permPaymentManageAny},
+	"UpdateScheduledPayment": {permission: permPaymentManage, owners: paymentMerchant, any: permPaymentManageAny},
+	"CancelScheduledPayment": {permission: permPaymentManage, owners: paymentMerchant, any: permPaymentManageAny},
+	"Transfer":               {permission: permPaymentPay, owners: requestSender, any: permPaymentManageAny},
+	// customer of the challenge is checked by the handler
+	"CompleteChallenge":         {permission: permPaymentPay},
+	"ImportPayments":            {permission: permPaymentImport},
+	"GetInstallmentPlan":        {permission: permPaymentRead, owners: planParties, any: permPaymentReadAny},
+	"GetDelinquentInstallments": {permission: permPaymentRead, owners: requestParties, any: permPaymentReadAny},
+	"ListScheduledPayments":     {permission: permPaymentRead, owners: requestParties, any: permPaymentReadAny},
+	"SubscribePaymentEvents":    {permission: permPaymentRead, owners: requestParties, any: permPaymentReadAny},
+	"ExportStatement":           {permission: permPaymentRead, owners: requestAccount, any: permPaymentReadAny},
+}
+
+// Validator of the access tokens
+type TokenValidator interface {
+	Validate(ctx context.Context, token string) (*jwks.Claims, error)
+}
+
+// Caller of the request from its access token
+type Caller struct {
+	AccountID     string
+	Role          string
+	EmailVerified bool
+}
+
+type callerKey struct{}
+
+// Caller of the request, none for the calls of the service itself
+func CallerFromContext(ctx context.Context) (*Caller, bool) {
+	caller, ok := ctx.Value(callerKey{}).(*Caller)
+	return caller, ok
+}
+
+// Check the caller of the payment service methods against the policy
+func (s *PaymentService) UnaryInterceptor(tokens TokenValidator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		rule, ok, err := methodRule(info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return handler(ctx, req)
+		}
+		ctx, err = authorize(ctx, tokens, rule)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.checkOwners(ctx, rule, req); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func (s *PaymentService) StreamInterceptor(tokens TokenValidator) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		rule, ok, err := methodRule(info.FullMethod)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return handler(srv, stream)
+		}
+		ctx, err := authorize(stream.Context(), tokens, rule)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &policyStream{ServerStream: stream, ctx: ctx, rule: rule, service: s})
+	}
+}
+
+// rule of the payment service method, methods without a rule are denied,
+// methods of other services are not checked
+func methodRule(fullMethod string) (rule, bool, error) {
+	prefix := "/" + paymentpb.PaymentService_ServiceDesc.ServiceName + "/"
+	if !strings.HasPrefix(fullMethod, prefix) {
+		return rule{}, false, nil
+	}
+	rule, ok := methodRules[strings.TrimPrefix(fullMethod, prefix)]
+	if !ok {
+		return rule, false, status.Error(codes.PermissionDenied, "method is not allowed")
+	}
+	return rule, true, nil
+}
+
+// context with the caller of the access token if its role has the permission
+func authorize(ctx context.Context, tokens TokenValidator, rule rule) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 || values[0] == "" {
+		return nil, status.Error(codes.Unauthenticated, "access token is required")
+	}
+	claims, err := tokens.Validate(ctx, strings.TrimPrefix(values[0], "Bearer "))
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid access token")
+	}
+	caller := &Caller{AccountID: claims.AccountID, Role: claims.Role, EmailVerified: true}
+	// tokens issued before the roles
+	if caller.Role == "" {
+		caller.Role = types.RoleCustomer
+	}
+	if claims.EmailVerified != nil {
+		caller.EmailVerified = *claims.EmailVerified
+	}
+	if !can(caller.Role, rule.permission) {
+		return nil, status.Error(codes.PermissionDenied, "permission denied")
+	}
+	if !caller.EmailVerified && verifiedPermissions[rule.permission] {
+		return nil, status.Error(codes.PermissionDenied, "email is not verified")
+	}
+	// client of the merchant server within its scopes
+	if claims.ClientID != "" && !hasScope(claims.Scope, permissionScopes[rule.permission]) {
+		return nil, status.Error(codes.PermissionDenied, "insufficient scope")
+	}
+	return context.WithValue(ctx, callerKey{}, caller), nil
+}
+
+// space separated scopes of the token contain the scope
+func hasScope(scopes, scope string) bool {
+	if scope == "" {
+		return false
+	}
+	for _, s := range strings.Fields(scopes) {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *PaymentService) checkOwners(ctx context.Context, rule rule, req interface{}) error {
+	if rule.owners == nil {
+		return nil
+	}
+	caller, ok := CallerFromContext(ctx)
+	if !ok || can(caller.Role, rule.any) {
+		return nil
+	}
+	owners, err := rule.owners(ctx, s, req)
+	if err != nil {
+		return err
+	}
+	return checkCaller(ctx, rule.any, owners...)
+}
+
+// the caller is one of the accounts or has the any permission,
+// calls of the service itself have no caller
+func checkCaller(ctx context.Context, anyPerm permission, accounts ...string) error {
+	caller, ok := CallerFromContext(ctx)
+	if !ok || can(caller.Role, anyPerm) {
+		return nil
+	}
+	for _, account := range accounts {
+		if account != "" && account == caller.AccountID {
+			return nil
+		}
+	}
+	return status.Error(codes.PermissionDenied, "permission denied")
+}
+
+// merchant of the new payment
+func requestMerchant(ctx context.Context, s *PaymentService, req interface{}) ([]string, error) {
+	switch req := req.(type) {
+	case *paymentpb.CreateRequest:
+		return []string{req.Merchant}, nil
+	case *paymentpb.SplitRequest:
+		return []string{req.Platform}, nil
+	}
+	return nil, nil
+}
+
+// merchant of the referenced payment
+func paymentMerchant(ctx context.Context, s *PaymentService, req interface{}) ([]string, error) {
+	r, ok := req.(interface{ GetPaymentId() string })
+	if !ok {
+		return nil, nil
+	}
+	if _, err := uuid.Parse(r.GetPaymentId()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid payment id")
+	}
+	payment, err := s.storage.GetPaymentByID(ctx, &paymentpb.PaidRequest{PaymentId: r.GetPaymentId()})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, status.Error(codes.NotFound, "payment not found")
+		}
+		return nil, err
+	}
+	return []string{payment.Merchant.String()}, nil
+}
+
+func requestSender(ctx context.Context, s *PaymentService, req interface{}) ([]string, error) {
+	if r, ok := req.(*paymentpb.TransferRequest); ok {
+		return []string{r.Sender}, nil
+	}
+	return nil, nil
+}
+
+// merchant or customer filter of the list, one of them is the caller
+func requestParties(ctx context.Context, s *PaymentService, req interface{}) ([]string, error) {
+	r, ok := req.(interface {
+		GetMerchant() string
+		GetCustomer() string
+	})
+	if !ok {
+		return nil, nil
+	}
+	return []string{r.GetMerchant(), r.GetCustomer()}, nil
+}
+
+func planParties(ctx context.Context, s *PaymentService, req interface{}) ([]string, error) {
+	r, ok := req.(*paymentpb.InstallmentPlanRequest)
+	if !ok {
+		return nil, nil
+	}
+	planID, err := uuid.Parse(r.PlanId)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid plan id")
+	}
+	plan, err := s.storage.GetInstallmentPlan(ctx, planID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, status.Error(codes.NotFound, "plan not found")
+		}
+		return nil, err
+	}
+	return []string{plan.Merchant.String(), plan.Customer.String()}, nil
+}
+
+func requestAccount(ctx context.Context, s *PaymentService, req interface{}) ([]string, error) {
+	if r, ok := req.(*paymentpb.ExportRequest); ok {
+		return []string{r.AccountId}, nil
+	}
+	return nil, nil
+}
+
+// stream with the caller, every received request is checked
+type policyStream struct {
+	grpc.ServerStream
+	ctx     context.Context
+	rule    rule
+	service *PaymentService
+}
+
+func (s *policyStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *policyStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return s.service.checkOwners(s.ctx, s.rule, m)
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/Edbeer/payment-grpc/pkg/jwks"
+	mockpay "github.com/Edbeer/payment-grpc/service/mock"
+	"github.com/Edbeer/payment-grpc/types"
+	paymentpb "github.com/Edbeer/payment-proto/payment-grpc/proto"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// access tokens by their string
+type fakeTokens map[string]*jwks.Claims
+
+func (f fakeTokens) Validate(ctx context.Context, token string) (*jwks.Claims, error) {
+	if claims, ok := f[token]; ok {
+		return claims, nil
+	}
+	return nil, errors.New("invalid token")
+}
+
+func withToken(token string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+}
+
+func Test_MethodRules(t *testing.T) {
+	t.Parallel()
+
+	// every method of the service has a rule
+	for _, method := range paymentpb.PaymentService_ServiceDesc.Methods {
+		require.Contains(t, methodRules, method.MethodName)
+	}
+	for _, stream := range paymentpb.PaymentService_ServiceDesc.Streams {
+		require.Contains(t, methodRules, stream.StreamName)
+	}
+}
+
+func Test_UnaryInterceptor(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storagePay := mockpay.NewMockStorage(ctrl)
+	servicePay := NewPaymentService(storagePay, nil, nil, nil)
+
+	merchant, other, customer := uuid.New(), uuid.New(), uuid.New()
+	unverified := false
+	tokens := fakeTokens{
+		"merchant": {AccountID: merchant.String(), Role: types.RoleMerchant},
+		"other":    {AccountID: other.String(), Role: types.RoleMerchant},
+		"customer": {AccountID: customer.String(), Role: types.RoleCustomer},
+		"support":  {AccountID: uuid.New().String(), Role: types.RoleSupport},
+		"admin":    {AccountID: uuid.New().String(), Role: types.RoleAdmin},
+		// issued before the roles
+		"legacy": {AccountID: customer.String()},
+		// merchant server of the merchant
+		"client":   {AccountID: merchant.String(), Role: types.RoleMerchant, ClientID: "cli_1", Scope: "payments:read"},
+		"admin-cl": {AccountID: uuid.New().String(), Role: types.RoleAdmin, ClientID: "cli_2", Scope: "payments:write payments:read"},
+		// account with an unverified email
+		"unverified-merchant": {AccountID: merchant.String(), Role: types.RoleMerchant, EmailVerified: &unverified},
+		"unverified-customer": {AccountID: customer.String(), Role: types.RoleCustomer, EmailVerified: &unverified},
+	}
+	interceptor := servicePay.UnaryInterceptor(tokens)
+
+	payment := &types.Payment{PaymentId: uuid.New(), Merchant: merchant, Customer: customer}
+	unknown := uuid.New().String()
+	storagePay.EXPECT().GetPaymentByID(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, req *paymentpb.PaidRequest) (*types.Payment, error) {
+			if req.PaymentId != payment.PaymentId.String() {
+				return nil, sql.ErrNoRows
+			}
+			return payment, nil
+		},
+	).AnyTimes()
+
+	capture := &paymentpb.PaidRequest{PaymentId: payment.PaymentId.String(), Amount: 10}
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		method string
+		req    interface{}
+		code   codes.Code
+	}{
+		{"No token", context.Background(), "CapturePayment", capture, codes.Unauthenticated},
+		{"Invalid token", withToken("forged"), "CapturePayment", capture, codes.Unauthenticated},
+		{"Merchant of the payment", withToken("merchant"), "CapturePayment", capture, codes.OK},
+		{"Other merchant", withToken("other"), "RefundPayment", capture, codes.PermissionDenied},
+		{"Customer", withToken("customer"), "RefundPayment", capture, codes.PermissionDenied},
+		{"Support", withToken("support"), "CancelPayment", capture, codes.PermissionDenied},
+		{"Admin", withToken("admin"), "RefundPayment", capture, codes.OK},
+		{"Unknown payment", withToken("merchant"), "CapturePayment", &paymentpb.PaidRequest{PaymentId: unknown}, codes.NotFound},
+		{"Payment of the merchant", withToken("merchant"), "CreatePayment", &paymentpb.CreateRequest{Merchant: merchant.String()}, codes.OK},
+		{"Payment of other merchant", withToken("merchant"), "CreatePayment", &paymentpb.CreateRequest{Merchant: other.String()}, codes.PermissionDenied},
+		{"Customer creates payment", withToken("customer"), "CreatePayment", &paymentpb.CreateRequest{Merchant: customer.String()}, codes.PermissionDenied},
+		{"Transfer of the sender", withToken("legacy"), "Transfer", &paymentpb.TransferRequest{Sender: customer.String()}, codes.OK},
+		{"Transfer of other sender", withToken("customer"), "Transfer", &paymentpb.TransferRequest{Sender: merchant.String()}, codes.PermissionDenied},
+		{"Own list", withToken("customer"), "ListScheduledPayments", &paymentpb.ScheduledListRequest{Customer: customer.String()}, codes.OK},
+		{"List without filter", withToken("customer"), "ListScheduledPayments", &paymentpb.ScheduledListRequest{}, codes.PermissionDenied},
+		{"Support lists", withToken("support"), "GetDelinquentInstallments", &paymentpb.DelinquentRequest{}, codes.OK},
+		{"Import", withToken("merchant"), "ImportPayments", &paymentpb.ImportRequest{}, codes.PermissionDenied},
+		{"Admin imports", withToken("admin"), "ImportPayments", &paymentpb.ImportRequest{}, codes.OK},
+		{"Client reads", withToken("client"), "ListScheduledPayments", &paymentpb.ScheduledListRequest{Merchant: merchant.String()}, codes.OK},
+		{"Client without scope", withToken("client"), "CapturePayment", capture, codes.PermissionDenied},
+		{"Client imports", withToken("admin-cl"), "ImportPayments", &paymentpb.ImportRequest{}, codes.PermissionDenied},
+		{"Unverified creates payment", withToken("unverified-merchant"), "CreatePayment", &paymentpb.CreateRequest{Merchant: merchant.String()}, codes.PermissionDenied},
+		{"Unverified transfers", withToken("unverified-customer"), "Transfer", &paymentpb.TransferRequest{Sender: customer.String()}, codes.PermissionDenied},
+		{"Unverified captures", withToken("unverified-merchant"), "CapturePayment", capture, codes.OK},
+		{"Unverified lists", withToken("unverified-customer"), "ListScheduledPayments", &paymentpb.ScheduledListRequest{Customer: customer.String()}, codes.OK},
+		{"Unknown method", withToken("admin"), "DropPayments", &paymentpb.PaidRequest{}, codes.PermissionDenied},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := interceptor(tt.ctx, tt.req, &grpc.UnaryServerInfo{
+				FullMethod: "/payment.PaymentService/" + tt.method,
+			}, func(ctx context.Context, req interface{}) (interface{}, error) {
+				_, ok := CallerFromContext(ctx)
+				require.True(t, ok)
+				return nil, nil
+			})
+			require.Equal(t, tt.code, status.Code(err))
+		})
+	}
+}
+
+func Test_CompleteChallengeCaller(t *testing.T) {
+	t.Parallel()
+
+	customer := uuid.New()
+	ctx := context.WithValue(context.Background(), callerKey{}, &Caller{
+		AccountID: uuid.New().String(),
+		Role:      types.RoleCustomer,
+	})
+	err := checkCaller(ctx, permPaymentManageAny, customer.String())
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	// calls of the service itself
+	require.NoError(t, checkCaller(context.Background(), permPaymentManageAny, customer.String()))
+}
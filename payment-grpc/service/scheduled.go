@@ -0,0 +1,385 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/Edbeer/payment-grpc/types"
+	authpb "github.com/Edbeer/payment-proto/auth-grpc/proto"
+	paymentpb "github.com/Edbeer/payment-proto/payment-grpc/proto"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// execution of a scheduled payment claimed longer ago
+// is taken over by another run, the replica is considered gone
+var scheduledClaimTTL = 10 * time.Minute
+
+// save the payment in "Scheduled" status, money is not blocked until the execution
+func (s *PaymentService) schedulePayment(ctx context.Context, req *paymentpb.CreateRequest) (*paymentpb.Statement, error) {
+	// Begin Tx
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	// get customer and merchant
+	accounts, err := getAccounts(ctx, s.client, req.Customer, req.Merchant)
+	if err != nil {
+		return nil, err
+	}
+	customer, merchant := accounts[0], accounts[1]
+	// card is checked now, the security code is not stored
+	if req.CardNumber != customer.CardNumber ||
+		req.CardExpiryMonth != customer.CardExpiryMonth ||
+		req.CardExpiryYear != customer.CardExpiryYear ||
+		req.CardSecurityCode != customer.CardSecurityCode {
+		return s.declinePayment(ctx, tx, types.CreateAuthPayment(req, customer, merchant, "wrong payment request"), merchant)
+	}
+	savedPayment, err := s.storage.SavePayment(ctx, types.CreateAuthPayment(req, customer, merchant, types.ScheduledPending), tx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.storage.SaveScheduledPayment(ctx, types.CreateScheduledPayment(savedPayment, req.ExecuteAt.AsTime()), tx); err != nil {
+		return nil, err
+	}
+	sts := []*authpb.StatementRequest{newStatement(merchant.Id, savedPayment, 0)}
+	if err := s.commitPayment(ctx, tx, nil, sts); err != nil {
+		return nil, err
+	}
+	return &paymentpb.Statement{
+		PaymentId: savedPayment.PaymentId.String(),
+		Status:    savedPayment.Status,
+	}, nil
+}
+
+func (s *PaymentService) ListScheduledPayments(ctx context.Context, req *paymentpb.ScheduledListRequest) (*paymentpb.ScheduledPaymentList, error) {
+	if req.Merchant != "" {
+		if _, err := uuid.Parse(req.Merchant); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid merchant id")
+		}
+	}
+	if req.Customer != "" {
+		if _, err := uuid.Parse(req.Customer); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid customer id")
+		}
+	}
+	payments, err := s.storage.GetScheduledPayments(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	list := &paymentpb.ScheduledPaymentList{}
+	for _, scheduled := range payments {
+		list.Payments = append(list.Payments, scheduledToProto(scheduled))
+	}
+	return list, nil
+}
+
+// change amount or execution time of the payment until it is executed
+func (s *PaymentService) UpdateScheduledPayment(ctx context.Context, req *paymentpb.ScheduledUpdateRequest) (*paymentpb.ScheduledPayment, error) {
+	paymentID, err := uuid.Parse(req.PaymentId)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid payment id")
+	}
+	if req.ExecuteAt != nil && !req.ExecuteAt.AsTime().After(time.Now()) {
+		return nil, status.Error(codes.InvalidArgument, "execution time must be in the future")
+	}
+	// Begin Tx
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	// Get scheduled payment, the scheduler waits for the end of tx
+	scheduled, err := s.storage.GetScheduledPayment(ctx, paymentID, tx)
+	if err != nil {
+		return nil, err
+	}
+	if scheduled.State != types.ScheduledPending {
+		return nil, status.Error(codes.FailedPrecondition, "payment is not scheduled")
+	}
+	if req.Amount > 0 {
+		if err := s.storage.UpdatePaymentAmount(ctx, paymentID, req.Amount, tx); err != nil {
+			return nil, err
+		}
+		scheduled.Amount = req.Amount
+	}
+	if req.ExecuteAt != nil {
+		scheduled.ExecuteAt = req.ExecuteAt.AsTime()
+	}
+	scheduled.UpdatedAt = time.Now()
+	if err := s.storage.UpdateScheduledPayment(ctx, scheduled, tx); err != nil {
+		return nil, err
+	}
+	// commit tx
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return scheduledToProto(scheduled), nil
+}
+
+// cancel the payment until it is executed
+func (s *PaymentService) CancelScheduledPayment(ctx context.Context, req *paymentpb.PaidRequest) (*paymentpb.ScheduledPayment, error) {
+	paymentID, err := uuid.Parse(req.PaymentId)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid payment id")
+	}
+	// Begin Tx
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	// Get scheduled payment, the scheduler waits for the end of tx
+	scheduled, err := s.storage.GetScheduledPayment(ctx, paymentID, tx)
+	if err != nil {
+		return nil, err
+	}
+	if scheduled.State != types.ScheduledPending {
+		return nil, status.Error(codes.FailedPrecondition, "payment is not scheduled")
+	}
+	refPayment, err := s.storage.GetPaymentByID(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	// cancel is kept in history with a link to the scheduled payment
+	refPayment.Operation = "Cancel"
+	cancelPayment := types.CreateCompletePayment(&paymentpb.PaidRequest{
+		PaymentId: req.PaymentId,
+		Amount:    refPayment.Amount,
+	}, refPayment, "Successful cancel")
+	cancelPayment.ReferenceId = uuid.NullUUID{UUID: refPayment.PaymentId, Valid: true}
+	savedPayment, err := s.storage.SavePayment(ctx, cancelPayment, tx)
+	if err != nil {
+		return nil, err
+	}
+	scheduled.State = types.ScheduledCancelled
+	scheduled.ResultId = uuid.NullUUID{UUID: savedPayment.PaymentId, Valid: true}
+	scheduled.UpdatedAt = time.Now()
+	if err := s.storage.UpdateScheduledPayment(ctx, scheduled, tx); err != nil {
+		return nil, err
+	}
+	sts := []*authpb.StatementRequest{newStatement(refPayment.Merchant.String(), savedPayment, 0)}
+	if err := s.commitPayment(ctx, tx, nil, sts); err != nil {
+		return nil, err
+	}
+	return scheduledToProto(scheduled), nil
+}
+
+// authorize and capture the scheduled payment,
+// the payment is claimed first so that it is executed by one replica only once
+func (s *PaymentService) ExecuteScheduledPayment(ctx context.Context, paymentID uuid.UUID) error {
+	scheduled, err := s.claimScheduledPayment(ctx, paymentID)
+	if err != nil || scheduled == nil {
+		return err
+	}
+	refPayment, err := s.storage.GetPaymentByID(ctx, &paymentpb.PaidRequest{
+		PaymentId: paymentID.String(),
+	})
+	if err != nil {
+		return s.finishScheduledPayment(ctx, scheduled, types.ScheduledFailed, nil, err)
+	}
+	// results of the authorization and the capture are saved
+	// in their transactions while the claim is held
+	ctx = context.WithValue(ctx, scheduledClaimKey{}, scheduled)
+	// the claim was taken over after the authorization,
+	// its hold is captured instead of authorizing again
+	if scheduled.ResultId.Valid {
+		return s.captureScheduledPayment(ctx, scheduled, &paymentpb.Statement{
+			PaymentId: scheduled.ResultId.UUID.String(),
+			Status:    "Approved",
+		}, refPayment.Amount)
+	}
+	authorization, err := s.authorize(ctx, &paymentpb.CreateRequest{
+		Merchant:        refPayment.Merchant.String(),
+		Customer:        refPayment.Customer.String(),
+		CardNumber:      refPayment.CardNumber,
+		CardExpiryMonth: refPayment.CardExpiryMonth,
+		CardExpiryYear:  refPayment.CardExpiryYear,
+		Currency:        refPayment.Currency,
+		Amount:          refPayment.Amount,
+		Escrow:          refPayment.Escrow,
+		EscrowHoldHours: refPayment.EscrowHoldHours,
+	}, true)
+	if err != nil {
+		return s.finishScheduledPayment(ctx, scheduled, types.ScheduledFailed, nil, err)
+	}
+	if authorization.Status != "Approved" {
+		return s.finishScheduledPayment(ctx, scheduled, types.ScheduledFailed, authorization, nil)
+	}
+	return s.captureScheduledPayment(ctx, scheduled, authorization, refPayment.Amount)
+}
+
+// capture the approved authorization of the scheduled payment
+func (s *PaymentService) captureScheduledPayment(
+	ctx context.Context, scheduled *types.ScheduledPayment,
+	authorization *paymentpb.Statement, amount uint64,
+) error {
+	capture, err := s.CapturePayment(ctx, &paymentpb.PaidRequest{
+		PaymentId: authorization.PaymentId,
+		Amount:    amount,
+	})
+	if err != nil {
+		// authorization stays approved and can be captured by the merchant
+		return s.finishScheduledPayment(ctx, scheduled, types.ScheduledFailed, authorization, err)
+	}
+	return s.finishScheduledPayment(ctx, scheduled, types.ScheduledExecuted, capture, nil)
+}
+
+// move due scheduled payment to "Processing", nil if it is not due anymore,
+// the payment in "Processing" is claimed again when the claim is stale
+func (s *PaymentService) claimScheduledPayment(ctx context.Context, paymentID uuid.UUID) (*types.ScheduledPayment, error) {
+	// Begin Tx
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	// Get scheduled payment, other replicas wait for the end of tx
+	scheduled, err := s.storage.GetScheduledPayment(ctx, paymentID, tx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	switch {
+	case scheduled.State == types.ScheduledPending && !scheduled.ExecuteAt.After(now):
+	case scheduled.State == types.ScheduledProcessing && scheduled.ClaimedAt.Valid &&
+		scheduled.ClaimedAt.Time.Before(now.Add(-scheduledClaimTTL)):
+		log.Printf("scheduled payment %s: claim of %s is taken over", scheduled.PaymentId, scheduled.ClaimedAt.Time)
+	default:
+		// executed by another replica, cancelled or postponed
+		return nil, nil
+	}
+	scheduled.State = types.ScheduledProcessing
+	// the claim time fences the results of the execution,
+	// it is compared with the one stored in microseconds
+	scheduled.ClaimedAt = sql.NullTime{Time: now.Truncate(time.Microsecond), Valid: true}
+	scheduled.UpdatedAt = now
+	if err := s.storage.UpdateScheduledPayment(ctx, scheduled, tx); err != nil {
+		return nil, err
+	}
+	// commit tx
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return scheduled, nil
+}
+
+// save the result of the execution, execErr is returned to the caller,
+// ErrClaimLost if the execution was taken over by another replica
+func (s *PaymentService) finishScheduledPayment(
+	ctx context.Context, scheduled *types.ScheduledPayment,
+	state string, result *paymentpb.Statement, execErr error,
+) error {
+	// Begin Tx
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	resultID := scheduled.ResultId
+	if result != nil {
+		id, err := uuid.Parse(result.PaymentId)
+		if err != nil {
+			return err
+		}
+		resultID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+	finished, err := s.saveScheduledResult(ctx, tx, scheduled, state, resultID)
+	if err != nil {
+		return err
+	}
+	// commit tx
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	*scheduled = *finished
+	return execErr
+}
+
+// context key of the scheduled payment claimed by the execution
+type scheduledClaimKey struct{}
+
+// save the result of the scheduled payment executed with ctx in tx of the payment,
+// before money of the payment is moved
+func (s *PaymentService) recordScheduledResult(ctx context.Context, tx *sql.Tx, state string, result *types.Payment) error {
+	scheduled, ok := ctx.Value(scheduledClaimKey{}).(*types.ScheduledPayment)
+	if !ok {
+		return nil
+	}
+	_, err := s.saveScheduledResult(ctx, tx, scheduled, state, uuid.NullUUID{UUID: result.PaymentId, Valid: true})
+	return err
+}
+
+// save state and result if the payment is still claimed by the execution,
+// the claimed payment is changed after the commit only
+func (s *PaymentService) saveScheduledResult(
+	ctx context.Context, tx *sql.Tx, scheduled *types.ScheduledPayment,
+	state string, resultID uuid.NullUUID,
+) (*types.ScheduledPayment, error) {
+	finished := *scheduled
+	finished.State = state
+	finished.ResultId = resultID
+	finished.UpdatedAt = time.Now()
+	ok, err := s.storage.FinishScheduledPayment(ctx, &finished, tx)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		log.Printf("scheduled payment %s: claim of %s is lost", scheduled.PaymentId, scheduled.ClaimedAt.Time)
+		return nil, types.ErrClaimLost
+	}
+	return &finished, nil
+}
+
+// execute scheduled payments whose time has come
+func (s *PaymentService) ExecuteDueScheduledPayments(ctx context.Context) error {
+	now := time.Now()
+	payments, err := s.storage.GetDueScheduledPayments(ctx, now, now.Add(-scheduledClaimTTL))
+	if err != nil {
+		return err
+	}
+	for _, scheduled := range payments {
+		if err := s.ExecuteScheduledPayment(ctx, scheduled.PaymentId); err != nil {
+			log.Printf("execute scheduled payment %s: %v", scheduled.PaymentId, err)
+		}
+	}
+	return nil
+}
+
+// execute due scheduled payments every interval until ctx is done
+func (s *PaymentService) RunScheduledPayments(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.ExecuteDueScheduledPayments(ctx); err != nil {
+				log.Printf("execute scheduled payments: %v", err)
+			}
+		}
+	}
+}
+
+func scheduledToProto(scheduled *types.ScheduledPayment) *paymentpb.ScheduledPayment {
+	pbScheduled := &paymentpb.ScheduledPayment{
+		PaymentId: scheduled.PaymentId.String(),
+		Merchant:  scheduled.Merchant.String(),
+		Customer:  scheduled.Customer.String(),
+		Currency:  scheduled.Currency,
+		Amount:    scheduled.Amount,
+		ExecuteAt: timestamppb.New(scheduled.ExecuteAt),
+		State:     scheduled.State,
+		CreatedAt: timestamppb.New(scheduled.CreatedAt),
+	}
+	if scheduled.ResultId.Valid {
+		pbScheduled.ResultId = scheduled.ResultId.UUID.String()
+	}
+	return pbScheduled
+}
@@ -0,0 +1,453 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	paymentpb "github.com/Edbeer/payment-proto/payment-grpc/proto"
+	mockpay "github.com/Edbeer/payment-grpc/service/mock"
+
+	"github.com/Edbeer/payment-grpc/types"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func Test_SchedulePayment(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	customer := newSplitAccount(100, 0)
+	merchant := newSplitAccount(0, 0)
+	clientAuth, updates := mockSplitAccounts(ctrl, customer, merchant)
+	storagePay := mockpay.NewMockStorage(ctrl)
+	servicePay := NewPaymentService(storagePay, clientAuth, db, nil)
+
+	executeAt := time.Now().Add(24 * time.Hour)
+	req := &paymentpb.CreateRequest{
+		Merchant:         merchant.Id,
+		Customer:         customer.Id,
+		CardNumber:       customer.CardNumber,
+		CardExpiryMonth:  customer.CardExpiryMonth,
+		CardExpiryYear:   customer.CardExpiryYear,
+		CardSecurityCode: customer.CardSecurityCode,
+		Currency:         "rub",
+		Amount:           50,
+		ExecuteAt:        timestamppb.New(executeAt),
+	}
+
+	mock.ExpectBegin()
+	var saved *types.Payment
+	storagePay.EXPECT().SavePayment(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, payment *types.Payment, tx *sql.Tx) (*types.Payment, error) {
+			saved = payment
+			return payment, nil
+		},
+	)
+	var scheduled *types.ScheduledPayment
+	storagePay.EXPECT().SaveScheduledPayment(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, sp *types.ScheduledPayment, tx *sql.Tx) error {
+			scheduled = sp
+			return nil
+		},
+	)
+	mock.ExpectCommit()
+
+	st, err := servicePay.CreatePayment(context.Background(), req)
+	require.NoError(t, err)
+	require.Equal(t, types.ScheduledPending, st.Status)
+
+	// money is not blocked until the execution
+	require.Empty(t, updates)
+	require.Equal(t, "Authorization", saved.Operation)
+	require.Equal(t, saved.PaymentId, scheduled.PaymentId)
+	require.Equal(t, types.ScheduledPending, scheduled.State)
+	require.True(t, executeAt.Equal(scheduled.ExecuteAt))
+}
+
+func Test_ChangeScheduledPayment(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	newScheduled := func() (*types.Payment, *types.ScheduledPayment) {
+		payment := &types.Payment{
+			PaymentId: uuid.New(),
+			Merchant:  uuid.New(),
+			Customer:  uuid.New(),
+			Currency:  "rub",
+			Operation: "Authorization",
+			Status:    types.ScheduledPending,
+			Amount:    50,
+			CreatedAt: time.Now(),
+		}
+		return payment, types.CreateScheduledPayment(payment, time.Now().Add(time.Hour))
+	}
+
+	t.Run("Update", func(t *testing.T) {
+		storagePay := mockpay.NewMockStorage(ctrl)
+		servicePay := NewPaymentService(storagePay, nil, db, nil)
+
+		payment, scheduled := newScheduled()
+		executeAt := time.Now().Add(48 * time.Hour)
+		mock.ExpectBegin()
+		storagePay.EXPECT().GetScheduledPayment(context.Background(), payment.PaymentId, gomock.Any()).Return(scheduled, nil)
+		storagePay.EXPECT().UpdatePaymentAmount(context.Background(), payment.PaymentId, uint64(70), gomock.Any()).Return(nil)
+		storagePay.EXPECT().UpdateScheduledPayment(context.Background(), scheduled, gomock.Any()).Return(nil)
+		mock.ExpectCommit()
+
+		sp, err := servicePay.UpdateScheduledPayment(context.Background(), &paymentpb.ScheduledUpdateRequest{
+			PaymentId: payment.PaymentId.String(),
+			Amount:    70,
+			ExecuteAt: timestamppb.New(executeAt),
+		})
+		require.NoError(t, err)
+		require.Equal(t, uint64(70), sp.Amount)
+		require.True(t, executeAt.Equal(sp.ExecuteAt.AsTime()))
+	})
+
+	t.Run("Cancel", func(t *testing.T) {
+		clientAuth, _ := mockSplitAccounts(ctrl)
+		storagePay := mockpay.NewMockStorage(ctrl)
+		servicePay := NewPaymentService(storagePay, clientAuth, db, nil)
+
+		payment, scheduled := newScheduled()
+		mock.ExpectBegin()
+		storagePay.EXPECT().GetScheduledPayment(context.Background(), payment.PaymentId, gomock.Any()).Return(scheduled, nil)
+		storagePay.EXPECT().GetPaymentByID(context.Background(), gomock.Any()).Return(payment, nil)
+		var saved *types.Payment
+		storagePay.EXPECT().SavePayment(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, payment *types.Payment, tx *sql.Tx) (*types.Payment, error) {
+				saved = payment
+				return payment, nil
+			},
+		)
+		storagePay.EXPECT().UpdateScheduledPayment(context.Background(), scheduled, gomock.Any()).Return(nil)
+		mock.ExpectCommit()
+
+		sp, err := servicePay.CancelScheduledPayment(context.Background(), &paymentpb.PaidRequest{
+			PaymentId: payment.PaymentId.String(),
+		})
+		require.NoError(t, err)
+		require.Equal(t, types.ScheduledCancelled, sp.State)
+		require.Equal(t, saved.PaymentId.String(), sp.ResultId)
+		require.Equal(t, "Cancel", saved.Operation)
+		require.Equal(t, payment.PaymentId, saved.ReferenceId.UUID)
+	})
+
+	t.Run("Already executed", func(t *testing.T) {
+		storagePay := mockpay.NewMockStorage(ctrl)
+		servicePay := NewPaymentService(storagePay, nil, db, nil)
+
+		payment, scheduled := newScheduled()
+		scheduled.State = types.ScheduledExecuted
+		mock.ExpectBegin()
+		storagePay.EXPECT().GetScheduledPayment(context.Background(), payment.PaymentId, gomock.Any()).Return(scheduled, nil)
+		mock.ExpectRollback()
+
+		_, err := servicePay.UpdateScheduledPayment(context.Background(), &paymentpb.ScheduledUpdateRequest{
+			PaymentId: payment.PaymentId.String(),
+			Amount:    70,
+		})
+		require.Error(t, err)
+		require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+}
+
+func Test_ExecuteScheduledPayment(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	customer := newSplitAccount(100, 0)
+	merchant := newSplitAccount(0, 0)
+	newScheduled := func(executeAt time.Time) (*types.Payment, *types.ScheduledPayment) {
+		payment := &types.Payment{
+			PaymentId:       uuid.New(),
+			Merchant:        uuid.MustParse(merchant.Id),
+			Customer:        uuid.MustParse(customer.Id),
+			CardNumber:      customer.CardNumber,
+			CardExpiryMonth: customer.CardExpiryMonth,
+			CardExpiryYear:  customer.CardExpiryYear,
+			Currency:        "rub",
+			Operation:       "Authorization",
+			Status:          types.ScheduledPending,
+			Amount:          60,
+			CreatedAt:       time.Now(),
+		}
+		return payment, types.CreateScheduledPayment(payment, executeAt)
+	}
+
+	t.Run("Executed", func(t *testing.T) {
+		clientAuth, updates := mockSplitAccounts(ctrl, customer, merchant)
+		storagePay := mockpay.NewMockStorage(ctrl)
+		servicePay := NewPaymentService(storagePay, clientAuth, db, nil)
+
+		payment, scheduled := newScheduled(time.Now().Add(-time.Minute))
+		saved := map[string]*types.Payment{payment.PaymentId.String(): payment}
+		// claim
+		mock.ExpectBegin()
+		storagePay.EXPECT().GetScheduledPayment(context.Background(), payment.PaymentId, gomock.Any()).Return(scheduled, nil)
+		storagePay.EXPECT().UpdateScheduledPayment(context.Background(), scheduled, gomock.Any()).Return(nil)
+		mock.ExpectCommit()
+		// results of the authorization and the capture in their tx, and the result
+		finished := []*types.ScheduledPayment{}
+		storagePay.EXPECT().FinishScheduledPayment(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, sp *types.ScheduledPayment, tx *sql.Tx) (bool, error) {
+				finished = append(finished, sp)
+				return true, nil
+			},
+		).Times(3)
+		storagePay.EXPECT().GetPaymentByID(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, req *paymentpb.PaidRequest) (*types.Payment, error) {
+				return saved[req.PaymentId], nil
+			},
+		).Times(2)
+		// authorization and capture
+		mock.ExpectBegin()
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		storagePay.EXPECT().GetChildPayments(gomock.Any(), gomock.Any()).Return([]*types.Payment{}, nil)
+		storagePay.EXPECT().SavePayment(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, p *types.Payment, tx *sql.Tx) (*types.Payment, error) {
+				saved[p.PaymentId.String()] = p
+				return p, nil
+			},
+		).Times(2)
+		storagePay.EXPECT().UpdatePaymentHold(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, paymentID, holdID uuid.UUID, tx *sql.Tx) error {
+				saved[paymentID.String()].HoldId = uuid.NullUUID{UUID: holdID, Valid: true}
+				return nil
+			},
+		)
+		mock.ExpectCommit()
+		// result
+		mock.ExpectBegin()
+		mock.ExpectCommit()
+
+		err := servicePay.ExecuteScheduledPayment(context.Background(), payment.PaymentId)
+		require.NoError(t, err)
+		require.Equal(t, types.ScheduledExecuted, scheduled.State)
+		// the authorization is saved with the claim before the money is held
+		require.Equal(t, types.ScheduledProcessing, finished[0].State)
+		require.Equal(t, scheduled.ClaimedAt, finished[0].ClaimedAt)
+		require.NotEqual(t, scheduled.ResultId, finished[0].ResultId)
+		// the capture is saved with the claim before the money is paid
+		require.Equal(t, types.ScheduledExecuted, finished[1].State)
+		require.Equal(t, scheduled.ResultId, finished[1].ResultId)
+
+		capture := saved[scheduled.ResultId.UUID.String()]
+		require.Equal(t, "Capture", capture.Operation)
+		require.Equal(t, "Successful payment", capture.Status)
+		require.Equal(t, uint64(60), updates[merchant.Id].Balance)
+		require.Equal(t, uint64(0), updates[customer.Id].BlockedMoney)
+	})
+
+	t.Run("Taken over", func(t *testing.T) {
+		clientAuth, updates := mockSplitAccounts(ctrl, customer, merchant)
+		storagePay := mockpay.NewMockStorage(ctrl)
+		servicePay := NewPaymentService(storagePay, clientAuth, db, nil)
+
+		payment, scheduled := newScheduled(time.Now().Add(-time.Hour))
+		saved := map[string]*types.Payment{payment.PaymentId.String(): payment}
+		storagePay.EXPECT().SavePayment(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, p *types.Payment, tx *sql.Tx) (*types.Payment, error) {
+				saved[p.PaymentId.String()] = p
+				return p, nil
+			},
+		).Times(2)
+		storagePay.EXPECT().UpdatePaymentHold(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, paymentID, holdID uuid.UUID, tx *sql.Tx) error {
+				saved[paymentID.String()].HoldId = uuid.NullUUID{UUID: holdID, Valid: true}
+				return nil
+			},
+		)
+		// the replica that claimed the payment stopped after the authorization
+		mock.ExpectBegin()
+		mock.ExpectCommit()
+		authorization, err := servicePay.authorize(context.Background(), &paymentpb.CreateRequest{
+			Merchant:        merchant.Id,
+			Customer:        customer.Id,
+			CardNumber:      customer.CardNumber,
+			CardExpiryMonth: customer.CardExpiryMonth,
+			CardExpiryYear:  customer.CardExpiryYear,
+			Currency:        "rub",
+			Amount:          60,
+		}, true)
+		require.NoError(t, err)
+		scheduled.State = types.ScheduledProcessing
+		scheduled.ClaimedAt = sql.NullTime{Time: time.Now().Add(-time.Hour), Valid: true}
+		scheduled.ResultId = uuid.NullUUID{UUID: uuid.MustParse(authorization.PaymentId), Valid: true}
+
+		// claim, the authorization is captured without authorizing again
+		mock.ExpectBegin()
+		storagePay.EXPECT().GetScheduledPayment(context.Background(), payment.PaymentId, gomock.Any()).Return(scheduled, nil)
+		storagePay.EXPECT().UpdateScheduledPayment(context.Background(), scheduled, gomock.Any()).Return(nil)
+		mock.ExpectCommit()
+		storagePay.EXPECT().FinishScheduledPayment(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil).Times(2)
+		storagePay.EXPECT().GetPaymentByID(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, req *paymentpb.PaidRequest) (*types.Payment, error) {
+				return saved[req.PaymentId], nil
+			},
+		).Times(2)
+		mock.ExpectBegin()
+		storagePay.EXPECT().GetChildPayments(gomock.Any(), gomock.Any()).Return([]*types.Payment{}, nil)
+		mock.ExpectCommit()
+		// result
+		mock.ExpectBegin()
+		mock.ExpectCommit()
+
+		err = servicePay.ExecuteScheduledPayment(context.Background(), payment.PaymentId)
+		require.NoError(t, err)
+		require.Equal(t, types.ScheduledExecuted, scheduled.State)
+		require.Equal(t, "Capture", saved[scheduled.ResultId.UUID.String()].Operation)
+		require.Equal(t, uint64(60), updates[merchant.Id].Balance)
+		require.Equal(t, uint64(40), updates[customer.Id].Balance)
+	})
+
+	t.Run("Claim lost", func(t *testing.T) {
+		// the customer can pay twice
+		customer := newSplitAccount(200, 0)
+		clientAuth, updates := mockSplitAccounts(ctrl, customer, merchant)
+		storagePay := mockpay.NewMockStorage(ctrl)
+		replicaDB, replicaMock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer replicaDB.Close()
+		// two replicas over the same storage
+		servicePay := NewPaymentService(storagePay, clientAuth, db, nil)
+		replicaPay := NewPaymentService(storagePay, clientAuth, replicaDB, nil)
+
+		payment, row := newScheduled(time.Now().Add(-time.Minute))
+		payment.Customer = uuid.MustParse(customer.Id)
+		payment.CardNumber = customer.CardNumber
+		saved := map[string]*types.Payment{payment.PaymentId.String(): payment}
+		claims := []*types.ScheduledPayment{}
+		storagePay.EXPECT().GetScheduledPayment(gomock.Any(), payment.PaymentId, gomock.Any()).DoAndReturn(
+			func(ctx context.Context, paymentID uuid.UUID, tx *sql.Tx) (*types.ScheduledPayment, error) {
+				claim := *row
+				claims = append(claims, &claim)
+				return &claim, nil
+			},
+		).Times(2)
+		storagePay.EXPECT().UpdateScheduledPayment(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, sp *types.ScheduledPayment, tx *sql.Tx) error {
+				*row = *sp
+				return nil
+			},
+		).Times(2)
+		storagePay.EXPECT().FinishScheduledPayment(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, sp *types.ScheduledPayment, tx *sql.Tx) (bool, error) {
+				if !sp.ClaimedAt.Time.Equal(row.ClaimedAt.Time) {
+					return false, nil
+				}
+				*row = *sp
+				return true, nil
+			},
+		).AnyTimes()
+		storagePay.EXPECT().SavePayment(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, p *types.Payment, tx *sql.Tx) (*types.Payment, error) {
+				saved[p.PaymentId.String()] = p
+				return p, nil
+			},
+		).AnyTimes()
+		storagePay.EXPECT().UpdatePaymentHold(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, paymentID, holdID uuid.UUID, tx *sql.Tx) error {
+				saved[paymentID.String()].HoldId = uuid.NullUUID{UUID: holdID, Valid: true}
+				return nil
+			},
+		)
+		storagePay.EXPECT().GetChildPayments(gomock.Any(), gomock.Any()).Return([]*types.Payment{}, nil)
+		// the first replica stalls after the claim until the claim expires,
+		// the second one takes it over and executes the payment
+		var replicaErr error
+		storagePay.EXPECT().GetPaymentByID(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, req *paymentpb.PaidRequest) (*types.Payment, error) {
+				if len(claims) == 1 {
+					expired := sql.NullTime{Time: claims[0].ClaimedAt.Time.Add(-time.Hour), Valid: true}
+					claims[0].ClaimedAt, row.ClaimedAt = expired, expired
+					replicaErr = replicaPay.ExecuteScheduledPayment(context.Background(), payment.PaymentId)
+				}
+				return saved[req.PaymentId], nil
+			},
+		).Times(3)
+
+		// claim, authorization and capture, result of the second replica
+		for i := 0; i < 4; i++ {
+			replicaMock.ExpectBegin()
+			replicaMock.ExpectCommit()
+		}
+		// claim of the first replica
+		mock.ExpectBegin()
+		mock.ExpectCommit()
+		// authorization is not committed, the result is not saved
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		err = servicePay.ExecuteScheduledPayment(context.Background(), payment.PaymentId)
+		require.ErrorIs(t, err, types.ErrClaimLost)
+		require.NoError(t, replicaErr)
+		require.NoError(t, replicaMock.ExpectationsWereMet())
+
+		require.Equal(t, types.ScheduledExecuted, row.State)
+		require.Equal(t, claims[1].ClaimedAt, row.ClaimedAt)
+		require.Equal(t, "Capture", saved[row.ResultId.UUID.String()].Operation)
+		// the customer is charged once
+		require.Equal(t, uint64(140), updates[customer.Id].Balance)
+		require.Equal(t, uint64(0), updates[customer.Id].BlockedMoney)
+		require.Equal(t, uint64(60), updates[merchant.Id].Balance)
+	})
+
+	t.Run("Claimed by another replica", func(t *testing.T) {
+		storagePay := mockpay.NewMockStorage(ctrl)
+		servicePay := NewPaymentService(storagePay, nil, db, nil)
+
+		payment, scheduled := newScheduled(time.Now().Add(-time.Minute))
+		scheduled.State = types.ScheduledProcessing
+		scheduled.ClaimedAt = sql.NullTime{Time: time.Now(), Valid: true}
+		mock.ExpectBegin()
+		storagePay.EXPECT().GetScheduledPayment(context.Background(), payment.PaymentId, gomock.Any()).Return(scheduled, nil)
+		mock.ExpectRollback()
+
+		err := servicePay.ExecuteScheduledPayment(context.Background(), payment.PaymentId)
+		require.NoError(t, err)
+		require.Equal(t, types.ScheduledProcessing, scheduled.State)
+	})
+
+	t.Run("Postponed", func(t *testing.T) {
+		storagePay := mockpay.NewMockStorage(ctrl)
+		servicePay := NewPaymentService(storagePay, nil, db, nil)
+
+		payment, scheduled := newScheduled(time.Now().Add(time.Hour))
+		mock.ExpectBegin()
+		storagePay.EXPECT().GetScheduledPayment(context.Background(), payment.PaymentId, gomock.Any()).Return(scheduled, nil)
+		mock.ExpectRollback()
+
+		err := servicePay.ExecuteScheduledPayment(context.Background(), payment.PaymentId)
+		require.NoError(t, err)
+		require.Equal(t, types.ScheduledPending, scheduled.State)
+	})
+}
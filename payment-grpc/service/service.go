@@ -4,186 +4,126 @@ package service
 import (
 	"context"
 	"database/sql"
+	"io"
+	"time"
 
 	authpb "github.com/Edbeer/payment-proto/auth-grpc/proto"
 	paymentpb "github.com/Edbeer/payment-proto/payment-grpc/proto"
 	"github.com/Edbeer/payment-grpc/types"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+// max number of events read from storage at once
+const eventsBatchSize = 100
+
+// how often subscribers look for new payment events
+var eventsPollInterval = time.Second
+
 type Storage interface {
 	SavePayment(ctx context.Context, payment *types.Payment, tx *sql.Tx) (*types.Payment, error)
 	GetPaymentByID(ctx context.Context, req *paymentpb.PaidRequest) (*types.Payment, error)
+	GetPaymentForUpdate(ctx context.Context, paymentID uuid.UUID, tx *sql.Tx) (*types.Payment, error)
+	UpdatePaymentAmount(ctx context.Context, paymentID uuid.UUID, amount uint64, tx *sql.Tx) error
+	UpdatePaymentHold(ctx context.Context, paymentID, holdID uuid.UUID, tx *sql.Tx) error
+	GetChildPayments(ctx context.Context, parentID uuid.UUID) ([]*types.Payment, error)
+	GetPaymentEvents(ctx context.Context, req *paymentpb.SubscribeRequest, limit int) ([]*types.PaymentEvent, error)
+	SaveEscrow(ctx context.Context, escrow *types.Escrow, tx *sql.Tx) (*types.Escrow, error)
+	GetEscrow(ctx context.Context, paymentID uuid.UUID, tx *sql.Tx) (*types.Escrow, error)
+	UpdateEscrow(ctx context.Context, escrow *types.Escrow, tx *sql.Tx) (*types.Escrow, error)
+	GetDueEscrows(ctx context.Context, now time.Time) ([]*types.Escrow, error)
+	SaveChallenge(ctx context.Context, challenge *types.Challenge, tx *sql.Tx) (*types.Challenge, error)
+	GetChallenge(ctx context.Context, challengeID uuid.UUID, tx *sql.Tx) (*types.Challenge, error)
+	UpdateChallenge(ctx context.Context, challenge *types.Challenge, tx *sql.Tx) error
+	GetExpiredChallenges(ctx context.Context, now time.Time) ([]*types.Challenge, error)
+	SaveTransfer(ctx context.Context, transfer *types.Transfer, tx *sql.Tx) (*types.Transfer, error)
+	GetTransferByKey(ctx context.Context, sender uuid.UUID, key string) (*types.Transfer, error)
+	SaveInstallmentPlan(ctx context.Context, plan *types.InstallmentPlan, tx *sql.Tx) (*types.InstallmentPlan, error)
+	SaveInstallment(ctx context.Context, installment *types.Installment, tx *sql.Tx) (*types.Installment, error)
+	GetInstallmentPlan(ctx context.Context, planID uuid.UUID) (*types.InstallmentPlan, error)
+	GetInstallments(ctx context.Context, planID uuid.UUID) ([]*types.Installment, error)
+	GetDueInstallments(ctx context.Context, now time.Time) ([]*types.Installment, error)
+	GetDelinquentInstallments(ctx context.Context, req *paymentpb.DelinquentRequest) ([]*types.Installment, error)
+	GetInstallmentForUpdate(ctx context.Context, installmentID uuid.UUID, tx *sql.Tx) (*types.Installment, error)
+	UpdateInstallment(ctx context.Context, installment *types.Installment, tx *sql.Tx) error
+	RefreshInstallmentPlan(ctx context.Context, planID uuid.UUID, tx *sql.Tx) error
+	SaveScheduledPayment(ctx context.Context, scheduled *types.ScheduledPayment, tx *sql.Tx) error
+	GetScheduledPayment(ctx context.Context, paymentID uuid.UUID, tx *sql.Tx) (*types.ScheduledPayment, error)
+	GetScheduledPayments(ctx context.Context, req *paymentpb.ScheduledListRequest) ([]*types.ScheduledPayment, error)
+	GetDueScheduledPayments(ctx context.Context, now, claimedBefore time.Time) ([]*types.ScheduledPayment, error)
+	UpdateScheduledPayment(ctx context.Context, scheduled *types.ScheduledPayment, tx *sql.Tx) error
+	FinishScheduledPayment(ctx context.Context, scheduled *types.ScheduledPayment, tx *sql.Tx) (bool, error)
+}
+
+// delivery of one-time codes to the customer
+type Notifier interface {
+	Notify(ctx context.Context, accountID, challengeID, code string) error
 }
 
 type PaymentService struct {
 	paymentpb.UnimplementedPaymentServiceServer
-	client  authpb.AuthServiceClient
-	storage Storage
-	db      *sql.DB
+	client   authpb.AuthServiceClient
+	storage  Storage
+	db       *sql.DB
+	notifier Notifier
 }
 
-func NewPaymentService(storage Storage, client authpb.AuthServiceClient, db *sql.DB) *PaymentService {
-	return &PaymentService{storage: storage, client: client, db: db}
+func NewPaymentService(storage Storage, client authpb.AuthServiceClient, db *sql.DB, notifier Notifier) *PaymentService {
+	return &PaymentService{storage: storage, client: client, db: db, notifier: notifier}
 }
 
 func (s *PaymentService) CreatePayment(ctx context.Context, req *paymentpb.CreateRequest) (*paymentpb.Statement, error) {
+	// future-dated payment waits for the scheduler
+	if req.ExecuteAt != nil && req.ExecuteAt.AsTime().After(time.Now()) {
+		return s.schedulePayment(ctx, req)
+	}
+	return s.authorize(ctx, req, false)
+}
+
+// block the payment amount on the customer account,
+// scheduled execution has no security code and no customer for the challenge
+func (s *PaymentService) authorize(ctx context.Context, req *paymentpb.CreateRequest, scheduled bool) (*paymentpb.Statement, error) {
 	// Begin Tx
 	tx, err := s.db.BeginTx(context.Background(), nil)
 	if err != nil {
 		return nil, err
 	}
 	defer tx.Rollback()
-	// get customer
-	customer, err := s.client.GetAccountByID(ctx, &authpb.GetIDRequest{
-		Id: req.Customer,
-	})
+	// get customer and merchant
+	accounts, err := getAccounts(ctx, s.client, req.Customer, req.Merchant)
 	if err != nil {
 		return nil, err
 	}
-	// get merchant
-	merchant, err := s.client.GetAccountByID(ctx, &authpb.GetIDRequest{
-		Id: req.Merchant,
-	})
-	if err != nil {
-		return nil, err
-	}
-	// statements
-	sts := []*authpb.StatementRequest{}
+	customer, merchant := accounts[0], accounts[1]
 	// check payment request
 	if req.CardNumber != customer.CardNumber ||
 		req.CardExpiryMonth != customer.CardExpiryMonth ||
 		req.CardExpiryYear != customer.CardExpiryYear ||
-		req.CardSecurityCode != customer.CardSecurityCode {
-		// create payment
-		payment := types.CreateAuthPayment(req, customer, merchant, "wrong payment request")
-		savedPayment, err := s.storage.SavePayment(ctx, payment, tx)
-		if err != nil {
-			return nil, err
-		}
-		// send statements to auth service
-		sts = append(sts, &authpb.StatementRequest{
-			AccountId: merchant.Id,
-			PaymentId: savedPayment.PaymentId.String(),
-		})
-		if err := createStatement(ctx, s.client, sts); err != nil {
-			return nil, err
-		}
-		// commit tx
-		if err := tx.Commit(); err != nil {
-			return nil, err
-		}
-		return &paymentpb.Statement{
-			PaymentId: savedPayment.PaymentId.String(),
-			Status:    savedPayment.Status,
-		}, nil
+		!scheduled && req.CardSecurityCode != customer.CardSecurityCode {
+		return s.declinePayment(ctx, tx, types.CreateAuthPayment(req, customer, merchant, "wrong payment request"), merchant)
 	}
-	// consume customer balance
 	// balance < req amount
 	if customer.Balance < req.Amount {
-		// create payment
-		payment := types.CreateAuthPayment(req, customer, merchant, "Insufficient funds")
-		savedPayment, err := s.storage.SavePayment(ctx, payment, tx)
-		if err != nil {
-			return nil, err
-		}
-		// save statement for merchant
-		sts = append(sts, &authpb.StatementRequest{
-			AccountId: merchant.Id,
-			PaymentId: savedPayment.PaymentId.String(),
-		})
-		// send statements to auth service
-		if err := createStatement(ctx, s.client, sts); err != nil {
-			return nil, err
-		}
-		// commit tx
-		if err := tx.Commit(); err != nil {
-			return nil, err
-		}
-		return &paymentpb.Statement{
-			PaymentId: savedPayment.PaymentId.String(),
-			Status:    savedPayment.Status,
-		}, nil
+		return s.declinePayment(ctx, tx, types.CreateAuthPayment(req, customer, merchant, "Insufficient funds"), merchant)
 	}
-	// balance > req amount
-	// customer acc new balance
-	customer.Balance = customer.Balance - req.Amount
-	customer.BlockedMoney = customer.BlockedMoney + req.Amount
-	customer, err = s.client.UpdateBalance(ctx, &authpb.UpdateBalanceRequest{
-		Id:           customer.Id,
-		Balance:      customer.Balance,
-		BlockedMoney: customer.BlockedMoney,
-	})
-	if err != nil {
-		return nil, err
-	}
-	// merchant acc new balance
-	merchant.BlockedMoney = merchant.BlockedMoney + req.Amount
-	merchant, err = s.client.UpdateBalance(ctx, &authpb.UpdateBalanceRequest{
-		Id:           merchant.Id,
-		Balance:      merchant.Balance,
-		BlockedMoney: merchant.BlockedMoney,
-	})
-	if err != nil {
-		customer.Balance = customer.Balance + req.Amount
-		customer.BlockedMoney = customer.BlockedMoney - req.Amount
-		customer, _ = s.client.UpdateBalance(ctx, &authpb.UpdateBalanceRequest{
-			Id:           customer.Id,
-			Balance:      customer.Balance,
-			BlockedMoney: customer.BlockedMoney,
-		})
-
-		return nil, err
+	// payment waits for 3-D Secure challenge of the customer
+	if !scheduled && (req.Challenge || req.Amount >= challengeAmount) {
+		return s.requireChallenge(ctx, tx, req, customer, merchant)
 	}
 	// create new payment
-	payment := types.CreateAuthPayment(req, customer, merchant, "Approved")
-	savedPayment, err := s.storage.SavePayment(ctx, payment, tx)
+	savedPayment, err := s.storage.SavePayment(ctx, types.CreateAuthPayment(req, customer, merchant, "Approved"), tx)
 	if err != nil {
-		customer.Balance = customer.Balance + req.Amount
-		customer.BlockedMoney = customer.BlockedMoney - req.Amount
-		customer, _ = s.client.UpdateBalance(ctx, &authpb.UpdateBalanceRequest{
-			Id:           customer.Id,
-			Balance:      customer.Balance,
-			BlockedMoney: customer.BlockedMoney,
-		})
-
-		merchant.BlockedMoney = merchant.BlockedMoney - req.Amount
-		merchant, err = s.client.UpdateBalance(ctx, &authpb.UpdateBalanceRequest{
-			Id:           merchant.Id,
-			Balance:      merchant.Balance,
-			BlockedMoney: merchant.BlockedMoney,
-		})
 		return nil, err
 	}
-	// save statement for customer
-	sts = append(sts, &authpb.StatementRequest{
-		AccountId: customer.Id,
-		PaymentId: savedPayment.PaymentId.String(),
-	})
-	// save statement for merchant
-	sts = append(sts, &authpb.StatementRequest{
-		AccountId: merchant.Id,
-		PaymentId: savedPayment.PaymentId.String(),
-	})
-	// send statements to auth service
-	if err := createStatement(ctx, s.client, sts); err != nil {
-		customer.Balance = customer.Balance + req.Amount
-		customer.BlockedMoney = customer.BlockedMoney - req.Amount
-		customer, _ = s.client.UpdateBalance(ctx, &authpb.UpdateBalanceRequest{
-			Id:           customer.Id,
-			Balance:      customer.Balance,
-			BlockedMoney: customer.BlockedMoney,
-		})
-
-		merchant.BlockedMoney = merchant.BlockedMoney - req.Amount
-		merchant, err = s.client.UpdateBalance(ctx, &authpb.UpdateBalanceRequest{
-			Id:           merchant.Id,
-			Balance:      merchant.Balance,
-			BlockedMoney: merchant.BlockedMoney,
-		})
-		return nil, err
+	// hold money of the customer
+	amount := int64(req.Amount)
+	sts := []*authpb.StatementRequest{
+		newStatement(customer.Id, savedPayment, -amount),
+		newStatement(merchant.Id, savedPayment, 0),
 	}
-	// commit tx
-	if err := tx.Commit(); err != nil {
+	if err := s.commitHold(ctx, tx, savedPayment, sts); err != nil {
 		return nil, err
 	}
 	return &paymentpb.Statement{
@@ -204,124 +144,46 @@ func (s *PaymentService) CapturePayment(ctx context.Context, req *paymentpb.Paid
 	if err != nil {
 		return nil, err
 	}
-	// Get customer
-	customer, err := s.client.GetAccountByID(ctx, &authpb.GetIDRequest{
-		Id: refPayment.Customer.String(),
-	})
-	// Get merchant
-	merchant, err := s.client.GetAccountByID(ctx, &authpb.GetIDRequest{
-		Id: refPayment.Merchant.String(),
-	})
-	// statements
-	sts := []*authpb.StatementRequest{}
-	if refPayment.Operation == "Authorization" && refPayment.Status == "Approved" {
+	// Get customer and merchant
+	accounts, err := getAccounts(ctx, s.client, refPayment.Customer.String(), refPayment.Merchant.String())
+	if err != nil {
+		return nil, err
+	}
+	customer, merchant := accounts[0], accounts[1]
+	if refPayment.Operation == "Authorization" && refPayment.Status == "Approved" && !refPayment.ParentId.Valid {
+		refPayment.Operation = "Capture"
 		// Invalid amount
 		if refPayment.Amount < req.Amount {
-			refPayment.Operation = "Capture"
-			completedPayment := types.CreateCompletePayment(req, refPayment, "Invalid amount")
-			invalidPayment, err := s.storage.SavePayment(ctx, completedPayment, tx)
-			if err != nil {
-				return nil, err
-			}
-			// send statements to auth service
-			sts = append(sts, &authpb.StatementRequest{
-				AccountId: merchant.Id,
-				PaymentId: completedPayment.PaymentId.String(),
-			})
-			if err := createStatement(ctx, s.client, sts); err != nil {
-				return nil, err
-			}
-			// commit tx
-			if err := tx.Commit(); err != nil {
-				return nil, err
-			}
-			return &paymentpb.Statement{
-				PaymentId: invalidPayment.PaymentId.String(),
-				Status:    invalidPayment.Status,
-			}, nil
+			return s.declinePayment(ctx, tx, types.CreateCompletePayment(req, refPayment, "Invalid amount"), merchant)
 		}
-		// Successful payment
-		// update customer balance and append new statement
-		customer.BlockedMoney = customer.BlockedMoney - req.Amount
-
-		customer, err = s.client.UpdateBalance(ctx, &authpb.UpdateBalanceRequest{
-			Id:           customer.Id,
-			BlockedMoney: customer.BlockedMoney,
-		})
+		// split payment is distributed between the sellers
+		children, err := s.storage.GetChildPayments(ctx, refPayment.PaymentId)
 		if err != nil {
 			return nil, err
 		}
-		// update new merchant balance and append new statement
-		merchant.Balance = merchant.Balance + req.Amount
-		merchant.BlockedMoney = merchant.BlockedMoney - req.Amount
-
-		merchant, err = s.client.UpdateBalance(ctx, &authpb.UpdateBalanceRequest{
-			Id:           merchant.Id,
-			Balance:      merchant.Balance,
-			BlockedMoney: merchant.BlockedMoney,
-		})
-		if err != nil {
-			customer.BlockedMoney = customer.BlockedMoney + req.Amount
-
-			customer, _ = s.client.UpdateBalance(ctx, &authpb.UpdateBalanceRequest{
-				Id:           customer.Id,
-				BlockedMoney: customer.BlockedMoney,
-			})
-			return nil, err
+		if len(children) > 0 {
+			return s.completeSplitPayment(ctx, tx, req, refPayment, children, customer, merchant,
+				"Successful payment", splitCapture)
+		}
+		// escrow payment is held until released
+		if refPayment.Escrow {
+			return s.captureToEscrow(ctx, tx, req, refPayment, customer, merchant)
 		}
 		// make complete payment
-		completedPayment := types.CreateCompletePayment(req, refPayment, "Successful payment")
-		completedPayment.Operation = "Capture"
-		completedPayment, err = s.storage.SavePayment(ctx, completedPayment, tx)
+		completedPayment, err := s.storage.SavePayment(ctx, types.CreateCompletePayment(req, refPayment, "Successful payment"), tx)
 		if err != nil {
-			customer.BlockedMoney = customer.BlockedMoney + req.Amount
-
-			customer, _ = s.client.UpdateBalance(ctx, &authpb.UpdateBalanceRequest{
-				Id:           customer.Id,
-				BlockedMoney: customer.BlockedMoney,
-			})
-
-			merchant.Balance = merchant.Balance - req.Amount
-			merchant.BlockedMoney = merchant.BlockedMoney + req.Amount
-
-			merchant, _ = s.client.UpdateBalance(ctx, &authpb.UpdateBalanceRequest{
-				Id:           merchant.Id,
-				Balance:      merchant.Balance,
-				BlockedMoney: merchant.BlockedMoney,
-			})
 			return nil, err
 		}
-		// save statement for customer
-		sts = append(sts, &authpb.StatementRequest{
-			AccountId: customer.Id,
-			PaymentId: completedPayment.PaymentId.String(),
-		})
-		// save statement for merchant
-		sts = append(sts, &authpb.StatementRequest{
-			AccountId: merchant.Id,
-			PaymentId: completedPayment.PaymentId.String(),
-		})
-		// send statements to auth service
-		if err := createStatement(ctx, s.client, sts); err != nil {
-			customer.BlockedMoney = customer.BlockedMoney + req.Amount
-
-			customer, _ = s.client.UpdateBalance(ctx, &authpb.UpdateBalanceRequest{
-				Id:           customer.Id,
-				BlockedMoney: customer.BlockedMoney,
-			})
-
-			merchant.Balance = merchant.Balance - req.Amount
-			merchant.BlockedMoney = merchant.BlockedMoney + req.Amount
-
-			merchant, _ = s.client.UpdateBalance(ctx, &authpb.UpdateBalanceRequest{
-				Id:           merchant.Id,
-				Balance:      merchant.Balance,
-				BlockedMoney: merchant.BlockedMoney,
-			})
-			return nil, err
+		// Successful payment
+		// held money of the customer goes to the merchant balance,
+		// the rest of the hold is returned to the customer
+		amount := int64(req.Amount)
+		movements := []*movement{{account: merchant, balance: amount}}
+		sts := []*authpb.StatementRequest{
+			newStatement(customer.Id, completedPayment, int64(refPayment.Amount)-amount),
+			newStatement(merchant.Id, completedPayment, amount),
 		}
-		// commit tx
-		if err := tx.Commit(); err != nil {
+		if err := s.commitCapture(ctx, tx, refPayment, completedPayment, movements, sts); err != nil {
 			return nil, err
 		}
 		return &paymentpb.Statement{
@@ -347,118 +209,44 @@ func (s *PaymentService) RefundPayment(ctx context.Context, req *paymentpb.PaidR
 	if err != nil {
 		return nil, err
 	}
-	// Get customer
-	customer, err := s.client.GetAccountByID(ctx, &authpb.GetIDRequest{
-		Id: refPayment.Customer.String(),
-	})
-	// Get merchant
-	merchant, err := s.client.GetAccountByID(ctx, &authpb.GetIDRequest{
-		Id: refPayment.Merchant.String(),
-	})
-	// statements
-	sts := []*authpb.StatementRequest{}
-	if refPayment.Operation == "Capture" && refPayment.Status == "Successful payment" {
+	// Get customer and merchant
+	accounts, err := getAccounts(ctx, s.client, refPayment.Customer.String(), refPayment.Merchant.String())
+	if err != nil {
+		return nil, err
+	}
+	customer, merchant := accounts[0], accounts[1]
+	if refPayment.Operation == "Capture" && refPayment.Status == "Successful payment" && !refPayment.ParentId.Valid {
+		refPayment.Operation = "Refund"
 		// Invalid amount
 		if refPayment.Amount < req.Amount {
-			refPayment.Operation = "Refund"
-			completedPayment := types.CreateCompletePayment(req, refPayment, "Invalid amount")
-			invalidPayment, err := s.storage.SavePayment(ctx, completedPayment, tx)
-			if err != nil {
-				return nil, err
-			}
-			// send statements to auth service
-			sts = append(sts, &authpb.StatementRequest{
-				AccountId: merchant.Id,
-				PaymentId: completedPayment.PaymentId.String(),
-			})
-			if err := createStatement(ctx, s.client, sts); err != nil {
-				return nil, err
-			}
-			// commit tx
-			if err := tx.Commit(); err != nil {
-				return nil, err
-			}
-			return &paymentpb.Statement{
-				PaymentId: invalidPayment.PaymentId.String(),
-				Status:    invalidPayment.Status,
-			}, nil
+			return s.declinePayment(ctx, tx, types.CreateCompletePayment(req, refPayment, "Invalid amount"), merchant)
 		}
-		// Successful refund
-		// update customer balance
-		customer.Balance = customer.Balance + req.Amount
-
-		customer, err = s.client.UpdateBalance(ctx, &authpb.UpdateBalanceRequest{
-			Id:      customer.Id,
-			Balance: customer.Balance,
-		})
+		// split payment is reversed proportionally
+		children, err := s.storage.GetChildPayments(ctx, refPayment.PaymentId)
 		if err != nil {
 			return nil, err
 		}
-		// update new merchant balance
-		merchant.Balance = merchant.Balance - req.Amount
-
-		merchant, err = s.client.UpdateBalance(ctx, &authpb.UpdateBalanceRequest{
-			Id:      merchant.Id,
-			Balance: merchant.Balance,
-		})
-		if err != nil {
-			customer.Balance = customer.Balance - req.Amount
-
-			customer, _ = s.client.UpdateBalance(ctx, &authpb.UpdateBalanceRequest{
-				Id:      customer.Id,
-				Balance: customer.Balance,
-			})
-			return nil, err
+		if len(children) > 0 {
+			return s.completeSplitPayment(ctx, tx, req, refPayment, children, customer, merchant,
+				"Successful refund", splitRefund)
 		}
 		// make complete refund
-		refPayment.Operation = "Refund"
-		completedPayment := types.CreateCompletePayment(req, refPayment, "Successful refund")
-		completedPayment, err = s.storage.SavePayment(ctx, completedPayment, tx)
+		completedPayment, err := s.storage.SavePayment(ctx, types.CreateCompletePayment(req, refPayment, "Successful refund"), tx)
 		if err != nil {
-			customer.Balance = customer.Balance - req.Amount
-
-			customer, _ = s.client.UpdateBalance(ctx, &authpb.UpdateBalanceRequest{
-				Id:      customer.Id,
-				Balance: customer.Balance,
-			})
-
-			merchant.Balance = merchant.Balance + req.Amount
-
-			merchant, err = s.client.UpdateBalance(ctx, &authpb.UpdateBalanceRequest{
-				Id:      merchant.Id,
-				Balance: merchant.Balance,
-			})
 			return nil, err
 		}
-
-		sts = append(sts, &authpb.StatementRequest{
-			AccountId: customer.Id,
-			PaymentId: completedPayment.PaymentId.String(),
-		})
-
-		sts = append(sts, &authpb.StatementRequest{
-			AccountId: merchant.Id,
-			PaymentId: completedPayment.PaymentId.String(),
-		})
-		// send statements to auth service
-		if err := createStatement(ctx, s.client, sts); err != nil {
-			customer.Balance = customer.Balance - req.Amount
-
-			customer, _ = s.client.UpdateBalance(ctx, &authpb.UpdateBalanceRequest{
-				Id:      customer.Id,
-				Balance: customer.Balance,
-			})
-
-			merchant.Balance = merchant.Balance + req.Amount
-
-			merchant, err = s.client.UpdateBalance(ctx, &authpb.UpdateBalanceRequest{
-				Id:      merchant.Id,
-				Balance: merchant.Balance,
-			})
-			return nil, err
+		// Successful refund
+		// money goes back from the merchant to the customer
+		amount := int64(req.Amount)
+		movements := []*movement{
+			{account: customer, balance: amount},
+			{account: merchant, balance: -amount},
+		}
+		sts := []*authpb.StatementRequest{
+			newStatement(customer.Id, completedPayment, amount),
+			newStatement(merchant.Id, completedPayment, -amount),
 		}
-		// commit tx
-		if err := tx.Commit(); err != nil {
+		if err := s.commitPayment(ctx, tx, movements, sts); err != nil {
 			return nil, err
 		}
 		return &paymentpb.Statement{
@@ -484,123 +272,39 @@ func (s *PaymentService) CancelPayment(ctx context.Context, req *paymentpb.PaidR
 	if err != nil {
 		return nil, err
 	}
-	// Get customer
-	customer, err := s.client.GetAccountByID(ctx, &authpb.GetIDRequest{
-		Id: refPayment.Customer.String(),
-	})
-	// Get merchant
-	merchant, err := s.client.GetAccountByID(ctx, &authpb.GetIDRequest{
-		Id: refPayment.Merchant.String(),
-	})
-	// statements
-	sts := []*authpb.StatementRequest{}
-	if refPayment.Operation == "Authorization" && refPayment.Status == "Approved" {
+	// Get customer and merchant
+	accounts, err := getAccounts(ctx, s.client, refPayment.Customer.String(), refPayment.Merchant.String())
+	if err != nil {
+		return nil, err
+	}
+	customer, merchant := accounts[0], accounts[1]
+	if refPayment.Operation == "Authorization" && refPayment.Status == "Approved" && !refPayment.ParentId.Valid {
+		refPayment.Operation = "Cancel"
 		// Invalid amount
 		if refPayment.Amount < req.Amount {
-			refPayment.Operation = "Cancel"
-			completedPayment := types.CreateCompletePayment(req, refPayment, "Invalid amount")
-			invalidPayment, err := s.storage.SavePayment(ctx, completedPayment, tx)
-			if err != nil {
-				return nil, err
-			}
-			// send statements to auth service
-			sts = append(sts, &authpb.StatementRequest{
-				AccountId: merchant.Id,
-				PaymentId: completedPayment.PaymentId.String(),
-			})
-			if err := createStatement(ctx, s.client, sts); err != nil {
-				return nil, err
-			}
-			// commit tx
-			if err := tx.Commit(); err != nil {
-				return nil, err
-			}
-			return &paymentpb.Statement{
-				PaymentId: invalidPayment.PaymentId.String(),
-				Status:    invalidPayment.Status,
-			}, nil
+			return s.declinePayment(ctx, tx, types.CreateCompletePayment(req, refPayment, "Invalid amount"), merchant)
 		}
-		// Successful cancel
-		// update customer balance and append new statement
-		customer.Balance = customer.Balance + req.Amount
-		customer.BlockedMoney = customer.BlockedMoney - req.Amount
-		customer, err = s.client.UpdateBalance(ctx, &authpb.UpdateBalanceRequest{
-			Id:           customer.Id,
-			Balance:      customer.Balance,
-			BlockedMoney: customer.BlockedMoney,
-		})
+		// split payment is released for every seller
+		children, err := s.storage.GetChildPayments(ctx, refPayment.PaymentId)
 		if err != nil {
 			return nil, err
 		}
-
-		// update new merchant balance and append new statement
-		merchant.BlockedMoney = merchant.BlockedMoney - req.Amount
-
-		merchant, err = s.client.UpdateBalance(ctx, &authpb.UpdateBalanceRequest{
-			Id:      merchant.Id,
-			Balance: merchant.BlockedMoney,
-		})
-		if err != nil {
-			customer.Balance = customer.Balance - req.Amount
-			customer.BlockedMoney = customer.BlockedMoney + req.Amount
-			customer, _ = s.client.UpdateBalance(ctx, &authpb.UpdateBalanceRequest{
-				Id:           customer.Id,
-				Balance:      customer.Balance,
-				BlockedMoney: customer.BlockedMoney,
-			})
-			return nil, err
+		if len(children) > 0 {
+			return s.completeSplitPayment(ctx, tx, req, refPayment, children, customer, merchant,
+				"Successful cancel", splitCancel)
 		}
 		// make cancel
-		refPayment.Operation = "Cancel"
-		completedPayment := types.CreateCompletePayment(req, refPayment, "Successful cancel")
-		completedPayment, err = s.storage.SavePayment(ctx, completedPayment, tx)
+		completedPayment, err := s.storage.SavePayment(ctx, types.CreateCompletePayment(req, refPayment, "Successful cancel"), tx)
 		if err != nil {
-			customer.Balance = customer.Balance - req.Amount
-			customer.BlockedMoney = customer.BlockedMoney + req.Amount
-			customer, _ = s.client.UpdateBalance(ctx, &authpb.UpdateBalanceRequest{
-				Id:           customer.Id,
-				Balance:      customer.Balance,
-				BlockedMoney: customer.BlockedMoney,
-			})
-
-			merchant.BlockedMoney = merchant.BlockedMoney + req.Amount
-
-			merchant, _ = s.client.UpdateBalance(ctx, &authpb.UpdateBalanceRequest{
-				Id:      merchant.Id,
-				Balance: merchant.BlockedMoney,
-			})
 			return nil, err
 		}
-
-		sts = append(sts, &authpb.StatementRequest{
-			AccountId: customer.Id,
-			PaymentId: completedPayment.PaymentId.String(),
-		})
-
-		sts = append(sts, &authpb.StatementRequest{
-			AccountId: merchant.Id,
-			PaymentId: completedPayment.PaymentId.String(),
-		})
-		// send statements to auth service
-		if err := createStatement(ctx, s.client, sts); err != nil {
-			customer.Balance = customer.Balance - req.Amount
-			customer.BlockedMoney = customer.BlockedMoney + req.Amount
-			customer, _ = s.client.UpdateBalance(ctx, &authpb.UpdateBalanceRequest{
-				Id:           customer.Id,
-				Balance:      customer.Balance,
-				BlockedMoney: customer.BlockedMoney,
-			})
-
-			merchant.BlockedMoney = merchant.BlockedMoney + req.Amount
-
-			merchant, _ = s.client.UpdateBalance(ctx, &authpb.UpdateBalanceRequest{
-				Id:      merchant.Id,
-				Balance: merchant.BlockedMoney,
-			})
-			return nil, err
+		// Successful cancel
+		// the whole hold is released
+		sts := []*authpb.StatementRequest{
+			newStatement(customer.Id, completedPayment, int64(refPayment.Amount)),
+			newStatement(merchant.Id, completedPayment, 0),
 		}
-		// commit tx
-		if err := tx.Commit(); err != nil {
+		if err := s.commitRelease(ctx, tx, refPayment, nil, sts); err != nil {
 			return nil, err
 		}
 		return &paymentpb.Statement{
@@ -614,22 +318,224 @@ func (s *PaymentService) CancelPayment(ctx context.Context, req *paymentpb.PaidR
 	}, nil
 }
 
-func createStatement(ctx context.Context, client authpb.AuthServiceClient, sts []*authpb.StatementRequest) error {
-	stream, err := client.CreateStatement(ctx)
-	if err != nil {
-		return err
+func (s *PaymentService) SubscribePaymentEvents(req *paymentpb.SubscribeRequest, stream paymentpb.PaymentService_SubscribePaymentEventsServer) error {
+	if req.Merchant != "" {
+		if _, err := uuid.Parse(req.Merchant); err != nil {
+			return status.Error(codes.InvalidArgument, "invalid merchant id")
+		}
 	}
-	for _, statement := range sts {
-		if err := stream.Send(statement); err != nil {
-			return err
+	if req.Customer != "" {
+		if _, err := uuid.Parse(req.Customer); err != nil {
+			return status.Error(codes.InvalidArgument, "invalid customer id")
 		}
-		_, err := stream.Recv()
+	}
+	ticker := time.NewTicker(eventsPollInterval)
+	defer ticker.Stop()
+	// resume after the client cursor
+	cursor := req.Cursor
+	for {
+		events, err := s.storage.GetPaymentEvents(stream.Context(), &paymentpb.SubscribeRequest{
+			Merchant: req.Merchant,
+			Customer: req.Customer,
+			Cursor:   cursor,
+		}, eventsBatchSize)
 		if err != nil {
 			return err
 		}
+		for _, event := range events {
+			if err := stream.Send(eventToProto(event)); err != nil {
+				return status.Error(codes.Canceled, "Stream has ended")
+			}
+			cursor = event.Cursor
+		}
+		// full batch, there may be more events waiting
+		if len(events) == eventsBatchSize {
+			continue
+		}
+		select {
+		case <-stream.Context().Done():
+			return status.Error(codes.Canceled, "Stream has ended")
+		case <-ticker.C:
+		}
+	}
+}
+
+func eventToProto(event *types.PaymentEvent) *paymentpb.PaymentEvent {
+	parentID := ""
+	if event.ParentId.Valid {
+		parentID = event.ParentId.UUID.String()
+	}
+	return &paymentpb.PaymentEvent{
+		Cursor:    event.Cursor,
+		PaymentId: event.PaymentId.String(),
+		Merchant:  event.Merchant.String(),
+		Customer:  event.Customer.String(),
+		Currency:  event.Currency,
+		Operation: event.Operation,
+		Status:    event.Status,
+		Amount:    event.Amount,
+		CreatedAt: timestamppb.New(event.CreatedAt),
+		ParentId:  parentID,
+	}
+}
+
+// balance change of an account
+type movement struct {
+	account *authpb.Account
+	balance int64
+	escrow  int64
+}
+
+// change of the account balance made by the movements
+func statementAmount(movements []*movement, accountID string) int64 {
+	var change int64
+	for _, m := range movements {
+		if m.account.Id == accountID {
+			change += m.balance
+		}
+	}
+	return change
+}
+
+// accounts in one request, in order of the ids
+func getAccounts(ctx context.Context, client authpb.AuthServiceClient, ids ...string) ([]*authpb.Account, error) {
+	resp, err := client.GetAccountsByIDs(ctx, &authpb.GetIDsRequest{Ids: ids})
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*authpb.Account, len(resp.Accounts))
+	for _, account := range resp.Accounts {
+		byID[account.Id] = account
+	}
+	accounts := make([]*authpb.Account, 0, len(ids))
+	for _, id := range ids {
+		account, ok := byID[id]
+		if !ok {
+			return nil, status.Error(codes.NotFound, "account not found")
+		}
+		accounts = append(accounts, account)
 	}
-	if err := stream.CloseSend(); err != nil {
+	return accounts, nil
+}
+
+// balance changes and statements of the payment in one auth transaction,
+// the accounts of the movements are replaced by the updated ones
+func applyMovements(ctx context.Context, client authpb.AuthServiceClient, movements []*movement, sts []*authpb.StatementRequest) error {
+	if len(movements) == 0 && len(sts) == 0 {
+		return nil
+	}
+	req := &authpb.MovementsRequest{Statements: sts}
+	for _, m := range movements {
+		req.Movements = append(req.Movements, &authpb.Movement{
+			AccountId:   m.account.Id,
+			Balance:     m.balance,
+			EscrowMoney: m.escrow,
+		})
+	}
+	resp, err := client.ApplyPaymentMovements(ctx, req)
+	if err != nil {
 		return err
 	}
+	byID := make(map[string]*authpb.Account, len(resp.Accounts))
+	for _, account := range resp.Accounts {
+		byID[account.Id] = account
+	}
+	for _, m := range movements {
+		if account, ok := byID[m.account.Id]; ok {
+			m.account = account
+		}
+	}
 	return nil
 }
+
+// restore balances changed by applyMovements, statements are kept
+func revertBalances(ctx context.Context, client authpb.AuthServiceClient, movements []*movement) {
+	reverted := make([]*movement, 0, len(movements))
+	for _, m := range movements {
+		reverted = append(reverted, &movement{
+			account: m.account,
+			balance: -m.balance,
+			escrow:  -m.escrow,
+		})
+	}
+	applyMovements(ctx, client, reverted, nil)
+}
+
+// apply balance changes and statements of the saved payment and commit it,
+// balances are restored if the payment is not committed
+func (s *PaymentService) commitPayment(ctx context.Context, tx *sql.Tx, movements []*movement, sts []*authpb.StatementRequest) error {
+	if err := applyMovements(ctx, s.client, movements, sts); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		revertBalances(ctx, s.client, movements)
+		return err
+	}
+	return nil
+}
+
+// statement of the account with the details of the payment,
+// the counterparty is the other side of the payment
+func newStatement(accountID string, payment *types.Payment, amount int64) *authpb.StatementRequest {
+	counterparty := payment.Customer
+	if accountID == payment.Customer.String() {
+		counterparty = payment.Merchant
+	}
+	return &authpb.StatementRequest{
+		AccountId:      accountID,
+		PaymentId:      payment.PaymentId.String(),
+		Amount:         amount,
+		CounterpartyId: counterparty.String(),
+		Operation:      payment.Operation,
+		Status:         payment.Status,
+		Currency:       payment.Currency,
+	}
+}
+
+// statement entries read from auth service at once
+const statementPageSize = 1000
+
+// statement entries of the account booked in the period, zero time - the
+// period is open; at most limit entries, 0 - all.
+// Pages are read until the last one is not full
+func statementEntries(ctx context.Context, client authpb.AuthServiceClient, accountID string, from, to time.Time, limit int) ([]*authpb.Statement, error) {
+	entries := []*authpb.Statement{}
+	cursor := uint64(0)
+	for {
+		size := statementPageSize
+		if limit > 0 && limit-len(entries) < size {
+			size = limit - len(entries)
+		}
+		req := &authpb.StatementGet{
+			AccountId: accountID,
+			Cursor:    cursor,
+			Limit:     uint32(size),
+		}
+		if !from.IsZero() {
+			req.From = timestamppb.New(from)
+		}
+		if !to.IsZero() {
+			req.To = timestamppb.New(to)
+		}
+		stream, err := client.GetStatement(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		count := 0
+		for {
+			statement, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, statement)
+			cursor = statement.Cursor
+			count++
+		}
+		if count < size || len(entries) == limit {
+			return entries, nil
+		}
+	}
+}
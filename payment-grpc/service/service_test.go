@@ -9,6 +9,7 @@ import (
 	"github.com/DATA-DOG/go-sqlmock"
 	mock_proto "github.com/Edbeer/payment-proto/auth-grpc/client/mock"
 	authpb "github.com/Edbeer/payment-proto/auth-grpc/proto"
+	mockevents "github.com/Edbeer/payment-proto/payment-grpc/client/mock"
 
 	paymentpb "github.com/Edbeer/payment-proto/payment-grpc/proto"
 	mockpay "github.com/Edbeer/payment-grpc/service/mock"
@@ -18,6 +19,8 @@ import (
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
@@ -35,7 +38,7 @@ func Test_CreatePayment(t *testing.T) {
 		storagePay := mockpay.NewMockStorage(ctrl)
 		clientAuth := mock_proto.NewMockAuthServiceClient(ctrl)
 
-		servicePay := NewPaymentService(storagePay, clientAuth, db)
+		servicePay := NewPaymentService(storagePay, clientAuth, db, nil)
 		req := &paymentpb.CreateRequest{
 			Merchant:         uuid.New().String(),
 			Customer:         uuid.New().String(),
@@ -82,37 +85,12 @@ func Test_CreatePayment(t *testing.T) {
 		}
 
 		mock.ExpectBegin()
-		clientAuth.EXPECT().GetAccountByID(gomock.Any(), gomock.Any()).
-			DoAndReturn(
-				func(ctx context.Context, req *authpb.GetIDRequest, opts ...grpc.CallOption) (*authpb.Account, error) {
-					if req.Id == reqIDC.Id {
-						return customer, nil
-					}
-					if req.Id == reqIDM.Id {
-						return merchant, nil
-					}
-					return nil, fmt.Errorf("not found")
-				},
-			).AnyTimes()
+		clientAuth.EXPECT().GetAccountsByIDs(gomock.Any(), &authpb.GetIDsRequest{
+			Ids: []string{reqIDC.Id, reqIDM.Id},
+		}).Return(&authpb.Accounts{Accounts: []*authpb.Account{customer, merchant}}, nil).AnyTimes()
 
 		sts := []*authpb.StatementRequest{}
 
-		customer.Balance = customer.Balance - req.Amount
-		customer.BlockedMoney = customer.BlockedMoney + req.Amount
-		merchant.BlockedMoney = merchant.BlockedMoney + req.Amount
-
-		clientAuth.EXPECT().UpdateBalance(gomock.Any(), gomock.Any()).DoAndReturn(
-			func(ctx context.Context, req *authpb.UpdateBalanceRequest, opts ...grpc.CallOption) (*authpb.Account, error) {
-				if req.Id == customer.Id {
-					return customer, nil
-				}
-				if req.Id == merchant.Id {
-					return merchant, nil
-				}
-				return nil, fmt.Errorf("not found")
-			},
-		).AnyTimes()
-
 		payment := types.CreateAuthPayment(req, customer, merchant, "Approved")
 		storagePay.EXPECT().SavePayment(context.Background(), gomock.Any(), gomock.Any()).Return(payment, nil).AnyTimes()
 
@@ -125,21 +103,22 @@ func Test_CreatePayment(t *testing.T) {
 			PaymentId: payment.PaymentId.String(),
 		})
 
-		streamSts := mock_proto.NewMockAuthService_CreateStatementClient(ctrl)
-		clientAuth.EXPECT().CreateStatement(context.Background()).Return(streamSts, nil).AnyTimes()
-		streamSts.EXPECT().Send(gomock.Any()).DoAndReturn(
-			func(req *authpb.StatementRequest) error {
-				if req.AccountId == customer.Id {
-					return nil
+		// amount of the payment is held on the customer account
+		holdID := uuid.New()
+		clientAuth.EXPECT().CreateHold(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, req *authpb.CreateHoldRequest, opts ...grpc.CallOption) (*authpb.Hold, error) {
+				if req.AccountId != customer.Id || req.Amount != 50 || req.Reference != payment.PaymentId.String() {
+					return nil, fmt.Errorf("wrong hold")
 				}
-				if req.AccountId == merchant.Id {
-					return nil
+				for _, st := range req.Statements {
+					if st.AccountId != customer.Id && st.AccountId != merchant.Id {
+						return nil, fmt.Errorf("not found")
+					}
 				}
-				return fmt.Errorf("not found")
+				return &authpb.Hold{Id: holdID.String(), AccountId: customer.Id, Amount: req.Amount}, nil
 			},
-		).AnyTimes()
-		streamSts.EXPECT().Recv().Return(&authpb.StatementResponse{}, nil).AnyTimes()
-		streamSts.EXPECT().CloseSend().Return(nil).AnyTimes()
+		)
+		storagePay.EXPECT().UpdatePaymentHold(context.Background(), payment.PaymentId, holdID, gomock.Any()).Return(nil)
 		mock.ExpectCommit()
 		st, err := servicePay.CreatePayment(context.Background(), req)
 		require.NoError(t, err)
@@ -152,7 +131,7 @@ func Test_CreatePayment(t *testing.T) {
 		storagePay := mockpay.NewMockStorage(ctrl)
 		clientAuth := mock_proto.NewMockAuthServiceClient(ctrl)
 
-		servicePay := NewPaymentService(storagePay, clientAuth, db)
+		servicePay := NewPaymentService(storagePay, clientAuth, db, nil)
 		req := &paymentpb.CreateRequest{
 			Merchant:         uuid.New().String(),
 			Customer:         uuid.New().String(),
@@ -202,18 +181,9 @@ func Test_CreatePayment(t *testing.T) {
 
 		mock.ExpectBegin()
 
-		clientAuth.EXPECT().GetAccountByID(gomock.Any(), gomock.Any()).
-			DoAndReturn(
-				func(ctx context.Context, req *authpb.GetIDRequest, opts ...grpc.CallOption) (*authpb.Account, error) {
-					if req.Id == reqIDC.Id {
-						return customer, nil
-					}
-					if req.Id == reqIDM.Id {
-						return merchant, nil
-					}
-					return nil, fmt.Errorf("not found")
-				},
-			).AnyTimes()
+		clientAuth.EXPECT().GetAccountsByIDs(gomock.Any(), &authpb.GetIDsRequest{
+			Ids: []string{reqIDC.Id, reqIDM.Id},
+		}).Return(&authpb.Accounts{Accounts: []*authpb.Account{customer, merchant}}, nil).AnyTimes()
 
 		payment := types.CreateAuthPayment(req, customer, merchant, "wrong payment request")
 		storagePay.EXPECT().SavePayment(context.Background(), gomock.Any(), gomock.Any()).Return(payment, nil).AnyTimes()
@@ -224,14 +194,16 @@ func Test_CreatePayment(t *testing.T) {
 			PaymentId: payment.PaymentId.String(),
 		})
 
-		streamSts := mock_proto.NewMockAuthService_CreateStatementClient(ctrl)
-		clientAuth.EXPECT().CreateStatement(context.Background()).Return(streamSts, nil).AnyTimes()
-		streamSts.EXPECT().Send(&authpb.StatementRequest{
-			AccountId: merchant.Id,
-			PaymentId: payment.PaymentId.String(),
-		}).Return(nil).AnyTimes()
-		streamSts.EXPECT().Recv().Return(&authpb.StatementResponse{}, nil).AnyTimes()
-		streamSts.EXPECT().CloseSend().Return(nil).AnyTimes()
+		clientAuth.EXPECT().ApplyPaymentMovements(gomock.Any(), &authpb.MovementsRequest{
+			Statements: []*authpb.StatementRequest{{
+					AccountId:      merchant.Id,
+					PaymentId:      payment.PaymentId.String(),
+					CounterpartyId: customer.Id,
+					Operation:      "Authorization",
+					Status:         "wrong payment request",
+					Currency:       req.Currency,
+			}},
+		}).Return(&authpb.Accounts{}, nil).AnyTimes()
 		mock.ExpectCommit()
 		st, err := servicePay.CreatePayment(context.Background(), req)
 		require.NoError(t, err)
@@ -244,7 +216,7 @@ func Test_CreatePayment(t *testing.T) {
 		storagePay := mockpay.NewMockStorage(ctrl)
 		clientAuth := mock_proto.NewMockAuthServiceClient(ctrl)
 
-		servicePay := NewPaymentService(storagePay, clientAuth, db)
+		servicePay := NewPaymentService(storagePay, clientAuth, db, nil)
 
 		req := &paymentpb.CreateRequest{
 			Merchant:         uuid.New().String(),
@@ -293,18 +265,9 @@ func Test_CreatePayment(t *testing.T) {
 
 		mock.ExpectBegin()
 
-		clientAuth.EXPECT().GetAccountByID(gomock.Any(), gomock.Any()).
-			DoAndReturn(
-				func(ctx context.Context, req *authpb.GetIDRequest, opts ...grpc.CallOption) (*authpb.Account, error) {
-					if req.Id == reqIDC.Id {
-						return customer, nil
-					}
-					if req.Id == reqIDM.Id {
-						return merchant, nil
-					}
-					return nil, fmt.Errorf("not found")
-				},
-			).AnyTimes()
+		clientAuth.EXPECT().GetAccountsByIDs(gomock.Any(), &authpb.GetIDsRequest{
+			Ids: []string{reqIDC.Id, reqIDM.Id},
+		}).Return(&authpb.Accounts{Accounts: []*authpb.Account{customer, merchant}}, nil).AnyTimes()
 
 		payment := types.CreateAuthPayment(req, customer, merchant, "Insufficient funds")
 		storagePay.EXPECT().SavePayment(context.Background(), gomock.Any(), gomock.Any()).Return(payment, nil).AnyTimes()
@@ -315,14 +278,16 @@ func Test_CreatePayment(t *testing.T) {
 			PaymentId: payment.PaymentId.String(),
 		})
 
-		streamSts := mock_proto.NewMockAuthService_CreateStatementClient(ctrl)
-		clientAuth.EXPECT().CreateStatement(context.Background()).Return(streamSts, nil).AnyTimes()
-		streamSts.EXPECT().Send(&authpb.StatementRequest{
-			AccountId: merchant.Id,
-			PaymentId: payment.PaymentId.String(),
-		}).Return(nil).AnyTimes()
-		streamSts.EXPECT().Recv().Return(&authpb.StatementResponse{}, nil).AnyTimes()
-		streamSts.EXPECT().CloseSend().Return(nil).AnyTimes()
+		clientAuth.EXPECT().ApplyPaymentMovements(gomock.Any(), &authpb.MovementsRequest{
+			Statements: []*authpb.StatementRequest{{
+					AccountId:      merchant.Id,
+					PaymentId:      payment.PaymentId.String(),
+					CounterpartyId: customer.Id,
+					Operation:      "Authorization",
+					Status:         "Insufficient funds",
+					Currency:       req.Currency,
+			}},
+		}).Return(&authpb.Accounts{}, nil).AnyTimes()
 		mock.ExpectCommit()
 		st, err := servicePay.CreatePayment(context.Background(), req)
 		require.NoError(t, err)
@@ -346,7 +311,7 @@ func Test_CapturePayment(t *testing.T) {
 		storagePay := mockpay.NewMockStorage(ctrl)
 		clientAuth := mock_proto.NewMockAuthServiceClient(ctrl)
 
-		servicePay := NewPaymentService(storagePay, clientAuth, db)
+		servicePay := NewPaymentService(storagePay, clientAuth, db, nil)
 
 		req := &paymentpb.PaidRequest{
 			PaymentId: uuid.New().String(),
@@ -366,6 +331,7 @@ func Test_CapturePayment(t *testing.T) {
 			Status:          "Approved",
 			Amount:          50,
 			CreatedAt:       time.Now(),
+			HoldId:          uuid.NullUUID{UUID: uuid.New(), Valid: true},
 		}
 
 		mock.ExpectBegin()
@@ -405,40 +371,15 @@ func Test_CapturePayment(t *testing.T) {
 			CreatedAt:        timestamppb.Now(),
 		}
 
-		clientAuth.EXPECT().GetAccountByID(gomock.Any(), gomock.Any()).
-			DoAndReturn(
-				func(ctx context.Context, req *authpb.GetIDRequest, opts ...grpc.CallOption) (*authpb.Account, error) {
-					if req.Id == reqIDC.Id {
-						return customer, nil
-					}
-					if req.Id == reqIDM.Id {
-						return merchant, nil
-					}
-					return nil, fmt.Errorf("not found")
-				},
-			).AnyTimes()
+		clientAuth.EXPECT().GetAccountsByIDs(gomock.Any(), &authpb.GetIDsRequest{
+			Ids: []string{reqIDC.Id, reqIDM.Id},
+		}).Return(&authpb.Accounts{Accounts: []*authpb.Account{customer, merchant}}, nil).AnyTimes()
 
+		// not a split payment
+		storagePay.EXPECT().GetChildPayments(context.Background(), refPayment.PaymentId).Return([]*types.Payment{}, nil)
 		newPayment := types.CreateCompletePayment(req, refPayment, "Successful payment")
 		storagePay.EXPECT().SavePayment(context.Background(), gomock.Any(), gomock.Any()).Return(newPayment, nil).AnyTimes()
 
-		// update balance
-		customer.BlockedMoney = customer.BlockedMoney - req.Amount
-
-		merchant.Balance = merchant.Balance + req.Amount
-		merchant.BlockedMoney = merchant.BlockedMoney - req.Amount
-
-		clientAuth.EXPECT().UpdateBalance(gomock.Any(), gomock.Any()).DoAndReturn(
-			func(ctx context.Context, req *authpb.UpdateBalanceRequest, opts ...grpc.CallOption) (*authpb.Account, error) {
-				if req.Id == customer.Id {
-					return customer, nil
-				}
-				if req.Id == merchant.Id {
-					return merchant, nil
-				}
-				return nil, fmt.Errorf("not found")
-			},
-		).AnyTimes()
-
 		sts := []*authpb.StatementRequest{}
 
 		sts = append(sts, &authpb.StatementRequest{
@@ -450,21 +391,21 @@ func Test_CapturePayment(t *testing.T) {
 			PaymentId: newPayment.PaymentId.String(),
 		})
 
-		streamSts := mock_proto.NewMockAuthService_CreateStatementClient(ctrl)
-		clientAuth.EXPECT().CreateStatement(context.Background()).Return(streamSts, nil).AnyTimes()
-		streamSts.EXPECT().Send(gomock.Any()).DoAndReturn(
-			func(req *authpb.StatementRequest) error {
-				if req.AccountId == customer.Id {
-					return nil
+		// held money of the customer goes to the merchant
+		clientAuth.EXPECT().CaptureHold(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, req *authpb.CaptureHoldRequest, opts ...grpc.CallOption) (*authpb.Hold, error) {
+				if req.HoldId != refPayment.HoldId.UUID.String() || len(req.Transfers) != 1 ||
+					req.Transfers[0].AccountId != merchant.Id || req.Transfers[0].Amount != 50 {
+					return nil, fmt.Errorf("wrong capture")
 				}
-				if req.AccountId == merchant.Id {
-					return nil
+				for _, st := range req.Statements {
+					if st.AccountId != customer.Id && st.AccountId != merchant.Id {
+						return nil, fmt.Errorf("not found")
+					}
 				}
-				return fmt.Errorf("not found")
+				return &authpb.Hold{Id: req.HoldId, State: "captured"}, nil
 			},
-		).AnyTimes()
-		streamSts.EXPECT().Recv().Return(&authpb.StatementResponse{}, nil).AnyTimes()
-		streamSts.EXPECT().CloseSend().Return(nil).AnyTimes()
+		)
 		mock.ExpectCommit()
 		st, err := servicePay.CapturePayment(context.Background(), req)
 		require.NoError(t, err)
@@ -477,7 +418,7 @@ func Test_CapturePayment(t *testing.T) {
 		storagePay := mockpay.NewMockStorage(ctrl)
 		clientAuth := mock_proto.NewMockAuthServiceClient(ctrl)
 
-		servicePay := NewPaymentService(storagePay, clientAuth, db)
+		servicePay := NewPaymentService(storagePay, clientAuth, db, nil)
 
 		req := &paymentpb.PaidRequest{
 			PaymentId: uuid.New().String(),
@@ -536,18 +477,9 @@ func Test_CapturePayment(t *testing.T) {
 			CreatedAt:        timestamppb.Now(),
 		}
 
-		clientAuth.EXPECT().GetAccountByID(gomock.Any(), gomock.Any()).
-			DoAndReturn(
-				func(ctx context.Context, req *authpb.GetIDRequest, opts ...grpc.CallOption) (*authpb.Account, error) {
-					if req.Id == reqIDC.Id {
-						return customer, nil
-					}
-					if req.Id == reqIDM.Id {
-						return merchant, nil
-					}
-					return nil, fmt.Errorf("not found")
-				},
-			).AnyTimes()
+		clientAuth.EXPECT().GetAccountsByIDs(gomock.Any(), &authpb.GetIDsRequest{
+			Ids: []string{reqIDC.Id, reqIDM.Id},
+		}).Return(&authpb.Accounts{Accounts: []*authpb.Account{customer, merchant}}, nil).AnyTimes()
 
 		newPayment := types.CreateCompletePayment(req, refPayment, "Invalid amount")
 		storagePay.EXPECT().SavePayment(context.Background(), gomock.Any(), gomock.Any()).Return(newPayment, nil).AnyTimes()
@@ -557,11 +489,7 @@ func Test_CapturePayment(t *testing.T) {
 			PaymentId: newPayment.PaymentId.String(),
 		})
 
-		streamSts := mock_proto.NewMockAuthService_CreateStatementClient(ctrl)
-		clientAuth.EXPECT().CreateStatement(context.Background()).Return(streamSts, nil).AnyTimes()
-		streamSts.EXPECT().Send(gomock.Any()).Return(nil).AnyTimes()
-		streamSts.EXPECT().Recv().Return(&authpb.StatementResponse{}, nil).AnyTimes()
-		streamSts.EXPECT().CloseSend().Return(nil).AnyTimes()
+		clientAuth.EXPECT().ApplyPaymentMovements(gomock.Any(), gomock.Any()).Return(&authpb.Accounts{}, nil).AnyTimes()
 		mock.ExpectCommit()
 
 		st, err := servicePay.CapturePayment(context.Background(), req)
@@ -586,7 +514,7 @@ func Test_RefundPayment(t *testing.T) {
 		storagePay := mockpay.NewMockStorage(ctrl)
 		clientAuth := mock_proto.NewMockAuthServiceClient(ctrl)
 
-		servicePay := NewPaymentService(storagePay, clientAuth, db)
+		servicePay := NewPaymentService(storagePay, clientAuth, db, nil)
 
 		req := &paymentpb.PaidRequest{
 			PaymentId: uuid.New().String(),
@@ -645,19 +573,12 @@ func Test_RefundPayment(t *testing.T) {
 			CreatedAt:        timestamppb.Now(),
 		}
 
-		clientAuth.EXPECT().GetAccountByID(gomock.Any(), gomock.Any()).
-			DoAndReturn(
-				func(ctx context.Context, req *authpb.GetIDRequest, opts ...grpc.CallOption) (*authpb.Account, error) {
-					if req.Id == reqIDC.Id {
-						return customer, nil
-					}
-					if req.Id == reqIDM.Id {
-						return merchant, nil
-					}
-					return nil, fmt.Errorf("not found")
-				},
-			).AnyTimes()
+		clientAuth.EXPECT().GetAccountsByIDs(gomock.Any(), &authpb.GetIDsRequest{
+			Ids: []string{reqIDC.Id, reqIDM.Id},
+		}).Return(&authpb.Accounts{Accounts: []*authpb.Account{customer, merchant}}, nil).AnyTimes()
 
+		// not a split payment
+		storagePay.EXPECT().GetChildPayments(context.Background(), refPayment.PaymentId).Return([]*types.Payment{}, nil)
 		newPayment := types.CreateCompletePayment(req, refPayment, "Successful refund")
 		storagePay.EXPECT().SavePayment(context.Background(), gomock.Any(), gomock.Any()).Return(newPayment, nil).AnyTimes()
 
@@ -666,18 +587,6 @@ func Test_RefundPayment(t *testing.T) {
 
 		merchant.Balance = merchant.Balance - req.Amount
 
-		clientAuth.EXPECT().UpdateBalance(gomock.Any(), gomock.Any()).DoAndReturn(
-			func(ctx context.Context, req *authpb.UpdateBalanceRequest, opts ...grpc.CallOption) (*authpb.Account, error) {
-				if req.Id == customer.Id {
-					return customer, nil
-				}
-				if req.Id == merchant.Id {
-					return merchant, nil
-				}
-				return nil, fmt.Errorf("not found")
-			},
-		).AnyTimes()
-
 		sts := []*authpb.StatementRequest{}
 
 		sts = append(sts, &authpb.StatementRequest{
@@ -689,21 +598,16 @@ func Test_RefundPayment(t *testing.T) {
 			PaymentId: newPayment.PaymentId.String(),
 		})
 
-		streamSts := mock_proto.NewMockAuthService_CreateStatementClient(ctrl)
-		clientAuth.EXPECT().CreateStatement(context.Background()).Return(streamSts, nil).AnyTimes()
-		streamSts.EXPECT().Send(gomock.Any()).DoAndReturn(
-			func(req *authpb.StatementRequest) error {
-				if req.AccountId == customer.Id {
-					return nil
-				}
-				if req.AccountId == merchant.Id {
-					return nil
+		clientAuth.EXPECT().ApplyPaymentMovements(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, req *authpb.MovementsRequest, opts ...grpc.CallOption) (*authpb.Accounts, error) {
+				for _, st := range req.Statements {
+					if st.AccountId != customer.Id && st.AccountId != merchant.Id {
+						return nil, fmt.Errorf("not found")
+					}
 				}
-				return fmt.Errorf("not found")
+				return &authpb.Accounts{Accounts: []*authpb.Account{customer, merchant}}, nil
 			},
 		).AnyTimes()
-		streamSts.EXPECT().Recv().Return(&authpb.StatementResponse{}, nil).AnyTimes()
-		streamSts.EXPECT().CloseSend().Return(nil).AnyTimes()
 		mock.ExpectCommit()
 
 		st, err := servicePay.RefundPayment(context.Background(), req)
@@ -717,7 +621,7 @@ func Test_RefundPayment(t *testing.T) {
 		storagePay := mockpay.NewMockStorage(ctrl)
 		clientAuth := mock_proto.NewMockAuthServiceClient(ctrl)
 
-		servicePay := NewPaymentService(storagePay, clientAuth, db)
+		servicePay := NewPaymentService(storagePay, clientAuth, db, nil)
 
 		req := &paymentpb.PaidRequest{
 			PaymentId: uuid.New().String(),
@@ -776,18 +680,9 @@ func Test_RefundPayment(t *testing.T) {
 			CreatedAt:        timestamppb.Now(),
 		}
 
-		clientAuth.EXPECT().GetAccountByID(gomock.Any(), gomock.Any()).
-			DoAndReturn(
-				func(ctx context.Context, req *authpb.GetIDRequest, opts ...grpc.CallOption) (*authpb.Account, error) {
-					if req.Id == reqIDC.Id {
-						return customer, nil
-					}
-					if req.Id == reqIDM.Id {
-						return merchant, nil
-					}
-					return nil, fmt.Errorf("not found")
-				},
-			).AnyTimes()
+		clientAuth.EXPECT().GetAccountsByIDs(gomock.Any(), &authpb.GetIDsRequest{
+			Ids: []string{reqIDC.Id, reqIDM.Id},
+		}).Return(&authpb.Accounts{Accounts: []*authpb.Account{customer, merchant}}, nil).AnyTimes()
 
 		newPayment := types.CreateCompletePayment(req, refPayment, "Invalid amount")
 		storagePay.EXPECT().SavePayment(context.Background(), gomock.Any(), gomock.Any()).Return(newPayment, nil).AnyTimes()
@@ -797,11 +692,7 @@ func Test_RefundPayment(t *testing.T) {
 			PaymentId: newPayment.PaymentId.String(),
 		})
 
-		streamSts := mock_proto.NewMockAuthService_CreateStatementClient(ctrl)
-		clientAuth.EXPECT().CreateStatement(context.Background()).Return(streamSts, nil).AnyTimes()
-		streamSts.EXPECT().Send(gomock.Any()).Return(nil).AnyTimes()
-		streamSts.EXPECT().Recv().Return(&authpb.StatementResponse{}, nil).AnyTimes()
-		streamSts.EXPECT().CloseSend().Return(nil).AnyTimes()
+		clientAuth.EXPECT().ApplyPaymentMovements(gomock.Any(), gomock.Any()).Return(&authpb.Accounts{}, nil).AnyTimes()
 		mock.ExpectCommit()
 
 		st, err := servicePay.RefundPayment(context.Background(), req)
@@ -826,7 +717,7 @@ func Test_CancelPayment(t *testing.T) {
 		storagePay := mockpay.NewMockStorage(ctrl)
 		clientAuth := mock_proto.NewMockAuthServiceClient(ctrl)
 
-		servicePay := NewPaymentService(storagePay, clientAuth, db)
+		servicePay := NewPaymentService(storagePay, clientAuth, db, nil)
 
 		req := &paymentpb.PaidRequest{
 			PaymentId: uuid.New().String(),
@@ -846,6 +737,7 @@ func Test_CancelPayment(t *testing.T) {
 			Status:          "Approved",
 			Amount:          50,
 			CreatedAt:       time.Now(),
+			HoldId:          uuid.NullUUID{UUID: uuid.New(), Valid: true},
 		}
 
 		mock.ExpectBegin()
@@ -885,40 +777,15 @@ func Test_CancelPayment(t *testing.T) {
 			CreatedAt:        timestamppb.Now(),
 		}
 
-		clientAuth.EXPECT().GetAccountByID(gomock.Any(), gomock.Any()).
-			DoAndReturn(
-				func(ctx context.Context, req *authpb.GetIDRequest, opts ...grpc.CallOption) (*authpb.Account, error) {
-					if req.Id == reqIDC.Id {
-						return customer, nil
-					}
-					if req.Id == reqIDM.Id {
-						return merchant, nil
-					}
-					return nil, fmt.Errorf("not found")
-				},
-			).AnyTimes()
+		clientAuth.EXPECT().GetAccountsByIDs(gomock.Any(), &authpb.GetIDsRequest{
+			Ids: []string{reqIDC.Id, reqIDM.Id},
+		}).Return(&authpb.Accounts{Accounts: []*authpb.Account{customer, merchant}}, nil).AnyTimes()
 
+		// not a split payment
+		storagePay.EXPECT().GetChildPayments(context.Background(), refPayment.PaymentId).Return([]*types.Payment{}, nil)
 		newPayment := types.CreateCompletePayment(req, refPayment, "Successful cancel")
 		storagePay.EXPECT().SavePayment(context.Background(), gomock.Any(), gomock.Any()).Return(newPayment, nil).AnyTimes()
 
-		// update balance
-		customer.Balance = customer.Balance + req.Amount
-		customer.BlockedMoney = customer.BlockedMoney - req.Amount
-
-		merchant.BlockedMoney = merchant.BlockedMoney - req.Amount
-
-		clientAuth.EXPECT().UpdateBalance(gomock.Any(), gomock.Any()).DoAndReturn(
-			func(ctx context.Context, req *authpb.UpdateBalanceRequest, opts ...grpc.CallOption) (*authpb.Account, error) {
-				if req.Id == customer.Id {
-					return customer, nil
-				}
-				if req.Id == merchant.Id {
-					return merchant, nil
-				}
-				return nil, fmt.Errorf("not found")
-			},
-		).AnyTimes()
-
 		sts := []*authpb.StatementRequest{}
 
 		sts = append(sts, &authpb.StatementRequest{
@@ -930,21 +797,20 @@ func Test_CancelPayment(t *testing.T) {
 			PaymentId: newPayment.PaymentId.String(),
 		})
 
-		streamSts := mock_proto.NewMockAuthService_CreateStatementClient(ctrl)
-		clientAuth.EXPECT().CreateStatement(context.Background()).Return(streamSts, nil).AnyTimes()
-		streamSts.EXPECT().Send(gomock.Any()).DoAndReturn(
-			func(req *authpb.StatementRequest) error {
-				if req.AccountId == customer.Id {
-					return nil
+		// the hold is released
+		clientAuth.EXPECT().ReleaseHold(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, req *authpb.ReleaseHoldRequest, opts ...grpc.CallOption) (*authpb.Hold, error) {
+				if req.HoldId != refPayment.HoldId.UUID.String() {
+					return nil, fmt.Errorf("wrong hold")
 				}
-				if req.AccountId == merchant.Id {
-					return nil
+				for _, st := range req.Statements {
+					if st.AccountId != customer.Id && st.AccountId != merchant.Id {
+						return nil, fmt.Errorf("not found")
+					}
 				}
-				return fmt.Errorf("not found")
+				return &authpb.Hold{Id: req.HoldId, State: "released"}, nil
 			},
-		).AnyTimes()
-		streamSts.EXPECT().Recv().Return(&authpb.StatementResponse{}, nil).AnyTimes()
-		streamSts.EXPECT().CloseSend().Return(nil).AnyTimes()
+		)
 
 		mock.ExpectCommit()
 
@@ -959,7 +825,7 @@ func Test_CancelPayment(t *testing.T) {
 		storagePay := mockpay.NewMockStorage(ctrl)
 		clientAuth := mock_proto.NewMockAuthServiceClient(ctrl)
 
-		servicePay := NewPaymentService(storagePay, clientAuth, db)
+		servicePay := NewPaymentService(storagePay, clientAuth, db, nil)
 
 		req := &paymentpb.PaidRequest{
 			PaymentId: uuid.New().String(),
@@ -1018,18 +884,9 @@ func Test_CancelPayment(t *testing.T) {
 			CreatedAt:        timestamppb.Now(),
 		}
 
-		clientAuth.EXPECT().GetAccountByID(gomock.Any(), gomock.Any()).
-			DoAndReturn(
-				func(ctx context.Context, req *authpb.GetIDRequest, opts ...grpc.CallOption) (*authpb.Account, error) {
-					if req.Id == reqIDC.Id {
-						return customer, nil
-					}
-					if req.Id == reqIDM.Id {
-						return merchant, nil
-					}
-					return nil, fmt.Errorf("not found")
-				},
-			).AnyTimes()
+		clientAuth.EXPECT().GetAccountsByIDs(gomock.Any(), &authpb.GetIDsRequest{
+			Ids: []string{reqIDC.Id, reqIDM.Id},
+		}).Return(&authpb.Accounts{Accounts: []*authpb.Account{customer, merchant}}, nil).AnyTimes()
 
 		newPayment := types.CreateCompletePayment(req, refPayment, "Invalid amount")
 		storagePay.EXPECT().SavePayment(context.Background(), gomock.Any(), gomock.Any()).Return(newPayment, nil).AnyTimes()
@@ -1039,11 +896,7 @@ func Test_CancelPayment(t *testing.T) {
 			PaymentId: newPayment.PaymentId.String(),
 		})
 
-		streamSts := mock_proto.NewMockAuthService_CreateStatementClient(ctrl)
-		clientAuth.EXPECT().CreateStatement(context.Background()).Return(streamSts, nil).AnyTimes()
-		streamSts.EXPECT().Send(gomock.Any()).Return(nil).AnyTimes()
-		streamSts.EXPECT().Recv().Return(&authpb.StatementResponse{}, nil).AnyTimes()
-		streamSts.EXPECT().CloseSend().Return(nil).AnyTimes()
+		clientAuth.EXPECT().ApplyPaymentMovements(gomock.Any(), gomock.Any()).Return(&authpb.Accounts{}, nil).AnyTimes()
 
 		mock.ExpectCommit()
 
@@ -1054,3 +907,129 @@ func Test_CancelPayment(t *testing.T) {
 		require.Equal(t, st.Status, "Invalid amount")
 	})
 }
+
+func Test_SubscribePaymentEvents(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	t.Run("Resume from cursor", func(t *testing.T) {
+		storagePay := mockpay.NewMockStorage(ctrl)
+		clientAuth := mock_proto.NewMockAuthServiceClient(ctrl)
+
+		servicePay := NewPaymentService(storagePay, clientAuth, db, nil)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		merchant := uuid.New()
+		req := &paymentpb.SubscribeRequest{
+			Merchant: merchant.String(),
+			Cursor:   10,
+		}
+		events := []*types.PaymentEvent{
+			{
+				Cursor:    11,
+				PaymentId: uuid.New(),
+				Merchant:  merchant,
+				Customer:  uuid.New(),
+				Currency:  "rub",
+				Operation: "Authorization",
+				Status:    "Approved",
+				Amount:    50,
+				CreatedAt: time.Now(),
+			},
+			{
+				Cursor:    12,
+				PaymentId: uuid.New(),
+				Merchant:  merchant,
+				Customer:  uuid.New(),
+				Currency:  "rub",
+				Operation: "Capture",
+				Status:    "Successful payment",
+				Amount:    50,
+				CreatedAt: time.Now(),
+			},
+		}
+
+		gomock.InOrder(
+			storagePay.EXPECT().GetPaymentEvents(ctx, gomock.Any(), eventsBatchSize).DoAndReturn(
+				func(ctx context.Context, req *paymentpb.SubscribeRequest, limit int) ([]*types.PaymentEvent, error) {
+					require.Equal(t, uint64(10), req.Cursor)
+					require.Equal(t, merchant.String(), req.Merchant)
+					return events, nil
+				},
+			),
+			storagePay.EXPECT().GetPaymentEvents(ctx, gomock.Any(), eventsBatchSize).DoAndReturn(
+				func(ctx context.Context, req *paymentpb.SubscribeRequest, limit int) ([]*types.PaymentEvent, error) {
+					// next poll continues after the last sent event
+					require.Equal(t, uint64(12), req.Cursor)
+					cancel()
+					return []*types.PaymentEvent{}, nil
+				},
+			),
+		)
+
+		sent := []uint64{}
+		stream := mockevents.NewMockPaymentService_SubscribePaymentEventsServer(ctrl)
+		stream.EXPECT().Context().Return(ctx).AnyTimes()
+		stream.EXPECT().Send(gomock.Any()).DoAndReturn(
+			func(event *paymentpb.PaymentEvent) error {
+				sent = append(sent, event.Cursor)
+				return nil
+			},
+		).Times(2)
+
+		err := servicePay.SubscribePaymentEvents(req, stream)
+		require.Error(t, err)
+		require.Equal(t, codes.Canceled, status.Code(err))
+		require.Equal(t, []uint64{11, 12}, sent)
+	})
+
+	t.Run("Invalid merchant", func(t *testing.T) {
+		storagePay := mockpay.NewMockStorage(ctrl)
+		clientAuth := mock_proto.NewMockAuthServiceClient(ctrl)
+
+		servicePay := NewPaymentService(storagePay, clientAuth, db, nil)
+
+		stream := mockevents.NewMockPaymentService_SubscribePaymentEventsServer(ctrl)
+		err := servicePay.SubscribePaymentEvents(&paymentpb.SubscribeRequest{
+			Merchant: "merchant",
+		}, stream)
+		require.Error(t, err)
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func Test_NewStatement(t *testing.T) {
+	t.Parallel()
+
+	payment := &types.Payment{
+		PaymentId: uuid.New(),
+		Merchant:  uuid.New(),
+		Customer:  uuid.New(),
+		Currency:  "rub",
+		Operation: "Refund",
+		Status:    "Successful refund",
+		Amount:    40,
+	}
+	customer := newStatement(payment.Customer.String(), payment, 40)
+	require.Equal(t, &authpb.StatementRequest{
+		AccountId:      payment.Customer.String(),
+		PaymentId:      payment.PaymentId.String(),
+		Amount:         40,
+		CounterpartyId: payment.Merchant.String(),
+		Operation:      "Refund",
+		Status:         "Successful refund",
+		Currency:       "rub",
+	}, customer)
+
+	merchant := newStatement(payment.Merchant.String(), payment, -40)
+	require.Equal(t, payment.Customer.String(), merchant.CounterpartyId)
+	require.Equal(t, int64(-40), merchant.Amount)
+}
@@ -0,0 +1,288 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"math/bits"
+
+	"github.com/Edbeer/payment-grpc/types"
+	authpb "github.com/Edbeer/payment-proto/auth-grpc/proto"
+	paymentpb "github.com/Edbeer/payment-proto/payment-grpc/proto"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// 100% in basis points
+const fullPercent = 10000
+
+// completion of a split payment
+type splitOperation int
+
+const (
+	// held money of the customer is paid to the parties
+	splitCapture splitOperation = iota
+	// parties return money to the customer
+	splitRefund
+	// held money is released
+	splitCancel
+)
+
+func (s *PaymentService) CreateSplitPayment(ctx context.Context, req *paymentpb.SplitRequest) (*paymentpb.Statement, error) {
+	shares, err := splitShares(req)
+	if err != nil {
+		return nil, err
+	}
+	// Begin Tx
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	// get customer, platform and sellers
+	ids := []string{req.Customer, req.Platform}
+	for _, split := range req.Splits {
+		ids = append(ids, split.Merchant)
+	}
+	accounts, err := getAccounts(ctx, s.client, ids...)
+	if err != nil {
+		return nil, err
+	}
+	customer, platform, sellers := accounts[0], accounts[1], accounts[2:]
+	// the whole payment is authorized for the platform
+	authReq := &paymentpb.CreateRequest{
+		Merchant:         req.Platform,
+		Customer:         req.Customer,
+		CardNumber:       req.CardNumber,
+		CardExpiryMonth:  req.CardExpiryMonth,
+		CardExpiryYear:   req.CardExpiryYear,
+		CardSecurityCode: req.CardSecurityCode,
+		Currency:         req.Currency,
+		Amount:           req.Amount,
+	}
+	// check payment request
+	if req.CardNumber != customer.CardNumber ||
+		req.CardExpiryMonth != customer.CardExpiryMonth ||
+		req.CardExpiryYear != customer.CardExpiryYear ||
+		req.CardSecurityCode != customer.CardSecurityCode {
+		payment := types.CreateAuthPayment(authReq, customer, platform, "wrong payment request")
+		return s.declinePayment(ctx, tx, payment, platform)
+	}
+	// balance < req amount
+	if customer.Balance < req.Amount {
+		payment := types.CreateAuthPayment(authReq, customer, platform, "Insufficient funds")
+		return s.declinePayment(ctx, tx, payment, platform)
+	}
+	// hold the whole amount of the customer, parties are paid on capture
+	movements := []*movement{
+		{account: customer, balance: -int64(req.Amount)},
+		{account: platform},
+	}
+	for _, seller := range sellers {
+		movements = append(movements, &movement{account: seller})
+	}
+	// create new payment with a part for every seller
+	payment := types.CreateAuthPayment(authReq, customer, platform, "Approved")
+	savedPayment, parts, err := s.saveSplitPayment(ctx, tx, payment, sellers, shares)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.commitHold(ctx, tx, savedPayment, splitStatements(savedPayment, parts, movements)); err != nil {
+		return nil, err
+	}
+	return &paymentpb.Statement{
+		PaymentId: savedPayment.PaymentId.String(),
+		Status:    savedPayment.Status,
+	}, nil
+}
+
+// capture, refund or cancel of a split payment:
+// the amount is distributed between the parts proportionally to their shares
+func (s *PaymentService) completeSplitPayment(
+	ctx context.Context, tx *sql.Tx,
+	req *paymentpb.PaidRequest, refPayment *types.Payment, children []*types.Payment,
+	customer, platform *authpb.Account,
+	paymentStatus string, operation splitOperation,
+) (*paymentpb.Statement, error) {
+	portions := distribute(req.Amount, refPayment.Amount, children)
+	// get sellers
+	ids := make([]string, 0, len(children))
+	for _, child := range children {
+		ids = append(ids, child.Merchant.String())
+	}
+	sellers, err := getAccounts(ctx, s.client, ids...)
+	if err != nil {
+		return nil, err
+	}
+	amount := int64(req.Amount)
+	// capture returns the rest of the hold to the customer
+	customerChange, partySign := int64(refPayment.Amount)-amount, int64(1)
+	switch operation {
+	case splitRefund:
+		customerChange, partySign = amount, -1
+	case splitCancel:
+		customerChange, partySign = int64(refPayment.Amount), 0
+	}
+	platformPortion := int64(req.Amount - sum(portions))
+	movements := []*movement{
+		{account: customer, balance: customerChange},
+		{account: platform, balance: partySign * platformPortion},
+	}
+	for i, seller := range sellers {
+		movements = append(movements, &movement{account: seller, balance: partySign * int64(portions[i])})
+	}
+	// make complete payment with a part for every seller
+	completedPayment := types.CreateCompletePayment(req, refPayment, paymentStatus)
+	savedPayment, parts, err := s.saveSplitPayment(ctx, tx, completedPayment, sellers, portions)
+	if err != nil {
+		return nil, err
+	}
+	sts := splitStatements(savedPayment, parts, movements)
+	switch operation {
+	case splitCapture:
+		err = s.commitCapture(ctx, tx, refPayment, savedPayment, movements[1:], sts)
+	case splitCancel:
+		err = s.commitRelease(ctx, tx, refPayment, nil, sts)
+	default:
+		err = s.commitPayment(ctx, tx, movements, sts)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &paymentpb.Statement{
+		PaymentId: savedPayment.PaymentId.String(),
+		Status:    savedPayment.Status,
+	}, nil
+}
+
+// save declined payment with the statement for the merchant
+func (s *PaymentService) declinePayment(ctx context.Context, tx *sql.Tx, payment *types.Payment, merchant *authpb.Account) (*paymentpb.Statement, error) {
+	savedPayment, err := s.storage.SavePayment(ctx, payment, tx)
+	if err != nil {
+		return nil, err
+	}
+	sts := []*authpb.StatementRequest{newStatement(merchant.Id, savedPayment, 0)}
+	if err := s.commitPayment(ctx, tx, nil, sts); err != nil {
+		return nil, err
+	}
+	return &paymentpb.Statement{
+		PaymentId: savedPayment.PaymentId.String(),
+		Status:    savedPayment.Status,
+	}, nil
+}
+
+// save the payment and a linked part for every seller
+func (s *PaymentService) saveSplitPayment(ctx context.Context, tx *sql.Tx, payment *types.Payment, sellers []*authpb.Account, amounts []uint64) (*types.Payment, []*types.Payment, error) {
+	savedPayment, err := s.storage.SavePayment(ctx, payment, tx)
+	if err != nil {
+		return nil, nil, err
+	}
+	parts := make([]*types.Payment, 0, len(sellers))
+	for i, seller := range sellers {
+		mid, err := uuid.Parse(seller.Id)
+		if err != nil {
+			return nil, nil, err
+		}
+		part, err := s.storage.SavePayment(ctx, types.CreateSplitPayment(savedPayment, mid, amounts[i]), tx)
+		if err != nil {
+			return nil, nil, err
+		}
+		parts = append(parts, part)
+	}
+	return savedPayment, parts, nil
+}
+
+// statements for the customer and the platform with the whole payment,
+// for every seller with its part, movements are
+// of the customer, the platform and the sellers in order of the parts
+func splitStatements(payment *types.Payment, parts []*types.Payment, movements []*movement) []*authpb.StatementRequest {
+	sts := []*authpb.StatementRequest{
+		newStatement(movements[0].account.Id, payment, movements[0].balance),
+		newStatement(movements[1].account.Id, payment, movements[1].balance),
+	}
+	for i, part := range parts {
+		sts = append(sts, newStatement(part.Merchant.String(), part, movements[i+2].balance))
+	}
+	return sts
+}
+
+// shares of the sellers, the rest of the amount goes to the platform
+// and must cover the platform fee
+func splitShares(req *paymentpb.SplitRequest) ([]uint64, error) {
+	if len(req.Splits) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "split payment without splits")
+	}
+	if req.PlatformFee != 0 && req.PlatformFeePercent != 0 {
+		return nil, status.Error(codes.InvalidArgument, "platform fee is either fixed or percentage")
+	}
+	if req.PlatformFeePercent > fullPercent {
+		return nil, status.Error(codes.InvalidArgument, "invalid platform fee")
+	}
+	// every account takes part in the payment once
+	accounts := map[string]bool{req.Customer: true, req.Platform: true}
+	shares := make([]uint64, 0, len(req.Splits))
+	var total uint64
+	for _, split := range req.Splits {
+		if _, err := uuid.Parse(split.Merchant); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid merchant id")
+		}
+		if accounts[split.Merchant] {
+			return nil, status.Error(codes.InvalidArgument, "duplicate split merchant")
+		}
+		accounts[split.Merchant] = true
+
+		switch {
+		case split.Amount != 0 && split.Percent == 0:
+			shares = append(shares, split.Amount)
+		case split.Amount == 0 && split.Percent != 0 && split.Percent <= fullPercent:
+			shares = append(shares, percentOf(req.Amount, split.Percent))
+		default:
+			return nil, status.Error(codes.InvalidArgument, "split share is either fixed or percentage")
+		}
+		share := shares[len(shares)-1]
+		if share > req.Amount-total {
+			return nil, status.Error(codes.InvalidArgument, "split shares exceed the payment amount")
+		}
+		total += share
+	}
+	fee := req.PlatformFee
+	if req.PlatformFeePercent != 0 {
+		fee = percentOf(req.Amount, req.PlatformFeePercent)
+	}
+	if req.Amount-total < fee {
+		return nil, status.Error(codes.InvalidArgument, "split shares leave no room for the platform fee")
+	}
+	return shares, nil
+}
+
+// distribute amount between the parts proportionally to their share of total,
+// the rounding remainder is left to the platform
+func distribute(amount, total uint64, parts []*types.Payment) []uint64 {
+	portions := make([]uint64, 0, len(parts))
+	for _, part := range parts {
+		portions = append(portions, mulDiv(part.Amount, amount, total))
+	}
+	return portions
+}
+
+func percentOf(amount uint64, percent uint32) uint64 {
+	return mulDiv(amount, uint64(percent), fullPercent)
+}
+
+// a * b / c without overflow, the quotient must fit uint64
+func mulDiv(a, b, c uint64) uint64 {
+	if c == 0 {
+		return 0
+	}
+	hi, lo := bits.Mul64(a, b)
+	q, _ := bits.Div64(hi, lo, c)
+	return q
+}
+
+func sum(amounts []uint64) uint64 {
+	var total uint64
+	for _, amount := range amounts {
+		total += amount
+	}
+	return total
+}
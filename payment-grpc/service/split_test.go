@@ -0,0 +1,574 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	mock_proto "github.com/Edbeer/payment-proto/auth-grpc/client/mock"
+	authpb "github.com/Edbeer/payment-proto/auth-grpc/proto"
+	paymentpb "github.com/Edbeer/payment-proto/payment-grpc/proto"
+	mockpay "github.com/Edbeer/payment-grpc/service/mock"
+
+	"github.com/Edbeer/payment-grpc/types"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func newSplitAccount(balance, blocked uint64) *authpb.Account {
+	return &authpb.Account{
+		Id:               uuid.New().String(),
+		FirstName:        "Pasha",
+		LastName:         "Volkov",
+		CardNumber:       "4444444444444444",
+		CardExpiryMonth:  "12",
+		CardExpiryYear:   "24",
+		CardSecurityCode: "999",
+		Balance:          balance,
+		BlockedMoney:     blocked,
+		Statement:        []string{},
+		CreatedAt:        timestamppb.Now(),
+	}
+}
+
+// holds of the mock auth clients by id
+var mockHolds sync.Map
+
+// approved authorization with its amount already held on the customer account
+func heldPayment(payment *types.Payment) *types.Payment {
+	hold := &authpb.Hold{
+		Id:        uuid.New().String(),
+		AccountId: payment.Customer.String(),
+		Amount:    payment.Amount,
+		State:     "active",
+		Reference: payment.PaymentId.String(),
+	}
+	mockHolds.Store(hold.Id, hold)
+	payment.HoldId = uuid.NullUUID{UUID: uuid.MustParse(hold.Id), Valid: true}
+	return payment
+}
+
+// mock auth client keeping the accounts, movements and holds are applied to the accounts
+// and the updated accounts are returned by id
+func mockSplitAccounts(ctrl *gomock.Controller, accounts ...*authpb.Account) (*mock_proto.MockAuthServiceClient, map[string]*authpb.Account) {
+	clientAuth := mock_proto.NewMockAuthServiceClient(ctrl)
+	// concurrent payments share the accounts
+	mu := sync.Mutex{}
+	byID := map[string]*authpb.Account{}
+	for _, account := range accounts {
+		byID[account.Id] = account
+	}
+	clientAuth.EXPECT().GetAccountByID(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, req *authpb.GetIDRequest, opts ...grpc.CallOption) (*authpb.Account, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			account, ok := byID[req.Id]
+			if !ok {
+				return nil, fmt.Errorf("not found")
+			}
+			return account, nil
+		},
+	).AnyTimes()
+	clientAuth.EXPECT().GetAccountsByIDs(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, req *authpb.GetIDsRequest, opts ...grpc.CallOption) (*authpb.Accounts, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			resp := &authpb.Accounts{}
+			for _, id := range req.Ids {
+				if account, ok := byID[id]; ok {
+					resp.Accounts = append(resp.Accounts, account)
+				}
+			}
+			return resp, nil
+		},
+	).AnyTimes()
+
+	updates := map[string]*authpb.Account{}
+	clientAuth.EXPECT().ApplyPaymentMovements(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, req *authpb.MovementsRequest, opts ...grpc.CallOption) (*authpb.Accounts, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			resp := &authpb.Accounts{}
+			for _, m := range req.Movements {
+				account, ok := byID[m.AccountId]
+				if !ok {
+					return nil, fmt.Errorf("not found")
+				}
+				// the card is kept for the next payments
+				updated := proto.Clone(account).(*authpb.Account)
+				updated.Balance = uint64(int64(account.Balance) + m.Balance)
+				updated.EscrowMoney = uint64(int64(account.EscrowMoney) + m.EscrowMoney)
+				byID[m.AccountId] = updated
+				updates[m.AccountId] = updated
+				resp.Accounts = append(resp.Accounts, updated)
+			}
+			return resp, nil
+		},
+	).AnyTimes()
+
+	// change money of the account, called with mu held
+	move := func(id string, balance, blocked, escrow int64) error {
+		account, ok := byID[id]
+		if !ok {
+			return fmt.Errorf("not found")
+		}
+		if int64(account.Balance)+balance < 0 {
+			return status.Error(codes.FailedPrecondition, "insufficient funds")
+		}
+		updated := proto.Clone(account).(*authpb.Account)
+		updated.Balance = uint64(int64(account.Balance) + balance)
+		updated.BlockedMoney = uint64(int64(account.BlockedMoney) + blocked)
+		updated.EscrowMoney = uint64(int64(account.EscrowMoney) + escrow)
+		byID[id] = updated
+		updates[id] = updated
+		return nil
+	}
+	activeHold := func(id string) (*authpb.Hold, error) {
+		hold, ok := mockHolds.Load(id)
+		if !ok || hold.(*authpb.Hold).State != "active" {
+			return nil, status.Error(codes.FailedPrecondition, "hold is not active")
+		}
+		return hold.(*authpb.Hold), nil
+	}
+	clientAuth.EXPECT().CreateHold(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, req *authpb.CreateHoldRequest, opts ...grpc.CallOption) (*authpb.Hold, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if err := move(req.AccountId, -int64(req.Amount), int64(req.Amount), 0); err != nil {
+				return nil, err
+			}
+			hold := &authpb.Hold{
+				Id:        uuid.New().String(),
+				AccountId: req.AccountId,
+				Amount:    req.Amount,
+				State:     "active",
+				Reference: req.Reference,
+			}
+			mockHolds.Store(hold.Id, hold)
+			return hold, nil
+		},
+	).AnyTimes()
+	clientAuth.EXPECT().CaptureHold(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, req *authpb.CaptureHoldRequest, opts ...grpc.CallOption) (*authpb.Hold, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			hold, err := activeHold(req.HoldId)
+			if err != nil {
+				return nil, err
+			}
+			var captured uint64
+			for _, transfer := range req.Transfers {
+				captured += transfer.Amount
+			}
+			if captured > hold.Amount {
+				return nil, status.Error(codes.FailedPrecondition, "invalid hold amount")
+			}
+			move(hold.AccountId, int64(hold.Amount-captured), -int64(hold.Amount), 0)
+			for _, transfer := range req.Transfers {
+				if transfer.Escrow {
+					move(transfer.AccountId, 0, 0, int64(transfer.Amount))
+				} else {
+					move(transfer.AccountId, int64(transfer.Amount), 0, 0)
+				}
+			}
+			hold.CapturedAmount = captured
+			hold.State = "captured"
+			return hold, nil
+		},
+	).AnyTimes()
+	clientAuth.EXPECT().ReleaseHold(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, req *authpb.ReleaseHoldRequest, opts ...grpc.CallOption) (*authpb.Hold, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			hold, err := activeHold(req.HoldId)
+			if err != nil {
+				return nil, err
+			}
+			move(hold.AccountId, int64(hold.Amount), -int64(hold.Amount), 0)
+			hold.State = "released"
+			return hold, nil
+		},
+	).AnyTimes()
+	clientAuth.EXPECT().ChangeHold(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, req *authpb.ChangeHoldRequest, opts ...grpc.CallOption) (*authpb.Hold, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			hold, err := activeHold(req.HoldId)
+			if err != nil {
+				return nil, err
+			}
+			if err := move(hold.AccountId, -req.Amount, req.Amount, 0); err != nil {
+				return nil, err
+			}
+			hold.Amount = uint64(int64(hold.Amount) + req.Amount)
+			return hold, nil
+		},
+	).AnyTimes()
+	return clientAuth, updates
+}
+
+func Test_CreateSplitPayment(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	t.Run("Approved", func(t *testing.T) {
+		customer := newSplitAccount(150, 0)
+		platform := newSplitAccount(0, 0)
+		seller1 := newSplitAccount(0, 0)
+		seller2 := newSplitAccount(0, 5)
+		clientAuth, updates := mockSplitAccounts(ctrl, customer, platform, seller1, seller2)
+		storagePay := mockpay.NewMockStorage(ctrl)
+
+		servicePay := NewPaymentService(storagePay, clientAuth, db, nil)
+		req := &paymentpb.SplitRequest{
+			Platform:         platform.Id,
+			Customer:         customer.Id,
+			CardNumber:       customer.CardNumber,
+			CardExpiryMonth:  customer.CardExpiryMonth,
+			CardExpiryYear:   customer.CardExpiryYear,
+			CardSecurityCode: customer.CardSecurityCode,
+			Currency:         "rub",
+			Amount:           100,
+			Splits: []*paymentpb.Split{
+				{Merchant: seller1.Id, Amount: 30},
+				{Merchant: seller2.Id, Percent: 5000},
+			},
+			PlatformFee: 10,
+		}
+
+		saved := []*types.Payment{}
+		storagePay.EXPECT().SavePayment(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, payment *types.Payment, tx *sql.Tx) (*types.Payment, error) {
+				saved = append(saved, payment)
+				return payment, nil
+			},
+		).Times(3)
+		storagePay.EXPECT().UpdatePaymentHold(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+
+		mock.ExpectBegin()
+		mock.ExpectCommit()
+		st, err := servicePay.CreateSplitPayment(context.Background(), req)
+		require.NoError(t, err)
+		require.Equal(t, "Approved", st.Status)
+		require.Equal(t, saved[0].PaymentId.String(), st.PaymentId)
+
+		// customer amount is held, parties wait for capture
+		require.Equal(t, uint64(50), updates[customer.Id].Balance)
+		require.Equal(t, uint64(100), updates[customer.Id].BlockedMoney)
+		require.NotContains(t, updates, platform.Id)
+		require.NotContains(t, updates, seller1.Id)
+		require.NotContains(t, updates, seller2.Id)
+
+		// parts are linked to the whole payment
+		require.Len(t, saved, 3)
+		require.Equal(t, platform.Id, saved[0].Merchant.String())
+		require.False(t, saved[0].ParentId.Valid)
+		for _, part := range saved[1:] {
+			require.Equal(t, saved[0].PaymentId, part.ParentId.UUID)
+			require.Equal(t, "Authorization", part.Operation)
+		}
+		require.Equal(t, uint64(30), saved[1].Amount)
+		require.Equal(t, uint64(50), saved[2].Amount)
+	})
+
+	t.Run("Shares exceed amount", func(t *testing.T) {
+		storagePay := mockpay.NewMockStorage(ctrl)
+		clientAuth := mock_proto.NewMockAuthServiceClient(ctrl)
+
+		servicePay := NewPaymentService(storagePay, clientAuth, db, nil)
+		_, err := servicePay.CreateSplitPayment(context.Background(), &paymentpb.SplitRequest{
+			Platform: uuid.New().String(),
+			Customer: uuid.New().String(),
+			Amount:   100,
+			Splits: []*paymentpb.Split{
+				{Merchant: uuid.New().String(), Amount: 60},
+				{Merchant: uuid.New().String(), Percent: 5000},
+			},
+		})
+		require.Error(t, err)
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("Platform fee not covered", func(t *testing.T) {
+		storagePay := mockpay.NewMockStorage(ctrl)
+		clientAuth := mock_proto.NewMockAuthServiceClient(ctrl)
+
+		servicePay := NewPaymentService(storagePay, clientAuth, db, nil)
+		_, err := servicePay.CreateSplitPayment(context.Background(), &paymentpb.SplitRequest{
+			Platform: uuid.New().String(),
+			Customer: uuid.New().String(),
+			Amount:   100,
+			Splits: []*paymentpb.Split{
+				{Merchant: uuid.New().String(), Percent: 9500},
+			},
+			PlatformFeePercent: 1000,
+		})
+		require.Error(t, err)
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func Test_CaptureSplitPayment(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	customer := newSplitAccount(0, 100)
+	platform := newSplitAccount(0, 0)
+	seller1 := newSplitAccount(0, 0)
+	seller2 := newSplitAccount(0, 0)
+	clientAuth, updates := mockSplitAccounts(ctrl, customer, platform, seller1, seller2)
+	storagePay := mockpay.NewMockStorage(ctrl)
+
+	servicePay := NewPaymentService(storagePay, clientAuth, db, nil)
+
+	refPayment := heldPayment(&types.Payment{
+		PaymentId: uuid.New(),
+		Merchant:  uuid.MustParse(platform.Id),
+		Customer:  uuid.MustParse(customer.Id),
+		Currency:  "rub",
+		Operation: "Authorization",
+		Status:    "Approved",
+		Amount:    100,
+		CreatedAt: time.Now(),
+	})
+	children := []*types.Payment{
+		types.CreateSplitPayment(refPayment, uuid.MustParse(seller1.Id), 30),
+		types.CreateSplitPayment(refPayment, uuid.MustParse(seller2.Id), 50),
+	}
+	req := &paymentpb.PaidRequest{
+		PaymentId: refPayment.PaymentId.String(),
+		Amount:    55,
+	}
+
+	storagePay.EXPECT().GetPaymentByID(context.Background(), req).Return(refPayment, nil)
+	storagePay.EXPECT().GetChildPayments(context.Background(), refPayment.PaymentId).Return(children, nil)
+	saved := []*types.Payment{}
+	storagePay.EXPECT().SavePayment(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, payment *types.Payment, tx *sql.Tx) (*types.Payment, error) {
+			saved = append(saved, payment)
+			return payment, nil
+		},
+	).Times(3)
+
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+	st, err := servicePay.CapturePayment(context.Background(), req)
+	require.NoError(t, err)
+	require.Equal(t, "Successful payment", st.Status)
+
+	// 55 of 100: 16 (floor of 16.5) and 27 (floor of 27.5), the rest to the platform,
+	// the rest of the hold returns to the customer
+	require.Equal(t, uint64(45), updates[customer.Id].Balance)
+	require.Equal(t, uint64(0), updates[customer.Id].BlockedMoney)
+	require.Equal(t, uint64(16), updates[seller1.Id].Balance)
+	require.Equal(t, uint64(27), updates[seller2.Id].Balance)
+	require.Equal(t, uint64(12), updates[platform.Id].Balance)
+
+	require.Len(t, saved, 3)
+	require.Equal(t, "Capture", saved[0].Operation)
+	require.Equal(t, saved[0].PaymentId, saved[1].ParentId.UUID)
+	require.Equal(t, "Capture", saved[1].Operation)
+}
+
+func Test_RefundSplitPayment(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// captured split payment of 100: 30 and 50 to the sellers, 20 to the platform
+	refund := func(amount uint64) (map[string]*authpb.Account, []*authpb.Account, []*types.Payment) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		customer := newSplitAccount(0, 0)
+		platform := newSplitAccount(20, 0)
+		seller1 := newSplitAccount(30, 0)
+		seller2 := newSplitAccount(50, 0)
+		clientAuth, updates := mockSplitAccounts(ctrl, customer, platform, seller1, seller2)
+		storagePay := mockpay.NewMockStorage(ctrl)
+		servicePay := NewPaymentService(storagePay, clientAuth, db, nil)
+
+		refPayment := &types.Payment{
+			PaymentId: uuid.New(),
+			Merchant:  uuid.MustParse(platform.Id),
+			Customer:  uuid.MustParse(customer.Id),
+			Currency:  "rub",
+			Operation: "Capture",
+			Status:    "Successful payment",
+			Amount:    100,
+			CreatedAt: time.Now(),
+		}
+		children := []*types.Payment{
+			types.CreateSplitPayment(refPayment, uuid.MustParse(seller1.Id), 30),
+			types.CreateSplitPayment(refPayment, uuid.MustParse(seller2.Id), 50),
+		}
+		req := &paymentpb.PaidRequest{
+			PaymentId: refPayment.PaymentId.String(),
+			Amount:    amount,
+		}
+
+		storagePay.EXPECT().GetPaymentByID(context.Background(), req).Return(refPayment, nil)
+		storagePay.EXPECT().GetChildPayments(context.Background(), refPayment.PaymentId).Return(children, nil)
+		saved := []*types.Payment{}
+		storagePay.EXPECT().SavePayment(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, payment *types.Payment, tx *sql.Tx) (*types.Payment, error) {
+				saved = append(saved, payment)
+				return payment, nil
+			},
+		).Times(3)
+
+		mock.ExpectBegin()
+		mock.ExpectCommit()
+		st, err := servicePay.RefundPayment(context.Background(), req)
+		require.NoError(t, err)
+		require.Equal(t, "Successful refund", st.Status)
+		require.NoError(t, mock.ExpectationsWereMet())
+		return updates, []*authpb.Account{customer, platform, seller1, seller2}, saved
+	}
+
+	t.Run("Full refund", func(t *testing.T) {
+		updates, accounts, saved := refund(100)
+		customer, platform, seller1, seller2 := accounts[0], accounts[1], accounts[2], accounts[3]
+
+		// every party returns its whole part
+		require.Equal(t, uint64(100), updates[customer.Id].Balance)
+		require.Equal(t, uint64(0), updates[platform.Id].Balance)
+		require.Equal(t, uint64(0), updates[seller1.Id].Balance)
+		require.Equal(t, uint64(0), updates[seller2.Id].Balance)
+
+		require.Len(t, saved, 3)
+		require.Equal(t, "Refund", saved[0].Operation)
+		require.Equal(t, uint64(100), saved[0].Amount)
+		require.Equal(t, saved[0].PaymentId, saved[1].ParentId.UUID)
+		require.Equal(t, uint64(30), saved[1].Amount)
+		require.Equal(t, uint64(50), saved[2].Amount)
+	})
+
+	t.Run("Partial refund", func(t *testing.T) {
+		updates, accounts, saved := refund(33)
+		customer, platform, seller1, seller2 := accounts[0], accounts[1], accounts[2], accounts[3]
+
+		// 33 of 100: 9 (floor of 9.9) and 16 (floor of 16.5),
+		// the rounding remainder of 8 is returned by the platform
+		require.Equal(t, uint64(33), updates[customer.Id].Balance)
+		require.Equal(t, uint64(21), updates[seller1.Id].Balance)
+		require.Equal(t, uint64(34), updates[seller2.Id].Balance)
+		require.Equal(t, uint64(12), updates[platform.Id].Balance)
+
+		require.Len(t, saved, 3)
+		require.Equal(t, uint64(33), saved[0].Amount)
+		require.Equal(t, uint64(9), saved[1].Amount)
+		require.Equal(t, uint64(16), saved[2].Amount)
+	})
+}
+
+func Test_CancelSplitPayment(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	customer := newSplitAccount(0, 100)
+	platform := newSplitAccount(0, 0)
+	seller1 := newSplitAccount(0, 0)
+	seller2 := newSplitAccount(0, 0)
+	clientAuth, updates := mockSplitAccounts(ctrl, customer, platform, seller1, seller2)
+	storagePay := mockpay.NewMockStorage(ctrl)
+
+	servicePay := NewPaymentService(storagePay, clientAuth, db, nil)
+
+	refPayment := heldPayment(&types.Payment{
+		PaymentId: uuid.New(),
+		Merchant:  uuid.MustParse(platform.Id),
+		Customer:  uuid.MustParse(customer.Id),
+		Currency:  "rub",
+		Operation: "Authorization",
+		Status:    "Approved",
+		Amount:    100,
+		CreatedAt: time.Now(),
+	})
+	children := []*types.Payment{
+		types.CreateSplitPayment(refPayment, uuid.MustParse(seller1.Id), 30),
+		types.CreateSplitPayment(refPayment, uuid.MustParse(seller2.Id), 50),
+	}
+	req := &paymentpb.PaidRequest{
+		PaymentId: refPayment.PaymentId.String(),
+		Amount:    100,
+	}
+
+	storagePay.EXPECT().GetPaymentByID(context.Background(), req).Return(refPayment, nil)
+	storagePay.EXPECT().GetChildPayments(context.Background(), refPayment.PaymentId).Return(children, nil)
+	saved := []*types.Payment{}
+	storagePay.EXPECT().SavePayment(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, payment *types.Payment, tx *sql.Tx) (*types.Payment, error) {
+			saved = append(saved, payment)
+			return payment, nil
+		},
+	).Times(3)
+
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+	st, err := servicePay.CancelPayment(context.Background(), req)
+	require.NoError(t, err)
+	require.Equal(t, "Successful cancel", st.Status)
+
+	// the hold returns to the customer, the sellers and the platform get nothing
+	require.Equal(t, uint64(100), updates[customer.Id].Balance)
+	require.Equal(t, uint64(0), updates[customer.Id].BlockedMoney)
+	require.NotContains(t, updates, platform.Id)
+	require.NotContains(t, updates, seller1.Id)
+	require.NotContains(t, updates, seller2.Id)
+	hold, ok := mockHolds.Load(refPayment.HoldId.UUID.String())
+	require.True(t, ok)
+	require.Equal(t, "released", hold.(*authpb.Hold).State)
+
+	require.Len(t, saved, 3)
+	require.Equal(t, "Cancel", saved[0].Operation)
+	require.Equal(t, saved[0].PaymentId, saved[1].ParentId.UUID)
+	require.Equal(t, "Cancel", saved[2].Operation)
+}
+
+func Test_distribute(t *testing.T) {
+	t.Parallel()
+
+	parent := &types.Payment{PaymentId: uuid.New(), Amount: 3}
+	parts := []*types.Payment{
+		types.CreateSplitPayment(parent, uuid.New(), 1),
+		types.CreateSplitPayment(parent, uuid.New(), 1),
+	}
+	require.Equal(t, []uint64{0, 0}, distribute(1, 3, parts))
+	require.Equal(t, []uint64{1, 1}, distribute(3, 3, parts))
+
+	huge := uint64(1) << 62
+	parent.Amount = huge
+	parts = []*types.Payment{types.CreateSplitPayment(parent, uuid.New(), huge/2)}
+	require.Equal(t, []uint64{huge / 4}, distribute(huge/2, huge, parts))
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/Edbeer/payment-grpc/types"
+	authpb "github.com/Edbeer/payment-proto/auth-grpc/proto"
+	paymentpb "github.com/Edbeer/payment-proto/payment-grpc/proto"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *PaymentService) Transfer(ctx context.Context, req *paymentpb.TransferRequest) (*paymentpb.Statement, error) {
+	senderID, err := uuid.Parse(req.Sender)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid sender id")
+	}
+	if _, err := uuid.Parse(req.Receiver); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid receiver id")
+	}
+	if req.Sender == req.Receiver {
+		return nil, status.Error(codes.InvalidArgument, "sender and receiver are the same account")
+	}
+	if req.Amount == 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid amount")
+	}
+	if req.IdempotencyKey == "" {
+		return nil, status.Error(codes.InvalidArgument, "idempotency key is required")
+	}
+	// repeated request returns the first result
+	transfer, err := s.storage.GetTransferByKey(ctx, senderID, req.IdempotencyKey)
+	if err == nil {
+		return s.repeatedTransfer(ctx, req, transfer)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	// Begin Tx
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	// get sender and receiver
+	accounts, err := getAccounts(ctx, s.client, req.Sender, req.Receiver)
+	if err != nil {
+		return nil, err
+	}
+	sender, receiver := accounts[0], accounts[1]
+	// balance < req amount
+	if sender.Balance < req.Amount {
+		savedPayment, err := s.saveTransfer(ctx, tx, req, "Insufficient funds")
+		if errors.Is(err, types.ErrDuplicateTransfer) {
+			return s.concurrentTransfer(ctx, senderID, req)
+		}
+		if err != nil {
+			return nil, err
+		}
+		sts := []*authpb.StatementRequest{newStatement(sender.Id, savedPayment, 0)}
+		if err := s.commitPayment(ctx, tx, nil, sts); err != nil {
+			return nil, err
+		}
+		return &paymentpb.Statement{
+			PaymentId: savedPayment.PaymentId.String(),
+			Status:    savedPayment.Status,
+		}, nil
+	}
+	// the key is reserved before the money moves,
+	// concurrent request with the same key fails on the unique index
+	savedPayment, err := s.saveTransfer(ctx, tx, req, "Successful transfer")
+	if errors.Is(err, types.ErrDuplicateTransfer) {
+		return s.concurrentTransfer(ctx, senderID, req)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// debit sender and credit receiver in one auth transaction,
+	// the sender balance is checked again under the account lock
+	amount := int64(req.Amount)
+	movements := []*movement{
+		{account: sender, balance: -amount},
+		{account: receiver, balance: amount},
+	}
+	sts := []*authpb.StatementRequest{
+		newStatement(sender.Id, savedPayment, -amount),
+		newStatement(receiver.Id, savedPayment, amount),
+	}
+	if err := s.commitPayment(ctx, tx, movements, sts); err != nil {
+		return nil, err
+	}
+	return &paymentpb.Statement{
+		PaymentId: savedPayment.PaymentId.String(),
+		Status:    savedPayment.Status,
+	}, nil
+}
+
+// result of the saved transfer with the key of the request,
+// the key can't be reused for another transfer
+func (s *PaymentService) repeatedTransfer(ctx context.Context, req *paymentpb.TransferRequest, transfer *types.Transfer) (*paymentpb.Statement, error) {
+	payment, err := s.storage.GetPaymentByID(ctx, &paymentpb.PaidRequest{
+		PaymentId: transfer.PaymentId.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if transfer.Receiver.String() != req.Receiver ||
+		payment.Amount != req.Amount ||
+		payment.Currency != req.Currency {
+		return nil, status.Error(codes.InvalidArgument, "idempotency key is used by another transfer")
+	}
+	return &paymentpb.Statement{
+		PaymentId: payment.PaymentId.String(),
+		Status:    payment.Status,
+	}, nil
+}
+
+// result of the request with the same key committed first
+func (s *PaymentService) concurrentTransfer(ctx context.Context, senderID uuid.UUID, req *paymentpb.TransferRequest) (*paymentpb.Statement, error) {
+	transfer, err := s.storage.GetTransferByKey(ctx, senderID, req.IdempotencyKey)
+	if err != nil {
+		return nil, err
+	}
+	return s.repeatedTransfer(ctx, req, transfer)
+}
+
+// save transfer payment with its details
+func (s *PaymentService) saveTransfer(ctx context.Context, tx *sql.Tx, req *paymentpb.TransferRequest, paymentStatus string) (*types.Payment, error) {
+	savedPayment, err := s.storage.SavePayment(ctx, types.CreateTransferPayment(req, paymentStatus), tx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.storage.SaveTransfer(ctx, types.CreateTransfer(savedPayment, req), tx); err != nil {
+		return nil, err
+	}
+	return savedPayment, nil
+}
@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	paymentpb "github.com/Edbeer/payment-proto/payment-grpc/proto"
+	mockpay "github.com/Edbeer/payment-grpc/service/mock"
+
+	"github.com/Edbeer/payment-grpc/types"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func Test_Transfer(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	sender := newSplitAccount(100, 0)
+	receiver := newSplitAccount(0, 0)
+
+	t.Run("Successful transfer", func(t *testing.T) {
+		clientAuth, updates := mockSplitAccounts(ctrl, sender, receiver)
+		storagePay := mockpay.NewMockStorage(ctrl)
+		servicePay := NewPaymentService(storagePay, clientAuth, db, nil)
+		req := &paymentpb.TransferRequest{
+			Sender:         sender.Id,
+			Receiver:       receiver.Id,
+			Currency:       "rub",
+			Amount:         40,
+			Note:           "dinner",
+			IdempotencyKey: "key-1",
+		}
+
+		storagePay.EXPECT().GetTransferByKey(context.Background(), gomock.Any(), req.IdempotencyKey).Return(nil, sql.ErrNoRows)
+		mock.ExpectBegin()
+		var saved *types.Payment
+		storagePay.EXPECT().SavePayment(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, payment *types.Payment, tx *sql.Tx) (*types.Payment, error) {
+				saved = payment
+				return payment, nil
+			},
+		)
+		var transfer *types.Transfer
+		storagePay.EXPECT().SaveTransfer(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, tr *types.Transfer, tx *sql.Tx) (*types.Transfer, error) {
+				transfer = tr
+				return tr, nil
+			},
+		)
+		mock.ExpectCommit()
+
+		st, err := servicePay.Transfer(context.Background(), req)
+		require.NoError(t, err)
+		require.Equal(t, "Successful transfer", st.Status)
+
+		require.Equal(t, "Transfer", saved.Operation)
+		require.Equal(t, sender.Id, saved.Customer.String())
+		require.Equal(t, receiver.Id, saved.Merchant.String())
+		require.Equal(t, saved.PaymentId, transfer.PaymentId)
+		require.Equal(t, "dinner", transfer.Note)
+		require.Equal(t, "key-1", transfer.IdempotencyKey)
+		require.Equal(t, uint64(60), updates[sender.Id].Balance)
+		require.Equal(t, uint64(40), updates[receiver.Id].Balance)
+	})
+
+	t.Run("Repeated request", func(t *testing.T) {
+		clientAuth, _ := mockSplitAccounts(ctrl, sender, receiver)
+		storagePay := mockpay.NewMockStorage(ctrl)
+		servicePay := NewPaymentService(storagePay, clientAuth, db, nil)
+		req := &paymentpb.TransferRequest{
+			Sender:         sender.Id,
+			Receiver:       receiver.Id,
+			Currency:       "rub",
+			Amount:         40,
+			IdempotencyKey: "key-1",
+		}
+		payment := types.CreateTransferPayment(req, "Successful transfer")
+
+		storagePay.EXPECT().GetTransferByKey(context.Background(), gomock.Any(), req.IdempotencyKey).Return(types.CreateTransfer(payment, req), nil)
+		storagePay.EXPECT().GetPaymentByID(context.Background(), &paymentpb.PaidRequest{
+			PaymentId: payment.PaymentId.String(),
+		}).Return(payment, nil)
+
+		st, err := servicePay.Transfer(context.Background(), req)
+		require.NoError(t, err)
+		require.Equal(t, payment.PaymentId.String(), st.PaymentId)
+		require.Equal(t, "Successful transfer", st.Status)
+	})
+
+	t.Run("Repeated key of another transfer", func(t *testing.T) {
+		storagePay := mockpay.NewMockStorage(ctrl)
+		servicePay := NewPaymentService(storagePay, nil, db, nil)
+		req := &paymentpb.TransferRequest{
+			Sender:         sender.Id,
+			Receiver:       receiver.Id,
+			Currency:       "rub",
+			Amount:         40,
+			IdempotencyKey: "key-1",
+		}
+		payment := types.CreateTransferPayment(req, "Successful transfer")
+
+		storagePay.EXPECT().GetTransferByKey(context.Background(), gomock.Any(), req.IdempotencyKey).Return(types.CreateTransfer(payment, req), nil)
+		storagePay.EXPECT().GetPaymentByID(context.Background(), gomock.Any()).Return(payment, nil)
+
+		_, err := servicePay.Transfer(context.Background(), &paymentpb.TransferRequest{
+			Sender:         sender.Id,
+			Receiver:       receiver.Id,
+			Currency:       "rub",
+			Amount:         50,
+			IdempotencyKey: "key-1",
+		})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("Concurrent request", func(t *testing.T) {
+		clientAuth, updates := mockSplitAccounts(ctrl, sender, receiver)
+		storagePay := mockpay.NewMockStorage(ctrl)
+		servicePay := NewPaymentService(storagePay, clientAuth, db, nil)
+		req := &paymentpb.TransferRequest{
+			Sender:         sender.Id,
+			Receiver:       receiver.Id,
+			Currency:       "rub",
+			Amount:         40,
+			IdempotencyKey: "key-4",
+		}
+		payment := types.CreateTransferPayment(req, "Successful transfer")
+
+		// the request with the same key commits between the lookup and the save
+		gomock.InOrder(
+			storagePay.EXPECT().GetTransferByKey(context.Background(), gomock.Any(), req.IdempotencyKey).Return(nil, sql.ErrNoRows),
+			storagePay.EXPECT().GetTransferByKey(context.Background(), gomock.Any(), req.IdempotencyKey).Return(types.CreateTransfer(payment, req), nil),
+		)
+		mock.ExpectBegin()
+		storagePay.EXPECT().SavePayment(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, payment *types.Payment, tx *sql.Tx) (*types.Payment, error) {
+				return payment, nil
+			},
+		)
+		storagePay.EXPECT().SaveTransfer(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, types.ErrDuplicateTransfer)
+		mock.ExpectRollback()
+		storagePay.EXPECT().GetPaymentByID(context.Background(), gomock.Any()).Return(payment, nil)
+
+		st, err := servicePay.Transfer(context.Background(), req)
+		require.NoError(t, err)
+		require.Equal(t, payment.PaymentId.String(), st.PaymentId)
+		require.Empty(t, updates)
+	})
+
+	t.Run("Insufficient funds", func(t *testing.T) {
+		clientAuth, _ := mockSplitAccounts(ctrl, sender, receiver)
+		storagePay := mockpay.NewMockStorage(ctrl)
+		servicePay := NewPaymentService(storagePay, clientAuth, db, nil)
+		req := &paymentpb.TransferRequest{
+			Sender:         sender.Id,
+			Receiver:       receiver.Id,
+			Currency:       "rub",
+			Amount:         150,
+			IdempotencyKey: "key-2",
+		}
+
+		storagePay.EXPECT().GetTransferByKey(context.Background(), gomock.Any(), req.IdempotencyKey).Return(nil, sql.ErrNoRows)
+		mock.ExpectBegin()
+		storagePay.EXPECT().SavePayment(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, payment *types.Payment, tx *sql.Tx) (*types.Payment, error) {
+				return payment, nil
+			},
+		)
+		storagePay.EXPECT().SaveTransfer(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, tr *types.Transfer, tx *sql.Tx) (*types.Transfer, error) {
+				return tr, nil
+			},
+		)
+		mock.ExpectCommit()
+
+		st, err := servicePay.Transfer(context.Background(), req)
+		require.NoError(t, err)
+		require.Equal(t, "Insufficient funds", st.Status)
+	})
+
+	t.Run("Same account", func(t *testing.T) {
+		storagePay := mockpay.NewMockStorage(ctrl)
+		servicePay := NewPaymentService(storagePay, nil, db, nil)
+
+		_, err := servicePay.Transfer(context.Background(), &paymentpb.TransferRequest{
+			Sender:         sender.Id,
+			Receiver:       sender.Id,
+			Amount:         10,
+			IdempotencyKey: "key-3",
+		})
+		require.Error(t, err)
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
@@ -3,12 +3,17 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	paymentpb "github.com/Edbeer/payment-proto/payment-grpc/proto"
 	"github.com/Edbeer/payment-grpc/types"
-	_ "github.com/lib/pq"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+// postgres error code of the unique constraint violation
+const uniqueViolation = "23505"
+
 type PostgresStorage struct {
 	db *sql.DB
 }
@@ -23,8 +28,9 @@ func (s *PostgresStorage) SavePayment(ctx context.Context, payment *types.Paymen
 	query := `INSERT INTO payment (merchant, 
 		customer, card_number, card_expiry_month,
 		card_expiry_year, currency, operation,
-		status, amount, created_at)
-			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		status, amount, created_at, parent_id,
+		escrow, escrow_hold_hours, reference_id)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
 			RETURNING *`
 	pay := &types.Payment{}
 	if err := tx.QueryRowContext(
@@ -39,13 +45,19 @@ func (s *PostgresStorage) SavePayment(ctx context.Context, payment *types.Paymen
 		payment.Status,
 		payment.Amount,
 		payment.CreatedAt,
+		payment.ParentId,
+		payment.Escrow,
+		payment.EscrowHoldHours,
+		payment.ReferenceId,
 	).Scan(
 		&pay.PaymentId, &pay.Merchant,
 		&pay.Customer, &pay.CardNumber,
 		&pay.CardExpiryMonth, &pay.CardExpiryYear, 
 		&pay.Currency, &pay.Operation,
 		&pay.Status, &pay.Amount,
-		&pay.CreatedAt,
+		&pay.CreatedAt, &pay.ParentId,
+		&pay.Escrow, &pay.EscrowHoldHours,
+		&pay.ReferenceId, &pay.HoldId,
 	); err != nil {
 		return nil, err
 	}
@@ -63,9 +75,764 @@ func (s *PostgresStorage) GetPaymentByID(ctx context.Context, req *paymentpb.Pai
 		&pay.CardExpiryMonth, &pay.CardExpiryYear, 
 		&pay.Currency, &pay.Operation,
 		&pay.Status, &pay.Amount,
-		&pay.CreatedAt,
+		&pay.CreatedAt, &pay.ParentId,
+		&pay.Escrow, &pay.EscrowHoldHours,
+		&pay.ReferenceId, &pay.HoldId,
+	); err != nil {
+		return nil, err
+	}
+	return pay, nil
+}
+
+// Get payment, locked until the end of tx
+func (s *PostgresStorage) GetPaymentForUpdate(ctx context.Context, paymentID uuid.UUID, tx *sql.Tx) (*types.Payment, error) {
+	query := `SELECT * FROM payment WHERE payment_id = $1 FOR UPDATE`
+	pay := &types.Payment{}
+	if err := tx.QueryRowContext(
+		ctx, query, paymentID,
+	).Scan(
+		&pay.PaymentId, &pay.Merchant,
+		&pay.Customer, &pay.CardNumber,
+		&pay.CardExpiryMonth, &pay.CardExpiryYear,
+		&pay.Currency, &pay.Operation,
+		&pay.Status, &pay.Amount,
+		&pay.CreatedAt, &pay.ParentId,
+		&pay.Escrow, &pay.EscrowHoldHours,
+		&pay.ReferenceId, &pay.HoldId,
 	); err != nil {
 		return nil, err
 	}
 	return pay, nil
-}
\ No newline at end of file
+}
+
+// Update authorized amount of payment
+func (s *PostgresStorage) UpdatePaymentAmount(ctx context.Context, paymentID uuid.UUID, amount uint64, tx *sql.Tx) error {
+	query := `UPDATE payment SET amount = $1 WHERE payment_id = $2`
+	if _, err := tx.ExecContext(ctx, query, amount, paymentID); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Save auth service hold of the authorization
+func (s *PostgresStorage) UpdatePaymentHold(ctx context.Context, paymentID, holdID uuid.UUID, tx *sql.Tx) error {
+	query := `UPDATE payment SET hold_id = $1 WHERE payment_id = $2`
+	if _, err := tx.ExecContext(ctx, query, holdID, paymentID); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Get seller parts of a split payment
+func (s *PostgresStorage) GetChildPayments(ctx context.Context, parentID uuid.UUID) ([]*types.Payment, error) {
+	query := `SELECT * FROM payment WHERE parent_id = $1`
+	rows, err := s.db.QueryContext(ctx, query, parentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	payments := []*types.Payment{}
+	for rows.Next() {
+		pay := &types.Payment{}
+		if err := rows.Scan(
+			&pay.PaymentId, &pay.Merchant,
+			&pay.Customer, &pay.CardNumber,
+			&pay.CardExpiryMonth, &pay.CardExpiryYear,
+			&pay.Currency, &pay.Operation,
+			&pay.Status, &pay.Amount,
+			&pay.CreatedAt, &pay.ParentId,
+			&pay.Escrow, &pay.EscrowHoldHours,
+			&pay.ReferenceId, &pay.HoldId,
+		); err != nil {
+			return nil, err
+		}
+		payments = append(payments, pay)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
+
+// Get payment events after cursor, filtered by merchant and customer
+func (s *PostgresStorage) GetPaymentEvents(ctx context.Context, req *paymentpb.SubscribeRequest, limit int) ([]*types.PaymentEvent, error) {
+	query := `SELECT event_id, payment_id, merchant, customer,
+		currency, operation, status, amount, created_at, parent_id
+			FROM payment_event
+				WHERE event_id > $1
+					AND merchant = COALESCE(NULLIF($2, '')::uuid, merchant)
+					AND customer = COALESCE(NULLIF($3, '')::uuid, customer)
+				ORDER BY event_id
+				LIMIT $4`
+	rows, err := s.db.QueryContext(
+		ctx, query,
+		req.Cursor,
+		req.Merchant,
+		req.Customer,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []*types.PaymentEvent{}
+	for rows.Next() {
+		event := &types.PaymentEvent{}
+		if err := rows.Scan(
+			&event.Cursor, &event.PaymentId,
+			&event.Merchant, &event.Customer,
+			&event.Currency, &event.Operation,
+			&event.Status, &event.Amount,
+			&event.CreatedAt, &event.ParentId,
+		); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// Save escrow hold of a captured payment
+func (s *PostgresStorage) SaveEscrow(ctx context.Context, escrow *types.Escrow, tx *sql.Tx) (*types.Escrow, error) {
+	query := `INSERT INTO escrow (payment_id, merchant,
+		customer, currency, amount, state,
+		release_at, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			RETURNING *`
+	esc := &types.Escrow{}
+	if err := tx.QueryRowContext(
+		ctx, query,
+		escrow.PaymentId,
+		escrow.Merchant,
+		escrow.Customer,
+		escrow.Currency,
+		escrow.Amount,
+		escrow.State,
+		escrow.ReleaseAt,
+		escrow.CreatedAt,
+	).Scan(
+		&esc.PaymentId, &esc.Merchant,
+		&esc.Customer, &esc.Currency,
+		&esc.Amount, &esc.State,
+		&esc.ReleaseAt, &esc.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return esc, nil
+}
+
+// Get escrow hold, locked until the end of tx
+func (s *PostgresStorage) GetEscrow(ctx context.Context, paymentID uuid.UUID, tx *sql.Tx) (*types.Escrow, error) {
+	query := `SELECT * FROM escrow WHERE payment_id = $1 FOR UPDATE`
+	esc := &types.Escrow{}
+	if err := tx.QueryRowContext(
+		ctx, query, paymentID,
+	).Scan(
+		&esc.PaymentId, &esc.Merchant,
+		&esc.Customer, &esc.Currency,
+		&esc.Amount, &esc.State,
+		&esc.ReleaseAt, &esc.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return esc, nil
+}
+
+// Update held amount and state of escrow
+func (s *PostgresStorage) UpdateEscrow(ctx context.Context, escrow *types.Escrow, tx *sql.Tx) (*types.Escrow, error) {
+	query := `UPDATE escrow
+				SET amount = $1,
+					state = $2
+				WHERE payment_id = $3
+				RETURNING *`
+	esc := &types.Escrow{}
+	if err := tx.QueryRowContext(
+		ctx, query,
+		escrow.Amount,
+		escrow.State,
+		escrow.PaymentId,
+	).Scan(
+		&esc.PaymentId, &esc.Merchant,
+		&esc.Customer, &esc.Currency,
+		&esc.Amount, &esc.State,
+		&esc.ReleaseAt, &esc.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return esc, nil
+}
+
+// Get held escrows to be released by now
+func (s *PostgresStorage) GetDueEscrows(ctx context.Context, now time.Time) ([]*types.Escrow, error) {
+	query := `SELECT * FROM escrow
+				WHERE state = $1 AND release_at <= $2
+				ORDER BY release_at`
+	rows, err := s.db.QueryContext(ctx, query, types.EscrowHeld, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	escrows := []*types.Escrow{}
+	for rows.Next() {
+		esc := &types.Escrow{}
+		if err := rows.Scan(
+			&esc.PaymentId, &esc.Merchant,
+			&esc.Customer, &esc.Currency,
+			&esc.Amount, &esc.State,
+			&esc.ReleaseAt, &esc.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		escrows = append(escrows, esc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return escrows, nil
+}
+
+// Save challenge of a payment
+func (s *PostgresStorage) SaveChallenge(ctx context.Context, challenge *types.Challenge, tx *sql.Tx) (*types.Challenge, error) {
+	query := `INSERT INTO challenge (challenge_id, payment_id,
+		customer, code_hash, attempts, state,
+		expires_at, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			RETURNING *`
+	ch := &types.Challenge{}
+	if err := tx.QueryRowContext(
+		ctx, query,
+		challenge.ChallengeId,
+		challenge.PaymentId,
+		challenge.Customer,
+		challenge.CodeHash,
+		challenge.Attempts,
+		challenge.State,
+		challenge.ExpiresAt,
+		challenge.CreatedAt,
+	).Scan(
+		&ch.ChallengeId, &ch.PaymentId,
+		&ch.Customer, &ch.CodeHash,
+		&ch.Attempts, &ch.State,
+		&ch.ExpiresAt, &ch.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return ch, nil
+}
+
+// Get challenge, locked until the end of tx
+func (s *PostgresStorage) GetChallenge(ctx context.Context, challengeID uuid.UUID, tx *sql.Tx) (*types.Challenge, error) {
+	query := `SELECT * FROM challenge WHERE challenge_id = $1 FOR UPDATE`
+	ch := &types.Challenge{}
+	if err := tx.QueryRowContext(
+		ctx, query, challengeID,
+	).Scan(
+		&ch.ChallengeId, &ch.PaymentId,
+		&ch.Customer, &ch.CodeHash,
+		&ch.Attempts, &ch.State,
+		&ch.ExpiresAt, &ch.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return ch, nil
+}
+
+// Update attempts and state of challenge
+func (s *PostgresStorage) UpdateChallenge(ctx context.Context, challenge *types.Challenge, tx *sql.Tx) error {
+	query := `UPDATE challenge
+				SET attempts = $1,
+					state = $2
+				WHERE challenge_id = $3`
+	if _, err := tx.ExecContext(
+		ctx, query,
+		challenge.Attempts,
+		challenge.State,
+		challenge.ChallengeId,
+	); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Get pending challenges expired by now
+func (s *PostgresStorage) GetExpiredChallenges(ctx context.Context, now time.Time) ([]*types.Challenge, error) {
+	query := `SELECT * FROM challenge
+				WHERE state = $1 AND expires_at <= $2
+				ORDER BY expires_at`
+	rows, err := s.db.QueryContext(ctx, query, types.ChallengePending, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	challenges := []*types.Challenge{}
+	for rows.Next() {
+		ch := &types.Challenge{}
+		if err := rows.Scan(
+			&ch.ChallengeId, &ch.PaymentId,
+			&ch.Customer, &ch.CodeHash,
+			&ch.Attempts, &ch.State,
+			&ch.ExpiresAt, &ch.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		challenges = append(challenges, ch)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return challenges, nil
+}
+
+// Save transfer details, the idempotency key is unique for the sender
+func (s *PostgresStorage) SaveTransfer(ctx context.Context, transfer *types.Transfer, tx *sql.Tx) (*types.Transfer, error) {
+	query := `INSERT INTO transfer (payment_id, sender,
+		receiver, idempotency_key, note, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING *`
+	tr := &types.Transfer{}
+	if err := tx.QueryRowContext(
+		ctx, query,
+		transfer.PaymentId,
+		transfer.Sender,
+		transfer.Receiver,
+		transfer.IdempotencyKey,
+		transfer.Note,
+		transfer.CreatedAt,
+	).Scan(
+		&tr.PaymentId, &tr.Sender,
+		&tr.Receiver, &tr.IdempotencyKey,
+		&tr.Note, &tr.CreatedAt,
+	); err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == uniqueViolation {
+			return nil, types.ErrDuplicateTransfer
+		}
+		return nil, err
+	}
+	return tr, nil
+}
+
+// Get transfer of the sender by idempotency key
+func (s *PostgresStorage) GetTransferByKey(ctx context.Context, sender uuid.UUID, key string) (*types.Transfer, error) {
+	query := `SELECT * FROM transfer WHERE sender = $1 AND idempotency_key = $2`
+	tr := &types.Transfer{}
+	if err := s.db.QueryRowContext(
+		ctx, query, sender, key,
+	).Scan(
+		&tr.PaymentId, &tr.Sender,
+		&tr.Receiver, &tr.IdempotencyKey,
+		&tr.Note, &tr.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return tr, nil
+}
+
+// Save installment plan
+func (s *PostgresStorage) SaveInstallmentPlan(ctx context.Context, plan *types.InstallmentPlan, tx *sql.Tx) (*types.InstallmentPlan, error) {
+	query := `INSERT INTO installment_plan (plan_id, payment_id,
+		authorization_id, merchant, customer, currency,
+		amount, fee, state, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			RETURNING *`
+	p := &types.InstallmentPlan{}
+	if err := tx.QueryRowContext(
+		ctx, query,
+		plan.PlanId,
+		plan.PaymentId,
+		plan.AuthorizationId,
+		plan.Merchant,
+		plan.Customer,
+		plan.Currency,
+		plan.Amount,
+		plan.Fee,
+		plan.State,
+		plan.CreatedAt,
+	).Scan(
+		&p.PlanId, &p.PaymentId,
+		&p.AuthorizationId, &p.Merchant,
+		&p.Customer, &p.Currency,
+		&p.Amount, &p.Fee,
+		&p.State, &p.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Save scheduled installment of a plan
+func (s *PostgresStorage) SaveInstallment(ctx context.Context, installment *types.Installment, tx *sql.Tx) (*types.Installment, error) {
+	query := `INSERT INTO installment (installment_id, plan_id,
+		seq, amount, fee, due_at, state,
+		attempts, next_attempt_at, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			RETURNING *`
+	inst := &types.Installment{}
+	if err := tx.QueryRowContext(
+		ctx, query,
+		installment.InstallmentId,
+		installment.PlanId,
+		installment.Seq,
+		installment.Amount,
+		installment.Fee,
+		installment.DueAt,
+		installment.State,
+		installment.Attempts,
+		installment.NextAttemptAt,
+		installment.CreatedAt,
+	).Scan(
+		&inst.InstallmentId, &inst.PlanId,
+		&inst.Seq, &inst.Amount,
+		&inst.Fee, &inst.DueAt,
+		&inst.State, &inst.Attempts,
+		&inst.NextAttemptAt, &inst.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return inst, nil
+}
+
+// Get installment plan
+func (s *PostgresStorage) GetInstallmentPlan(ctx context.Context, planID uuid.UUID) (*types.InstallmentPlan, error) {
+	query := `SELECT * FROM installment_plan WHERE plan_id = $1`
+	p := &types.InstallmentPlan{}
+	if err := s.db.QueryRowContext(
+		ctx, query, planID,
+	).Scan(
+		&p.PlanId, &p.PaymentId,
+		&p.AuthorizationId, &p.Merchant,
+		&p.Customer, &p.Currency,
+		&p.Amount, &p.Fee,
+		&p.State, &p.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Get installments of a plan in schedule order
+func (s *PostgresStorage) GetInstallments(ctx context.Context, planID uuid.UUID) ([]*types.Installment, error) {
+	query := `SELECT * FROM installment WHERE plan_id = $1 ORDER BY seq`
+	rows, err := s.db.QueryContext(ctx, query, planID)
+	if err != nil {
+		return nil, err
+	}
+	return scanInstallments(rows)
+}
+
+// Get scheduled and delinquent installments to be charged by now
+func (s *PostgresStorage) GetDueInstallments(ctx context.Context, now time.Time) ([]*types.Installment, error) {
+	query := `SELECT * FROM installment
+				WHERE state IN ($1, $2) AND next_attempt_at <= $3
+				ORDER BY next_attempt_at`
+	rows, err := s.db.QueryContext(ctx, query, types.InstallmentScheduled, types.InstallmentDelinquent, now)
+	if err != nil {
+		return nil, err
+	}
+	return scanInstallments(rows)
+}
+
+// Get delinquent and defaulted installments of the merchant or the customer
+func (s *PostgresStorage) GetDelinquentInstallments(ctx context.Context, req *paymentpb.DelinquentRequest) ([]*types.Installment, error) {
+	query := `SELECT i.* FROM installment i
+				JOIN installment_plan p ON p.plan_id = i.plan_id
+				WHERE i.state IN ($1, $2)
+					AND p.merchant = COALESCE(NULLIF($3, '')::uuid, p.merchant)
+					AND p.customer = COALESCE(NULLIF($4, '')::uuid, p.customer)
+				ORDER BY i.due_at`
+	rows, err := s.db.QueryContext(
+		ctx, query,
+		types.InstallmentDelinquent,
+		types.InstallmentDefaulted,
+		req.Merchant,
+		req.Customer,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanInstallments(rows)
+}
+
+// Get installment, locked until the end of tx
+func (s *PostgresStorage) GetInstallmentForUpdate(ctx context.Context, installmentID uuid.UUID, tx *sql.Tx) (*types.Installment, error) {
+	query := `SELECT * FROM installment WHERE installment_id = $1 FOR UPDATE`
+	inst := &types.Installment{}
+	if err := tx.QueryRowContext(
+		ctx, query, installmentID,
+	).Scan(
+		&inst.InstallmentId, &inst.PlanId,
+		&inst.Seq, &inst.Amount,
+		&inst.Fee, &inst.DueAt,
+		&inst.State, &inst.Attempts,
+		&inst.NextAttemptAt, &inst.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return inst, nil
+}
+
+// Update state and retry schedule of installment
+func (s *PostgresStorage) UpdateInstallment(ctx context.Context, installment *types.Installment, tx *sql.Tx) error {
+	query := `UPDATE installment
+				SET state = $1,
+					attempts = $2,
+					next_attempt_at = $3
+				WHERE installment_id = $4`
+	if _, err := tx.ExecContext(
+		ctx, query,
+		installment.State,
+		installment.Attempts,
+		installment.NextAttemptAt,
+		installment.InstallmentId,
+	); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Derive plan state from the states of its installments
+func (s *PostgresStorage) RefreshInstallmentPlan(ctx context.Context, planID uuid.UUID, tx *sql.Tx) error {
+	query := `UPDATE installment_plan
+				SET state = CASE
+					WHEN NOT EXISTS (SELECT 1 FROM installment WHERE plan_id = $1 AND state <> $2) THEN $3
+					WHEN EXISTS (SELECT 1 FROM installment WHERE plan_id = $1 AND state = $4) THEN $5
+					WHEN EXISTS (SELECT 1 FROM installment WHERE plan_id = $1 AND state = $6) THEN $7
+					ELSE $8
+				END
+				WHERE plan_id = $1`
+	if _, err := tx.ExecContext(
+		ctx, query,
+		planID,
+		types.InstallmentPaid, types.PlanCompleted,
+		types.InstallmentDefaulted, types.PlanDefaulted,
+		types.InstallmentDelinquent, types.PlanDelinquent,
+		types.PlanActive,
+	); err != nil {
+		return err
+	}
+	return nil
+}
+
+func scanInstallments(rows *sql.Rows) ([]*types.Installment, error) {
+	defer rows.Close()
+
+	installments := []*types.Installment{}
+	for rows.Next() {
+		inst := &types.Installment{}
+		if err := rows.Scan(
+			&inst.InstallmentId, &inst.PlanId,
+			&inst.Seq, &inst.Amount,
+			&inst.Fee, &inst.DueAt,
+			&inst.State, &inst.Attempts,
+			&inst.NextAttemptAt, &inst.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		installments = append(installments, inst)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return installments, nil
+}
+
+// Save schedule of the payment in "Scheduled" status
+func (s *PostgresStorage) SaveScheduledPayment(ctx context.Context, scheduled *types.ScheduledPayment, tx *sql.Tx) error {
+	query := `INSERT INTO scheduled_payment (payment_id, execute_at,
+		state, result_id, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6)`
+	if _, err := tx.ExecContext(
+		ctx, query,
+		scheduled.PaymentId,
+		scheduled.ExecuteAt,
+		scheduled.State,
+		scheduled.ResultId,
+		scheduled.CreatedAt,
+		scheduled.UpdatedAt,
+	); err != nil {
+		return err
+	}
+	return nil
+}
+
+// columns of the scheduled payment with the details of its payment row
+const scheduledColumns = `s.payment_id, p.merchant, p.customer, p.currency, p.amount,
+		s.execute_at, s.state, s.result_id, s.created_at, s.updated_at, s.claimed_at
+			FROM scheduled_payment s JOIN payment p ON p.payment_id = s.payment_id`
+
+// Get scheduled payment, locked until the end of tx
+func (s *PostgresStorage) GetScheduledPayment(ctx context.Context, paymentID uuid.UUID, tx *sql.Tx) (*types.ScheduledPayment, error) {
+	query := `SELECT ` + scheduledColumns + `
+				WHERE s.payment_id = $1 FOR UPDATE OF s`
+	scheduled := &types.ScheduledPayment{}
+	if err := tx.QueryRowContext(
+		ctx, query, paymentID,
+	).Scan(
+		&scheduled.PaymentId, &scheduled.Merchant,
+		&scheduled.Customer, &scheduled.Currency,
+		&scheduled.Amount, &scheduled.ExecuteAt,
+		&scheduled.State, &scheduled.ResultId,
+		&scheduled.CreatedAt, &scheduled.UpdatedAt,
+		&scheduled.ClaimedAt,
+	); err != nil {
+		return nil, err
+	}
+	return scheduled, nil
+}
+
+// Get scheduled payments of the merchant or the customer
+func (s *PostgresStorage) GetScheduledPayments(ctx context.Context, req *paymentpb.ScheduledListRequest) ([]*types.ScheduledPayment, error) {
+	query := `SELECT ` + scheduledColumns + `
+				WHERE p.merchant = COALESCE(NULLIF($1, '')::uuid, p.merchant)
+					AND p.customer = COALESCE(NULLIF($2, '')::uuid, p.customer)
+				ORDER BY s.execute_at`
+	rows, err := s.db.QueryContext(ctx, query, req.Merchant, req.Customer)
+	if err != nil {
+		return nil, err
+	}
+	return scanScheduledPayments(rows)
+}
+
+// Get scheduled payments to be executed by now
+// and payments whose execution was claimed before claimedBefore
+func (s *PostgresStorage) GetDueScheduledPayments(ctx context.Context, now, claimedBefore time.Time) ([]*types.ScheduledPayment, error) {
+	query := `SELECT ` + scheduledColumns + `
+				WHERE s.state = $1 AND s.execute_at <= $2
+					OR s.state = $3 AND s.claimed_at <= $4
+				ORDER BY s.execute_at`
+	rows, err := s.db.QueryContext(ctx, query, types.ScheduledPending, now, types.ScheduledProcessing, claimedBefore)
+	if err != nil {
+		return nil, err
+	}
+	return scanScheduledPayments(rows)
+}
+
+// Update execution time, state, result and claim of scheduled payment
+func (s *PostgresStorage) UpdateScheduledPayment(ctx context.Context, scheduled *types.ScheduledPayment, tx *sql.Tx) error {
+	query := `UPDATE scheduled_payment
+				SET execute_at = $1,
+					state = $2,
+					result_id = $3,
+					updated_at = $4,
+					claimed_at = $5
+				WHERE payment_id = $6`
+	if _, err := tx.ExecContext(
+		ctx, query,
+		scheduled.ExecuteAt,
+		scheduled.State,
+		scheduled.ResultId,
+		scheduled.UpdatedAt,
+		scheduled.ClaimedAt,
+		scheduled.PaymentId,
+	); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Save state and result of scheduled payment if it is still claimed at its claim time,
+// false when the claim was taken over by another replica.
+// Every claim changes the claim time, the result is saved again with the same claim
+func (s *PostgresStorage) FinishScheduledPayment(ctx context.Context, scheduled *types.ScheduledPayment, tx *sql.Tx) (bool, error) {
+	query := `UPDATE scheduled_payment
+				SET state = $1,
+					result_id = $2,
+					updated_at = $3
+				WHERE payment_id = $4 AND claimed_at = $5`
+	result, err := tx.ExecContext(
+		ctx, query,
+		scheduled.State,
+		scheduled.ResultId,
+		scheduled.UpdatedAt,
+		scheduled.PaymentId,
+		scheduled.ClaimedAt,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+func scanScheduledPayments(rows *sql.Rows) ([]*types.ScheduledPayment, error) {
+	defer rows.Close()
+
+	payments := []*types.ScheduledPayment{}
+	for rows.Next() {
+		scheduled := &types.ScheduledPayment{}
+		if err := rows.Scan(
+			&scheduled.PaymentId, &scheduled.Merchant,
+			&scheduled.Customer, &scheduled.Currency,
+			&scheduled.Amount, &scheduled.ExecuteAt,
+			&scheduled.State, &scheduled.ResultId,
+			&scheduled.CreatedAt, &scheduled.UpdatedAt,
+			&scheduled.ClaimedAt,
+		); err != nil {
+			return nil, err
+		}
+		payments = append(payments, scheduled)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
+
+// Save authorization of the card terminal
+func (s *PostgresStorage) SaveCardReference(ctx context.Context, ref *types.CardReference) error {
+	query := `INSERT INTO card_reference (terminal_id, rrn,
+		payment_id, completed_by, created_at)
+			VALUES ($1, $2, $3, $4, $5)`
+	if _, err := s.db.ExecContext(
+		ctx, query,
+		ref.TerminalId,
+		ref.Rrn,
+		ref.PaymentId,
+		ref.CompletedBy,
+		ref.CreatedAt,
+	); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Get authorization of the card terminal by retrieval reference number
+func (s *PostgresStorage) GetCardReference(ctx context.Context, terminalID, rrn string) (*types.CardReference, error) {
+	query := `SELECT * FROM card_reference WHERE terminal_id = $1 AND rrn = $2`
+	ref := &types.CardReference{}
+	if err := s.db.QueryRowContext(
+		ctx, query, terminalID, rrn,
+	).Scan(
+		&ref.TerminalId, &ref.Rrn,
+		&ref.PaymentId, &ref.CompletedBy,
+		&ref.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return ref, nil
+}
+
+// Change completion of the card reference if it is still the expected one,
+// concurrent completions of the same authorization are claimed only once
+func (s *PostgresStorage) UpdateCardReference(ctx context.Context, terminalID, rrn, from, to string) (bool, error) {
+	query := `UPDATE card_reference SET completed_by = $4
+		WHERE terminal_id = $1 AND rrn = $2 AND completed_by = $3`
+	result, err := s.db.ExecContext(ctx, query, terminalID, rrn, from, to)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
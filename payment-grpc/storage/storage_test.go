@@ -11,6 +11,7 @@ import (
 	paymentpb "github.com/Edbeer/payment-proto/payment-grpc/proto"
 	"github.com/Edbeer/payment-grpc/types"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
@@ -76,6 +77,11 @@ func Test_SavePayment(t *testing.T) {
 			"status",
 			"amount",
 			"created_at",
+			"parent_id",
+			"escrow",
+			"escrow_hold_hours",
+			"reference_id",
+			"hold_id",
 		}
 		rows := sqlmock.NewRows(colums).AddRow(
 			payment.PaymentId,
@@ -89,13 +95,19 @@ func Test_SavePayment(t *testing.T) {
 			"",
 			50,
 			payment.CreatedAt,
+			nil,
+			false,
+			0,
+			nil,
+			nil,
 		)
 		mock.ExpectBegin()
 		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO payment (merchant, 
 			customer, card_number, card_expiry_month,
 			card_expiry_year, currency, operation,
-			status, amount, created_at)
-				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			status, amount, created_at, parent_id,
+			escrow, escrow_hold_hours, reference_id)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
 				RETURNING *`)).WithArgs(
 					payment.Merchant,
 					payment.Customer,
@@ -106,7 +118,11 @@ func Test_SavePayment(t *testing.T) {
 					payment.Operation,
 					payment.Status,
 					payment.Amount,
-					payment.CreatedAt,).WillReturnRows(rows)
+					payment.CreatedAt,
+					payment.ParentId,
+					payment.Escrow,
+					payment.EscrowHoldHours,
+					payment.ReferenceId,).WillReturnRows(rows)
 		tx, _ := db.BeginTx(context.Background(), nil)
 		pay, err := psql.SavePayment(context.Background(), payment, tx)
 		require.NoError(t, err)
@@ -140,6 +156,11 @@ func Test_GetPaymentByID(t *testing.T) {
 			"status",
 			"amount",
 			"created_at",
+			"parent_id",
+			"escrow",
+			"escrow_hold_hours",
+			"reference_id",
+			"hold_id",
 		}
 		rows := sqlmock.NewRows(colums).AddRow(
 			req.PaymentId,
@@ -153,6 +174,11 @@ func Test_GetPaymentByID(t *testing.T) {
 			"",
 			50,
 			time.Now(),
+			nil,
+			false,
+			0,
+			nil,
+			nil,
 		)
 
 		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM payment WHERE payment_id = $1`)).WithArgs(req.PaymentId).WillReturnRows(rows)
@@ -161,4 +187,835 @@ func Test_GetPaymentByID(t *testing.T) {
 		require.NoError(t, err)
 		require.NotNil(t, pay)
 	})
-}
\ No newline at end of file
+}
+func Test_GetPaymentEvents(t *testing.T) {
+	t.Parallel()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	psql := NewPostgresStorage(db)
+
+	t.Run("GetPaymentEvents", func(t *testing.T) {
+		req := &paymentpb.SubscribeRequest{
+			Merchant: uuid.New().String(),
+			Cursor:   10,
+		}
+
+		colums := []string{
+			"event_id",
+			"payment_id",
+			"merchant",
+			"customer",
+			"currency",
+			"operation",
+			"status",
+			"amount",
+			"created_at",
+			"parent_id",
+		}
+		rows := sqlmock.NewRows(colums).AddRow(
+			11,
+			uuid.New().String(),
+			req.Merchant,
+			uuid.New().String(),
+			"RUB",
+			"Authorization",
+			"Approved",
+			50,
+			time.Now(),
+			nil,
+		).AddRow(
+			12,
+			uuid.New().String(),
+			req.Merchant,
+			uuid.New().String(),
+			"RUB",
+			"Capture",
+			"Successful payment",
+			50,
+			time.Now(),
+			nil,
+		)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT event_id, payment_id, merchant, customer,
+		currency, operation, status, amount, created_at, parent_id
+			FROM payment_event`)).
+			WithArgs(req.Cursor, req.Merchant, req.Customer, 100).WillReturnRows(rows)
+
+		events, err := psql.GetPaymentEvents(context.Background(), req, 100)
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+		require.Equal(t, uint64(11), events[0].Cursor)
+		require.Equal(t, uint64(12), events[1].Cursor)
+		require.Equal(t, "Capture", events[1].Operation)
+	})
+}
+
+func Test_GetChildPayments(t *testing.T) {
+	t.Parallel()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	psql := NewPostgresStorage(db)
+
+	t.Run("GetChildPayments", func(t *testing.T) {
+		parentID := uuid.New()
+
+		colums := []string{
+			"payment_id",
+			"merchant",
+			"customer",
+			"card_number",
+			"card_expiry_month",
+			"card_expiry_year",
+			"currency",
+			"operation",
+			"status",
+			"amount",
+			"created_at",
+			"parent_id",
+			"escrow",
+			"escrow_hold_hours",
+			"reference_id",
+			"hold_id",
+		}
+		rows := sqlmock.NewRows(colums).AddRow(
+			uuid.New().String(),
+			uuid.New().String(),
+			uuid.New().String(),
+			"444444444444444",
+			"12",
+			"24",
+			"RUB",
+			"Authorization",
+			"Approved",
+			30,
+			time.Now(),
+			parentID.String(),
+			false,
+			0,
+			nil,
+			nil,
+		)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM payment WHERE parent_id = $1`)).WithArgs(parentID).WillReturnRows(rows)
+
+		children, err := psql.GetChildPayments(context.Background(), parentID)
+		require.NoError(t, err)
+		require.Len(t, children, 1)
+		require.True(t, children[0].ParentId.Valid)
+		require.Equal(t, parentID, children[0].ParentId.UUID)
+	})
+}
+
+func Test_Escrow(t *testing.T) {
+	t.Parallel()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	psql := NewPostgresStorage(db)
+
+	colums := []string{
+		"payment_id",
+		"merchant",
+		"customer",
+		"currency",
+		"amount",
+		"state",
+		"release_at",
+		"created_at",
+	}
+	escrow := types.CreateEscrow(&types.Payment{
+		PaymentId:       uuid.New(),
+		Merchant:        uuid.New(),
+		Customer:        uuid.New(),
+		Currency:        "RUB",
+		Amount:          50,
+		EscrowHoldHours: 24,
+	})
+	escrowRow := func() *sqlmock.Rows {
+		return sqlmock.NewRows(colums).AddRow(
+			escrow.PaymentId.String(),
+			escrow.Merchant.String(),
+			escrow.Customer.String(),
+			escrow.Currency,
+			escrow.Amount,
+			escrow.State,
+			escrow.ReleaseAt.Time,
+			escrow.CreatedAt,
+		)
+	}
+
+	t.Run("SaveEscrow", func(t *testing.T) {
+		mock.ExpectBegin()
+		tx, err := db.BeginTx(context.Background(), nil)
+		require.NoError(t, err)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO escrow (payment_id, merchant,
+		customer, currency, amount, state,
+		release_at, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			RETURNING *`)).WithArgs(
+			escrow.PaymentId,
+			escrow.Merchant,
+			escrow.Customer,
+			escrow.Currency,
+			escrow.Amount,
+			escrow.State,
+			escrow.ReleaseAt,
+			escrow.CreatedAt,
+		).WillReturnRows(escrowRow())
+
+		saved, err := psql.SaveEscrow(context.Background(), escrow, tx)
+		require.NoError(t, err)
+		require.Equal(t, escrow.PaymentId, saved.PaymentId)
+		require.True(t, saved.ReleaseAt.Valid)
+	})
+
+	t.Run("GetEscrow", func(t *testing.T) {
+		mock.ExpectBegin()
+		tx, err := db.BeginTx(context.Background(), nil)
+		require.NoError(t, err)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM escrow WHERE payment_id = $1 FOR UPDATE`)).
+			WithArgs(escrow.PaymentId).WillReturnRows(escrowRow())
+
+		held, err := psql.GetEscrow(context.Background(), escrow.PaymentId, tx)
+		require.NoError(t, err)
+		require.Equal(t, types.EscrowHeld, held.State)
+		require.Equal(t, uint64(50), held.Amount)
+	})
+
+	t.Run("UpdateEscrow", func(t *testing.T) {
+		mock.ExpectBegin()
+		tx, err := db.BeginTx(context.Background(), nil)
+		require.NoError(t, err)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE escrow
+				SET amount = $1,
+					state = $2
+				WHERE payment_id = $3
+				RETURNING *`)).WithArgs(escrow.Amount, escrow.State, escrow.PaymentId).WillReturnRows(escrowRow())
+
+		_, err = psql.UpdateEscrow(context.Background(), escrow, tx)
+		require.NoError(t, err)
+	})
+
+	t.Run("GetDueEscrows", func(t *testing.T) {
+		now := time.Now()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM escrow
+				WHERE state = $1 AND release_at <= $2
+				ORDER BY release_at`)).WithArgs(types.EscrowHeld, now).WillReturnRows(escrowRow())
+
+		escrows, err := psql.GetDueEscrows(context.Background(), now)
+		require.NoError(t, err)
+		require.Len(t, escrows, 1)
+	})
+}
+
+func Test_ChangePaymentAmount(t *testing.T) {
+	t.Parallel()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	psql := NewPostgresStorage(db)
+
+	t.Run("GetPaymentForUpdate", func(t *testing.T) {
+		paymentID := uuid.New()
+		mock.ExpectBegin()
+		tx, err := db.BeginTx(context.Background(), nil)
+		require.NoError(t, err)
+
+		colums := []string{
+			"payment_id",
+			"merchant",
+			"customer",
+			"card_number",
+			"card_expiry_month",
+			"card_expiry_year",
+			"currency",
+			"operation",
+			"status",
+			"amount",
+			"created_at",
+			"parent_id",
+			"escrow",
+			"escrow_hold_hours",
+			"reference_id",
+			"hold_id",
+		}
+		rows := sqlmock.NewRows(colums).AddRow(
+			paymentID.String(),
+			uuid.New().String(),
+			uuid.New().String(),
+			"444444444444444",
+			"12",
+			"24",
+			"RUB",
+			"Authorization",
+			"Approved",
+			100,
+			time.Now(),
+			nil,
+			false,
+			0,
+			nil,
+			nil,
+		)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM payment WHERE payment_id = $1 FOR UPDATE`)).
+			WithArgs(paymentID).WillReturnRows(rows)
+
+		payment, err := psql.GetPaymentForUpdate(context.Background(), paymentID, tx)
+		require.NoError(t, err)
+		require.Equal(t, paymentID, payment.PaymentId)
+		require.Equal(t, uint64(100), payment.Amount)
+	})
+
+	t.Run("UpdatePaymentAmount", func(t *testing.T) {
+		paymentID := uuid.New()
+		mock.ExpectBegin()
+		tx, err := db.BeginTx(context.Background(), nil)
+		require.NoError(t, err)
+
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE payment SET amount = $1 WHERE payment_id = $2`)).
+			WithArgs(130, paymentID).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err = psql.UpdatePaymentAmount(context.Background(), paymentID, 130, tx)
+		require.NoError(t, err)
+	})
+
+	t.Run("UpdatePaymentHold", func(t *testing.T) {
+		paymentID, holdID := uuid.New(), uuid.New()
+		mock.ExpectBegin()
+		tx, err := db.BeginTx(context.Background(), nil)
+		require.NoError(t, err)
+
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE payment SET hold_id = $1 WHERE payment_id = $2`)).
+			WithArgs(holdID, paymentID).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err = psql.UpdatePaymentHold(context.Background(), paymentID, holdID, tx)
+		require.NoError(t, err)
+	})
+}
+
+func Test_Challenge(t *testing.T) {
+	t.Parallel()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	psql := NewPostgresStorage(db)
+
+	colums := []string{
+		"challenge_id",
+		"payment_id",
+		"customer",
+		"code_hash",
+		"attempts",
+		"state",
+		"expires_at",
+		"created_at",
+	}
+	challenge := types.CreateChallenge(&types.Payment{
+		PaymentId: uuid.New(),
+		Customer:  uuid.New(),
+	}, "hash", time.Minute)
+	challengeRow := func() *sqlmock.Rows {
+		return sqlmock.NewRows(colums).AddRow(
+			challenge.ChallengeId.String(),
+			challenge.PaymentId.String(),
+			challenge.Customer.String(),
+			challenge.CodeHash,
+			challenge.Attempts,
+			challenge.State,
+			challenge.ExpiresAt,
+			challenge.CreatedAt,
+		)
+	}
+
+	t.Run("SaveChallenge", func(t *testing.T) {
+		mock.ExpectBegin()
+		tx, err := db.BeginTx(context.Background(), nil)
+		require.NoError(t, err)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO challenge (challenge_id, payment_id,
+		customer, code_hash, attempts, state,
+		expires_at, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			RETURNING *`)).WithArgs(
+			challenge.ChallengeId,
+			challenge.PaymentId,
+			challenge.Customer,
+			challenge.CodeHash,
+			challenge.Attempts,
+			challenge.State,
+			challenge.ExpiresAt,
+			challenge.CreatedAt,
+		).WillReturnRows(challengeRow())
+
+		saved, err := psql.SaveChallenge(context.Background(), challenge, tx)
+		require.NoError(t, err)
+		require.Equal(t, challenge.ChallengeId, saved.ChallengeId)
+	})
+
+	t.Run("GetChallenge", func(t *testing.T) {
+		mock.ExpectBegin()
+		tx, err := db.BeginTx(context.Background(), nil)
+		require.NoError(t, err)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM challenge WHERE challenge_id = $1 FOR UPDATE`)).
+			WithArgs(challenge.ChallengeId).WillReturnRows(challengeRow())
+
+		ch, err := psql.GetChallenge(context.Background(), challenge.ChallengeId, tx)
+		require.NoError(t, err)
+		require.Equal(t, types.ChallengePending, ch.State)
+		require.Equal(t, "hash", ch.CodeHash)
+	})
+
+	t.Run("UpdateChallenge", func(t *testing.T) {
+		mock.ExpectBegin()
+		tx, err := db.BeginTx(context.Background(), nil)
+		require.NoError(t, err)
+
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE challenge
+				SET attempts = $1,
+					state = $2
+				WHERE challenge_id = $3`)).
+			WithArgs(challenge.Attempts, challenge.State, challenge.ChallengeId).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		require.NoError(t, psql.UpdateChallenge(context.Background(), challenge, tx))
+	})
+
+	t.Run("GetExpiredChallenges", func(t *testing.T) {
+		now := time.Now()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM challenge
+				WHERE state = $1 AND expires_at <= $2
+				ORDER BY expires_at`)).WithArgs(types.ChallengePending, now).WillReturnRows(challengeRow())
+
+		challenges, err := psql.GetExpiredChallenges(context.Background(), now)
+		require.NoError(t, err)
+		require.Len(t, challenges, 1)
+	})
+}
+
+func Test_Transfer(t *testing.T) {
+	t.Parallel()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	psql := NewPostgresStorage(db)
+
+	colums := []string{
+		"payment_id",
+		"sender",
+		"receiver",
+		"idempotency_key",
+		"note",
+		"created_at",
+	}
+	req := &paymentpb.TransferRequest{
+		Sender:         uuid.New().String(),
+		Receiver:       uuid.New().String(),
+		Currency:       "RUB",
+		Amount:         40,
+		Note:           "dinner",
+		IdempotencyKey: "key-1",
+	}
+	transfer := types.CreateTransfer(types.CreateTransferPayment(req, "Successful transfer"), req)
+	transferRow := func() *sqlmock.Rows {
+		return sqlmock.NewRows(colums).AddRow(
+			transfer.PaymentId.String(),
+			transfer.Sender.String(),
+			transfer.Receiver.String(),
+			transfer.IdempotencyKey,
+			transfer.Note,
+			transfer.CreatedAt,
+		)
+	}
+
+	t.Run("SaveTransfer", func(t *testing.T) {
+		mock.ExpectBegin()
+		tx, err := db.BeginTx(context.Background(), nil)
+		require.NoError(t, err)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO transfer (payment_id, sender,
+		receiver, idempotency_key, note, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING *`)).WithArgs(
+			transfer.PaymentId,
+			transfer.Sender,
+			transfer.Receiver,
+			transfer.IdempotencyKey,
+			transfer.Note,
+			transfer.CreatedAt,
+		).WillReturnRows(transferRow())
+
+		saved, err := psql.SaveTransfer(context.Background(), transfer, tx)
+		require.NoError(t, err)
+		require.Equal(t, transfer.PaymentId, saved.PaymentId)
+	})
+
+	t.Run("SaveTransfer with used key", func(t *testing.T) {
+		mock.ExpectBegin()
+		tx, err := db.BeginTx(context.Background(), nil)
+		require.NoError(t, err)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO transfer`)).
+			WillReturnError(&pq.Error{Code: "23505"})
+
+		_, err = psql.SaveTransfer(context.Background(), transfer, tx)
+		require.ErrorIs(t, err, types.ErrDuplicateTransfer)
+	})
+
+	t.Run("GetTransferByKey", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM transfer WHERE sender = $1 AND idempotency_key = $2`)).
+			WithArgs(transfer.Sender, "key-1").WillReturnRows(transferRow())
+
+		tr, err := psql.GetTransferByKey(context.Background(), transfer.Sender, "key-1")
+		require.NoError(t, err)
+		require.Equal(t, "dinner", tr.Note)
+	})
+}
+
+func Test_Installment(t *testing.T) {
+	t.Parallel()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	psql := NewPostgresStorage(db)
+
+	colums := []string{
+		"installment_id",
+		"plan_id",
+		"seq",
+		"amount",
+		"fee",
+		"due_at",
+		"state",
+		"attempts",
+		"next_attempt_at",
+		"created_at",
+	}
+	plan := types.CreateInstallmentPlan(&types.Payment{
+		PaymentId: uuid.New(),
+		Merchant:  uuid.New(),
+		Customer:  uuid.New(),
+		Currency:  "RUB",
+		Amount:    100,
+		CreatedAt: time.Now(),
+	}, uuid.New(), 0)
+	installment := types.CreateInstallments(plan, 2, time.Hour)[0]
+	installmentRow := func() *sqlmock.Rows {
+		return sqlmock.NewRows(colums).AddRow(
+			installment.InstallmentId.String(),
+			installment.PlanId.String(),
+			installment.Seq,
+			installment.Amount,
+			installment.Fee,
+			installment.DueAt,
+			installment.State,
+			installment.Attempts,
+			installment.NextAttemptAt,
+			installment.CreatedAt,
+		)
+	}
+
+	t.Run("SaveInstallmentPlan", func(t *testing.T) {
+		mock.ExpectBegin()
+		tx, err := db.BeginTx(context.Background(), nil)
+		require.NoError(t, err)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO installment_plan (plan_id, payment_id,
+		authorization_id, merchant, customer, currency,
+		amount, fee, state, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			RETURNING *`)).WithArgs(
+			plan.PlanId,
+			plan.PaymentId,
+			plan.AuthorizationId,
+			plan.Merchant,
+			plan.Customer,
+			plan.Currency,
+			plan.Amount,
+			plan.Fee,
+			plan.State,
+			plan.CreatedAt,
+		).WillReturnRows(sqlmock.NewRows([]string{
+			"plan_id",
+			"payment_id",
+			"authorization_id",
+			"merchant",
+			"customer",
+			"currency",
+			"amount",
+			"fee",
+			"state",
+			"created_at",
+		}).AddRow(
+			plan.PlanId.String(),
+			plan.PaymentId.String(),
+			plan.AuthorizationId.String(),
+			plan.Merchant.String(),
+			plan.Customer.String(),
+			plan.Currency,
+			plan.Amount,
+			plan.Fee,
+			plan.State,
+			plan.CreatedAt,
+		))
+
+		saved, err := psql.SaveInstallmentPlan(context.Background(), plan, tx)
+		require.NoError(t, err)
+		require.Equal(t, plan.PlanId, saved.PlanId)
+		require.Equal(t, types.PlanActive, saved.State)
+	})
+
+	t.Run("GetDueInstallments", func(t *testing.T) {
+		now := time.Now()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM installment
+				WHERE state IN ($1, $2) AND next_attempt_at <= $3
+				ORDER BY next_attempt_at`)).
+			WithArgs(types.InstallmentScheduled, types.InstallmentDelinquent, now).
+			WillReturnRows(installmentRow())
+
+		installments, err := psql.GetDueInstallments(context.Background(), now)
+		require.NoError(t, err)
+		require.Len(t, installments, 1)
+		require.Equal(t, uint64(50), installments[0].Amount)
+	})
+
+	t.Run("GetDelinquentInstallments", func(t *testing.T) {
+		req := &paymentpb.DelinquentRequest{Merchant: plan.Merchant.String()}
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT i.* FROM installment i`)).
+			WithArgs(types.InstallmentDelinquent, types.InstallmentDefaulted, req.Merchant, "").
+			WillReturnRows(installmentRow())
+
+		installments, err := psql.GetDelinquentInstallments(context.Background(), req)
+		require.NoError(t, err)
+		require.Len(t, installments, 1)
+	})
+
+	t.Run("UpdateInstallment", func(t *testing.T) {
+		mock.ExpectBegin()
+		tx, err := db.BeginTx(context.Background(), nil)
+		require.NoError(t, err)
+
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE installment
+				SET state = $1,
+					attempts = $2,
+					next_attempt_at = $3
+				WHERE installment_id = $4`)).
+			WithArgs(installment.State, installment.Attempts, installment.NextAttemptAt, installment.InstallmentId).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		require.NoError(t, psql.UpdateInstallment(context.Background(), installment, tx))
+	})
+
+	t.Run("RefreshInstallmentPlan", func(t *testing.T) {
+		mock.ExpectBegin()
+		tx, err := db.BeginTx(context.Background(), nil)
+		require.NoError(t, err)
+
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE installment_plan`)).
+			WithArgs(
+				plan.PlanId,
+				types.InstallmentPaid, types.PlanCompleted,
+				types.InstallmentDefaulted, types.PlanDefaulted,
+				types.InstallmentDelinquent, types.PlanDelinquent,
+				types.PlanActive,
+			).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		require.NoError(t, psql.RefreshInstallmentPlan(context.Background(), plan.PlanId, tx))
+	})
+}
+
+func Test_ScheduledPayment(t *testing.T) {
+	t.Parallel()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	psql := NewPostgresStorage(db)
+
+	colums := []string{
+		"payment_id",
+		"merchant",
+		"customer",
+		"currency",
+		"amount",
+		"execute_at",
+		"state",
+		"result_id",
+		"created_at",
+		"updated_at",
+		"claimed_at",
+	}
+	scheduled := types.CreateScheduledPayment(&types.Payment{
+		PaymentId: uuid.New(),
+		Merchant:  uuid.New(),
+		Customer:  uuid.New(),
+		Currency:  "RUB",
+		Amount:    50,
+		CreatedAt: time.Now(),
+	}, time.Now().Add(time.Hour))
+	scheduledRow := func() *sqlmock.Rows {
+		return sqlmock.NewRows(colums).AddRow(
+			scheduled.PaymentId.String(),
+			scheduled.Merchant.String(),
+			scheduled.Customer.String(),
+			scheduled.Currency,
+			scheduled.Amount,
+			scheduled.ExecuteAt,
+			scheduled.State,
+			nil,
+			scheduled.CreatedAt,
+			scheduled.UpdatedAt,
+			nil,
+		)
+	}
+
+	t.Run("SaveScheduledPayment", func(t *testing.T) {
+		mock.ExpectBegin()
+		tx, err := db.BeginTx(context.Background(), nil)
+		require.NoError(t, err)
+
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO scheduled_payment (payment_id, execute_at,
+		state, result_id, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6)`)).WithArgs(
+			scheduled.PaymentId,
+			scheduled.ExecuteAt,
+			scheduled.State,
+			scheduled.ResultId,
+			scheduled.CreatedAt,
+			scheduled.UpdatedAt,
+		).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		require.NoError(t, psql.SaveScheduledPayment(context.Background(), scheduled, tx))
+	})
+
+	t.Run("GetScheduledPayment", func(t *testing.T) {
+		mock.ExpectBegin()
+		tx, err := db.BeginTx(context.Background(), nil)
+		require.NoError(t, err)
+
+		mock.ExpectQuery(`SELECT s.payment_id, p.merchant, (.+) WHERE s.payment_id = \$1 FOR UPDATE OF s`).
+			WithArgs(scheduled.PaymentId).WillReturnRows(scheduledRow())
+
+		sp, err := psql.GetScheduledPayment(context.Background(), scheduled.PaymentId, tx)
+		require.NoError(t, err)
+		require.Equal(t, types.ScheduledPending, sp.State)
+		require.Equal(t, scheduled.Merchant, sp.Merchant)
+		require.False(t, sp.ResultId.Valid)
+	})
+
+	t.Run("GetDueScheduledPayments", func(t *testing.T) {
+		now := time.Now()
+		claimedBefore := now.Add(-time.Minute)
+		mock.ExpectQuery(`SELECT s.payment_id, p.merchant, (.+) WHERE s.state = \$1 AND s.execute_at <= \$2
+					OR s.state = \$3 AND s.claimed_at <= \$4`).
+			WithArgs(types.ScheduledPending, now, types.ScheduledProcessing, claimedBefore).WillReturnRows(scheduledRow())
+
+		payments, err := psql.GetDueScheduledPayments(context.Background(), now, claimedBefore)
+		require.NoError(t, err)
+		require.Len(t, payments, 1)
+	})
+
+	t.Run("UpdateScheduledPayment", func(t *testing.T) {
+		mock.ExpectBegin()
+		tx, err := db.BeginTx(context.Background(), nil)
+		require.NoError(t, err)
+
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE scheduled_payment
+				SET execute_at = $1,
+					state = $2,
+					result_id = $3,
+					updated_at = $4,
+					claimed_at = $5
+				WHERE payment_id = $6`)).
+			WithArgs(scheduled.ExecuteAt, scheduled.State, scheduled.ResultId, scheduled.UpdatedAt, scheduled.ClaimedAt, scheduled.PaymentId).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		require.NoError(t, psql.UpdateScheduledPayment(context.Background(), scheduled, tx))
+	})
+}
+
+func Test_CardReference(t *testing.T) {
+	t.Parallel()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	psql := NewPostgresStorage(db)
+
+	ref := &types.CardReference{
+		TerminalId: "T0000001",
+		Rrn:        "000000000042",
+		PaymentId:  uuid.New(),
+		CreatedAt:  time.Now(),
+	}
+
+	t.Run("SaveCardReference", func(t *testing.T) {
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO card_reference (terminal_id, rrn,
+		payment_id, completed_by, created_at)
+			VALUES ($1, $2, $3, $4, $5)`)).WithArgs(
+			ref.TerminalId,
+			ref.Rrn,
+			ref.PaymentId,
+			"",
+			ref.CreatedAt,
+		).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		require.NoError(t, psql.SaveCardReference(context.Background(), ref))
+	})
+
+	t.Run("GetCardReference", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"terminal_id", "rrn", "payment_id", "completed_by", "created_at"}).AddRow(
+			ref.TerminalId,
+			ref.Rrn,
+			ref.PaymentId.String(),
+			"",
+			ref.CreatedAt,
+		)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM card_reference WHERE terminal_id = $1 AND rrn = $2`)).
+			WithArgs(ref.TerminalId, ref.Rrn).WillReturnRows(rows)
+
+		saved, err := psql.GetCardReference(context.Background(), ref.TerminalId, ref.Rrn)
+		require.NoError(t, err)
+		require.Equal(t, ref.PaymentId, saved.PaymentId)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("UpdateCardReference", func(t *testing.T) {
+		query := regexp.QuoteMeta(`UPDATE card_reference SET completed_by = $4
+		WHERE terminal_id = $1 AND rrn = $2 AND completed_by = $3`)
+		mock.ExpectExec(query).WithArgs(ref.TerminalId, ref.Rrn, "", "0200").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		// already completed
+		mock.ExpectExec(query).WithArgs(ref.TerminalId, ref.Rrn, "", "0400").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		claimed, err := psql.UpdateCardReference(context.Background(), ref.TerminalId, ref.Rrn, "", "0200")
+		require.NoError(t, err)
+		require.True(t, claimed)
+		claimed, err = psql.UpdateCardReference(context.Background(), ref.TerminalId, ref.Rrn, "", "0400")
+		require.NoError(t, err)
+		require.False(t, claimed)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
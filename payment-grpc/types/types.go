@@ -1,6 +1,8 @@
 package types
 
 import (
+	"database/sql"
+	"errors"
 	"time"
 
 	authpb "github.com/Edbeer/payment-proto/auth-grpc/proto"
@@ -20,6 +22,15 @@ type Payment struct {
 	Status          string    `json:"status"`
 	Amount          uint64    `json:"amount"`
 	CreatedAt       time.Time `json:"creation_at"`
+	// split payment this row belongs to
+	ParentId uuid.NullUUID `json:"parent_id"`
+	// captured amount is held in escrow
+	Escrow          bool   `json:"escrow"`
+	EscrowHoldHours uint32 `json:"escrow_hold_hours"`
+	// authorization changed by this row
+	ReferenceId uuid.NullUUID `json:"reference_id"`
+	// auth service hold of the authorized money
+	HoldId uuid.NullUUID `json:"hold_id"`
 }
 
 func CreateAuthPayment(req *paymentpb.CreateRequest, customer *authpb.Account, merchant *authpb.Account, status string) *Payment {
@@ -43,6 +54,8 @@ func CreateAuthPayment(req *paymentpb.CreateRequest, customer *authpb.Account, m
 		Status:          status,
 		Amount:          req.Amount,
 		CreatedAt:       time.Now(),
+		Escrow:          req.Escrow,
+		EscrowHoldHours: req.EscrowHoldHours,
 	}
 }
 
@@ -60,6 +73,329 @@ func CreateCompletePayment(paidReq *paymentpb.PaidRequest, referncedPayment *Pay
 		Status:          status,
 		Amount:          paidReq.Amount,
 		CreatedAt:       time.Now(),
+		Escrow:          referncedPayment.Escrow,
+		EscrowHoldHours: referncedPayment.EscrowHoldHours,
 	}
 }
 
+
+// creating a seller part of a split payment
+func CreateSplitPayment(parent *Payment, merchant uuid.UUID, amount uint64) *Payment {
+	return &Payment{
+		PaymentId:       uuid.New(),
+		Merchant:        merchant,
+		Customer:        parent.Customer,
+		CardNumber:      parent.CardNumber,
+		CardExpiryMonth: parent.CardExpiryMonth,
+		CardExpiryYear:  parent.CardExpiryYear,
+		Currency:        parent.Currency,
+		Operation:       parent.Operation,
+		Status:          parent.Status,
+		Amount:          amount,
+		CreatedAt:       time.Now(),
+		ParentId:        uuid.NullUUID{UUID: parent.PaymentId, Valid: true},
+	}
+}
+
+// creating a transfer between accounts,
+// the receiver is kept as merchant and the sender as customer
+func CreateTransferPayment(req *paymentpb.TransferRequest, status string) *Payment {
+	receiver, err := uuid.Parse(req.Receiver)
+	if err != nil {
+		return nil
+	}
+	sender, err := uuid.Parse(req.Sender)
+	if err != nil {
+		return nil
+	}
+	return &Payment{
+		PaymentId: uuid.New(),
+		Merchant:  receiver,
+		Customer:  sender,
+		Currency:  req.Currency,
+		Operation: "Transfer",
+		Status:    status,
+		Amount:    req.Amount,
+		CreatedAt: time.Now(),
+	}
+}
+
+// Payment event, one per saved payment row
+type PaymentEvent struct {
+	Cursor    uint64    `json:"cursor"`
+	PaymentId uuid.UUID `json:"payment_id"`
+	Merchant  uuid.UUID `json:"merchant"`
+	Customer  uuid.UUID `json:"customer"`
+	Currency  string    `json:"currency"`
+	Operation string    `json:"operation"`
+	Status    string    `json:"status"`
+	Amount    uint64    `json:"amount"`
+	CreatedAt time.Time `json:"created_at"`
+	// split payment this row belongs to
+	ParentId uuid.NullUUID `json:"parent_id"`
+}
+
+// Escrow states
+const (
+	EscrowHeld     = "Held"
+	EscrowReleased = "Released"
+	EscrowRefunded = "Refunded"
+)
+
+// Escrow hold of a captured payment
+type Escrow struct {
+	PaymentId uuid.UUID    `json:"payment_id"`
+	Merchant  uuid.UUID    `json:"merchant"`
+	Customer  uuid.UUID    `json:"customer"`
+	Currency  string       `json:"currency"`
+	Amount    uint64       `json:"amount"`
+	State     string       `json:"state"`
+	ReleaseAt sql.NullTime `json:"release_at"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+// creating escrow hold for a captured payment
+func CreateEscrow(capture *Payment) *Escrow {
+	escrow := &Escrow{
+		PaymentId: capture.PaymentId,
+		Merchant:  capture.Merchant,
+		Customer:  capture.Customer,
+		Currency:  capture.Currency,
+		Amount:    capture.Amount,
+		State:     EscrowHeld,
+		CreatedAt: time.Now(),
+	}
+	if capture.EscrowHoldHours > 0 {
+		escrow.ReleaseAt = sql.NullTime{
+			Time:  escrow.CreatedAt.Add(time.Duration(capture.EscrowHoldHours) * time.Hour),
+			Valid: true,
+		}
+	}
+	return escrow
+}
+
+// Challenge states
+const (
+	ChallengePending   = "Pending"
+	ChallengeCompleted = "Completed"
+	ChallengeFailed    = "Failed"
+	ChallengeExpired   = "Expired"
+)
+
+// 3-D Secure challenge of a payment
+type Challenge struct {
+	ChallengeId uuid.UUID `json:"challenge_id"`
+	PaymentId   uuid.UUID `json:"payment_id"`
+	Customer    uuid.UUID `json:"customer"`
+	CodeHash    string    `json:"-"`
+	Attempts    uint32    `json:"attempts"`
+	State       string    `json:"state"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// creating challenge for a payment waiting for the customer
+func CreateChallenge(payment *Payment, codeHash string, ttl time.Duration) *Challenge {
+	now := time.Now()
+	return &Challenge{
+		ChallengeId: uuid.New(),
+		PaymentId:   payment.PaymentId,
+		Customer:    payment.Customer,
+		CodeHash:    codeHash,
+		State:       ChallengePending,
+		ExpiresAt:   now.Add(ttl),
+		CreatedAt:   now,
+	}
+}
+
+// idempotency key of the sender is taken by a saved transfer
+var ErrDuplicateTransfer = errors.New("transfer with the idempotency key exists")
+
+// Transfer details of a payment row
+type Transfer struct {
+	PaymentId      uuid.UUID `json:"payment_id"`
+	Sender         uuid.UUID `json:"sender"`
+	Receiver       uuid.UUID `json:"receiver"`
+	IdempotencyKey string    `json:"idempotency_key"`
+	Note           string    `json:"note"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// creating transfer details for a saved payment
+func CreateTransfer(payment *Payment, req *paymentpb.TransferRequest) *Transfer {
+	return &Transfer{
+		PaymentId:      payment.PaymentId,
+		Sender:         payment.Customer,
+		Receiver:       payment.Merchant,
+		IdempotencyKey: req.IdempotencyKey,
+		Note:           req.Note,
+		CreatedAt:      payment.CreatedAt,
+	}
+}
+
+// Installment plan states
+const (
+	PlanActive     = "Active"
+	PlanDelinquent = "Delinquent"
+	PlanDefaulted  = "Defaulted"
+	PlanCompleted  = "Completed"
+)
+
+// Installment states
+const (
+	InstallmentScheduled  = "Scheduled"
+	InstallmentPaid       = "Paid"
+	InstallmentDelinquent = "Delinquent"
+	InstallmentDefaulted  = "Defaulted"
+)
+
+// Authorization paid by the customer in several installments
+type InstallmentPlan struct {
+	PlanId          uuid.UUID `json:"plan_id"`
+	PaymentId       uuid.UUID `json:"payment_id"`
+	AuthorizationId uuid.UUID `json:"authorization_id"`
+	Merchant        uuid.UUID `json:"merchant"`
+	Customer        uuid.UUID `json:"customer"`
+	Currency        string    `json:"currency"`
+	Amount          uint64    `json:"amount"`
+	Fee             uint64    `json:"fee"`
+	State           string    `json:"state"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// Scheduled charge of the customer
+type Installment struct {
+	InstallmentId uuid.UUID `json:"installment_id"`
+	PlanId        uuid.UUID `json:"plan_id"`
+	Seq           uint32    `json:"seq"`
+	Amount        uint64    `json:"amount"`
+	Fee           uint64    `json:"fee"`
+	DueAt         time.Time `json:"due_at"`
+	State         string    `json:"state"`
+	Attempts      uint32    `json:"attempts"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// creating plan for the payment of the converted authorization
+func CreateInstallmentPlan(payment *Payment, authorizationID uuid.UUID, fee uint64) *InstallmentPlan {
+	return &InstallmentPlan{
+		PlanId:          uuid.New(),
+		PaymentId:       payment.PaymentId,
+		AuthorizationId: authorizationID,
+		Merchant:        payment.Merchant,
+		Customer:        payment.Customer,
+		Currency:        payment.Currency,
+		Amount:          payment.Amount,
+		Fee:             fee,
+		State:           PlanActive,
+		CreatedAt:       payment.CreatedAt,
+	}
+}
+
+// creating count installments every interval after the plan creation,
+// the remainder of the division is paid with the first installments
+func CreateInstallments(plan *InstallmentPlan, count uint32, interval time.Duration) []*Installment {
+	installments := make([]*Installment, 0, count)
+	n := uint64(count)
+	for i := uint64(0); i < n; i++ {
+		amount := plan.Amount / n
+		if i < plan.Amount%n {
+			amount++
+		}
+		fee := plan.Fee / n
+		if i < plan.Fee%n {
+			fee++
+		}
+		dueAt := plan.CreatedAt.Add(time.Duration(i+1) * interval)
+		installments = append(installments, &Installment{
+			InstallmentId: uuid.New(),
+			PlanId:        plan.PlanId,
+			Seq:           uint32(i + 1),
+			Amount:        amount + fee,
+			Fee:           fee,
+			DueAt:         dueAt,
+			State:         InstallmentScheduled,
+			NextAttemptAt: dueAt,
+			CreatedAt:     plan.CreatedAt,
+		})
+	}
+	return installments
+}
+
+// creating payment row of the installment charge
+func CreateInstallmentPayment(plan *InstallmentPlan, installment *Installment, status string) *Payment {
+	return &Payment{
+		PaymentId:   uuid.New(),
+		Merchant:    plan.Merchant,
+		Customer:    plan.Customer,
+		Currency:    plan.Currency,
+		Operation:   "Installment",
+		Status:      status,
+		Amount:      installment.Amount,
+		CreatedAt:   time.Now(),
+		ReferenceId: uuid.NullUUID{UUID: plan.PaymentId, Valid: true},
+	}
+}
+
+// Scheduled payment states
+const (
+	ScheduledPending    = "Scheduled"
+	ScheduledProcessing = "Processing"
+	ScheduledExecuted   = "Executed"
+	ScheduledFailed     = "Failed"
+	ScheduledCancelled  = "Cancelled"
+)
+
+// execution of the scheduled payment was taken over by another replica
+var ErrClaimLost = errors.New("scheduled payment is claimed by another replica")
+
+// Payment authorized and captured at the execution time
+type ScheduledPayment struct {
+	PaymentId uuid.UUID `json:"payment_id"`
+	Merchant  uuid.UUID `json:"merchant"`
+	Customer  uuid.UUID `json:"customer"`
+	Currency  string    `json:"currency"`
+	Amount    uint64    `json:"amount"`
+	ExecuteAt time.Time `json:"execute_at"`
+	State     string    `json:"state"`
+	// capture or declined authorization of the execution
+	ResultId  uuid.NullUUID `json:"result_id"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+	// start of the execution by a replica
+	ClaimedAt sql.NullTime `json:"claimed_at"`
+}
+
+// creating schedule of the payment in "Scheduled" status
+func CreateScheduledPayment(payment *Payment, executeAt time.Time) *ScheduledPayment {
+	return &ScheduledPayment{
+		PaymentId: payment.PaymentId,
+		Merchant:  payment.Merchant,
+		Customer:  payment.Customer,
+		Currency:  payment.Currency,
+		Amount:    payment.Amount,
+		ExecuteAt: executeAt,
+		State:     ScheduledPending,
+		CreatedAt: payment.CreatedAt,
+		UpdatedAt: payment.CreatedAt,
+	}
+}
+
+// Authorization of the card terminal by retrieval reference number
+type CardReference struct {
+	TerminalId string    `json:"terminal_id"`
+	Rrn        string    `json:"rrn"`
+	PaymentId  uuid.UUID `json:"payment_id"`
+	// message type of the completion or reversal, empty - not completed
+	CompletedBy string    `json:"completed_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Roles of the accounts, set by the auth service
+const (
+	RoleCustomer = "customer"
+	RoleMerchant = "merchant"
+	RoleSupport  = "support"
+	RoleAdmin    = "admin"
+)
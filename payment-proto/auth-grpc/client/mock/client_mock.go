@@ -0,0 +1,797 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/Edbeer/payment-proto/auth-grpc/proto (interfaces: AuthServiceClient)
+
+// Package mock_proto is a generated GoMock package.
+package mock_proto
+
+import (
+	context "context"
+	reflect "reflect"
+
+	authpb "github.com/Edbeer/payment-proto/auth-grpc/proto"
+	gomock "github.com/golang/mock/gomock"
+	grpc "google.golang.org/grpc"
+)
+
+// MockAuthServiceClient is a mock of AuthServiceClient interface.
+type MockAuthServiceClient struct {
+	ctrl     *gomock.Controller
+	recorder *MockAuthServiceClientMockRecorder
+}
+
+// MockAuthServiceClientMockRecorder is the mock recorder for MockAuthServiceClient.
+type MockAuthServiceClientMockRecorder struct {
+	mock *MockAuthServiceClient
+}
+
+// NewMockAuthServiceClient creates a new mock instance.
+func NewMockAuthServiceClient(ctrl *gomock.Controller) *MockAuthServiceClient {
+	mock := &MockAuthServiceClient{ctrl: ctrl}
+	mock.recorder = &MockAuthServiceClientMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAuthServiceClient) EXPECT() *MockAuthServiceClientMockRecorder {
+	return m.recorder
+}
+
+// ApplyPaymentMovements mocks base method.
+func (m *MockAuthServiceClient) ApplyPaymentMovements(arg0 context.Context, arg1 *authpb.MovementsRequest, arg2 ...grpc.CallOption) (*authpb.Accounts, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ApplyPaymentMovements", varargs...)
+	ret0, _ := ret[0].(*authpb.Accounts)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ApplyPaymentMovements indicates an expected call of ApplyPaymentMovements.
+func (mr *MockAuthServiceClientMockRecorder) ApplyPaymentMovements(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplyPaymentMovements", reflect.TypeOf((*MockAuthServiceClient)(nil).ApplyPaymentMovements), varargs...)
+}
+
+// CaptureHold mocks base method.
+func (m *MockAuthServiceClient) CaptureHold(arg0 context.Context, arg1 *authpb.CaptureHoldRequest, arg2 ...grpc.CallOption) (*authpb.Hold, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "CaptureHold", varargs...)
+	ret0, _ := ret[0].(*authpb.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CaptureHold indicates an expected call of CaptureHold.
+func (mr *MockAuthServiceClientMockRecorder) CaptureHold(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CaptureHold", reflect.TypeOf((*MockAuthServiceClient)(nil).CaptureHold), varargs...)
+}
+
+// ChangeHold mocks base method.
+func (m *MockAuthServiceClient) ChangeHold(arg0 context.Context, arg1 *authpb.ChangeHoldRequest, arg2 ...grpc.CallOption) (*authpb.Hold, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ChangeHold", varargs...)
+	ret0, _ := ret[0].(*authpb.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ChangeHold indicates an expected call of ChangeHold.
+func (mr *MockAuthServiceClientMockRecorder) ChangeHold(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeHold", reflect.TypeOf((*MockAuthServiceClient)(nil).ChangeHold), varargs...)
+}
+
+// ChangePassword mocks base method.
+func (m *MockAuthServiceClient) ChangePassword(arg0 context.Context, arg1 *authpb.ChangePasswordRequest, arg2 ...grpc.CallOption) (*authpb.ChangePasswordResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ChangePassword", varargs...)
+	ret0, _ := ret[0].(*authpb.ChangePasswordResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ChangePassword indicates an expected call of ChangePassword.
+func (mr *MockAuthServiceClientMockRecorder) ChangePassword(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangePassword", reflect.TypeOf((*MockAuthServiceClient)(nil).ChangePassword), varargs...)
+}
+
+// CreateAccount mocks base method.
+func (m *MockAuthServiceClient) CreateAccount(arg0 context.Context, arg1 *authpb.CreateRequest, arg2 ...grpc.CallOption) (*authpb.AccountWithTokens, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "CreateAccount", varargs...)
+	ret0, _ := ret[0].(*authpb.AccountWithTokens)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAccount indicates an expected call of CreateAccount.
+func (mr *MockAuthServiceClientMockRecorder) CreateAccount(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAccount", reflect.TypeOf((*MockAuthServiceClient)(nil).CreateAccount), varargs...)
+}
+
+// CreateHold mocks base method.
+func (m *MockAuthServiceClient) CreateHold(arg0 context.Context, arg1 *authpb.CreateHoldRequest, arg2 ...grpc.CallOption) (*authpb.Hold, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "CreateHold", varargs...)
+	ret0, _ := ret[0].(*authpb.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateHold indicates an expected call of CreateHold.
+func (mr *MockAuthServiceClientMockRecorder) CreateHold(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateHold", reflect.TypeOf((*MockAuthServiceClient)(nil).CreateHold), varargs...)
+}
+
+// CreateOAuthClient mocks base method.
+func (m *MockAuthServiceClient) CreateOAuthClient(arg0 context.Context, arg1 *authpb.CreateOAuthClientRequest, arg2 ...grpc.CallOption) (*authpb.OAuthClient, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "CreateOAuthClient", varargs...)
+	ret0, _ := ret[0].(*authpb.OAuthClient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateOAuthClient indicates an expected call of CreateOAuthClient.
+func (mr *MockAuthServiceClientMockRecorder) CreateOAuthClient(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOAuthClient", reflect.TypeOf((*MockAuthServiceClient)(nil).CreateOAuthClient), varargs...)
+}
+
+// CreateStatement mocks base method.
+func (m *MockAuthServiceClient) CreateStatement(arg0 context.Context, arg1 ...grpc.CallOption) (authpb.AuthService_CreateStatementClient, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0}
+	for _, a := range arg1 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "CreateStatement", varargs...)
+	ret0, _ := ret[0].(authpb.AuthService_CreateStatementClient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateStatement indicates an expected call of CreateStatement.
+func (mr *MockAuthServiceClientMockRecorder) CreateStatement(arg0 interface{}, arg1 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0}, arg1...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateStatement", reflect.TypeOf((*MockAuthServiceClient)(nil).CreateStatement), varargs...)
+}
+
+// DeleteAccount mocks base method.
+func (m *MockAuthServiceClient) DeleteAccount(arg0 context.Context, arg1 *authpb.DeleteRequest, arg2 ...grpc.CallOption) (*authpb.DeleteResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DeleteAccount", varargs...)
+	ret0, _ := ret[0].(*authpb.DeleteResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteAccount indicates an expected call of DeleteAccount.
+func (mr *MockAuthServiceClientMockRecorder) DeleteAccount(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAccount", reflect.TypeOf((*MockAuthServiceClient)(nil).DeleteAccount), varargs...)
+}
+
+// DeleteOAuthClient mocks base method.
+func (m *MockAuthServiceClient) DeleteOAuthClient(arg0 context.Context, arg1 *authpb.DeleteOAuthClientRequest, arg2 ...grpc.CallOption) (*authpb.DeleteOAuthClientResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DeleteOAuthClient", varargs...)
+	ret0, _ := ret[0].(*authpb.DeleteOAuthClientResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteOAuthClient indicates an expected call of DeleteOAuthClient.
+func (mr *MockAuthServiceClientMockRecorder) DeleteOAuthClient(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOAuthClient", reflect.TypeOf((*MockAuthServiceClient)(nil).DeleteOAuthClient), varargs...)
+}
+
+// DepositAccount mocks base method.
+func (m *MockAuthServiceClient) DepositAccount(arg0 context.Context, arg1 *authpb.DepositRequest, arg2 ...grpc.CallOption) (*authpb.DepositResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DepositAccount", varargs...)
+	ret0, _ := ret[0].(*authpb.DepositResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DepositAccount indicates an expected call of DepositAccount.
+func (mr *MockAuthServiceClientMockRecorder) DepositAccount(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DepositAccount", reflect.TypeOf((*MockAuthServiceClient)(nil).DepositAccount), varargs...)
+}
+
+// DisableTOTP mocks base method.
+func (m *MockAuthServiceClient) DisableTOTP(arg0 context.Context, arg1 *authpb.DisableTOTPRequest, arg2 ...grpc.CallOption) (*authpb.DisableTOTPResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DisableTOTP", varargs...)
+	ret0, _ := ret[0].(*authpb.DisableTOTPResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DisableTOTP indicates an expected call of DisableTOTP.
+func (mr *MockAuthServiceClientMockRecorder) DisableTOTP(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisableTOTP", reflect.TypeOf((*MockAuthServiceClient)(nil).DisableTOTP), varargs...)
+}
+
+// EnrollTOTP mocks base method.
+func (m *MockAuthServiceClient) EnrollTOTP(arg0 context.Context, arg1 *authpb.EnrollTOTPRequest, arg2 ...grpc.CallOption) (*authpb.EnrollTOTPResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "EnrollTOTP", varargs...)
+	ret0, _ := ret[0].(*authpb.EnrollTOTPResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EnrollTOTP indicates an expected call of EnrollTOTP.
+func (mr *MockAuthServiceClientMockRecorder) EnrollTOTP(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnrollTOTP", reflect.TypeOf((*MockAuthServiceClient)(nil).EnrollTOTP), varargs...)
+}
+
+// GetAccount mocks base method.
+func (m *MockAuthServiceClient) GetAccount(arg0 context.Context, arg1 *authpb.GetRequest, arg2 ...grpc.CallOption) (authpb.AuthService_GetAccountClient, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetAccount", varargs...)
+	ret0, _ := ret[0].(authpb.AuthService_GetAccountClient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAccount indicates an expected call of GetAccount.
+func (mr *MockAuthServiceClientMockRecorder) GetAccount(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccount", reflect.TypeOf((*MockAuthServiceClient)(nil).GetAccount), varargs...)
+}
+
+// GetAccountByID mocks base method.
+func (m *MockAuthServiceClient) GetAccountByID(arg0 context.Context, arg1 *authpb.GetIDRequest, arg2 ...grpc.CallOption) (*authpb.Account, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetAccountByID", varargs...)
+	ret0, _ := ret[0].(*authpb.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAccountByID indicates an expected call of GetAccountByID.
+func (mr *MockAuthServiceClientMockRecorder) GetAccountByID(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccountByID", reflect.TypeOf((*MockAuthServiceClient)(nil).GetAccountByID), varargs...)
+}
+
+// GetAccountsByIDs mocks base method.
+func (m *MockAuthServiceClient) GetAccountsByIDs(arg0 context.Context, arg1 *authpb.GetIDsRequest, arg2 ...grpc.CallOption) (*authpb.Accounts, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetAccountsByIDs", varargs...)
+	ret0, _ := ret[0].(*authpb.Accounts)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAccountsByIDs indicates an expected call of GetAccountsByIDs.
+func (mr *MockAuthServiceClientMockRecorder) GetAccountsByIDs(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccountsByIDs", reflect.TypeOf((*MockAuthServiceClient)(nil).GetAccountsByIDs), varargs...)
+}
+
+// GetHolds mocks base method.
+func (m *MockAuthServiceClient) GetHolds(arg0 context.Context, arg1 *authpb.GetHoldsRequest, arg2 ...grpc.CallOption) (*authpb.Holds, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetHolds", varargs...)
+	ret0, _ := ret[0].(*authpb.Holds)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHolds indicates an expected call of GetHolds.
+func (mr *MockAuthServiceClientMockRecorder) GetHolds(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHolds", reflect.TypeOf((*MockAuthServiceClient)(nil).GetHolds), varargs...)
+}
+
+// GetJWKS mocks base method.
+func (m *MockAuthServiceClient) GetJWKS(arg0 context.Context, arg1 *authpb.JWKSRequest, arg2 ...grpc.CallOption) (*authpb.JWKS, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetJWKS", varargs...)
+	ret0, _ := ret[0].(*authpb.JWKS)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetJWKS indicates an expected call of GetJWKS.
+func (mr *MockAuthServiceClientMockRecorder) GetJWKS(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetJWKS", reflect.TypeOf((*MockAuthServiceClient)(nil).GetJWKS), varargs...)
+}
+
+// GetStatement mocks base method.
+func (m *MockAuthServiceClient) GetStatement(arg0 context.Context, arg1 *authpb.StatementGet, arg2 ...grpc.CallOption) (authpb.AuthService_GetStatementClient, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetStatement", varargs...)
+	ret0, _ := ret[0].(authpb.AuthService_GetStatementClient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetStatement indicates an expected call of GetStatement.
+func (mr *MockAuthServiceClientMockRecorder) GetStatement(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStatement", reflect.TypeOf((*MockAuthServiceClient)(nil).GetStatement), varargs...)
+}
+
+// IssueClientToken mocks base method.
+func (m *MockAuthServiceClient) IssueClientToken(arg0 context.Context, arg1 *authpb.ClientTokenRequest, arg2 ...grpc.CallOption) (*authpb.ClientToken, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "IssueClientToken", varargs...)
+	ret0, _ := ret[0].(*authpb.ClientToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IssueClientToken indicates an expected call of IssueClientToken.
+func (mr *MockAuthServiceClientMockRecorder) IssueClientToken(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IssueClientToken", reflect.TypeOf((*MockAuthServiceClient)(nil).IssueClientToken), varargs...)
+}
+
+// ListOAuthClients mocks base method.
+func (m *MockAuthServiceClient) ListOAuthClients(arg0 context.Context, arg1 *authpb.ListOAuthClientsRequest, arg2 ...grpc.CallOption) (*authpb.OAuthClients, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ListOAuthClients", varargs...)
+	ret0, _ := ret[0].(*authpb.OAuthClients)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListOAuthClients indicates an expected call of ListOAuthClients.
+func (mr *MockAuthServiceClientMockRecorder) ListOAuthClients(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListOAuthClients", reflect.TypeOf((*MockAuthServiceClient)(nil).ListOAuthClients), varargs...)
+}
+
+// ListSessions mocks base method.
+func (m *MockAuthServiceClient) ListSessions(arg0 context.Context, arg1 *authpb.ListSessionsRequest, arg2 ...grpc.CallOption) (*authpb.Sessions, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ListSessions", varargs...)
+	ret0, _ := ret[0].(*authpb.Sessions)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSessions indicates an expected call of ListSessions.
+func (mr *MockAuthServiceClientMockRecorder) ListSessions(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSessions", reflect.TypeOf((*MockAuthServiceClient)(nil).ListSessions), varargs...)
+}
+
+// RefreshTokens mocks base method.
+func (m *MockAuthServiceClient) RefreshTokens(arg0 context.Context, arg1 *authpb.RefreshRequest, arg2 ...grpc.CallOption) (*authpb.Tokens, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "RefreshTokens", varargs...)
+	ret0, _ := ret[0].(*authpb.Tokens)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RefreshTokens indicates an expected call of RefreshTokens.
+func (mr *MockAuthServiceClientMockRecorder) RefreshTokens(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefreshTokens", reflect.TypeOf((*MockAuthServiceClient)(nil).RefreshTokens), varargs...)
+}
+
+// ReleaseHold mocks base method.
+func (m *MockAuthServiceClient) ReleaseHold(arg0 context.Context, arg1 *authpb.ReleaseHoldRequest, arg2 ...grpc.CallOption) (*authpb.Hold, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ReleaseHold", varargs...)
+	ret0, _ := ret[0].(*authpb.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReleaseHold indicates an expected call of ReleaseHold.
+func (mr *MockAuthServiceClientMockRecorder) ReleaseHold(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseHold", reflect.TypeOf((*MockAuthServiceClient)(nil).ReleaseHold), varargs...)
+}
+
+// RequestPasswordReset mocks base method.
+func (m *MockAuthServiceClient) RequestPasswordReset(arg0 context.Context, arg1 *authpb.PasswordResetRequest, arg2 ...grpc.CallOption) (*authpb.PasswordResetResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "RequestPasswordReset", varargs...)
+	ret0, _ := ret[0].(*authpb.PasswordResetResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RequestPasswordReset indicates an expected call of RequestPasswordReset.
+func (mr *MockAuthServiceClientMockRecorder) RequestPasswordReset(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RequestPasswordReset", reflect.TypeOf((*MockAuthServiceClient)(nil).RequestPasswordReset), varargs...)
+}
+
+// ResendVerification mocks base method.
+func (m *MockAuthServiceClient) ResendVerification(arg0 context.Context, arg1 *authpb.ResendVerificationRequest, arg2 ...grpc.CallOption) (*authpb.VerifyEmailResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ResendVerification", varargs...)
+	ret0, _ := ret[0].(*authpb.VerifyEmailResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResendVerification indicates an expected call of ResendVerification.
+func (mr *MockAuthServiceClientMockRecorder) ResendVerification(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResendVerification", reflect.TypeOf((*MockAuthServiceClient)(nil).ResendVerification), varargs...)
+}
+
+// ResetPassword mocks base method.
+func (m *MockAuthServiceClient) ResetPassword(arg0 context.Context, arg1 *authpb.ResetPasswordRequest, arg2 ...grpc.CallOption) (*authpb.PasswordResetResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ResetPassword", varargs...)
+	ret0, _ := ret[0].(*authpb.PasswordResetResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResetPassword indicates an expected call of ResetPassword.
+func (mr *MockAuthServiceClientMockRecorder) ResetPassword(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetPassword", reflect.TypeOf((*MockAuthServiceClient)(nil).ResetPassword), varargs...)
+}
+
+// RevokeAllSessions mocks base method.
+func (m *MockAuthServiceClient) RevokeAllSessions(arg0 context.Context, arg1 *authpb.RevokeAllSessionsRequest, arg2 ...grpc.CallOption) (*authpb.RevokeSessionResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "RevokeAllSessions", varargs...)
+	ret0, _ := ret[0].(*authpb.RevokeSessionResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevokeAllSessions indicates an expected call of RevokeAllSessions.
+func (mr *MockAuthServiceClientMockRecorder) RevokeAllSessions(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAllSessions", reflect.TypeOf((*MockAuthServiceClient)(nil).RevokeAllSessions), varargs...)
+}
+
+// RevokeSession mocks base method.
+func (m *MockAuthServiceClient) RevokeSession(arg0 context.Context, arg1 *authpb.RevokeSessionRequest, arg2 ...grpc.CallOption) (*authpb.RevokeSessionResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "RevokeSession", varargs...)
+	ret0, _ := ret[0].(*authpb.RevokeSessionResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevokeSession indicates an expected call of RevokeSession.
+func (mr *MockAuthServiceClientMockRecorder) RevokeSession(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeSession", reflect.TypeOf((*MockAuthServiceClient)(nil).RevokeSession), varargs...)
+}
+
+// SetRole mocks base method.
+func (m *MockAuthServiceClient) SetRole(arg0 context.Context, arg1 *authpb.SetRoleRequest, arg2 ...grpc.CallOption) (*authpb.SetRoleResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "SetRole", varargs...)
+	ret0, _ := ret[0].(*authpb.SetRoleResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetRole indicates an expected call of SetRole.
+func (mr *MockAuthServiceClientMockRecorder) SetRole(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetRole", reflect.TypeOf((*MockAuthServiceClient)(nil).SetRole), varargs...)
+}
+
+// SignIn mocks base method.
+func (m *MockAuthServiceClient) SignIn(arg0 context.Context, arg1 *authpb.LoginRequest, arg2 ...grpc.CallOption) (*authpb.AccountWithTokens, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "SignIn", varargs...)
+	ret0, _ := ret[0].(*authpb.AccountWithTokens)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SignIn indicates an expected call of SignIn.
+func (mr *MockAuthServiceClientMockRecorder) SignIn(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignIn", reflect.TypeOf((*MockAuthServiceClient)(nil).SignIn), varargs...)
+}
+
+// SignInMFA mocks base method.
+func (m *MockAuthServiceClient) SignInMFA(arg0 context.Context, arg1 *authpb.MFASignInRequest, arg2 ...grpc.CallOption) (*authpb.AccountWithTokens, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "SignInMFA", varargs...)
+	ret0, _ := ret[0].(*authpb.AccountWithTokens)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SignInMFA indicates an expected call of SignInMFA.
+func (mr *MockAuthServiceClientMockRecorder) SignInMFA(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignInMFA", reflect.TypeOf((*MockAuthServiceClient)(nil).SignInMFA), varargs...)
+}
+
+// SignOut mocks base method.
+func (m *MockAuthServiceClient) SignOut(arg0 context.Context, arg1 *authpb.QuitRequest, arg2 ...grpc.CallOption) (*authpb.QuitResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "SignOut", varargs...)
+	ret0, _ := ret[0].(*authpb.QuitResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SignOut indicates an expected call of SignOut.
+func (mr *MockAuthServiceClientMockRecorder) SignOut(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignOut", reflect.TypeOf((*MockAuthServiceClient)(nil).SignOut), varargs...)
+}
+
+// UnlockAccount mocks base method.
+func (m *MockAuthServiceClient) UnlockAccount(arg0 context.Context, arg1 *authpb.UnlockAccountRequest, arg2 ...grpc.CallOption) (*authpb.UnlockAccountResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "UnlockAccount", varargs...)
+	ret0, _ := ret[0].(*authpb.UnlockAccountResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UnlockAccount indicates an expected call of UnlockAccount.
+func (mr *MockAuthServiceClientMockRecorder) UnlockAccount(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnlockAccount", reflect.TypeOf((*MockAuthServiceClient)(nil).UnlockAccount), varargs...)
+}
+
+// UpdateAccount mocks base method.
+func (m *MockAuthServiceClient) UpdateAccount(arg0 context.Context, arg1 *authpb.UpdateRequest, arg2 ...grpc.CallOption) (*authpb.Account, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "UpdateAccount", varargs...)
+	ret0, _ := ret[0].(*authpb.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateAccount indicates an expected call of UpdateAccount.
+func (mr *MockAuthServiceClientMockRecorder) UpdateAccount(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAccount", reflect.TypeOf((*MockAuthServiceClient)(nil).UpdateAccount), varargs...)
+}
+
+// UpdateBalance mocks base method.
+func (m *MockAuthServiceClient) UpdateBalance(arg0 context.Context, arg1 *authpb.UpdateBalanceRequest, arg2 ...grpc.CallOption) (*authpb.Account, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "UpdateBalance", varargs...)
+	ret0, _ := ret[0].(*authpb.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateBalance indicates an expected call of UpdateBalance.
+func (mr *MockAuthServiceClientMockRecorder) UpdateBalance(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateBalance", reflect.TypeOf((*MockAuthServiceClient)(nil).UpdateBalance), varargs...)
+}
+
+// VerifyEmail mocks base method.
+func (m *MockAuthServiceClient) VerifyEmail(arg0 context.Context, arg1 *authpb.VerifyEmailRequest, arg2 ...grpc.CallOption) (*authpb.VerifyEmailResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "VerifyEmail", varargs...)
+	ret0, _ := ret[0].(*authpb.VerifyEmailResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VerifyEmail indicates an expected call of VerifyEmail.
+func (mr *MockAuthServiceClientMockRecorder) VerifyEmail(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyEmail", reflect.TypeOf((*MockAuthServiceClient)(nil).VerifyEmail), varargs...)
+}
+
+// VerifyTOTP mocks base method.
+func (m *MockAuthServiceClient) VerifyTOTP(arg0 context.Context, arg1 *authpb.VerifyTOTPRequest, arg2 ...grpc.CallOption) (*authpb.VerifyTOTPResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "VerifyTOTP", varargs...)
+	ret0, _ := ret[0].(*authpb.VerifyTOTPResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VerifyTOTP indicates an expected call of VerifyTOTP.
+func (mr *MockAuthServiceClientMockRecorder) VerifyTOTP(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyTOTP", reflect.TypeOf((*MockAuthServiceClient)(nil).VerifyTOTP), varargs...)
+}
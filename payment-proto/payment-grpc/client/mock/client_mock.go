@@ -0,0 +1,347 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/Edbeer/payment-proto/payment-grpc/proto (interfaces: PaymentServiceServer)
+
+// Package mock_proto is a generated GoMock package.
+package mock_proto
+
+import (
+	context "context"
+	reflect "reflect"
+
+	paymentpb "github.com/Edbeer/payment-proto/payment-grpc/proto"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockPaymentServiceServer is a mock of PaymentServiceServer interface.
+type MockPaymentServiceServer struct {
+	ctrl     *gomock.Controller
+	recorder *MockPaymentServiceServerMockRecorder
+}
+
+// MockPaymentServiceServerMockRecorder is the mock recorder for MockPaymentServiceServer.
+type MockPaymentServiceServerMockRecorder struct {
+	mock *MockPaymentServiceServer
+}
+
+// NewMockPaymentServiceServer creates a new mock instance.
+func NewMockPaymentServiceServer(ctrl *gomock.Controller) *MockPaymentServiceServer {
+	mock := &MockPaymentServiceServer{ctrl: ctrl}
+	mock.recorder = &MockPaymentServiceServerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPaymentServiceServer) EXPECT() *MockPaymentServiceServerMockRecorder {
+	return m.recorder
+}
+
+// CancelPayment mocks base method.
+func (m *MockPaymentServiceServer) CancelPayment(arg0 context.Context, arg1 *paymentpb.PaidRequest) (*paymentpb.Statement, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelPayment", arg0, arg1)
+	ret0, _ := ret[0].(*paymentpb.Statement)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CancelPayment indicates an expected call of CancelPayment.
+func (mr *MockPaymentServiceServerMockRecorder) CancelPayment(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelPayment", reflect.TypeOf((*MockPaymentServiceServer)(nil).CancelPayment), arg0, arg1)
+}
+
+// CancelScheduledPayment mocks base method.
+func (m *MockPaymentServiceServer) CancelScheduledPayment(arg0 context.Context, arg1 *paymentpb.PaidRequest) (*paymentpb.ScheduledPayment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelScheduledPayment", arg0, arg1)
+	ret0, _ := ret[0].(*paymentpb.ScheduledPayment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CancelScheduledPayment indicates an expected call of CancelScheduledPayment.
+func (mr *MockPaymentServiceServerMockRecorder) CancelScheduledPayment(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelScheduledPayment", reflect.TypeOf((*MockPaymentServiceServer)(nil).CancelScheduledPayment), arg0, arg1)
+}
+
+// CapturePayment mocks base method.
+func (m *MockPaymentServiceServer) CapturePayment(arg0 context.Context, arg1 *paymentpb.PaidRequest) (*paymentpb.Statement, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CapturePayment", arg0, arg1)
+	ret0, _ := ret[0].(*paymentpb.Statement)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CapturePayment indicates an expected call of CapturePayment.
+func (mr *MockPaymentServiceServerMockRecorder) CapturePayment(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CapturePayment", reflect.TypeOf((*MockPaymentServiceServer)(nil).CapturePayment), arg0, arg1)
+}
+
+// CompleteChallenge mocks base method.
+func (m *MockPaymentServiceServer) CompleteChallenge(arg0 context.Context, arg1 *paymentpb.ChallengeRequest) (*paymentpb.Statement, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompleteChallenge", arg0, arg1)
+	ret0, _ := ret[0].(*paymentpb.Statement)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CompleteChallenge indicates an expected call of CompleteChallenge.
+func (mr *MockPaymentServiceServerMockRecorder) CompleteChallenge(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompleteChallenge", reflect.TypeOf((*MockPaymentServiceServer)(nil).CompleteChallenge), arg0, arg1)
+}
+
+// CreateInstallmentPlan mocks base method.
+func (m *MockPaymentServiceServer) CreateInstallmentPlan(arg0 context.Context, arg1 *paymentpb.InstallmentRequest) (*paymentpb.InstallmentPlan, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateInstallmentPlan", arg0, arg1)
+	ret0, _ := ret[0].(*paymentpb.InstallmentPlan)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateInstallmentPlan indicates an expected call of CreateInstallmentPlan.
+func (mr *MockPaymentServiceServerMockRecorder) CreateInstallmentPlan(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateInstallmentPlan", reflect.TypeOf((*MockPaymentServiceServer)(nil).CreateInstallmentPlan), arg0, arg1)
+}
+
+// CreatePayment mocks base method.
+func (m *MockPaymentServiceServer) CreatePayment(arg0 context.Context, arg1 *paymentpb.CreateRequest) (*paymentpb.Statement, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePayment", arg0, arg1)
+	ret0, _ := ret[0].(*paymentpb.Statement)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreatePayment indicates an expected call of CreatePayment.
+func (mr *MockPaymentServiceServerMockRecorder) CreatePayment(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePayment", reflect.TypeOf((*MockPaymentServiceServer)(nil).CreatePayment), arg0, arg1)
+}
+
+// CreateSplitPayment mocks base method.
+func (m *MockPaymentServiceServer) CreateSplitPayment(arg0 context.Context, arg1 *paymentpb.SplitRequest) (*paymentpb.Statement, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSplitPayment", arg0, arg1)
+	ret0, _ := ret[0].(*paymentpb.Statement)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateSplitPayment indicates an expected call of CreateSplitPayment.
+func (mr *MockPaymentServiceServerMockRecorder) CreateSplitPayment(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSplitPayment", reflect.TypeOf((*MockPaymentServiceServer)(nil).CreateSplitPayment), arg0, arg1)
+}
+
+// DecrementAuthorization mocks base method.
+func (m *MockPaymentServiceServer) DecrementAuthorization(arg0 context.Context, arg1 *paymentpb.PaidRequest) (*paymentpb.Statement, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DecrementAuthorization", arg0, arg1)
+	ret0, _ := ret[0].(*paymentpb.Statement)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DecrementAuthorization indicates an expected call of DecrementAuthorization.
+func (mr *MockPaymentServiceServerMockRecorder) DecrementAuthorization(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DecrementAuthorization", reflect.TypeOf((*MockPaymentServiceServer)(nil).DecrementAuthorization), arg0, arg1)
+}
+
+// ExportStatement mocks base method.
+func (m *MockPaymentServiceServer) ExportStatement(arg0 context.Context, arg1 *paymentpb.ExportRequest) (*paymentpb.StatementFile, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportStatement", arg0, arg1)
+	ret0, _ := ret[0].(*paymentpb.StatementFile)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExportStatement indicates an expected call of ExportStatement.
+func (mr *MockPaymentServiceServerMockRecorder) ExportStatement(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportStatement", reflect.TypeOf((*MockPaymentServiceServer)(nil).ExportStatement), arg0, arg1)
+}
+
+// GetDelinquentInstallments mocks base method.
+func (m *MockPaymentServiceServer) GetDelinquentInstallments(arg0 context.Context, arg1 *paymentpb.DelinquentRequest) (*paymentpb.InstallmentList, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDelinquentInstallments", arg0, arg1)
+	ret0, _ := ret[0].(*paymentpb.InstallmentList)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDelinquentInstallments indicates an expected call of GetDelinquentInstallments.
+func (mr *MockPaymentServiceServerMockRecorder) GetDelinquentInstallments(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDelinquentInstallments", reflect.TypeOf((*MockPaymentServiceServer)(nil).GetDelinquentInstallments), arg0, arg1)
+}
+
+// GetInstallmentPlan mocks base method.
+func (m *MockPaymentServiceServer) GetInstallmentPlan(arg0 context.Context, arg1 *paymentpb.InstallmentPlanRequest) (*paymentpb.InstallmentPlan, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetInstallmentPlan", arg0, arg1)
+	ret0, _ := ret[0].(*paymentpb.InstallmentPlan)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetInstallmentPlan indicates an expected call of GetInstallmentPlan.
+func (mr *MockPaymentServiceServerMockRecorder) GetInstallmentPlan(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInstallmentPlan", reflect.TypeOf((*MockPaymentServiceServer)(nil).GetInstallmentPlan), arg0, arg1)
+}
+
+// ImportPayments mocks base method.
+func (m *MockPaymentServiceServer) ImportPayments(arg0 context.Context, arg1 *paymentpb.ImportRequest) (*paymentpb.ImportReport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ImportPayments", arg0, arg1)
+	ret0, _ := ret[0].(*paymentpb.ImportReport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ImportPayments indicates an expected call of ImportPayments.
+func (mr *MockPaymentServiceServerMockRecorder) ImportPayments(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImportPayments", reflect.TypeOf((*MockPaymentServiceServer)(nil).ImportPayments), arg0, arg1)
+}
+
+// IncrementAuthorization mocks base method.
+func (m *MockPaymentServiceServer) IncrementAuthorization(arg0 context.Context, arg1 *paymentpb.PaidRequest) (*paymentpb.Statement, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrementAuthorization", arg0, arg1)
+	ret0, _ := ret[0].(*paymentpb.Statement)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IncrementAuthorization indicates an expected call of IncrementAuthorization.
+func (mr *MockPaymentServiceServerMockRecorder) IncrementAuthorization(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrementAuthorization", reflect.TypeOf((*MockPaymentServiceServer)(nil).IncrementAuthorization), arg0, arg1)
+}
+
+// ListScheduledPayments mocks base method.
+func (m *MockPaymentServiceServer) ListScheduledPayments(arg0 context.Context, arg1 *paymentpb.ScheduledListRequest) (*paymentpb.ScheduledPaymentList, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListScheduledPayments", arg0, arg1)
+	ret0, _ := ret[0].(*paymentpb.ScheduledPaymentList)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListScheduledPayments indicates an expected call of ListScheduledPayments.
+func (mr *MockPaymentServiceServerMockRecorder) ListScheduledPayments(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListScheduledPayments", reflect.TypeOf((*MockPaymentServiceServer)(nil).ListScheduledPayments), arg0, arg1)
+}
+
+// RefundEscrow mocks base method.
+func (m *MockPaymentServiceServer) RefundEscrow(arg0 context.Context, arg1 *paymentpb.PaidRequest) (*paymentpb.Statement, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RefundEscrow", arg0, arg1)
+	ret0, _ := ret[0].(*paymentpb.Statement)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RefundEscrow indicates an expected call of RefundEscrow.
+func (mr *MockPaymentServiceServerMockRecorder) RefundEscrow(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefundEscrow", reflect.TypeOf((*MockPaymentServiceServer)(nil).RefundEscrow), arg0, arg1)
+}
+
+// RefundPayment mocks base method.
+func (m *MockPaymentServiceServer) RefundPayment(arg0 context.Context, arg1 *paymentpb.PaidRequest) (*paymentpb.Statement, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RefundPayment", arg0, arg1)
+	ret0, _ := ret[0].(*paymentpb.Statement)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RefundPayment indicates an expected call of RefundPayment.
+func (mr *MockPaymentServiceServerMockRecorder) RefundPayment(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefundPayment", reflect.TypeOf((*MockPaymentServiceServer)(nil).RefundPayment), arg0, arg1)
+}
+
+// ReleaseEscrow mocks base method.
+func (m *MockPaymentServiceServer) ReleaseEscrow(arg0 context.Context, arg1 *paymentpb.PaidRequest) (*paymentpb.Statement, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseEscrow", arg0, arg1)
+	ret0, _ := ret[0].(*paymentpb.Statement)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReleaseEscrow indicates an expected call of ReleaseEscrow.
+func (mr *MockPaymentServiceServerMockRecorder) ReleaseEscrow(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseEscrow", reflect.TypeOf((*MockPaymentServiceServer)(nil).ReleaseEscrow), arg0, arg1)
+}
+
+// SubscribePaymentEvents mocks base method.
+func (m *MockPaymentServiceServer) SubscribePaymentEvents(arg0 *paymentpb.SubscribeRequest, arg1 paymentpb.PaymentService_SubscribePaymentEventsServer) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SubscribePaymentEvents", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SubscribePaymentEvents indicates an expected call of SubscribePaymentEvents.
+func (mr *MockPaymentServiceServerMockRecorder) SubscribePaymentEvents(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SubscribePaymentEvents", reflect.TypeOf((*MockPaymentServiceServer)(nil).SubscribePaymentEvents), arg0, arg1)
+}
+
+// Transfer mocks base method.
+func (m *MockPaymentServiceServer) Transfer(arg0 context.Context, arg1 *paymentpb.TransferRequest) (*paymentpb.Statement, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Transfer", arg0, arg1)
+	ret0, _ := ret[0].(*paymentpb.Statement)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Transfer indicates an expected call of Transfer.
+func (mr *MockPaymentServiceServerMockRecorder) Transfer(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Transfer", reflect.TypeOf((*MockPaymentServiceServer)(nil).Transfer), arg0, arg1)
+}
+
+// UpdateScheduledPayment mocks base method.
+func (m *MockPaymentServiceServer) UpdateScheduledPayment(arg0 context.Context, arg1 *paymentpb.ScheduledUpdateRequest) (*paymentpb.ScheduledPayment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateScheduledPayment", arg0, arg1)
+	ret0, _ := ret[0].(*paymentpb.ScheduledPayment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateScheduledPayment indicates an expected call of UpdateScheduledPayment.
+func (mr *MockPaymentServiceServerMockRecorder) UpdateScheduledPayment(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateScheduledPayment", reflect.TypeOf((*MockPaymentServiceServer)(nil).UpdateScheduledPayment), arg0, arg1)
+}
+
+// mustEmbedUnimplementedPaymentServiceServer mocks base method.
+func (m *MockPaymentServiceServer) mustEmbedUnimplementedPaymentServiceServer() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "mustEmbedUnimplementedPaymentServiceServer")
+}
+
+// mustEmbedUnimplementedPaymentServiceServer indicates an expected call of mustEmbedUnimplementedPaymentServiceServer.
+func (mr *MockPaymentServiceServerMockRecorder) mustEmbedUnimplementedPaymentServiceServer() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "mustEmbedUnimplementedPaymentServiceServer", reflect.TypeOf((*MockPaymentServiceServer)(nil).mustEmbedUnimplementedPaymentServiceServer))
+}
@@ -0,0 +1,256 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/Edbeer/payment-proto/payment-grpc/proto (interfaces: PaymentService_SubscribePaymentEventsClient,PaymentService_SubscribePaymentEventsServer)
+
+// Package mock_proto is a generated GoMock package.
+package mock_proto
+
+import (
+	context "context"
+	reflect "reflect"
+
+	paymentpb "github.com/Edbeer/payment-proto/payment-grpc/proto"
+	gomock "github.com/golang/mock/gomock"
+	metadata "google.golang.org/grpc/metadata"
+)
+
+// MockPaymentService_SubscribePaymentEventsClient is a mock of PaymentService_SubscribePaymentEventsClient interface.
+type MockPaymentService_SubscribePaymentEventsClient struct {
+	ctrl     *gomock.Controller
+	recorder *MockPaymentService_SubscribePaymentEventsClientMockRecorder
+}
+
+// MockPaymentService_SubscribePaymentEventsClientMockRecorder is the mock recorder for MockPaymentService_SubscribePaymentEventsClient.
+type MockPaymentService_SubscribePaymentEventsClientMockRecorder struct {
+	mock *MockPaymentService_SubscribePaymentEventsClient
+}
+
+// NewMockPaymentService_SubscribePaymentEventsClient creates a new mock instance.
+func NewMockPaymentService_SubscribePaymentEventsClient(ctrl *gomock.Controller) *MockPaymentService_SubscribePaymentEventsClient {
+	mock := &MockPaymentService_SubscribePaymentEventsClient{ctrl: ctrl}
+	mock.recorder = &MockPaymentService_SubscribePaymentEventsClientMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPaymentService_SubscribePaymentEventsClient) EXPECT() *MockPaymentService_SubscribePaymentEventsClientMockRecorder {
+	return m.recorder
+}
+
+// CloseSend mocks base method.
+func (m *MockPaymentService_SubscribePaymentEventsClient) CloseSend() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CloseSend")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CloseSend indicates an expected call of CloseSend.
+func (mr *MockPaymentService_SubscribePaymentEventsClientMockRecorder) CloseSend() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseSend", reflect.TypeOf((*MockPaymentService_SubscribePaymentEventsClient)(nil).CloseSend))
+}
+
+// Context mocks base method.
+func (m *MockPaymentService_SubscribePaymentEventsClient) Context() context.Context {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Context")
+	ret0, _ := ret[0].(context.Context)
+	return ret0
+}
+
+// Context indicates an expected call of Context.
+func (mr *MockPaymentService_SubscribePaymentEventsClientMockRecorder) Context() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockPaymentService_SubscribePaymentEventsClient)(nil).Context))
+}
+
+// Header mocks base method.
+func (m *MockPaymentService_SubscribePaymentEventsClient) Header() (metadata.MD, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Header")
+	ret0, _ := ret[0].(metadata.MD)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Header indicates an expected call of Header.
+func (mr *MockPaymentService_SubscribePaymentEventsClientMockRecorder) Header() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Header", reflect.TypeOf((*MockPaymentService_SubscribePaymentEventsClient)(nil).Header))
+}
+
+// Recv mocks base method.
+func (m *MockPaymentService_SubscribePaymentEventsClient) Recv() (*paymentpb.PaymentEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Recv")
+	ret0, _ := ret[0].(*paymentpb.PaymentEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Recv indicates an expected call of Recv.
+func (mr *MockPaymentService_SubscribePaymentEventsClientMockRecorder) Recv() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Recv", reflect.TypeOf((*MockPaymentService_SubscribePaymentEventsClient)(nil).Recv))
+}
+
+// RecvMsg mocks base method.
+func (m *MockPaymentService_SubscribePaymentEventsClient) RecvMsg(arg0 interface{}) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecvMsg", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecvMsg indicates an expected call of RecvMsg.
+func (mr *MockPaymentService_SubscribePaymentEventsClientMockRecorder) RecvMsg(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecvMsg", reflect.TypeOf((*MockPaymentService_SubscribePaymentEventsClient)(nil).RecvMsg), arg0)
+}
+
+// SendMsg mocks base method.
+func (m *MockPaymentService_SubscribePaymentEventsClient) SendMsg(arg0 interface{}) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendMsg", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendMsg indicates an expected call of SendMsg.
+func (mr *MockPaymentService_SubscribePaymentEventsClientMockRecorder) SendMsg(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendMsg", reflect.TypeOf((*MockPaymentService_SubscribePaymentEventsClient)(nil).SendMsg), arg0)
+}
+
+// Trailer mocks base method.
+func (m *MockPaymentService_SubscribePaymentEventsClient) Trailer() metadata.MD {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Trailer")
+	ret0, _ := ret[0].(metadata.MD)
+	return ret0
+}
+
+// Trailer indicates an expected call of Trailer.
+func (mr *MockPaymentService_SubscribePaymentEventsClientMockRecorder) Trailer() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Trailer", reflect.TypeOf((*MockPaymentService_SubscribePaymentEventsClient)(nil).Trailer))
+}
+
+// MockPaymentService_SubscribePaymentEventsServer is a mock of PaymentService_SubscribePaymentEventsServer interface.
+type MockPaymentService_SubscribePaymentEventsServer struct {
+	ctrl     *gomock.Controller
+	recorder *MockPaymentService_SubscribePaymentEventsServerMockRecorder
+}
+
+// MockPaymentService_SubscribePaymentEventsServerMockRecorder is the mock recorder for MockPaymentService_SubscribePaymentEventsServer.
+type MockPaymentService_SubscribePaymentEventsServerMockRecorder struct {
+	mock *MockPaymentService_SubscribePaymentEventsServer
+}
+
+// NewMockPaymentService_SubscribePaymentEventsServer creates a new mock instance.
+func NewMockPaymentService_SubscribePaymentEventsServer(ctrl *gomock.Controller) *MockPaymentService_SubscribePaymentEventsServer {
+	mock := &MockPaymentService_SubscribePaymentEventsServer{ctrl: ctrl}
+	mock.recorder = &MockPaymentService_SubscribePaymentEventsServerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPaymentService_SubscribePaymentEventsServer) EXPECT() *MockPaymentService_SubscribePaymentEventsServerMockRecorder {
+	return m.recorder
+}
+
+// Context mocks base method.
+func (m *MockPaymentService_SubscribePaymentEventsServer) Context() context.Context {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Context")
+	ret0, _ := ret[0].(context.Context)
+	return ret0
+}
+
+// Context indicates an expected call of Context.
+func (mr *MockPaymentService_SubscribePaymentEventsServerMockRecorder) Context() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockPaymentService_SubscribePaymentEventsServer)(nil).Context))
+}
+
+// RecvMsg mocks base method.
+func (m *MockPaymentService_SubscribePaymentEventsServer) RecvMsg(arg0 interface{}) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecvMsg", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecvMsg indicates an expected call of RecvMsg.
+func (mr *MockPaymentService_SubscribePaymentEventsServerMockRecorder) RecvMsg(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecvMsg", reflect.TypeOf((*MockPaymentService_SubscribePaymentEventsServer)(nil).RecvMsg), arg0)
+}
+
+// Send mocks base method.
+func (m *MockPaymentService_SubscribePaymentEventsServer) Send(arg0 *paymentpb.PaymentEvent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Send", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Send indicates an expected call of Send.
+func (mr *MockPaymentService_SubscribePaymentEventsServerMockRecorder) Send(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Send", reflect.TypeOf((*MockPaymentService_SubscribePaymentEventsServer)(nil).Send), arg0)
+}
+
+// SendHeader mocks base method.
+func (m *MockPaymentService_SubscribePaymentEventsServer) SendHeader(arg0 metadata.MD) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendHeader", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendHeader indicates an expected call of SendHeader.
+func (mr *MockPaymentService_SubscribePaymentEventsServerMockRecorder) SendHeader(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendHeader", reflect.TypeOf((*MockPaymentService_SubscribePaymentEventsServer)(nil).SendHeader), arg0)
+}
+
+// SendMsg mocks base method.
+func (m *MockPaymentService_SubscribePaymentEventsServer) SendMsg(arg0 interface{}) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendMsg", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendMsg indicates an expected call of SendMsg.
+func (mr *MockPaymentService_SubscribePaymentEventsServerMockRecorder) SendMsg(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendMsg", reflect.TypeOf((*MockPaymentService_SubscribePaymentEventsServer)(nil).SendMsg), arg0)
+}
+
+// SetHeader mocks base method.
+func (m *MockPaymentService_SubscribePaymentEventsServer) SetHeader(arg0 metadata.MD) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetHeader", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetHeader indicates an expected call of SetHeader.
+func (mr *MockPaymentService_SubscribePaymentEventsServerMockRecorder) SetHeader(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetHeader", reflect.TypeOf((*MockPaymentService_SubscribePaymentEventsServer)(nil).SetHeader), arg0)
+}
+
+// SetTrailer mocks base method.
+func (m *MockPaymentService_SubscribePaymentEventsServer) SetTrailer(arg0 metadata.MD) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetTrailer", arg0)
+}
+
+// SetTrailer indicates an expected call of SetTrailer.
+func (mr *MockPaymentService_SubscribePaymentEventsServerMockRecorder) SetTrailer(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTrailer", reflect.TypeOf((*MockPaymentService_SubscribePaymentEventsServer)(nil).SetTrailer), arg0)
+}
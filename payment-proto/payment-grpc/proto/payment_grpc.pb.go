@@ -0,0 +1,837 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             v3.21.6
+// source: payment.proto
+
+package paymentpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// PaymentServiceClient is the client API for PaymentService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type PaymentServiceClient interface {
+	CreatePayment(ctx context.Context, in *CreateRequest, opts ...grpc.CallOption) (*Statement, error)
+	CapturePayment(ctx context.Context, in *PaidRequest, opts ...grpc.CallOption) (*Statement, error)
+	CancelPayment(ctx context.Context, in *PaidRequest, opts ...grpc.CallOption) (*Statement, error)
+	RefundPayment(ctx context.Context, in *PaidRequest, opts ...grpc.CallOption) (*Statement, error)
+	// change of the approved authorization amount
+	IncrementAuthorization(ctx context.Context, in *PaidRequest, opts ...grpc.CallOption) (*Statement, error)
+	DecrementAuthorization(ctx context.Context, in *PaidRequest, opts ...grpc.CallOption) (*Statement, error)
+	// instant account-to-account transfer
+	Transfer(ctx context.Context, in *TransferRequest, opts ...grpc.CallOption) (*Statement, error)
+	// batch of transfers from CSV or ISO 20022 pain.001 file
+	ImportPayments(ctx context.Context, in *ImportRequest, opts ...grpc.CallOption) (*ImportReport, error)
+	// approved authorization paid by the customer in several installments
+	CreateInstallmentPlan(ctx context.Context, in *InstallmentRequest, opts ...grpc.CallOption) (*InstallmentPlan, error)
+	GetInstallmentPlan(ctx context.Context, in *InstallmentPlanRequest, opts ...grpc.CallOption) (*InstallmentPlan, error)
+	GetDelinquentInstallments(ctx context.Context, in *DelinquentRequest, opts ...grpc.CallOption) (*InstallmentList, error)
+	// future-dated payments created with execute_at, modified until execution
+	ListScheduledPayments(ctx context.Context, in *ScheduledListRequest, opts ...grpc.CallOption) (*ScheduledPaymentList, error)
+	UpdateScheduledPayment(ctx context.Context, in *ScheduledUpdateRequest, opts ...grpc.CallOption) (*ScheduledPayment, error)
+	CancelScheduledPayment(ctx context.Context, in *PaidRequest, opts ...grpc.CallOption) (*ScheduledPayment, error)
+	// customer answer to the 3-D Secure challenge of the payment
+	CompleteChallenge(ctx context.Context, in *ChallengeRequest, opts ...grpc.CallOption) (*Statement, error)
+	// marketplace payment split between several merchants
+	CreateSplitPayment(ctx context.Context, in *SplitRequest, opts ...grpc.CallOption) (*Statement, error)
+	// escrow of captured payments
+	ReleaseEscrow(ctx context.Context, in *PaidRequest, opts ...grpc.CallOption) (*Statement, error)
+	RefundEscrow(ctx context.Context, in *PaidRequest, opts ...grpc.CallOption) (*Statement, error)
+	// account statement for the period as camt.053, csv or ofx file
+	ExportStatement(ctx context.Context, in *ExportRequest, opts ...grpc.CallOption) (*StatementFile, error)
+	// live feed of payment state changes
+	SubscribePaymentEvents(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (PaymentService_SubscribePaymentEventsClient, error)
+}
+
+type paymentServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPaymentServiceClient(cc grpc.ClientConnInterface) PaymentServiceClient {
+	return &paymentServiceClient{cc}
+}
+
+func (c *paymentServiceClient) CreatePayment(ctx context.Context, in *CreateRequest, opts ...grpc.CallOption) (*Statement, error) {
+	out := new(Statement)
+	err := c.cc.Invoke(ctx, "/payment.PaymentService/CreatePayment", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) CapturePayment(ctx context.Context, in *PaidRequest, opts ...grpc.CallOption) (*Statement, error) {
+	out := new(Statement)
+	err := c.cc.Invoke(ctx, "/payment.PaymentService/CapturePayment", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) CancelPayment(ctx context.Context, in *PaidRequest, opts ...grpc.CallOption) (*Statement, error) {
+	out := new(Statement)
+	err := c.cc.Invoke(ctx, "/payment.PaymentService/CancelPayment", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) RefundPayment(ctx context.Context, in *PaidRequest, opts ...grpc.CallOption) (*Statement, error) {
+	out := new(Statement)
+	err := c.cc.Invoke(ctx, "/payment.PaymentService/RefundPayment", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) IncrementAuthorization(ctx context.Context, in *PaidRequest, opts ...grpc.CallOption) (*Statement, error) {
+	out := new(Statement)
+	err := c.cc.Invoke(ctx, "/payment.PaymentService/IncrementAuthorization", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) DecrementAuthorization(ctx context.Context, in *PaidRequest, opts ...grpc.CallOption) (*Statement, error) {
+	out := new(Statement)
+	err := c.cc.Invoke(ctx, "/payment.PaymentService/DecrementAuthorization", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) Transfer(ctx context.Context, in *TransferRequest, opts ...grpc.CallOption) (*Statement, error) {
+	out := new(Statement)
+	err := c.cc.Invoke(ctx, "/payment.PaymentService/Transfer", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) ImportPayments(ctx context.Context, in *ImportRequest, opts ...grpc.CallOption) (*ImportReport, error) {
+	out := new(ImportReport)
+	err := c.cc.Invoke(ctx, "/payment.PaymentService/ImportPayments", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) CreateInstallmentPlan(ctx context.Context, in *InstallmentRequest, opts ...grpc.CallOption) (*InstallmentPlan, error) {
+	out := new(InstallmentPlan)
+	err := c.cc.Invoke(ctx, "/payment.PaymentService/CreateInstallmentPlan", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) GetInstallmentPlan(ctx context.Context, in *InstallmentPlanRequest, opts ...grpc.CallOption) (*InstallmentPlan, error) {
+	out := new(InstallmentPlan)
+	err := c.cc.Invoke(ctx, "/payment.PaymentService/GetInstallmentPlan", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) GetDelinquentInstallments(ctx context.Context, in *DelinquentRequest, opts ...grpc.CallOption) (*InstallmentList, error) {
+	out := new(InstallmentList)
+	err := c.cc.Invoke(ctx, "/payment.PaymentService/GetDelinquentInstallments", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) ListScheduledPayments(ctx context.Context, in *ScheduledListRequest, opts ...grpc.CallOption) (*ScheduledPaymentList, error) {
+	out := new(ScheduledPaymentList)
+	err := c.cc.Invoke(ctx, "/payment.PaymentService/ListScheduledPayments", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) UpdateScheduledPayment(ctx context.Context, in *ScheduledUpdateRequest, opts ...grpc.CallOption) (*ScheduledPayment, error) {
+	out := new(ScheduledPayment)
+	err := c.cc.Invoke(ctx, "/payment.PaymentService/UpdateScheduledPayment", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) CancelScheduledPayment(ctx context.Context, in *PaidRequest, opts ...grpc.CallOption) (*ScheduledPayment, error) {
+	out := new(ScheduledPayment)
+	err := c.cc.Invoke(ctx, "/payment.PaymentService/CancelScheduledPayment", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) CompleteChallenge(ctx context.Context, in *ChallengeRequest, opts ...grpc.CallOption) (*Statement, error) {
+	out := new(Statement)
+	err := c.cc.Invoke(ctx, "/payment.PaymentService/CompleteChallenge", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) CreateSplitPayment(ctx context.Context, in *SplitRequest, opts ...grpc.CallOption) (*Statement, error) {
+	out := new(Statement)
+	err := c.cc.Invoke(ctx, "/payment.PaymentService/CreateSplitPayment", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) ReleaseEscrow(ctx context.Context, in *PaidRequest, opts ...grpc.CallOption) (*Statement, error) {
+	out := new(Statement)
+	err := c.cc.Invoke(ctx, "/payment.PaymentService/ReleaseEscrow", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) RefundEscrow(ctx context.Context, in *PaidRequest, opts ...grpc.CallOption) (*Statement, error) {
+	out := new(Statement)
+	err := c.cc.Invoke(ctx, "/payment.PaymentService/RefundEscrow", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) ExportStatement(ctx context.Context, in *ExportRequest, opts ...grpc.CallOption) (*StatementFile, error) {
+	out := new(StatementFile)
+	err := c.cc.Invoke(ctx, "/payment.PaymentService/ExportStatement", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) SubscribePaymentEvents(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (PaymentService_SubscribePaymentEventsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &PaymentService_ServiceDesc.Streams[0], "/payment.PaymentService/SubscribePaymentEvents", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &paymentServiceSubscribePaymentEventsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type PaymentService_SubscribePaymentEventsClient interface {
+	Recv() (*PaymentEvent, error)
+	grpc.ClientStream
+}
+
+type paymentServiceSubscribePaymentEventsClient struct {
+	grpc.ClientStream
+}
+
+func (x *paymentServiceSubscribePaymentEventsClient) Recv() (*PaymentEvent, error) {
+	m := new(PaymentEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// PaymentServiceServer is the server API for PaymentService service.
+// All implementations must embed UnimplementedPaymentServiceServer
+// for forward compatibility
+type PaymentServiceServer interface {
+	CreatePayment(context.Context, *CreateRequest) (*Statement, error)
+	CapturePayment(context.Context, *PaidRequest) (*Statement, error)
+	CancelPayment(context.Context, *PaidRequest) (*Statement, error)
+	RefundPayment(context.Context, *PaidRequest) (*Statement, error)
+	// change of the approved authorization amount
+	IncrementAuthorization(context.Context, *PaidRequest) (*Statement, error)
+	DecrementAuthorization(context.Context, *PaidRequest) (*Statement, error)
+	// instant account-to-account transfer
+	Transfer(context.Context, *TransferRequest) (*Statement, error)
+	// batch of transfers from CSV or ISO 20022 pain.001 file
+	ImportPayments(context.Context, *ImportRequest) (*ImportReport, error)
+	// approved authorization paid by the customer in several installments
+	CreateInstallmentPlan(context.Context, *InstallmentRequest) (*InstallmentPlan, error)
+	GetInstallmentPlan(context.Context, *InstallmentPlanRequest) (*InstallmentPlan, error)
+	GetDelinquentInstallments(context.Context, *DelinquentRequest) (*InstallmentList, error)
+	// future-dated payments created with execute_at, modified until execution
+	ListScheduledPayments(context.Context, *ScheduledListRequest) (*ScheduledPaymentList, error)
+	UpdateScheduledPayment(context.Context, *ScheduledUpdateRequest) (*ScheduledPayment, error)
+	CancelScheduledPayment(context.Context, *PaidRequest) (*ScheduledPayment, error)
+	// customer answer to the 3-D Secure challenge of the payment
+	CompleteChallenge(context.Context, *ChallengeRequest) (*Statement, error)
+	// marketplace payment split between several merchants
+	CreateSplitPayment(context.Context, *SplitRequest) (*Statement, error)
+	// escrow of captured payments
+	ReleaseEscrow(context.Context, *PaidRequest) (*Statement, error)
+	RefundEscrow(context.Context, *PaidRequest) (*Statement, error)
+	// account statement for the period as camt.053, csv or ofx file
+	ExportStatement(context.Context, *ExportRequest) (*StatementFile, error)
+	// live feed of payment state changes
+	SubscribePaymentEvents(*SubscribeRequest, PaymentService_SubscribePaymentEventsServer) error
+	mustEmbedUnimplementedPaymentServiceServer()
+}
+
+// UnimplementedPaymentServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedPaymentServiceServer struct {
+}
+
+func (UnimplementedPaymentServiceServer) CreatePayment(context.Context, *CreateRequest) (*Statement, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreatePayment not implemented")
+}
+func (UnimplementedPaymentServiceServer) CapturePayment(context.Context, *PaidRequest) (*Statement, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CapturePayment not implemented")
+}
+func (UnimplementedPaymentServiceServer) CancelPayment(context.Context, *PaidRequest) (*Statement, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelPayment not implemented")
+}
+func (UnimplementedPaymentServiceServer) RefundPayment(context.Context, *PaidRequest) (*Statement, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RefundPayment not implemented")
+}
+func (UnimplementedPaymentServiceServer) IncrementAuthorization(context.Context, *PaidRequest) (*Statement, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method IncrementAuthorization not implemented")
+}
+func (UnimplementedPaymentServiceServer) DecrementAuthorization(context.Context, *PaidRequest) (*Statement, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DecrementAuthorization not implemented")
+}
+func (UnimplementedPaymentServiceServer) Transfer(context.Context, *TransferRequest) (*Statement, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Transfer not implemented")
+}
+func (UnimplementedPaymentServiceServer) ImportPayments(context.Context, *ImportRequest) (*ImportReport, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ImportPayments not implemented")
+}
+func (UnimplementedPaymentServiceServer) CreateInstallmentPlan(context.Context, *InstallmentRequest) (*InstallmentPlan, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateInstallmentPlan not implemented")
+}
+func (UnimplementedPaymentServiceServer) GetInstallmentPlan(context.Context, *InstallmentPlanRequest) (*InstallmentPlan, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetInstallmentPlan not implemented")
+}
+func (UnimplementedPaymentServiceServer) GetDelinquentInstallments(context.Context, *DelinquentRequest) (*InstallmentList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDelinquentInstallments not implemented")
+}
+func (UnimplementedPaymentServiceServer) ListScheduledPayments(context.Context, *ScheduledListRequest) (*ScheduledPaymentList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListScheduledPayments not implemented")
+}
+func (UnimplementedPaymentServiceServer) UpdateScheduledPayment(context.Context, *ScheduledUpdateRequest) (*ScheduledPayment, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateScheduledPayment not implemented")
+}
+func (UnimplementedPaymentServiceServer) CancelScheduledPayment(context.Context, *PaidRequest) (*ScheduledPayment, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelScheduledPayment not implemented")
+}
+func (UnimplementedPaymentServiceServer) CompleteChallenge(context.Context, *ChallengeRequest) (*Statement, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CompleteChallenge not implemented")
+}
+func (UnimplementedPaymentServiceServer) CreateSplitPayment(context.Context, *SplitRequest) (*Statement, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateSplitPayment not implemented")
+}
+func (UnimplementedPaymentServiceServer) ReleaseEscrow(context.Context, *PaidRequest) (*Statement, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReleaseEscrow not implemented")
+}
+func (UnimplementedPaymentServiceServer) RefundEscrow(context.Context, *PaidRequest) (*Statement, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RefundEscrow not implemented")
+}
+func (UnimplementedPaymentServiceServer) ExportStatement(context.Context, *ExportRequest) (*StatementFile, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ExportStatement not implemented")
+}
+func (UnimplementedPaymentServiceServer) SubscribePaymentEvents(*SubscribeRequest, PaymentService_SubscribePaymentEventsServer) error {
+	return status.Errorf(codes.Unimplemented, "method SubscribePaymentEvents not implemented")
+}
+func (UnimplementedPaymentServiceServer) mustEmbedUnimplementedPaymentServiceServer() {}
+
+// UnsafePaymentServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PaymentServiceServer will
+// result in compilation errors.
+type UnsafePaymentServiceServer interface {
+	mustEmbedUnimplementedPaymentServiceServer()
+}
+
+func RegisterPaymentServiceServer(s grpc.ServiceRegistrar, srv PaymentServiceServer) {
+	s.RegisterService(&PaymentService_ServiceDesc, srv)
+}
+
+func _PaymentService_CreatePayment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).CreatePayment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/payment.PaymentService/CreatePayment",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).CreatePayment(ctx, req.(*CreateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_CapturePayment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PaidRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).CapturePayment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/payment.PaymentService/CapturePayment",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).CapturePayment(ctx, req.(*PaidRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_CancelPayment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PaidRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).CancelPayment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/payment.PaymentService/CancelPayment",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).CancelPayment(ctx, req.(*PaidRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_RefundPayment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PaidRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).RefundPayment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/payment.PaymentService/RefundPayment",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).RefundPayment(ctx, req.(*PaidRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_IncrementAuthorization_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PaidRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).IncrementAuthorization(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/payment.PaymentService/IncrementAuthorization",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).IncrementAuthorization(ctx, req.(*PaidRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_DecrementAuthorization_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PaidRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).DecrementAuthorization(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/payment.PaymentService/DecrementAuthorization",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).DecrementAuthorization(ctx, req.(*PaidRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_Transfer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TransferRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).Transfer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/payment.PaymentService/Transfer",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).Transfer(ctx, req.(*TransferRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_ImportPayments_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ImportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).ImportPayments(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/payment.PaymentService/ImportPayments",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).ImportPayments(ctx, req.(*ImportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_CreateInstallmentPlan_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InstallmentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).CreateInstallmentPlan(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/payment.PaymentService/CreateInstallmentPlan",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).CreateInstallmentPlan(ctx, req.(*InstallmentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_GetInstallmentPlan_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InstallmentPlanRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).GetInstallmentPlan(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/payment.PaymentService/GetInstallmentPlan",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).GetInstallmentPlan(ctx, req.(*InstallmentPlanRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_GetDelinquentInstallments_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DelinquentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).GetDelinquentInstallments(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/payment.PaymentService/GetDelinquentInstallments",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).GetDelinquentInstallments(ctx, req.(*DelinquentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_ListScheduledPayments_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ScheduledListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).ListScheduledPayments(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/payment.PaymentService/ListScheduledPayments",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).ListScheduledPayments(ctx, req.(*ScheduledListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_UpdateScheduledPayment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ScheduledUpdateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).UpdateScheduledPayment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/payment.PaymentService/UpdateScheduledPayment",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).UpdateScheduledPayment(ctx, req.(*ScheduledUpdateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_CancelScheduledPayment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PaidRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).CancelScheduledPayment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/payment.PaymentService/CancelScheduledPayment",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).CancelScheduledPayment(ctx, req.(*PaidRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_CompleteChallenge_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChallengeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).CompleteChallenge(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/payment.PaymentService/CompleteChallenge",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).CompleteChallenge(ctx, req.(*ChallengeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_CreateSplitPayment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SplitRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).CreateSplitPayment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/payment.PaymentService/CreateSplitPayment",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).CreateSplitPayment(ctx, req.(*SplitRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_ReleaseEscrow_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PaidRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).ReleaseEscrow(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/payment.PaymentService/ReleaseEscrow",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).ReleaseEscrow(ctx, req.(*PaidRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_RefundEscrow_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PaidRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).RefundEscrow(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/payment.PaymentService/RefundEscrow",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).RefundEscrow(ctx, req.(*PaidRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_ExportStatement_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).ExportStatement(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/payment.PaymentService/ExportStatement",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).ExportStatement(ctx, req.(*ExportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_SubscribePaymentEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PaymentServiceServer).SubscribePaymentEvents(m, &paymentServiceSubscribePaymentEventsServer{stream})
+}
+
+type PaymentService_SubscribePaymentEventsServer interface {
+	Send(*PaymentEvent) error
+	grpc.ServerStream
+}
+
+type paymentServiceSubscribePaymentEventsServer struct {
+	grpc.ServerStream
+}
+
+func (x *paymentServiceSubscribePaymentEventsServer) Send(m *PaymentEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// PaymentService_ServiceDesc is the grpc.ServiceDesc for PaymentService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PaymentService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "payment.PaymentService",
+	HandlerType: (*PaymentServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreatePayment",
+			Handler:    _PaymentService_CreatePayment_Handler,
+		},
+		{
+			MethodName: "CapturePayment",
+			Handler:    _PaymentService_CapturePayment_Handler,
+		},
+		{
+			MethodName: "CancelPayment",
+			Handler:    _PaymentService_CancelPayment_Handler,
+		},
+		{
+			MethodName: "RefundPayment",
+			Handler:    _PaymentService_RefundPayment_Handler,
+		},
+		{
+			MethodName: "IncrementAuthorization",
+			Handler:    _PaymentService_IncrementAuthorization_Handler,
+		},
+		{
+			MethodName: "DecrementAuthorization",
+			Handler:    _PaymentService_DecrementAuthorization_Handler,
+		},
+		{
+			MethodName: "Transfer",
+			Handler:    _PaymentService_Transfer_Handler,
+		},
+		{
+			MethodName: "ImportPayments",
+			Handler:    _PaymentService_ImportPayments_Handler,
+		},
+		{
+			MethodName: "CreateInstallmentPlan",
+			Handler:    _PaymentService_CreateInstallmentPlan_Handler,
+		},
+		{
+			MethodName: "GetInstallmentPlan",
+			Handler:    _PaymentService_GetInstallmentPlan_Handler,
+		},
+		{
+			MethodName: "GetDelinquentInstallments",
+			Handler:    _PaymentService_GetDelinquentInstallments_Handler,
+		},
+		{
+			MethodName: "ListScheduledPayments",
+			Handler:    _PaymentService_ListScheduledPayments_Handler,
+		},
+		{
+			MethodName: "UpdateScheduledPayment",
+			Handler:    _PaymentService_UpdateScheduledPayment_Handler,
+		},
+		{
+			MethodName: "CancelScheduledPayment",
+			Handler:    _PaymentService_CancelScheduledPayment_Handler,
+		},
+		{
+			MethodName: "CompleteChallenge",
+			Handler:    _PaymentService_CompleteChallenge_Handler,
+		},
+		{
+			MethodName: "CreateSplitPayment",
+			Handler:    _PaymentService_CreateSplitPayment_Handler,
+		},
+		{
+			MethodName: "ReleaseEscrow",
+			Handler:    _PaymentService_ReleaseEscrow_Handler,
+		},
+		{
+			MethodName: "RefundEscrow",
+			Handler:    _PaymentService_RefundEscrow_Handler,
+		},
+		{
+			MethodName: "ExportStatement",
+			Handler:    _PaymentService_ExportStatement_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SubscribePaymentEvents",
+			Handler:       _PaymentService_SubscribePaymentEvents_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "payment.proto",
+}